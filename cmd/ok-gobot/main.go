@@ -6,6 +6,9 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	// Embed the zone database so per-chat and per-job timezones work on
+	// hosts without /usr/share/zoneinfo.
+	_ "time/tzdata"
 
 	// Suppress OSC 11 background-color query by setting TERM=dumb before any
 	// charmbracelet package init runs. envfix must NOT import lipgloss/bubbletea
@@ -17,6 +17,12 @@ type SessionStore interface {
 	GetSessionOption(chatID int64, key string) (string, error)
 }
 
+// ToolApprover asks the user of a chat to confirm a side-effecting tool action.
+// Implemented by the Telegram bot.
+type ToolApprover interface {
+	RequestToolApproval(chatID int64, command string) (bool, error)
+}
+
 // AIResolverConfig holds AI provider configuration for creating clients.
 type AIResolverConfig struct {
 	Provider        string
@@ -38,7 +44,11 @@ type RunResolver struct {
 	AIConfig           AIResolverConfig
 	ToolRegistry       *tools.Registry
 	Scheduler          tools.CronScheduler
-	SubagentSubmitter  tools.SubagentSubmitter // injected after hub creation
+	SubagentSubmitter  tools.SubagentSubmitter  // injected after hub creation
+	Approver           ToolApprover             // optional: binds approval-gated tools to the run's chat
+	Checkpoints        *tools.CheckpointManager // optional: snapshots files before tools modify them
+	OutputSpool        *tools.OutputSpool       // optional: stores oversized tool results as artifacts
+	CustomTools        *tools.CustomToolSet     // optional: workspace-defined tools (tools/*.yaml), hot-reloaded
 }
 
 // RunOverrides allows callers to explicitly override model/thinking level
@@ -72,6 +82,7 @@ func (r *RunResolver) Resolve(chatID int64, overrides *RunOverrides, job *delega
 	ta := NewToolCallingAgent(aiClient, toolReg, profile.Personality)
 	ta.SetModel(model)
 	ta.SetModelAliases(aliases)
+	ta.SetOutputSpool(r.OutputSpool)
 	if thinkLevel != "" {
 		ta.SetThinkLevel(thinkLevel)
 	}
@@ -167,6 +178,21 @@ func (r *RunResolver) buildAIClient(model, thinkLevel string) ai.Client {
 func (r *RunResolver) buildToolRegistry(chatID int64, profile *AgentProfile, isSubagent bool, job *delegation.Job) *tools.Registry {
 	base := r.ToolRegistry
 
+	// Add workspace-defined tools before any filtering so agent restrictions,
+	// approvals and the capability policy cover them like built-ins.
+	if custom := r.CustomTools.List(); len(custom) > 0 {
+		merged := base.Child()
+		for _, tool := range base.List() {
+			merged.Register(tool)
+		}
+		for _, tool := range custom {
+			if _, exists := base.Get(tool.Name()); !exists {
+				merged.Register(tool)
+			}
+		}
+		base = merged
+	}
+
 	// Filter by agent's allowed tools.
 	if profile.HasToolRestrictions() {
 		filtered := base.Child()
@@ -206,6 +232,38 @@ func (r *RunResolver) buildToolRegistry(chatID int64, profile *AgentProfile, isS
 		base = chatRegistry
 	}
 
+	// Scope the git tool to the delegated job's workspace instead of the soul dir.
+	if job != nil && job.WorkspaceRoot != "" {
+		if tool, ok := base.Get("git"); ok {
+			if gitTool, ok := tools.AsGitTool(tool); ok {
+				scoped := base.Child()
+				for _, t := range base.List() {
+					if t.Name() != "git" {
+						scoped.Register(t)
+					}
+				}
+				scoped.Register(gitTool.WithRoot(job.WorkspaceRoot))
+				base = scoped
+			}
+		}
+	}
+
+	// Bind approval-gated tools (e.g. http_request) to the chat that owns the
+	// run so mutating actions prompt the right user.
+	if r.Approver != nil && chatID != 0 {
+		approve := func(command string) (bool, error) {
+			return r.Approver.RequestToolApproval(chatID, command)
+		}
+		bound := base.Child()
+		for _, tool := range base.List() {
+			if rebound, ok := tools.BindApproval(tool, approve); ok {
+				tool = rebound
+			}
+			bound.Register(tool)
+		}
+		base = bound
+	}
+
 	if job != nil && len(job.ToolAllowlist) > 0 {
 		filtered := base.Child()
 		allowed := make(map[string]struct{}, len(job.ToolAllowlist))
@@ -40,6 +40,9 @@ func (noopCronScheduler) RemoveJob(int64) error                                {
 func (noopCronScheduler) ToggleJob(int64, bool) error                          { return nil }
 func (noopCronScheduler) ListJobs() ([]storage.CronJob, error)                 { return nil, nil }
 func (noopCronScheduler) GetNextRun(int64) (time.Time, error)                  { return time.Time{}, nil }
+func (noopCronScheduler) AddScheduledJob(storage.CronJob) (int64, error)       { return 0, nil }
+func (noopCronScheduler) ChatLocation(int64) *time.Location                    { return time.Local }
+func (noopCronScheduler) SetChatTimezone(int64, string) error                  { return nil }
 
 func TestRunResolverBuildToolRegistry_PreservesEstopForJobToolAllowlist(t *testing.T) {
 	t.Parallel()
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"ok-gobot/internal/delegation"
 	"ok-gobot/internal/tools"
 )
 
@@ -252,3 +253,124 @@ func TestBuildToolRegistry_FileReadOnlyViaBuildToolRegistry(t *testing.T) {
 		t.Fatalf("expected ToolDenial, got %v", err)
 	}
 }
+
+type recordingApprover struct {
+	chatID  int64
+	command string
+}
+
+func (a *recordingApprover) RequestToolApproval(chatID int64, command string) (bool, error) {
+	a.chatID = chatID
+	a.command = command
+	return false, nil
+}
+
+func TestBuildToolRegistry_BindsApprovalToChat(t *testing.T) {
+	t.Parallel()
+
+	base := tools.NewRegistry()
+	base.Register(tools.NewHTTPRequestTool(nil, 0))
+
+	approver := &recordingApprover{}
+	resolver := &RunResolver{ToolRegistry: base, Approver: approver}
+
+	reg := resolver.buildToolRegistry(42, &AgentProfile{}, false, nil)
+	tool, ok := reg.Get("http_request")
+	if !ok {
+		t.Fatal("http_request missing from registry")
+	}
+	out, err := tool.Execute(context.Background(), "DELETE", "https://93.184.216.34/item/1")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if out != "Request denied by user" {
+		t.Errorf("out = %q, want user denial", out)
+	}
+	if approver.chatID != 42 {
+		t.Errorf("approval routed to chat %d, want 42", approver.chatID)
+	}
+
+	// Runs without a chat keep the unbound tool, which refuses mutating calls.
+	reg = resolver.buildToolRegistry(0, &AgentProfile{}, false, nil)
+	tool, _ = reg.Get("http_request")
+	if _, err := tool.Execute(context.Background(), "DELETE", "https://93.184.216.34/item/1"); err == nil {
+		t.Fatal("expected unbound http_request to refuse DELETE")
+	}
+}
+
+func TestBuildToolRegistry_ScopesGitToJobWorkspace(t *testing.T) {
+	t.Parallel()
+
+	base := tools.NewRegistry()
+	base.Register(tools.NewGitTool("/soul"))
+	resolver := &RunResolver{ToolRegistry: base}
+
+	reg := resolver.buildToolRegistry(0, &AgentProfile{}, false, &delegation.Job{WorkspaceRoot: "/work/repo"})
+	tool, ok := reg.Get("git")
+	if !ok {
+		t.Fatal("git missing from registry")
+	}
+	gitTool, ok := tools.AsGitTool(tool)
+	if !ok || gitTool.RepoPath != "/work/repo" {
+		t.Fatalf("git not rescoped to job workspace: %+v", gitTool)
+	}
+
+	// The shared registry keeps its original root.
+	orig, _ := base.Get("git")
+	if orig.(*tools.GitTool).RepoPath != "/soul" {
+		t.Error("base registry git tool was mutated")
+	}
+}
+
+func TestBuildToolRegistry_PolicyAllowsGitHistoryRewrite(t *testing.T) {
+	t.Parallel()
+
+	base := tools.NewRegistry()
+	base.Register(tools.NewGitTool("/repo"))
+	resolver := &RunResolver{ToolRegistry: base}
+
+	reg := resolver.buildToolRegistry(0, &AgentProfile{Policy: &tools.CapabilityPolicy{Shell: true, GitHistoryRewrite: true}}, false, nil)
+	tool, _ := reg.Get("git")
+	gitTool, ok := tools.AsGitTool(tool)
+	if !ok || !gitTool.AllowHistoryRewrite {
+		t.Fatal("policy should enable git history rewrite")
+	}
+
+	reg = resolver.buildToolRegistry(0, &AgentProfile{Policy: &tools.CapabilityPolicy{Shell: true}}, false, nil)
+	tool, _ = reg.Get("git")
+	if gitTool, _ := tools.AsGitTool(tool); gitTool.AllowHistoryRewrite {
+		t.Fatal("history rewrite must stay disabled by default")
+	}
+}
+
+func TestBuildToolRegistry_CustomToolsMergedUnderPolicy(t *testing.T) {
+	t.Parallel()
+
+	base := tools.NewRegistry()
+	base.Register(&resolverDangerousTool{name: "file"})
+
+	def, err := tools.ParseCustomToolDefinition([]byte("name: deploy\ndescription: Deploy the app\ncommand: echo deployed\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shadow, err := tools.ParseCustomToolDefinition([]byte("name: file\ndescription: Shadow a built-in\ncommand: echo shadow\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := tools.NewCustomToolSet()
+	custom.Replace([]*tools.CustomTool{tools.NewCustomTool(def, t.TempDir()), tools.NewCustomTool(shadow, t.TempDir())})
+
+	resolver := &RunResolver{ToolRegistry: base, CustomTools: custom}
+	reg := resolver.buildToolRegistry(0, &AgentProfile{}, false, nil)
+	if out, err := reg.Execute(context.Background(), "deploy"); err != nil || out != "deployed\n" {
+		t.Fatalf("deploy = %q, %v", out, err)
+	}
+	if tool, _ := reg.Get("file"); tool.Description() == "Shadow a built-in" {
+		t.Fatal("workspace tools must not replace built-ins")
+	}
+
+	restricted := resolver.buildToolRegistry(0, &AgentProfile{Policy: &tools.CapabilityPolicy{Network: true}}, false, nil)
+	if _, err := restricted.Execute(context.Background(), "deploy"); err == nil {
+		t.Fatal("expected shell policy to block the workspace tool")
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"ok-gobot/internal/ai"
 	"ok-gobot/internal/delegation"
+	"ok-gobot/internal/tools"
 )
 
 // SessionKey is the canonical identifier for a chat session.
@@ -196,6 +197,14 @@ func (h *RuntimeHub) Submit(req RunRequest) <-chan RunEvent {
 		ctx, cancel = context.WithCancel(req.Context)
 	}
 	slot := &runSlot{cancel: cancel}
+	ctx = tools.WithSessionKey(ctx, string(req.SessionKey))
+
+	// Snapshot files before tools modify them so the run can be undone.
+	var checkpoints *tools.CheckpointRecorder
+	if h.resolver.Checkpoints != nil {
+		checkpoints = h.resolver.Checkpoints.Recorder(string(req.SessionKey), fmt.Sprintf("run-%d", time.Now().UnixNano()))
+		ctx = tools.WithCheckpointRecorder(ctx, checkpoints)
+	}
 
 	h.mu.Lock()
 	if existing, ok := h.active[req.SessionKey]; ok {
@@ -209,11 +218,22 @@ func (h *RuntimeHub) Submit(req RunRequest) <-chan RunEvent {
 		defer func() {
 			h.mu.Lock()
 			// Only remove our slot; a newer Submit may have replaced it already.
-			if h.active[req.SessionKey] == slot {
+			current := h.active[req.SessionKey] == slot
+			if current {
 				delete(h.active, req.SessionKey)
 			}
 			h.mu.Unlock()
 			cancel()
+			// Free per-run tool state such as isolated browser contexts,
+			// unless a newer run for this session took over.
+			if current {
+				tools.ReleaseSessionResources(h.resolver.ToolRegistry, string(req.SessionKey))
+			}
+			if checkpoints != nil && checkpoints.Count() > 0 {
+				if _, err := h.resolver.Checkpoints.Prune(); err != nil {
+					log.Printf("[hub] checkpoint pruning failed: %v", err)
+				}
+			}
 			close(events)
 		}()
 
@@ -32,6 +32,9 @@ type ToolEvent struct {
 	Output   string            // truncated result text (populated on Finished)
 	Err      error             // non-nil if Type is ToolEventFinished and tool failed
 	Denial   *tools.ToolDenial // non-nil when the tool was blocked by policy
+	// Attachments are files the tool produced for the user (e.g. charts),
+	// populated on Finished.
+	Attachments []tools.Attachment
 }
 
 // ToolTimeoutSpawnFunc is called when a tool execution exceeds ToolTimeout.
@@ -55,6 +58,7 @@ type ToolCallingAgent struct {
 	onDeltaReset  func()             // fired when tool calls follow streaming text (content discarded)
 	ToolTimeout   time.Duration      // max duration for a single tool call before auto-spawn (0 = no limit)
 	onToolTimeout ToolTimeoutSpawnFunc
+	outputSpool   *tools.OutputSpool // stores oversized tool results as artifacts (nil = off)
 }
 
 // SetToolEventCallback sets a callback that fires on tool lifecycle events.
@@ -84,6 +88,12 @@ func (a *ToolCallingAgent) SetToolTimeoutCallback(timeout time.Duration, cb Tool
 	a.onToolTimeout = cb
 }
 
+// SetOutputSpool enables storing oversized tool results as artifacts; the
+// model then sees a preview and pages through them with read_artifact.
+func (a *ToolCallingAgent) SetOutputSpool(spool *tools.OutputSpool) {
+	a.outputSpool = spool
+}
+
 // NewToolCallingAgent creates a new agent
 func NewToolCallingAgent(aiClient ai.Client, toolRegistry *tools.Registry, personality *Personality) *ToolCallingAgent {
 	return &ToolCallingAgent{
@@ -182,6 +192,15 @@ func (a *ToolCallingAgent) ProcessRequestWithContent(
 	completed := false
 	toolCallsUsed := 0
 
+	// A resumed job attempt continues the interrupted transcript.
+	if cp, ok := loadAgentCheckpoint(ctx); ok {
+		logger.Debugf("ToolAgent: resuming from checkpoint with %d messages", len(cp.Messages))
+		messages = resumeMessages(messages, cp)
+		usedTools = cp.UsedTools
+		toolResults = cp.ToolResults
+		toolCallsUsed = cp.ToolCallsUsed
+	}
+
 	// Resolve streaming client once so we don't re-type-assert on every iteration.
 	streamClient, hasStreaming := a.aiClient.(ai.StreamingClient)
 
@@ -259,7 +278,8 @@ iterationLoop:
 				}
 
 				// Execute tool with optional timeout-triggered subagent spawn.
-				result, err := a.executeToolWithTimeout(ctx, functionName, arguments)
+				callCtx, attachments := tools.WithAttachmentCollector(ctx)
+				result, err := a.executeToolWithTimeout(callCtx, functionName, arguments)
 
 				// Check for structured denial (estop / policy block).
 				var denial *tools.ToolDenial
@@ -284,10 +304,22 @@ iterationLoop:
 					if len(out) > 300 {
 						out = out[:300] + "…"
 					}
-					a.onToolEvent(ToolEvent{ToolName: functionName, Type: ToolEventFinished, Output: out, Err: err, Denial: denial})
+					a.onToolEvent(ToolEvent{
+						ToolName:    functionName,
+						Type:        ToolEventFinished,
+						Output:      out,
+						Err:         err,
+						Denial:      denial,
+						Attachments: attachments.Attachments(),
+					})
 				}
 				logger.Tracef("ToolAgent: tool %s result (%d chars): %.500s", functionName, len(result), result)
 
+				// Keep huge results out of the context window.
+				if denial == nil {
+					result = a.outputSpool.Spool(callCtx, functionName, arguments, result)
+				}
+
 				// Add assistant message with tool call
 				messages = append(messages, ai.ChatMessage{
 					Role:      ai.RoleAssistant,
@@ -307,6 +339,8 @@ iterationLoop:
 				toolResults = append(toolResults, result)
 			}
 
+			saveAgentCheckpoint(ctx, messages, usedTools, toolResults, toolCallsUsed)
+
 			// Continue the loop to get the final response
 			continue
 		}
@@ -565,8 +599,10 @@ func (a *ToolCallingAgent) parseToolCall(response string) *ToolCall {
 // subagent and a notification string is returned as the tool "result" so the
 // model can inform the user.
 func (a *ToolCallingAgent) executeToolWithTimeout(ctx context.Context, toolName, argsJSON string) (string, error) {
-	// browser_task manages its own timeout via SubmitAndWait — skip the generic timeout.
-	if a.ToolTimeout <= 0 || a.onToolTimeout == nil || toolName == "browser_task" {
+	// browser_task manages its own timeout via SubmitAndWait, while
+	// browser_script (per step) and run_code enforce their own limits —
+	// skip the generic timeout for them.
+	if a.ToolTimeout <= 0 || a.onToolTimeout == nil || toolName == "browser_task" || toolName == "browser_script" || toolName == "run_code" {
 		return a.executeToolFromJSON(ctx, toolName, argsJSON)
 	}
 
@@ -623,7 +659,14 @@ func (a *ToolCallingAgent) executeToolFromJSON(ctx context.Context, toolName str
 	if je, ok := tool.(JSONExecutor); ok {
 		strParams := make(map[string]string, len(argsMap))
 		for k, v := range argsMap {
-			strParams[k] = fmt.Sprintf("%v", v)
+			switch v.(type) {
+			case map[string]interface{}, []interface{}:
+				// Nested objects/arrays are passed as JSON so tools can decode them.
+				raw, _ := json.Marshal(v)
+				strParams[k] = string(raw)
+			default:
+				strParams[k] = fmt.Sprintf("%v", v)
+			}
 		}
 		return je.ExecuteJSON(ctx, strParams)
 	}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+
+	"ok-gobot/internal/ai"
+	"ok-gobot/internal/logger"
+	"ok-gobot/internal/runtime"
+)
+
+// resumeNote tells the model that the transcript it sees was restored from a
+// checkpoint, so it carries on instead of repeating finished tool calls.
+const resumeNote = "[System note: the previous attempt at this task was interrupted. " +
+	"The conversation above was restored from its last checkpoint; the tool calls shown " +
+	"already ran and their side effects happened. Do not repeat them — continue from where it stopped.]"
+
+// agentCheckpoint is the resumable state of a tool-calling run inside a
+// durable job. The system prompt is rebuilt on resume, so only the messages
+// after it are stored.
+type agentCheckpoint struct {
+	Messages      []ai.ChatMessage `json:"messages"`
+	UsedTools     []string         `json:"used_tools,omitempty"`
+	ToolResults   []string         `json:"tool_results,omitempty"`
+	ToolCallsUsed int              `json:"tool_calls_used"`
+}
+
+// saveAgentCheckpoint persists the transcript when ctx belongs to a durable
+// job. Failures are logged: a missing checkpoint only costs a full retry.
+func saveAgentCheckpoint(ctx context.Context, messages []ai.ChatMessage, usedTools, toolResults []string, toolCallsUsed int) {
+	if _, ok := runtime.JobIDFromContext(ctx); !ok {
+		return
+	}
+
+	start := 0
+	for start < len(messages) && messages[start].Role == ai.RoleSystem {
+		start++
+	}
+	transcript := make([]ai.ChatMessage, 0, len(messages)-start)
+	for _, m := range messages[start:] {
+		// Image blocks don't round-trip through JSON; keep the text.
+		m.ContentBlocks = nil
+		transcript = append(transcript, m)
+	}
+
+	if err := runtime.SaveContextCheckpoint(ctx, agentCheckpoint{
+		Messages:      transcript,
+		UsedTools:     usedTools,
+		ToolResults:   toolResults,
+		ToolCallsUsed: toolCallsUsed,
+	}); err != nil {
+		logger.Warnf("ToolAgent: failed to save job checkpoint: %v", err)
+	}
+}
+
+// loadAgentCheckpoint returns the checkpoint a resumed job attempt was
+// started with, if it holds an agent transcript.
+func loadAgentCheckpoint(ctx context.Context) (*agentCheckpoint, bool) {
+	payload, ok := runtime.ResumeCheckpointFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	var cp agentCheckpoint
+	if err := json.Unmarshal([]byte(payload), &cp); err != nil || len(cp.Messages) == 0 {
+		return nil, false
+	}
+	return &cp, true
+}
+
+// resumeMessages replaces everything after the system prompt with the
+// checkpointed transcript, followed by a note about the interruption.
+func resumeMessages(messages []ai.ChatMessage, cp *agentCheckpoint) []ai.ChatMessage {
+	var resumed []ai.ChatMessage
+	for _, m := range messages {
+		if m.Role != ai.RoleSystem {
+			break
+		}
+		resumed = append(resumed, m)
+	}
+	resumed = append(resumed, cp.Messages...)
+	return append(resumed, ai.ChatMessage{Role: ai.RoleUser, Content: resumeNote})
+}
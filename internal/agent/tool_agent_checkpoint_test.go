@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ok-gobot/internal/ai"
+	"ok-gobot/internal/runtime"
+	"ok-gobot/internal/storage"
+	"ok-gobot/internal/tools"
+)
+
+func TestToolCallingAgent_ResumesFromJobCheckpoint(t *testing.T) {
+	store, err := storage.New(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatalf("storage.New failed: %v", err)
+	}
+	defer store.Close() //nolint:errcheck
+
+	deploy := &mockTool{name: "deploy", desc: "deploy the app"}
+	registry := tools.NewRegistry()
+	registry.Register(deploy)
+	personality := &Personality{Files: map[string]string{"IDENTITY.md": "Test Bot"}}
+
+	svc := runtime.NewJobService(store)
+	first, err := svc.StartDetached(context.Background(), runtime.JobSpec{Kind: "agent_task", MaxAttempts: 2},
+		func(ctx context.Context, _ *storage.Job, _ *runtime.JobService) (runtime.JobRunResult, error) {
+			agent := NewToolCallingAgent(&mockAIClient{toolCallName: "deploy", toolCallArgs: `{"command":"prod"}`}, registry, personality)
+			if _, err := agent.ProcessRequest(ctx, "deploy to prod", ""); err != nil {
+				return runtime.JobRunResult{}, err
+			}
+			// Simulate a crash after the tool ran.
+			return runtime.JobRunResult{}, context.Canceled
+		})
+	if err != nil {
+		t.Fatalf("StartDetached failed: %v", err)
+	}
+	waitForAgentJob(t, store, first.JobID)
+
+	if _, ok, _ := svc.LatestCheckpoint(first.JobID); !ok {
+		t.Fatal("expected a checkpoint after the tool iteration")
+	}
+	deploy.allArgs = nil
+
+	resumed := &recordingAIClient{finalText: "deployed"}
+	result := make(chan string, 1)
+	svc.RegisterKind("agent_task", runtime.ResumeAbandon, func(*storage.Job) (runtime.JobRunner, error) {
+		return func(ctx context.Context, _ *storage.Job, _ *runtime.JobService) (runtime.JobRunResult, error) {
+			agent := NewToolCallingAgent(resumed, registry, personality)
+			resp, err := agent.ProcessRequest(ctx, "deploy to prod", "")
+			if err != nil {
+				return runtime.JobRunResult{}, err
+			}
+			result <- resp.ToolName
+			return runtime.JobRunResult{Summary: resp.Message}, nil
+		}, nil
+	})
+	if _, err := svc.Retry(context.Background(), first.JobID, runtime.RetryResume); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+
+	if toolName := <-result; toolName != "deploy" {
+		t.Errorf("resumed run should report the checkpointed tool, got %q", toolName)
+	}
+	if len(deploy.allArgs) != 0 {
+		t.Errorf("tool ran again on resume with %v", deploy.allArgs)
+	}
+
+	msgs := resumed.lastMessages
+	if len(msgs) < 4 || msgs[0].Role != ai.RoleSystem {
+		t.Fatalf("unexpected resumed transcript: %+v", msgs)
+	}
+	var sawResult bool
+	for _, m := range msgs {
+		if m.Role == ai.RoleTool && strings.Contains(m.Content, "OK: executed deploy") {
+			sawResult = true
+		}
+	}
+	if !sawResult {
+		t.Errorf("resumed transcript is missing the tool result: %+v", msgs)
+	}
+	if last := msgs[len(msgs)-1]; last.Role != ai.RoleUser || !strings.Contains(last.Content, "interrupted") {
+		t.Errorf("expected the interruption note last, got %+v", last)
+	}
+}
+
+func waitForAgentJob(t *testing.T, store *storage.Store, jobID string) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := store.GetJob(jobID)
+		if err != nil {
+			t.Fatalf("GetJob failed: %v", err)
+		}
+		if job != nil && job.CompletedAt != "" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", jobID)
+}
@@ -3,11 +3,16 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"ok-gobot/internal/config"
+	"ok-gobot/internal/cron"
+	"ok-gobot/internal/role"
 	"ok-gobot/internal/runtime"
 	"ok-gobot/internal/storage"
 )
@@ -20,6 +25,8 @@ type mockDataProvider struct {
 	artifacts []storage.JobArtifact
 	workers   []runtime.WorkerSnapshot
 	cancelErr error
+	retryErr  error
+	retried   []string
 }
 
 func (m *mockDataProvider) ListJobs(status string, limit int) ([]storage.Job, error) {
@@ -51,6 +58,14 @@ func (m *mockDataProvider) CancelJob(jobID string) error {
 	return m.cancelErr
 }
 
+func (m *mockDataProvider) RetryJob(jobID string, resume bool) (*storage.Job, error) {
+	if m.retryErr != nil {
+		return nil, m.retryErr
+	}
+	m.retried = append(m.retried, jobID)
+	return &storage.Job{JobID: jobID + "-retry", Attempt: 2}, nil
+}
+
 func (m *mockDataProvider) WorkerSnapshots() []runtime.WorkerSnapshot {
 	return m.workers
 }
@@ -323,3 +338,340 @@ func TestHandleJobsWrongMethod(t *testing.T) {
 		t.Fatalf("Expected 405, got %d", w.Code)
 	}
 }
+
+// mockRoleStarter implements RoleStarter for testing.
+type mockRoleStarter struct {
+	planErr error
+	runs    []map[string]any
+}
+
+func (m *mockRoleStarter) PlanRole(name, task string, params map[string]any) (*role.Plan, error) {
+	if m.planErr != nil {
+		return nil, m.planErr
+	}
+	return &role.Plan{Role: name, Worker: "premium", Params: params, Prompt: "Check.", Task: task}, nil
+}
+
+func (m *mockRoleStarter) RunRole(name, task string, params map[string]any, chatID int64) (string, error) {
+	m.runs = append(m.runs, params)
+	return "job-role-1", nil
+}
+
+func TestHandleMissionRoleRun(t *testing.T) {
+	rs := &mockRoleStarter{}
+	srv := newTestServer(&mockDataProvider{})
+	srv.SetRoleStarter(rs)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		srv.handleMissionRoleByName(w, req)
+		return w
+	}
+
+	w := post("/api/mission/roles/uptime/run", `{"params":{"host":"db1"},"dry_run":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("dry run: status %d, body %s", w.Code, w.Body)
+	}
+	var dry struct {
+		Plan     role.Plan `json:"plan"`
+		CostTier string    `json:"cost_tier"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&dry); err != nil {
+		t.Fatal(err)
+	}
+	if dry.Plan.Role != "uptime" || dry.CostTier != "premium" || len(rs.runs) != 0 {
+		t.Fatalf("dry run = %+v, runs %v", dry, rs.runs)
+	}
+
+	w = post("/api/mission/roles/uptime/run", `{"params":{"host":"db1"}}`)
+	if w.Code != http.StatusAccepted || !strings.Contains(w.Body.String(), "job-role-1") {
+		t.Fatalf("run: status %d, body %s", w.Code, w.Body)
+	}
+	if len(rs.runs) != 1 || rs.runs[0]["host"] != "db1" {
+		t.Fatalf("runs = %v", rs.runs)
+	}
+
+	if w := post("/api/mission/roles/uptime/delete", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown action: status %d", w.Code)
+	}
+	rs.planErr = fmt.Errorf("%w: nope", role.ErrNotFound)
+	if w := post("/api/mission/roles/nope/run", `{}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown role: status %d", w.Code)
+	}
+	rs.planErr = fmt.Errorf(`role "uptime": param host is required`)
+	if w := post("/api/mission/roles/uptime/run", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unbound param: status %d", w.Code)
+	}
+}
+
+// mockRoleEditor implements RoleEditor for testing.
+type mockRoleEditor struct {
+	sources map[string]string
+}
+
+func (m *mockRoleEditor) Roles() ([]*role.Manifest, []error) {
+	var out []*role.Manifest
+	for name, src := range m.sources {
+		if man, err := role.Parse(name, []byte(src)); err == nil {
+			out = append(out, man)
+		}
+	}
+	return out, []error{errors.New("parsing broken.md: bad frontmatter")}
+}
+
+func (m *mockRoleEditor) RoleSource(name string) (string, error) {
+	src, ok := m.sources[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", role.ErrNotFound, name)
+	}
+	return src, nil
+}
+
+func (m *mockRoleEditor) SaveRole(name, content string) (*role.Manifest, bool, error) {
+	man, err := role.Parse(name, []byte(content))
+	if err != nil {
+		return nil, false, err
+	}
+	_, exists := m.sources[name]
+	m.sources[name] = content
+	return man, !exists, nil
+}
+
+func (m *mockRoleEditor) DeleteRole(name string) error {
+	if _, ok := m.sources[name]; !ok {
+		return fmt.Errorf("%w: %s", role.ErrNotFound, name)
+	}
+	delete(m.sources, name)
+	return nil
+}
+
+func TestHandleMissionRoleManifests(t *testing.T) {
+	re := &mockRoleEditor{sources: map[string]string{}}
+	srv := newTestServer(&mockDataProvider{})
+	srv.SetRoleEditor(re)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		if path == "/api/mission/roles" {
+			srv.handleMissionRoles(w, req)
+		} else {
+			srv.handleMissionRoleByName(w, req)
+		}
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/mission/roles", `{"name":"uptime","content":"---\nworker: cheap\n---\nCheck."}`)
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"worker":"cheap"`) {
+		t.Fatalf("create: status %d, body %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodPost, "/api/mission/roles", `{"name":"uptime","content":"Check."}`); w.Code != http.StatusConflict {
+		t.Errorf("create existing: status %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/api/mission/roles/uptime", `{"content":"---\napproval: sometimes\n---\nX."}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid manifest: status %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/api/mission/roles/uptime", `{"content":"---\nworker: premium\n---\nCheck."}`); w.Code != http.StatusOK {
+		t.Errorf("update: status %d, body %s", w.Code, w.Body)
+	}
+
+	w = do(http.MethodGet, "/api/mission/roles/uptime", "")
+	var entry manifestEntry
+	if err := json.NewDecoder(w.Body).Decode(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Worker != "premium" || !strings.Contains(entry.Content, "worker: premium") {
+		t.Errorf("get = %+v", entry)
+	}
+
+	w = do(http.MethodGet, "/api/mission/roles/", "")
+	var list struct {
+		Roles  []manifestEntry `json:"roles"`
+		Errors []string        `json:"errors"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Roles) != 1 || len(list.Errors) != 1 {
+		t.Errorf("list = %+v", list)
+	}
+
+	if w := do(http.MethodDelete, "/api/mission/roles/uptime", ""); w.Code != http.StatusOK {
+		t.Errorf("delete: status %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/mission/roles/uptime", ""); w.Code != http.StatusNotFound {
+		t.Errorf("delete missing: status %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/mission/roles/uptime", ""); w.Code != http.StatusNotFound {
+		t.Errorf("get deleted: status %d", w.Code)
+	}
+}
+
+// mockScheduleController implements ScheduleController for testing.
+type mockScheduleController struct {
+	enabled map[int64]bool
+	fired   []int64
+}
+
+func (m *mockScheduleController) ToggleJob(jobID int64, enabled bool) error {
+	if _, ok := m.enabled[jobID]; !ok {
+		return cron.ErrJobNotFound
+	}
+	m.enabled[jobID] = enabled
+	return nil
+}
+
+func (m *mockScheduleController) RunNow(jobID int64) error {
+	if _, ok := m.enabled[jobID]; !ok {
+		return cron.ErrJobNotFound
+	}
+	m.fired = append(m.fired, jobID)
+	return nil
+}
+
+func TestHandleMissionScheduleActions(t *testing.T) {
+	sc := &mockScheduleController{enabled: map[int64]bool{7: true}}
+	srv := newTestServer(&mockDataProvider{})
+	srv.SetScheduleController(sc)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		srv.handleMissionScheduleByID(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, "/api/mission/schedules/7/disable"); w.Code != http.StatusOK || sc.enabled[7] {
+		t.Errorf("disable: status %d, enabled %v", w.Code, sc.enabled[7])
+	}
+	if w := do(http.MethodPost, "/api/mission/schedules/7/enable"); w.Code != http.StatusOK || !sc.enabled[7] {
+		t.Errorf("enable: status %d, enabled %v", w.Code, sc.enabled[7])
+	}
+	if w := do(http.MethodPost, "/api/mission/schedules/7/run"); w.Code != http.StatusAccepted || len(sc.fired) != 1 {
+		t.Errorf("run: status %d, fired %v", w.Code, sc.fired)
+	}
+	if w := do(http.MethodPost, "/api/mission/schedules/8/run"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown schedule: status %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/mission/schedules/abc/run"); w.Code != http.StatusBadRequest {
+		t.Errorf("bad ID: status %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/mission/schedules/7/run"); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET run: status %d", w.Code)
+	}
+}
+
+func TestHandleMissionRunActions(t *testing.T) {
+	dp := &mockDataProvider{job: &storage.Job{JobID: "job-1", Status: "failed"}}
+	srv := newTestServer(dp)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		srv.handleMissionRunByID(w, req)
+		return w
+	}
+
+	if w := post("/api/mission/runs/job-1/cancel", ""); w.Code != http.StatusOK {
+		t.Errorf("cancel: status %d", w.Code)
+	}
+	w := post("/api/mission/runs/job-1/retry", "")
+	if w.Code != http.StatusAccepted || !strings.Contains(w.Body.String(), "job-1-retry") {
+		t.Errorf("retry: status %d, body %s", w.Code, w.Body)
+	}
+	dp.retryErr = errors.New(`job "job-1" is not retryable while status=running`)
+	if w := post("/api/mission/runs/job-1/retry", `{"resume":true}`); w.Code != http.StatusConflict {
+		t.Errorf("refused retry: status %d", w.Code)
+	}
+	if w := post("/api/mission/runs/job-1/pause", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown action: status %d", w.Code)
+	}
+	dp.job = nil
+	if w := post("/api/mission/runs/job-2/retry", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown job: status %d", w.Code)
+	}
+}
+
+// mockPolicyEditor implements PolicyEditor for testing.
+type mockPolicyEditor struct {
+	policies []config.RolePolicyEntry
+}
+
+func (m *mockPolicyEditor) RolePolicies() []config.RolePolicyEntry {
+	return m.policies
+}
+
+func (m *mockPolicyEditor) SetRolePolicy(entry config.RolePolicyEntry) (bool, error) {
+	for i := range m.policies {
+		if m.policies[i].Name == entry.Name {
+			m.policies[i] = entry
+			return false, nil
+		}
+	}
+	m.policies = append(m.policies, entry)
+	return true, nil
+}
+
+func (m *mockPolicyEditor) DeleteRolePolicy(name string) error {
+	for i := range m.policies {
+		if m.policies[i].Name == name {
+			m.policies = append(m.policies[:i], m.policies[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", config.ErrRolePolicyNotFound, name)
+}
+
+func TestHandleMissionPolicies(t *testing.T) {
+	pe := &mockPolicyEditor{}
+	srv := newTestServer(&mockDataProvider{})
+	srv.SetPolicyEditor(pe)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		if path == "/api/mission/policies" {
+			srv.handleMissionPolicies(w, req)
+		} else {
+			srv.handleMissionPolicyByName(w, req)
+		}
+		return w
+	}
+
+	body := `{"default_tier":"cheap","tiers":{"cheap":{"model":"haiku","max_duration":"5m"}}}`
+	if w := do(http.MethodPut, "/api/mission/policies/researcher", body); w.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, body %s", w.Code, w.Body)
+	}
+	if pe.policies[0].Tiers["cheap"].Model != "haiku" {
+		t.Errorf("saved policy = %+v", pe.policies[0])
+	}
+	if w := do(http.MethodPut, "/api/mission/policies/researcher", `{"default_tier":"standard"}`); w.Code != http.StatusOK {
+		t.Errorf("update: status %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/api/mission/policies/researcher", `{"tiers":{"gold":{}}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid tier: status %d", w.Code)
+	}
+	if w := do(http.MethodPut, "/api/mission/policies/researcher", `{"tiers":{"cheap":{"max_duration":"soon"}}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid duration: status %d", w.Code)
+	}
+
+	w := do(http.MethodGet, "/api/mission/policies", "")
+	var list []policyEntry
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "researcher" || list[0].DefaultTier != "standard" {
+		t.Errorf("list = %+v", list)
+	}
+
+	if w := do(http.MethodDelete, "/api/mission/policies/researcher", ""); w.Code != http.StatusOK {
+		t.Errorf("delete: status %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/mission/policies/researcher", ""); w.Code != http.StatusNotFound {
+		t.Errorf("delete missing: status %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/mission/policies/researcher", ""); w.Code != http.StatusNotFound {
+		t.Errorf("get deleted: status %d", w.Code)
+	}
+}
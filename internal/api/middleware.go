@@ -5,12 +5,16 @@ import (
 	"strings"
 )
 
+// webhookPrefix is where role webhook triggers are served.
+const webhookPrefix = "/hooks/"
+
 // authMiddleware creates a middleware that validates API key
 func authMiddleware(apiKey string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Skip auth for health endpoint
-			if r.URL.Path == "/api/health" {
+			// Skip auth for the health endpoint and for webhook triggers,
+			// which are authenticated by their HMAC signature
+			if r.URL.Path == "/api/health" || strings.HasPrefix(r.URL.Path, webhookPrefix) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -57,7 +61,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 			allowedOrigin = origin
 		}
 		w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key, Authorization")
 
 		if r.Method == "OPTIONS" {
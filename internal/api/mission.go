@@ -1,13 +1,25 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+
+	"ok-gobot/internal/role"
+	"ok-gobot/internal/runtime"
 )
 
-// handleMissionRoles returns all registered agent profiles.
+// handleMissionRoles returns all registered agent profiles. POST creates a
+// role manifest (see handleRoleCreate).
 func (s *APIServer) handleMissionRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		s.handleRoleCreate(w, r)
+		return
+	}
 	if r.Method != http.MethodGet {
 		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -56,6 +68,92 @@ func (s *APIServer) handleMissionRoles(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, roles)
 }
 
+// roleRunRequest is the body of POST /api/mission/roles/{name}/run.
+type roleRunRequest struct {
+	Task   string         `json:"task"`
+	Params map[string]any `json:"params"`
+	ChatID int64          `json:"chat_id"`
+	DryRun bool           `json:"dry_run"`
+}
+
+// handleMissionRoleByName serves the role manifest endpoints:
+//
+//	GET    /api/mission/roles/           — list manifests
+//	GET    /api/mission/roles/{name}     — manifest with its markdown
+//	PUT    /api/mission/roles/{name}     — create or replace a manifest
+//	DELETE /api/mission/roles/{name}     — delete a manifest
+//	POST   /api/mission/roles/{name}/run — start a run, or with dry_run
+//	                                       return the resolved plan
+func (s *APIServer) handleMissionRoleByName(w http.ResponseWriter, r *http.Request) {
+	name, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/mission/roles/"), "/")
+	switch {
+	case action == "run" && name != "":
+		s.handleRoleRun(w, r, name)
+	case action != "":
+		writeJSONError(w, "Not found", http.StatusNotFound)
+	case name == "" && r.Method == http.MethodGet:
+		s.handleRoleList(w)
+	case name == "":
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		s.handleRoleManifest(w, r, name)
+	}
+}
+
+// handleRoleRun starts a run of a role manifest with bound params.
+func (s *APIServer) handleRoleRun(w http.ResponseWriter, r *http.Request, name string) {
+	if s.roles == nil {
+		writeJSONError(w, "Role runs not available", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req roleRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeJSONError(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := s.roles.PlanRole(name, req.Task, req.Params)
+	if err != nil {
+		writeRoleError(w, err)
+		return
+	}
+	if req.DryRun {
+		tier, _ := runtime.ParseCostTier(plan.Worker)
+		writeJSON(w, map[string]interface{}{
+			"plan":      plan,
+			"cost_tier": tier,
+		})
+		return
+	}
+
+	jobID, err := s.roles.RunRole(name, req.Task, req.Params, req.ChatID)
+	if err != nil {
+		writeRoleError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{ //nolint:errcheck
+		"job_id": jobID,
+		"role":   name,
+	})
+}
+
+// writeRoleError reports a role that does not exist as 404 and any other
+// role error (bad manifest, unbound params) as 400.
+func writeRoleError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, role.ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	writeJSONError(w, err.Error(), status)
+}
+
 // handleMissionSchedules returns all cron jobs with next-run times.
 func (s *APIServer) handleMissionSchedules(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
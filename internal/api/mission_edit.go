@@ -0,0 +1,448 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ok-gobot/internal/config"
+	"ok-gobot/internal/cron"
+	"ok-gobot/internal/role"
+)
+
+// --- Role manifests ---
+
+// manifestEntry describes a role manifest in mission control responses.
+type manifestEntry struct {
+	Name     string         `json:"name"`
+	Extends  string         `json:"extends,omitempty"`
+	Worker   string         `json:"worker,omitempty"`
+	Tools    []string       `json:"tools,omitempty"`
+	Schedule string         `json:"schedule,omitempty"`
+	Triggers []triggerEntry `json:"triggers,omitempty"`
+	Params   []string       `json:"params,omitempty"`
+	Outputs  []string       `json:"outputs,omitempty"`
+	Approval string         `json:"approval"`
+	Content  string         `json:"content,omitempty"`
+}
+
+type triggerEntry struct {
+	Kind   string `json:"kind"`
+	Source string `json:"source"`
+}
+
+func newManifestEntry(m *role.Manifest) manifestEntry {
+	entry := manifestEntry{
+		Name:     m.Name,
+		Extends:  m.Extends,
+		Worker:   m.Worker,
+		Tools:    m.Tools,
+		Schedule: m.Schedule,
+		Approval: string(m.Approval),
+	}
+	for _, t := range m.Triggers {
+		entry.Triggers = append(entry.Triggers, triggerEntry{Kind: t.Kind(), Source: t.Source()})
+	}
+	for _, p := range m.Params {
+		entry.Params = append(entry.Params, p.Name)
+	}
+	for _, o := range m.Outputs {
+		entry.Outputs = append(entry.Outputs, o.Name)
+	}
+	return entry
+}
+
+// roleManifestRequest is the body of POST /api/mission/roles and
+// PUT /api/mission/roles/{name}.
+type roleManifestRequest struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// handleRoleList returns the role manifests, with the errors of files that
+// do not load.
+func (s *APIServer) handleRoleList(w http.ResponseWriter) {
+	if s.editor == nil {
+		writeJSONError(w, "Role editing not available", http.StatusServiceUnavailable)
+		return
+	}
+	manifests, errs := s.editor.Roles()
+	roles := make([]manifestEntry, 0, len(manifests))
+	for _, m := range manifests {
+		roles = append(roles, newManifestEntry(m))
+	}
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	writeJSON(w, map[string]interface{}{
+		"roles":  roles,
+		"errors": messages,
+	})
+}
+
+// handleRoleCreate creates a role manifest. An existing role is not
+// replaced; use PUT for that.
+//
+//	POST /api/mission/roles  {"name": "uptime", "content": "---\nworker: cheap\n---\n..."}
+func (s *APIServer) handleRoleCreate(w http.ResponseWriter, r *http.Request) {
+	if s.editor == nil {
+		writeJSONError(w, "Role editing not available", http.StatusServiceUnavailable)
+		return
+	}
+	var req roleManifestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		writeJSONError(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if _, err := s.editor.RoleSource(req.Name); err == nil {
+		writeJSONError(w, "role "+req.Name+" already exists", http.StatusConflict)
+		return
+	} else if !errors.Is(err, role.ErrNotFound) {
+		writeRoleError(w, err)
+		return
+	}
+	s.saveRole(w, req.Name, req.Content)
+}
+
+// handleRoleManifest reads, replaces or deletes one role manifest.
+func (s *APIServer) handleRoleManifest(w http.ResponseWriter, r *http.Request, name string) {
+	if s.editor == nil {
+		writeJSONError(w, "Role editing not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		content, err := s.editor.RoleSource(name)
+		if err != nil {
+			writeRoleError(w, err)
+			return
+		}
+		var entry manifestEntry
+		if m, err := role.Parse(name, []byte(content)); err == nil {
+			entry = newManifestEntry(m)
+		} else {
+			entry = manifestEntry{Name: name}
+		}
+		entry.Content = content
+		writeJSON(w, entry)
+
+	case http.MethodPut:
+		var req roleManifestRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Name != "" && req.Name != name {
+			writeJSONError(w, "name does not match the URL", http.StatusBadRequest)
+			return
+		}
+		s.saveRole(w, name, req.Content)
+
+	case http.MethodDelete:
+		if err := s.editor.DeleteRole(name); err != nil {
+			writeRoleError(w, err)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"success": true,
+			"message": "Role deleted",
+		})
+
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// saveRole validates and writes a manifest, answering 201 for a new role.
+func (s *APIServer) saveRole(w http.ResponseWriter, name, content string) {
+	if strings.TrimSpace(content) == "" {
+		writeJSONError(w, "content is required", http.StatusBadRequest)
+		return
+	}
+	m, created, err := s.editor.SaveRole(name, content)
+	if err != nil {
+		writeRoleError(w, err)
+		return
+	}
+	entry := newManifestEntry(m)
+	if !created {
+		writeJSON(w, entry)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry) //nolint:errcheck
+}
+
+// --- Schedules ---
+
+// handleMissionScheduleByID serves the schedule actions:
+//
+//	POST /api/mission/schedules/{id}/enable
+//	POST /api/mission/schedules/{id}/disable
+//	POST /api/mission/schedules/{id}/run     — fire once now
+func (s *APIServer) handleMissionScheduleByID(w http.ResponseWriter, r *http.Request) {
+	if s.cron == nil {
+		writeJSONError(w, "Scheduler not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/mission/schedules/"), "/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeJSONError(w, "invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+	if action != "enable" && action != "disable" && action != "run" {
+		writeJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if action == "run" {
+		err = s.cron.RunNow(id)
+	} else {
+		err = s.cron.ToggleJob(id, action == "enable")
+	}
+	if errors.Is(err, cron.ErrJobNotFound) {
+		writeJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if action == "run" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{ //nolint:errcheck
+			"success": true,
+			"id":      id,
+		})
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"success": true,
+		"id":      id,
+		"enabled": action == "enable",
+	})
+}
+
+// --- Runs ---
+
+// retryRequest is the optional body of POST /api/mission/runs/{job_id}/retry.
+type retryRequest struct {
+	Resume bool `json:"resume"` // continue from the job's last checkpoint
+}
+
+// handleMissionRunByID serves the run actions:
+//
+//	POST /api/mission/runs/{job_id}/cancel
+//	POST /api/mission/runs/{job_id}/retry  {"resume": false}
+func (s *APIServer) handleMissionRunByID(w http.ResponseWriter, r *http.Request) {
+	if s.data == nil {
+		writeJSONError(w, "Data provider not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	jobID, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/mission/runs/"), "/")
+	if jobID == "" || (action != "cancel" && action != "retry") {
+		writeJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, err := s.data.GetJob(jobID)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		writeJSONError(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	if action == "cancel" {
+		if err := s.data.CancelJob(jobID); err != nil {
+			writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"success": true,
+			"message": "Cancellation requested",
+		})
+		return
+	}
+
+	var req retryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	retry, err := s.data.RetryJob(jobID, req.Resume)
+	if err != nil {
+		// The job exists, so the retry was refused: still running, out of
+		// attempts or without a checkpoint to resume from.
+		writeJSONError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{ //nolint:errcheck
+		"job_id":   retry.JobID,
+		"retry_of": jobID,
+		"attempt":  retry.Attempt,
+	})
+}
+
+// --- Cost tier policies ---
+
+// policyEntry is a runtime.roles cost tier policy in mission control
+// requests and responses.
+type policyEntry struct {
+	Name        string               `json:"name"`
+	DefaultTier string               `json:"default_tier,omitempty"`
+	Tiers       map[string]tierEntry `json:"tiers,omitempty"`
+}
+
+type tierEntry struct {
+	Model        string `json:"model,omitempty"`
+	Provider     string `json:"provider,omitempty"`
+	BaseURL      string `json:"base_url,omitempty"`
+	Thinking     string `json:"thinking,omitempty"`
+	MaxToolCalls int    `json:"max_tool_calls,omitempty"`
+	MaxDuration  string `json:"max_duration,omitempty"`
+}
+
+func newPolicyEntry(p config.RolePolicyEntry) policyEntry {
+	entry := policyEntry{Name: p.Name, DefaultTier: p.DefaultTier}
+	if len(p.Tiers) > 0 {
+		entry.Tiers = make(map[string]tierEntry, len(p.Tiers))
+		for name, t := range p.Tiers {
+			entry.Tiers[name] = tierEntry(t)
+		}
+	}
+	return entry
+}
+
+func (p policyEntry) config() config.RolePolicyEntry {
+	entry := config.RolePolicyEntry{Name: p.Name, DefaultTier: p.DefaultTier}
+	if len(p.Tiers) > 0 {
+		entry.Tiers = make(map[string]config.CostTierEntry, len(p.Tiers))
+		for name, t := range p.Tiers {
+			entry.Tiers[name] = config.CostTierEntry(t)
+		}
+	}
+	return entry
+}
+
+// handleMissionPolicies returns the configured cost tier policies.
+//
+//	GET /api/mission/policies
+func (s *APIServer) handleMissionPolicies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.policy == nil {
+		writeJSONError(w, "Policy editing not available", http.StatusServiceUnavailable)
+		return
+	}
+	policies := s.policy.RolePolicies()
+	result := make([]policyEntry, 0, len(policies))
+	for _, p := range policies {
+		result = append(result, newPolicyEntry(p))
+	}
+	writeJSON(w, result)
+}
+
+// handleMissionPolicyByName reads, replaces or deletes one cost tier policy.
+//
+//	GET    /api/mission/policies/{name}
+//	PUT    /api/mission/policies/{name}  {"default_tier": "cheap", "tiers": {"cheap": {"model": "haiku"}}}
+//	DELETE /api/mission/policies/{name}
+func (s *APIServer) handleMissionPolicyByName(w http.ResponseWriter, r *http.Request) {
+	if s.policy == nil {
+		writeJSONError(w, "Policy editing not available", http.StatusServiceUnavailable)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/api/mission/policies/")
+	if name == "" || strings.Contains(name, "/") {
+		writeJSONError(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		for _, p := range s.policy.RolePolicies() {
+			if p.Name == name {
+				writeJSON(w, newPolicyEntry(p))
+				return
+			}
+		}
+		writeJSONError(w, "policy not found", http.StatusNotFound)
+
+	case http.MethodPut:
+		var req policyEntry
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Name != "" && req.Name != name {
+			writeJSONError(w, "name does not match the URL", http.StatusBadRequest)
+			return
+		}
+		req.Name = name
+		entry := req.config()
+		if err := entry.Validate(); err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		created, err := s.policy.SetRolePolicy(entry)
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !created {
+			writeJSON(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(req) //nolint:errcheck
+
+	case http.MethodDelete:
+		err := s.policy.DeleteRolePolicy(name)
+		if errors.Is(err, config.ErrRolePolicyNotFound) {
+			writeJSONError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]interface{}{
+			"success": true,
+			"message": "Policy deleted",
+		})
+
+	default:
+		writeJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
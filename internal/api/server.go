@@ -10,6 +10,7 @@ import (
 
 	"ok-gobot/internal/bot"
 	"ok-gobot/internal/config"
+	"ok-gobot/internal/role"
 	"ok-gobot/internal/runtime"
 	"ok-gobot/internal/storage"
 )
@@ -22,14 +23,48 @@ type DataProvider interface {
 	GetJobEvents(jobID string, limit int) ([]storage.JobEvent, error)
 	GetJobArtifacts(jobID string, limit int) ([]storage.JobArtifact, error)
 	CancelJob(jobID string) error
+	RetryJob(jobID string, resume bool) (*storage.Job, error)
 	WorkerSnapshots() []runtime.WorkerSnapshot
 }
 
+// RoleStarter plans and starts role runs for the mission control API.
+type RoleStarter interface {
+	PlanRole(name, task string, params map[string]any) (*role.Plan, error)
+	RunRole(name, task string, params map[string]any, chatID int64) (string, error)
+}
+
+// RoleEditor reads and writes role manifests for the mission control API.
+// Saved and deleted roles take effect without a restart.
+type RoleEditor interface {
+	Roles() ([]*role.Manifest, []error)
+	RoleSource(name string) (string, error)
+	SaveRole(name, content string) (*role.Manifest, bool, error)
+	DeleteRole(name string) error
+}
+
+// ScheduleController enables, disables and fires cron schedules.
+type ScheduleController interface {
+	ToggleJob(jobID int64, enabled bool) error
+	RunNow(jobID int64) error
+}
+
+// PolicyEditor reads and writes the cost tier policies of runtime.roles.
+type PolicyEditor interface {
+	RolePolicies() []config.RolePolicyEntry
+	SetRolePolicy(entry config.RolePolicyEntry) (bool, error)
+	DeleteRolePolicy(name string) error
+}
+
 // APIServer handles HTTP API requests
 type APIServer struct {
 	config config.APIConfig
 	bot    *bot.Bot
 	data   DataProvider
+	roles  RoleStarter
+	editor RoleEditor
+	cron   ScheduleController
+	policy PolicyEditor
+	hooks  http.Handler
 	server *http.Server
 	uptime time.Time
 }
@@ -48,6 +83,34 @@ func (s *APIServer) SetDataProvider(dp DataProvider) {
 	s.data = dp
 }
 
+// SetRoleStarter enables running roles through the mission control API.
+func (s *APIServer) SetRoleStarter(rs RoleStarter) {
+	s.roles = rs
+}
+
+// SetRoleEditor enables editing role manifests through the mission control API.
+func (s *APIServer) SetRoleEditor(re RoleEditor) {
+	s.editor = re
+}
+
+// SetScheduleController enables toggling and firing schedules through the
+// mission control API.
+func (s *APIServer) SetScheduleController(sc ScheduleController) {
+	s.cron = sc
+}
+
+// SetPolicyEditor enables editing cost tier policies through the mission
+// control API.
+func (s *APIServer) SetPolicyEditor(pe PolicyEditor) {
+	s.policy = pe
+}
+
+// SetWebhookHandler serves role webhook triggers under /hooks/. Those
+// requests skip the API key check; the handler verifies their signatures.
+func (s *APIServer) SetWebhookHandler(h http.Handler) {
+	s.hooks = h
+}
+
 // Start initializes and starts the HTTP server
 func (s *APIServer) Start(ctx context.Context) error {
 	mux := http.NewServeMux()
@@ -64,9 +127,19 @@ func (s *APIServer) Start(ctx context.Context) error {
 
 	// Mission control routes
 	mux.HandleFunc("/api/mission/roles", s.handleMissionRoles)
+	mux.HandleFunc("/api/mission/roles/", s.handleMissionRoleByName)
 	mux.HandleFunc("/api/mission/schedules", s.handleMissionSchedules)
+	mux.HandleFunc("/api/mission/schedules/", s.handleMissionScheduleByID)
 	mux.HandleFunc("/api/mission/runs", s.handleMissionRuns)
+	mux.HandleFunc("/api/mission/runs/", s.handleMissionRunByID)
 	mux.HandleFunc("/api/mission/stats", s.handleMissionStats)
+	mux.HandleFunc("/api/mission/policies", s.handleMissionPolicies)
+	mux.HandleFunc("/api/mission/policies/", s.handleMissionPolicyByName)
+
+	// Role webhook triggers
+	if s.hooks != nil {
+		mux.Handle(webhookPrefix, s.hooks)
+	}
 
 	// Apply middleware
 	handler := loggingMiddleware(mux)
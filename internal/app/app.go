@@ -6,6 +6,7 @@ import (
 	"log"
 	"strings"
 	"sync"
+	"time"
 
 	"ok-gobot/internal/agent"
 	"ok-gobot/internal/ai"
@@ -20,6 +21,7 @@ import (
 	"ok-gobot/internal/memorymcp"
 	"ok-gobot/internal/runtime"
 	"ok-gobot/internal/storage"
+	"ok-gobot/internal/trigger"
 )
 
 // App orchestrates all components
@@ -32,6 +34,8 @@ type App struct {
 	personality   *agent.Personality
 	memory        *agent.Memory
 	scheduler     *cron.Scheduler
+	jobs          *runtime.JobService
+	triggers      *trigger.Engine
 	memoryManager *memory.MemoryManager
 	memoryMCP     *memorymcp.Server
 	apiServer     *api.APIServer
@@ -70,10 +74,19 @@ func (a *stateAdapter) GetStatusText(sessionID string) string {
 	return a.b.GetStatusText(sessionID)
 }
 
+func (a *stateAdapter) UndoSession(sessionKey string, n int) (string, error) {
+	return a.b.UndoSession(sessionKey, n)
+}
+
+func (a *stateAdapter) CheckpointsReport(sessionKey string) (string, error) {
+	return a.b.CheckpointsReport(sessionKey)
+}
+
 // dataProvider implements api.DataProvider by bridging storage and the runtime hub.
 type dataProvider struct {
 	store *storage.Store
 	bot   *bot.Bot
+	jobs  *runtime.JobService
 }
 
 func (d *dataProvider) ListJobs(status string, limit int) ([]storage.Job, error) {
@@ -96,12 +109,48 @@ func (d *dataProvider) CancelJob(jobID string) error {
 	return d.store.UpdateJobCancelRequested(jobID, true)
 }
 
+func (d *dataProvider) RetryJob(jobID string, resume bool) (*storage.Job, error) {
+	mode := runtime.RetryRestart
+	if resume {
+		mode = runtime.RetryResume
+	}
+	return d.jobs.Retry(context.Background(), jobID, mode)
+}
+
 func (d *dataProvider) WorkerSnapshots() []runtime.WorkerSnapshot {
-	hub := d.bot.SubagentHub()
-	if hub == nil {
-		return nil
+	var snaps []runtime.WorkerSnapshot
+	if hub := d.bot.SubagentHub(); hub != nil {
+		snaps = hub.ListWorkers()
+	}
+	snaps = runtime.AttachBrowsers(snaps, d.bot.BrowserWorkers())
+	if d.jobs != nil {
+		snaps = runtime.AttachJobs(snaps, d.jobs.QueueSnapshot())
 	}
-	return hub.ListWorkers()
+	return snaps
+}
+
+// policyEditor implements api.PolicyEditor over the live configuration,
+// which the config watcher replaces when the file changes.
+type policyEditor struct {
+	a *App
+}
+
+func (p *policyEditor) RolePolicies() []config.RolePolicyEntry {
+	p.a.mu.RLock()
+	defer p.a.mu.RUnlock()
+	return append([]config.RolePolicyEntry(nil), p.a.config.Runtime.Roles...)
+}
+
+func (p *policyEditor) SetRolePolicy(entry config.RolePolicyEntry) (bool, error) {
+	p.a.mu.Lock()
+	defer p.a.mu.Unlock()
+	return p.a.config.SetRolePolicy(entry)
+}
+
+func (p *policyEditor) DeleteRolePolicy(name string) error {
+	p.a.mu.Lock()
+	defer p.a.mu.Unlock()
+	return p.a.config.DeleteRolePolicy(name)
 }
 
 // New creates a new application instance
@@ -211,6 +260,13 @@ func (a *App) Start(ctx context.Context) error {
 
 	// Initialize durable job service for background work
 	jobService := runtime.NewJobService(a.store)
+	jobService.SetNotifier(func(chatID int64, message string) {
+		if a.bot != nil {
+			a.bot.SendMessage(chatID, message) //nolint:errcheck
+		}
+	})
+	jobService.SetSchedulerLimits(schedulerLimits(a.config.Runtime.Scheduler))
+	a.jobs = jobService
 
 	// Initialize cron scheduler
 	a.scheduler = cron.NewScheduler(a.store, func(ctx context.Context, job storage.CronJob) error {
@@ -232,13 +288,6 @@ func (a *App) Start(ctx context.Context) error {
 		}
 	})
 
-	// Start cron scheduler
-	if err := a.scheduler.Start(ctx); err != nil {
-		log.Printf("⚠️ Failed to start cron scheduler: %v", err)
-	} else {
-		log.Println("📅 Cron scheduler started")
-	}
-
 	// Initialize semantic memory manager if enabled
 	if a.config.Memory.Enabled {
 		apiKey := a.config.Memory.EmbeddingsAPIKey
@@ -314,11 +363,44 @@ func (a *App) Start(ctx context.Context) error {
 		ModelAliases:    a.config.ModelAliases,
 		DefaultThinking: a.config.AI.DefaultThinking,
 	}
-	b, err := bot.New(a.config.Telegram.Token, a.store, a.ai, aiCfg, a.personality, agentRegistry, a.config.Auth, a.config.Groups, a.config.TTS, a.config.Browser, a.scheduler, a.memoryManager, a.config.Contacts)
+	b, err := bot.New(a.config.Telegram.Token, a.store, a.ai, aiCfg, a.personality, agentRegistry, a.config.Auth, a.config.Groups, a.config.TTS, a.config.Browser, a.config.Tools, a.scheduler, a.memoryManager, a.config.Contacts)
 	if err != nil {
 		return fmt.Errorf("failed to create bot: %w", err)
 	}
 	a.bot = b
+	b.EnableWorkflows(a.jobs)
+	b.EnableJobQuestions(a.jobs)
+
+	// Start role triggers (webhooks, file watches, feeds, mail, schedules) before
+	// reconciling, so interrupted trigger jobs can be rebuilt
+	if a.personality != nil && a.personality.BasePath != "" {
+		a.triggers = trigger.NewEngine(a.store, a.jobs, a.personality.BasePath, b.RunRole)
+		a.triggers.SetNotifier(func(chatID int64, message string) {
+			a.bot.SendMessage(chatID, message) //nolint:errcheck
+		})
+		if err := a.triggers.Start(ctx); err != nil {
+			log.Printf("⚠️ Failed to start role triggers: %v", err)
+		} else if a.triggers.HasWebhooks() && !a.config.API.Enabled {
+			log.Println("⚠️ Roles define webhook triggers but the API server is disabled; enable api to receive them")
+		}
+	}
+
+	// Settle jobs orphaned by a previous run now that their chats can be notified
+	if recovered, err := a.jobs.Reconcile(ctx); err != nil {
+		log.Printf("⚠️ Failed to reconcile interrupted jobs: %v", err)
+	} else if len(recovered) > 0 {
+		log.Printf("♻️ Reconciled %d interrupted job(s)", len(recovered))
+	}
+	// Start retries queued from the CLI while the bot runs
+	go a.jobs.WatchQueued(ctx, 5*time.Second)
+
+	// Start the cron scheduler only now: its misfire catch-up fires jobs at
+	// once, and they need the bot and a reconciled job table
+	if err := a.scheduler.Start(ctx); err != nil {
+		log.Printf("⚠️ Failed to start cron scheduler: %v", err)
+	} else {
+		log.Println("📅 Cron scheduler started")
+	}
 
 	// Initialize approval system
 	log.Println("🔒 Setting up command approval system...")
@@ -332,7 +414,14 @@ func (a *App) Start(ctx context.Context) error {
 		}
 		log.Printf("🌐 Initializing API server on port %d...", a.config.API.Port)
 		a.apiServer = api.NewAPIServer(a.config.API, a.bot)
-		a.apiServer.SetDataProvider(&dataProvider{store: a.store, bot: a.bot})
+		a.apiServer.SetDataProvider(&dataProvider{store: a.store, bot: a.bot, jobs: a.jobs})
+		a.apiServer.SetScheduleController(a.scheduler)
+		a.apiServer.SetPolicyEditor(&policyEditor{a: a})
+		if a.triggers != nil {
+			a.apiServer.SetWebhookHandler(a.triggers)
+			a.apiServer.SetRoleStarter(a.triggers)
+			a.apiServer.SetRoleEditor(a.triggers)
+		}
 
 		// Start API server in goroutine
 		go func() {
@@ -353,6 +442,7 @@ func (a *App) Start(ctx context.Context) error {
 		adapter := &stateAdapter{b: a.bot}
 		a.controlServer = control.New(ctrlCfg, adapter)
 		a.controlServer.SetStore(a.store)
+		a.controlServer.SetJobService(a.jobs)
 		a.bot.SetControlHub(a.controlServer.Hub())
 		go func() {
 			if err := a.controlServer.Start(ctx); err != nil {
@@ -371,6 +461,24 @@ func (a *App) GetScheduler() *cron.Scheduler {
 	return a.scheduler
 }
 
+// schedulerLimits converts the runtime.scheduler config into job scheduler
+// limits. Config validation has already rejected unknown tiers.
+func schedulerLimits(cfg config.JobSchedulerConfig) runtime.SchedulerLimits {
+	limits := runtime.SchedulerLimits{
+		MaxConcurrent: cfg.MaxConcurrent,
+		PerWorker:     cfg.PerWorker,
+	}
+	if len(cfg.PerTier) > 0 {
+		limits.PerTier = make(map[runtime.CostTier]int, len(cfg.PerTier))
+		for name, limit := range cfg.PerTier {
+			if tier, ok := runtime.ParseCostTier(name); ok {
+				limits.PerTier[tier] = limit
+			}
+		}
+	}
+	return limits
+}
+
 // Stop gracefully shuts down all components
 func (a *App) Stop() error {
 	if a.watcher != nil {
@@ -382,6 +490,9 @@ func (a *App) Stop() error {
 	if a.scheduler != nil {
 		a.scheduler.Stop()
 	}
+	if a.triggers != nil {
+		a.triggers.Stop()
+	}
 	if a.apiServer != nil {
 		ctx := context.Background()
 		if err := a.apiServer.Stop(ctx); err != nil {
@@ -412,6 +523,9 @@ func (a *App) startBootstrapWatcher(name string, personality *agent.Personality)
 			return
 		}
 		log.Printf("system prompt reloaded (%s from %s)", name, personality.BasePath)
+		if personality == a.personality && a.bot != nil {
+			a.bot.ReloadCustomTools()
+		}
 	})
 	if err != nil {
 		log.Printf("[bootstrap] failed to start watcher for %s bootstrap at %s: %v", name, personality.BasePath, err)
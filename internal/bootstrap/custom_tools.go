@@ -0,0 +1,138 @@
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"ok-gobot/internal/tools"
+)
+
+// CustomToolsDir is the workspace directory holding declarative tool definitions.
+const CustomToolsDir = "tools"
+
+// scriptFindingPrefix starts the audit message for script files, which are
+// expected in the tools directory when a definition runs them.
+const scriptFindingPrefix = "script or executable file"
+
+// LoadCustomTools loads tool definitions from tools/*.yaml, *.yml and *.md in
+// the workspace. The directory is audited like a skill first: a definition
+// is skipped when its file or the script it runs has an audit error, and
+// invalid definitions are reported as findings instead of failing the load.
+func LoadCustomTools(basePath string) ([]*tools.CustomTool, []AuditFinding, error) {
+	basePath = ExpandPath(basePath)
+	dir := filepath.Join(basePath, CustomToolsDir)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, nil, nil
+	}
+
+	audit, err := AuditSkill(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read tools directory: %w", err)
+	}
+
+	type candidate struct {
+		file   string
+		def    *tools.CustomToolDefinition
+		script string // audit path of the script, if any
+	}
+	var (
+		candidates []candidate
+		findings   []AuditFinding
+		scripts    = map[string]bool{}
+	)
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".md") {
+			continue
+		}
+		if strings.EqualFold(entry.Name(), "README.md") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			findings = append(findings, AuditFinding{Severity: SeverityError, Path: entry.Name(), Message: err.Error()})
+			continue
+		}
+		def, err := tools.ParseCustomToolDefinition(data, ext == ".md")
+		if err != nil {
+			findings = append(findings, AuditFinding{Severity: SeverityError, Path: entry.Name(), Message: err.Error()})
+			continue
+		}
+		def.Source = path
+
+		c := candidate{file: entry.Name(), def: def}
+		if def.Script != "" {
+			rel, err := scriptAuditPath(dir, def.Script)
+			if err != nil {
+				findings = append(findings, AuditFinding{Severity: SeverityError, Path: entry.Name(), Message: err.Error()})
+				continue
+			}
+			c.script = rel
+			scripts[rel] = true
+		}
+		candidates = append(candidates, c)
+	}
+
+	// Scripts a definition runs are expected (and may be executable);
+	// everything else the audit found still counts.
+	blocked := map[string]bool{}
+	for _, f := range audit {
+		if scripts[f.Path] && (f.Severity == SeverityWarning || strings.HasPrefix(f.Message, scriptFindingPrefix)) {
+			continue
+		}
+		findings = append(findings, f)
+		if f.Severity == SeverityError {
+			blocked[f.Path] = true
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].file < candidates[j].file })
+	seen := map[string]string{}
+	var loaded []*tools.CustomTool
+	for _, c := range candidates {
+		if blocked[c.file] || (c.script != "" && blocked[c.script]) {
+			findings = append(findings, AuditFinding{
+				Severity: SeverityError,
+				Path:     c.file,
+				Message:  fmt.Sprintf("tool %q not loaded: fix the audit errors above", c.def.Name),
+			})
+			continue
+		}
+		if other, dup := seen[c.def.Name]; dup {
+			findings = append(findings, AuditFinding{
+				Severity: SeverityError,
+				Path:     c.file,
+				Message:  fmt.Sprintf("tool %q is already defined in %s", c.def.Name, other),
+			})
+			continue
+		}
+		seen[c.def.Name] = c.file
+		loaded = append(loaded, tools.NewCustomTool(c.def, basePath))
+	}
+	return loaded, findings, nil
+}
+
+// scriptAuditPath returns the script's path relative to the tools directory,
+// refusing scripts outside it so every script the bot runs is audited.
+func scriptAuditPath(dir, script string) (string, error) {
+	if filepath.IsAbs(script) {
+		return "", fmt.Errorf("script %q must be relative to the tools directory", script)
+	}
+	rel := filepath.Clean(script)
+	if rel == "." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) || rel == ".." {
+		return "", fmt.Errorf("script %q escapes the tools directory", script)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, rel)); err != nil {
+		return "", fmt.Errorf("script %q not found", script)
+	}
+	return rel, nil
+}
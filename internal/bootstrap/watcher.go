@@ -55,6 +55,10 @@ func NewWatcher(basePath string, onChange func()) (*Watcher, error) {
 		watcher.Close()
 		return nil, err
 	}
+	if err := bw.watchPath(filepath.Join(basePath, CustomToolsDir)); err != nil && !os.IsNotExist(err) {
+		watcher.Close()
+		return nil, err
+	}
 
 	go bw.watch()
 	return bw, nil
@@ -99,6 +103,7 @@ func (bw *Watcher) watch() {
 
 			if event.Op&fsnotify.Create == fsnotify.Create {
 				bw.watchNewSkillDir(event.Name)
+				bw.watchNewToolsDir(event.Name)
 			}
 
 			if bw.isBootstrapEvent(event.Name) &&
@@ -168,6 +173,23 @@ func (bw *Watcher) watchNewSkillDir(path string) {
 	_ = bw.watchPath(path)
 }
 
+// watchNewToolsDir starts watching a tools/ directory created after
+// startup and reloads, since definitions may have been moved in with it.
+func (bw *Watcher) watchNewToolsDir(path string) {
+	if path != filepath.Join(bw.basePath, CustomToolsDir) {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return
+	}
+
+	if err := bw.watchPath(path); err == nil {
+		bw.debounceReload()
+	}
+}
+
 func (bw *Watcher) watchPath(path string) error {
 	if _, err := os.Stat(path); err != nil {
 		return err
@@ -195,5 +217,10 @@ func (bw *Watcher) isBootstrapEvent(path string) bool {
 		return true
 	}
 
+	// Tool definitions and the scripts they run.
+	if strings.HasPrefix(rel, CustomToolsDir+"/") && !strings.HasPrefix(filepath.Base(rel), ".") {
+		return true
+	}
+
 	return strings.HasPrefix(rel, "skills/") && filepath.Base(rel) == "SKILL.md"
 }
@@ -102,3 +102,33 @@ func TestWatcherIgnoresUntrackedFiles(t *testing.T) {
 		t.Fatalf("expected no reload for untracked file, got %d", got)
 	}
 }
+
+func TestWatcherWatchesToolsDirCreatedLater(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	reloaded := make(chan struct{}, 4)
+	watcher, err := NewWatcher(tmpDir, func() {
+		reloaded <- struct{}{}
+	})
+	if err != nil {
+		t.Fatalf("new watcher: %v", err)
+	}
+	defer watcher.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if err := os.Mkdir(filepath.Join(tmpDir, CustomToolsDir), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	select {
+	case <-reloaded:
+	case <-time.After(4 * time.Second):
+		t.Fatal("timed out waiting for reload after tools/ was created")
+	}
+
+	writeWatcherFile(t, filepath.Join(tmpDir, CustomToolsDir, "ping.yaml"), "name: ping\n")
+	select {
+	case <-reloaded:
+	case <-time.After(4 * time.Second):
+		t.Fatal("timed out waiting for reload after a definition was added")
+	}
+}
@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/telebot.v4"
+
+	"ok-gobot/internal/agent"
+	"ok-gobot/internal/tools"
+)
+
+// maxUploadSize is the largest document saved for run_code (Telegram's bot
+// download limit).
+const maxUploadSize = 20 * 1024 * 1024
+
+var unsafeUploadChars = regexp.MustCompile(`[^A-Za-z0-9._ -]+`)
+
+// deliverToolAttachments wraps a tool event callback so files produced by
+// tools are sent to the chat: images (e.g. run_code charts) as photos, OGG
+// audio (tts) as voice notes, everything else (e.g. browser downloads) as
+// documents.
+func (b *Bot) deliverToolAttachments(chat *telebot.Chat, next func(agent.ToolEvent)) func(agent.ToolEvent) {
+	return func(event agent.ToolEvent) {
+		if event.Type == agent.ToolEventFinished {
+			for _, att := range event.Attachments {
+				var err error
+				switch {
+				case att.IsImage():
+					err = b.SendPhotoToChat(chat.ID, att.Path, att.Caption)
+				case att.IsVoice():
+					err = b.SendVoiceToChat(chat.ID, att.Path, att.Caption)
+				default:
+					err = b.SendDocumentToChat(chat.ID, att.Path, att.Caption)
+				}
+				if err != nil {
+					log.Printf("[bot] failed to send %s attachment %s: %v", event.ToolName, att.Path, err)
+				}
+			}
+		}
+		if next != nil {
+			next(event)
+		}
+	}
+}
+
+// saveUploadForSession downloads a document into the session's run_code
+// scratch directory and returns its path relative to that directory.
+func (b *Bot) saveUploadForSession(sessionKey agent.SessionKey, doc *telebot.Document) (string, error) {
+	if doc.FileSize > maxUploadSize {
+		return "", fmt.Errorf("document is larger than %d MB", maxUploadSize/(1024*1024))
+	}
+	name := strings.TrimSpace(unsafeUploadChars.ReplaceAllString(filepath.Base(doc.FileName), "_"))
+	if name == "" || name == "." || name == ".." {
+		name = "upload"
+	}
+
+	dir, err := b.sessionUploadDir(sessionKey)
+	if err != nil {
+		return "", err
+	}
+
+	reader, err := b.api.File(&doc.File)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	out, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, io.LimitReader(reader, maxUploadSize)); err != nil {
+		out.Close() //nolint:errcheck
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	return filepath.Join("uploads", name), nil
+}
+
+// savePhotoForSession stores a received photo in the session's uploads
+// directory, where image_gen (edits) and run_code can read it, and returns
+// its path relative to the session directory.
+func (b *Bot) savePhotoForSession(sessionKey agent.SessionKey, messageID int, data []byte) (string, error) {
+	dir, err := b.sessionUploadDir(sessionKey)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("photo_%d.jpg", messageID)
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		return "", err
+	}
+	return filepath.Join("uploads", name), nil
+}
+
+func (b *Bot) sessionUploadDir(sessionKey agent.SessionKey) (string, error) {
+	dir := filepath.Join(tools.RunCodeSessionDir(b.scratchDir, string(sessionKey)), "uploads")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
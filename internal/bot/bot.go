@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -52,7 +53,11 @@ type Bot struct {
 	queueManager     *QueueManager
 	scheduler        tools.CronScheduler
 	ackManager       *AckHandleManager
-	controlHub       *control.Hub // optional: emit run/tool/approval events over WebSocket
+	controlHub       *control.Hub             // optional: emit run/tool/approval events over WebSocket
+	checkpoints      *tools.CheckpointManager // optional: file snapshots backing /undo
+	scratchDir       string                   // run_code scratch root; uploads land in the session's directory
+	customTools      *tools.CustomToolSet     // workspace-defined tools from tools/*.yaml
+	jobs             *runtime.JobService      // runs /task sub-agents and answers questions asked by background jobs
 }
 
 // AIConfig holds AI configuration for status display
@@ -67,7 +72,7 @@ type AIConfig struct {
 }
 
 // New creates a new bot instance
-func New(token string, store *storage.Store, aiClient ai.Client, aiCfg AIConfig, personality *agent.Personality, agentRegistry *agent.AgentRegistry, authCfg config.AuthConfig, groupsCfg config.GroupsConfig, ttsCfg config.TTSConfig, browserCfg config.BrowserConfig, scheduler tools.CronScheduler, memoryManager *memory.MemoryManager, contacts map[string]int64) (*Bot, error) {
+func New(token string, store *storage.Store, aiClient ai.Client, aiCfg AIConfig, personality *agent.Personality, agentRegistry *agent.AgentRegistry, authCfg config.AuthConfig, groupsCfg config.GroupsConfig, ttsCfg config.TTSConfig, browserCfg config.BrowserConfig, toolsCfg config.ToolsConfig, scheduler tools.CronScheduler, memoryManager *memory.MemoryManager, contacts map[string]int64) (*Bot, error) {
 	pref := telebot.Settings{
 		Token:  token,
 		Poller: &telebot.LongPoller{Timeout: 10 * time.Second},
@@ -83,16 +88,58 @@ func New(token string, store *storage.Store, aiClient ai.Client, aiCfg AIConfig,
 	// so each job gets the correct chatID. Use personality.BasePath as the workspace
 	// root so that file/path tools resolve relative paths against the configured soul
 	// directory instead of the process working directory.
+	scratchDir := toolsCfg.RunCode.ScratchDir
+	if scratchDir == "" {
+		scratchDir = tools.DefaultScratchDir()
+	}
 	toolsConfig := &tools.ToolsConfig{
 		OpenAIAPIKey:    aiCfg.APIKey,
 		TTSProvider:     ttsCfg.Provider,
 		TTSVoice:        ttsCfg.DefaultVoice,
+		ImageProviders:  imageProviderConfigs(toolsCfg.ImageGen.Providers),
+		ImageProvider:   toolsCfg.ImageGen.Provider,
+		ImageOutputDir:  toolsCfg.ImageGen.OutputDir,
+		TTSVoices:       ttsCfg.Voices,
+		TTSChunkChars:   ttsCfg.MaxChunkChars,
+		TTSPiper:        tools.PiperConfig{Binary: ttsCfg.Piper.Binary, ModelsDir: ttsCfg.Piper.ModelsDir},
 		ChromePath:      browserCfg.ChromePath,
 		BrowserProfile:  browserCfg.ProfilePath,
 		BrowserDebugURL: browserCfg.DebugURL,
-		MemoryManager:   memoryManager,
-		PatternStore:    store,
-		EmergencyStop:   store,
+		BrowserContexts: browserCfg.MaxContexts,
+		BrowserShared:   browserCfg.IsolateSubagents != nil && !*browserCfg.IsolateSubagents,
+		HTTPProfiles:    httpCredentialProfiles(toolsCfg.HTTP.Profiles),
+		HTTPMaxBytes:    toolsCfg.HTTP.MaxResponseBytes,
+		RunCode: tools.RunCodeConfig{
+			ScratchDir: scratchDir,
+			Timeout:    time.Duration(toolsCfg.RunCode.TimeoutSeconds) * time.Second,
+			MemoryMB:   toolsCfg.RunCode.MemoryMB,
+			Python:     toolsCfg.RunCode.Python,
+			Node:       toolsCfg.RunCode.Node,
+			Go:         toolsCfg.RunCode.Go,
+			Unattended: toolsCfg.RunCode.Unattended,
+		},
+		Obsidian: tools.ObsidianConfig{
+			VaultPath:     toolsCfg.Obsidian.VaultPath,
+			DailyFolder:   toolsCfg.Obsidian.DailyFolder,
+			DailyFormat:   toolsCfg.Obsidian.DailyFormat,
+			DailyTemplate: toolsCfg.Obsidian.DailyTemplate,
+		},
+		ObsidianIndexMemory: toolsCfg.Obsidian.IndexMemory,
+		SearchProviders:     searchProviderConfigs(toolsCfg.Search.Providers),
+		SearchCacheTTL:      time.Duration(toolsCfg.Search.CacheTTLMinutes) * time.Minute,
+		MemoryManager:       memoryManager,
+		PatternStore:        store,
+		EmergencyStop:       store,
+	}
+	if store != nil {
+		toolsConfig.SearchCache = store
+		toolsConfig.WebFetchCache = store
+		if _, err := store.PruneSearchCache(); err != nil {
+			log.Printf("[bot] warning: search cache pruning failed: %v", err)
+		}
+		if _, err := store.PruneWebCache(tools.DefaultWebCacheMaxAge); err != nil {
+			log.Printf("[bot] warning: web cache pruning failed: %v", err)
+		}
 	}
 	toolRegistry, _ := tools.LoadFromConfigWithOptions(personality.BasePath, toolsConfig)
 
@@ -134,6 +181,7 @@ func New(token string, store *storage.Store, aiClient ai.Client, aiCfg AIConfig,
 		queueManager:     NewQueueManager(),
 		ackManager:       NewAckHandleManager(),
 		scheduler:        scheduler,
+		scratchDir:       scratchDir,
 	}
 
 	// Register message tool: bot itself is the sender (self-reference is safe post-creation)
@@ -150,6 +198,36 @@ func New(token string, store *storage.Store, aiClient ai.Client, aiCfg AIConfig,
 		toolRegistry.Register(tools.NewCronTool(scheduler, 0))
 	}
 
+	// Snapshot files before file/patch/obsidian writes so runs can be undone.
+	if toolsCfg.Checkpoints.Enabled && store != nil {
+		b.checkpoints = tools.NewCheckpointManager(store, tools.CheckpointPolicy{
+			MaxAge:        time.Duration(toolsCfg.Checkpoints.MaxAgeHours) * time.Hour,
+			MaxTotalBytes: toolsCfg.Checkpoints.MaxTotalBytes,
+			MaxFileBytes:  toolsCfg.Checkpoints.MaxFileBytes,
+		})
+		if _, err := b.checkpoints.Prune(); err != nil {
+			log.Printf("[bot] warning: checkpoint pruning failed: %v", err)
+		}
+	}
+
+	// Load workspace-defined tools; the bootstrap watcher reloads them.
+	b.customTools = tools.NewCustomToolSet()
+	b.ReloadCustomTools()
+
+	// Spool oversized tool results into artifacts instead of the context.
+	var outputSpool *tools.OutputSpool
+	if store != nil && toolsCfg.OutputSpool.ThresholdChars >= 0 {
+		outputSpool = tools.NewOutputSpool(store, toolsCfg.OutputSpool.ThresholdChars)
+		toolRegistry.Register(tools.NewReadArtifactTool(store))
+		maxAge := toolsCfg.OutputSpool.MaxAgeHours
+		if maxAge == 0 {
+			maxAge = 168
+		}
+		if _, err := store.PruneJobArtifacts(tools.SpoolArtifactType, maxAge); err != nil {
+			log.Printf("[bot] warning: spooled output pruning failed: %v", err)
+		}
+	}
+
 	// Build the RunResolver — the RuntimeHub uses this to own agent creation,
 	// tool registry filtering, and AI client lifecycle for every run.
 	resolver := &agent.RunResolver{
@@ -167,6 +245,10 @@ func New(token string, store *storage.Store, aiClient ai.Client, aiCfg AIConfig,
 		},
 		ToolRegistry: toolRegistry,
 		Scheduler:    scheduler,
+		Approver:     b,
+		Checkpoints:  b.checkpoints,
+		OutputSpool:  outputSpool,
+		CustomTools:  b.customTools,
 	}
 	b.hub = agent.NewRuntimeHub(resolver)
 
@@ -182,6 +264,57 @@ func New(token string, store *storage.Store, aiClient ai.Client, aiCfg AIConfig,
 	return b, nil
 }
 
+// httpCredentialProfiles converts configured credential profiles into the
+// form consumed by the http_request tool.
+func httpCredentialProfiles(cfg map[string]config.HTTPCredentialProfileConfig) map[string]tools.HTTPCredentialProfile {
+	if len(cfg) == 0 {
+		return nil
+	}
+	profiles := make(map[string]tools.HTTPCredentialProfile, len(cfg))
+	for name, p := range cfg {
+		profiles[name] = tools.HTTPCredentialProfile{
+			Type:     p.Type,
+			Token:    p.Token,
+			Username: p.Username,
+			Password: p.Password,
+			Header:   p.Header,
+			Value:    p.Value,
+			Hosts:    p.Hosts,
+		}
+	}
+	return profiles
+}
+
+// searchProviderConfigs converts the configured search chain into the form
+// consumed by the search tool.
+func searchProviderConfigs(cfg []config.SearchProviderConfig) []tools.SearchProviderConfig {
+	providers := make([]tools.SearchProviderConfig, 0, len(cfg))
+	for _, p := range cfg {
+		providers = append(providers, tools.SearchProviderConfig{
+			Type:    p.Type,
+			BaseURL: p.BaseURL,
+			APIKey:  p.APIKey,
+		})
+	}
+	return providers
+}
+
+func imageProviderConfigs(cfg []config.ImageProviderConfig) []tools.ImageProviderConfig {
+	providers := make([]tools.ImageProviderConfig, 0, len(cfg))
+	for _, p := range cfg {
+		providers = append(providers, tools.ImageProviderConfig{
+			Name:           p.Name,
+			Type:           p.Type,
+			BaseURL:        p.BaseURL,
+			APIKey:         p.APIKey,
+			Model:          p.Model,
+			Steps:          p.Steps,
+			NegativePrompt: p.NegativePrompt,
+		})
+	}
+	return providers
+}
+
 // SendToChat implements tools.MessageSender, allowing the message tool to send
 // Telegram messages through the live bot instance.
 func (b *Bot) SendToChat(chatID int64, text string) error {
@@ -207,6 +340,28 @@ func (b *Bot) SendPhotoToChat(chatID int64, filePath, caption string) error {
 	return err
 }
 
+// SendDocumentToChat sends a file to a Telegram chat as a document.
+func (b *Bot) SendDocumentToChat(chatID int64, filePath, caption string) error {
+	doc := &telebot.Document{
+		File:     telebot.FromDisk(filePath),
+		FileName: filepath.Base(filePath),
+		Caption:  caption,
+	}
+	_, err := b.api.Send(&telebot.Chat{ID: chatID}, doc)
+	return err
+}
+
+// SendVoiceToChat sends an OGG/Opus file to a Telegram chat as a voice note.
+func (b *Bot) SendVoiceToChat(chatID int64, filePath, caption string) error {
+	voice := &telebot.Voice{
+		File:    telebot.FromDisk(filePath),
+		Caption: caption,
+		MIME:    "audio/ogg",
+	}
+	_, err := b.api.Send(&telebot.Chat{ID: chatID}, voice)
+	return err
+}
+
 // EnableStreaming enables or disables streaming mode
 func (b *Bot) EnableStreaming(enable bool) {
 	b.enableStream = enable && b.streamingAI != nil
@@ -235,6 +390,8 @@ func (b *Bot) registerCommands() {
 		{Text: "verbose", Description: "Toggle verbose mode"},
 		{Text: "queue", Description: "Adjust queue settings"},
 		{Text: "tts", Description: "Control text-to-speech"},
+		{Text: "undo", Description: "Undo file changes of the last run"},
+		{Text: "checkpoints", Description: "List undoable file changes"},
 		{Text: "estop", Description: "Emergency stop dangerous tools"},
 		{Text: "task", Description: "Spawn a sub-agent task"},
 		{Text: "activate", Description: "Activate bot in group"},
@@ -294,6 +451,11 @@ func (b *Bot) Start(ctx context.Context) error {
 /note <text> - Quick note to today's memory
 /memory - Show today's memory
 /tools - List available tools
+/undo [n] - Undo file changes of the last n runs
+/checkpoints - List undoable file changes
+/answer [id] <text> - Answer a question from a background job
+/cron - List scheduled tasks and reminders
+/timezone [zone] - Show or set this chat's timezone
 /model - Manage AI model (list/set/clear)
 /agent - Manage agents (list/switch)
 /auth - Authorization management (admin only)
@@ -312,6 +474,9 @@ func (b *Bot) Start(ctx context.Context) error {
 		for _, t := range b.toolRegistry.List() {
 			toolsList = append(toolsList, fmt.Sprintf("• %s: %s", t.Name(), t.Description()))
 		}
+		for _, t := range b.customTools.List() {
+			toolsList = append(toolsList, fmt.Sprintf("• %s (workspace): %s", t.Name(), t.Description()))
+		}
 		return c.Send(fmt.Sprintf("🔧 Available Tools:\n\n%s", strings.Join(toolsList, "\n")))
 	}))
 
@@ -409,6 +574,11 @@ func (b *Bot) handleMessage(ctx context.Context, c telebot.Context) error {
 		return c.Send(agent.GetStopPhraseResponse())
 	}
 
+	// A reply to a job's question answers it instead of starting a run.
+	if handled, err := b.answerQuestionReply(c); handled {
+		return err
+	}
+
 	// Check if bot should respond in groups — do this BEFORE persisting to
 	// memory/transcript so standby group traffic is never silently ingested.
 	if !b.groupManager.ShouldRespond(chatID, msg, b.api.Me.Username) {
@@ -819,6 +989,31 @@ func (b *Bot) SubagentHub() *runtime.Hub {
 	return b.subagentHub
 }
 
+// BrowserWorkers returns the isolated browser contexts held by subagent
+// runs, keyed by session.
+func (b *Bot) BrowserWorkers() map[string]runtime.WorkerBrowser {
+	if b.toolRegistry == nil {
+		return nil
+	}
+	t, ok := b.toolRegistry.Get("browser")
+	if !ok {
+		return nil
+	}
+	bt, ok := tools.AsBrowserTool(t)
+	if !ok {
+		return nil
+	}
+	contexts := bt.Contexts()
+	if len(contexts) == 0 {
+		return nil
+	}
+	out := make(map[string]runtime.WorkerBrowser, len(contexts))
+	for _, c := range contexts {
+		out[c.Key] = runtime.WorkerBrowser{ContextID: c.ID, Tabs: c.Tabs, CreatedAt: c.CreatedAt, LastUsed: c.LastUsed}
+	}
+	return out
+}
+
 // handleAuthCommand handles the /auth command (admin only)
 func (b *Bot) handleAuthCommand(c telebot.Context) error {
 	userID := c.Sender().ID
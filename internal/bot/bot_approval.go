@@ -69,6 +69,23 @@ func (b *Bot) setApprovalFuncOnLocalCommand(localCmd *tools.LocalCommand) {
 	}
 }
 
+// RequestToolApproval implements agent.ToolApprover. Unlike the local command
+// hook it always prompts: callers only ask for actions that have side effects.
+func (b *Bot) RequestToolApproval(chatID int64, command string) (bool, error) {
+	if chatID == 0 {
+		return false, fmt.Errorf("approval requires a chat context")
+	}
+
+	resultCh, _ := b.approvalManager.RequestApproval(chatID, command)
+
+	select {
+	case approved := <-resultCh:
+		return approved, nil
+	case <-time.After(65 * time.Second):
+		return false, fmt.Errorf("approval request timed out")
+	}
+}
+
 // chatIDMap stores per-goroutine chat IDs keyed by goroutine-associated chat ID.
 // This replaces the previous racy global variable.
 var (
@@ -2,6 +2,7 @@ package bot
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
@@ -11,6 +12,7 @@ import (
 
 	"ok-gobot/internal/agent"
 	runtimepkg "ok-gobot/internal/runtime"
+	"ok-gobot/internal/storage"
 )
 
 type taskNotificationStyle struct {
@@ -111,17 +113,76 @@ func (b *Bot) deliverRoutingText(c telebot.Context, text string, canReuseAck boo
 	return c.Send(text)
 }
 
+// taskJobKind is the durable job kind of /task sub-agents.
+const taskJobKind = "task"
+
+// taskJobMaxAttempts leaves room for an interrupted /task job to resume from
+// its checkpoint after a restart or a manual retry.
+const taskJobMaxAttempts = 3
+
+// taskJobInput is the stored request of a /task job, from which the job is
+// rebuilt after a restart or a retry.
+type taskJobInput struct {
+	ChatID     int64                      `json:"chat_id"`
+	ChatType   telebot.ChatType           `json:"chat_type"`
+	Request    agent.SubagentSpawnRequest `json:"request"`
+	Background bool                       `json:"background,omitempty"`
+}
+
+// startTaskRun runs a /task sub-agent. With a job service it is admitted as a
+// durable job, so it waits for a slot under the scheduler limits like
+// workflows and role runs; otherwise it starts right away.
 func (b *Bot) startTaskRun(chat *telebot.Chat, chatID int64, req agent.SubagentSpawnRequest, style taskNotificationStyle) {
-	model := req.Model
-	if model != "" {
-		model = b.resolveModelAlias(model)
+	if req.Model != "" {
+		req.Model = b.resolveModelAlias(req.Model)
 	}
-	job := req.Job()
-	if model != "" {
-		job.Model = model
+	run := b.taskRun(chat, chatID, req, style)
+
+	if b.jobs != nil {
+		_, err := b.jobs.StartDetached(context.Background(), taskJobSpec(chat, chatID, req, style), taskJobRunner(run))
+		if err == nil {
+			return
+		}
+		log.Printf("[task] failed to queue sub-agent job for chat=%d, running it now: %v", chatID, err)
+	}
+	go run(context.Background())
+}
+
+// taskJobSpec describes the durable job behind a /task run. Notices and
+// job questions are delivered through the originating chat's session route.
+// A /task the user is waiting on is interactive; a job the router sent to
+// the background queues as an ordinary user job.
+func taskJobSpec(chat *telebot.Chat, chatID int64, req agent.SubagentSpawnRequest, style taskNotificationStyle) runtimepkg.JobSpec {
+	sessionKey := string(sessionKeyForChat(chat))
+	priority := runtimepkg.JobPriorityInteractive
+	if style == backgroundJobNotifications {
+		priority = runtimepkg.JobPriorityUser
 	}
+	return runtimepkg.JobSpec{
+		Kind:               taskJobKind,
+		Worker:             "subagent",
+		SessionKey:         sessionKey,
+		DeliverySessionKey: sessionKey,
+		Priority:           priority,
+		Description:        req.Description,
+		MaxAttempts:        taskJobMaxAttempts,
+		Input: taskJobInput{
+			ChatID:     chatID,
+			ChatType:   chat.Type,
+			Request:    req,
+			Background: style == backgroundJobNotifications,
+		},
+	}
+}
 
-	go func() {
+// taskRun returns the function that runs one /task sub-agent and reports
+// the outcome to chat. A resumed job's context carries the checkpoint the
+// sub-agent continues from.
+func (b *Bot) taskRun(chat *telebot.Chat, chatID int64, req agent.SubagentSpawnRequest, style taskNotificationStyle) func(context.Context) (string, error) {
+	model := req.Model
+	job := req.Job()
+
+	return func(ctx context.Context) (string, error) {
 		log.Printf("[task] spawning sub-agent for chat=%d model=%s thinking=%s desc=%.80s",
 			chatID, model, req.ThinkLevel, req.Description)
 
@@ -132,21 +193,25 @@ func (b *Bot) startTaskRun(chat *telebot.Chat, chatID int64, req agent.SubagentS
 			ChatID:     chatID,
 			Content:    req.Description,
 			Session:    "",
-			Context:    context.Background(),
+			Context:    ctx,
 			Job:        &job,
 			IsSubagent: true,
 		})
 
-		var notifText string
+		var (
+			result    string
+			runErr    error
+			notifText string
+		)
 		for ev := range events {
 			switch ev.Type {
 			case agent.RunEventDone:
-				result := ""
 				if ev.Result != nil {
 					result = ev.Result.Message
 				}
 				notifText = fmt.Sprintf("%s\n\n%s", style.doneHeading, job.CompletionSummary(result))
 			case agent.RunEventError:
+				runErr = ev.Err
 				notifText = fmt.Sprintf("%s\n\n%s", style.failHeading, ev.Err.Error())
 			}
 		}
@@ -156,7 +221,40 @@ func (b *Bot) startTaskRun(chat *telebot.Chat, chatID int64, req agent.SubagentS
 				log.Printf("[task] failed to send completion notification to chat=%d: %v", chatID, err)
 			}
 		}
-	}()
+		return result, runErr
+	}
+}
+
+func taskJobRunner(run func(context.Context) (string, error)) runtimepkg.JobRunner {
+	return func(ctx context.Context, _ *storage.Job, _ *runtimepkg.JobService) (runtimepkg.JobRunResult, error) {
+		result, err := run(ctx)
+		return runtimepkg.JobRunResult{Summary: result}, err
+	}
+}
+
+// rebuildTaskRunner recreates the runner of an interrupted or retried /task
+// job from its stored request.
+func (b *Bot) rebuildTaskRunner(job *storage.Job) (runtimepkg.JobRunner, error) {
+	raw, ok, err := b.jobs.JobInput(job.JobID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("job %s has no stored task request", job.JobID)
+	}
+	var in taskJobInput
+	if err := json.Unmarshal([]byte(raw), &in); err != nil {
+		return nil, fmt.Errorf("job %s: invalid task request: %w", job.JobID, err)
+	}
+	if in.ChatID == 0 {
+		return nil, fmt.Errorf("job %s has no chat to report to", job.JobID)
+	}
+	style := taskCommandNotifications
+	if in.Background {
+		style = backgroundJobNotifications
+	}
+	chat := &telebot.Chat{ID: in.ChatID, Type: in.ChatType}
+	return taskJobRunner(b.taskRun(chat, in.ChatID, in.Request, style)), nil
 }
 
 func abbreviateForAck(input string, maxRunes int) string {
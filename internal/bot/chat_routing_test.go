@@ -1,6 +1,16 @@
 package bot
 
-import "testing"
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/telebot.v4"
+
+	"ok-gobot/internal/agent"
+	runtimepkg "ok-gobot/internal/runtime"
+	"ok-gobot/internal/storage"
+)
 
 func TestAbbreviateForAck(t *testing.T) {
 	tests := []struct {
@@ -37,3 +47,77 @@ func TestAbbreviateForAck(t *testing.T) {
 		})
 	}
 }
+
+func TestRebuildTaskRunnerUsesStoredRequest(t *testing.T) {
+	store, err := storage.New(filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer store.Close() //nolint:errcheck
+
+	jobs := runtimepkg.NewJobService(store)
+	b := &Bot{jobs: jobs}
+	noop := func(context.Context, *storage.Job, *runtimepkg.JobService) (runtimepkg.JobRunResult, error) {
+		return runtimepkg.JobRunResult{}, nil
+	}
+
+	job, err := jobs.StartDetached(context.Background(), runtimepkg.JobSpec{
+		Kind:        taskJobKind,
+		Description: "summarize",
+		Input: taskJobInput{
+			ChatID:   42,
+			ChatType: telebot.ChatPrivate,
+			Request:  agent.SubagentSpawnRequest{Description: "summarize", ThinkLevel: "high"},
+		},
+	}, noop)
+	if err != nil {
+		t.Fatalf("StartDetached() error = %v", err)
+	}
+	if runner, err := b.rebuildTaskRunner(job); err != nil || runner == nil {
+		t.Fatalf("rebuildTaskRunner() = %v, %v", runner, err)
+	}
+
+	bare, err := jobs.StartDetached(context.Background(), runtimepkg.JobSpec{Kind: taskJobKind, Description: "no input"}, noop)
+	if err != nil {
+		t.Fatalf("StartDetached() error = %v", err)
+	}
+	if _, err := b.rebuildTaskRunner(bare); err == nil {
+		t.Fatal("expected an error for a task job without a stored request")
+	}
+}
+
+func TestTaskJobSpecDeliversToOriginatingChat(t *testing.T) {
+	store, err := storage.New(filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer store.Close() //nolint:errcheck
+
+	chat := &telebot.Chat{ID: 42, Type: telebot.ChatPrivate}
+	spec := taskJobSpec(chat, chat.ID, agent.SubagentSpawnRequest{Description: "summarize"}, taskCommandNotifications)
+	if want := string(sessionKeyForChat(chat)); spec.DeliverySessionKey != want {
+		t.Fatalf("DeliverySessionKey = %q, want %q", spec.DeliverySessionKey, want)
+	}
+	if spec.Priority != runtimepkg.JobPriorityInteractive {
+		t.Fatalf("/task Priority = %q, want interactive", spec.Priority)
+	}
+	background := taskJobSpec(chat, chat.ID, agent.SubagentSpawnRequest{Description: "summarize"}, backgroundJobNotifications)
+	if background.Priority != runtimepkg.JobPriorityUser {
+		t.Fatalf("background Priority = %q, want user", background.Priority)
+	}
+
+	if err := store.SaveSessionRoute(storage.SessionRoute{SessionKey: spec.DeliverySessionKey, Channel: "telegram", ChatID: chat.ID}); err != nil {
+		t.Fatalf("SaveSessionRoute: %v", err)
+	}
+	jobs := runtimepkg.NewJobService(store)
+	noop := func(context.Context, *storage.Job, *runtimepkg.JobService) (runtimepkg.JobRunResult, error) {
+		return runtimepkg.JobRunResult{}, nil
+	}
+	job, err := jobs.StartDetached(context.Background(), spec, noop)
+	if err != nil {
+		t.Fatalf("StartDetached() error = %v", err)
+	}
+	if job.DeliverySessionKey != spec.DeliverySessionKey {
+		t.Fatalf("job.DeliverySessionKey = %q, want %q", job.DeliverySessionKey, spec.DeliverySessionKey)
+	}
+}
@@ -0,0 +1,126 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/telebot.v4"
+
+	"ok-gobot/internal/agent"
+	"ok-gobot/internal/tools"
+)
+
+const maxUndoRuns = 20
+
+// handleUndoCommand rolls back file changes made by the last n runs.
+func (b *Bot) handleUndoCommand(c telebot.Context) error {
+	n := 1
+	if arg := strings.TrimSpace(c.Message().Payload); arg != "" {
+		parsed, err := strconv.Atoi(arg)
+		if err != nil || parsed < 1 {
+			return c.Send("❌ Usage: /undo [n] — roll back file changes of the last n runs")
+		}
+		n = parsed
+	}
+	report, err := b.UndoSession(string(sessionKeyForChat(c.Chat())), n)
+	if err != nil {
+		return c.Send("❌ " + err.Error())
+	}
+	return c.Send(report)
+}
+
+// handleCheckpointsCommand lists the runs whose file changes can be undone.
+func (b *Bot) handleCheckpointsCommand(c telebot.Context) error {
+	report, err := b.CheckpointsReport(string(sessionKeyForChat(c.Chat())))
+	if err != nil {
+		return c.Send("❌ " + err.Error())
+	}
+	return c.Send(report)
+}
+
+// UndoSession restores files changed by the last n runs of a session and
+// returns a human-readable report. Shared by Telegram and the TUI.
+func (b *Bot) UndoSession(sessionKey string, n int) (string, error) {
+	if b.checkpoints == nil {
+		return "", fmt.Errorf("file checkpoints are disabled (tools.checkpoints.enabled)")
+	}
+	if b.hub != nil && b.hub.IsActive(agent.SessionKey(sessionKey)) {
+		return "", fmt.Errorf("a run is still active; /stop it before undoing")
+	}
+	if n > maxUndoRuns {
+		n = maxUndoRuns
+	}
+	results, err := b.checkpoints.Undo(sessionKey, n)
+	if err != nil {
+		return "", fmt.Errorf("undo failed: %w", err)
+	}
+	if len(results) == 0 {
+		return "ℹ️ Nothing to undo: no file changes recorded for this session.", nil
+	}
+	return formatUndoResults(results), nil
+}
+
+// CheckpointsReport lists the checkpointed runs of a session, newest first.
+func (b *Bot) CheckpointsReport(sessionKey string) (string, error) {
+	if b.checkpoints == nil {
+		return "", fmt.Errorf("file checkpoints are disabled (tools.checkpoints.enabled)")
+	}
+	runs, err := b.checkpoints.List(sessionKey, 10)
+	if err != nil {
+		return "", fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	if len(runs) == 0 {
+		return "ℹ️ No checkpoints for this session.", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🗂 Checkpoints (newest first):\n")
+	for i, run := range runs {
+		sb.WriteString(fmt.Sprintf("%d. %s — %d file(s), %s via %s\n",
+			i+1, run.CreatedAt, run.Files, formatCheckpointBytes(run.Bytes), run.Tools))
+	}
+	sb.WriteString("\n/undo rolls back #1; /undo n rolls back the first n.")
+	return sb.String(), nil
+}
+
+func formatUndoResults(results []tools.UndoResult) string {
+	var (
+		sb       strings.Builder
+		restored int
+		removed  int
+		failed   []string
+	)
+	for _, r := range results {
+		restored += len(r.Restored)
+		removed += len(r.Removed)
+		failed = append(failed, r.Failed...)
+	}
+	sb.WriteString(fmt.Sprintf("↩️ Undid %d run(s): %d file(s) restored, %d removed", len(results), restored, removed))
+	for _, r := range results {
+		for _, path := range r.Restored {
+			sb.WriteString("\n  restored " + path)
+		}
+		for _, path := range r.Removed {
+			sb.WriteString("\n  removed " + path)
+		}
+	}
+	if len(failed) > 0 {
+		sb.WriteString(fmt.Sprintf("\n⚠️ %d file(s) could not be restored:", len(failed)))
+		for _, f := range failed {
+			sb.WriteString("\n  " + f)
+		}
+	}
+	return sb.String()
+}
+
+func formatCheckpointBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -80,6 +80,22 @@ func (b *Bot) registerExtraHandlers() {
 	b.api.Handle("/task", b.guardUnauthorizedDM(false, func(c telebot.Context) error {
 		return b.handleTaskCommand(c)
 	}))
+
+	b.api.Handle("/undo", b.guardUnauthorizedDM(false, func(c telebot.Context) error {
+		return b.handleUndoCommand(c)
+	}))
+
+	b.api.Handle("/checkpoints", b.guardUnauthorizedDM(false, func(c telebot.Context) error {
+		return b.handleCheckpointsCommand(c)
+	}))
+
+	b.api.Handle("/cron", b.guardUnauthorizedDM(false, func(c telebot.Context) error {
+		return b.handleCronCommand(c)
+	}))
+
+	b.api.Handle("/timezone", b.guardUnauthorizedDM(false, func(c telebot.Context) error {
+		return b.handleTimezoneCommand(c)
+	}))
 }
 
 // handleWhoamiCommand shows sender info
@@ -129,6 +145,8 @@ func (b *Bot) handleCommandsCommand(c telebot.Context) error {
 		{"memory", "Show today's memory"},
 		{"tools", "List available tools"},
 		{"model", "Show or set AI model"},
+		{"cron", "List scheduled tasks and reminders"},
+		{"timezone", "Show or set this chat's timezone"},
 		{"agent", "Manage agents"},
 		{"usage", "Usage footer control (off/tokens/full)"},
 		{"context", "Explain how context is built"},
@@ -137,6 +155,9 @@ func (b *Bot) handleCommandsCommand(c telebot.Context) error {
 		{"verbose", "Toggle verbose mode (on/off)"},
 		{"queue", "Adjust queue settings"},
 		{"tts", "Control text-to-speech"},
+		{"undo", "Undo file changes of the last n runs (/undo [n])"},
+		{"checkpoints", "List undoable file changes"},
+		{"answer", "Answer a question from a background job (/answer [id] <text>)"},
 		{"estop", "Emergency stop for dangerous tools (admin)"},
 		{"task", "Spawn a sub-agent task"},
 		{"activate", "Activate bot in group"},
@@ -486,6 +507,44 @@ func (b *Bot) handleThinkCommand(c telebot.Context) error {
 		&telebot.SendOptions{ParseMode: telebot.ModeMarkdown})
 }
 
+// handleCronCommand lists this chat's schedules with their next runs, or
+// removes/toggles one.
+func (b *Bot) handleCronCommand(c telebot.Context) error {
+	if b.scheduler == nil {
+		return c.Send("❌ Scheduler not configured")
+	}
+	args := strings.Fields(c.Message().Payload)
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch args[0] {
+	case "list", "remove", "delete", "toggle", "help":
+	default:
+		return c.Send("Usage: /cron [list | remove <id> | toggle <id> on|off]\n\nAsk me in plain words to schedule something, e.g. \"remind me tomorrow at 9 to call the bank\".")
+	}
+	out, err := tools.NewCronTool(b.scheduler, c.Chat().ID).Execute(context.Background(), args...)
+	if err != nil {
+		return c.Send("❌ " + err.Error())
+	}
+	return c.Send(out)
+}
+
+// handleTimezoneCommand shows or sets the chat's scheduling timezone
+func (b *Bot) handleTimezoneCommand(c telebot.Context) error {
+	if b.scheduler == nil {
+		return c.Send("❌ Scheduler not configured")
+	}
+	args := strings.TrimSpace(c.Message().Payload)
+	out, err := tools.NewCronTool(b.scheduler, c.Chat().ID).Execute(context.Background(), "timezone", args)
+	if err != nil {
+		return c.Send("❌ " + err.Error() + "\n\nUse an IANA name such as Europe/Berlin or America/New_York.")
+	}
+	if args == "" {
+		out += "\n\nSet with /timezone Europe/Berlin"
+	}
+	return c.Send(out)
+}
+
 // handleVerboseCommand toggles verbose mode
 func (b *Bot) handleVerboseCommand(c telebot.Context) error {
 	chatID := c.Chat().ID
@@ -551,25 +610,35 @@ func (b *Bot) handleQueueCommand(c telebot.Context) error {
 
 // handleTTSCommand controls text-to-speech
 func (b *Bot) handleTTSCommand(c telebot.Context) error {
+	chatID := c.Chat().ID
 	args := strings.TrimSpace(c.Message().Payload)
 
 	if args == "" || args == "help" {
 		return c.Send(`🔊 *TTS Commands:*
 
-/tts on — Enable auto-TTS
-/tts off — Disable auto-TTS
+/tts auto — Answer voice messages with voice
+/tts on — Speak every reply
+/tts off — Text replies only
 /tts status — Show TTS settings`, &telebot.SendOptions{ParseMode: telebot.ModeMarkdown})
 	}
 
 	switch args {
-	case "on":
-		return c.Send("✅ TTS enabled (not yet fully implemented)")
-	case "off":
-		return c.Send("✅ TTS disabled")
+	case ttsModeOn, ttsModeOff, ttsModeAuto:
+		if err := b.store.SetSessionOption(chatID, "tts_mode", args); err != nil {
+			return c.Send("❌ Failed to set TTS mode")
+		}
+		if args != ttsModeOff && b.ttsTool() == nil {
+			return c.Send(fmt.Sprintf("✅ TTS mode: %s\n⚠️ No TTS provider is configured, replies stay text-only.", args))
+		}
+		return c.Send(fmt.Sprintf("✅ TTS mode: %s", args))
 	case "status":
-		return c.Send("🔊 TTS: off (default)")
+		provider := "not configured"
+		if b.ttsTool() != nil {
+			provider = b.ttsTool().DefaultProvider()
+		}
+		return c.Send(fmt.Sprintf("🔊 TTS: %s (provider: %s)", b.ttsMode(chatID), provider))
 	default:
-		return c.Send("❌ Unknown TTS action. Use: on, off, status, help")
+		return c.Send("❌ Unknown TTS action. Use: auto, on, off, status, help")
 	}
 }
 
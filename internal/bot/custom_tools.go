@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"log"
+
+	"ok-gobot/internal/bootstrap"
+	"ok-gobot/internal/tools"
+)
+
+// ReloadCustomTools reloads the workspace tool definitions (tools/*.yaml).
+// It is called at startup and by the bootstrap watcher; runs already in
+// progress keep the tools they started with.
+func (b *Bot) ReloadCustomTools() {
+	if b.customTools == nil || b.personality == nil || b.personality.BasePath == "" {
+		return
+	}
+
+	loaded, findings, err := bootstrap.LoadCustomTools(b.personality.BasePath)
+	if err != nil {
+		log.Printf("[tools] failed to load workspace tools: %v", err)
+		return
+	}
+	for _, f := range findings {
+		log.Printf("[tools] workspace tools audit: %s", f)
+	}
+
+	var usable []*tools.CustomTool
+	for _, t := range loaded {
+		if _, exists := b.toolRegistry.Get(t.Name()); exists {
+			log.Printf("[tools] workspace tool %q skipped: a built-in tool has the same name", t.Name())
+			continue
+		}
+		usable = append(usable, t)
+	}
+	if len(usable) > 0 || len(b.customTools.List()) > 0 {
+		log.Printf("[tools] loaded %d workspace tool(s)", len(usable))
+	}
+	b.customTools.Replace(usable)
+}
@@ -186,6 +186,8 @@ func (b *Bot) processViaHubWithContent(
 		}
 	}
 
+	onToolEvent = b.deliverToolAttachments(delivery.Chat, onToolEvent)
+
 	// Emit session.accepted and run.started to control hub.
 	if b.controlHub != nil {
 		b.controlHub.Emit(control.EvtSessionAccepted, control.SessionInfo{
@@ -367,6 +369,13 @@ func (b *Bot) processViaHubWithContent(
 		}
 	}
 
+	// Speak the reply when the chat's TTS mode asks for it (/tts on, or
+	// /tts auto in answer to a voice message).
+	if shouldSpeakReply(b.ttsMode(chatID), delivery.Message) {
+		spoken, _ := parseReactions(result.Message)
+		b.sendVoiceReply(ctx, delivery, parseReplyTags(spoken).Clean)
+	}
+
 	// Persist to daily memory.
 	memoryEntry := fmt.Sprintf("Assistant (%s): %s", profileName, result.Message)
 	if result.ToolUsed {
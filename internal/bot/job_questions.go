@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"gopkg.in/telebot.v4"
+
+	"ok-gobot/internal/control"
+	"ok-gobot/internal/runtime"
+	"ok-gobot/internal/storage"
+	"ok-gobot/internal/tools"
+)
+
+// answerCallback is the inline-button unique for job question choices.
+// Button data is "<question-id>|<choice number>".
+const answerCallback = "answer"
+
+// EnableJobQuestions registers the ask_user tool and posts the questions
+// jobs ask to their delivery chat and the TUI. It also runs /task sub-agents
+// as durable jobs that resume from their checkpoint after a restart.
+func (b *Bot) EnableJobQuestions(jobs *runtime.JobService) {
+	if jobs == nil {
+		return
+	}
+	b.jobs = jobs
+	jobs.SetQuestionHandler(b.postJobQuestion)
+	jobs.RegisterKind(taskJobKind, runtime.ResumeFromCheckpoint, b.rebuildTaskRunner)
+	b.toolRegistry.Register(tools.NewAskUserTool())
+
+	b.api.Handle("\f"+answerCallback, func(c telebot.Context) error {
+		return b.handleAnswerCallback(c)
+	})
+	b.api.Handle("/answer", b.guardUnauthorizedDM(false, func(c telebot.Context) error {
+		return b.handleAnswerCommand(c)
+	}))
+}
+
+// postJobQuestion sends a question to the job's chat, with one button per
+// choice, and mirrors it to TUI clients.
+func (b *Bot) postJobQuestion(job *storage.Job, q storage.JobQuestion) {
+	if b.controlHub != nil {
+		b.controlHub.Emit(control.EvtQuestionRequest, control.QuestionRequestPayload{
+			QuestionID: q.QuestionID,
+			JobID:      job.JobID,
+			ChatID:     q.ChatID,
+			Question:   q.Question,
+			Choices:    q.Choices,
+			Default:    q.DefaultAnswer,
+		})
+	}
+	if q.ChatID == 0 {
+		log.Printf("[jobs] %s asked %s with no delivery chat; answer with `ok-gobot jobs answer`", job.JobID, q.QuestionID)
+		return
+	}
+
+	opts := &telebot.SendOptions{}
+	if len(q.Choices) > 0 {
+		keyboard := &telebot.ReplyMarkup{}
+		rows := make([]telebot.Row, 0, len(q.Choices))
+		for i, choice := range q.Choices {
+			rows = append(rows, keyboard.Row(keyboard.Data(choice, answerCallback, q.QuestionID, strconv.Itoa(i+1))))
+		}
+		keyboard.Inline(rows...)
+		opts.ReplyMarkup = keyboard
+	}
+
+	msg, err := b.api.Send(&telebot.Chat{ID: q.ChatID}, formatJobQuestion(job, q), opts)
+	if err != nil {
+		log.Printf("[jobs] failed to post question %s for %s: %v", q.QuestionID, job.JobID, err)
+		return
+	}
+	if err := b.store.SetJobQuestionMessage(q.QuestionID, q.ChatID, msg.ID); err != nil {
+		log.Printf("[jobs] failed to record message for question %s: %v", q.QuestionID, err)
+	}
+}
+
+// formatJobQuestion renders a question as plain text; it is model output,
+// so it is not sent as Markdown.
+func formatJobQuestion(job *storage.Job, q storage.JobQuestion) string {
+	label := job.Description
+	if label == "" {
+		label = job.Kind
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "❓ Job %s (%s) asks:\n\n%s\n\n", job.JobID, label, q.Question)
+	if q.DefaultAnswer != "" {
+		fmt.Fprintf(&sb, "If nobody answers in time: %s\n", q.DefaultAnswer)
+	}
+	if len(q.Choices) > 0 {
+		sb.WriteString("Tap a choice, or reply to this message with your own answer.")
+	} else {
+		sb.WriteString("Reply to this message with your answer, or use /answer <text>.")
+	}
+	return sb.String()
+}
+
+func (b *Bot) handleAnswerCallback(c telebot.Context) error {
+	cb := c.Callback()
+	if cb == nil || b.jobs == nil {
+		return nil
+	}
+	// Buttons only ever appear on the question's own message; refuse
+	// callbacks that cannot be tied to that chat.
+	if cb.Message == nil || cb.Message.Chat == nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "This question is no longer open"})
+	}
+	questionID, choice, _ := strings.Cut(cb.Data, "|")
+	q, err := b.jobs.AnswerQuestionInChat(cb.Message.Chat.ID, questionID, choice, "telegram")
+	if err != nil {
+		return c.Respond(&telebot.CallbackResponse{Text: "This question is no longer open"})
+	}
+	_, _ = b.api.Edit(cb.Message, cb.Message.Text+"\n\n✅ Answer: "+q.Answer)
+	return c.Respond()
+}
+
+func (b *Bot) handleAnswerCommand(c telebot.Context) error {
+	if b.jobs == nil {
+		return c.Send("❌ Job questions are not available")
+	}
+	payload := strings.TrimSpace(c.Message().Payload)
+	if payload == "" {
+		return c.Send("❌ Usage: /answer [question-id] <answer>")
+	}
+	q, err := b.jobs.AnswerPending(c.Chat().ID, payload, "telegram")
+	if err != nil {
+		return c.Send("❌ " + err.Error())
+	}
+	return c.Send(fmt.Sprintf("✅ Sent to job %s: %s", q.JobID, q.Answer))
+}
+
+// answerQuestionReply answers a pending question when the message replies
+// to it. handled is false for any other message.
+func (b *Bot) answerQuestionReply(c telebot.Context) (handled bool, err error) {
+	msg := c.Message()
+	if b.jobs == nil || msg == nil || msg.ReplyTo == nil {
+		return false, nil
+	}
+	q, err := b.store.FindJobQuestionByMessage(msg.Chat.ID, msg.ReplyTo.ID)
+	if err != nil || q == nil {
+		return false, nil
+	}
+	if _, err := b.jobs.AnswerQuestion(q.QuestionID, msg.Text, "telegram"); err != nil {
+		return true, c.Send("❌ " + err.Error())
+	}
+	return true, c.Send("✅ Answer sent to job " + q.JobID)
+}
@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/telebot.v4"
 )
@@ -178,10 +179,16 @@ func (m *MediaHandler) downloadFile(telegramPath, localPath string) error {
 
 // transcribeAudio uses whisper CLI to transcribe audio
 func (m *MediaHandler) transcribeAudio(audioPath string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*10) // 10 min timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
+	return transcribeWithWhisper(ctx, audioPath)
+}
 
-	cmd := exec.CommandContext(ctx, "whisper", audioPath, "--model", "base", "--output_format", "txt")
+// transcribeWithWhisper runs the whisper CLI on audioPath and returns the
+// transcript. Whisper writes <name>.txt into --output_dir, which is set to the
+// audio file's directory so the result is found regardless of the cwd.
+func transcribeWithWhisper(ctx context.Context, audioPath string) (string, error) {
+	cmd := exec.CommandContext(ctx, "whisper", audioPath, "--model", "base", "--output_format", "txt", "--output_dir", filepath.Dir(audioPath))
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		log.Printf("Whisper error: %v, output: %s", err, string(output))
@@ -190,6 +197,7 @@ func (m *MediaHandler) transcribeAudio(audioPath string) (string, error) {
 
 	// Read the output file
 	txtPath := strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".txt"
+	defer os.Remove(txtPath)
 	content, err := os.ReadFile(txtPath)
 	if err != nil {
 		return "", err
@@ -190,6 +190,14 @@ func (b *Bot) handlePhotoMessage(ctx context.Context, c telebot.Context) error {
 	// Process as a text message with photo description
 	content := fmt.Sprintf("[Photo attached: %dx%d, %d bytes] %s", photo.Width, photo.Height, len(data), caption)
 
+	// Keep the photo so image_gen can edit it or make variations.
+	sessionKey := sessionKeyForChat(msg.Chat)
+	if rel, err := b.savePhotoForSession(sessionKey, msg.ID, data); err != nil {
+		log.Printf("Failed to save photo: %v", err)
+	} else {
+		content = fmt.Sprintf("[Photo attached: %dx%d, %d bytes, saved as %s for image_gen edits] %s", photo.Width, photo.Height, len(data), rel, caption)
+	}
+
 	logger.Debugf("Bot: processing photo message len=%d caption=%q", len(data), caption)
 
 	// Save and process through normal pipeline
@@ -198,7 +206,6 @@ func (b *Bot) handlePhotoMessage(ctx context.Context, c telebot.Context) error {
 	}
 
 	delivery := newTelegramDelivery(c)
-	sessionKey := sessionKeyForChat(msg.Chat)
 	b.sendImmediateAck(delivery.Chat, msg.ID)
 	b.debouncer.Debounce(chatID, content, func(combined string) {
 		session, err := b.store.GetSession(chatID)
@@ -259,14 +266,35 @@ func (b *Bot) handleVoiceMessage(ctx context.Context, c telebot.Context) error {
 
 	logger.Debugf("Bot: voice from user=%d chat=%d duration=%ds", userID, chatID, voice.Duration)
 
-	// For now, inform the user that voice is received but transcription is pending
-	content := fmt.Sprintf("[Voice message: %ds duration] (transcription not yet implemented)", voice.Duration)
+	transcript, err := b.transcribeVoice(ctx, voice)
+	if err != nil {
+		log.Printf("Voice transcription failed for chat %d: %v", chatID, err)
+		content := fmt.Sprintf("[Voice message: %ds duration] (not transcribed)", voice.Duration)
+		if err := b.store.SaveMessage(chatID, int64(msg.ID), userID, msg.Sender.Username, content); err != nil {
+			log.Printf("Failed to save message: %v", err)
+		}
+		return c.Send("🎤 Voice message received, but it could not be transcribed. Install the whisper CLI to enable transcription.")
+	}
+
+	content := fmt.Sprintf("[Voice message, %ds] %s", voice.Duration, transcript)
 
 	if err := b.store.SaveMessage(chatID, int64(msg.ID), userID, msg.Sender.Username, content); err != nil {
 		log.Printf("Failed to save message: %v", err)
 	}
 
-	return c.Send("🎤 Voice message received. Transcription is not yet implemented.")
+	delivery := newTelegramDelivery(c)
+	sessionKey := sessionKeyForChat(msg.Chat)
+	b.sendImmediateAck(delivery.Chat, msg.ID)
+	b.debouncer.Debounce(chatID, content, func(combined string) {
+		session, err := b.store.GetSession(chatID)
+		if err != nil {
+			log.Printf("Failed to get session: %v", err)
+		}
+		b.runViaHubAsync(ctx, delivery, sessionKey, combined, nil, session,
+			"❌ Sorry, I encountered an error processing your voice message.", "")
+	})
+
+	return nil
 }
 
 // handleStickerMessage processes incoming stickers
@@ -343,12 +371,19 @@ func (b *Bot) handleDocumentMessage(ctx context.Context, c telebot.Context) erro
 
 	content := fmt.Sprintf("[Document: %s, %d bytes] %s", doc.FileName, doc.FileSize, caption)
 
+	// Save the file where run_code snippets can read it.
+	sessionKey := sessionKeyForChat(msg.Chat)
+	if rel, err := b.saveUploadForSession(sessionKey, doc); err != nil {
+		log.Printf("Failed to save document %s: %v", doc.FileName, err)
+	} else {
+		content = fmt.Sprintf("[Document: %s, %d bytes, saved as %s in the run_code working directory] %s", doc.FileName, doc.FileSize, rel, caption)
+	}
+
 	if err := b.store.SaveMessage(chatID, int64(msg.ID), userID, msg.Sender.Username, content); err != nil {
 		log.Printf("Failed to save message: %v", err)
 	}
 
 	delivery := newTelegramDelivery(c)
-	sessionKey := sessionKeyForChat(msg.Chat)
 	b.sendImmediateAck(delivery.Chat, msg.ID)
 	b.debouncer.Debounce(chatID, content, func(combined string) {
 		session, err := b.store.GetSession(chatID)
@@ -1,6 +1,10 @@
 package bot
 
-import "testing"
+import (
+	"testing"
+
+	"gopkg.in/telebot.v4"
+)
 
 func TestBuildVisionImageContent(t *testing.T) {
 	t.Parallel()
@@ -40,3 +44,27 @@ func TestBuildVisionImageContentEmptyData(t *testing.T) {
 		t.Fatalf("expected nil blocks for empty data, got %#v", got)
 	}
 }
+
+func TestShouldSpeakReply(t *testing.T) {
+	t.Parallel()
+
+	voice := &telebot.Message{Voice: &telebot.Voice{}}
+	text := &telebot.Message{Text: "hi"}
+	cases := []struct {
+		mode     string
+		incoming *telebot.Message
+		want     bool
+	}{
+		{ttsModeOff, voice, false},
+		{ttsModeAuto, voice, true},
+		{ttsModeAuto, text, false},
+		{ttsModeAuto, nil, false},
+		{ttsModeOn, text, true},
+		{"", voice, false},
+	}
+	for _, tc := range cases {
+		if got := shouldSpeakReply(tc.mode, tc.incoming); got != tc.want {
+			t.Errorf("shouldSpeakReply(%q, %+v) = %v, want %v", tc.mode, tc.incoming, got, tc.want)
+		}
+	}
+}
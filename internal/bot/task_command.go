@@ -11,6 +11,7 @@ import (
 
 	"ok-gobot/internal/agent"
 	"ok-gobot/internal/delegation"
+	"ok-gobot/internal/storage"
 )
 
 // parseTaskArgs parses the /task command payload into a SubagentSpawnRequest.
@@ -143,6 +144,22 @@ func (b *Bot) handleTaskCommand(c telebot.Context) error {
 	// Capture chat reference for the notification goroutine.
 	chat := c.Chat()
 
+	// /task may be the first message in a chat; the job delivers through
+	// this route, so persist it before the job is created.
+	route := storage.SessionRoute{
+		SessionKey:       string(sessionKeyForChat(chat)),
+		Channel:          "telegram",
+		ChatID:           chatID,
+		ReplyToMessageID: c.Message().ID,
+	}
+	if sender := c.Sender(); sender != nil {
+		route.UserID = sender.ID
+		route.Username = sender.Username
+	}
+	if err := b.store.SaveSessionRoute(route); err != nil {
+		log.Printf("[task] failed to persist session route for %s: %v", route.SessionKey, err)
+	}
+
 	req.Model = model
 	b.startTaskRun(chat, chatID, req, taskCommandNotifications)
 
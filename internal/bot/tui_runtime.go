@@ -6,6 +6,8 @@ import (
 	"log"
 	"time"
 
+	"gopkg.in/telebot.v4"
+
 	"ok-gobot/internal/agent"
 	"ok-gobot/internal/control"
 )
@@ -67,10 +69,11 @@ func (b *Bot) RunCronTask(ctx context.Context, chatID int64, task string) error
 	subKey := agent.SessionKey(fmt.Sprintf("cron:%d:%d", chatID, time.Now().UnixNano()))
 
 	events := b.hub.Submit(agent.RunRequest{
-		SessionKey: subKey,
-		ChatID:     chatID,
-		Content:    task,
-		Context:    ctx,
+		SessionKey:  subKey,
+		ChatID:      chatID,
+		Content:     task,
+		Context:     ctx,
+		OnToolEvent: b.deliverToolAttachments(&telebot.Chat{ID: chatID}, nil),
 	})
 
 	for ev := range events {
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/telebot.v4"
+
+	"ok-gobot/internal/tools"
+)
+
+// TTS reply modes stored in the tts_mode session option.
+const (
+	ttsModeOff  = "off"  // text replies only
+	ttsModeOn   = "on"   // every reply is also spoken
+	ttsModeAuto = "auto" // voice messages are answered with voice
+)
+
+// voiceReplyTimeout bounds speech synthesis for one reply.
+const voiceReplyTimeout = 3 * time.Minute
+
+// ttsMode returns the chat's TTS reply mode, defaulting to off.
+func (b *Bot) ttsMode(chatID int64) string {
+	if b.store == nil {
+		return ttsModeOff
+	}
+	mode, _ := b.store.GetSessionOption(chatID, "tts_mode")
+	switch mode {
+	case ttsModeOn, ttsModeAuto:
+		return mode
+	}
+	return ttsModeOff
+}
+
+// shouldSpeakReply reports whether a reply to incoming should be sent as
+// voice in the given mode.
+func shouldSpeakReply(mode string, incoming *telebot.Message) bool {
+	switch mode {
+	case ttsModeOn:
+		return true
+	case ttsModeAuto:
+		return incoming != nil && (incoming.Voice != nil || incoming.VideoNote != nil)
+	}
+	return false
+}
+
+// ttsTool returns the registered TTS tool, or nil when TTS is not configured.
+func (b *Bot) ttsTool() *tools.TTSTool {
+	if b.toolRegistry == nil {
+		return nil
+	}
+	t, ok := b.toolRegistry.Get("tts")
+	if !ok {
+		return nil
+	}
+	tts, _ := t.(*tools.TTSTool)
+	return tts
+}
+
+// sendVoiceReply speaks a reply and sends it as one or more voice notes after
+// the text. Failures are logged; the text reply has already been delivered.
+func (b *Bot) sendVoiceReply(ctx context.Context, delivery telegramDelivery, reply string) {
+	tts := b.ttsTool()
+	if tts == nil {
+		log.Printf("[bot] voice reply skipped for chat %d: tts is not configured", delivery.Chat.ID)
+		return
+	}
+	text := tools.SpeechText(reply)
+	if text == "" {
+		return
+	}
+
+	b.api.Notify(delivery.Chat, telebot.RecordingAudio) //nolint:errcheck
+	ctx, cancel := context.WithTimeout(ctx, voiceReplyTimeout)
+	defer cancel()
+
+	paths, err := tts.Speak(ctx, text, "")
+	if err != nil {
+		log.Printf("[bot] voice reply failed for chat %d: %v", delivery.Chat.ID, err)
+		return
+	}
+	for _, path := range paths {
+		if err := b.SendVoiceToChat(delivery.Chat.ID, path, ""); err != nil {
+			log.Printf("[bot] failed to send voice note for chat %d: %v", delivery.Chat.ID, err)
+		}
+		os.Remove(path)
+	}
+}
+
+// transcribeVoice downloads a voice message and transcribes it with the
+// whisper CLI. It returns an error when whisper is not installed.
+func (b *Bot) transcribeVoice(ctx context.Context, voice *telebot.Voice) (string, error) {
+	if _, err := exec.LookPath("whisper"); err != nil {
+		return "", fmt.Errorf("whisper CLI not found")
+	}
+	if voice.FileSize > maxMediaSize {
+		return "", fmt.Errorf("voice message is larger than %d MB", maxMediaSize/(1024*1024))
+	}
+
+	dir, err := os.MkdirTemp("", "okgobot-voice-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	reader, err := b.api.File(&voice.File)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	audioPath := filepath.Join(dir, "voice.ogg")
+	out, err := os.Create(audioPath)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, io.LimitReader(reader, maxMediaSize)); err != nil {
+		out.Close() //nolint:errcheck
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+	text, err := transcribeWithWhisper(ctx, audioPath)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("empty transcription")
+	}
+	return text, nil
+}
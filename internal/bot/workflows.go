@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"ok-gobot/internal/agent"
+	"ok-gobot/internal/delegation"
+	"ok-gobot/internal/role"
+	"ok-gobot/internal/runtime"
+	"ok-gobot/internal/tools"
+	"ok-gobot/internal/workflow"
+)
+
+// EnableWorkflows registers the workflow tool, running steps through the
+// hub as child jobs of jobs.
+func (b *Bot) EnableWorkflows(jobs *runtime.JobService) {
+	if jobs == nil || b.personality == nil || b.personality.BasePath == "" {
+		return
+	}
+	engine := workflow.NewEngine(b.store, jobs, b.personality.BasePath, workflow.Executors{
+		Agent:  b.runWorkflowAgent,
+		Role:   b.runWorkflowRole,
+		Worker: workflow.DefaultWorker,
+	})
+	b.toolRegistry.Register(tools.NewWorkflowTool(engine, b.personality.BasePath))
+}
+
+// runWorkflowAgent runs one agent step as an isolated delegated run. It runs
+// for the chat that started the workflow, so the step gets that chat's agent
+// profile, capability policy and approval prompts.
+func (b *Bot) runWorkflowAgent(ctx context.Context, task string, job delegation.Job) (string, error) {
+	sessionKey := "workflow"
+	var chatID int64
+	if jobID, ok := runtime.JobIDFromContext(ctx); ok {
+		sessionKey = "workflow:" + jobID
+		chatID = b.jobChatID(jobID)
+	}
+	events := b.hub.Submit(agent.RunRequest{
+		SessionKey: agent.SessionKey(sessionKey),
+		ChatID:     chatID,
+		Content:    task,
+		Context:    ctx,
+		Job:        &job,
+		IsSubagent: true,
+	})
+
+	var result string
+	for ev := range events {
+		switch ev.Type {
+		case agent.RunEventDone:
+			result = ev.Result.Message
+		case agent.RunEventError:
+			return "", ev.Err
+		}
+	}
+	return result, nil
+}
+
+// jobChatID returns the chat whose session started the job, or 0 when the
+// job did not come from a chat.
+func (b *Bot) jobChatID(jobID string) int64 {
+	job, err := b.store.GetJob(jobID)
+	if err != nil || job == nil {
+		return 0
+	}
+	key := job.DeliverySessionKey
+	if key == "" {
+		key = job.SessionKey
+	}
+	route, err := b.store.GetSessionRoute(key)
+	if err != nil || route == nil {
+		return 0
+	}
+	return route.ChatID
+}
+
+// runWorkflowRole runs a task under a role manifest from roles/<name>.md,
+// with its params at their defaults. The role's tool list narrows the step
+// contract.
+func (b *Bot) runWorkflowRole(ctx context.Context, name, task string, job delegation.Job) (string, error) {
+	manifest, err := role.Load(filepath.Join(b.personality.BasePath, role.Dir), name)
+	if err != nil {
+		return "", fmt.Errorf("role %q: %w", name, err)
+	}
+	allowed := intersectTools(manifest.Tools, job.ToolAllowlist)
+	if len(allowed) == 0 && (len(manifest.Tools) > 0 || len(job.ToolAllowlist) > 0) {
+		return "", fmt.Errorf("role %q allows none of the step's tools", name)
+	}
+	job.ToolAllowlist = allowed
+	return b.runRole(ctx, manifest, task, nil, job)
+}
+
+// RunRole runs task as the role with params bound, limited to the role's
+// tools, and returns its report. Trigger jobs run roles through it.
+func (b *Bot) RunRole(ctx context.Context, manifest *role.Manifest, task string, params map[string]any) (string, error) {
+	return b.runRole(ctx, manifest, task, params, delegation.Job{ToolAllowlist: manifest.Tools})
+}
+
+// runRole prefixes the task with the role's rendered prompt, runs it as an
+// isolated delegated run and formats the output with the role's report
+// template. Roles with declared outputs must answer with that JSON object.
+func (b *Bot) runRole(ctx context.Context, manifest *role.Manifest, task string, params map[string]any, job delegation.Job) (string, error) {
+	plan, err := manifest.Plan(params, task)
+	if err != nil {
+		return "", err
+	}
+	if plan.OutputSchema != "" {
+		job.OutputFormat = delegation.OutputFormatJSON
+		job.OutputSchema = plan.OutputSchema
+	}
+	output, err := b.runWorkflowAgent(ctx, plan.Content(), job)
+	if err != nil {
+		return "", err
+	}
+
+	data := role.ReportData{Title: manifest.Name, Body: output, Params: plan.Params}
+	if manifest.HasOutputs() {
+		if data.Outputs, err = manifest.ParseOutputs(output); err != nil {
+			return "", err
+		}
+	}
+	report, err := manifest.RenderReport(data)
+	if err != nil {
+		return "", err
+	}
+	if report != "" {
+		return report, nil
+	}
+	return output, nil
+}
+
+// intersectTools combines two allowlists; an empty list allows everything.
+func intersectTools(a, b []string) []string {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	allowed := make(map[string]bool, len(b))
+	for _, name := range b {
+		allowed[name] = true
+	}
+	var out []string
+	for _, name := range a {
+		if allowed[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
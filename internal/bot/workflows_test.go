@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"ok-gobot/internal/storage"
+)
+
+func TestJobChatIDFollowsSessionRoute(t *testing.T) {
+	t.Parallel()
+
+	store, err := storage.New(filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatalf("storage.New() error = %v", err)
+	}
+	defer store.Close() //nolint:errcheck
+
+	const sessionKey = "agent:default:telegram:dm:77"
+	if err := store.SaveSessionRoute(storage.SessionRoute{SessionKey: sessionKey, Channel: "telegram", ChatID: 77}); err != nil {
+		t.Fatalf("SaveSessionRoute: %v", err)
+	}
+	for _, job := range []storage.Job{
+		{JobID: "step-chat", Kind: "workflow_step", Status: "running", SessionKey: sessionKey},
+		{JobID: "step-cli", Kind: "workflow_step", Status: "running"},
+	} {
+		if err := store.CreateJob(job); err != nil {
+			t.Fatalf("CreateJob: %v", err)
+		}
+	}
+
+	b := &Bot{store: store}
+	if got := b.jobChatID("step-chat"); got != 77 {
+		t.Errorf("jobChatID(step-chat) = %d, want 77", got)
+	}
+	if got := b.jobChatID("step-cli"); got != 0 {
+		t.Errorf("jobChatID(step-cli) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,271 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+// DefaultMaxContexts caps concurrent isolated contexts when Manager.MaxContexts is zero.
+const DefaultMaxContexts = 4
+
+// ErrContextLimit is returned when no isolated context slot frees up in time.
+var ErrContextLimit = errors.New("too many isolated browser contexts")
+
+// ContextInfo describes an isolated browser context.
+type ContextInfo struct {
+	Key       string    `json:"key"`
+	ID        string    `json:"id"` // DevTools browser context ID
+	Tabs      int       `json:"tabs"`
+	CreatedAt time.Time `json:"created_at"`
+	LastUsed  time.Time `json:"last_used"`
+}
+
+// isolatedContext is an incognito-style browser context: its own cookie jar,
+// storage and tab set inside the openclaw browser process.
+type isolatedContext struct {
+	key       string
+	id        string
+	root      context.Context // first tab; cancelling it disposes the context
+	cancel    context.CancelFunc
+	rootTab   string
+	tabs      map[string]struct{}
+	createdAt time.Time
+	lastUsed  time.Time
+}
+
+// openContextFunc creates a browser context with one tab under browserCtx and
+// returns that tab's context, its target ID and the browser context ID.
+type openContextFunc func(browserCtx context.Context) (ctx context.Context, cancel context.CancelFunc, tabID, contextID string, err error)
+
+// newContextTabFunc opens another tab in the browser context of root.
+type newContextTabFunc func(root context.Context) (ctx context.Context, cancel context.CancelFunc, tabID string, err error)
+
+func defaultOpenContext(browserCtx context.Context) (context.Context, context.CancelFunc, string, string, error) {
+	ctx, cancel := chromedp.NewContext(browserCtx, chromedp.WithNewBrowserContext())
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return nil, nil, "", "", err
+	}
+	c := chromedp.FromContext(ctx)
+	return ctx, cancel, string(c.Target.TargetID), string(c.BrowserContextID), nil
+}
+
+func defaultNewContextTab(root context.Context) (context.Context, context.CancelFunc, string, error) {
+	// A child context without WithTargetID inherits the parent's browser context.
+	ctx, cancel := chromedp.NewContext(root)
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return nil, nil, "", err
+	}
+	return ctx, cancel, string(chromedp.FromContext(ctx).Target.TargetID), nil
+}
+
+func (m *Manager) maxContexts() int {
+	if m.MaxContexts > 0 {
+		return m.MaxContexts
+	}
+	return DefaultMaxContexts
+}
+
+// OpenContext returns the first tab of the isolated context for key, creating
+// the context in the openclaw browser if needed. When MaxContexts contexts
+// are already open it waits for one to close until ctx is done. The context
+// is created without holding m.mu, so a slow browser does not block other
+// tabs and contexts; the limit is checked again before it is registered.
+func (m *Manager) OpenContext(ctx context.Context, key string) (context.Context, error) {
+	if key == "" {
+		return nil, errors.New("isolated context key is required")
+	}
+	for {
+		m.mu.Lock()
+		if root, ok := m.reuseContextLocked(key); ok {
+			m.mu.Unlock()
+			return root, nil
+		}
+		if len(m.contexts) >= m.maxContexts() {
+			freed := m.contextFreed
+			m.mu.Unlock()
+			select {
+			case <-freed:
+				continue
+			case <-ctx.Done():
+				return nil, fmt.Errorf("%w (limit %d): %v", ErrContextLimit, m.maxContexts(), ctx.Err())
+			}
+		}
+		inst, err := m.ensureProfileLocked(ProfileOpenclaw)
+		if err != nil {
+			m.mu.Unlock()
+			return nil, err
+		}
+		browserCtx := inst.browserCtx
+		m.mu.Unlock()
+
+		root, cancel, tabID, contextID, err := m.openContext(browserCtx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create isolated browser context: %w", err)
+		}
+
+		m.mu.Lock()
+		if existing, ok := m.reuseContextLocked(key); ok {
+			// Another call for the same key won the race.
+			m.mu.Unlock()
+			cancel()
+			return existing, nil
+		}
+		if len(m.contexts) >= m.maxContexts() {
+			// The last slot went to another key meanwhile; wait for one again.
+			m.mu.Unlock()
+			cancel()
+			continue
+		}
+		now := time.Now()
+		m.contexts[key] = &isolatedContext{
+			key:       key,
+			id:        contextID,
+			root:      root,
+			cancel:    cancel,
+			rootTab:   tabID,
+			tabs:      map[string]struct{}{tabID: {}},
+			createdAt: now,
+			lastUsed:  now,
+		}
+		m.attachNavigationInvalidation(root)
+		m.mu.Unlock()
+		return root, nil
+	}
+}
+
+// reuseContextLocked returns the live root tab of the context for key,
+// dropping the context if its root tab has gone away. Must hold m.mu.
+func (m *Manager) reuseContextLocked(key string) (context.Context, bool) {
+	ic, ok := m.contexts[key]
+	if !ok {
+		return nil, false
+	}
+	if ic.root.Err() != nil {
+		m.closeContextLocked(key)
+		return nil, false
+	}
+	ic.lastUsed = time.Now()
+	return ic.root, true
+}
+
+// NewContextTab opens another tab in the isolated context for key.
+func (m *Manager) NewContextTab(key string) (context.Context, context.CancelFunc, error) {
+	m.mu.Lock()
+	ic, ok := m.contexts[key]
+	m.mu.Unlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("no isolated browser context for %s", key)
+	}
+	ctx, cancel, tabID, err := m.newContextTab(ic.root)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.mu.Lock()
+	ic.tabs[tabID] = struct{}{}
+	ic.lastUsed = time.Now()
+	m.mu.Unlock()
+	m.attachNavigationInvalidation(ctx)
+	return ctx, cancel, nil
+}
+
+// ContextRootTab returns the target ID of the first tab of the context for
+// key. Closing it would dispose the whole context.
+func (m *Manager) ContextRootTab(key string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ic, ok := m.contexts[key]; ok {
+		return ic.rootTab
+	}
+	return ""
+}
+
+// ContextTabs lists the page targets of the isolated context for key.
+func (m *Manager) ContextTabs(key string) ([]TabInfo, error) {
+	m.mu.Lock()
+	ic, ok := m.contexts[key]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no isolated browser context for %s", key)
+	}
+	targets, err := m.listTargets(ic.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list targets: %w", err)
+	}
+	var tabs []TabInfo
+	for _, t := range targets {
+		if t.Type == "page" && string(t.BrowserContextID) == ic.id {
+			tabs = append(tabs, TabInfo{TargetID: string(t.TargetID), Title: t.Title, URL: t.URL})
+		}
+	}
+	return tabs, nil
+}
+
+// CloseContext disposes the isolated context for key with all its tabs and
+// cookies. It is a no-op when no such context exists.
+func (m *Manager) CloseContext(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeContextLocked(key)
+}
+
+func (m *Manager) closeContextLocked(key string) {
+	ic, ok := m.contexts[key]
+	if !ok {
+		return
+	}
+	delete(m.contexts, key)
+	ic.cancel()
+	for tabID := range ic.tabs {
+		m.invalidateSnapshotForTab(tabID)
+	}
+	close(m.contextFreed)
+	m.contextFreed = make(chan struct{})
+}
+
+func (m *Manager) closeAllContextsLocked() {
+	for key := range m.contexts {
+		m.closeContextLocked(key)
+	}
+}
+
+// Contexts lists the open isolated contexts, oldest first.
+func (m *Manager) Contexts() []ContextInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]ContextInfo, 0, len(m.contexts))
+	for _, ic := range m.contexts {
+		out = append(out, ContextInfo{
+			Key:       ic.key,
+			ID:        ic.id,
+			Tabs:      len(ic.tabs),
+			CreatedAt: ic.createdAt,
+			LastUsed:  ic.lastUsed,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// isolatedContextIDsLocked returns the DevTools IDs of all isolated
+// contexts so ListTabs can hide their tabs from the shared profile.
+func (m *Manager) isolatedContextIDsLocked() map[string]struct{} {
+	ids := make(map[string]struct{}, len(m.contexts))
+	for _, ic := range m.contexts {
+		ids[ic.id] = struct{}{}
+	}
+	return ids
+}
+
+// forgetTabLocked drops a closed tab from the context that owns it.
+func (m *Manager) forgetTabLocked(tabID string) {
+	for _, ic := range m.contexts {
+		delete(ic.tabs, tabID)
+	}
+}
@@ -0,0 +1,201 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// stubContextManager returns a manager whose browser and isolated contexts
+// are faked; opened counts calls to openContext.
+func stubContextManager(t *testing.T, opened *int) *Manager {
+	t.Helper()
+	m := newManager(t.TempDir(), false)
+	t.Cleanup(m.Stop)
+
+	m.launchFn = func(cfg profileConfig, userDataDir string, debugPort int) (*profileInstance, error) {
+		return stubInstance(cfg, userDataDir, debugPort), nil
+	}
+	m.healthFn = func(port int) error { return nil }
+	m.openContext = func(browserCtx context.Context) (context.Context, context.CancelFunc, string, string, error) {
+		*opened++
+		// An unstarted chromedp context is enough for listener registration.
+		ctx, cancel := chromedp.NewContext(context.Background())
+		return ctx, cancel, fmt.Sprintf("tab-%d", *opened), fmt.Sprintf("ctx-%d", *opened), nil
+	}
+	return m
+}
+
+func TestOpenContextReusesContextForKey(t *testing.T) {
+	opened := 0
+	m := stubContextManager(t, &opened)
+
+	first, err := m.OpenContext(context.Background(), "run-a")
+	if err != nil {
+		t.Fatalf("OpenContext failed: %v", err)
+	}
+	second, err := m.OpenContext(context.Background(), "run-a")
+	if err != nil {
+		t.Fatalf("second OpenContext failed: %v", err)
+	}
+	if first != second || opened != 1 {
+		t.Fatalf("expected the context to be reused, opened=%d", opened)
+	}
+	if got := m.ContextRootTab("run-a"); got != "tab-1" {
+		t.Fatalf("ContextRootTab = %q, want tab-1", got)
+	}
+
+	infos := m.Contexts()
+	if len(infos) != 1 || infos[0].Key != "run-a" || infos[0].ID != "ctx-1" || infos[0].Tabs != 1 {
+		t.Fatalf("unexpected contexts: %+v", infos)
+	}
+
+	m.CloseContext("run-a")
+	if first.Err() == nil {
+		t.Fatalf("expected CloseContext to cancel the root tab")
+	}
+	if len(m.Contexts()) != 0 {
+		t.Fatalf("expected no contexts after CloseContext")
+	}
+}
+
+func TestOpenContextWaitsForFreeSlot(t *testing.T) {
+	opened := 0
+	m := stubContextManager(t, &opened)
+	m.MaxContexts = 1
+
+	if _, err := m.OpenContext(context.Background(), "run-a"); err != nil {
+		t.Fatalf("OpenContext failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := m.OpenContext(ctx, "run-b"); !errors.Is(err, ErrContextLimit) {
+		t.Fatalf("expected ErrContextLimit, got %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.OpenContext(context.Background(), "run-b")
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	m.CloseContext("run-a")
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("OpenContext after release failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("OpenContext did not proceed after a slot was freed")
+	}
+	if infos := m.Contexts(); len(infos) != 1 || infos[0].Key != "run-b" {
+		t.Fatalf("unexpected contexts: %+v", infos)
+	}
+}
+
+func TestStopClosesIsolatedContexts(t *testing.T) {
+	opened := 0
+	m := stubContextManager(t, &opened)
+
+	root, err := m.OpenContext(context.Background(), "run-a")
+	if err != nil {
+		t.Fatalf("OpenContext failed: %v", err)
+	}
+	m.Stop()
+	if root.Err() == nil {
+		t.Fatalf("expected Stop to dispose isolated contexts")
+	}
+	if len(m.Contexts()) != 0 {
+		t.Fatalf("expected no contexts after Stop")
+	}
+}
+
+func TestListTabsHidesIsolatedContextTabs(t *testing.T) {
+	opened := 0
+	m := stubContextManager(t, &opened)
+
+	if _, err := m.OpenContext(context.Background(), "run-a"); err != nil {
+		t.Fatalf("OpenContext failed: %v", err)
+	}
+	m.listTargets = func(ctx context.Context) ([]*target.Info, error) {
+		return []*target.Info{
+			{TargetID: "shared", Type: "page", URL: "https://example.com"},
+			{TargetID: "tab-1", Type: "page", URL: "https://isolated.example", BrowserContextID: cdp.BrowserContextID("ctx-1")},
+		}, nil
+	}
+
+	tabs, err := m.ListTabs(ProfileOpenclaw)
+	if err != nil {
+		t.Fatalf("ListTabs failed: %v", err)
+	}
+	if len(tabs) != 1 || tabs[0].TargetID != "shared" {
+		t.Fatalf("expected only the shared tab, got %+v", tabs)
+	}
+
+	tabs, err = m.ContextTabs("run-a")
+	if err != nil {
+		t.Fatalf("ContextTabs failed: %v", err)
+	}
+	if len(tabs) != 1 || tabs[0].TargetID != "tab-1" {
+		t.Fatalf("expected only the isolated tab, got %+v", tabs)
+	}
+}
+
+func TestOpenContextDoesNotHoldLockWhileCreating(t *testing.T) {
+	opened := 0
+	m := stubContextManager(t, &opened)
+	m.MaxContexts = 1
+
+	// Launch the profile before openContext is made to block.
+	if _, err := m.OpenContext(context.Background(), "warmup"); err != nil {
+		t.Fatalf("OpenContext failed: %v", err)
+	}
+	m.CloseContext("warmup")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls int
+	m.openContext = func(browserCtx context.Context) (context.Context, context.CancelFunc, string, string, error) {
+		calls++
+		if calls == 1 {
+			close(started)
+			<-release
+		}
+		ctx, cancel := chromedp.NewContext(context.Background())
+		return ctx, cancel, fmt.Sprintf("slow-tab-%d", calls), fmt.Sprintf("slow-ctx-%d", calls), nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.OpenContext(context.Background(), "run-a")
+		done <- err
+	}()
+	<-started
+
+	listed := make(chan []ContextInfo, 1)
+	go func() { listed <- m.Contexts() }()
+	select {
+	case infos := <-listed:
+		if len(infos) != 0 {
+			t.Errorf("context listed before it was created: %+v", infos)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Contexts blocked while a context was being created")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("OpenContext failed: %v", err)
+	}
+	if infos := m.Contexts(); len(infos) != 1 || infos[0].Key != "run-a" {
+		t.Fatalf("unexpected contexts: %+v", infos)
+	}
+}
@@ -0,0 +1,323 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// downloadStartTimeout is how long a trigger may take to start a download.
+const downloadStartTimeout = 15 * time.Second
+
+// DownloadedFile is a file saved by Download.
+type DownloadedFile struct {
+	Path string
+	URL  string
+	Size int64
+}
+
+// Download runs trigger (a click or navigation) in the tab and waits for the
+// download it starts to finish. The file is saved in dir under the name the
+// server suggested, made unique if a file with that name already exists.
+func (m *Manager) Download(ctx context.Context, dir string, trigger func(context.Context) error) (*DownloadedFile, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+
+	var (
+		mu       sync.Mutex
+		begun    *cdpbrowser.EventDownloadWillBegin
+		started  = make(chan struct{})
+		finished = make(chan *cdpbrowser.EventDownloadProgress, 1)
+	)
+	listenCtx, stopListening := context.WithCancel(ctx)
+	defer stopListening()
+	chromedp.ListenTarget(listenCtx, func(ev interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch e := ev.(type) {
+		case *cdpbrowser.EventDownloadWillBegin:
+			if begun == nil {
+				begun = e
+				close(started)
+			}
+		case *cdpbrowser.EventDownloadProgress:
+			if begun != nil && e.GUID == begun.GUID && e.State != cdpbrowser.DownloadProgressStateInProgress {
+				select {
+				case finished <- e:
+				default:
+				}
+			}
+		}
+	})
+
+	// allowAndName saves the file under its GUID so concurrent downloads
+	// cannot clobber each other; it is renamed once complete.
+	behavior := cdpbrowser.SetDownloadBehavior(cdpbrowser.SetDownloadBehaviorBehaviorAllowAndName).
+		WithDownloadPath(dir).
+		WithEventsEnabled(true)
+	if id := browserContextID(ctx); id != "" {
+		behavior = behavior.WithBrowserContextID(id)
+	}
+	if err := chromedp.Run(ctx, behavior); err != nil {
+		return nil, fmt.Errorf("failed to enable downloads: %w", err)
+	}
+
+	// Navigating to a file URL aborts the navigation (net::ERR_ABORTED) once
+	// the download takes over, so a trigger error only counts when no
+	// download started.
+	triggerErr := trigger(ctx)
+	select {
+	case <-started:
+	case <-time.After(downloadStartTimeout):
+		if triggerErr != nil {
+			return nil, triggerErr
+		}
+		return nil, fmt.Errorf("no download started within %s", downloadStartTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var progress *cdpbrowser.EventDownloadProgress
+	select {
+	case progress = <-finished:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("download did not finish: %w", ctx.Err())
+	}
+	mu.Lock()
+	info := begun
+	mu.Unlock()
+	if progress.State == cdpbrowser.DownloadProgressStateCanceled {
+		return nil, fmt.Errorf("download of %s was canceled", info.URL)
+	}
+
+	src := filepath.Join(dir, info.GUID)
+	if progress.FilePath != "" {
+		src = progress.FilePath
+	}
+	dst := uniquePath(dir, SafeFileName(info.SuggestedFilename, "download"))
+	if err := os.Rename(src, dst); err != nil {
+		return nil, fmt.Errorf("failed to save download: %w", err)
+	}
+	st, err := os.Stat(dst)
+	if err != nil {
+		return nil, err
+	}
+	return &DownloadedFile{Path: dst, URL: info.URL, Size: st.Size()}, nil
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^\p{L}\p{N}._ -]+`)
+
+// SafeFileName strips path separators and unusual characters from name.
+func SafeFileName(name, fallback string) string {
+	name = strings.TrimSpace(unsafeFileNameChars.ReplaceAllString(filepath.Base(name), "_"))
+	name = strings.Trim(name, ". ")
+	if name == "" {
+		return fallback
+	}
+	if len(name) > 120 {
+		ext := filepath.Ext(name)
+		if len(ext) > 10 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:120-len(ext)], "") + ext
+	}
+	return name
+}
+
+// uniquePath returns dir/name, adding " (n)" before the extension when the
+// name is taken.
+func uniquePath(dir, name string) string {
+	path := filepath.Join(dir, name)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+}
+
+// UploadFiles sets the files of a file input matched by a CSS selector.
+func (m *Manager) UploadFiles(ctx context.Context, selector string, paths []string) error {
+	return chromedp.Run(ctx, chromedp.SetUploadFiles(selector, paths, chromedp.ByQuery))
+}
+
+// UploadFilesByRef sets the files of a file input previously returned by Snapshot.
+func (m *Manager) UploadFilesByRef(ctx context.Context, snapshotID, ref string, paths []string) error {
+	nodeID, err := m.resolveNodeID(ctx, snapshotID, ref)
+	if err != nil {
+		return err
+	}
+	return chromedp.Run(ctx, chromedp.SetUploadFiles([]cdp.NodeID{nodeID}, paths, chromedp.ByNodeID))
+}
+
+// PrintToPDF renders the current page as a PDF.
+func (m *Manager) PrintToPDF(ctx context.Context, landscape bool) ([]byte, error) {
+	var buf []byte
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(innerCtx context.Context) error {
+		var err error
+		buf, _, err = page.PrintToPDF().
+			WithLandscape(landscape).
+			WithPrintBackground(true).
+			WithPreferCSSPageSize(true).
+			Do(innerCtx)
+		return err
+	}))
+	return buf, err
+}
+
+// FullScreenshot captures the whole scrollable page as a PNG.
+func (m *Manager) FullScreenshot(buf *[]byte) chromedp.EmulateAction {
+	return chromedp.FullScreenshot(buf, 100)
+}
+
+// ElementScreenshot captures the first element matching selector as a PNG.
+func (m *Manager) ElementScreenshot(selector string, buf *[]byte) chromedp.QueryAction {
+	return chromedp.Screenshot(selector, buf, chromedp.ByQuery)
+}
+
+// browserContextID returns the browser context of the tab in ctx, or "" for
+// the default context.
+func browserContextID(ctx context.Context) cdp.BrowserContextID {
+	if c := chromedp.FromContext(ctx); c != nil {
+		return c.BrowserContextID
+	}
+	return ""
+}
+
+// Cookie is the portable cookie format used for import and export. It uses
+// the DevTools field names, which Playwright's storage state also uses.
+type Cookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path,omitempty"`
+	Expires  float64 `json:"expires,omitempty"` // unix seconds; 0 or negative for session cookies
+	HTTPOnly bool    `json:"httpOnly,omitempty"`
+	Secure   bool    `json:"secure,omitempty"`
+	SameSite string  `json:"sameSite,omitempty"`
+}
+
+// ExportCookies returns the browser's cookies, limited to domain and its
+// subdomains when domain is set.
+func (m *Manager) ExportCookies(ctx context.Context, domain string) ([]Cookie, error) {
+	var raw []*network.Cookie
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(innerCtx context.Context) error {
+		var err error
+		get := storage.GetCookies()
+		if id := browserContextID(ctx); id != "" {
+			get = get.WithBrowserContextID(id)
+		}
+		raw, err = get.Do(innerCtx)
+		return err
+	}))
+	if err != nil {
+		return nil, err
+	}
+	domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+	cookies := make([]Cookie, 0, len(raw))
+	for _, c := range raw {
+		if domain != "" && !cookieDomainMatches(c.Domain, domain) {
+			continue
+		}
+		cookie := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			SameSite: string(c.SameSite),
+		}
+		if !c.Session && c.Expires > 0 {
+			cookie.Expires = c.Expires
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+func cookieDomainMatches(cookieDomain, domain string) bool {
+	cookieDomain = strings.TrimPrefix(strings.ToLower(cookieDomain), ".")
+	return cookieDomain == domain || strings.HasSuffix(cookieDomain, "."+domain)
+}
+
+// ImportCookies adds cookies to the browser. Expired cookies are skipped;
+// the number imported is returned.
+func (m *Manager) ImportCookies(ctx context.Context, cookies []Cookie) (int, error) {
+	now := float64(time.Now().Unix())
+	params := make([]*network.CookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		if c.Name == "" || c.Domain == "" {
+			continue
+		}
+		if c.Expires > 0 && c.Expires < now {
+			continue
+		}
+		p := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+		}
+		if p.Path == "" {
+			p.Path = "/"
+		}
+		switch network.CookieSameSite(c.SameSite) {
+		case network.CookieSameSiteStrict, network.CookieSameSiteLax, network.CookieSameSiteNone:
+			p.SameSite = network.CookieSameSite(c.SameSite)
+		}
+		if c.Expires > 0 {
+			expires := cdp.TimeSinceEpoch(time.Unix(int64(c.Expires), 0))
+			p.Expires = &expires
+		}
+		params = append(params, p)
+	}
+	if len(params) == 0 {
+		return 0, nil
+	}
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(innerCtx context.Context) error {
+		set := storage.SetCookies(params)
+		if id := browserContextID(ctx); id != "" {
+			set = set.WithBrowserContextID(id)
+		}
+		return set.Do(innerCtx)
+	}))
+	if err != nil {
+		return 0, err
+	}
+	return len(params), nil
+}
+
+// ParseCookies reads a cookie file: either a JSON array of cookies or an
+// object with a "cookies" array (Playwright storage state).
+func ParseCookies(data []byte) ([]Cookie, error) {
+	var cookies []Cookie
+	if err := json.Unmarshal(data, &cookies); err == nil {
+		return cookies, nil
+	}
+	var state struct {
+		Cookies []Cookie `json:"cookies"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid cookie file: %w", err)
+	}
+	return state.Cookies, nil
+}
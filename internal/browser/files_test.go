@@ -0,0 +1,70 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSafeFileName(t *testing.T) {
+	cases := map[string]string{
+		"invoice-2024.pdf":    "invoice-2024.pdf",
+		"../../etc/passwd":    "passwd",
+		"report: Q1/Q2?.xlsx": "Q2_.xlsx",
+		"  ..  ":              "fallback",
+		"":                    "fallback",
+		"Счёт №12.pdf":        "Счёт _12.pdf",
+		"a\x00b<c>.txt":       "a_b_c_.txt",
+	}
+	for in, want := range cases {
+		if got := SafeFileName(in, "fallback"); got != want {
+			t.Errorf("SafeFileName(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	long := SafeFileName(strings.Repeat("x", 300)+".pdf", "")
+	if len(long) > 120 || !strings.HasSuffix(long, ".pdf") {
+		t.Errorf("long name not truncated with extension kept: %q (%d)", long, len(long))
+	}
+}
+
+func TestUniquePath(t *testing.T) {
+	dir := t.TempDir()
+	if got := uniquePath(dir, "a.pdf"); got != filepath.Join(dir, "a.pdf") {
+		t.Fatalf("free name changed: %s", got)
+	}
+	for _, name := range []string{"a.pdf", "a (2).pdf"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := uniquePath(dir, "a.pdf"); got != filepath.Join(dir, "a (3).pdf") {
+		t.Fatalf("uniquePath = %s, want a (3).pdf", got)
+	}
+}
+
+func TestParseCookies(t *testing.T) {
+	list, err := ParseCookies([]byte(`[{"name":"sid","value":"1","domain":".example.com","httpOnly":true}]`))
+	if err != nil || len(list) != 1 || list[0].Name != "sid" || !list[0].HTTPOnly {
+		t.Fatalf("array form: %+v, %v", list, err)
+	}
+
+	state, err := ParseCookies([]byte(`{"cookies":[{"name":"a","value":"x","domain":"example.com","expires":1893456000}],"origins":[]}`))
+	if err != nil || len(state) != 1 || state[0].Expires != 1893456000 {
+		t.Fatalf("storage state form: %+v, %v", state, err)
+	}
+
+	if _, err := ParseCookies([]byte(`not json`)); err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+}
+
+func TestCookieDomainMatches(t *testing.T) {
+	if !cookieDomainMatches(".example.com", "example.com") || !cookieDomainMatches("shop.example.com", "example.com") {
+		t.Fatal("expected domain and subdomain to match")
+	}
+	if cookieDomainMatches("notexample.com", "example.com") {
+		t.Fatal("suffix without dot must not match")
+	}
+}
@@ -57,9 +57,12 @@ type Manager struct {
 	ChromePath     string // explicit path to Chrome/Chromium binary; empty = auto-detect
 	RemoteDebugURL string // connect to existing browser instead of launching (e.g. http://127.0.0.1:9222)
 	Headless       bool
+	MaxContexts    int // concurrent isolated contexts; DefaultMaxContexts when zero
 
-	mu        sync.Mutex
-	instances map[string]*profileInstance
+	mu           sync.Mutex
+	instances    map[string]*profileInstance
+	contexts     map[string]*isolatedContext // key -> isolated context
+	contextFreed chan struct{}               // closed and replaced whenever a context closes
 
 	snapshotMu    sync.RWMutex
 	snapshotCache map[string]snapshotCacheEntry
@@ -69,6 +72,9 @@ type Manager struct {
 	resolveNodeIDs nodeIDsResolver
 	clickByNodeID  clickByNodeIDFunc
 	typeByNodeID   typeByNodeIDFunc
+	describeNode   describeNodeFunc
+	openContext    openContextFunc
+	newContextTab  newContextTabFunc
 
 	launchFn       func(cfg profileConfig, userDataDir string, debugPort int) (*profileInstance, error)
 	healthFn       func(port int) error
@@ -98,6 +104,8 @@ func newManager(profilePath string, enableSignals bool) *Manager {
 		UserDataDir:   profilePath,
 		Headless:      false, // Default to visible for user interaction
 		instances:     make(map[string]*profileInstance),
+		contexts:      make(map[string]*isolatedContext),
+		contextFreed:  make(chan struct{}),
 		snapshotCache: make(map[string]snapshotCacheEntry),
 		httpClient: &http.Client{
 			Timeout: healthProbeTimeout,
@@ -112,6 +120,9 @@ func newManager(profilePath string, enableSignals bool) *Manager {
 	m.resolveNodeIDs = pushNodesByBackendIDs
 	m.clickByNodeID = m.defaultClickByNodeID
 	m.typeByNodeID = m.defaultTypeByNodeID
+	m.describeNode = defaultDescribeNode
+	m.openContext = defaultOpenContext
+	m.newContextTab = defaultNewContextTab
 	m.listTargets = m.defaultListTargets
 	m.activateTarget = m.defaultActivateTarget
 	m.closeTarget = m.defaultCloseTarget
@@ -274,6 +285,10 @@ func (m *Manager) stopProfileLocked(profile string) {
 	if !ok {
 		return
 	}
+	if profile == ProfileOpenclaw {
+		// Isolated contexts live inside the openclaw browser.
+		m.closeAllContextsLocked()
+	}
 	m.cleanupInstance(inst)
 	delete(m.instances, profile)
 }
@@ -613,10 +628,12 @@ type TabInfo struct {
 	URL      string `json:"url"`
 }
 
-// ListTabs returns all page-type targets in the given profile.
+// ListTabs returns all page-type targets in the given profile, except tabs
+// of isolated contexts.
 func (m *Manager) ListTabs(profile string) ([]TabInfo, error) {
 	m.mu.Lock()
 	inst, ok := m.instances[profile]
+	isolated := m.isolatedContextIDsLocked()
 	m.mu.Unlock()
 	if !ok {
 		return nil, fmt.Errorf("profile %s is not running", profile)
@@ -632,6 +649,9 @@ func (m *Manager) ListTabs(profile string) ([]TabInfo, error) {
 		if t.Type != "page" {
 			continue
 		}
+		if _, ok := isolated[string(t.BrowserContextID)]; ok {
+			continue
+		}
 		tabs = append(tabs, TabInfo{
 			TargetID: string(t.TargetID),
 			Title:    t.Title,
@@ -662,7 +682,13 @@ func (m *Manager) CloseTab(profile string, targetID string) error {
 		return fmt.Errorf("profile %s is not running", profile)
 	}
 
-	return m.closeTarget(inst.browserCtx, target.ID(targetID))
+	if err := m.closeTarget(inst.browserCtx, target.ID(targetID)); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.forgetTabLocked(targetID)
+	m.mu.Unlock()
+	return nil
 }
 
 // ContextForTarget returns a chromedp context attached to the given target.
@@ -0,0 +1,214 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+// ElementInfo describes a DOM element in terms that survive a page reload:
+// a CSS selector built from stable attributes rather than a snapshot ref.
+type ElementInfo struct {
+	Selector string `json:"selector"`
+	Text     string `json:"text,omitempty"`  // visible text, empty for form fields
+	Field    string `json:"field,omitempty"` // name/id/placeholder/aria-label of a form field
+	Secret   bool   `json:"secret,omitempty"`
+}
+
+type describeNodeFunc func(ctx context.Context, nodeID cdp.NodeID) (ElementInfo, error)
+
+// describeElementJS runs with `this` bound to the element. Selectors are
+// tried from most to least stable and the first unique one wins; a
+// tag:nth-of-type path anchored at the nearest stable id is the last resort.
+const describeElementJS = `function() {
+	const el = this.nodeType === 1 ? this : this.parentElement;
+	if (!el) return {};
+	const esc = (v) => (window.CSS && CSS.escape) ? CSS.escape(v) : String(v).replace(/["\\]/g, '\\$&');
+	const unique = (sel) => { try { return document.querySelectorAll(sel).length === 1; } catch (e) { return false; } };
+	const stableID = (id) => id && !/\d{4,}|[:.]/.test(id);
+	const tag = el.tagName.toLowerCase();
+	const name = el.getAttribute('name');
+	const aria = el.getAttribute('aria-label');
+	const placeholder = el.getAttribute('placeholder');
+	const candidates = [];
+	for (const attr of ['data-testid', 'data-test', 'data-qa', 'data-cy']) {
+		const v = el.getAttribute(attr);
+		if (v) candidates.push('[' + attr + '="' + esc(v) + '"]');
+	}
+	if (stableID(el.id)) candidates.push('#' + esc(el.id));
+	if (name) candidates.push(tag + '[name="' + esc(name) + '"]');
+	if (aria) candidates.push(tag + '[aria-label="' + esc(aria) + '"]');
+	if (placeholder) candidates.push(tag + '[placeholder="' + esc(placeholder) + '"]');
+	const href = el.getAttribute('href');
+	if (tag === 'a' && href && !href.startsWith('javascript:')) candidates.push('a[href="' + esc(href) + '"]');
+	let selector = candidates.find(unique) || '';
+	if (!selector) {
+		const parts = [];
+		let node = el;
+		while (node && node.nodeType === 1 && node !== document.documentElement) {
+			if (stableID(node.id)) { parts.unshift('#' + esc(node.id)); break; }
+			let part = node.tagName.toLowerCase();
+			const parent = node.parentElement;
+			if (parent) {
+				const same = Array.from(parent.children).filter((c) => c.tagName === node.tagName);
+				if (same.length > 1) part += ':nth-of-type(' + (same.indexOf(node) + 1) + ')';
+			}
+			parts.unshift(part);
+			node = parent;
+		}
+		selector = parts.join(' > ');
+	}
+	const isField = tag === 'input' || tag === 'textarea' || tag === 'select' || el.isContentEditable;
+	const hint = [el.getAttribute('type'), name, el.id, el.getAttribute('autocomplete')].join(' ').toLowerCase();
+	const secret = el.type === 'password' || /pass|otp|one-time-code|cvv|cvc|token|secret|\bpin\b/.test(hint);
+	const text = isField ? '' : (el.innerText || aria || '').trim().replace(/\s+/g, ' ').slice(0, 80);
+	return {selector: selector, text: text, field: isField ? (name || el.id || placeholder || aria || '') : '', secret: secret};
+}`
+
+// DescribeRef returns a replayable description of a node previously returned
+// by Snapshot.
+func (m *Manager) DescribeRef(ctx context.Context, snapshotID, ref string) (ElementInfo, error) {
+	nodeID, err := m.resolveNodeID(ctx, snapshotID, ref)
+	if err != nil {
+		return ElementInfo{}, err
+	}
+	info, err := m.describeNode(ctx, nodeID)
+	if err != nil {
+		return ElementInfo{}, fmt.Errorf("failed to describe ref %q: %w", ref, err)
+	}
+	return info, nil
+}
+
+// DescribeSelector returns a replayable description of the first element
+// matching a CSS selector.
+func (m *Manager) DescribeSelector(ctx context.Context, selector string) (ElementInfo, error) {
+	var nodeIDs []cdp.NodeID
+	if err := chromedp.Run(ctx, chromedp.NodeIDs(selector, &nodeIDs, chromedp.ByQuery)); err != nil {
+		return ElementInfo{}, err
+	}
+	if len(nodeIDs) == 0 {
+		return ElementInfo{}, fmt.Errorf("no element matches %q", selector)
+	}
+	return m.describeNode(ctx, nodeIDs[0])
+}
+
+func defaultDescribeNode(ctx context.Context, nodeID cdp.NodeID) (ElementInfo, error) {
+	var info ElementInfo
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(innerCtx context.Context) error {
+		obj, err := dom.ResolveNode().WithNodeID(nodeID).Do(innerCtx)
+		if err != nil {
+			return err
+		}
+		res, exc, err := runtime.CallFunctionOn(describeElementJS).
+			WithObjectID(obj.ObjectID).
+			WithReturnByValue(true).
+			Do(innerCtx)
+		if err != nil {
+			return err
+		}
+		if exc != nil {
+			return exc
+		}
+		return json.Unmarshal(res.Value, &info)
+	}))
+	if err == nil && info.Selector == "" {
+		err = fmt.Errorf("could not build a selector for node %d", nodeID)
+	}
+	return info, err
+}
+
+// Recorder accumulates the steps of an agent-driven flow so it can be saved
+// as a Script. It is safe for concurrent use.
+type Recorder struct {
+	mu          sync.Mutex
+	name        string
+	description string
+	started     time.Time
+	steps       []ScriptStep
+	params      []ScriptParam
+}
+
+// NewRecorder starts recording a flow that will be saved under name.
+func NewRecorder(name, description string) (*Recorder, error) {
+	if err := ValidateScriptName(name); err != nil {
+		return nil, err
+	}
+	return &Recorder{name: name, description: description, started: time.Now()}, nil
+}
+
+// Name returns the script name being recorded.
+func (r *Recorder) Name() string {
+	return r.name
+}
+
+// Add appends a step.
+func (r *Recorder) Add(step ScriptStep) {
+	r.mu.Lock()
+	r.steps = append(r.steps, step)
+	r.mu.Unlock()
+}
+
+// Len returns the number of recorded steps.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.steps)
+}
+
+// Param declares a parameter and returns the {{name}} placeholder to store
+// in place of the typed value. Secret parameters keep no default, so the
+// value never reaches disk; others default to the value seen while recording.
+func (r *Recorder) Param(name, value string, secret bool) string {
+	name = ParamName(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, p := range r.params {
+		if p.Name == name {
+			if secret {
+				r.params[i].Secret = true
+				r.params[i].Default = ""
+			}
+			return "{{" + name + "}}"
+		}
+	}
+	p := ScriptParam{Name: name, Secret: secret}
+	if !secret {
+		p.Default = value
+	}
+	r.params = append(r.params, p)
+	return "{{" + name + "}}"
+}
+
+// Script returns the recorded flow.
+func (r *Recorder) Script() *Script {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Script{
+		Name:        r.name,
+		Description: r.description,
+		CreatedAt:   r.started.UTC().Truncate(time.Second),
+		Params:      append([]ScriptParam(nil), r.params...),
+		Steps:       append([]ScriptStep(nil), r.steps...),
+	}
+}
+
+var paramNameCleanRe = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// ParamName turns a form field name such as "user[email]" into a usable
+// parameter name ("user_email").
+func ParamName(field string) string {
+	name := strings.Trim(paramNameCleanRe.ReplaceAllString(strings.ToLower(field), "_"), "_")
+	if name == "" {
+		return "value"
+	}
+	return name
+}
@@ -0,0 +1,283 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	// DefaultStepTimeout bounds each replayed step, including its assertion polling.
+	DefaultStepTimeout = 20 * time.Second
+
+	assertPollInterval = 250 * time.Millisecond
+)
+
+// ScriptDriver performs script steps against a browser tab.
+type ScriptDriver interface {
+	Navigate(ctx context.Context, url string) error
+	Click(ctx context.Context, selector string) error
+	ClickText(ctx context.Context, text string) error
+	Type(ctx context.Context, selector, value string) error
+	WaitVisible(ctx context.Context, selector string) error
+	Text(ctx context.Context, selector string) (string, error)
+	URL(ctx context.Context) (string, error)
+	Screenshot(ctx context.Context) ([]byte, error)
+}
+
+// RunOptions controls a script replay.
+type RunOptions struct {
+	StepTimeout   time.Duration // per step; DefaultStepTimeout when zero
+	ScreenshotDir string        // where screenshot steps and failure captures are written
+
+	// OnStep, if set, is called after each step with its 1-based index and outcome.
+	OnStep func(n int, step ScriptStep, err error)
+}
+
+// RunResult reports how far a replay got.
+type RunResult struct {
+	Script      string
+	Completed   int           // steps that succeeded
+	Total       int           // steps in the script
+	FailedStep  int           // 1-based index of the failing step, 0 on success
+	Err         error         // failure of FailedStep
+	Screenshot  string        // page capture taken when the step failed
+	Screenshots []string      // captures from screenshot steps
+	Duration    time.Duration // wall time of the replay
+}
+
+// OK reports whether every step succeeded.
+func (r *RunResult) OK() bool {
+	return r.FailedStep == 0
+}
+
+// RunScript replays s with the given parameter values. It returns an error
+// only when the run cannot start (missing parameters); step failures stop
+// the run and are reported in the result together with a screenshot.
+func RunScript(ctx context.Context, d ScriptDriver, s *Script, values map[string]string, opts RunOptions) (*RunResult, error) {
+	params, err := s.ResolveParams(values)
+	if err != nil {
+		return nil, err
+	}
+	if opts.StepTimeout <= 0 {
+		opts.StepTimeout = DefaultStepTimeout
+	}
+
+	start := time.Now()
+	result := &RunResult{Script: s.Name, Total: len(s.Steps)}
+	for i, step := range s.Steps {
+		stepCtx, cancel := context.WithTimeout(ctx, opts.StepTimeout)
+		shot, err := runStep(stepCtx, d, step, params)
+		cancel()
+		if err == nil && shot != nil {
+			var path string
+			if path, err = saveScriptScreenshot(opts.ScreenshotDir, s.Name, fmt.Sprintf("step%02d", i+1), shot); err == nil {
+				result.Screenshots = append(result.Screenshots, path)
+			}
+		}
+		if opts.OnStep != nil {
+			opts.OnStep(i+1, step, err)
+		}
+		if err != nil {
+			result.FailedStep = i + 1
+			result.Err = err
+			if ctx.Err() == nil {
+				captureCtx, cancel := context.WithTimeout(ctx, opts.StepTimeout)
+				if buf, shotErr := d.Screenshot(captureCtx); shotErr == nil {
+					result.Screenshot, _ = saveScriptScreenshot(opts.ScreenshotDir, s.Name, "failed", buf)
+				}
+				cancel()
+			}
+			break
+		}
+		result.Completed++
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// runStep executes one step. Screenshot steps return the captured image.
+func runStep(ctx context.Context, d ScriptDriver, step ScriptStep, params map[string]string) ([]byte, error) {
+	switch step.Action {
+	case StepNavigate:
+		return nil, d.Navigate(ctx, Expand(step.URL, params))
+	case StepClick:
+		switch {
+		case step.Selector == "":
+			return nil, d.ClickText(ctx, step.Text)
+		case step.Text == "":
+			return nil, d.Click(ctx, step.Selector)
+		}
+		// Leave half of the step budget for the visible-text fallback.
+		selCtx, cancel := halfContext(ctx)
+		err := d.Click(selCtx, step.Selector)
+		cancel()
+		if err == nil || ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, d.ClickText(ctx, step.Text)
+	case StepType:
+		return nil, d.Type(ctx, step.Selector, Expand(step.Value, params))
+	case StepWait:
+		return nil, d.WaitVisible(ctx, step.Selector)
+	case StepAssertText:
+		want := Expand(step.Value, params)
+		var last string
+		err := poll(ctx, func() (bool, error) {
+			text, err := d.Text(ctx, step.Selector)
+			last = text
+			return strings.Contains(text, want), err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("expected %s to contain %q, got %q: %w", step.Selector, want, truncateText(last, 200), err)
+		}
+		return nil, nil
+	case StepAssertURL:
+		want := Expand(step.URL, params)
+		var last string
+		err := poll(ctx, func() (bool, error) {
+			u, err := d.URL(ctx)
+			last = u
+			return strings.Contains(u, want), err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("expected URL to contain %q, got %q: %w", want, last, err)
+		}
+		return nil, nil
+	case StepScreenshot:
+		return d.Screenshot(ctx)
+	default:
+		return nil, fmt.Errorf("unknown action %q", step.Action)
+	}
+}
+
+func halfContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithTimeout(ctx, time.Until(deadline)/2)
+	}
+	return context.WithCancel(ctx)
+}
+
+// poll calls check until it reports true or ctx expires. Errors from check
+// are retried; the last one is returned on timeout.
+func poll(ctx context.Context, check func() (bool, error)) error {
+	var lastErr error
+	for {
+		ok, err := check()
+		if ok {
+			return nil
+		}
+		lastErr = err
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return lastErr
+			}
+			return ctx.Err()
+		case <-time.After(assertPollInterval):
+		}
+	}
+}
+
+func truncateText(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > n {
+		return strings.ToValidUTF8(s[:n], "") + "…"
+	}
+	return s
+}
+
+func saveScriptScreenshot(dir, script, label string, buf []byte) (string, error) {
+	if dir == "" {
+		homeDir, _ := os.UserHomeDir()
+		dir = filepath.Join(homeDir, ".ok-gobot", "screenshots")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create screenshot directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s_%s.png", script, label, time.Now().Format("20060102_150405")))
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		return "", fmt.Errorf("failed to save screenshot: %w", err)
+	}
+	return path, nil
+}
+
+// chromeDriver drives a chromedp tab context. The contexts passed to its
+// methods must derive from that tab context.
+type chromeDriver struct{}
+
+// NewChromeDriver returns a ScriptDriver backed by chromedp.
+func NewChromeDriver() ScriptDriver {
+	return chromeDriver{}
+}
+
+func (chromeDriver) Navigate(ctx context.Context, url string) error {
+	if err := chromedp.Run(ctx, chromedp.Navigate(url)); err != nil {
+		return fmt.Errorf("failed to navigate: %w", err)
+	}
+	// Best effort: some pages never settle a body before the timeout.
+	_ = chromedp.Run(ctx, chromedp.WaitReady("body"))
+	return nil
+}
+
+func (chromeDriver) Click(ctx context.Context, selector string) error {
+	return chromedp.Run(ctx,
+		chromedp.WaitVisible(selector, chromedp.ByQuery),
+		chromedp.Click(selector, chromedp.ByQuery),
+	)
+}
+
+func (chromeDriver) ClickText(ctx context.Context, text string) error {
+	xpath := fmt.Sprintf(`//*[self::a or self::button or @role="button" or @role="link" or @role="menuitem" or self::label][normalize-space(.)=%s]`, xpathLiteral(text))
+	return chromedp.Run(ctx,
+		chromedp.WaitVisible(xpath, chromedp.BySearch),
+		chromedp.Click(xpath, chromedp.BySearch),
+	)
+}
+
+func (chromeDriver) Type(ctx context.Context, selector, value string) error {
+	return chromedp.Run(ctx,
+		chromedp.WaitVisible(selector, chromedp.ByQuery),
+		chromedp.Clear(selector, chromedp.ByQuery),
+		chromedp.SendKeys(selector, value, chromedp.ByQuery),
+	)
+}
+
+func (chromeDriver) WaitVisible(ctx context.Context, selector string) error {
+	return chromedp.Run(ctx, chromedp.WaitVisible(selector, chromedp.ByQuery))
+}
+
+func (chromeDriver) Text(ctx context.Context, selector string) (string, error) {
+	var text string
+	err := chromedp.Run(ctx, chromedp.Text(selector, &text, chromedp.ByQuery))
+	return text, err
+}
+
+func (chromeDriver) URL(ctx context.Context) (string, error) {
+	var u string
+	err := chromedp.Run(ctx, chromedp.Location(&u))
+	return u, err
+}
+
+func (chromeDriver) Screenshot(ctx context.Context) ([]byte, error) {
+	var buf []byte
+	err := chromedp.Run(ctx, chromedp.CaptureScreenshot(&buf))
+	return buf, err
+}
+
+// xpathLiteral quotes s for use in an XPath 1.0 expression.
+func xpathLiteral(s string) string {
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	parts := strings.Split(s, `"`)
+	return `concat("` + strings.Join(parts, `", '"', "`) + `")`
+}
@@ -0,0 +1,150 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeDriver struct {
+	calls   []string
+	missing map[string]bool // selectors that never appear
+	url     string
+	text    map[string]string
+}
+
+func (d *fakeDriver) Navigate(_ context.Context, url string) error {
+	d.calls = append(d.calls, "navigate "+url)
+	d.url = url
+	return nil
+}
+
+func (d *fakeDriver) Click(ctx context.Context, selector string) error {
+	d.calls = append(d.calls, "click "+selector)
+	if d.missing[selector] {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (d *fakeDriver) ClickText(_ context.Context, text string) error {
+	d.calls = append(d.calls, "click_text "+text)
+	return nil
+}
+
+func (d *fakeDriver) Type(_ context.Context, selector, value string) error {
+	d.calls = append(d.calls, "type "+selector+"="+value)
+	return nil
+}
+
+func (d *fakeDriver) WaitVisible(ctx context.Context, selector string) error {
+	if d.missing[selector] {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (d *fakeDriver) Text(_ context.Context, selector string) (string, error) {
+	if t, ok := d.text[selector]; ok {
+		return t, nil
+	}
+	return "", errors.New("no such element")
+}
+
+func (d *fakeDriver) URL(context.Context) (string, error) { return d.url, nil }
+
+func (d *fakeDriver) Screenshot(context.Context) ([]byte, error) { return []byte("png"), nil }
+
+func TestRunScriptReplaysStepsWithParamsAndFallback(t *testing.T) {
+	d := &fakeDriver{
+		missing: map[string]bool{"#old-login": true},
+		text:    map[string]string{".greeting": "Welcome back, Ann"},
+	}
+	s := &Script{
+		Name:   "login",
+		Params: []ScriptParam{{Name: "user"}, {Name: "password", Secret: true}},
+		Steps: []ScriptStep{
+			{Action: StepNavigate, URL: "https://example.com/login"},
+			{Action: StepType, Selector: "#user", Value: "{{user}}"},
+			{Action: StepType, Selector: "#pass", Value: "{{password}}"},
+			{Action: StepClick, Selector: "#old-login", Text: "Sign in"},
+			{Action: StepAssertText, Selector: ".greeting", Value: "{{user}}"},
+			{Action: StepAssertURL, URL: "example.com"},
+			{Action: StepScreenshot},
+		},
+	}
+
+	var progress []int
+	res, err := RunScript(context.Background(), d, s, map[string]string{"user": "Ann", "password": "pw"}, RunOptions{
+		StepTimeout:   200 * time.Millisecond,
+		ScreenshotDir: t.TempDir(),
+		OnStep:        func(n int, _ ScriptStep, _ error) { progress = append(progress, n) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.OK() || res.Completed != 7 {
+		t.Fatalf("run failed at step %d: %v", res.FailedStep, res.Err)
+	}
+	wantCalls := "navigate https://example.com/login|type #user=Ann|type #pass=pw|click #old-login|click_text Sign in"
+	if got := strings.Join(d.calls, "|"); got != wantCalls {
+		t.Errorf("calls = %s\nwant    %s", got, wantCalls)
+	}
+	if len(progress) != 7 || len(res.Screenshots) != 1 {
+		t.Errorf("progress = %v, screenshots = %v", progress, res.Screenshots)
+	}
+}
+
+func TestRunScriptStopsAtFailedAssertion(t *testing.T) {
+	d := &fakeDriver{text: map[string]string{".status": "Payment declined"}}
+	s := &Script{
+		Name: "checkout",
+		Steps: []ScriptStep{
+			{Action: StepNavigate, URL: "https://example.com/cart"},
+			{Action: StepAssertText, Selector: ".status", Value: "Order confirmed"},
+			{Action: StepClick, Selector: "#next"},
+		},
+	}
+	res, err := RunScript(context.Background(), d, s, nil, RunOptions{StepTimeout: 300 * time.Millisecond, ScreenshotDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.OK() || res.FailedStep != 2 || res.Completed != 1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if !strings.Contains(res.Err.Error(), "Payment declined") {
+		t.Errorf("error should include the actual text: %v", res.Err)
+	}
+	if _, err := os.Stat(res.Screenshot); err != nil {
+		t.Errorf("failure screenshot not written: %v", err)
+	}
+	for _, c := range d.calls {
+		if c == "click #next" {
+			t.Error("steps after the failure were run")
+		}
+	}
+}
+
+func TestRunScriptRequiresParams(t *testing.T) {
+	s := &Script{Name: "x", Params: []ScriptParam{{Name: "token", Secret: true}}, Steps: []ScriptStep{{Action: StepType, Selector: "#t", Value: "{{token}}"}}}
+	if _, err := RunScript(context.Background(), &fakeDriver{}, s, nil, RunOptions{}); err == nil {
+		t.Fatal("expected missing parameter error")
+	}
+}
+
+func TestXPathLiteral(t *testing.T) {
+	for in, want := range map[string]string{
+		`Sign in`:       `"Sign in"`,
+		`Say "hi"`:      `'Say "hi"'`,
+		`It's "quoted"`: `concat("It's ", '"', "quoted", '"', "")`,
+	} {
+		if got := xpathLiteral(in); got != want {
+			t.Errorf("xpathLiteral(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
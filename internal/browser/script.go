@@ -0,0 +1,273 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Script step actions.
+const (
+	StepNavigate   = "navigate"
+	StepClick      = "click"
+	StepType       = "type"
+	StepWait       = "wait"
+	StepAssertText = "assert_text"
+	StepAssertURL  = "assert_url"
+	StepScreenshot = "screenshot"
+)
+
+// Script is a recorded browser flow that can be replayed without the model.
+// Values may reference parameters as {{name}}; secret parameters are never
+// stored in the script and must be supplied at run time.
+type Script struct {
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description,omitempty"`
+	CreatedAt   time.Time     `yaml:"created_at"`
+	Params      []ScriptParam `yaml:"params,omitempty"`
+	Steps       []ScriptStep  `yaml:"steps"`
+}
+
+// ScriptParam declares a value supplied when the script runs.
+type ScriptParam struct {
+	Name    string `yaml:"name"`
+	Secret  bool   `yaml:"secret,omitempty"`
+	Default string `yaml:"default,omitempty"`
+}
+
+// ScriptStep is one replayable action.
+type ScriptStep struct {
+	Action   string `yaml:"action"`
+	URL      string `yaml:"url,omitempty"`      // navigate; substring for assert_url
+	Selector string `yaml:"selector,omitempty"` // CSS selector for click/type/wait/assert_text
+	Text     string `yaml:"text,omitempty"`     // visible text used as a fallback locator for click
+	Value    string `yaml:"value,omitempty"`    // typed value or expected text, may contain {{param}}
+}
+
+var (
+	scriptNameRe  = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+	scriptParamRe = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+)
+
+// DefaultScriptsDir returns the directory recorded scripts are stored in.
+func DefaultScriptsDir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".ok-gobot", "browser-scripts")
+}
+
+// ValidateScriptName checks that name is usable as a script file name.
+func ValidateScriptName(name string) error {
+	if !scriptNameRe.MatchString(name) {
+		return fmt.Errorf("invalid script name %q: use lowercase letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// Validate checks the script's steps and parameter references.
+func (s *Script) Validate() error {
+	if err := ValidateScriptName(s.Name); err != nil {
+		return err
+	}
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("script %q has no steps", s.Name)
+	}
+	declared := make(map[string]bool, len(s.Params))
+	for _, p := range s.Params {
+		declared[p.Name] = true
+	}
+	for i, step := range s.Steps {
+		n := i + 1
+		switch step.Action {
+		case StepNavigate:
+			if step.URL == "" {
+				return fmt.Errorf("step %d: navigate requires url", n)
+			}
+		case StepClick:
+			if step.Selector == "" && step.Text == "" {
+				return fmt.Errorf("step %d: click requires selector or text", n)
+			}
+		case StepType, StepWait, StepAssertText:
+			if step.Selector == "" {
+				return fmt.Errorf("step %d: %s requires selector", n, step.Action)
+			}
+		case StepAssertURL:
+			if step.URL == "" {
+				return fmt.Errorf("step %d: assert_url requires url", n)
+			}
+		case StepScreenshot:
+		default:
+			return fmt.Errorf("step %d: unknown action %q", n, step.Action)
+		}
+		for _, ref := range scriptParamRe.FindAllStringSubmatch(step.URL+step.Value, -1) {
+			if !declared[ref[1]] {
+				return fmt.Errorf("step %d: parameter %q is not declared", n, ref[1])
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveParams merges supplied values with defaults and reports missing ones.
+func (s *Script) ResolveParams(values map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(s.Params))
+	var missing []string
+	for _, p := range s.Params {
+		v, ok := values[p.Name]
+		if !ok || v == "" {
+			v = p.Default
+		}
+		if v == "" {
+			missing = append(missing, p.Name)
+			continue
+		}
+		resolved[p.Name] = v
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing script parameters: %s", strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
+// ParamEnvVar is the environment variable consulted for a parameter that was
+// not passed explicitly, so secrets can be kept out of chat and shell history.
+func ParamEnvVar(param string) string {
+	return "OKGOBOT_SCRIPT_" + strings.ToUpper(param)
+}
+
+// WithEnvParams returns values extended with parameters found in the
+// environment. Explicit values win.
+func (s *Script) WithEnvParams(values map[string]string) map[string]string {
+	merged := make(map[string]string, len(values))
+	for k, v := range values {
+		merged[k] = v
+	}
+	for _, p := range s.Params {
+		if merged[p.Name] != "" {
+			continue
+		}
+		if v := os.Getenv(ParamEnvVar(p.Name)); v != "" {
+			merged[p.Name] = v
+		}
+	}
+	return merged
+}
+
+// Expand substitutes {{param}} references in value.
+func Expand(value string, params map[string]string) string {
+	return scriptParamRe.ReplaceAllStringFunc(value, func(m string) string {
+		name := scriptParamRe.FindStringSubmatch(m)[1]
+		if v, ok := params[name]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// Describe renders a step for logs and failure reports. Values are shown
+// unexpanded, so secret parameters appear only as {{name}}.
+func (st ScriptStep) Describe() string {
+	target := st.Selector
+	if target == "" {
+		target = st.Text
+	}
+	switch st.Action {
+	case StepNavigate, StepAssertURL:
+		return fmt.Sprintf("%s %s", st.Action, st.URL)
+	case StepType:
+		return fmt.Sprintf("type %q into %s", st.Value, target)
+	case StepAssertText:
+		return fmt.Sprintf("assert %s contains %q", target, st.Value)
+	case StepScreenshot:
+		return "screenshot"
+	default:
+		return fmt.Sprintf("%s %s", st.Action, target)
+	}
+}
+
+func scriptPath(dir, name string) string {
+	return filepath.Join(dir, name+".yaml")
+}
+
+// SaveScript writes the script to dir, replacing any script with the same name.
+func SaveScript(dir string, s *Script) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create scripts directory: %w", err)
+	}
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(scriptPath(dir, s.Name), data, 0o600)
+}
+
+// LoadScript reads a named script from dir.
+func LoadScript(dir, name string) (*Script, error) {
+	if err := ValidateScriptName(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(scriptPath(dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("browser script %q not found", name)
+		}
+		return nil, err
+	}
+	var s Script
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse script %q: %w", name, err)
+	}
+	if s.Name == "" {
+		s.Name = name
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListScripts returns all scripts in dir sorted by name. Unreadable files are skipped.
+func ListScripts(dir string) ([]*Script, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var scripts []*Script
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".yaml" {
+			continue
+		}
+		s, err := LoadScript(dir, strings.TrimSuffix(e.Name(), ".yaml"))
+		if err != nil {
+			continue
+		}
+		scripts = append(scripts, s)
+	}
+	sort.Slice(scripts, func(i, j int) bool { return scripts[i].Name < scripts[j].Name })
+	return scripts, nil
+}
+
+// DeleteScript removes a named script from dir.
+func DeleteScript(dir, name string) error {
+	if err := ValidateScriptName(name); err != nil {
+		return err
+	}
+	if err := os.Remove(scriptPath(dir, name)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("browser script %q not found", name)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package browser
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/cdp"
+)
+
+func TestScriptSaveLoadListDelete(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder("shop-login", "Log in to the shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Add(ScriptStep{Action: StepNavigate, URL: "https://shop.example.com/login"})
+	rec.Add(ScriptStep{Action: StepType, Selector: "input[name=\"email\"]", Value: rec.Param("user[email]", "me@example.com", false)})
+	rec.Add(ScriptStep{Action: StepType, Selector: "#password", Value: rec.Param("password", "hunter2", true)})
+	rec.Add(ScriptStep{Action: StepClick, Selector: "[data-testid=\"submit\"]", Text: "Sign in"})
+	rec.Add(ScriptStep{Action: StepAssertURL, URL: "/account"})
+
+	if err := SaveScript(dir, rec.Script()); err != nil {
+		t.Fatalf("SaveScript: %v", err)
+	}
+
+	loaded, err := LoadScript(dir, "shop-login")
+	if err != nil {
+		t.Fatalf("LoadScript: %v", err)
+	}
+	if len(loaded.Steps) != 5 || loaded.Description != "Log in to the shop" {
+		t.Fatalf("unexpected script %+v", loaded)
+	}
+	if loaded.Steps[1].Value != "{{user_email}}" || loaded.Steps[2].Value != "{{password}}" {
+		t.Errorf("typed values not parameterised: %+v", loaded.Steps)
+	}
+	want := []ScriptParam{{Name: "user_email", Default: "me@example.com"}, {Name: "password", Secret: true}}
+	if len(loaded.Params) != 2 || loaded.Params[0] != want[0] || loaded.Params[1] != want[1] {
+		t.Errorf("params = %+v, want %+v", loaded.Params, want)
+	}
+
+	scripts, err := ListScripts(dir)
+	if err != nil || len(scripts) != 1 {
+		t.Fatalf("ListScripts = %v, %v", scripts, err)
+	}
+	if err := DeleteScript(dir, "shop-login"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadScript(dir, "shop-login"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found after delete, got %v", err)
+	}
+}
+
+func TestScriptNeverStoresSecrets(t *testing.T) {
+	dir := t.TempDir()
+	rec, _ := NewRecorder("otp", "")
+	rec.Param("code", "123456", false)
+	rec.Add(ScriptStep{Action: StepType, Selector: "#code", Value: rec.Param("code", "123456", true)})
+	if err := SaveScript(dir, rec.Script()); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadScript(dir, "otp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := loaded.Params[0]; !p.Secret || p.Default != "" {
+		t.Errorf("secret param kept a default: %+v", p)
+	}
+}
+
+func TestScriptValidate(t *testing.T) {
+	for name, s := range map[string]Script{
+		"bad name":         {Name: "Bad Name", Steps: []ScriptStep{{Action: StepScreenshot}}},
+		"no steps":         {Name: "empty"},
+		"unknown action":   {Name: "x", Steps: []ScriptStep{{Action: "hover"}}},
+		"click no target":  {Name: "x", Steps: []ScriptStep{{Action: StepClick}}},
+		"undeclared param": {Name: "x", Steps: []ScriptStep{{Action: StepType, Selector: "#q", Value: "{{query}}"}}},
+	} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestScriptParams(t *testing.T) {
+	s := &Script{Params: []ScriptParam{{Name: "query", Default: "go"}, {Name: "password", Secret: true}}}
+
+	if _, err := s.ResolveParams(nil); err == nil || !strings.Contains(err.Error(), "password") {
+		t.Fatalf("expected missing password error, got %v", err)
+	}
+
+	t.Setenv(ParamEnvVar("password"), "from-env")
+	values := s.WithEnvParams(map[string]string{"query": "rust"})
+	resolved, err := s.ResolveParams(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved["query"] != "rust" || resolved["password"] != "from-env" {
+		t.Errorf("resolved = %v", resolved)
+	}
+	if got := Expand("q={{ query }}&p={{password}}&x={{other}}", resolved); got != "q=rust&p=from-env&x={{other}}" {
+		t.Errorf("Expand = %q", got)
+	}
+}
+
+func TestDescribeRefResolvesSnapshotNode(t *testing.T) {
+	m := newManager(t.TempDir(), false)
+	m.resolveTabID = func(context.Context) string { return "tab-1" }
+	m.storeSnapshotForTab("tab-1", "snap-1", map[string]cdp.NodeID{"e3": 42})
+	m.describeNode = func(_ context.Context, nodeID cdp.NodeID) (ElementInfo, error) {
+		if nodeID != 42 {
+			t.Fatalf("describeNode got node %d, want 42", nodeID)
+		}
+		return ElementInfo{Selector: "#password", Field: "password", Secret: true}, nil
+	}
+
+	info, err := m.DescribeRef(context.Background(), "snap-1", "e3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Selector != "#password" || !info.Secret {
+		t.Errorf("info = %+v", info)
+	}
+	if _, err := m.DescribeRef(context.Background(), "stale", "e3"); err == nil {
+		t.Error("expected stale snapshot error")
+	}
+}
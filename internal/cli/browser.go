@@ -1,16 +1,23 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"ok-gobot/internal/browser"
+	"ok-gobot/internal/config"
+	"ok-gobot/internal/runtime"
 )
 
-func newBrowserCommand() *cobra.Command {
+func newBrowserCommand(cfg *config.Config) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "browser",
 		Short: "Manage Chrome browser for automation",
@@ -18,7 +25,9 @@ func newBrowserCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(newBrowserSetupCommand())
-	cmd.AddCommand(newBrowserStatusCommand())
+	cmd.AddCommand(newBrowserStatusCommand(cfg))
+	cmd.AddCommand(newBrowserScriptsCommand())
+	cmd.AddCommand(newBrowserRunCommand(cfg))
 
 	return cmd
 }
@@ -98,7 +107,7 @@ and will preserve your history, logins, and extensions.`,
 	}
 }
 
-func newBrowserStatusCommand() *cobra.Command {
+func newBrowserStatusCommand(cfg *config.Config) *cobra.Command {
 	return &cobra.Command{
 		Use:   "status",
 		Short: "Check Chrome browser status",
@@ -127,6 +136,77 @@ func newBrowserStatusCommand() *cobra.Command {
 			} else {
 				fmt.Println("❌ Chrome not installed")
 			}
+
+			printBrowserContexts(cfg)
 		},
 	}
 }
+
+// printBrowserContexts shows the subagent isolation settings and, when the
+// HTTP API is enabled, the isolated contexts held by the running bot.
+func printBrowserContexts(cfg *config.Config) {
+	fmt.Println("\n🧩 Subagent contexts")
+	if cfg.Browser.IsolateSubagents != nil && !*cfg.Browser.IsolateSubagents {
+		fmt.Println("   Subagents share the persistent profile (browser.isolate_subagents: false)")
+		return
+	}
+	maxContexts := cfg.Browser.MaxContexts
+	if maxContexts <= 0 {
+		maxContexts = browser.DefaultMaxContexts
+	}
+	fmt.Printf("   Isolated per run, at most %d at a time\n", maxContexts)
+
+	if !cfg.API.Enabled {
+		fmt.Println("   Enable the HTTP API (api.enabled) to list live contexts")
+		return
+	}
+	workers, err := fetchWorkers(cfg.API)
+	if err != nil {
+		fmt.Printf("   ⚠️  Could not reach the bot: %v\n", err)
+		return
+	}
+	open := 0
+	for _, w := range workers {
+		if w.Browser == nil {
+			continue
+		}
+		open++
+		fmt.Printf("   • %s — %d tab(s), open %s, idle %s\n", w.SessionKey, w.Browser.Tabs,
+			time.Since(w.Browser.CreatedAt).Round(time.Second), time.Since(w.Browser.LastUsed).Round(time.Second))
+	}
+	if open == 0 {
+		fmt.Println("   No isolated contexts open")
+	}
+}
+
+// apiBaseURL is where the running bot's API answers local requests.
+func apiBaseURL(apiCfg config.APIConfig) string {
+	host := apiCfg.BindAddr
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(apiCfg.Port))
+}
+
+// fetchWorkers reads /api/workers from the running bot.
+func fetchWorkers(apiCfg config.APIConfig) ([]runtime.WorkerSnapshot, error) {
+	url := apiBaseURL(apiCfg) + "/api/workers"
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-API-Key", apiCfg.APIKey)
+	resp, err := (&http.Client{Timeout: 3 * time.Second}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	var workers []runtime.WorkerSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&workers); err != nil {
+		return nil, fmt.Errorf("invalid /api/workers response: %w", err)
+	}
+	return workers, nil
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ok-gobot/internal/browser"
+	"ok-gobot/internal/config"
+)
+
+func newBrowserScriptsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "scripts",
+		Short: "List recorded browser scripts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := browser.DefaultScriptsDir()
+			scripts, err := browser.ListScripts(dir)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if len(scripts) == 0 {
+				_, _ = fmt.Fprintf(out, "No browser scripts in %s\n", dir)
+				return nil
+			}
+			for _, s := range scripts {
+				_, _ = fmt.Fprintf(out, "%-24s %2d steps", s.Name, len(s.Steps))
+				if s.Description != "" {
+					_, _ = fmt.Fprintf(out, "  %s", s.Description)
+				}
+				_, _ = fmt.Fprintln(out)
+				for _, p := range s.Params {
+					switch {
+					case p.Secret:
+						_, _ = fmt.Fprintf(out, "    --param %s=...  (secret; or set %s)\n", p.Name, browser.ParamEnvVar(p.Name))
+					case p.Default != "":
+						_, _ = fmt.Fprintf(out, "    --param %s=...  (default %q)\n", p.Name, p.Default)
+					default:
+						_, _ = fmt.Fprintf(out, "    --param %s=...\n", p.Name)
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func newBrowserRunCommand(cfg *config.Config) *cobra.Command {
+	var (
+		paramFlags []string
+		headless   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "run <script>",
+		Short: "Replay a recorded browser script",
+		Long: `Replay a browser script recorded by the agent (browser record_start/record_stop).
+
+Parameters are passed with --param name=value. Parameters that are not
+passed are read from OKGOBOT_SCRIPT_<NAME> environment variables, which
+keeps secrets out of shell history. The command exits non-zero when a step
+or assertion fails and prints the path of a screenshot taken at failure.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			script, err := browser.LoadScript(browser.DefaultScriptsDir(), args[0])
+			if err != nil {
+				return err
+			}
+			values := make(map[string]string, len(paramFlags))
+			for _, kv := range paramFlags {
+				k, v, ok := strings.Cut(kv, "=")
+				if !ok {
+					return fmt.Errorf("invalid --param %q, expected name=value", kv)
+				}
+				values[strings.TrimSpace(k)] = v
+			}
+			values = script.WithEnvParams(values)
+			if _, err := script.ResolveParams(values); err != nil {
+				return err
+			}
+
+			manager := browser.NewManager(cfg.Browser.ProfilePath)
+			manager.ChromePath = cfg.Browser.ChromePath
+			manager.RemoteDebugURL = cfg.Browser.DebugURL
+			manager.Headless = headless
+			if manager.RemoteDebugURL == "" && !manager.IsChromeInstalled() {
+				return fmt.Errorf("chrome not installed")
+			}
+			defer manager.Stop()
+
+			tabCtx, cancelTab, err := manager.NewTab()
+			if err != nil {
+				return fmt.Errorf("failed to open browser tab: %w", err)
+			}
+			defer cancelTab()
+			ctx, stop := signal.NotifyContext(tabCtx, os.Interrupt)
+			defer stop()
+
+			out := cmd.OutOrStdout()
+			result, err := browser.RunScript(ctx, browser.NewChromeDriver(), script, values, browser.RunOptions{
+				OnStep: func(n int, step browser.ScriptStep, err error) {
+					mark := "✓"
+					if err != nil {
+						mark = "✗"
+					}
+					_, _ = fmt.Fprintf(out, "%s %d/%d %s\n", mark, n, len(script.Steps), step.Describe())
+				},
+			})
+			if err != nil {
+				return err
+			}
+			for _, path := range result.Screenshots {
+				_, _ = fmt.Fprintf(out, "Screenshot: %s\n", path)
+			}
+			if !result.OK() {
+				if result.Screenshot != "" {
+					_, _ = fmt.Fprintf(out, "Failure screenshot: %s\n", result.Screenshot)
+				}
+				return fmt.Errorf("script %q failed at step %d: %w", script.Name, result.FailedStep, result.Err)
+			}
+			_, _ = fmt.Fprintf(out, "Script %q completed in %s\n", script.Name, result.Duration.Round(100*time.Millisecond))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&paramFlags, "param", nil, "script parameter as name=value (repeatable)")
+	cmd.Flags().BoolVar(&headless, "headless", false, "run Chrome without a window")
+	return cmd
+}
@@ -0,0 +1,280 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ok-gobot/internal/config"
+	"ok-gobot/internal/cron"
+	"ok-gobot/internal/storage"
+)
+
+func newCronCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cron",
+		Short: "Inspect scheduled tasks, their run history and run policies",
+	}
+
+	cmd.AddCommand(newCronListCommand(cfg))
+	cmd.AddCommand(newCronShowCommand(cfg))
+	cmd.AddCommand(newCronHistoryCommand(cfg))
+	cmd.AddCommand(newCronSetCommand(cfg))
+
+	return cmd
+}
+
+// --- list ---
+
+func newCronListCommand(cfg *config.Config) *cobra.Command {
+	var chatID int64
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List scheduled tasks with their next run times",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := storage.New(cfg.StoragePath)
+			if err != nil {
+				return fmt.Errorf("failed to open storage: %w", err)
+			}
+			defer store.Close() //nolint:errcheck
+
+			jobs, err := store.ListCronJobs()
+			if err != nil {
+				return fmt.Errorf("failed to list cron jobs: %w", err)
+			}
+
+			now := time.Now()
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tSTATE\tTYPE\tCHAT\tSCHEDULE\tNEXT RUN\tLAST RUN\tTASK")
+			shown := 0
+			for _, j := range jobs {
+				if chatID != 0 && j.ChatID != chatID {
+					continue
+				}
+				shown++
+				state := "enabled"
+				if !j.Enabled {
+					state = "disabled"
+				}
+				next := "-"
+				if j.Enabled {
+					if t, err := cron.NextRun(j, now); err != nil {
+						next = "invalid: " + err.Error()
+					} else if !t.IsZero() {
+						next = t.In(cron.JobLocation(j)).Format("2006-01-02 15:04 MST")
+						if !t.After(now) {
+							next += " (overdue)"
+						}
+					}
+				}
+				kind := j.Type
+				if cron.IsOneShot(j) {
+					kind += "/once"
+				}
+				last := "-"
+				if runs, err := store.ListCronRuns(j.ID, 1); err == nil && len(runs) > 0 {
+					last = formatTime(runs[0].StartedAt) + " " + runs[0].Status
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+					j.ID, state, kind, j.ChatID, cron.DescribeSchedule(j), next, last, truncate(j.Task, 40))
+			}
+			if shown == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No scheduled tasks.")
+				return nil
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().Int64Var(&chatID, "chat", 0, "only show tasks delivered to this chat ID")
+	return cmd
+}
+
+// --- show ---
+
+func newCronShowCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <id>",
+		Short: "Show a scheduled task with its policies and recent runs",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := storage.New(cfg.StoragePath)
+			if err != nil {
+				return fmt.Errorf("failed to open storage: %w", err)
+			}
+			defer store.Close() //nolint:errcheck
+
+			job, err := loadCronJob(store, args[0])
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Schedule:     #%d\n", job.ID)
+			fmt.Fprintf(out, "Task:         %s\n", job.Task)
+			fmt.Fprintf(out, "Type:         %s\n", job.Type)
+			fmt.Fprintf(out, "When:         %s\n", cron.DescribeSchedule(*job))
+			if job.Enabled {
+				if t, err := cron.NextRun(*job, time.Now()); err == nil && !t.IsZero() {
+					fmt.Fprintf(out, "Next run:     %s\n", t.In(cron.JobLocation(*job)).Format("2006-01-02 15:04 MST"))
+				}
+			} else {
+				fmt.Fprintf(out, "State:        disabled\n")
+			}
+			if job.ChatID != 0 {
+				fmt.Fprintf(out, "Chat:         %d\n", job.ChatID)
+			}
+			if job.TimeoutSeconds > 0 {
+				fmt.Fprintf(out, "Timeout:      %s\n", (time.Duration(job.TimeoutSeconds) * time.Second).String())
+			}
+			fmt.Fprintf(out, "Policies:     %s\n", cron.DescribePolicies(*job))
+			if n, err := store.CountConsecutiveCronFailures(job.ID); err == nil && n > 0 {
+				fmt.Fprintf(out, "Failing:      %d in a row\n", n)
+			}
+			fmt.Fprintf(out, "Created:      %s\n", job.CreatedAt)
+
+			runs, err := store.ListCronRuns(job.ID, 10)
+			if err != nil {
+				return fmt.Errorf("failed to list runs: %w", err)
+			}
+			if len(runs) > 0 {
+				fmt.Fprintln(out)
+				fmt.Fprintln(out, "Recent runs:")
+				writeCronRuns(out, runs, "  ")
+			}
+			return nil
+		},
+	}
+}
+
+// --- history ---
+
+func newCronHistoryCommand(cfg *config.Config) *cobra.Command {
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "history <id>",
+		Short: "List the runs of a scheduled task, newest first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := storage.New(cfg.StoragePath)
+			if err != nil {
+				return fmt.Errorf("failed to open storage: %w", err)
+			}
+			defer store.Close() //nolint:errcheck
+
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid schedule ID %q", args[0])
+			}
+			// Runs outlive their schedule, so a deleted one still has history.
+			runs, err := store.ListCronRuns(id, limit)
+			if err != nil {
+				return fmt.Errorf("failed to list runs: %w", err)
+			}
+			if len(runs) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No runs recorded.")
+				return nil
+			}
+			writeCronRuns(cmd.OutOrStdout(), runs, "")
+			return nil
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 20, "maximum number of runs to show")
+	return cmd
+}
+
+// --- set ---
+
+func newCronSetCommand(cfg *config.Config) *cobra.Command {
+	var (
+		concurrency  string
+		misfire      string
+		misfireLimit int
+		jitter       time.Duration
+		alertAfter   int
+	)
+	cmd := &cobra.Command{
+		Use:   "set <id>",
+		Short: "Change the run policies of a scheduled task",
+		Long: `Change how a scheduled task handles overlaps, missed runs, jitter and failures.
+
+Changes apply from the next fire; the misfire policy is evaluated when the daemon starts.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := storage.New(cfg.StoragePath)
+			if err != nil {
+				return fmt.Errorf("failed to open storage: %w", err)
+			}
+			defer store.Close() //nolint:errcheck
+
+			job, err := loadCronJob(store, args[0])
+			if err != nil {
+				return err
+			}
+
+			flags := cmd.Flags()
+			if flags.Changed("concurrency") {
+				job.ConcurrencyPolicy = concurrency
+			}
+			if flags.Changed("misfire") {
+				job.MisfirePolicy = misfire
+			}
+			if flags.Changed("misfire-limit") {
+				job.MisfireLimit = misfireLimit
+			}
+			if flags.Changed("jitter") {
+				job.JitterSeconds = int(jitter / time.Second)
+			}
+			if flags.Changed("alert-after") {
+				job.AlertAfter = alertAfter
+			}
+			if err := cron.ValidatePolicies(*job); err != nil {
+				return err
+			}
+			if err := store.UpdateCronJobPolicies(*job); err != nil {
+				return fmt.Errorf("failed to update schedule: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Schedule #%d: %s\n", job.ID, cron.DescribePolicies(*job))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&concurrency, "concurrency", "", "while a run is going: allow, skip or queue")
+	cmd.Flags().StringVar(&misfire, "misfire", "", "runs missed while down: skip, run_once or run_all")
+	cmd.Flags().IntVar(&misfireLimit, "misfire-limit", 0, "most missed runs run_all replays (0 = default 5)")
+	cmd.Flags().DurationVar(&jitter, "jitter", 0, "random delay of up to this long before each run, e.g. 30s")
+	cmd.Flags().IntVar(&alertAfter, "alert-after", 0, "alert the chat after this many failures in a row (0 = default 3, -1 = never)")
+	return cmd
+}
+
+func loadCronJob(store *storage.Store, arg string) (*storage.CronJob, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule ID %q", arg)
+	}
+	job, err := store.GetCronJob(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+	if job == nil {
+		return nil, fmt.Errorf("schedule #%d not found", id)
+	}
+	return job, nil
+}
+
+func writeCronRuns(out io.Writer, runs []storage.CronRun, indent string) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%sRUN\tDUE\tSTARTED\tFINISHED\tTRIGGER\tSTATUS\tJOB\tERROR\n", indent)
+	for _, r := range runs {
+		jobID := r.JobID
+		if jobID == "" {
+			jobID = "-"
+		}
+		fmt.Fprintf(w, "%s%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", indent,
+			r.ID, formatTime(r.ScheduledAt), formatTime(r.StartedAt), formatTime(r.FinishedAt),
+			r.Trigger, r.Status, jobID, truncate(r.Error, 50))
+	}
+	w.Flush() //nolint:errcheck
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"ok-gobot/internal/config"
+	"ok-gobot/internal/runtime"
 	"ok-gobot/internal/storage"
 )
 
@@ -23,6 +24,7 @@ func newJobsCommand(cfg *config.Config) *cobra.Command {
 	cmd.AddCommand(newJobsCancelCommand(cfg))
 	cmd.AddCommand(newJobsRetryCommand(cfg))
 	cmd.AddCommand(newJobsTailCommand(cfg))
+	cmd.AddCommand(newJobsAnswerCommand(cfg))
 
 	return cmd
 }
@@ -69,7 +71,7 @@ func newJobsListCommand(cfg *config.Config) *cobra.Command {
 			return w.Flush()
 		},
 	}
-	cmd.Flags().StringVar(&status, "status", "", "filter by status (pending, running, succeeded, failed, cancelled, timed_out)")
+	cmd.Flags().StringVar(&status, "status", "", "filter by status (pending, running, succeeded, failed, cancelled, timed_out, interrupted)")
 	cmd.Flags().IntVar(&limit, "limit", 50, "maximum number of jobs to show")
 	return cmd
 }
@@ -119,6 +121,9 @@ func newJobsInspectCommand(cfg *config.Config) *cobra.Command {
 			if job.RetryOfJobID != "" {
 				fmt.Fprintf(out, "Retry of:     %s\n", job.RetryOfJobID)
 			}
+			if job.ParentJobID != "" {
+				fmt.Fprintf(out, "Parent:       %s\n", job.ParentJobID)
+			}
 			if job.CancelRequested {
 				fmt.Fprintf(out, "Cancel req:   yes\n")
 			}
@@ -153,6 +158,38 @@ func newJobsInspectCommand(cfg *config.Config) *cobra.Command {
 				w.Flush() //nolint:errcheck
 			}
 
+			// Child jobs (workflow steps)
+			children, err := store.ListChildJobs(job.JobID)
+			if err != nil {
+				return fmt.Errorf("failed to list child jobs: %w", err)
+			}
+			if len(children) > 0 {
+				fmt.Fprintln(out)
+				fmt.Fprintln(out, "Children:")
+				w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "  ID\tSTATUS\tATTEMPT\tDESCRIPTION")
+				for _, c := range children {
+					fmt.Fprintf(w, "  %s\t%s\t%d/%d\t%s\n", c.JobID, c.Status, c.Attempt, c.MaxAttempts, truncate(c.Description, 50))
+				}
+				w.Flush() //nolint:errcheck
+			}
+
+			// Questions asked with ask_user
+			questions, err := store.ListJobQuestions(job.JobID)
+			if err != nil {
+				return fmt.Errorf("failed to list questions: %w", err)
+			}
+			if len(questions) > 0 {
+				fmt.Fprintln(out)
+				fmt.Fprintln(out, "Questions:")
+				w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "  ID\tSTATUS\tQUESTION\tANSWER")
+				for _, q := range questions {
+					fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", q.QuestionID, q.Status, truncate(q.Question, 50), truncate(q.Answer, 30))
+				}
+				w.Flush() //nolint:errcheck
+			}
+
 			// Artifacts
 			artifacts, err := store.ListJobArtifacts(job.JobID, 100)
 			if err != nil {
@@ -174,6 +211,33 @@ func newJobsInspectCommand(cfg *config.Config) *cobra.Command {
 	}
 }
 
+// --- answer ---
+
+func newJobsAnswerCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "answer <question-id> <answer...>",
+		Short: "Answer a question a job asked with ask_user",
+		Long: `Answer a question a waiting job asked with ask_user. Question IDs are
+shown by 'jobs inspect'. For questions with choices, a number picks that
+choice. The running bot instance hands the answer to the job within a second.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := storage.New(cfg.StoragePath)
+			if err != nil {
+				return fmt.Errorf("failed to open storage: %w", err)
+			}
+			defer store.Close() //nolint:errcheck
+
+			q, err := runtime.NewJobService(store).AnswerQuestion(args[0], strings.Join(args[1:], " "), "cli")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Answered %s for job %s: %s\n", q.QuestionID, q.JobID, q.Answer)
+			return nil
+		},
+	}
+}
+
 // --- cancel ---
 
 func newJobsCancelCommand(cfg *config.Config) *cobra.Command {
@@ -198,7 +262,7 @@ func newJobsCancelCommand(cfg *config.Config) *cobra.Command {
 			}
 
 			switch job.Status {
-			case "succeeded", "cancelled", "timed_out":
+			case "succeeded", "cancelled", "timed_out", "interrupted":
 				return fmt.Errorf("job %q already in terminal state: %s", jobID, job.Status)
 			}
 
@@ -236,13 +300,16 @@ func newJobsCancelCommand(cfg *config.Config) *cobra.Command {
 // --- retry ---
 
 func newJobsRetryCommand(cfg *config.Config) *cobra.Command {
-	return &cobra.Command{
+	var resume bool
+	cmd := &cobra.Command{
 		Use:   "retry <job-id>",
 		Short: "Queue a retry for a completed job",
-		Long: `Queue a retry for a completed (failed, cancelled, timed_out, or succeeded) job.
+		Long: `Queue a retry for a completed (failed, cancelled, timed_out, interrupted, or succeeded) job.
 
 Creates a new pending job record linked to the original. The retry will be
-picked up by the running bot instance.`,
+picked up by the running bot instance (or on its next start). With --resume
+the retry continues from the job's last checkpoint instead of starting over,
+so finished tool calls are not repeated.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			store, err := storage.New(cfg.StoragePath)
@@ -261,7 +328,7 @@ picked up by the running bot instance.`,
 			}
 
 			switch job.Status {
-			case "pending", "running":
+			case "pending", "running", "waiting_input":
 				return fmt.Errorf("job %q is still %s and cannot be retried", jobID, job.Status)
 			}
 
@@ -269,8 +336,34 @@ picked up by the running bot instance.`,
 				return fmt.Errorf("job %q reached max attempts (%d)", jobID, job.MaxAttempts)
 			}
 
+			var checkpoint string
+			if resume {
+				cp, ok, err := runtime.NewJobService(store).LatestCheckpoint(job.JobID)
+				if err != nil {
+					return fmt.Errorf("failed to read checkpoint: %w", err)
+				}
+				if !ok {
+					return fmt.Errorf("job %q has no checkpoint to resume from", jobID)
+				}
+				checkpoint = cp
+			}
+
 			newID := newCLIJobID()
 			attempt := job.Attempt + 1
+			mode, label := string(runtime.RetryRestart), "retry"
+			if resume {
+				mode, label = string(runtime.RetryResume), "resumed retry"
+				// Written before the job row so the bot never starts the
+				// retry without its checkpoint.
+				if err := store.AddJobEvent(storage.JobEvent{
+					JobID:     newID,
+					EventType: "checkpoint",
+					Message:   "checkpoint carried from " + job.JobID,
+					Payload:   checkpoint,
+				}); err != nil {
+					return fmt.Errorf("failed to copy checkpoint: %w", err)
+				}
+			}
 			if err := store.CreateJob(storage.Job{
 				JobID:              newID,
 				Kind:               job.Kind,
@@ -278,6 +371,9 @@ picked up by the running bot instance.`,
 				SessionKey:         job.SessionKey,
 				DeliverySessionKey: job.DeliverySessionKey,
 				RetryOfJobID:       job.JobID,
+				ParentJobID:        job.ParentJobID,
+				Priority:           job.Priority,
+				CostTier:           job.CostTier,
 				Description:        job.Description,
 				Status:             "pending",
 				Attempt:            attempt,
@@ -290,7 +386,7 @@ picked up by the running bot instance.`,
 			if err := store.AddJobEvent(storage.JobEvent{
 				JobID:     newID,
 				EventType: "created",
-				Message:   fmt.Sprintf("retry of %s (attempt %d)", job.JobID, attempt),
+				Message:   fmt.Sprintf("%s of %s (attempt %d)", label, job.JobID, attempt),
 			}); err != nil {
 				return fmt.Errorf("failed to record creation event: %w", err)
 			}
@@ -299,6 +395,7 @@ picked up by the running bot instance.`,
 				JobID:     job.JobID,
 				EventType: "retry_requested",
 				Message:   fmt.Sprintf("retry queued as %s via CLI", newID),
+				Payload:   fmt.Sprintf(`{"retry_job_id":%q,"mode":%q}`, newID, mode),
 			}); err != nil {
 				return fmt.Errorf("failed to record retry event: %w", err)
 			}
@@ -307,6 +404,8 @@ picked up by the running bot instance.`,
 			return nil
 		},
 	}
+	cmd.Flags().BoolVar(&resume, "resume", false, "continue from the job's last checkpoint instead of starting over")
+	return cmd
 }
 
 // --- tail ---
@@ -402,7 +501,7 @@ func printEvent(out interface{ Write([]byte) (int, error) }, e storage.JobEvent)
 
 func isTerminalStatus(status string) bool {
 	switch status {
-	case "succeeded", "failed", "cancelled", "timed_out":
+	case "succeeded", "failed", "cancelled", "timed_out", "interrupted":
 		return true
 	}
 	return false
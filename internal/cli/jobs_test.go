@@ -144,6 +144,15 @@ func TestJobsInspect(t *testing.T) {
 	}); err != nil {
 		t.Fatalf("AddJobEvent error = %v", err)
 	}
+	seedJob(t, store, storage.Job{
+		JobID:       "job-inspect-child",
+		Kind:        "workflow_step",
+		ParentJobID: "job-inspect-1",
+		Description: "pipeline/fetch",
+		Status:      "succeeded",
+		Attempt:     1,
+		MaxAttempts: 1,
+	})
 
 	cmd := newJobsCommand(cfg)
 	var out bytes.Buffer
@@ -156,7 +165,7 @@ func TestJobsInspect(t *testing.T) {
 	}
 
 	output := out.String()
-	for _, want := range []string{"job-inspect-1", "succeeded", "research", "agent-x", "deep research task", "1 / 3", "all done", "Events:", "created"} {
+	for _, want := range []string{"job-inspect-1", "succeeded", "research", "agent-x", "deep research task", "1 / 3", "all done", "Events:", "created", "Children:", "job-inspect-child", "pipeline/fetch"} {
 		if !strings.Contains(output, want) {
 			t.Errorf("expected %q in output: %q", want, output)
 		}
@@ -324,6 +333,49 @@ func TestJobsRetry(t *testing.T) {
 	}
 }
 
+func TestJobsRetry_Resume(t *testing.T) {
+	t.Parallel()
+	store, cfg := newTestStore(t)
+
+	seedJob(t, store, storage.Job{
+		JobID:       "job-resume-1",
+		Kind:        "research",
+		Status:      "failed",
+		Attempt:     1,
+		MaxAttempts: 2,
+	})
+
+	run := func() error {
+		cmd := newJobsCommand(cfg)
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&out)
+		cmd.SetArgs([]string{"retry", "--resume", "job-resume-1"})
+		return cmd.Execute()
+	}
+	if err := run(); err == nil || !strings.Contains(err.Error(), "no checkpoint") {
+		t.Fatalf("expected missing checkpoint error, got: %v", err)
+	}
+
+	store.AddJobEvent(storage.JobEvent{JobID: "job-resume-1", EventType: "checkpoint", Payload: `{"step":1}`}) //nolint:errcheck
+	store.AddJobEvent(storage.JobEvent{JobID: "job-resume-1", EventType: "checkpoint", Payload: `{"step":2}`}) //nolint:errcheck
+	if err := run(); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+
+	jobs, err := store.ListJobsByStatus("pending", 10)
+	if err != nil || len(jobs) != 1 || jobs[0].RetryOfJobID != "job-resume-1" {
+		t.Fatalf("expected one queued retry, got %+v (%v)", jobs, err)
+	}
+	events, err := store.ListJobEvents(jobs[0].JobID, 10)
+	if err != nil {
+		t.Fatalf("ListJobEvents error = %v", err)
+	}
+	if len(events) == 0 || events[0].EventType != "checkpoint" || events[0].Payload != `{"step":2}` {
+		t.Fatalf("expected the latest checkpoint on the retry, got %+v", events)
+	}
+}
+
 func TestJobsRetry_StillRunning(t *testing.T) {
 	t.Parallel()
 	store, cfg := newTestStore(t)
@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"ok-gobot/internal/bootstrap"
+	"ok-gobot/internal/config"
+	"ok-gobot/internal/role"
+	"ok-gobot/internal/runtime"
+	"ok-gobot/internal/storage"
+)
+
+func newRoleCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "role",
+		Short: "Run role manifests and inspect their event triggers",
+	}
+
+	cmd.AddCommand(newRoleRunCommand(cfg))
+	cmd.AddCommand(newRoleTriggersCommand(cfg))
+
+	return cmd
+}
+
+// --- run ---
+
+func newRoleRunCommand(cfg *config.Config) *cobra.Command {
+	var (
+		params []string
+		task   string
+		chatID int64
+		dryRun bool
+	)
+	cmd := &cobra.Command{
+		Use:   "run <name>",
+		Short: "Run a role once, or show what a run would do",
+		Long: `Resolve roles/<name>.md with its extends chain and the given params.
+
+With --dry-run, print the resolved prompt, tool set, cost tier and approval
+mode without running anything. Otherwise the running bot starts the role as
+a background job through its API (api.enabled must be set); follow it with
+ok-gobot jobs tail <id>.`,
+		Example: `  ok-gobot role run uptime-report --param host=db1.internal --dry-run
+  ok-gobot role run uptime-report --param host=db1.internal --chat 123456`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			values, err := parseRoleParams(params)
+			if err != nil {
+				return err
+			}
+			dir := filepath.Join(bootstrap.ExpandPath(cfg.GetSoulPath()), role.Dir)
+			m, err := role.Load(dir, args[0])
+			if err != nil {
+				return err
+			}
+			plan, err := m.Plan(values, task)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if dryRun {
+				writeRolePlan(out, plan)
+				return nil
+			}
+
+			jobID, err := startRoleRun(cfg.API, plan.Role, task, values, chatID)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Started role %s as job %s.\n", plan.Role, jobID)
+			fmt.Fprintf(out, "Follow it with: ok-gobot jobs tail %s\n", jobID)
+			return nil
+		},
+	}
+	cmd.Flags().StringArrayVarP(&params, "param", "p", nil, "bind a role param as key=value (repeatable)")
+	cmd.Flags().StringVar(&task, "task", "", "task given to the role after its prompt")
+	cmd.Flags().Int64Var(&chatID, "chat", 0, "Telegram chat to send the report to")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the resolved run without starting it")
+	return cmd
+}
+
+// parseRoleParams reads repeated key=value flags.
+func parseRoleParams(flags []string) (map[string]any, error) {
+	values := make(map[string]any, len(flags))
+	for _, f := range flags {
+		key, value, ok := strings.Cut(f, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --param %q (want key=value)", f)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// writeRolePlan prints a resolved role run.
+func writeRolePlan(out io.Writer, plan *role.Plan) {
+	name := plan.Role
+	if plan.Extends != "" {
+		name += " (extends " + plan.Extends + ")"
+	}
+	worker, tier := plan.Worker, "-"
+	if t, ok := runtime.ParseCostTier(plan.Worker); ok {
+		tier = string(t)
+	}
+	if worker == "" {
+		worker = "default"
+	}
+	tools := "all"
+	if len(plan.Tools) > 0 {
+		tools = strings.Join(plan.Tools, ", ")
+	}
+
+	fmt.Fprintf(out, "Role:      %s\n", name)
+	fmt.Fprintf(out, "Worker:    %s\n", worker)
+	fmt.Fprintf(out, "Tier:      %s\n", tier)
+	fmt.Fprintf(out, "Approval:  %s\n", plan.Approval)
+	fmt.Fprintf(out, "Tools:     %s\n", tools)
+	if len(plan.Params) > 0 {
+		names := make([]string, 0, len(plan.Params))
+		for n := range plan.Params {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		fmt.Fprintln(out, "Params:")
+		for _, n := range names {
+			v, _ := json.Marshal(plan.Params[n])
+			fmt.Fprintf(out, "  %s = %s\n", n, v)
+		}
+	}
+	if plan.OutputSchema != "" {
+		fmt.Fprintf(out, "Outputs:   %s\n", plan.OutputSchema)
+	}
+	if plan.Task != "" {
+		fmt.Fprintf(out, "Task:      %s\n", plan.Task)
+	}
+	fmt.Fprintf(out, "\nPrompt:\n%s\n", plan.Prompt)
+}
+
+// startRoleRun asks the running bot to start the role.
+func startRoleRun(apiCfg config.APIConfig, name, task string, params map[string]any, chatID int64) (string, error) {
+	if !apiCfg.Enabled {
+		return "", fmt.Errorf("role runs go through the bot's API; enable api in the config, or use --dry-run")
+	}
+	body, err := json.Marshal(map[string]any{"task": task, "params": params, "chat_id": chatID})
+	if err != nil {
+		return "", err
+	}
+	endpoint := apiBaseURL(apiCfg) + "/api/mission/roles/" + url.PathEscape(name) + "/run"
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", apiCfg.APIKey)
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("bot API not reachable (is the bot running?): %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		JobID string `json:"job_id"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid response from %s: %s", endpoint, resp.Status)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		if result.Error == "" {
+			result.Error = resp.Status
+		}
+		return "", fmt.Errorf("role run failed: %s", result.Error)
+	}
+	return result.JobID, nil
+}
+
+// --- triggers ---
+
+func newRoleTriggersCommand(cfg *config.Config) *cobra.Command {
+	var events int
+	cmd := &cobra.Command{
+		Use:   "triggers",
+		Short: "List role triggers with their last event and error",
+		Long: `List the webhook, file watch, feed and mail triggers the daemon has set up.
+
+With --events, also show the most recent events that started a role run.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := storage.New(cfg.StoragePath)
+			if err != nil {
+				return fmt.Errorf("failed to open storage: %w", err)
+			}
+			defer store.Close() //nolint:errcheck
+
+			triggers, err := store.ListRoleTriggers()
+			if err != nil {
+				return fmt.Errorf("failed to list triggers: %w", err)
+			}
+			out := cmd.OutOrStdout()
+			if len(triggers) == 0 {
+				fmt.Fprintln(out, "No role triggers. Add a triggers: list to a role in roles/<name>.md.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ROLE\tKIND\tSOURCE\tLAST EVENT\tERROR")
+			for _, t := range triggers {
+				last := "-"
+				if t.LastEventAt != "" {
+					last = formatTime(t.LastEventAt)
+				}
+				errMsg := "-"
+				if t.LastError != "" {
+					errMsg = truncate(t.LastError, 60)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.Role, t.Kind, truncate(t.Source, 50), last, errMsg)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			if events <= 0 {
+				return nil
+			}
+			recent, err := store.ListTriggerEvents("", events)
+			if err != nil {
+				return fmt.Errorf("failed to list events: %w", err)
+			}
+			fmt.Fprintln(out)
+			if len(recent) == 0 {
+				fmt.Fprintln(out, "No events yet.")
+				return nil
+			}
+			w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "TIME\tTRIGGER\tEVENT\tJOB")
+			for _, ev := range recent {
+				jobID := ev.JobID
+				if jobID == "" {
+					jobID = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", formatTime(ev.CreatedAt), truncate(ev.TriggerID, 50), truncate(ev.Title, 50), jobID)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().IntVar(&events, "events", 0, "also show this many recent events")
+	return cmd
+}
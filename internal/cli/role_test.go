@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ok-gobot/internal/config"
+)
+
+func writeRoleFile(t *testing.T, soul, name, content string) {
+	t.Helper()
+	dir := filepath.Join(soul, "roles")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const uptimeRole = `---
+worker: cheap
+tools: [http_request]
+params:
+  host: {type: string, required: true}
+  port: {type: int, default: 443}
+---
+Check {{.Params.host}}:{{.Params.port}}.`
+
+func TestRoleRun_DryRun(t *testing.T) {
+	t.Parallel()
+	soul := t.TempDir()
+	writeRoleFile(t, soul, "uptime", uptimeRole)
+
+	cmd := newRoleCommand(&config.Config{SoulPath: soul})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"run", "uptime", "--param", "host=db1", "-p", "port=5432", "--dry-run"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+
+	for _, want := range []string{
+		"Role:      uptime",
+		"Tier:      cheap",
+		"Approval:  auto",
+		"Tools:     http_request",
+		`port = 5432`,
+		"Check db1:5432.",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRoleRun_MissingParam(t *testing.T) {
+	t.Parallel()
+	soul := t.TempDir()
+	writeRoleFile(t, soul, "uptime", uptimeRole)
+
+	cmd := newRoleCommand(&config.Config{SoulPath: soul})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"run", "uptime", "--dry-run"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "host is required") {
+		t.Fatalf("Execute error = %v, want missing host", err)
+	}
+}
+
+func TestRoleRun_StartsThroughAPI(t *testing.T) {
+	t.Parallel()
+	soul := t.TempDir()
+	writeRoleFile(t, soul, "uptime", uptimeRole)
+
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/mission/roles/uptime/run" || r.Header.Get("X-API-Key") != "k" {
+			http.Error(w, `{"error":"unexpected request"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got) //nolint:errcheck
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"job_id":"job-42","role":"uptime"}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	portNum, _ := strconv.Atoi(port)
+
+	cmd := newRoleCommand(&config.Config{
+		SoulPath: soul,
+		API:      config.APIConfig{Enabled: true, BindAddr: host, Port: portNum, APIKey: "k"},
+	})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"run", "uptime", "-p", "host=db1", "--chat", "7"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute error = %v", err)
+	}
+	if !strings.Contains(out.String(), "job job-42") {
+		t.Errorf("output = %q", out.String())
+	}
+	params, _ := got["params"].(map[string]any)
+	if params["host"] != "db1" || got["chat_id"] != float64(7) {
+		t.Errorf("request body = %v", got)
+	}
+}
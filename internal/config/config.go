@@ -50,6 +50,16 @@ type RuntimeConfig struct {
 	CostTiers map[string]CostTierEntry `mapstructure:"cost_tiers"`
 	// Roles defines named role policies that map work to cost tiers.
 	Roles []RolePolicyEntry `mapstructure:"roles"`
+	// Scheduler caps how many durable jobs run at once.
+	Scheduler JobSchedulerConfig `mapstructure:"scheduler"`
+}
+
+// JobSchedulerConfig limits concurrent durable jobs. Queued jobs start by
+// priority (interactive, user, cron), round-robin across chats. 0 = no limit.
+type JobSchedulerConfig struct {
+	MaxConcurrent int            `mapstructure:"max_concurrent"`
+	PerTier       map[string]int `mapstructure:"per_tier"`   // cost tier -> max running jobs
+	PerWorker     map[string]int `mapstructure:"per_worker"` // job worker (e.g. cron_scheduler, claude) -> max running jobs
 }
 
 // CostTierEntry describes the execution settings for one cost tier in configuration.
@@ -69,11 +79,134 @@ type RolePolicyEntry struct {
 	Tiers       map[string]CostTierEntry `mapstructure:"tiers"`
 }
 
+// Validate checks the policy's name, tier names and durations.
+func (r RolePolicyEntry) Validate() error {
+	validCostTiers := map[string]bool{
+		"premium": true, "standard": true, "cheap": true, "local": true,
+	}
+	if r.Name == "" {
+		return fmt.Errorf("runtime.roles: each role must have a name")
+	}
+	if r.DefaultTier != "" && !validCostTiers[r.DefaultTier] {
+		return fmt.Errorf("runtime.roles[%s].default_tier: invalid tier %q", r.Name, r.DefaultTier)
+	}
+	for tierName, entry := range r.Tiers {
+		if !validCostTiers[tierName] {
+			return fmt.Errorf("runtime.roles[%s].tiers: invalid tier %q", r.Name, tierName)
+		}
+		if entry.MaxDuration != "" {
+			if _, err := time.ParseDuration(entry.MaxDuration); err != nil {
+				return fmt.Errorf("runtime.roles[%s].tiers.%s.max_duration: %w", r.Name, tierName, err)
+			}
+		}
+	}
+	return nil
+}
+
 // BrowserConfig holds browser automation settings.
 type BrowserConfig struct {
 	ChromePath  string `mapstructure:"chrome_path"`  // explicit path to Chrome/Chromium binary
 	ProfilePath string `mapstructure:"profile_path"` // user data directory for browser profiles
 	DebugURL    string `mapstructure:"debug_url"`    // connect to existing browser (e.g. http://127.0.0.1:9222)
+
+	MaxContexts      int   `mapstructure:"max_contexts"`      // concurrent isolated subagent contexts (default 4)
+	IsolateSubagents *bool `mapstructure:"isolate_subagents"` // own cookie jar and tabs per subagent run (default true)
+}
+
+// ToolsConfig holds settings for optional agent tools.
+type ToolsConfig struct {
+	HTTP        HTTPToolConfig    `mapstructure:"http"`
+	Checkpoints CheckpointConfig  `mapstructure:"checkpoints"`
+	RunCode     RunCodeToolConfig `mapstructure:"run_code"`
+	Search      SearchToolConfig  `mapstructure:"search"`
+	Obsidian    ObsidianConfig    `mapstructure:"obsidian"`
+	ImageGen    ImageGenConfig    `mapstructure:"image_gen"`
+	OutputSpool OutputSpoolConfig `mapstructure:"output_spool"`
+}
+
+// OutputSpoolConfig controls how oversized tool results are kept out of the
+// model context: they are stored as artifacts and read back with read_artifact.
+type OutputSpoolConfig struct {
+	ThresholdChars int `mapstructure:"threshold_chars"` // Results longer than this are spooled (0 = 16000, -1 = off)
+	MaxAgeHours    int `mapstructure:"max_age_hours"`   // Delete spooled output older than this at startup (0 = 168)
+}
+
+// ImageGenConfig holds settings for the image_gen tool.
+type ImageGenConfig struct {
+	Provider  string                `mapstructure:"provider"`   // Default provider name (default: first provider, or openai)
+	OutputDir string                `mapstructure:"output_dir"` // Where images and their prompt/seed metadata are stored (default ~/.ok-gobot/images)
+	Providers []ImageProviderConfig `mapstructure:"providers"`  // Extra backends; OpenAI (DALL-E) is added when ai.api_key is set
+}
+
+// ImageProviderConfig describes one image generation backend.
+type ImageProviderConfig struct {
+	Name           string `mapstructure:"name"`            // Name used to select the provider (default: the type)
+	Type           string `mapstructure:"type"`            // "openai", "openai_compatible", or "automatic1111"
+	BaseURL        string `mapstructure:"base_url"`        // API base URL (required for openai_compatible; automatic1111 default http://127.0.0.1:7860)
+	APIKey         string `mapstructure:"api_key"`         // Bearer token; "user:password" for automatic1111 --api-auth
+	Model          string `mapstructure:"model"`           // Default model or Stable Diffusion checkpoint
+	Steps          int    `mapstructure:"steps"`           // Sampling steps for automatic1111 (0 = 25)
+	NegativePrompt string `mapstructure:"negative_prompt"` // Default negative prompt for automatic1111
+}
+
+// ObsidianConfig holds settings for the obsidian vault tool.
+type ObsidianConfig struct {
+	VaultPath     string `mapstructure:"vault_path"`     // Vault directory (default ~/Obsidian); the tool is registered only if it exists
+	DailyFolder   string `mapstructure:"daily_folder"`   // Daily notes folder; overrides .obsidian/daily-notes.json
+	DailyFormat   string `mapstructure:"daily_format"`   // Daily note name in moment.js format (default YYYY-MM-DD)
+	DailyTemplate string `mapstructure:"daily_template"` // Template note for new daily notes
+	IndexMemory   bool   `mapstructure:"index_memory"`   // Index vault notes into semantic memory (needs memory embeddings)
+}
+
+// SearchToolConfig holds settings for the search tool.
+type SearchToolConfig struct {
+	Providers       []SearchProviderConfig `mapstructure:"providers"`         // Backends tried in order; later ones are fallbacks
+	CacheTTLMinutes int                    `mapstructure:"cache_ttl_minutes"` // Reuse results for identical queries (0 = 60, -1 = off)
+}
+
+// SearchProviderConfig describes one search backend.
+type SearchProviderConfig struct {
+	Type    string `mapstructure:"type"`     // "searxng", "brave", "exa", or "serper"
+	BaseURL string `mapstructure:"base_url"` // SearXNG instance URL; optional endpoint override for others
+	APIKey  string `mapstructure:"api_key"`  // Required for brave, exa and serper
+}
+
+// RunCodeToolConfig holds settings for the run_code tool.
+type RunCodeToolConfig struct {
+	ScratchDir     string `mapstructure:"scratch_dir"`     // Parent of per-session working directories (default ~/.ok-gobot/scratch)
+	TimeoutSeconds int    `mapstructure:"timeout_seconds"` // Default per-run time limit (0 = 30)
+	MemoryMB       int    `mapstructure:"memory_mb"`       // Memory limit per run (0 = 1024)
+	Python         string `mapstructure:"python"`          // Python interpreter (default python3)
+	Node           string `mapstructure:"node"`            // Node.js binary (default node)
+	Go             string `mapstructure:"go"`              // Go toolchain binary (default go)
+	Unattended     bool   `mapstructure:"unattended"`      // Allow runs without a chat to approve them (cron, triggers)
+}
+
+// CheckpointConfig controls the snapshots taken before tools modify files,
+// which back the /undo and /checkpoints commands.
+type CheckpointConfig struct {
+	Enabled       bool  `mapstructure:"enabled"`         // Snapshot files before file/patch/obsidian writes
+	MaxAgeHours   int   `mapstructure:"max_age_hours"`   // Prune snapshots older than this (0 = 168)
+	MaxTotalBytes int64 `mapstructure:"max_total_bytes"` // Prune oldest runs beyond this total (0 = 64MB)
+	MaxFileBytes  int64 `mapstructure:"max_file_bytes"`  // Skip snapshots of larger files (0 = 2MB)
+}
+
+// HTTPToolConfig holds settings for the http_request tool.
+type HTTPToolConfig struct {
+	MaxResponseBytes int64                                  `mapstructure:"max_response_bytes"` // Response body limit in bytes (0 = 1MB)
+	Profiles         map[string]HTTPCredentialProfileConfig `mapstructure:"profiles"`           // Named credential profiles injected server-side
+}
+
+// HTTPCredentialProfileConfig describes one named credential profile.
+// Secrets stay in config; the model only sees the profile name.
+type HTTPCredentialProfileConfig struct {
+	Type     string   `mapstructure:"type"`     // "bearer", "basic", or "header"
+	Token    string   `mapstructure:"token"`    // Bearer token
+	Username string   `mapstructure:"username"` // Basic auth username
+	Password string   `mapstructure:"password"` // Basic auth password
+	Header   string   `mapstructure:"header"`   // Header name for type "header"
+	Value    string   `mapstructure:"value"`    // Header value for type "header"
+	Hosts    []string `mapstructure:"hosts"`    // Hosts the profile may be sent to. Empty = any.
 }
 
 // SessionConfig holds session-key derivation behavior.
@@ -98,6 +231,7 @@ type Config struct {
 	Groups       GroupsConfig      `mapstructure:"groups"`
 	TTS          TTSConfig         `mapstructure:"tts"`
 	Memory       MemoryConfig      `mapstructure:"memory"`
+	Tools        ToolsConfig       `mapstructure:"tools"`
 	Agents       []AgentConfig     `mapstructure:"agents"`
 	Models       []string          `mapstructure:"models"` // list of models for TUI/web picker
 	ModelAliases map[string]string `mapstructure:"model_aliases"`
@@ -155,8 +289,17 @@ type GroupsConfig struct {
 
 // TTSConfig holds text-to-speech configuration
 type TTSConfig struct {
-	Provider     string `mapstructure:"provider"`      // "openai" or "edge"
-	DefaultVoice string `mapstructure:"default_voice"` // Provider-specific default voice
+	Provider      string            `mapstructure:"provider"`        // "openai", "edge" or "piper"
+	DefaultVoice  string            `mapstructure:"default_voice"`   // Provider-specific default voice
+	Voices        map[string]string `mapstructure:"voices"`          // Language code -> voice used when the text's language is detected
+	MaxChunkChars int               `mapstructure:"max_chunk_chars"` // Longest text per voice note (default 1000)
+	Piper         PiperTTSConfig    `mapstructure:"piper"`
+}
+
+// PiperTTSConfig holds settings for the offline Piper TTS provider.
+type PiperTTSConfig struct {
+	Binary    string `mapstructure:"binary"`     // piper executable (default "piper" from PATH)
+	ModelsDir string `mapstructure:"models_dir"` // Directory with <voice>.onnx models (default ~/.ok-gobot/piper-voices)
 }
 
 // MemoryConfig holds semantic memory configuration
@@ -183,14 +326,15 @@ type MemoryMCPConfig struct {
 // All *bool fields default to true (permissive) when nil.
 // A nil *CapabilityPolicyConfig on an agent means no restrictions (backward compatible).
 type CapabilityPolicyConfig struct {
-	Shell            *bool    `mapstructure:"shell"`             // Allow shell execution (local, ssh). Default: true.
-	Network          *bool    `mapstructure:"network"`           // Allow network tools (web_fetch, search, browser). Default: true.
-	NetworkAllowlist []string `mapstructure:"network_allowlist"` // Allowed hostnames when network is true. Empty = all.
-	Cron             *bool    `mapstructure:"cron"`              // Allow cron scheduling. Default: true.
-	MemoryWrite      *bool    `mapstructure:"memory_write"`      // Allow memory write tools. Default: true.
-	Spawn            *bool    `mapstructure:"spawn"`             // Allow sub-agent/job spawning. Default: true.
-	FilesystemRoots  []string `mapstructure:"filesystem_roots"`  // Allowed absolute filesystem paths. Empty = no restriction.
-	FileWriteScope   string   `mapstructure:"file_write_scope"`  // "full" (default) or "read_only".
+	Shell             *bool    `mapstructure:"shell"`               // Allow shell execution (local, ssh). Default: true.
+	Network           *bool    `mapstructure:"network"`             // Allow network tools (web_fetch, search, browser). Default: true.
+	NetworkAllowlist  []string `mapstructure:"network_allowlist"`   // Allowed hostnames when network is true. Empty = all.
+	Cron              *bool    `mapstructure:"cron"`                // Allow cron scheduling. Default: true.
+	MemoryWrite       *bool    `mapstructure:"memory_write"`        // Allow memory write tools. Default: true.
+	Spawn             *bool    `mapstructure:"spawn"`               // Allow sub-agent/job spawning. Default: true.
+	FilesystemRoots   []string `mapstructure:"filesystem_roots"`    // Allowed absolute filesystem paths. Empty = no restriction.
+	FileWriteScope    string   `mapstructure:"file_write_scope"`    // "full" (default) or "read_only".
+	GitHistoryRewrite bool     `mapstructure:"git_history_rewrite"` // Allow force-push and history rewrite in the git tool. Default: false.
 }
 
 // AgentConfig holds configuration for a single agent
@@ -248,7 +392,9 @@ func Load() (*Config, error) {
 	v.SetDefault("control.token", "")
 	v.SetDefault("control.allow_loopback_without_token", true)
 	v.SetDefault("runtime.session_queue_limit", 100)
+	v.SetDefault("runtime.scheduler.max_concurrent", 8)
 	v.SetDefault("session.dm_scope", "main")
+	v.SetDefault("tools.checkpoints.enabled", true)
 
 	// Environment variable prefix
 	v.SetEnvPrefix("OKGOBOT")
@@ -289,6 +435,10 @@ func Load() (*Config, error) {
 	// Expand paths
 	cfg.StoragePath = expandPath(cfg.StoragePath)
 	cfg.SoulPath = expandPath(cfg.SoulPath)
+	cfg.Tools.RunCode.ScratchDir = expandPath(cfg.Tools.RunCode.ScratchDir)
+	cfg.Tools.Obsidian.VaultPath = expandPath(cfg.Tools.Obsidian.VaultPath)
+	cfg.TTS.Piper.ModelsDir = expandPath(cfg.TTS.Piper.ModelsDir)
+	cfg.Tools.ImageGen.OutputDir = expandPath(cfg.Tools.ImageGen.OutputDir)
 	cfg.ConfigPath = v.ConfigFileUsed()
 
 	// Migrate legacy openai config to ai config
@@ -346,7 +496,9 @@ func LoadFrom(configPath string) (*Config, error) {
 	v.SetDefault("control.token", "")
 	v.SetDefault("control.allow_loopback_without_token", true)
 	v.SetDefault("runtime.session_queue_limit", 100)
+	v.SetDefault("runtime.scheduler.max_concurrent", 8)
 	v.SetDefault("session.dm_scope", "main")
+	v.SetDefault("tools.checkpoints.enabled", true)
 
 	// Environment variable prefix
 	v.SetEnvPrefix("OKGOBOT")
@@ -368,6 +520,10 @@ func LoadFrom(configPath string) (*Config, error) {
 	// Expand paths
 	cfg.StoragePath = expandPath(cfg.StoragePath)
 	cfg.SoulPath = expandPath(cfg.SoulPath)
+	cfg.Tools.RunCode.ScratchDir = expandPath(cfg.Tools.RunCode.ScratchDir)
+	cfg.Tools.Obsidian.VaultPath = expandPath(cfg.Tools.Obsidian.VaultPath)
+	cfg.TTS.Piper.ModelsDir = expandPath(cfg.TTS.Piper.ModelsDir)
+	cfg.Tools.ImageGen.OutputDir = expandPath(cfg.Tools.ImageGen.OutputDir)
 	cfg.ConfigPath = configPath
 
 	// Migrate legacy openai config to ai config
@@ -430,23 +586,27 @@ func (c *Config) Validate() error {
 		}
 	}
 
-	// Validate role policies.
-	for _, role := range c.Runtime.Roles {
-		if role.Name == "" {
-			return fmt.Errorf("runtime.roles: each role must have a name")
+	if c.Runtime.Scheduler.MaxConcurrent < 0 {
+		return fmt.Errorf("invalid runtime.scheduler.max_concurrent: %d (must be >= 0)", c.Runtime.Scheduler.MaxConcurrent)
+	}
+	for name, limit := range c.Runtime.Scheduler.PerTier {
+		if !validCostTiers[name] {
+			return fmt.Errorf("invalid runtime.scheduler.per_tier key: %q (allowed: premium, standard, cheap, local)", name)
 		}
-		if role.DefaultTier != "" && !validCostTiers[role.DefaultTier] {
-			return fmt.Errorf("runtime.roles[%s].default_tier: invalid tier %q", role.Name, role.DefaultTier)
+		if limit < 0 {
+			return fmt.Errorf("invalid runtime.scheduler.per_tier.%s: %d (must be >= 0)", name, limit)
 		}
-		for tierName, entry := range role.Tiers {
-			if !validCostTiers[tierName] {
-				return fmt.Errorf("runtime.roles[%s].tiers: invalid tier %q", role.Name, tierName)
-			}
-			if entry.MaxDuration != "" {
-				if _, err := time.ParseDuration(entry.MaxDuration); err != nil {
-					return fmt.Errorf("runtime.roles[%s].tiers.%s.max_duration: %w", role.Name, tierName, err)
-				}
-			}
+	}
+	for name, limit := range c.Runtime.Scheduler.PerWorker {
+		if limit < 0 {
+			return fmt.Errorf("invalid runtime.scheduler.per_worker.%s: %d (must be >= 0)", name, limit)
+		}
+	}
+
+	// Validate role policies.
+	for _, role := range c.Runtime.Roles {
+		if err := role.Validate(); err != nil {
+			return err
 		}
 	}
 
@@ -458,9 +618,9 @@ func (c *Config) Validate() error {
 
 	// Validate TTS provider
 	if c.TTS.Provider != "" {
-		validTTSProviders := map[string]bool{"openai": true, "edge": true}
+		validTTSProviders := map[string]bool{"openai": true, "edge": true, "piper": true}
 		if !validTTSProviders[c.TTS.Provider] {
-			return fmt.Errorf("invalid tts.provider: %s (must be 'openai' or 'edge')", c.TTS.Provider)
+			return fmt.Errorf("invalid tts.provider: %s (must be 'openai', 'edge' or 'piper')", c.TTS.Provider)
 		}
 	}
 
@@ -474,6 +634,65 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate http_request credential profiles.
+	for name, profile := range c.Tools.HTTP.Profiles {
+		switch profile.Type {
+		case "bearer":
+			if profile.Token == "" {
+				return fmt.Errorf("tools.http.profiles.%s: bearer profile requires token", name)
+			}
+		case "basic":
+			if profile.Username == "" {
+				return fmt.Errorf("tools.http.profiles.%s: basic profile requires username", name)
+			}
+		case "header":
+			if profile.Header == "" {
+				return fmt.Errorf("tools.http.profiles.%s: header profile requires header", name)
+			}
+		default:
+			return fmt.Errorf("tools.http.profiles.%s: invalid type %q (must be 'bearer', 'basic', or 'header')", name, profile.Type)
+		}
+	}
+	if c.Tools.HTTP.MaxResponseBytes < 0 {
+		return fmt.Errorf("invalid tools.http.max_response_bytes: %d (must be >= 0)", c.Tools.HTTP.MaxResponseBytes)
+	}
+	if c.Tools.RunCode.TimeoutSeconds < 0 || c.Tools.RunCode.MemoryMB < 0 {
+		return fmt.Errorf("invalid tools.run_code: timeout_seconds and memory_mb must be >= 0")
+	}
+	if c.Tools.OutputSpool.ThresholdChars < -1 || c.Tools.OutputSpool.MaxAgeHours < 0 {
+		return fmt.Errorf("invalid tools.output_spool: threshold_chars must be >= -1 and max_age_hours >= 0")
+	}
+	if c.Tools.Checkpoints.MaxAgeHours < 0 || c.Tools.Checkpoints.MaxTotalBytes < 0 || c.Tools.Checkpoints.MaxFileBytes < 0 {
+		return fmt.Errorf("invalid tools.checkpoints: limits must be >= 0")
+	}
+	for i, p := range c.Tools.Search.Providers {
+		switch strings.ToLower(strings.TrimSpace(p.Type)) {
+		case "searxng", "brave", "exa", "serper":
+		default:
+			return fmt.Errorf("invalid tools.search.providers[%d].type %q: must be searxng, brave, exa, or serper", i, p.Type)
+		}
+	}
+
+	imageProviders := map[string]bool{}
+	for i, p := range c.Tools.ImageGen.Providers {
+		switch strings.ToLower(strings.TrimSpace(p.Type)) {
+		case "openai", "openai_compatible", "automatic1111":
+		default:
+			return fmt.Errorf("invalid tools.image_gen.providers[%d].type %q: must be openai, openai_compatible, or automatic1111", i, p.Type)
+		}
+		name := p.Name
+		if name == "" {
+			name = strings.ToLower(strings.TrimSpace(p.Type))
+		}
+		if imageProviders[name] {
+			return fmt.Errorf("invalid tools.image_gen.providers[%d]: duplicate name %q", i, name)
+		}
+		imageProviders[name] = true
+	}
+	if p := c.Tools.ImageGen.Provider; p != "" && !imageProviders[p] && p != "openai" {
+		return fmt.Errorf("invalid tools.image_gen.provider %q: no provider with that name", p)
+	}
+
 	// Check storage path is set
 	if c.StoragePath == "" {
 		return fmt.Errorf("storage_path is required")
@@ -534,6 +753,13 @@ func (c *Config) Save() error {
 	if len(c.Runtime.Roles) > 0 {
 		v.Set("runtime.roles", c.Runtime.Roles)
 	}
+	v.Set("runtime.scheduler.max_concurrent", c.Runtime.Scheduler.MaxConcurrent)
+	if len(c.Runtime.Scheduler.PerTier) > 0 {
+		v.Set("runtime.scheduler.per_tier", c.Runtime.Scheduler.PerTier)
+	}
+	if len(c.Runtime.Scheduler.PerWorker) > 0 {
+		v.Set("runtime.scheduler.per_worker", c.Runtime.Scheduler.PerWorker)
+	}
 	v.Set("session.dm_scope", c.Session.DMScope)
 
 	// Persist fields that were previously omitted causing lossy round-trips.
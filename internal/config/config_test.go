@@ -37,6 +37,9 @@ storage_path: "/tmp/test.db"
 	if cfg.Runtime.SessionQueueLimit != 100 {
 		t.Errorf("expected runtime.session_queue_limit=%d, got %d", 100, cfg.Runtime.SessionQueueLimit)
 	}
+	if cfg.Runtime.Scheduler.MaxConcurrent != 8 {
+		t.Errorf("expected runtime.scheduler.max_concurrent=%d, got %d", 8, cfg.Runtime.Scheduler.MaxConcurrent)
+	}
 	if cfg.Session.DMScope != "main" {
 		t.Errorf("expected session.dm_scope=%q, got %q", "main", cfg.Session.DMScope)
 	}
@@ -222,6 +225,45 @@ storage_path: "/tmp/test.db"
 	}
 }
 
+func TestValidateRejectsUnknownSchedulerTier(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config-test-scheduler-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	content := `telegram:
+  token: "test-token"
+ai:
+  api_key: "test-key"
+  model: "test-model"
+runtime:
+  scheduler:
+    max_concurrent: 4
+    per_tier:
+      premium: 1
+      gold: 2
+    per_worker:
+      claude: 2
+storage_path: "/tmp/test.db"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := LoadFrom(configPath)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	if cfg.Runtime.Scheduler.PerTier["premium"] != 1 || cfg.Runtime.Scheduler.PerWorker["claude"] != 2 {
+		t.Fatalf("scheduler limits not loaded: %+v", cfg.Runtime.Scheduler)
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected validation error for unknown runtime.scheduler.per_tier key")
+	}
+}
+
 func TestValidateRejectsInvalidSessionDMScope(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "config-test-invalid-dm-scope-*")
 	if err != nil {
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrRolePolicyNotFound is returned when deleting a role policy that is not
+// configured.
+var ErrRolePolicyNotFound = errors.New("role policy not found")
+
+// SetRolePolicy adds the policy, or replaces the one with the same name, and
+// writes runtime.roles to the config file. It reports whether the policy is
+// new. Nothing changes when the policy is invalid or the write fails.
+func (c *Config) SetRolePolicy(entry RolePolicyEntry) (bool, error) {
+	if err := entry.Validate(); err != nil {
+		return false, err
+	}
+
+	roles := append([]RolePolicyEntry(nil), c.Runtime.Roles...)
+	created := true
+	for i := range roles {
+		if roles[i].Name == entry.Name {
+			roles[i] = entry
+			created = false
+		}
+	}
+	if created {
+		roles = append(roles, entry)
+	}
+
+	if err := SaveRolePolicies(c.ConfigPath, roles); err != nil {
+		return false, err
+	}
+	c.Runtime.Roles = roles
+	return created, nil
+}
+
+// DeleteRolePolicy removes the named policy and writes runtime.roles to the
+// config file.
+func (c *Config) DeleteRolePolicy(name string) error {
+	roles := make([]RolePolicyEntry, 0, len(c.Runtime.Roles))
+	for _, r := range c.Runtime.Roles {
+		if r.Name != name {
+			roles = append(roles, r)
+		}
+	}
+	if len(roles) == len(c.Runtime.Roles) {
+		return fmt.Errorf("%w: %s", ErrRolePolicyNotFound, name)
+	}
+
+	if err := SaveRolePolicies(c.ConfigPath, roles); err != nil {
+		return err
+	}
+	c.Runtime.Roles = roles
+	return nil
+}
+
+// rolePolicyYAML and costTierYAML give runtime.roles its config file layout.
+type rolePolicyYAML struct {
+	Name        string                  `yaml:"name"`
+	DefaultTier string                  `yaml:"default_tier,omitempty"`
+	Tiers       map[string]costTierYAML `yaml:"tiers,omitempty"`
+}
+
+type costTierYAML struct {
+	Model        string `yaml:"model,omitempty"`
+	Provider     string `yaml:"provider,omitempty"`
+	BaseURL      string `yaml:"base_url,omitempty"`
+	Thinking     string `yaml:"thinking,omitempty"`
+	MaxToolCalls int    `yaml:"max_tool_calls,omitempty"`
+	MaxDuration  string `yaml:"max_duration,omitempty"`
+}
+
+// SaveRolePolicies replaces runtime.roles in the YAML config file at path,
+// leaving the rest of the file, comments included, as it is. The file is
+// replaced atomically, so the config watcher never reads half of it.
+func SaveRolePolicies(path string, roles []RolePolicyEntry) error {
+	if path == "" {
+		return fmt.Errorf("config path not set")
+	}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+	default:
+		return fmt.Errorf("cannot edit %s: only YAML config files are supported", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config %s is not a YAML mapping", path)
+	}
+
+	runtimeNode := mappingValue(root, "runtime")
+	if runtimeNode == nil {
+		runtimeNode = &yaml.Node{Kind: yaml.MappingNode}
+		setMappingValue(root, "runtime", runtimeNode)
+	}
+	if runtimeNode.Kind != yaml.MappingNode {
+		return fmt.Errorf("config %s: runtime is not a mapping", path)
+	}
+
+	if len(roles) == 0 {
+		deleteMappingValue(runtimeNode, "roles")
+	} else {
+		out := make([]rolePolicyYAML, 0, len(roles))
+		for _, r := range roles {
+			entry := rolePolicyYAML{Name: r.Name, DefaultTier: r.DefaultTier}
+			if len(r.Tiers) > 0 {
+				entry.Tiers = make(map[string]costTierYAML, len(r.Tiers))
+				for name, t := range r.Tiers {
+					entry.Tiers[name] = costTierYAML(t)
+				}
+			}
+			out = append(out, entry)
+		}
+		var rolesNode yaml.Node
+		if err := rolesNode.Encode(out); err != nil {
+			return err
+		}
+		setMappingValue(runtimeNode, "roles", &rolesNode)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+func deleteMappingValue(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, keeping the file's permissions.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
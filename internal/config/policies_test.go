@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetAndDeleteRolePolicy(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	content := `# bot settings
+telegram:
+  token: "test-token" # keep me
+storage_path: "/tmp/test.db"
+runtime:
+  session_queue_limit: 50
+`
+	if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err := LoadFrom(configPath)
+	if err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+
+	if _, err := cfg.SetRolePolicy(RolePolicyEntry{Name: "researcher", DefaultTier: "gold"}); err == nil {
+		t.Fatal("expected error for invalid default tier")
+	}
+
+	created, err := cfg.SetRolePolicy(RolePolicyEntry{
+		Name:        "researcher",
+		DefaultTier: "standard",
+		Tiers: map[string]CostTierEntry{
+			"premium":  {Model: "opus", MaxDuration: "10m"},
+			"standard": {Model: "sonnet", MaxToolCalls: 20},
+		},
+	})
+	if err != nil || !created {
+		t.Fatalf("SetRolePolicy = created %v, %v", created, err)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	for _, want := range []string{"# bot settings", "# keep me", "session_queue_limit: 50", "default_tier: standard", "max_tool_calls: 20"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("config file lacks %q:\n%s", want, data)
+		}
+	}
+	if info, _ := os.Stat(configPath); info.Mode().Perm() != 0600 {
+		t.Errorf("config file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	reloaded, err := LoadFrom(configPath)
+	if err != nil {
+		t.Fatalf("LoadFrom after save failed: %v", err)
+	}
+	if len(reloaded.Runtime.Roles) != 1 {
+		t.Fatalf("reloaded roles = %+v", reloaded.Runtime.Roles)
+	}
+	r := reloaded.Runtime.Roles[0]
+	if r.Name != "researcher" || r.Tiers["premium"].MaxDuration != "10m" || r.Tiers["standard"].MaxToolCalls != 20 {
+		t.Errorf("reloaded role = %+v", r)
+	}
+	if reloaded.Runtime.SessionQueueLimit != 50 {
+		t.Errorf("session_queue_limit = %d, want 50", reloaded.Runtime.SessionQueueLimit)
+	}
+
+	created, err = cfg.SetRolePolicy(RolePolicyEntry{Name: "researcher", DefaultTier: "cheap"})
+	if err != nil || created {
+		t.Fatalf("SetRolePolicy update = created %v, %v", created, err)
+	}
+	if len(cfg.Runtime.Roles) != 1 || cfg.Runtime.Roles[0].DefaultTier != "cheap" {
+		t.Errorf("roles after update = %+v", cfg.Runtime.Roles)
+	}
+
+	if err := cfg.DeleteRolePolicy("researcher"); err != nil {
+		t.Fatalf("DeleteRolePolicy: %v", err)
+	}
+	if err := cfg.DeleteRolePolicy("researcher"); !errors.Is(err, ErrRolePolicyNotFound) {
+		t.Errorf("DeleteRolePolicy again = %v, want ErrRolePolicyNotFound", err)
+	}
+	data, _ = os.ReadFile(configPath)
+	if strings.Contains(string(data), "roles:") {
+		t.Errorf("roles still in config file:\n%s", data)
+	}
+}
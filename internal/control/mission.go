@@ -129,6 +129,8 @@ func missionSchedules(mp MissionProvider) http.HandlerFunc {
 			Type           string `json:"type"`
 			ChatID         int64  `json:"chat_id"`
 			TimeoutSeconds int    `json:"timeout_seconds"`
+			Timezone       string `json:"timezone,omitempty"`
+			RunAt          string `json:"run_at,omitempty"`
 			NextRun        string `json:"next_run"`
 			CreatedAt      string `json:"created_at"`
 		}
@@ -142,6 +144,8 @@ func missionSchedules(mp MissionProvider) http.HandlerFunc {
 				Type:           j.Type,
 				ChatID:         j.ChatID,
 				TimeoutSeconds: j.TimeoutSeconds,
+				Timezone:       j.Timezone,
+				RunAt:          j.RunAt,
 				CreatedAt:      j.CreatedAt,
 			}
 			if nr, err := scheduler.GetNextRun(j.ID); err == nil {
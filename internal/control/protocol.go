@@ -12,6 +12,7 @@ const (
 	EvtToolDenied       = "tool.denied"
 	EvtApprovalRequest  = "approval.request"
 	EvtApprovalResolved = "approval.resolved"
+	EvtQuestionRequest  = "question.request"
 )
 
 type SessionInfo struct {
@@ -54,6 +55,16 @@ type ApprovalRequestPayload struct {
 	Command    string `json:"command"`
 }
 
+// QuestionRequestPayload carries a question a background job asked with ask_user.
+type QuestionRequestPayload struct {
+	QuestionID string   `json:"question_id"`
+	JobID      string   `json:"job_id"`
+	ChatID     int64    `json:"chat_id,omitempty"`
+	Question   string   `json:"question"`
+	Choices    []string `json:"choices,omitempty"`
+	Default    string   `json:"default,omitempty"`
+}
+
 type ApprovalResolvedPayload struct {
 	ApprovalID string `json:"approval_id"`
 	Approved   bool   `json:"approved"`
@@ -67,6 +67,7 @@ type Server struct {
 	hub        *Hub
 	state      StateProvider
 	store      *storage.Store
+	jobs       *runtimepkg.JobService
 	httpSrv    *http.Server
 	runtimeHub *runtimepkg.Hub
 	tuiMu      sync.Mutex
@@ -91,6 +92,11 @@ func (s *Server) SetStore(store *storage.Store) {
 	s.store = store
 }
 
+// SetJobService attaches the durable job service used for job retries.
+func (s *Server) SetJobService(jobs *runtimepkg.JobService) {
+	s.jobs = jobs
+}
+
 // Hub returns the event hub so callers can emit events from elsewhere in the
 // application (e.g. bot callbacks, streaming AI responses).
 func (s *Server) Hub() *Hub {
@@ -8,6 +8,7 @@ import (
 	"log"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -41,6 +42,7 @@ func isTUICommand(cmdType string) bool {
 		CmdListJobEvents,
 		CmdListJobArtifacts,
 		CmdCancelJob,
+		CmdRetryJob,
 		CmdListWorkers:
 		return true
 	default:
@@ -210,6 +212,15 @@ func (s *Server) handleTUIRequest(c *client, cmd ClientMsg) {
 					if event.Err != nil {
 						msg.ToolError = event.Err.Error()
 					}
+					for _, att := range event.Attachments {
+						msg.Artifacts = append(msg.Artifacts, ArtifactInfo{
+							Name:         att.Caption,
+							ArtifactType: "file",
+							MimeType:     att.MimeType,
+							URI:          "file://" + att.Path,
+							CreatedAt:    time.Now().Format(time.RFC3339),
+						})
+					}
 					s.hub.BroadcastTUI(msg)
 				}
 			},
@@ -383,6 +394,8 @@ func (s *Server) handleTUIRequest(c *client, cmd ClientMsg) {
 		s.handleListJobArtifacts(c, cmd)
 	case CmdCancelJob:
 		s.handleCancelJob(c, cmd)
+	case CmdRetryJob:
+		s.handleRetryJob(c, cmd)
 	case CmdListWorkers:
 		s.handleListWorkers(c)
 
@@ -399,7 +412,7 @@ func (s *Server) handleTUIBotCommand(c *client, cmd ClientMsg) {
 	}
 
 	text := strings.TrimSpace(cmd.Text)
-	result := s.executeBotCommand(text)
+	result := s.executeBotCommand(sessionID, text)
 
 	// Deliver as a synthetic assistant message
 	c.sendTUIMsg(ServerMsg{
@@ -413,8 +426,8 @@ func (s *Server) handleTUIBotCommand(c *client, cmd ClientMsg) {
 	})
 }
 
-// executeBotCommand runs a slash command and returns the text result.
-func (s *Server) executeBotCommand(text string) string {
+// executeBotCommand runs a slash command for a TUI session and returns the text result.
+func (s *Server) executeBotCommand(sessionID, text string) string {
 	parts := strings.Fields(text)
 	if len(parts) == 0 {
 		return "unknown command"
@@ -427,6 +440,43 @@ func (s *Server) executeBotCommand(text string) string {
 			return provider.GetStatusText("")
 		}
 		return s.buildStatusText()
+	case "undo", "checkpoints":
+		provider, ok := s.state.(TUICheckpointProvider)
+		if !ok {
+			return "File checkpoints are not available in this runtime."
+		}
+		var (
+			result string
+			err    error
+		)
+		if cmd == "checkpoints" {
+			result, err = provider.CheckpointsReport(tuiSessionKeyForID(sessionID))
+		} else {
+			n := 1
+			if len(parts) > 1 {
+				if n, err = strconv.Atoi(parts[1]); err != nil || n < 1 {
+					return "Usage: /undo [n]"
+				}
+			}
+			result, err = provider.UndoSession(tuiSessionKeyForID(sessionID), n)
+		}
+		if err != nil {
+			return "❌ " + err.Error()
+		}
+		return result
+	case "answer":
+		if s.jobs == nil {
+			return "Job questions are not available in this runtime."
+		}
+		input := strings.TrimSpace(strings.TrimPrefix(text, parts[0]))
+		if input == "" {
+			return "Usage: /answer [question-id] <answer>"
+		}
+		q, err := s.jobs.AnswerPending(0, input, "tui")
+		if err != nil {
+			return "❌ " + err.Error()
+		}
+		return fmt.Sprintf("✅ Sent to job %s: %s", q.JobID, q.Answer)
 	case "commands", "help":
 		return `🦞 *Available commands*
 
@@ -443,6 +493,9 @@ func (s *Server) executeBotCommand(text string) string {
 /compact   — compact context window
 /new       — start new session
 /abort     — abort active run
+/undo [n]  — undo file changes of the last n runs
+/checkpoints — list undoable file changes
+/answer [id] <text> — answer a question from a background job
 
 *TUI shortcuts:*
 Ctrl+P     — session picker
@@ -967,6 +1020,14 @@ func (s *Server) handleGetJob(c *client, cmd ClientMsg) {
 		return
 	}
 	info := jobToInfo(*job)
+	children, err := s.store.ListChildJobs(jobID)
+	if err != nil {
+		c.sendTUIError("list child jobs: " + err.Error())
+		return
+	}
+	for _, child := range children {
+		info.Children = append(info.Children, jobToInfo(child))
+	}
 	c.sendTUIMsg(ServerMsg{Type: MsgTypeJobDetail, Job: &info})
 }
 
@@ -1067,6 +1128,29 @@ func (s *Server) handleCancelJob(c *client, cmd ClientMsg) {
 	c.sendTUIMsg(ServerMsg{Type: MsgTypeJobDetail, Job: &info})
 }
 
+func (s *Server) handleRetryJob(c *client, cmd ClientMsg) {
+	if s.jobs == nil {
+		c.sendTUIError("job service not configured")
+		return
+	}
+	jobID := strings.TrimSpace(cmd.JobID)
+	if jobID == "" {
+		c.sendTUIError("job_id is required")
+		return
+	}
+	mode := runtimepkg.RetryRestart
+	if cmd.Resume {
+		mode = runtimepkg.RetryResume
+	}
+	job, err := s.jobs.Retry(context.Background(), jobID, mode)
+	if err != nil {
+		c.sendTUIError("retry job: " + err.Error())
+		return
+	}
+	info := jobToInfo(*job)
+	c.sendTUIMsg(ServerMsg{Type: MsgTypeJobDetail, Job: &info})
+}
+
 func (s *Server) handleListWorkers(c *client) {
 	if s.runtimeHub == nil {
 		c.sendTUIMsg(ServerMsg{Type: MsgTypeWorkers, Workers: []WorkerInfo{}})
@@ -1092,6 +1176,7 @@ func jobToInfo(j storage.Job) JobInfo {
 		SessionKey:         j.SessionKey,
 		DeliverySessionKey: j.DeliverySessionKey,
 		RetryOfJobID:       j.RetryOfJobID,
+		ParentJobID:        j.ParentJobID,
 		Description:        j.Description,
 		Status:             j.Status,
 		CancelRequested:    j.CancelRequested,
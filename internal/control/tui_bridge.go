@@ -106,6 +106,19 @@ func legacyEventToTUI(evtType string, payload interface{}) []ServerMsg {
 			ApprovalID: p.ApprovalID,
 			Command:    p.Command,
 		}}
+	case EvtQuestionRequest:
+		p, ok := asQuestionRequestPayload(payload)
+		if !ok {
+			return nil
+		}
+		return []ServerMsg{{
+			Type:       MsgTypeEvent,
+			Kind:       KindQuestion,
+			QuestionID: p.QuestionID,
+			Content:    p.Question,
+			Choices:    p.Choices,
+			Message:    p.JobID,
+		}}
 	case EvtSessionQueued:
 		p, ok := asSessionInfo(payload)
 		if !ok {
@@ -192,6 +205,20 @@ func asApprovalRequestPayload(payload interface{}) (ApprovalRequestPayload, bool
 	}
 }
 
+func asQuestionRequestPayload(payload interface{}) (QuestionRequestPayload, bool) {
+	switch p := payload.(type) {
+	case QuestionRequestPayload:
+		return p, true
+	case *QuestionRequestPayload:
+		if p == nil {
+			return QuestionRequestPayload{}, false
+		}
+		return *p, true
+	default:
+		return QuestionRequestPayload{}, false
+	}
+}
+
 func asSessionInfo(payload interface{}) (SessionInfo, bool) {
 	switch p := payload.(type) {
 	case SessionInfo:
@@ -35,6 +35,13 @@ type TUIRunProvider interface {
 	GetStatusText(sessionID string) string
 }
 
+// TUICheckpointProvider is an optional state extension that lets TUI sessions
+// list and roll back file changes made by their runs.
+type TUICheckpointProvider interface {
+	UndoSession(sessionKey string, n int) (string, error)
+	CheckpointsReport(sessionKey string) (string, error)
+}
+
 type tuiSessionState struct {
 	ID            string
 	Name          string
@@ -27,6 +27,7 @@ const (
 	KindQueue       = "queue_update"
 	KindChildDone   = "child_done"
 	KindChildFailed = "child_failed"
+	KindQuestion    = "question"
 )
 
 // Command type constants for client→server messages.
@@ -45,6 +46,7 @@ const (
 	CmdListJobEvents    = "list_job_events"
 	CmdListJobArtifacts = "list_job_artifacts"
 	CmdCancelJob        = "cancel_job"
+	CmdRetryJob         = "retry_job"
 	CmdListWorkers      = "list_workers"
 )
 
@@ -68,6 +70,8 @@ type ServerMsg struct {
 	DenyRemediation  string           `json:"deny_remediation,omitempty"`
 	ApprovalID       string           `json:"approval_id,omitempty"`
 	Command          string           `json:"command,omitempty"`
+	QuestionID       string           `json:"question_id,omitempty"` // KindQuestion; answer with /answer
+	Choices          []string         `json:"choices,omitempty"`
 	QueueDepth       int              `json:"queue_depth,omitempty"`
 	Sessions         []TUISessionInfo `json:"sessions,omitempty"`
 	Message          string           `json:"message,omitempty"`
@@ -91,6 +95,7 @@ type JobInfo struct {
 	SessionKey         string `json:"session_key,omitempty"`
 	DeliverySessionKey string `json:"delivery_session_key,omitempty"`
 	RetryOfJobID       string `json:"retry_of_job_id,omitempty"`
+	ParentJobID        string `json:"parent_job_id,omitempty"`
 	Description        string `json:"description"`
 	Status             string `json:"status"`
 	CancelRequested    bool   `json:"cancel_requested,omitempty"`
@@ -103,6 +108,10 @@ type JobInfo struct {
 	StartedAt          string `json:"started_at,omitempty"`
 	CompletedAt        string `json:"completed_at,omitempty"`
 	UpdatedAt          string `json:"updated_at"`
+
+	// Children lists the jobs spawned by this one (workflow steps); only
+	// set on job_detail.
+	Children []JobInfo `json:"children,omitempty"`
 }
 
 // JobEventInfo is the JSON-friendly representation of a job lifecycle event.
@@ -161,6 +170,8 @@ type ClientMsg struct {
 	// Job dashboard fields.
 	JobID string `json:"job_id,omitempty"`
 	Limit int    `json:"limit,omitempty"`
+	// Resume continues a retried job from its last checkpoint (CmdRetryJob).
+	Resume bool `json:"resume,omitempty"`
 }
 
 // TUISessionInfo describes a session for the TUI session list.
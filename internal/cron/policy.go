@@ -0,0 +1,108 @@
+package cron
+
+import (
+	"fmt"
+
+	"ok-gobot/internal/storage"
+)
+
+// Concurrency policies: what a fire does while the job's previous run is
+// still going.
+const (
+	ConcurrencyAllow = "allow" // start another run alongside
+	ConcurrencySkip  = "skip"  // drop this fire (default)
+	ConcurrencyQueue = "queue" // run once the current run ends; one fire is kept
+)
+
+// Misfire policies: what happens on startup to runs that were due while
+// the daemon was down.
+const (
+	MisfireSkip    = "skip"     // record them as missed (default)
+	MisfireRunOnce = "run_once" // run once for all of them
+	MisfireRunAll  = "run_all"  // replay each, oldest first, up to the misfire limit
+)
+
+// Run triggers recorded in the run history.
+const (
+	TriggerSchedule = "schedule"
+	TriggerCatchUp  = "catch_up"
+	TriggerManual   = "manual" // RunNow
+)
+
+const (
+	defaultMisfireLimit = 5
+	defaultAlertAfter   = 3
+	maxJitterSeconds    = 3600
+)
+
+func concurrencyPolicy(job storage.CronJob) string {
+	if job.ConcurrencyPolicy == "" {
+		return ConcurrencySkip
+	}
+	return job.ConcurrencyPolicy
+}
+
+func misfirePolicy(job storage.CronJob) string {
+	if job.MisfirePolicy == "" {
+		return MisfireSkip
+	}
+	return job.MisfirePolicy
+}
+
+func misfireLimit(job storage.CronJob) int {
+	if job.MisfireLimit <= 0 {
+		return defaultMisfireLimit
+	}
+	return job.MisfireLimit
+}
+
+// alertAfter is the number of consecutive failures that triggers an alert;
+// 0 means never.
+func alertAfter(job storage.CronJob) int {
+	switch {
+	case job.AlertAfter < 0:
+		return 0
+	case job.AlertAfter == 0:
+		return defaultAlertAfter
+	}
+	return job.AlertAfter
+}
+
+// ValidatePolicies checks the run policy fields of job. Empty and zero
+// values select the defaults.
+func ValidatePolicies(job storage.CronJob) error {
+	switch job.ConcurrencyPolicy {
+	case "", ConcurrencyAllow, ConcurrencySkip, ConcurrencyQueue:
+	default:
+		return fmt.Errorf("unknown concurrency policy %q (use allow, skip or queue)", job.ConcurrencyPolicy)
+	}
+	switch job.MisfirePolicy {
+	case "", MisfireSkip, MisfireRunOnce, MisfireRunAll:
+	default:
+		return fmt.Errorf("unknown misfire policy %q (use skip, run_once or run_all)", job.MisfirePolicy)
+	}
+	if job.MisfireLimit < 0 {
+		return fmt.Errorf("misfire limit must not be negative")
+	}
+	if job.JitterSeconds < 0 || job.JitterSeconds > maxJitterSeconds {
+		return fmt.Errorf("jitter must be between 0 and %d seconds", maxJitterSeconds)
+	}
+	return nil
+}
+
+// DescribePolicies renders the effective policies of job for listings.
+func DescribePolicies(job storage.CronJob) string {
+	s := fmt.Sprintf("overlap=%s misfire=%s", concurrencyPolicy(job), misfirePolicy(job))
+	if misfirePolicy(job) == MisfireRunAll {
+		s += fmt.Sprintf(" (max %d)", misfireLimit(job))
+	}
+	if job.JitterSeconds > 0 {
+		s += fmt.Sprintf(" jitter=%ds", job.JitterSeconds)
+	}
+	if n := alertAfter(job); n > 0 {
+		s += fmt.Sprintf(" alert-after=%d", n)
+	} else {
+		s += " alert-after=never"
+	}
+	return s
+}
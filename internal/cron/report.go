@@ -14,7 +14,7 @@ type JobReport struct {
 	Expression string
 	Task       string
 	JobType    string // "llm" or "exec"
-	Status     string // "succeeded", "failed", "timed_out"
+	Status     string // "succeeded", "failed", "timed_out", "interrupted"
 	Summary    string // human-readable output or result
 	Error      string
 	Duration   time.Duration
@@ -30,6 +30,8 @@ func (r JobReport) FormatTelegram() string {
 		fmt.Fprintf(&b, "✅ *Schedule #%d completed*", r.CronJobID)
 	case "timed_out":
 		fmt.Fprintf(&b, "⏰ *Schedule #%d timed out*", r.CronJobID)
+	case "interrupted":
+		fmt.Fprintf(&b, "⚠️ *Schedule #%d interrupted by a restart*", r.CronJobID)
 	default:
 		fmt.Fprintf(&b, "❌ *Schedule #%d failed*", r.CronJobID)
 	}
@@ -0,0 +1,241 @@
+package cron
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"ok-gobot/internal/storage"
+)
+
+// maxMisfireScan bounds how many missed fire times catch-up walks through,
+// so an every-second job after a long outage stays cheap.
+const maxMisfireScan = 100000
+
+// pendingFire is a fire held back by the queue concurrency policy.
+type pendingFire struct {
+	scheduledAt time.Time
+	trigger     string
+	done        chan struct{}
+}
+
+// cronRun is a fire that passed the concurrency check.
+type cronRun struct {
+	job     storage.CronJob
+	id      int64 // cron_runs row; 0 when it could not be recorded
+	trigger string
+	done    chan struct{}
+}
+
+// fire runs one occurrence of a cron job: it re-reads the job so policy
+// changes apply without a restart, waits out the jitter, applies the
+// concurrency policy and records the run. done, if not nil, is closed when
+// the run ends or is not started. Manual fires run disabled jobs too.
+func (s *Scheduler) fire(id int64, scheduledAt time.Time, trigger string, done chan struct{}) {
+	job, err := s.store.GetCronJob(id)
+	if err != nil || job == nil || (!job.Enabled && trigger != TriggerManual) {
+		// Removed or disabled since it was scheduled.
+		closeDone(done)
+		return
+	}
+	if trigger == TriggerSchedule && job.JitterSeconds > 0 {
+		time.Sleep(time.Duration(rand.Int63n(int64(job.JitterSeconds) * int64(time.Second))))
+	}
+
+	start, skipReason := s.acquire(*job, scheduledAt, trigger, done)
+	if !start {
+		if skipReason != "" {
+			log.Printf("Cron job %d: skipping fire: %s", id, skipReason)
+			s.recordRun(storage.CronRun{
+				CronJobID:   id,
+				Trigger:     trigger,
+				Status:      storage.CronRunSkipped,
+				Error:       skipReason,
+				ScheduledAt: scheduledAt.UTC().Format(time.RFC3339),
+			})
+			closeDone(done)
+		}
+		return
+	}
+
+	log.Printf("Executing cron job %d (type=%s, %s): %s", job.ID, job.Type, trigger, job.Task)
+	run := &cronRun{
+		job:     *job,
+		trigger: trigger,
+		done:    done,
+		id: s.recordRun(storage.CronRun{
+			CronJobID:   id,
+			Trigger:     trigger,
+			Status:      storage.CronRunRunning,
+			ScheduledAt: scheduledAt.UTC().Format(time.RFC3339),
+		}),
+	}
+
+	timeout := defaultJobTimeout
+	if job.TimeoutSeconds > 0 {
+		timeout = time.Duration(job.TimeoutSeconds) * time.Second
+	}
+	if s.jobService != nil {
+		s.fireDurable(run, timeout)
+		return
+	}
+	status, errMsg := s.fireLegacy(*job, timeout)
+	s.finishRun(run, status, errMsg)
+}
+
+// acquire applies the concurrency policy. It reports whether the run may
+// start; a fire that is neither started nor skipped was queued.
+func (s *Scheduler) acquire(job storage.CronJob, scheduledAt time.Time, trigger string, done chan struct{}) (start bool, skipReason string) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if s.active[job.ID] > 0 {
+		switch concurrencyPolicy(job) {
+		case ConcurrencyAllow:
+		case ConcurrencyQueue:
+			if _, ok := s.queued[job.ID]; ok {
+				return false, "previous run still going and another is already queued"
+			}
+			s.queued[job.ID] = pendingFire{scheduledAt: scheduledAt, trigger: trigger, done: done}
+			return false, ""
+		default:
+			return false, "previous run still going"
+		}
+	}
+	s.active[job.ID]++
+	return true, ""
+}
+
+// release ends a run's slot and starts the fire queued behind it, if any.
+func (s *Scheduler) release(id int64) {
+	s.runMu.Lock()
+	if s.active[id] <= 1 {
+		delete(s.active, id)
+	} else {
+		s.active[id]--
+	}
+	next, ok := s.queued[id]
+	delete(s.queued, id)
+	s.runMu.Unlock()
+
+	if ok {
+		go s.fire(id, next.scheduledAt, next.trigger, next.done)
+	}
+}
+
+// finishRun records a run's outcome, alerts on repeated failures and
+// deletes one-shot jobs once their scheduled run is over.
+func (s *Scheduler) finishRun(run *cronRun, status, errMsg string) {
+	if run.id != 0 {
+		if err := s.store.FinishCronRun(run.id, status, errMsg); err != nil {
+			log.Printf("Cron job %d: failed to record run result: %v", run.job.ID, err)
+		}
+	}
+	s.release(run.job.ID)
+	if status == "failed" || status == "timed_out" {
+		s.checkFailures(run.job, errMsg)
+	}
+	if run.trigger != TriggerManual {
+		s.finishOneShot(run.job)
+	}
+	closeDone(run.done)
+}
+
+// checkFailures sends an alert when a job's failure streak reaches its
+// alert threshold.
+func (s *Scheduler) checkFailures(job storage.CronJob, errMsg string) {
+	threshold := alertAfter(job)
+	if threshold == 0 {
+		return
+	}
+	count, err := s.store.CountConsecutiveCronFailures(job.ID)
+	if err != nil || count != threshold {
+		return
+	}
+
+	msg := fmt.Sprintf("🚨 Schedule #%d has failed %d times in a row.\nTask: %s", job.ID, count, job.Task)
+	if errMsg != "" {
+		msg += "\nLast error: " + errMsg
+	}
+	msg += fmt.Sprintf("\n\nHistory: ok-gobot cron history %d", job.ID)
+	log.Printf("Cron job %d: %d consecutive failures", job.ID, count)
+	if s.notifier != nil && job.ChatID != 0 {
+		s.notifier(job.ChatID, msg)
+	}
+}
+
+// catchUp applies a job's misfire policy to the fires it missed since its
+// last recorded run. Jobs that never ran have nothing to catch up.
+func (s *Scheduler) catchUp(job storage.CronJob, now time.Time) {
+	if IsOneShot(job) {
+		return // one-shots fire late on their own
+	}
+	last, err := s.store.LastCronRunScheduledAt(job.ID)
+	if err != nil || last == "" {
+		return
+	}
+	since, err := time.Parse(time.RFC3339, last)
+	if err != nil {
+		return
+	}
+	schedule, err := parseSchedule(job)
+	if err != nil {
+		return
+	}
+
+	limit := misfireLimit(job)
+	var missed []time.Time
+	total := 0
+	for t := schedule.Next(since); !t.IsZero() && !t.After(now) && total < maxMisfireScan; t = schedule.Next(t) {
+		total++
+		missed = append(missed, t)
+		if len(missed) > limit {
+			missed = missed[1:]
+		}
+	}
+	if total == 0 {
+		return
+	}
+	latest := missed[len(missed)-1]
+
+	switch misfirePolicy(job) {
+	case MisfireRunOnce:
+		log.Printf("Cron job %d missed %d runs; running once to catch up", job.ID, total)
+		go s.fire(job.ID, latest, TriggerCatchUp, nil)
+	case MisfireRunAll:
+		log.Printf("Cron job %d missed %d runs; replaying the last %d", job.ID, total, len(missed))
+		go func() {
+			for _, at := range missed {
+				done := make(chan struct{})
+				s.fire(job.ID, at, TriggerCatchUp, done)
+				<-done
+			}
+		}()
+	default:
+		log.Printf("Cron job %d missed %d runs while the daemon was down", job.ID, total)
+		s.recordRun(storage.CronRun{
+			CronJobID:   job.ID,
+			Trigger:     TriggerCatchUp,
+			Status:      storage.CronRunMissed,
+			Error:       fmt.Sprintf("%d runs missed while the daemon was down", total),
+			ScheduledAt: latest.UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// recordRun stores a run row, logging instead of failing the fire.
+func (s *Scheduler) recordRun(run storage.CronRun) int64 {
+	id, err := s.store.CreateCronRun(run)
+	if err != nil {
+		log.Printf("Cron job %d: failed to record run: %v", run.CronJobID, err)
+		return 0
+	}
+	return id
+}
+
+func closeDone(done chan struct{}) {
+	if done != nil {
+		close(done)
+	}
+}
@@ -0,0 +1,278 @@
+package cron
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ok-gobot/internal/storage"
+)
+
+// waitForCronRuns polls until cond accepts the job's run history.
+func waitForCronRuns(t *testing.T, store *storage.Store, cronID int64, cond func([]storage.CronRun) bool) []storage.CronRun {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		runs, err := store.ListCronRuns(cronID, 50)
+		if err != nil {
+			t.Fatalf("ListCronRuns: %v", err)
+		}
+		if cond(runs) {
+			return runs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for cron runs, have %+v", runs)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func countRuns(runs []storage.CronRun, status string) int {
+	n := 0
+	for _, r := range runs {
+		if r.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+func TestFireConcurrencyPolicies(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []string{ConcurrencySkip, ConcurrencyQueue} {
+		policy := policy
+		t.Run(policy, func(t *testing.T) {
+			t.Parallel()
+
+			store := newTestStore(t)
+			defer store.Close() //nolint:errcheck
+			sched := NewScheduler(store, nil)
+
+			cronID, err := store.SaveCronJobSpec(storage.CronJob{
+				Expression:        "0 0 * * * *",
+				Task:              "sleep 1",
+				Type:              "exec",
+				ConcurrencyPolicy: policy,
+			})
+			if err != nil {
+				t.Fatalf("SaveCronJobSpec: %v", err)
+			}
+
+			now := time.Now().Truncate(time.Second)
+			first := make(chan struct{})
+			go sched.fire(cronID, now, TriggerSchedule, first)
+			waitForCronRuns(t, store, cronID, func(runs []storage.CronRun) bool {
+				return countRuns(runs, storage.CronRunRunning) == 1
+			})
+
+			second := make(chan struct{})
+			third := make(chan struct{})
+			sched.fire(cronID, now.Add(time.Second), TriggerSchedule, second)
+			sched.fire(cronID, now.Add(2*time.Second), TriggerSchedule, third)
+
+			// The third fire is always dropped: at most one waits in the queue.
+			<-third
+			<-first
+			<-second
+
+			runs := waitForCronRuns(t, store, cronID, func(runs []storage.CronRun) bool {
+				return countRuns(runs, storage.CronRunRunning) == 0
+			})
+			wantSucceeded, wantSkipped := 1, 2
+			if policy == ConcurrencyQueue {
+				wantSucceeded, wantSkipped = 2, 1
+			}
+			if countRuns(runs, "succeeded") != wantSucceeded || countRuns(runs, storage.CronRunSkipped) != wantSkipped {
+				t.Fatalf("policy %s: unexpected runs %+v", policy, runs)
+			}
+		})
+	}
+}
+
+func TestCatchUpMisfirePolicies(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().Truncate(time.Hour)
+	// The last run was due 5 hours ago, so five hourly fires were missed.
+	lastRun := now.Add(-5 * time.Hour)
+
+	cases := []struct {
+		policy   string
+		limit    int
+		wantRuns int // catch-up runs started
+	}{
+		{policy: MisfireSkip},
+		{policy: MisfireRunOnce, wantRuns: 1},
+		{policy: MisfireRunAll, limit: 3, wantRuns: 3},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.policy, func(t *testing.T) {
+			t.Parallel()
+
+			store := newTestStore(t)
+			defer store.Close() //nolint:errcheck
+			sched := NewScheduler(store, nil)
+
+			job := storage.CronJob{
+				Expression:    "0 0 * * * *",
+				Task:          "true",
+				Type:          "exec",
+				Timezone:      "UTC",
+				MisfirePolicy: tc.policy,
+				MisfireLimit:  tc.limit,
+			}
+			id, err := store.SaveCronJobSpec(job)
+			if err != nil {
+				t.Fatalf("SaveCronJobSpec: %v", err)
+			}
+			job.ID = id
+			runID, err := store.CreateCronRun(storage.CronRun{CronJobID: id, ScheduledAt: lastRun.UTC().Format(time.RFC3339)})
+			if err != nil {
+				t.Fatalf("CreateCronRun: %v", err)
+			}
+			if err := store.FinishCronRun(runID, "succeeded", ""); err != nil {
+				t.Fatalf("FinishCronRun: %v", err)
+			}
+
+			sched.catchUp(job, now)
+
+			if tc.policy == MisfireSkip {
+				runs := waitForCronRuns(t, store, id, func(runs []storage.CronRun) bool { return len(runs) == 2 })
+				if runs[0].Status != storage.CronRunMissed || !strings.Contains(runs[0].Error, "5 runs missed") {
+					t.Fatalf("unexpected missed run: %+v", runs[0])
+				}
+				return
+			}
+
+			runs := waitForCronRuns(t, store, id, func(runs []storage.CronRun) bool {
+				return countRuns(runs, "succeeded") == tc.wantRuns+1
+			})
+			var due []string
+			for _, r := range runs[:tc.wantRuns] {
+				if r.Trigger != TriggerCatchUp {
+					t.Fatalf("trigger = %q, want %s", r.Trigger, TriggerCatchUp)
+				}
+				due = append(due, r.ScheduledAt)
+			}
+			// Newest first; the most recent missed fire is always included.
+			if due[0] != now.UTC().Format(time.RFC3339) {
+				t.Fatalf("latest catch-up run due %s, want %s", due[0], now.UTC().Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestCatchUpIgnoresJobsWithoutHistory(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close() //nolint:errcheck
+	sched := NewScheduler(store, nil)
+
+	job := storage.CronJob{Expression: "* * * * * *", Task: "true", Type: "exec", MisfirePolicy: MisfireRunAll}
+	id, err := store.SaveCronJobSpec(job)
+	if err != nil {
+		t.Fatalf("SaveCronJobSpec: %v", err)
+	}
+	job.ID = id
+
+	sched.catchUp(job, time.Now())
+	time.Sleep(100 * time.Millisecond)
+	if runs, _ := store.ListCronRuns(id, 10); len(runs) != 0 {
+		t.Fatalf("expected no runs, got %+v", runs)
+	}
+}
+
+func TestFailureAlertAfterConsecutiveFailures(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close() //nolint:errcheck
+	sched := NewScheduler(store, nil)
+
+	var mu sync.Mutex
+	var alerts []string
+	sched.SetNotifier(func(chatID int64, msg string) {
+		if strings.Contains(msg, "in a row") {
+			mu.Lock()
+			alerts = append(alerts, msg)
+			mu.Unlock()
+		}
+	})
+
+	cronID, err := store.SaveCronJobSpec(storage.CronJob{
+		Expression: "0 0 * * * *",
+		Task:       "exit 3",
+		Type:       "exec",
+		ChatID:     42,
+		AlertAfter: 2,
+	})
+	if err != nil {
+		t.Fatalf("SaveCronJobSpec: %v", err)
+	}
+
+	// Alert once when the streak reaches the threshold, not on every
+	// failure after it.
+	for i := 0; i < 3; i++ {
+		done := make(chan struct{})
+		sched.fire(cronID, time.Now(), TriggerSchedule, done)
+		<-done
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1: %q", len(alerts), alerts)
+	}
+	if !strings.Contains(alerts[0], "failed 2 times in a row") || !strings.Contains(alerts[0], "exit status 3") {
+		t.Fatalf("unexpected alert: %s", alerts[0])
+	}
+}
+
+func TestToggleAndRunNow(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close() //nolint:errcheck
+	sched := NewScheduler(store, nil)
+
+	cronID, err := sched.AddExecJob("0 0 0 1 1 *", "echo manual", 0, 0)
+	if err != nil {
+		t.Fatalf("AddExecJob: %v", err)
+	}
+	if err := sched.ToggleJob(cronID, false); err != nil {
+		t.Fatalf("ToggleJob(false): %v", err)
+	}
+	if _, err := sched.GetNextRun(cronID); err == nil {
+		t.Error("disabled job is still scheduled")
+	}
+
+	// A manual run ignores the disabled flag and is recorded as such.
+	if err := sched.RunNow(cronID); err != nil {
+		t.Fatalf("RunNow: %v", err)
+	}
+	runs := waitForCronRuns(t, store, cronID, func(runs []storage.CronRun) bool {
+		return countRuns(runs, "succeeded") == 1
+	})
+	if runs[0].Trigger != TriggerManual {
+		t.Errorf("run trigger = %q, want %q", runs[0].Trigger, TriggerManual)
+	}
+
+	if err := sched.ToggleJob(cronID, true); err != nil {
+		t.Fatalf("ToggleJob(true): %v", err)
+	}
+	if _, err := sched.GetNextRun(cronID); err != nil {
+		t.Errorf("re-enabled job is not scheduled: %v", err)
+	}
+
+	if err := sched.RunNow(cronID + 100); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("RunNow(unknown) = %v, want ErrJobNotFound", err)
+	}
+	if err := sched.ToggleJob(cronID+100, true); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("ToggleJob(unknown) = %v, want ErrJobNotFound", err)
+	}
+}
@@ -0,0 +1,126 @@
+package cron
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"ok-gobot/internal/storage"
+)
+
+// lateOneShotDelay is how long after startup a one-shot job whose time
+// passed while the daemon was down is fired.
+const lateOneShotDelay = 10 * time.Second
+
+// specParser matches the parser cron.WithSeconds installs: six fields,
+// descriptors such as @daily, and CRON_TZ= prefixes.
+var specParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// onceSchedule fires a single time.
+type onceSchedule struct {
+	at time.Time
+}
+
+func (o onceSchedule) Next(t time.Time) time.Time {
+	if t.Before(o.at) {
+		return o.at
+	}
+	return time.Time{} // never again
+}
+
+// LoadLocation resolves an IANA zone name such as "Europe/Berlin". An empty
+// name is the server's local time.
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// SplitTimezone separates a leading "CRON_TZ=<zone>" (or "TZ=<zone>") from a
+// cron expression.
+func SplitTimezone(expression string) (timezone, rest string) {
+	expression = strings.TrimSpace(expression)
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if after, ok := strings.CutPrefix(expression, prefix); ok {
+			zone, rest, _ := strings.Cut(after, " ")
+			return zone, strings.TrimSpace(rest)
+		}
+	}
+	return "", expression
+}
+
+// IsOneShot reports whether job fires once at RunAt rather than on a
+// recurring expression.
+func IsOneShot(job storage.CronJob) bool {
+	return job.RunAt != ""
+}
+
+// parseSchedule builds the schedule a stored job fires on.
+func parseSchedule(job storage.CronJob) (cron.Schedule, error) {
+	if IsOneShot(job) {
+		at, err := time.Parse(time.RFC3339, job.RunAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid run time %q: %w", job.RunAt, err)
+		}
+		return onceSchedule{at: at}, nil
+	}
+	spec := job.Expression
+	if job.Timezone != "" {
+		if _, err := LoadLocation(job.Timezone); err != nil {
+			return nil, err
+		}
+		spec = "CRON_TZ=" + job.Timezone + " " + spec
+	}
+	schedule, err := specParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %w", err)
+	}
+	return schedule, nil
+}
+
+// NextRun computes when job fires next after now without a running
+// scheduler, e.g. for `ok-gobot cron list`. One-shot jobs report their run
+// time even when it has passed.
+func NextRun(job storage.CronJob, now time.Time) (time.Time, error) {
+	schedule, err := parseSchedule(job)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if once, ok := schedule.(onceSchedule); ok {
+		return once.at, nil
+	}
+	return schedule.Next(now), nil
+}
+
+// JobLocation is the location job's times are shown in.
+func JobLocation(job storage.CronJob) *time.Location {
+	loc, err := LoadLocation(job.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// DescribeSchedule renders a job's schedule for listings: the expression
+// with its timezone, or "once at …" for one-shot jobs.
+func DescribeSchedule(job storage.CronJob) string {
+	if IsOneShot(job) {
+		at, err := time.Parse(time.RFC3339, job.RunAt)
+		if err != nil {
+			return "once at " + job.RunAt
+		}
+		return "once at " + at.In(JobLocation(job)).Format("2006-01-02 15:04 MST")
+	}
+	if job.Timezone != "" {
+		return job.Expression + " (" + job.Timezone + ")"
+	}
+	return job.Expression
+}
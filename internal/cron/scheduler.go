@@ -2,6 +2,7 @@ package cron
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os/exec"
@@ -17,6 +18,9 @@ import (
 
 const defaultJobTimeout = 15 * time.Minute
 
+// ErrJobNotFound is returned for a cron job ID that does not exist.
+var ErrJobNotFound = errors.New("cron job not found")
+
 // JobExecutor is called when an LLM-type cron job fires.
 type JobExecutor func(ctx context.Context, job storage.CronJob) error
 
@@ -38,6 +42,12 @@ type Scheduler struct {
 	jobs       map[int64]cron.EntryID
 	mu         sync.RWMutex
 	running    bool
+
+	// runMu guards active and queued. It is separate from mu because Stop
+	// holds mu while waiting for in-flight fires to return.
+	runMu  sync.Mutex
+	active map[int64]int         // runs in progress per cron job
+	queued map[int64]pendingFire // fire held back by the queue policy
 }
 
 // NewScheduler creates a new cron scheduler.
@@ -47,10 +57,13 @@ func NewScheduler(store *storage.Store, executor JobExecutor) *Scheduler {
 		store:    store,
 		executor: executor,
 		jobs:     make(map[int64]cron.EntryID),
+		active:   make(map[int64]int),
+		queued:   make(map[int64]pendingFire),
 	}
 }
 
-// SetNotifier sets the callback for legacy exec-type job result delivery.
+// SetNotifier sets the callback for plain-text messages: legacy exec-type
+// job results and repeated-failure alerts.
 func (s *Scheduler) SetNotifier(n ExecResultNotifier) {
 	s.notifier = n
 }
@@ -58,6 +71,12 @@ func (s *Scheduler) SetNotifier(n ExecResultNotifier) {
 // SetJobService enables durable job tracking for every cron fire.
 func (s *Scheduler) SetJobService(js *runtime.JobService) {
 	s.jobService = js
+	if js != nil {
+		// The next scheduled fire replaces a run interrupted by a restart;
+		// the factory only serves manual retries.
+		js.RegisterKind("cron_exec", runtime.ResumeAbandon, s.rebuildRunner)
+		js.RegisterKind("cron_llm", runtime.ResumeAbandon, s.rebuildRunner)
+	}
 }
 
 // SetReportDeliverer sets the callback for standardized report delivery.
@@ -108,17 +127,27 @@ func (s *Scheduler) Stop() {
 	log.Println("Cron scheduler stopped")
 }
 
-// loadJobs loads all enabled jobs from the database.
+// loadJobs loads all enabled jobs from the database and applies their
+// misfire policies to runs missed while the daemon was down.
 func (s *Scheduler) loadJobs() error {
 	jobs, err := s.store.GetCronJobs()
 	if err != nil {
 		return err
 	}
 
+	if n, err := s.store.InterruptCronRuns(); err != nil {
+		log.Printf("Failed to mark interrupted cron runs: %v", err)
+	} else if n > 0 {
+		log.Printf("Marked %d cron runs interrupted by the last shutdown", n)
+	}
+
+	now := time.Now()
 	for _, job := range jobs {
 		if err := s.scheduleJob(job); err != nil {
 			log.Printf("Failed to schedule job %d: %v", job.ID, err)
+			continue
 		}
+		s.catchUp(job, now)
 	}
 
 	log.Printf("Loaded %d cron jobs", len(jobs))
@@ -135,72 +164,112 @@ func (s *Scheduler) scheduleJob(job storage.CronJob) error {
 		s.cron.Remove(entryID)
 	}
 
-	// Create the cron function
-	jobCopy := job // Capture for closure
-	timeout := defaultJobTimeout
-	if jobCopy.TimeoutSeconds > 0 {
-		timeout = time.Duration(jobCopy.TimeoutSeconds) * time.Second
-	}
-
-	entryID, err := s.cron.AddFunc(job.Expression, func() {
-		log.Printf("Executing cron job %d (type=%s): %s", jobCopy.ID, jobCopy.Type, jobCopy.Task)
-
-		if s.jobService != nil {
-			s.fireDurable(jobCopy, timeout)
-		} else {
-			s.fireLegacy(jobCopy, timeout)
-		}
-	})
-
+	schedule, err := parseSchedule(job)
 	if err != nil {
-		return fmt.Errorf("invalid cron expression: %w", err)
+		return err
 	}
+	if once, ok := schedule.(onceSchedule); ok && !once.at.After(time.Now()) {
+		// Due while the daemon was down: fire shortly after startup.
+		log.Printf("One-shot cron job %d was due at %s; firing late", job.ID, once.at.Format(time.RFC3339))
+		schedule = onceSchedule{at: time.Now().Add(lateOneShotDelay)}
+	}
+
+	id := job.ID
+	entryID := s.cron.Schedule(schedule, cron.FuncJob(func() {
+		s.fire(id, time.Now().Truncate(time.Second), TriggerSchedule, nil)
+	}))
 
 	s.jobs[job.ID] = entryID
 	return nil
 }
 
+// finishOneShot deletes a one-shot job once its single run is over.
+func (s *Scheduler) finishOneShot(job storage.CronJob) {
+	if !IsOneShot(job) {
+		return
+	}
+	if err := s.RemoveJob(job.ID); err != nil {
+		log.Printf("Failed to delete finished one-shot cron job %d: %v", job.ID, err)
+	}
+}
+
 // fireDurable creates a durable runtime.Job for the cron fire and delivers a
 // standardized report on completion.
-func (s *Scheduler) fireDurable(cronJob storage.CronJob, timeout time.Duration) {
+func (s *Scheduler) fireDurable(run *cronRun, timeout time.Duration) {
+	cronJob := run.job
 	kind := "cron_exec"
 	if cronJob.Type != "exec" {
 		kind = "cron_llm"
 	}
 
-	runner := func(ctx context.Context, job *storage.Job, svc *runtime.JobService) (runtime.JobRunResult, error) {
-		if cronJob.Type == "exec" {
-			return s.runExec(ctx, cronJob)
-		}
-		return s.runLLM(ctx, cronJob)
-	}
-
 	start := time.Now()
 	job, err := s.jobService.StartDetached(context.Background(), runtime.JobSpec{
 		Kind:        kind,
 		Worker:      "cron_scheduler",
+		SessionKey:  cronSessionKey(cronJob.ID),
+		Priority:    runtime.JobPriorityCron,
 		Description: fmt.Sprintf("schedule #%d: %s", cronJob.ID, cronJob.Task),
 		Timeout:     timeout,
-	}, runner)
+	}, s.durableRunner(cronJob))
 
 	if err != nil {
 		log.Printf("Cron job %d: failed to create durable job: %v", cronJob.ID, err)
 		// Fall back to legacy execution on job creation failure
-		s.fireLegacy(cronJob, timeout)
+		status, errMsg := s.fireLegacy(cronJob, timeout)
+		s.finishRun(run, status, errMsg)
 		return
 	}
+	if run.id != 0 {
+		if err := s.store.SetCronRunJob(run.id, job.JobID); err != nil {
+			log.Printf("Cron job %d: failed to link run to job %s: %v", cronJob.ID, job.JobID, err)
+		}
+	}
 
 	// Wait for the durable job to reach a terminal state, then deliver the report.
-	go s.waitAndDeliver(cronJob, job.JobID, start)
+	go s.waitAndDeliver(run, job.JobID, start)
+}
+
+func (s *Scheduler) durableRunner(cronJob storage.CronJob) runtime.JobRunner {
+	return func(ctx context.Context, job *storage.Job, svc *runtime.JobService) (runtime.JobRunResult, error) {
+		if cronJob.Type == "exec" {
+			return s.runExec(ctx, cronJob)
+		}
+		return s.runLLM(ctx, cronJob)
+	}
+}
+
+func cronSessionKey(id int64) string {
+	return fmt.Sprintf("cron:%d", id)
+}
+
+// rebuildRunner recreates the runner of a durable cron job from the schedule
+// it was fired for.
+func (s *Scheduler) rebuildRunner(job *storage.Job) (runtime.JobRunner, error) {
+	var id int64
+	if _, err := fmt.Sscanf(job.SessionKey, "cron:%d", &id); err != nil {
+		return nil, fmt.Errorf("job %s is not linked to a schedule", job.JobID)
+	}
+	jobs, err := s.store.GetCronJobs()
+	if err != nil {
+		return nil, err
+	}
+	for _, cronJob := range jobs {
+		if cronJob.ID == id {
+			return s.durableRunner(cronJob), nil
+		}
+	}
+	return nil, fmt.Errorf("schedule #%d is disabled or no longer exists", id)
 }
 
 // waitAndDeliver polls for the durable job to complete and delivers a report.
-func (s *Scheduler) waitAndDeliver(cronJob storage.CronJob, jobID string, start time.Time) {
+func (s *Scheduler) waitAndDeliver(run *cronRun, jobID string, start time.Time) {
+	cronJob := run.job
 	var finished *storage.Job
 	for {
 		j, err := s.store.GetJob(jobID)
 		if err != nil {
 			log.Printf("Cron job %d: failed to poll durable job %s: %v", cronJob.ID, jobID, err)
+			s.finishRun(run, "failed", err.Error())
 			return
 		}
 		if j != nil && isTerminal(j.Status) {
@@ -212,7 +281,7 @@ func (s *Scheduler) waitAndDeliver(cronJob storage.CronJob, jobID string, start
 
 	report := JobReport{
 		CronJobID:  cronJob.ID,
-		Expression: cronJob.Expression,
+		Expression: DescribeSchedule(cronJob),
 		Task:       cronJob.Task,
 		JobType:    cronJob.Type,
 		Status:     finished.Status,
@@ -227,21 +296,33 @@ func (s *Scheduler) waitAndDeliver(cronJob storage.CronJob, jobID string, start
 	}
 
 	s.deliver(cronJob.ChatID, report)
+	s.finishRun(run, finished.Status, finished.Error)
 }
 
-// fireLegacy executes the cron job directly without durable job tracking.
-func (s *Scheduler) fireLegacy(cronJob storage.CronJob, timeout time.Duration) {
+// fireLegacy executes the cron job directly without durable job tracking and
+// returns the run's final status.
+func (s *Scheduler) fireLegacy(cronJob storage.CronJob, timeout time.Duration) (status, errMsg string) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	var err error
 	if cronJob.Type == "exec" {
-		s.executeExecJob(ctx, cronJob)
-	} else {
-		if s.executor != nil {
-			if err := s.executor(ctx, cronJob); err != nil {
-				log.Printf("Cron job %d failed: %v", cronJob.ID, err)
-			}
+		err = s.executeExecJob(ctx, cronJob)
+	} else if s.executor != nil {
+		if err = s.executor(ctx, cronJob); err != nil {
+			log.Printf("Cron job %d failed: %v", cronJob.ID, err)
 		}
+	} else {
+		err = fmt.Errorf("no LLM executor configured")
+	}
+
+	switch {
+	case err == nil:
+		return "succeeded", ""
+	case ctx.Err() == context.DeadlineExceeded:
+		return "timed_out", err.Error()
+	default:
+		return "failed", err.Error()
 	}
 }
 
@@ -305,7 +386,7 @@ func (s *Scheduler) deliver(chatID int64, report JobReport) {
 }
 
 // executeExecJob runs a shell command directly without LLM (legacy path).
-func (s *Scheduler) executeExecJob(ctx context.Context, job storage.CronJob) {
+func (s *Scheduler) executeExecJob(ctx context.Context, job storage.CronJob) error {
 	cmd := exec.CommandContext(ctx, "bash", "-c", job.Task)
 	output, err := cmd.CombinedOutput()
 
@@ -319,7 +400,7 @@ func (s *Scheduler) executeExecJob(ctx context.Context, job storage.CronJob) {
 			}
 			s.notifier(job.ChatID, msg)
 		}
-		return
+		return fmt.Errorf("exec failed: %w", err)
 	}
 
 	log.Printf("Cron exec job %d completed. Output: %d bytes", job.ID, len(result))
@@ -330,63 +411,57 @@ func (s *Scheduler) executeExecJob(ctx context.Context, job storage.CronJob) {
 		}
 		s.notifier(job.ChatID, msg)
 	}
+	return nil
 }
 
 // AddJob creates and schedules a new job.
 func (s *Scheduler) AddJob(expression, task string, chatID int64) (int64, error) {
-	// Validate cron expression
-	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	if _, err := parser.Parse(expression); err != nil {
-		return 0, fmt.Errorf("invalid cron expression: %w", err)
-	}
-
-	// Save to database
-	jobID, err := s.store.SaveCronJob(expression, task, chatID)
-	if err != nil {
-		return 0, fmt.Errorf("failed to save job: %w", err)
-	}
-
-	// Schedule the job
-	job := storage.CronJob{
-		ID:         jobID,
+	return s.AddScheduledJob(storage.CronJob{
 		Expression: expression,
 		Task:       task,
 		ChatID:     chatID,
-		Enabled:    true,
-	}
-
-	if err := s.scheduleJob(job); err != nil {
-		// Clean up on failure
-		s.store.DeleteCronJob(jobID)
-		return 0, err
-	}
-
-	return jobID, nil
+	})
 }
 
 // AddExecJob creates and schedules a new exec-type job.
 func (s *Scheduler) AddExecJob(expression, task string, chatID int64, timeoutSeconds int) (int64, error) {
-	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	if _, err := parser.Parse(expression); err != nil {
-		return 0, fmt.Errorf("invalid cron expression: %w", err)
-	}
-
-	jobID, err := s.store.SaveCronJobFull(expression, task, chatID, "exec", timeoutSeconds)
-	if err != nil {
-		return 0, fmt.Errorf("failed to save job: %w", err)
-	}
-
-	job := storage.CronJob{
-		ID:             jobID,
+	return s.AddScheduledJob(storage.CronJob{
 		Expression:     expression,
 		Task:           task,
 		ChatID:         chatID,
-		Enabled:        true,
 		Type:           "exec",
 		TimeoutSeconds: timeoutSeconds,
+	})
+}
+
+// AddScheduledJob validates, saves and schedules job. A job with RunAt set
+// fires once and is deleted after it finishes; otherwise Expression is a
+// cron expression, evaluated in Timezone (or a CRON_TZ= prefix on it).
+func (s *Scheduler) AddScheduledJob(job storage.CronJob) (int64, error) {
+	if !IsOneShot(job) {
+		if tz, rest := SplitTimezone(job.Expression); tz != "" {
+			job.Timezone, job.Expression = tz, rest
+		}
+	}
+	if _, err := parseSchedule(job); err != nil {
+		return 0, err
+	}
+	if err := ValidatePolicies(job); err != nil {
+		return 0, err
 	}
 
+	jobID, err := s.store.SaveCronJobSpec(job)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save job: %w", err)
+	}
+
+	job.ID = jobID
+	job.Enabled = true
+	if job.Type == "" {
+		job.Type = "llm"
+	}
 	if err := s.scheduleJob(job); err != nil {
+		// Clean up on failure
 		s.store.DeleteCronJob(jobID)
 		return 0, err
 	}
@@ -394,6 +469,28 @@ func (s *Scheduler) AddExecJob(expression, task string, chatID int64, timeoutSec
 	return jobID, nil
 }
 
+// ChatLocation returns the timezone set for a chat with SetChatTimezone, or
+// the server's local time.
+func (s *Scheduler) ChatLocation(chatID int64) *time.Location {
+	if chatID != 0 {
+		if name, _ := s.store.GetSessionOption(chatID, "timezone"); name != "" {
+			if loc, err := LoadLocation(name); err == nil {
+				return loc
+			}
+		}
+	}
+	return time.Local
+}
+
+// SetChatTimezone stores the IANA timezone a chat schedules in.
+func (s *Scheduler) SetChatTimezone(chatID int64, name string) error {
+	name = strings.TrimSpace(name)
+	if _, err := LoadLocation(name); err != nil {
+		return err
+	}
+	return s.store.SetSessionOption(chatID, "timezone", name)
+}
+
 // RemoveJob removes a scheduled job.
 func (s *Scheduler) RemoveJob(jobID int64) error {
 	s.mu.Lock()
@@ -408,32 +505,43 @@ func (s *Scheduler) RemoveJob(jobID int64) error {
 
 // ToggleJob enables or disables a job.
 func (s *Scheduler) ToggleJob(jobID int64, enabled bool) error {
+	job, err := s.store.GetCronJob(jobID)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return ErrJobNotFound
+	}
 	if err := s.store.ToggleCronJob(jobID, enabled); err != nil {
 		return err
 	}
 
+	if enabled {
+		return s.scheduleJob(*job)
+	}
+
+	// Remove from scheduler
 	s.mu.Lock()
 	defer s.mu.Unlock()
-
-	if !enabled {
-		// Remove from scheduler
-		if entryID, ok := s.jobs[jobID]; ok {
-			s.cron.Remove(entryID)
-			delete(s.jobs, jobID)
-		}
-	} else {
-		// Reload job
-		jobs, err := s.store.GetCronJobs()
-		if err != nil {
-			return err
-		}
-		for _, job := range jobs {
-			if job.ID == jobID {
-				return s.scheduleJob(job)
-			}
-		}
+	if entryID, ok := s.jobs[jobID]; ok {
+		s.cron.Remove(entryID)
+		delete(s.jobs, jobID)
 	}
+	return nil
+}
 
+// RunNow fires a job once in the background, outside its schedule and
+// whether or not it is enabled. The job's concurrency policy applies and
+// the run is recorded with TriggerManual.
+func (s *Scheduler) RunNow(jobID int64) error {
+	job, err := s.store.GetCronJob(jobID)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return ErrJobNotFound
+	}
+	go s.fire(jobID, time.Now().Truncate(time.Second), TriggerManual, nil)
 	return nil
 }
 
@@ -458,7 +566,7 @@ func (s *Scheduler) GetNextRun(jobID int64) (time.Time, error) {
 
 func isTerminal(status string) bool {
 	switch status {
-	case "succeeded", "failed", "cancelled", "timed_out":
+	case "succeeded", "failed", "cancelled", "timed_out", "interrupted":
 		return true
 	}
 	return false
@@ -3,6 +3,7 @@ package cron
 import (
 	"context"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -246,3 +247,111 @@ func TestSchedulerLegacyFallback(t *testing.T) {
 		t.Fatal("expected legacy notifier to have been called")
 	}
 }
+
+func TestSchedulerOneShotRunsOnceAndIsDeleted(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	reports := make(chan JobReport, 4)
+	sched := NewScheduler(store, nil)
+	sched.SetJobService(runtime.NewJobService(store))
+	sched.SetReportDeliverer(func(chatID int64, report JobReport) {
+		reports <- report
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := sched.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer sched.Stop()
+
+	runAt := time.Now().Add(time.Second).Truncate(time.Second).Add(time.Second)
+	cronID, err := sched.AddScheduledJob(storage.CronJob{
+		Task:     "echo once",
+		ChatID:   42,
+		Type:     "exec",
+		RunAt:    runAt.UTC().Format(time.RFC3339),
+		Timezone: "UTC",
+	})
+	if err != nil {
+		t.Fatalf("AddScheduledJob failed: %v", err)
+	}
+	if next, err := sched.GetNextRun(cronID); err != nil || !next.Equal(runAt) {
+		t.Fatalf("GetNextRun = %s, %v; want %s", next, err, runAt)
+	}
+
+	select {
+	case r := <-reports:
+		if r.CronJobID != cronID || r.Status != "succeeded" || r.Summary != "once" {
+			t.Fatalf("unexpected report: %+v", r)
+		}
+		if !strings.HasPrefix(r.Expression, "once at ") {
+			t.Errorf("report schedule = %q", r.Expression)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("one-shot job did not run")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		jobs, err := store.ListCronJobs()
+		if err != nil {
+			t.Fatalf("ListCronJobs failed: %v", err)
+		}
+		if len(jobs) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("one-shot job was not deleted: %+v", jobs)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if _, err := sched.GetNextRun(cronID); err == nil {
+		t.Error("deleted one-shot job is still scheduled")
+	}
+}
+
+func TestSchedulerTimezones(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	sched := NewScheduler(store, nil)
+
+	id, err := sched.AddJob("CRON_TZ=Asia/Tokyo 0 0 9 * * *", "morning report", 7)
+	if err != nil {
+		t.Fatalf("AddJob failed: %v", err)
+	}
+	jobs, _ := store.GetCronJobs()
+	if len(jobs) != 1 || jobs[0].ID != id || jobs[0].Timezone != "Asia/Tokyo" || jobs[0].Expression != "0 0 9 * * *" {
+		t.Fatalf("unexpected stored job: %+v", jobs)
+	}
+	next, err := NextRun(jobs[0], time.Now())
+	if err != nil {
+		t.Fatalf("NextRun failed: %v", err)
+	}
+	if local := next.In(JobLocation(jobs[0])); local.Hour() != 9 || local.Minute() != 0 {
+		t.Fatalf("next run %s is not 09:00 in Tokyo", local)
+	}
+
+	if _, err := sched.AddScheduledJob(storage.CronJob{Expression: "0 0 9 * * *", Task: "x", Timezone: "Mars/Olympus"}); err == nil {
+		t.Fatal("expected an unknown timezone to be rejected")
+	}
+
+	if loc := sched.ChatLocation(7); loc != time.Local {
+		t.Fatalf("default chat location = %s, want Local", loc)
+	}
+	if err := sched.SetChatTimezone(7, "Nowhere/Land"); err == nil {
+		t.Fatal("expected SetChatTimezone to reject an unknown zone")
+	}
+	if err := sched.SetChatTimezone(7, "America/New_York"); err != nil {
+		t.Fatalf("SetChatTimezone failed: %v", err)
+	}
+	if loc := sched.ChatLocation(7); loc.String() != "America/New_York" {
+		t.Fatalf("chat location = %s", loc)
+	}
+}
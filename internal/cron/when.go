@@ -0,0 +1,246 @@
+package cron
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultClock is the time of day used when only a day is given
+// ("tomorrow", "next friday").
+const defaultClock = 9 * time.Hour
+
+var (
+	relativePart = regexp.MustCompile(`^(\d+|an?|one)\s*([a-z]+)`)
+	clockPattern = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?(am|pm)?$`)
+)
+
+var relativeUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var namedClocks = map[string]time.Duration{
+	"morning":   9 * time.Hour,
+	"noon":      12 * time.Hour,
+	"afternoon": 15 * time.Hour,
+	"evening":   18 * time.Hour,
+	"tonight":   20 * time.Hour,
+	"night":     20 * time.Hour,
+	"midnight":  0,
+}
+
+var absoluteLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseWhen turns a natural-language time into an absolute time in now's
+// location. It understands
+//
+//	in 2h, in 90 minutes, in 1 day and 3 hours
+//	tomorrow, tomorrow at 9, today 18:30, tonight, friday 5pm
+//	next monday 14:00 (never today), at 17:00 (today, or tomorrow if passed)
+//	2026-03-01 09:30, 2026-03-01 (at 09:00), RFC 3339
+//
+// A day without a time means 09:00. Times in the past are rejected.
+func ParseWhen(input string, now time.Time) (time.Time, error) {
+	s := strings.ToLower(strings.TrimSpace(input))
+	s = strings.TrimRight(s, ".!")
+	if s == "" {
+		return time.Time{}, fmt.Errorf("time is required")
+	}
+
+	if rest, ok := strings.CutPrefix(s, "in "); ok {
+		d, err := parseRelative(rest)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+	}
+
+	for _, layout := range absoluteLayouts {
+		t, err := time.ParseInLocation(layout, strings.TrimSpace(input), now.Location())
+		if err != nil {
+			continue
+		}
+		if layout == "2006-01-02" {
+			t = onDay(t, 0, defaultClock)
+		}
+		if !t.After(now) {
+			return time.Time{}, fmt.Errorf("%s is in the past", t.Format("2006-01-02 15:04 MST"))
+		}
+		return t, nil
+	}
+
+	return parseDayTime(s, now)
+}
+
+// parseRelative parses "2h", "90 minutes", "1 day and 3 hours", "an hour".
+func parseRelative(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(strings.ReplaceAll(s, " ", "")); err == nil && d > 0 {
+		return d, nil
+	}
+	var total time.Duration
+	rest := s
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		rest = strings.TrimPrefix(rest, "and ")
+		if rest == "" {
+			break
+		}
+		m := relativePart.FindStringSubmatch(rest)
+		if m == nil {
+			return 0, fmt.Errorf("cannot understand duration %q", s)
+		}
+		unit, ok := relativeUnits[m[2]]
+		if !ok {
+			return 0, fmt.Errorf("unknown time unit %q", m[2])
+		}
+		n := 1
+		if v, err := strconv.Atoi(m[1]); err == nil {
+			n = v
+		}
+		total += time.Duration(n) * unit
+		rest = rest[len(m[0]):]
+	}
+	if total <= 0 {
+		return 0, fmt.Errorf("duration must be positive")
+	}
+	return total, nil
+}
+
+// parseDayTime parses a day word and/or a clock time in any order.
+func parseDayTime(s string, now time.Time) (time.Time, error) {
+	var (
+		dayOffset  = -1 // days from today; -1 = not given
+		weekday    = time.Weekday(-1)
+		strictNext bool
+		clock      = time.Duration(-1)
+	)
+
+	tokens := strings.Fields(strings.ReplaceAll(s, ",", " "))
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok == "at" || tok == "on" || tok == "this":
+		case tok == "next":
+			strictNext = true
+		case tok == "today":
+			dayOffset = 0
+		case tok == "tomorrow":
+			dayOffset = 1
+		case tok == "tonight":
+			dayOffset = 0
+			if clock < 0 {
+				clock = namedClocks[tok]
+			}
+		default:
+			if wd, ok := weekdays[tok]; ok {
+				weekday = wd
+				continue
+			}
+			if c, ok := namedClocks[tok]; ok {
+				clock = c
+				continue
+			}
+			// "9 am" arrives as two tokens.
+			if i+1 < len(tokens) && (tokens[i+1] == "am" || tokens[i+1] == "pm") {
+				tok += tokens[i+1]
+				i++
+			}
+			c, err := parseClock(tok)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("cannot understand time %q", s)
+			}
+			clock = c
+		}
+	}
+
+	if dayOffset < 0 && weekday < 0 && clock < 0 {
+		return time.Time{}, fmt.Errorf("cannot understand time %q", s)
+	}
+	if strictNext && weekday < 0 {
+		return time.Time{}, fmt.Errorf("cannot understand time %q: say which day after \"next\"", s)
+	}
+
+	if clock < 0 {
+		clock = defaultClock
+	}
+	at := func(offset int) time.Time { return onDay(now, offset, clock) }
+
+	var t time.Time
+	switch {
+	case weekday >= 0:
+		ahead := (int(weekday) - int(now.Weekday()) + 7) % 7
+		if ahead == 0 && (strictNext || !at(0).After(now)) {
+			ahead = 7
+		}
+		t = at(ahead)
+	case dayOffset >= 0:
+		t = at(dayOffset)
+	default:
+		t = at(0)
+		if !t.After(now) {
+			t = at(1)
+		}
+	}
+
+	if !t.After(now) {
+		return time.Time{}, fmt.Errorf("%s is in the past", t.Format("2006-01-02 15:04 MST"))
+	}
+	return t, nil
+}
+
+// onDay returns the wall-clock time clock on the day offset days after t,
+// in t's location.
+func onDay(t time.Time, offset int, clock time.Duration) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+offset, int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, t.Location())
+}
+
+// parseClock parses "9", "9am", "9:30", "9.30pm" and "14:00".
+func parseClock(s string) (time.Duration, error) {
+	m := clockPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	switch m[3] {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		hour %= 12
+		if m[3] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
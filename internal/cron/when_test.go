@@ -0,0 +1,75 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWhen(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("zone data unavailable: %v", err)
+	}
+	// Wednesday 2026-03-04 10:30 in Berlin.
+	now := time.Date(2026, 3, 4, 10, 30, 0, 0, berlin)
+
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"in 2h", now.Add(2 * time.Hour)},
+		{"in 90 minutes", now.Add(90 * time.Minute)},
+		{"in 1 day and 3 hours", now.Add(27 * time.Hour)},
+		{"in an hour", now.Add(time.Hour)},
+		{"in 1h30m", now.Add(90 * time.Minute)},
+		{"tomorrow", time.Date(2026, 3, 5, 9, 0, 0, 0, berlin)},
+		{"tomorrow at 9pm", time.Date(2026, 3, 5, 21, 0, 0, 0, berlin)},
+		{"Tomorrow 7:45 am", time.Date(2026, 3, 5, 7, 45, 0, 0, berlin)},
+		{"today 18:30", time.Date(2026, 3, 4, 18, 30, 0, 0, berlin)},
+		{"tonight", time.Date(2026, 3, 4, 20, 0, 0, 0, berlin)},
+		{"at 17:00", time.Date(2026, 3, 4, 17, 0, 0, 0, berlin)},
+		{"9:00", time.Date(2026, 3, 5, 9, 0, 0, 0, berlin)}, // passed today
+		{"friday 14:00", time.Date(2026, 3, 6, 14, 0, 0, 0, berlin)},
+		{"next Friday 14:00", time.Date(2026, 3, 6, 14, 0, 0, 0, berlin)},
+		{"wednesday noon", time.Date(2026, 3, 4, 12, 0, 0, 0, berlin)},
+		{"next wednesday", time.Date(2026, 3, 11, 9, 0, 0, 0, berlin)},
+		{"wed 10:00", time.Date(2026, 3, 11, 10, 0, 0, 0, berlin)}, // passed today
+		{"on monday morning", time.Date(2026, 3, 9, 9, 0, 0, 0, berlin)},
+		{"2026-03-10 08:15", time.Date(2026, 3, 10, 8, 15, 0, 0, berlin)},
+		{"2026-03-10", time.Date(2026, 3, 10, 9, 0, 0, 0, berlin)},
+		{"2026-03-10T08:15:00Z", time.Date(2026, 3, 10, 8, 15, 0, 0, time.UTC)},
+		// DST starts 2026-03-29 in Berlin; wall-clock time is kept.
+		{"2026-03-29", time.Date(2026, 3, 29, 9, 0, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		got, err := ParseWhen(tt.input, now)
+		if err != nil {
+			t.Errorf("ParseWhen(%q) error: %v", tt.input, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseWhen(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"", "someday", "in 3 fortnights", "tomorrow at 25:00", "13pm", "next", "yesterday", "today 08:00", "2020-01-01"} {
+		if got, err := ParseWhen(input, now); err == nil {
+			t.Errorf("ParseWhen(%q) = %s, want error", input, got)
+		}
+	}
+}
+
+func TestSplitTimezone(t *testing.T) {
+	tz, rest := SplitTimezone("CRON_TZ=Asia/Tokyo 0 0 9 * * *")
+	if tz != "Asia/Tokyo" || rest != "0 0 9 * * *" {
+		t.Fatalf("SplitTimezone = %q, %q", tz, rest)
+	}
+	tz, rest = SplitTimezone("0 9 * * *")
+	if tz != "" || rest != "0 9 * * *" {
+		t.Fatalf("SplitTimezone without prefix = %q, %q", tz, rest)
+	}
+	if _, err := LoadLocation("Mars/Olympus"); err == nil || !strings.Contains(err.Error(), "unknown timezone") {
+		t.Fatalf("LoadLocation accepted an unknown zone: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"strings"
 )
 
@@ -123,6 +124,19 @@ func (m *MemoryManager) Recall(ctx context.Context, query string, topK int) ([]M
 	return m.Search(ctx, query, topK)
 }
 
+// IndexFile indexes one markdown file under rootPath immediately, so files
+// written by tools are searchable without waiting for a reindex.
+func (m *MemoryManager) IndexFile(ctx context.Context, rootPath, absPath string) error {
+	if m.client == nil || m.store == nil {
+		return fmt.Errorf("memory manager is not fully configured")
+	}
+	relativePath, err := filepath.Rel(rootPath, absPath)
+	if err != nil {
+		return fmt.Errorf("resolve memory source path: %w", err)
+	}
+	return NewIndexer(rootPath, m.store, m.client).IndexFile(ctx, absPath, relativePath)
+}
+
 // Remember is deprecated in v2 where markdown files are canonical.
 func (m *MemoryManager) Remember(ctx context.Context, content, category string) error {
 	return fmt.Errorf("memory remember is deprecated in v2; write to markdown files and reindex")
@@ -0,0 +1,109 @@
+package role
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxExtendsDepth bounds inheritance chains.
+const maxExtendsDepth = 8
+
+// resolveExtends returns m merged with the chain of roles it extends.
+// lookup returns the parsed, unresolved manifest of a role by name.
+//
+// The extending role inherits its parent's worker, tools, report template
+// and approval mode unless it sets them, and its params and outputs
+// unless it redeclares them by name. Its prompt is appended to the
+// parent's. Schedules and triggers are not inherited: a base role's events
+// would otherwise start every role built on it.
+func resolveExtends(m *Manifest, lookup func(name string) (*Manifest, error)) (*Manifest, error) {
+	return resolveChain(m, lookup, []string{m.Name})
+}
+
+func resolveChain(m *Manifest, lookup func(name string) (*Manifest, error), chain []string) (*Manifest, error) {
+	if m.Extends == "" {
+		return m, nil
+	}
+	for _, name := range chain {
+		if name == m.Extends {
+			return nil, fmt.Errorf("role %q: extends cycle: %s -> %s", chain[0], strings.Join(chain, " -> "), m.Extends)
+		}
+	}
+	if len(chain) > maxExtendsDepth {
+		return nil, fmt.Errorf("role %q: extends chain is deeper than %d roles", chain[0], maxExtendsDepth)
+	}
+
+	parent, err := lookup(m.Extends)
+	if err != nil {
+		return nil, fmt.Errorf("role %q extends %q: %w", m.Name, m.Extends, err)
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("role %q extends unknown role %q", m.Name, m.Extends)
+	}
+	parent, err = resolveChain(parent, lookup, append(chain, parent.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	merged := inherit(parent, m)
+	if err := merged.Validate(); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// inherit returns child with the fields it leaves unset taken from parent.
+func inherit(parent, child *Manifest) *Manifest {
+	out := *child
+
+	switch {
+	case parent.Prompt == "":
+	case child.Prompt == "":
+		out.Prompt = parent.Prompt
+	default:
+		out.Prompt = parent.Prompt + "\n\n" + child.Prompt
+	}
+	if out.Worker == "" {
+		out.Worker = parent.Worker
+	}
+	if len(out.Tools) == 0 {
+		out.Tools = append([]string(nil), parent.Tools...)
+	}
+	if out.ReportTemplate == "" {
+		out.ReportTemplate = parent.ReportTemplate
+	}
+	if !child.approvalSet {
+		out.Approval = parent.Approval
+		out.approvalSet = parent.approvalSet
+	}
+
+	out.Params = append(Params(nil), parent.Params...)
+	for _, p := range child.Params {
+		replaced := false
+		for i := range out.Params {
+			if out.Params[i].Name == p.Name {
+				out.Params[i] = p
+				replaced = true
+			}
+		}
+		if !replaced {
+			out.Params = append(out.Params, p)
+		}
+	}
+
+	out.Outputs = append(Outputs(nil), parent.Outputs...)
+	for _, o := range child.Outputs {
+		replaced := false
+		for i := range out.Outputs {
+			if out.Outputs[i].Name == o.Name {
+				out.Outputs[i] = o
+				replaced = true
+			}
+		}
+		if !replaced {
+			out.Outputs = append(out.Outputs, o)
+		}
+	}
+
+	return &out
+}
@@ -1,13 +1,18 @@
 package role
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 )
 
+// Dir is the workspace directory holding role manifests.
+const Dir = "roles"
+
 // LoadDir reads all .md files from dir and returns a slice of parsed manifests.
 // Files that are not valid manifests return an error; use LoadDirLenient to skip
 // invalid files instead.
@@ -39,6 +44,11 @@ func LoadDir(dir string) ([]*Manifest, error) {
 		manifests = append(manifests, m)
 	}
 
+	manifests, errs := resolveAll(manifests)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
 	sort.Slice(manifests, func(i, j int) bool {
 		return manifests[i].Name < manifests[j].Name
 	})
@@ -81,6 +91,9 @@ func LoadDirLenient(dir string) ([]*Manifest, []error) {
 		manifests = append(manifests, m)
 	}
 
+	manifests, resolveErrs := resolveAll(manifests)
+	errs = append(errs, resolveErrs...)
+
 	sort.Slice(manifests, func(i, j int) bool {
 		return manifests[i].Name < manifests[j].Name
 	})
@@ -88,9 +101,71 @@ func LoadDirLenient(dir string) ([]*Manifest, []error) {
 	return manifests, errs
 }
 
+// resolveAll resolves the extends chains of manifests loaded from one
+// directory. Roles whose chain cannot be resolved are dropped with an error.
+func resolveAll(manifests []*Manifest) ([]*Manifest, []error) {
+	byName := make(map[string]*Manifest, len(manifests))
+	for _, m := range manifests {
+		byName[m.Name] = m
+	}
+	lookup := func(name string) (*Manifest, error) {
+		return byName[name], nil
+	}
+
+	var resolved []*Manifest
+	var errs []error
+	for _, m := range manifests {
+		r, err := resolveExtends(m, lookup)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolving %s: %w", m.SourcePath, err))
+			continue
+		}
+		resolved = append(resolved, r)
+	}
+	return resolved, errs
+}
+
 // LoadFile reads and parses a single role manifest from path.
-// The role name is derived from the filename stem.
+// The role name is derived from the filename stem. Roles it extends are
+// read from the same directory.
 func LoadFile(path string) (*Manifest, error) {
+	m, err := parseFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	return resolveExtends(m, func(name string) (*Manifest, error) {
+		parent, err := parseFile(filepath.Join(dir, name+".md"))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return parent, err
+	})
+}
+
+// ErrNotFound is returned by Load for a role without a manifest.
+var ErrNotFound = errors.New("role not found")
+
+// Load reads the role called name from dir.
+func Load(dir, name string) (*Manifest, error) {
+	if !ValidName(name) {
+		return nil, fmt.Errorf("invalid role name %q", name)
+	}
+	m, err := LoadFile(filepath.Join(dir, name+".md"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return m, err
+}
+
+// ValidName reports whether name can name a role file.
+func ValidName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// parseFile reads and parses one manifest without resolving extends.
+func parseFile(path string) (*Manifest, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("reading %s: %w", path, err)
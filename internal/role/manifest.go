@@ -1,8 +1,10 @@
 // Package role implements markdown-first role manifests.
 //
 // Each role is a single markdown file with YAML frontmatter. The frontmatter
-// carries structured metadata (worker, tools, schedule, report template,
-// approval mode) while the markdown body is the role's system prompt.
+// carries structured metadata (worker, tools, schedule, event triggers,
+// params, outputs, report template, approval mode) while the markdown body is
+// the role's system prompt. A role may extend another role in the same
+// directory and override parts of it.
 //
 // Example manifest:
 //
@@ -10,13 +12,20 @@
 //	worker: standard
 //	tools: [web_fetch, search]
 //	schedule: "0 9 * * *"
+//	triggers:
+//	  - feed: https://example.com/papers.atom
+//	params:
+//	  topic: {type: string, default: LLM agents}
+//	outputs:
+//	  summary: string
+//	  papers: list
 //	report_template: |
-//	  ## {{.Title}}
-//	  {{.Body}}
+//	  ## {{.Title}}: {{.Params.topic}}
+//	  {{.Outputs.summary}}
 //	approval: auto
 //	---
 //	# Researcher
-//	You are a research agent. Your job is to gather information...
+//	You are a research agent. Gather new papers about {{.Params.topic}}...
 package role
 
 import (
@@ -55,11 +64,15 @@ func ValidApprovalMode(m ApprovalMode) bool {
 
 // frontmatter is the YAML structure parsed from between the --- delimiters.
 type frontmatter struct {
-	Worker         string   `yaml:"worker"`
-	Tools          []string `yaml:"tools"`
-	Schedule       string   `yaml:"schedule"`
-	ReportTemplate string   `yaml:"report_template"`
-	Approval       string   `yaml:"approval"`
+	Extends        string    `yaml:"extends"`
+	Worker         string    `yaml:"worker"`
+	Tools          []string  `yaml:"tools"`
+	Schedule       string    `yaml:"schedule"`
+	Triggers       []Trigger `yaml:"triggers"`
+	Params         Params    `yaml:"params"`
+	Outputs        Outputs   `yaml:"outputs"`
+	ReportTemplate string    `yaml:"report_template"`
+	Approval       string    `yaml:"approval"`
 }
 
 // Manifest is a parsed role definition loaded from a markdown file.
@@ -67,7 +80,11 @@ type Manifest struct {
 	// Name is the role identifier, derived from the filename (without .md).
 	Name string
 
+	// Extends names the role this one inherits from (see Resolve).
+	Extends string
+
 	// Prompt is the markdown body after frontmatter — the role's system prompt.
+	// When the role declares params it is a text/template over {{.Params}}.
 	Prompt string
 
 	// Worker selects the cost tier or worker adapter for this role.
@@ -76,21 +93,38 @@ type Manifest struct {
 	Worker string
 
 	// Tools lists the tool names this role is allowed to call.
-	// An empty slice means all tools are allowed.
+	// An empty slice means all tools are allowed; webhook, feed and IMAP
+	// triggers refuse such roles.
 	Tools []string
 
 	// Schedule is a cron expression for periodic execution.
 	// Empty means the role is not scheduled.
 	Schedule string
 
+	// Triggers start the role on outside events: webhooks, file changes,
+	// feed items and new mail.
+	Triggers []Trigger
+
+	// Params are the role's typed inputs, bound per run by schedules,
+	// triggers, the API and the CLI.
+	Params Params
+
+	// Outputs declare the JSON object the role answers with. Its fields are
+	// handed to the report template as {{.Outputs}}.
+	Outputs Outputs
+
 	// ReportTemplate is a Go text/template used to format the role's output.
-	// Empty means raw output is used as-is.
+	// It is executed against ReportData. Empty means raw output is used as-is.
 	ReportTemplate string
 
 	// Approval controls when the role requires human approval.
 	// Defaults to ApprovalAuto when not specified.
 	Approval ApprovalMode
 
+	// approvalSet records that approval was written in the frontmatter, so
+	// an extending role can tell it from the default.
+	approvalSet bool
+
 	// SourcePath is the absolute path to the source .md file.
 	SourcePath string
 }
@@ -113,12 +147,17 @@ func Parse(name string, data []byte) (*Manifest, error) {
 
 	m := &Manifest{
 		Name:           name,
+		Extends:        strings.TrimSpace(fm.Extends),
 		Prompt:         strings.TrimSpace(body),
 		Worker:         strings.TrimSpace(fm.Worker),
 		Tools:          cleanTools(fm.Tools),
 		Schedule:       strings.TrimSpace(fm.Schedule),
+		Triggers:       fm.Triggers,
+		Params:         fm.Params,
+		Outputs:        fm.Outputs,
 		ReportTemplate: fm.ReportTemplate,
 		Approval:       approval,
+		approvalSet:    fm.Approval != "",
 	}
 
 	if err := m.Validate(); err != nil {
@@ -134,12 +173,43 @@ func (m *Manifest) Validate() error {
 		return fmt.Errorf("role manifest: name is required")
 	}
 
+	if m.Extends != "" && !ValidName(m.Extends) {
+		return fmt.Errorf("role %q: invalid extends %q", m.Name, m.Extends)
+	}
+	if m.Extends == m.Name {
+		return fmt.Errorf("role %q: cannot extend itself", m.Name)
+	}
+
 	if m.ReportTemplate != "" {
 		if _, err := template.New("report").Parse(m.ReportTemplate); err != nil {
 			return fmt.Errorf("role %q: invalid report_template: %w", m.Name, err)
 		}
 	}
 
+	if err := m.validateParams(); err != nil {
+		return err
+	}
+	if err := m.validateOutputs(); err != nil {
+		return err
+	}
+	if len(m.Params) > 0 {
+		if _, err := template.New("prompt").Parse(m.Prompt); err != nil {
+			return fmt.Errorf("role %q: invalid prompt template: %w", m.Name, err)
+		}
+	}
+
+	if m.Schedule != "" {
+		if _, err := ParseSchedule(m.Schedule); err != nil {
+			return fmt.Errorf("role %q: %w", m.Name, err)
+		}
+	}
+
+	for i := range m.Triggers {
+		if err := m.Triggers[i].validate(m.Name, i); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -148,6 +218,20 @@ func (m *Manifest) HasSchedule() bool {
 	return m.Schedule != ""
 }
 
+// HasTriggers reports whether this role defines event triggers.
+func (m *Manifest) HasTriggers() bool {
+	return len(m.Triggers) > 0
+}
+
+// AllTriggers returns the role's triggers, with its top-level schedule as
+// a schedule trigger that runs with the params' defaults.
+func (m *Manifest) AllTriggers() []Trigger {
+	if m.Schedule == "" {
+		return m.Triggers
+	}
+	return append([]Trigger{{Schedule: m.Schedule}}, m.Triggers...)
+}
+
 // HasToolRestrictions reports whether this role restricts available tools.
 func (m *Manifest) HasToolRestrictions() bool {
 	return len(m.Tools) > 0
@@ -167,6 +251,31 @@ func (m *Manifest) IsToolAllowed(toolName string) bool {
 	return false
 }
 
+// ReportData is what a role's report template is executed against.
+type ReportData struct {
+	Title   string         // the role name
+	Body    string         // the role's raw answer
+	Params  map[string]any // the bound params of the run
+	Outputs map[string]any // the parsed declared outputs, if any
+}
+
+// RenderPrompt returns the role's prompt for a run with the given bound
+// params. Prompts of roles without params are returned as written.
+func (m *Manifest) RenderPrompt(params map[string]any) (string, error) {
+	if len(m.Params) == 0 {
+		return m.Prompt, nil
+	}
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(m.Prompt)
+	if err != nil {
+		return "", fmt.Errorf("role %q: prompt template parse error: %w", m.Name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, struct{ Params map[string]any }{Params: params}); err != nil {
+		return "", fmt.Errorf("role %q: prompt template execute error: %w", m.Name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
 // RenderReport executes the report template against data and returns the result.
 // If no template is set, it returns an empty string and no error.
 func (m *Manifest) RenderReport(data any) (string, error) {
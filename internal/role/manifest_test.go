@@ -1,6 +1,7 @@
 package role
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -419,3 +420,153 @@ func writeFile(t *testing.T, dir, name, content string) {
 		t.Fatal(err)
 	}
 }
+
+func TestParse_Triggers(t *testing.T) {
+	t.Setenv("TEST_ROLE_HOOK_SECRET", "abc")
+	data := []byte(`---
+triggers:
+  - webhook: ci/deploy
+    secret: ${TEST_ROLE_HOOK_SECRET}
+    chat: 42
+  - watch: ~/Downloads/*.pdf
+    task: "File {{.Fields.path}}"
+  - feed: https://example.com/releases.atom
+    every: 30s
+  - imap:
+      server: imap.example.com
+      username: bot@example.com
+---
+React to events.
+`)
+
+	m, err := Parse("ops", data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !m.HasTriggers() || len(m.Triggers) != 4 {
+		t.Fatalf("Triggers = %+v, want 4", m.Triggers)
+	}
+
+	hook := m.Triggers[0]
+	if hook.Kind() != TriggerWebhook || hook.Source() != "/hooks/ci/deploy" || hook.Chat != 42 {
+		t.Errorf("webhook trigger = %+v", hook)
+	}
+	if hook.SecretValue() != "abc" {
+		t.Errorf("SecretValue() = %q, want %q", hook.SecretValue(), "abc")
+	}
+	if got := m.Triggers[1].ID("ops"); got != "ops:watch:~/Downloads/*.pdf" {
+		t.Errorf("watch ID = %q", got)
+	}
+	if got := m.Triggers[2].Interval(); got != MinPollInterval {
+		t.Errorf("feed Interval() = %v, want the %v minimum", got, MinPollInterval)
+	}
+	mail := m.Triggers[3]
+	if mail.Source() != "bot@example.com@imap.example.com/INBOX" || mail.Interval() != DefaultIMAPInterval {
+		t.Errorf("imap trigger source %q interval %v", mail.Source(), mail.Interval())
+	}
+}
+
+func TestParse_InvalidTriggers(t *testing.T) {
+	tests := map[string]string{
+		"no kind":          "  - task: hi",
+		"two kinds":        "  - webhook: a\n    secret: s\n    feed: https://example.com/f",
+		"webhook path":     "  - webhook: ../etc\n    secret: s",
+		"webhook secret":   "  - webhook: deploy",
+		"watch dir glob":   "  - watch: inbox/*/a.pdf",
+		"feed url":         "  - feed: ftp://example.com/feed",
+		"imap server":      "  - imap:\n      username: me",
+		"every on webhook": "  - webhook: deploy\n    secret: s\n    every: 5m",
+		"bad every":        "  - feed: https://example.com/f\n    every: soon",
+		"bad task":         "  - feed: https://example.com/f\n    task: \"{{.Title\"",
+	}
+	for name, trigger := range tests {
+		t.Run(name, func(t *testing.T) {
+			data := []byte("---\ntriggers:\n" + trigger + "\n---\nPrompt.\n")
+			if _, err := Parse("bad", data); err == nil {
+				t.Errorf("expected error for %s", name)
+			}
+		})
+	}
+}
+
+func TestLoadDir_Extends(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "base.md", `---
+worker: cheap
+tools: [web_fetch]
+approval: always
+schedule: "0 9 * * *"
+params:
+  host: {type: string, required: true}
+  port: {type: int, default: 443}
+outputs:
+  status: string
+report_template: "{{.Outputs.status}}"
+---
+You check servers.`)
+	writeFile(t, dir, "uptime.md", `---
+extends: base
+worker: premium
+params:
+  port: {type: int, default: 8443}
+  path: /health
+---
+Fetch https://{{.Params.host}}:{{.Params.port}}{{.Params.path}}.`)
+
+	manifests, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	m := manifests[1]
+	if m.Name != "uptime" || m.Extends != "base" {
+		t.Fatalf("manifest = %+v", m)
+	}
+	if m.Worker != "premium" || m.Approval != ApprovalAlways || len(m.Tools) != 1 || m.ReportTemplate == "" {
+		t.Errorf("inherited fields: worker %q approval %q tools %v", m.Worker, m.Approval, m.Tools)
+	}
+	if m.HasSchedule() {
+		t.Error("schedule should not be inherited")
+	}
+	if len(m.Params) != 3 || m.Param("port").Default != int64(8443) || !m.Param("host").Required {
+		t.Errorf("params = %+v", m.Params)
+	}
+	if !m.HasOutputs() {
+		t.Error("outputs should be inherited")
+	}
+
+	plan, err := m.Plan(map[string]any{"host": "web1"}, "")
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if plan.Prompt != "You check servers.\n\nFetch https://web1:8443/health." {
+		t.Errorf("Prompt = %q", plan.Prompt)
+	}
+
+	// LoadFile resolves the chain from the file's directory.
+	single, err := LoadFile(filepath.Join(dir, "uptime.md"))
+	if err != nil || single.Worker != "premium" || len(single.Params) != 3 {
+		t.Errorf("LoadFile = %+v, %v", single, err)
+	}
+}
+
+func TestLoadDir_ExtendsErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.md", "---\nextends: b\n---\nA.")
+	writeFile(t, dir, "b.md", "---\nextends: a\n---\nB.")
+	writeFile(t, dir, "orphan.md", "---\nextends: missing\n---\nO.")
+	writeFile(t, dir, "ok.md", "Fine.")
+
+	if _, err := LoadDir(dir); err == nil {
+		t.Fatal("LoadDir: expected error")
+	}
+	manifests, errs := LoadDirLenient(dir)
+	if len(manifests) != 1 || manifests[0].Name != "ok" {
+		t.Errorf("lenient manifests = %v", manifests)
+	}
+	if len(errs) != 3 {
+		t.Errorf("lenient errors = %v, want 3", errs)
+	}
+	if _, err := Load(dir, "nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load(nope) = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,386 @@
+package role
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Parameter and output types.
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeNumber = "number"
+	TypeBool   = "bool"
+	TypeList   = "list"   // a list of strings; "a,b" binds as [a b]
+	TypeObject = "object" // outputs only
+)
+
+var fieldNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Param is a typed input of a role. The role's prompt reads bound values as
+// {{.Params.name}}.
+//
+//	params:
+//	  host: {type: string, required: true}
+//	  port: {type: int, default: 443}
+//	  region: eu-west-1   # shorthand for a string with a default
+type Param struct {
+	Name        string
+	Type        string
+	Default     any
+	Required    bool
+	Description string
+}
+
+// Params is the ordered list of a role's parameters, written in YAML as a
+// mapping from name to definition.
+type Params []Param
+
+// UnmarshalYAML reads the params mapping, keeping its order.
+func (p *Params) UnmarshalYAML(node *yaml.Node) error {
+	return decodeFields(node, "params", func(name string, value *yaml.Node) error {
+		param := Param{Name: name}
+		if value.Kind == yaml.MappingNode {
+			var def struct {
+				Type        string `yaml:"type"`
+				Default     any    `yaml:"default"`
+				Required    bool   `yaml:"required"`
+				Description string `yaml:"description"`
+			}
+			if err := value.Decode(&def); err != nil {
+				return err
+			}
+			param.Type, param.Default, param.Required, param.Description = def.Type, def.Default, def.Required, def.Description
+		} else if err := value.Decode(&param.Default); err != nil {
+			return err
+		}
+		*p = append(*p, param)
+		return nil
+	})
+}
+
+// Output is one field of the JSON object a role with declared outputs
+// returns. The report template reads it as {{.Outputs.name}}.
+//
+//	outputs:
+//	  status: {type: string, description: up, degraded or down}
+//	  latency_ms: int
+type Output struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// Outputs is the ordered list of a role's output fields.
+type Outputs []Output
+
+// UnmarshalYAML reads the outputs mapping, keeping its order.
+func (o *Outputs) UnmarshalYAML(node *yaml.Node) error {
+	return decodeFields(node, "outputs", func(name string, value *yaml.Node) error {
+		out := Output{Name: name}
+		if value.Kind == yaml.MappingNode {
+			var def struct {
+				Type        string `yaml:"type"`
+				Description string `yaml:"description"`
+			}
+			if err := value.Decode(&def); err != nil {
+				return err
+			}
+			out.Type, out.Description = def.Type, def.Description
+		} else if err := value.Decode(&out.Type); err != nil {
+			return err
+		}
+		*o = append(*o, out)
+		return nil
+	})
+}
+
+// decodeFields calls fn for each entry of a name -> definition mapping.
+func decodeFields(node *yaml.Node, what string, fn func(name string, value *yaml.Node) error) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s must be a mapping of name to definition", what)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := strings.TrimSpace(node.Content[i].Value)
+		if err := fn(name, node.Content[i+1]); err != nil {
+			return fmt.Errorf("%s.%s: %w", what, name, err)
+		}
+	}
+	return nil
+}
+
+// validateParams normalizes param types and checks names and defaults.
+func (m *Manifest) validateParams() error {
+	seen := make(map[string]bool, len(m.Params))
+	for i := range m.Params {
+		p := &m.Params[i]
+		if !fieldNameRe.MatchString(p.Name) {
+			return fmt.Errorf("role %q: invalid param name %q", m.Name, p.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("role %q: duplicate param %q", m.Name, p.Name)
+		}
+		seen[p.Name] = true
+
+		p.Type = strings.ToLower(strings.TrimSpace(p.Type))
+		if p.Type == "" {
+			p.Type = inferType(p.Default)
+		}
+		switch p.Type {
+		case TypeString, TypeInt, TypeNumber, TypeBool, TypeList:
+		default:
+			return fmt.Errorf("role %q: param %q: invalid type %q (want string, int, number, bool or list)", m.Name, p.Name, p.Type)
+		}
+		if p.Default != nil {
+			v, err := coerce(p.Type, p.Default)
+			if err != nil {
+				return fmt.Errorf("role %q: param %q: default: %w", m.Name, p.Name, err)
+			}
+			p.Default = v
+		}
+	}
+	return nil
+}
+
+// validateOutputs normalizes output types and checks names.
+func (m *Manifest) validateOutputs() error {
+	seen := make(map[string]bool, len(m.Outputs))
+	for i := range m.Outputs {
+		o := &m.Outputs[i]
+		if !fieldNameRe.MatchString(o.Name) {
+			return fmt.Errorf("role %q: invalid output name %q", m.Name, o.Name)
+		}
+		if seen[o.Name] {
+			return fmt.Errorf("role %q: duplicate output %q", m.Name, o.Name)
+		}
+		seen[o.Name] = true
+
+		o.Type = strings.ToLower(strings.TrimSpace(o.Type))
+		if o.Type == "" {
+			o.Type = TypeString
+		}
+		switch o.Type {
+		case TypeString, TypeInt, TypeNumber, TypeBool, TypeList, TypeObject:
+		default:
+			return fmt.Errorf("role %q: output %q: invalid type %q (want string, int, number, bool, list or object)", m.Name, o.Name, o.Type)
+		}
+	}
+	return nil
+}
+
+// inferType picks a param type from a shorthand default.
+func inferType(v any) string {
+	switch v.(type) {
+	case int, int64:
+		return TypeInt
+	case float64:
+		return TypeNumber
+	case bool:
+		return TypeBool
+	case []any:
+		return TypeList
+	}
+	return TypeString
+}
+
+// Param returns the named parameter, or nil.
+func (m *Manifest) Param(name string) *Param {
+	for i := range m.Params {
+		if m.Params[i].Name == name {
+			return &m.Params[i]
+		}
+	}
+	return nil
+}
+
+// BindParams checks values against the role's params and returns them
+// typed, with defaults filled in. Strings are converted to the declared
+// type, so command-line and template values bind like JSON ones. Unknown
+// names and missing required params are errors.
+func (m *Manifest) BindParams(values map[string]any) (map[string]any, error) {
+	var unknown []string
+	for name := range values {
+		if m.Param(name) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("role %q has no param %s", m.Name, strings.Join(unknown, ", "))
+	}
+
+	bound := make(map[string]any, len(m.Params))
+	for _, p := range m.Params {
+		raw, ok := values[p.Name]
+		switch {
+		case ok:
+			v, err := coerce(p.Type, raw)
+			if err != nil {
+				return nil, fmt.Errorf("role %q: param %s: %w", m.Name, p.Name, err)
+			}
+			bound[p.Name] = v
+		case p.Default != nil:
+			bound[p.Name] = p.Default
+		case p.Required:
+			return nil, fmt.Errorf("role %q: param %s is required", m.Name, p.Name)
+		default:
+			bound[p.Name] = zeroValue(p.Type)
+		}
+	}
+	return bound, nil
+}
+
+// coerce converts v to the Go value of a param type: string, int64,
+// float64, bool or []string.
+func coerce(typ string, v any) (any, error) {
+	switch typ {
+	case TypeString:
+		switch x := v.(type) {
+		case string:
+			return x, nil
+		case int, int64, float64, bool:
+			return fmt.Sprint(x), nil
+		}
+	case TypeInt:
+		switch x := v.(type) {
+		case int:
+			return int64(x), nil
+		case int64:
+			return x, nil
+		case float64:
+			if x == float64(int64(x)) {
+				return int64(x), nil
+			}
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(x), 10, 64); err == nil {
+				return n, nil
+			}
+		}
+	case TypeNumber:
+		switch x := v.(type) {
+		case int:
+			return float64(x), nil
+		case int64:
+			return float64(x), nil
+		case float64:
+			return x, nil
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(x), 64); err == nil {
+				return f, nil
+			}
+		}
+	case TypeBool:
+		switch x := v.(type) {
+		case bool:
+			return x, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(x)); err == nil {
+				return b, nil
+			}
+		}
+	case TypeList:
+		switch x := v.(type) {
+		case []string:
+			return x, nil
+		case []any:
+			out := make([]string, 0, len(x))
+			for _, item := range x {
+				out = append(out, fmt.Sprint(item))
+			}
+			return out, nil
+		case string:
+			var out []string
+			if strings.HasPrefix(strings.TrimSpace(x), "[") {
+				if err := json.Unmarshal([]byte(x), &out); err == nil {
+					return out, nil
+				}
+			}
+			out = []string{}
+			for _, item := range strings.Split(x, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					out = append(out, item)
+				}
+			}
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("%v is not a valid %s", v, typ)
+}
+
+func zeroValue(typ string) any {
+	switch typ {
+	case TypeInt:
+		return int64(0)
+	case TypeNumber:
+		return float64(0)
+	case TypeBool:
+		return false
+	case TypeList:
+		return []string{}
+	}
+	return ""
+}
+
+// HasOutputs reports whether the role declares an output schema.
+func (m *Manifest) HasOutputs() bool {
+	return len(m.Outputs) > 0
+}
+
+// OutputSchema describes the declared outputs as the JSON shape the role's
+// final answer must have, e.g. {"status": "string (up or down)"}. It is
+// empty when the role declares no outputs.
+func (m *Manifest) OutputSchema() string {
+	if !m.HasOutputs() {
+		return ""
+	}
+	parts := make([]string, 0, len(m.Outputs))
+	for _, o := range m.Outputs {
+		desc := o.Type
+		if o.Description != "" {
+			desc += " (" + o.Description + ")"
+		}
+		parts = append(parts, fmt.Sprintf("%q: %q", o.Name, desc))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// ParseOutputs reads the JSON object a role with declared outputs answered
+// with. A surrounding code fence or prose is tolerated; a missing field or
+// a value of the wrong type is an error.
+func (m *Manifest) ParseOutputs(output string) (map[string]any, error) {
+	start, end := strings.Index(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("role %q: output is not a JSON object", m.Name)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(output[start:end+1]), &fields); err != nil {
+		return nil, fmt.Errorf("role %q: output is not a JSON object: %w", m.Name, err)
+	}
+
+	out := make(map[string]any, len(m.Outputs))
+	for _, o := range m.Outputs {
+		v, ok := fields[o.Name]
+		if !ok || v == nil {
+			return nil, fmt.Errorf("role %q: output is missing %q", m.Name, o.Name)
+		}
+		if o.Type == TypeObject {
+			if _, isObject := v.(map[string]any); !isObject {
+				return nil, fmt.Errorf("role %q: output %s: %v is not an object", m.Name, o.Name, v)
+			}
+			out[o.Name] = v
+			continue
+		}
+		typed, err := coerce(o.Type, v)
+		if err != nil {
+			return nil, fmt.Errorf("role %q: output %s: %w", m.Name, o.Name, err)
+		}
+		out[o.Name] = typed
+	}
+	return out, nil
+}
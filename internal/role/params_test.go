@@ -0,0 +1,188 @@
+package role
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse_ParamsAndOutputs(t *testing.T) {
+	data := []byte(`---
+params:
+  host: {type: string, required: true, description: server to check}
+  port: {type: int, default: 443}
+  verbose: false
+  checks: [ping, http]
+outputs:
+  status: {type: string, description: up or down}
+  latency_ms: int
+---
+Check {{.Params.host}}:{{.Params.port}}.
+`)
+	m, err := Parse("uptime", data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var names, types []string
+	for _, p := range m.Params {
+		names = append(names, p.Name)
+		types = append(types, p.Type)
+	}
+	if !reflect.DeepEqual(names, []string{"host", "port", "verbose", "checks"}) {
+		t.Errorf("param order = %v", names)
+	}
+	if !reflect.DeepEqual(types, []string{TypeString, TypeInt, TypeBool, TypeList}) {
+		t.Errorf("param types = %v", types)
+	}
+	if !m.HasOutputs() || m.Outputs[1].Name != "latency_ms" || m.Outputs[1].Type != TypeInt {
+		t.Errorf("Outputs = %+v", m.Outputs)
+	}
+	if got, want := m.OutputSchema(), `{"status": "string (up or down)", "latency_ms": "int"}`; got != want {
+		t.Errorf("OutputSchema() = %s, want %s", got, want)
+	}
+}
+
+func TestParse_InvalidParams(t *testing.T) {
+	tests := map[string]string{
+		"bad type":       "params:\n  a: {type: date}",
+		"bad default":    "params:\n  a: {type: int, default: many}",
+		"bad name":       "params:\n  a-b: {type: string}",
+		"not a mapping":  "params: [a, b]",
+		"bad output":     "outputs:\n  a: date",
+		"prompt":         "params:\n  a: x\n---\n{{.Params.a",
+		"self extends":   "extends: bad",
+		"extends path":   "extends: ../secret",
+		"bad schedule":   "schedule: every morning",
+		"trigger params": "triggers:\n  - schedule: \"0 9 * * *\"\n    params: {a: \"{{.Title\"}",
+	}
+	for name, fm := range tests {
+		t.Run(name, func(t *testing.T) {
+			doc := "---\n" + fm + "\n---\nPrompt.\n"
+			if strings.Contains(fm, "\n---\n") {
+				doc = "---\n" + fm + "\n"
+			}
+			if _, err := Parse("bad", []byte(doc)); err == nil {
+				t.Errorf("expected error for %s", name)
+			}
+		})
+	}
+}
+
+func TestManifest_BindParams(t *testing.T) {
+	m, err := Parse("uptime", []byte(`---
+params:
+  host: {type: string, required: true}
+  port: {type: int, default: 443}
+  ratio: {type: number}
+  verbose: {type: bool}
+  checks: {type: list, default: [ping]}
+---
+Check.`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.BindParams(map[string]any{"host": "db1", "port": "5432", "verbose": "true", "checks": "ping, http"})
+	if err != nil {
+		t.Fatalf("BindParams: %v", err)
+	}
+	want := map[string]any{
+		"host":    "db1",
+		"port":    int64(5432),
+		"ratio":   float64(0),
+		"verbose": true,
+		"checks":  []string{"ping", "http"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BindParams = %#v, want %#v", got, want)
+	}
+
+	// JSON numbers bind like strings do.
+	if got, err := m.BindParams(map[string]any{"host": "db1", "port": float64(80)}); err != nil || got["port"] != int64(80) {
+		t.Errorf("BindParams(port=80.0) = %v, %v", got, err)
+	}
+
+	for name, values := range map[string]map[string]any{
+		"missing required": {},
+		"unknown":          {"host": "db1", "hots": "x"},
+		"wrong type":       {"host": "db1", "port": "https"},
+	} {
+		if _, err := m.BindParams(values); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestManifest_PlanRendersPrompt(t *testing.T) {
+	m, err := Parse("uptime", []byte(`---
+tools: [http_request]
+params:
+  host: {type: string, required: true}
+---
+Check {{.Params.host}}.`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := m.Plan(map[string]any{"host": "db1"}, "  Be brief. ")
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if plan.Prompt != "Check db1." || plan.Content() != "Check db1.\n\nBe brief." || plan.Approval != ApprovalAuto {
+		t.Errorf("plan = %+v", plan)
+	}
+
+	// Prompts of roles without params are not templates.
+	plain, err := Parse("plain", []byte("Use {{braces}} freely."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := plain.Plan(nil, ""); err != nil || p.Prompt != "Use {{braces}} freely." {
+		t.Errorf("plain plan = %+v, %v", p, err)
+	}
+}
+
+func TestManifest_ParseOutputs(t *testing.T) {
+	m, err := Parse("uptime", []byte(`---
+outputs:
+  status: string
+  latency_ms: int
+  details: object
+report_template: "{{.Title}}: {{.Outputs.status}} in {{.Outputs.latency_ms}}ms"
+---
+Check.`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := m.ParseOutputs("Here you go:\n```json\n{\"status\": \"up\", \"latency_ms\": 42, \"details\": {\"code\": 200}}\n```")
+	if err != nil {
+		t.Fatalf("ParseOutputs: %v", err)
+	}
+	report, err := m.RenderReport(ReportData{Title: m.Name, Outputs: out})
+	if err != nil || report != "uptime: up in 42ms" {
+		t.Errorf("report = %q, %v", report, err)
+	}
+
+	for _, bad := range []string{
+		"all good",
+		`{"status": "up"}`,
+		`{"status": "up", "latency_ms": "fast", "details": {}}`,
+		`{"status": "up", "latency_ms": 1, "details": "none"}`,
+	} {
+		if _, err := m.ParseOutputs(bad); err == nil {
+			t.Errorf("ParseOutputs(%q): expected error", bad)
+		}
+	}
+}
+
+func TestTrigger_IDIncludesParams(t *testing.T) {
+	a := Trigger{Schedule: "0 9 * * *", Params: map[string]string{"host": "db1", "port": "1"}}
+	b := Trigger{Schedule: "0 9 * * *", Params: map[string]string{"host": "db2"}}
+	if got := a.ID("uptime"); got != "uptime:schedule:0 9 * * *?host=db1&port=1" {
+		t.Errorf("ID = %q", got)
+	}
+	if a.ID("uptime") == b.ID("uptime") {
+		t.Error("triggers with different params share an ID")
+	}
+}
@@ -0,0 +1,51 @@
+package role
+
+import "strings"
+
+// Plan is a role run resolved ahead of time: what the agent will be told
+// and allowed to do. Dry runs print it; role runs execute it.
+type Plan struct {
+	Role         string         `json:"role"`
+	Extends      string         `json:"extends,omitempty"`
+	Worker       string         `json:"worker,omitempty"`
+	Tools        []string       `json:"tools"` // empty allows every tool
+	Approval     ApprovalMode   `json:"approval"`
+	Params       map[string]any `json:"params"`
+	Prompt       string         `json:"prompt"` // the rendered role prompt
+	Task         string         `json:"task,omitempty"`
+	OutputSchema string         `json:"output_schema,omitempty"`
+}
+
+// Plan binds values to the role's params and renders its prompt for task.
+func (m *Manifest) Plan(values map[string]any, task string) (*Plan, error) {
+	params, err := m.BindParams(values)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := m.RenderPrompt(params)
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{
+		Role:         m.Name,
+		Extends:      m.Extends,
+		Worker:       m.Worker,
+		Tools:        m.Tools,
+		Approval:     m.Approval,
+		Params:       params,
+		Prompt:       prompt,
+		Task:         strings.TrimSpace(task),
+		OutputSchema: m.OutputSchema(),
+	}, nil
+}
+
+// Content is the message the agent receives: the role prompt, then the task.
+func (p *Plan) Content() string {
+	switch {
+	case p.Prompt == "":
+		return p.Task
+	case p.Task == "":
+		return p.Prompt
+	}
+	return p.Prompt + "\n\n" + p.Task
+}
@@ -0,0 +1,265 @@
+package role
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Trigger kinds.
+const (
+	TriggerWebhook  = "webhook"
+	TriggerWatch    = "watch"
+	TriggerFeed     = "feed"
+	TriggerIMAP     = "imap"
+	TriggerSchedule = "schedule"
+)
+
+// Poll intervals for feed and imap triggers.
+const (
+	DefaultFeedInterval = 15 * time.Minute
+	DefaultIMAPInterval = 5 * time.Minute
+	MinPollInterval     = time.Minute
+)
+
+// Trigger starts a run of the role when something happens outside the bot.
+// Exactly one of Webhook, Watch, Feed, IMAP and Schedule selects the kind.
+//
+//	triggers:
+//	  - watch: ~/Downloads/*.pdf
+//	    task: "File the invoice at {{.Fields.path}}"
+//	    chat: 123456
+//	  - webhook: github
+//	    secret: $GITHUB_HOOK_SECRET
+//	  - feed: https://example.com/releases.atom
+//	    every: 30m
+//	  - schedule: "0 9 * * *"
+//	    params: {host: db1.internal}
+type Trigger struct {
+	// Webhook is the path under /hooks/ that receives events.
+	Webhook string `yaml:"webhook"`
+	// Secret is the HMAC-SHA256 key webhook requests are signed with.
+	// $NAME and ${NAME} are read from the environment.
+	Secret string `yaml:"secret"`
+
+	// Watch is a file glob; the wildcard may only appear in the file name.
+	// Relative paths are inside the workspace, ~/ is the home directory.
+	Watch string `yaml:"watch"`
+
+	// Feed is the URL of an RSS or Atom feed.
+	Feed string `yaml:"feed"`
+
+	// IMAP is a mailbox to watch for new mail.
+	IMAP *IMAPSource `yaml:"imap"`
+
+	// Schedule is a five-field cron expression (CRON_TZ= prefix allowed).
+	Schedule string `yaml:"schedule"`
+
+	// Every is the poll interval of feed and imap triggers.
+	Every string `yaml:"every"`
+
+	// Task is a text/template for the task given to the role. It is
+	// executed against the event ({{.Title}}, {{.Body}}, {{.Fields.path}},
+	// {{.Payload.action}}). Empty uses a description of the event.
+	Task string `yaml:"task"`
+
+	// Params bind the role's params for runs started by this trigger. Each
+	// value is a text/template executed against the event like Task.
+	Params map[string]string `yaml:"params"`
+
+	// Chat is the Telegram chat the report is sent to (0 = none).
+	Chat int64 `yaml:"chat"`
+}
+
+// IMAPSource is the mailbox an imap trigger polls.
+type IMAPSource struct {
+	Server   string `yaml:"server"`
+	Port     int    `yaml:"port"` // default 993, or 143 with plain
+	Username string `yaml:"username"`
+	// Password may be $NAME or ${NAME} to read it from the environment.
+	Password string `yaml:"password"`
+	Folder   string `yaml:"folder"` // default INBOX
+	// Plain disables TLS, for local bridges only.
+	Plain bool `yaml:"plain"`
+}
+
+var webhookPathRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}(/[a-z0-9][a-z0-9_-]{0,63})*$`)
+
+// Kind returns which kind of trigger this is, or "" when none is set.
+func (t *Trigger) Kind() string {
+	switch {
+	case t.Webhook != "":
+		return TriggerWebhook
+	case t.Watch != "":
+		return TriggerWatch
+	case t.Feed != "":
+		return TriggerFeed
+	case t.IMAP != nil:
+		return TriggerIMAP
+	case t.Schedule != "":
+		return TriggerSchedule
+	}
+	return ""
+}
+
+// Source describes what the trigger listens to, for IDs and listings.
+func (t *Trigger) Source() string {
+	switch t.Kind() {
+	case TriggerWebhook:
+		return "/hooks/" + t.Webhook
+	case TriggerWatch:
+		return t.Watch
+	case TriggerFeed:
+		return t.Feed
+	case TriggerIMAP:
+		folder := t.IMAP.Folder
+		if folder == "" {
+			folder = "INBOX"
+		}
+		return fmt.Sprintf("%s@%s/%s", t.IMAP.Username, t.IMAP.Server, folder)
+	case TriggerSchedule:
+		return t.Schedule
+	}
+	return ""
+}
+
+// ID identifies the trigger's persisted state. It changes when the role is
+// renamed or the trigger's source or params change, which starts it afresh.
+func (t *Trigger) ID(roleName string) string {
+	id := roleName + ":" + t.Kind() + ":" + t.Source()
+	if len(t.Params) == 0 {
+		return id
+	}
+	names := make([]string, 0, len(t.Params))
+	for name := range t.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = name + "=" + t.Params[name]
+	}
+	return id + "?" + strings.Join(names, "&")
+}
+
+// Interval returns the poll interval of feed and imap triggers.
+func (t *Trigger) Interval() time.Duration {
+	if d, err := time.ParseDuration(strings.TrimSpace(t.Every)); err == nil && d > 0 {
+		if d < MinPollInterval {
+			return MinPollInterval
+		}
+		return d
+	}
+	if t.Kind() == TriggerIMAP {
+		return DefaultIMAPInterval
+	}
+	return DefaultFeedInterval
+}
+
+// SecretValue returns the webhook secret with environment references
+// resolved.
+func (t *Trigger) SecretValue() string {
+	return expandSecret(t.Secret)
+}
+
+// PasswordValue returns the IMAP password with environment references
+// resolved.
+func (s *IMAPSource) PasswordValue() string {
+	return expandSecret(s.Password)
+}
+
+// validate checks one trigger of the named role.
+func (t *Trigger) validate(roleName string, i int) error {
+	kinds := 0
+	for _, set := range []bool{t.Webhook != "", t.Watch != "", t.Feed != "", t.IMAP != nil, t.Schedule != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("role %q: trigger %d: set exactly one of webhook, watch, feed, imap or schedule", roleName, i+1)
+	}
+
+	switch t.Kind() {
+	case TriggerWebhook:
+		if !webhookPathRe.MatchString(t.Webhook) {
+			return fmt.Errorf("role %q: trigger %d: webhook path %q must be lowercase letters, digits, - and _ separated by /", roleName, i+1, t.Webhook)
+		}
+		if strings.TrimSpace(t.Secret) == "" {
+			return fmt.Errorf("role %q: trigger %d: webhook %q needs a secret", roleName, i+1, t.Webhook)
+		}
+	case TriggerWatch:
+		dir := filepath.Dir(t.Watch)
+		if strings.ContainsAny(dir, "*?[") {
+			return fmt.Errorf("role %q: trigger %d: watch %q: wildcards are only allowed in the file name", roleName, i+1, t.Watch)
+		}
+		if _, err := filepath.Match(filepath.Base(t.Watch), ""); err != nil {
+			return fmt.Errorf("role %q: trigger %d: watch %q: %w", roleName, i+1, t.Watch, err)
+		}
+	case TriggerFeed:
+		u, err := url.Parse(t.Feed)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("role %q: trigger %d: feed %q is not an http(s) URL", roleName, i+1, t.Feed)
+		}
+	case TriggerIMAP:
+		if t.IMAP.Server == "" || t.IMAP.Username == "" {
+			return fmt.Errorf("role %q: trigger %d: imap needs server and username", roleName, i+1)
+		}
+	case TriggerSchedule:
+		if _, err := ParseSchedule(t.Schedule); err != nil {
+			return fmt.Errorf("role %q: trigger %d: %w", roleName, i+1, err)
+		}
+	}
+
+	if t.Every != "" {
+		if t.Kind() != TriggerFeed && t.Kind() != TriggerIMAP {
+			return fmt.Errorf("role %q: trigger %d: every only applies to feed and imap triggers", roleName, i+1)
+		}
+		if d, err := time.ParseDuration(strings.TrimSpace(t.Every)); err != nil || d <= 0 {
+			return fmt.Errorf("role %q: trigger %d: invalid every %q", roleName, i+1, t.Every)
+		}
+	}
+	if t.Task != "" {
+		if _, err := template.New("task").Parse(t.Task); err != nil {
+			return fmt.Errorf("role %q: trigger %d: invalid task template: %w", roleName, i+1, err)
+		}
+	}
+	for name, value := range t.Params {
+		if _, err := template.New(name).Parse(value); err != nil {
+			return fmt.Errorf("role %q: trigger %d: invalid template for param %s: %w", roleName, i+1, name, err)
+		}
+	}
+	return nil
+}
+
+// ParseSchedule parses a role or schedule trigger cron expression.
+func ParseSchedule(expr string) (cron.Schedule, error) {
+	s, err := cron.ParseStandard(strings.TrimSpace(expr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+	}
+	return s, nil
+}
+
+// expandSecret resolves a value that is entirely an environment reference;
+// anything else is returned as written.
+func expandSecret(value string) string {
+	value = strings.TrimSpace(value)
+	name := ""
+	switch {
+	case strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}"):
+		name = value[2 : len(value)-1]
+	case strings.HasPrefix(value, "$"):
+		name = value[1:]
+	default:
+		return value
+	}
+	return os.Getenv(name)
+}
@@ -0,0 +1,156 @@
+package role
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReadSource returns the raw markdown of the role called name in dir.
+func ReadSource(dir, name string) ([]byte, error) {
+	if !ValidName(name) {
+		return nil, fmt.Errorf("invalid role name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name+".md"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return data, err
+}
+
+// Save validates data as the manifest of the role called name and writes it
+// to dir, replacing the file atomically. The role's extends chain and every
+// role that extends it must still resolve with the new content; otherwise
+// nothing is written. It reports whether the role is new.
+func Save(dir, name string, data []byte) (*Manifest, bool, error) {
+	if !ValidName(name) {
+		return nil, false, fmt.Errorf("invalid role name %q", name)
+	}
+	m, err := Parse(name, data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	siblings, err := parseSiblings(dir)
+	if err != nil {
+		return nil, false, err
+	}
+	_, exists := siblings[name]
+	m.SourcePath = filepath.Join(dir, name+".md")
+	siblings[name] = m
+
+	resolved, err := resolveWithin(siblings, name)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, dep := range dependents(siblings, name) {
+		if _, err := resolveWithin(siblings, dep); err != nil {
+			return nil, false, fmt.Errorf("role %q extends %q: %w", dep, name, err)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, false, err
+	}
+	if err := writeFileAtomic(m.SourcePath, data); err != nil {
+		return nil, false, err
+	}
+	return resolved, !exists, nil
+}
+
+// Remove deletes the role called name from dir. A role that others extend
+// cannot be removed.
+func Remove(dir, name string) error {
+	if !ValidName(name) {
+		return fmt.Errorf("invalid role name %q", name)
+	}
+	siblings, err := parseSiblings(dir)
+	if err != nil {
+		return err
+	}
+	if deps := dependents(siblings, name); len(deps) > 0 {
+		return fmt.Errorf("role %q is extended by %s", name, strings.Join(deps, ", "))
+	}
+	err = os.Remove(filepath.Join(dir, name+".md"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return err
+}
+
+// parseSiblings parses the manifests in dir by name, skipping files that do
+// not parse. A missing directory holds no roles.
+func parseSiblings(dir string) (map[string]*Manifest, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]*Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading role directory %s: %w", dir, err)
+	}
+	byName := make(map[string]*Manifest, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			continue
+		}
+		m, err := parseFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		byName[m.Name] = m
+	}
+	return byName, nil
+}
+
+// resolveWithin resolves the extends chain of the named role against byName.
+func resolveWithin(byName map[string]*Manifest, name string) (*Manifest, error) {
+	return resolveExtends(byName[name], func(parent string) (*Manifest, error) {
+		return byName[parent], nil
+	})
+}
+
+// dependents returns the roles whose extends chain passes through name.
+func dependents(byName map[string]*Manifest, name string) []string {
+	var out []string
+	for n := range byName {
+		seen := map[string]bool{n: true}
+		for cur := byName[n]; cur != nil && cur.Extends != "" && !seen[cur.Extends]; cur = byName[cur.Extends] {
+			if cur.Extends == name {
+				out = append(out, n)
+				break
+			}
+			seen[cur.Extends] = true
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a partial manifest.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
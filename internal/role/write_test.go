@@ -0,0 +1,92 @@
+package role
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSave(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "roles")
+
+	m, created, err := Save(dir, "base", []byte("---\nworker: cheap\n---\nBase."))
+	if err != nil {
+		t.Fatalf("Save(base): %v", err)
+	}
+	if !created || m.Worker != "cheap" {
+		t.Errorf("Save(base) = %+v, created %v", m, created)
+	}
+
+	m, created, err = Save(dir, "child", []byte("---\nextends: base\n---\nChild."))
+	if err != nil {
+		t.Fatalf("Save(child): %v", err)
+	}
+	if !created || m.Worker != "cheap" || m.Prompt != "Base.\n\nChild." {
+		t.Errorf("Save(child) = %+v, created %v", m, created)
+	}
+
+	if _, created, err = Save(dir, "base", []byte("---\nworker: premium\n---\nBase v2.")); err != nil || created {
+		t.Fatalf("Save(base) update = created %v, %v", created, err)
+	}
+	data, err := ReadSource(dir, "base")
+	if err != nil || !strings.Contains(string(data), "Base v2.") {
+		t.Errorf("ReadSource(base) = %q, %v", data, err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("roles dir has %d entries, want 2 (no temp files left)", len(entries))
+	}
+}
+
+func TestSave_Rejects(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "base.md", "Base.")
+	writeFile(t, dir, "child.md", "---\nextends: base\n---\nChild.")
+
+	cases := []struct {
+		name, role, content string
+	}{
+		{"invalid name", "../evil", "Prompt."},
+		{"bad frontmatter", "x", "---\napproval: sometimes\n---\nPrompt."},
+		{"unknown parent", "x", "---\nextends: missing\n---\nPrompt."},
+		{"cycle through dependent", "base", "---\nextends: child\n---\nBase."},
+	}
+	for _, tc := range cases {
+		if _, _, err := Save(dir, tc.role, []byte(tc.content)); err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "base.md"))
+	if string(data) != "Base." {
+		t.Errorf("base.md changed to %q after rejected save", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x.md")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("x.md written after rejected save: %v", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "base.md", "Base.")
+	writeFile(t, dir, "child.md", "---\nextends: base\n---\nChild.")
+
+	if err := Remove(dir, "base"); err == nil || !strings.Contains(err.Error(), "child") {
+		t.Errorf("Remove(base) = %v, want extended-by error", err)
+	}
+	if err := Remove(dir, "child"); err != nil {
+		t.Fatalf("Remove(child): %v", err)
+	}
+	if err := Remove(dir, "base"); err != nil {
+		t.Fatalf("Remove(base): %v", err)
+	}
+	if err := Remove(dir, "base"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Remove(base) again = %v, want ErrNotFound", err)
+	}
+	if _, err := ReadSource(dir, "base"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ReadSource(base) = %v, want ErrNotFound", err)
+	}
+}
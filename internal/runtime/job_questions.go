@@ -0,0 +1,354 @@
+package runtime
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ok-gobot/internal/storage"
+)
+
+// questionTimeLayout matches SQLite's datetime('now') so expiry survives a
+// round trip through the store.
+const questionTimeLayout = "2006-01-02 15:04:05"
+
+// questionDeadlineReserve is how much of a job's remaining time is kept
+// back when a question's timeout is clamped to it, so the job can still use
+// a default answer before it times out.
+const questionDeadlineReserve = 30 * time.Second
+
+// questionPollInterval is how often a waiting job re-reads its question, to
+// see answers written by another process (such as `ok-gobot jobs answer`).
+const questionPollInterval = time.Second
+
+// ErrNotInJob is returned by AskUser when ctx does not belong to a durable job.
+var ErrNotInJob = errors.New("not running inside a durable job")
+
+// ErrNoAnswer is returned when a question times out without a default answer.
+var ErrNoAnswer = errors.New("no answer before the question timed out")
+
+// Question is what a job asks its user.
+type Question struct {
+	Text    string
+	Choices []string // optional; shown as buttons where the channel has them
+	Default string   // used when the timeout passes without an answer
+	Timeout time.Duration
+}
+
+// QuestionHandler posts a new question to a job's user. ChatID on q is the
+// job's delivery chat, or 0 when the job has none.
+type QuestionHandler func(job *storage.Job, q storage.JobQuestion)
+
+// SetQuestionHandler sets the callback that posts questions asked by jobs.
+func (s *JobService) SetQuestionHandler(fn QuestionHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.asker = fn
+}
+
+// AskUser asks the user of the durable job that owns ctx and blocks until
+// an answer arrives, the question times out or ctx ends.
+func AskUser(ctx context.Context, q Question) (string, error) {
+	jc, ok := ctx.Value(jobContextKey{}).(jobContext)
+	if !ok || jc.svc == nil {
+		return "", ErrNotInJob
+	}
+	return jc.svc.Ask(ctx, jc.jobID, q)
+}
+
+// Ask posts q for jobID and waits for the answer. The job is marked
+// waiting_input meanwhile and gives up its scheduler slot, queueing for one
+// again once answered. The question's timeout is clamped to the time the
+// job has left. A resumed attempt asking the same question picks up the
+// question (and any answer) left by the interrupted one.
+func (s *JobService) Ask(ctx context.Context, jobID string, q Question) (string, error) {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		return "", fmt.Errorf("question is required")
+	}
+	job, err := s.store.GetJob(jobID)
+	if err != nil {
+		return "", err
+	}
+	if job == nil {
+		return "", fmt.Errorf("job %q not found", jobID)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline)
+		remaining -= min(questionDeadlineReserve, remaining/4)
+		if q.Timeout <= 0 || q.Timeout > remaining {
+			q.Timeout = max(remaining, time.Second)
+		}
+	}
+
+	question, err := s.store.FindOpenJobQuestion(jobID, q.Text)
+	if err != nil {
+		return "", err
+	}
+	if question == nil {
+		if question, err = s.postQuestion(job, q); err != nil {
+			return "", err
+		}
+	}
+
+	wake := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.questions[question.QuestionID] = wake
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.questions, question.QuestionID)
+		s.mu.Unlock()
+	}()
+
+	if question.Status != storage.JobQuestionPending {
+		return s.awaitAnswer(ctx, question, wake)
+	}
+
+	if err := s.store.MarkJobWaitingInput(jobID); err != nil {
+		return "", err
+	}
+	slot, held := s.sched.suspend(jobID)
+	answer, err := s.awaitAnswer(ctx, question, wake)
+	if held {
+		if resumeErr := s.sched.resume(ctx, slot); resumeErr != nil && err == nil {
+			answer, err = "", resumeErr
+		}
+	}
+	if markErr := s.store.MarkJobRunning(jobID); markErr != nil {
+		log.Printf("[jobs] failed to mark %s running after input: %v", jobID, markErr)
+	}
+	return answer, err
+}
+
+// awaitAnswer blocks until question is answered, expires, is closed or ctx
+// ends.
+func (s *JobService) awaitAnswer(ctx context.Context, question *storage.JobQuestion, wake <-chan struct{}) (string, error) {
+	var expired <-chan time.Time
+	if question.ExpiresAt != "" {
+		if deadline, err := time.ParseInLocation(questionTimeLayout, question.ExpiresAt, time.UTC); err == nil {
+			timer := time.NewTimer(time.Until(deadline))
+			defer timer.Stop()
+			expired = timer.C
+		}
+	}
+	ticker := time.NewTicker(questionPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if question.Status == storage.JobQuestionAnswered {
+			return s.takeAnswer(question)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-expired:
+			return s.expireQuestion(question)
+		case <-wake:
+		case <-ticker.C:
+		}
+		current, err := s.store.GetJobQuestion(question.QuestionID)
+		if err != nil {
+			return "", err
+		}
+		if current == nil || current.Status == storage.JobQuestionClosed {
+			return "", fmt.Errorf("question %s was closed without an answer", question.QuestionID)
+		}
+		question = current
+	}
+}
+
+// AnswerQuestion records answer for a pending question and wakes the job
+// waiting on it. With choices, "2" selects the second choice.
+func (s *JobService) AnswerQuestion(questionID, answer, answeredBy string) (*storage.JobQuestion, error) {
+	return s.AnswerQuestionInChat(0, questionID, answer, answeredBy)
+}
+
+// AnswerQuestionInChat is AnswerQuestion for a caller in chatID: a question
+// posted to another chat is reported as not found. chatID 0 answers any
+// question.
+func (s *JobService) AnswerQuestionInChat(chatID int64, questionID, answer, answeredBy string) (*storage.JobQuestion, error) {
+	question, err := s.store.GetJobQuestion(questionID)
+	if err != nil {
+		return nil, err
+	}
+	if question == nil || (chatID != 0 && question.ChatID != chatID) {
+		return nil, fmt.Errorf("question %q not found", questionID)
+	}
+	answer = ResolveChoice(question.Choices, answer)
+	if answer == "" {
+		return nil, fmt.Errorf("answer is required")
+	}
+	ok, err := s.store.AnswerJobQuestion(question.QuestionID, answer, answeredBy)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("question %q is no longer waiting for an answer", questionID)
+	}
+	question.Status = storage.JobQuestionAnswered
+	question.Answer = answer
+	question.AnsweredBy = answeredBy
+
+	s.mu.Lock()
+	wake := s.questions[question.QuestionID]
+	s.mu.Unlock()
+	if wake != nil {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	return question, nil
+}
+
+// AnswerPending handles "/answer [question-id] <answer>" style input: with
+// no question ID it answers the oldest pending question. A non-zero chatID
+// limits both forms to questions posted to that chat.
+func (s *JobService) AnswerPending(chatID int64, input, answeredBy string) (*storage.JobQuestion, error) {
+	input = strings.TrimSpace(input)
+	if first, rest, _ := strings.Cut(input, " "); strings.HasPrefix(first, "q-") {
+		return s.AnswerQuestionInChat(chatID, first, rest, answeredBy)
+	}
+	pending, err := s.store.ListPendingJobQuestions(chatID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, fmt.Errorf("no job is waiting for an answer")
+	}
+	return s.AnswerQuestion(pending[0].QuestionID, input, answeredBy)
+}
+
+// ResolveChoice maps a 1-based choice number to the choice text. Other
+// answers are returned trimmed.
+func ResolveChoice(choices []string, answer string) string {
+	answer = strings.TrimSpace(answer)
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(choices) {
+		return choices[n-1]
+	}
+	return answer
+}
+
+func (s *JobService) postQuestion(job *storage.Job, q Question) (*storage.JobQuestion, error) {
+	question := storage.JobQuestion{
+		QuestionID:    newQuestionID(),
+		JobID:         job.JobID,
+		Question:      q.Text,
+		Choices:       q.Choices,
+		DefaultAnswer: strings.TrimSpace(q.Default),
+		Status:        storage.JobQuestionPending,
+		ChatID:        s.deliveryChat(job),
+	}
+	if q.Timeout > 0 {
+		question.ExpiresAt = time.Now().UTC().Add(q.Timeout).Format(questionTimeLayout)
+	}
+	if err := s.store.CreateJobQuestion(question); err != nil {
+		return nil, err
+	}
+	if err := s.AppendEvent(job.JobID, JobEventInputRequested, q.Text, map[string]any{
+		"question_id":     question.QuestionID,
+		"choices":         q.Choices,
+		"default":         question.DefaultAnswer,
+		"timeout_seconds": int(q.Timeout.Seconds()),
+	}); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	asker := s.asker
+	s.mu.Unlock()
+	if asker != nil {
+		asker(job, question)
+	} else {
+		log.Printf("[jobs] %s asked %s but no question handler is set", job.JobID, question.QuestionID)
+	}
+	return &question, nil
+}
+
+// takeAnswer closes an answered question and hands its answer to the job.
+func (s *JobService) takeAnswer(question *storage.JobQuestion) (string, error) {
+	if err := s.store.CloseJobQuestion(question.QuestionID); err != nil {
+		return "", err
+	}
+	if err := s.AppendEvent(question.JobID, JobEventInputReceived, question.Answer, map[string]any{
+		"question_id": question.QuestionID,
+		"answered_by": question.AnsweredBy,
+	}); err != nil {
+		return "", err
+	}
+	return question.Answer, nil
+}
+
+// expireQuestion falls back to the default answer, or fails without one.
+func (s *JobService) expireQuestion(question *storage.JobQuestion) (string, error) {
+	if question.DefaultAnswer != "" {
+		ok, err := s.store.AnswerJobQuestion(question.QuestionID, question.DefaultAnswer, "default")
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			question.Answer = question.DefaultAnswer
+			question.AnsweredBy = "default"
+			return s.takeAnswer(question)
+		}
+		// Answered just before the timer fired.
+		current, err := s.store.GetJobQuestion(question.QuestionID)
+		if err != nil {
+			return "", err
+		}
+		if current != nil && current.Status == storage.JobQuestionAnswered {
+			return s.takeAnswer(current)
+		}
+	}
+	if err := s.store.CloseJobQuestion(question.QuestionID); err != nil {
+		return "", err
+	}
+	if err := s.AppendEvent(question.JobID, JobEventInputReceived, "timed out without an answer", map[string]any{
+		"question_id": question.QuestionID,
+		"timed_out":   true,
+	}); err != nil {
+		return "", err
+	}
+	return "", ErrNoAnswer
+}
+
+// closeQuestions closes the questions of a job that has finished.
+func (s *JobService) closeQuestions(jobID string) {
+	if err := s.store.CloseJobQuestions(jobID); err != nil {
+		log.Printf("[jobs] failed to close questions of %s: %v", jobID, err)
+	}
+}
+
+// deliveryChat finds the chat a job reports to: its delivery route, its own
+// session's route, or the nearest parent job's.
+func (s *JobService) deliveryChat(job *storage.Job) int64 {
+	for depth := 0; job != nil && depth < 8; depth++ {
+		for _, key := range []string{job.DeliverySessionKey, job.SessionKey} {
+			route, err := s.store.GetSessionRoute(key)
+			if err == nil && route != nil && route.ChatID != 0 {
+				return route.ChatID
+			}
+		}
+		if job.ParentJobID == "" {
+			return 0
+		}
+		parent, err := s.store.GetJob(job.ParentJobID)
+		if err != nil {
+			return 0
+		}
+		job = parent
+	}
+	return 0
+}
+
+func newQuestionID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("q-%x", b)
+}
@@ -0,0 +1,305 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ok-gobot/internal/storage"
+)
+
+func TestJobServiceAskWaitsForAnswer(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	const routeKey = "agent:test:telegram:group:42"
+	if err := store.SaveSessionRoute(storage.SessionRoute{
+		SessionKey: routeKey,
+		Channel:    "telegram",
+		ChatID:     42,
+	}); err != nil {
+		t.Fatalf("SaveSessionRoute failed: %v", err)
+	}
+
+	asked := make(chan storage.JobQuestion, 1)
+	svc := NewJobService(store)
+	svc.SetQuestionHandler(func(job *storage.Job, q storage.JobQuestion) {
+		asked <- q
+	})
+
+	job, err := svc.StartDetached(context.Background(), JobSpec{
+		Kind:               "background_task",
+		Worker:             "test_runner",
+		SessionKey:         "agent:test:main",
+		DeliverySessionKey: routeKey,
+	}, func(ctx context.Context, job *storage.Job, svc *JobService) (JobRunResult, error) {
+		answer, err := AskUser(ctx, Question{
+			Text:    "Deploy to production?",
+			Choices: []string{"yes", "no"},
+			Timeout: time.Minute,
+		})
+		if err != nil {
+			return JobRunResult{}, err
+		}
+		return JobRunResult{Summary: "answer: " + answer}, nil
+	})
+	if err != nil {
+		t.Fatalf("StartDetached failed: %v", err)
+	}
+
+	var q storage.JobQuestion
+	select {
+	case q = <-asked:
+	case <-time.After(2 * time.Second):
+		t.Fatal("question was not posted")
+	}
+	if q.ChatID != 42 || len(q.Choices) != 2 || q.ExpiresAt == "" {
+		t.Fatalf("unexpected question: %+v", q)
+	}
+	waitForJobStatus(t, store, job.JobID, string(JobStatusWaitingInput))
+
+	// "2" picks the second choice.
+	answered, err := svc.AnswerPending(42, "2", "telegram")
+	if err != nil {
+		t.Fatalf("AnswerPending failed: %v", err)
+	}
+	if answered.QuestionID != q.QuestionID || answered.Answer != "no" {
+		t.Fatalf("unexpected answered question: %+v", answered)
+	}
+
+	done := waitForJobStatus(t, store, job.JobID, string(JobStatusSucceeded))
+	if done.Summary != "answer: no" {
+		t.Fatalf("summary = %q", done.Summary)
+	}
+	stored, _ := store.GetJobQuestion(q.QuestionID)
+	if stored.Status != storage.JobQuestionClosed || stored.AnsweredBy != "telegram" {
+		t.Fatalf("unexpected stored question: %+v", stored)
+	}
+	if _, err := svc.AnswerQuestion(q.QuestionID, "yes", "cli"); err == nil {
+		t.Fatal("answering a closed question should fail")
+	}
+}
+
+func TestJobServiceAskTimeout(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	svc := NewJobService(store)
+	run := func(q Question) (*storage.Job, error) {
+		job, err := svc.StartDetached(context.Background(), JobSpec{
+			Kind:       "background_task",
+			Worker:     "test_runner",
+			SessionKey: "agent:test:main",
+		}, func(ctx context.Context, job *storage.Job, svc *JobService) (JobRunResult, error) {
+			answer, err := AskUser(ctx, q)
+			if errors.Is(err, ErrNoAnswer) {
+				return JobRunResult{Summary: "no answer"}, nil
+			}
+			if err != nil {
+				return JobRunResult{}, err
+			}
+			return JobRunResult{Summary: "answer: " + answer}, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return waitForJobStatus(t, store, job.JobID, string(JobStatusSucceeded)), nil
+	}
+
+	withDefault, err := run(Question{Text: "Which region?", Default: "eu", Timeout: time.Millisecond})
+	if err != nil {
+		t.Fatalf("StartDetached failed: %v", err)
+	}
+	if withDefault.Summary != "answer: eu" {
+		t.Fatalf("summary = %q, want the default answer", withDefault.Summary)
+	}
+	questions, _ := store.ListJobQuestions(withDefault.JobID)
+	if len(questions) != 1 || questions[0].AnsweredBy != "default" {
+		t.Fatalf("unexpected questions: %+v", questions)
+	}
+
+	withoutDefault, err := run(Question{Text: "Which region?", Timeout: time.Millisecond})
+	if err != nil {
+		t.Fatalf("StartDetached failed: %v", err)
+	}
+	if withoutDefault.Summary != "no answer" {
+		t.Fatalf("summary = %q, want ErrNoAnswer", withoutDefault.Summary)
+	}
+}
+
+func TestAskUserOutsideJob(t *testing.T) {
+	t.Parallel()
+
+	if _, err := AskUser(context.Background(), Question{Text: "hello?"}); !errors.Is(err, ErrNotInJob) {
+		t.Fatalf("AskUser outside a job = %v, want ErrNotInJob", err)
+	}
+}
+
+func TestJobServiceReconcileCarriesQuestionsToRetry(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	// A job was waiting for input when the process stopped, and the user
+	// answered while it was down.
+	if err := store.CreateJob(storage.Job{
+		JobID:       "job-waiting",
+		Kind:        "approval",
+		Description: "needs a decision",
+		Status:      string(JobStatusWaitingInput),
+		Attempt:     1,
+		MaxAttempts: 3,
+	}); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	if err := store.CreateJobQuestion(storage.JobQuestion{
+		QuestionID: "q-test",
+		JobID:      "job-waiting",
+		Question:   "Proceed?",
+	}); err != nil {
+		t.Fatalf("CreateJobQuestion failed: %v", err)
+	}
+	svc := NewJobService(store)
+	if _, err := svc.AnswerQuestion("q-test", "go ahead", "cli"); err != nil {
+		t.Fatalf("AnswerQuestion failed: %v", err)
+	}
+
+	svc.SetQuestionHandler(func(job *storage.Job, q storage.JobQuestion) {
+		t.Errorf("resumed attempt re-posted question %s", q.QuestionID)
+	})
+	svc.RegisterKind("approval", ResumeRetry, func(job *storage.Job) (JobRunner, error) {
+		return func(ctx context.Context, job *storage.Job, _ *JobService) (JobRunResult, error) {
+			answer, err := AskUser(ctx, Question{Text: "Proceed?"})
+			if err != nil {
+				return JobRunResult{}, err
+			}
+			return JobRunResult{Summary: answer}, nil
+		}, nil
+	})
+
+	recovered, err := svc.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(recovered) != 1 || recovered[0].RetryJobID == "" {
+		t.Fatalf("unexpected recovery: %+v", recovered)
+	}
+	retry := waitForJobStatus(t, store, recovered[0].RetryJobID, string(JobStatusSucceeded))
+	if retry.Summary != "go ahead" {
+		t.Fatalf("summary = %q, want the answer given during downtime", retry.Summary)
+	}
+	// Waiting for input does not use up an attempt.
+	if retry.Attempt != 1 {
+		t.Fatalf("retry attempt = %d, want 1", retry.Attempt)
+	}
+	q, _ := store.GetJobQuestion("q-test")
+	if q.JobID != retry.JobID || q.Status != storage.JobQuestionClosed {
+		t.Fatalf("question not handed to the retry: %+v", q)
+	}
+}
+
+func TestJobServiceAskFreesSlotAndClampsTimeout(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	asked := make(chan storage.JobQuestion, 1)
+	svc := NewJobService(store)
+	svc.SetSchedulerLimits(SchedulerLimits{MaxConcurrent: 1})
+	svc.SetQuestionHandler(func(job *storage.Job, q storage.JobQuestion) {
+		asked <- q
+	})
+
+	asker, err := svc.StartDetached(context.Background(), JobSpec{
+		Kind:       "background_task",
+		Worker:     "test_runner",
+		SessionKey: "agent:test:main",
+		Timeout:    time.Minute,
+	}, func(ctx context.Context, job *storage.Job, svc *JobService) (JobRunResult, error) {
+		answer, err := AskUser(ctx, Question{Text: "Continue?", Timeout: time.Hour})
+		if err != nil {
+			return JobRunResult{}, err
+		}
+		return JobRunResult{Summary: "answer: " + answer}, nil
+	})
+	if err != nil {
+		t.Fatalf("StartDetached failed: %v", err)
+	}
+
+	var q storage.JobQuestion
+	select {
+	case q = <-asked:
+	case <-time.After(2 * time.Second):
+		t.Fatal("question was not posted")
+	}
+	expires, err := time.ParseInLocation(questionTimeLayout, q.ExpiresAt, time.UTC)
+	if err != nil || time.Until(expires) > time.Minute {
+		t.Fatalf("question expires at %q, want within the job's minute", q.ExpiresAt)
+	}
+
+	// The waiting job holds no slot, so another job runs meanwhile.
+	other, err := svc.StartDetached(context.Background(), JobSpec{
+		Kind:       "background_task",
+		Worker:     "test_runner",
+		SessionKey: "agent:test:other",
+	}, func(ctx context.Context, job *storage.Job, svc *JobService) (JobRunResult, error) {
+		return JobRunResult{Summary: "ran"}, nil
+	})
+	if err != nil {
+		t.Fatalf("StartDetached failed: %v", err)
+	}
+	waitForJobStatus(t, store, other.JobID, string(JobStatusSucceeded))
+
+	if _, err := svc.AnswerQuestion(q.QuestionID, "yes", "cli"); err != nil {
+		t.Fatalf("AnswerQuestion failed: %v", err)
+	}
+	done := waitForJobStatus(t, store, asker.JobID, string(JobStatusSucceeded))
+	if done.Summary != "answer: yes" {
+		t.Fatalf("summary = %q", done.Summary)
+	}
+}
+
+func TestJobServiceAnswerPendingRejectsOtherChats(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	if err := store.CreateJob(storage.Job{
+		JobID:  "job-asking",
+		Kind:   "approval",
+		Status: string(JobStatusWaitingInput),
+	}); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	if err := store.CreateJobQuestion(storage.JobQuestion{
+		QuestionID: "q-chat",
+		JobID:      "job-asking",
+		Question:   "Deploy?",
+		ChatID:     100,
+	}); err != nil {
+		t.Fatalf("CreateJobQuestion failed: %v", err)
+	}
+	svc := NewJobService(store)
+
+	if _, err := svc.AnswerPending(200, "q-chat yes", "telegram"); err == nil {
+		t.Fatal("answer from another chat was accepted")
+	}
+	if _, err := svc.AnswerQuestionInChat(200, "q-chat", "yes", "telegram"); err == nil {
+		t.Fatal("callback from another chat was accepted")
+	}
+	q, err := svc.AnswerPending(100, "q-chat yes", "telegram")
+	if err != nil {
+		t.Fatalf("AnswerPending from the question's chat failed: %v", err)
+	}
+	if q.Answer != "yes" {
+		t.Fatalf("answer = %q", q.Answer)
+	}
+}
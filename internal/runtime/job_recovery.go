@@ -0,0 +1,408 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ok-gobot/internal/storage"
+)
+
+// ResumePolicy decides what happens to a job of a given kind that was still
+// pending or running when the process stopped.
+type ResumePolicy string
+
+const (
+	// ResumeAbandon leaves the interrupted job alone. It is the default for
+	// kinds that were never registered.
+	ResumeAbandon ResumePolicy = "abandon"
+	// ResumeRetry starts the job again from scratch as a new attempt.
+	ResumeRetry ResumePolicy = "retry"
+	// ResumeFromCheckpoint starts a new attempt that receives the last
+	// checkpoint saved by the interrupted one.
+	ResumeFromCheckpoint ResumePolicy = "resume-from-checkpoint"
+)
+
+// JobRunnerFactory rebuilds the runner for a persisted job after a restart.
+type JobRunnerFactory func(job *storage.Job) (JobRunner, error)
+
+type jobKind struct {
+	policy  ResumePolicy
+	factory JobRunnerFactory
+}
+
+// JobRecovery records what Reconcile did with one orphaned job.
+type JobRecovery struct {
+	JobID          string
+	Kind           string
+	PreviousStatus string
+	Policy         ResumePolicy
+	// RetryJobID is the new attempt started for the job, if any.
+	RetryJobID string
+	// Outcome is a short human-readable description of the decision.
+	Outcome string
+}
+
+// RegisterKind sets the resume policy for a job kind and the factory that
+// rebuilds its runner from the persisted row. Retry policies need a factory;
+// without one an interrupted job of that kind is abandoned.
+func (s *JobService) RegisterKind(kind string, policy ResumePolicy, factory JobRunnerFactory) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kinds[strings.TrimSpace(kind)] = jobKind{policy: policy, factory: factory}
+}
+
+// SetNotifier sets the callback used to tell a job's delivery chat about
+// recovery decisions.
+func (s *JobService) SetNotifier(fn func(chatID int64, message string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifier = fn
+}
+
+// SaveCheckpoint persists resumable state for a running job. Only the latest
+// checkpoint is handed to a resumed attempt, so it replaces the earlier ones.
+func (s *JobService) SaveCheckpoint(jobID string, state any) error {
+	return s.replaceCheckpoint(jobID, "checkpoint saved", state)
+}
+
+// LatestCheckpoint returns the payload of the most recent checkpoint saved by
+// the job, if any.
+func (s *JobService) LatestCheckpoint(jobID string) (string, bool, error) {
+	event, err := s.store.GetLatestJobEvent(jobID, string(JobEventCheckpoint))
+	if err != nil || event == nil {
+		return "", false, err
+	}
+	return event.Payload, true, nil
+}
+
+// carryCheckpoint copies a checkpoint onto a new attempt, so a second
+// interruption resumes from it too.
+func (s *JobService) carryCheckpoint(fromJobID, toJobID, checkpoint string) error {
+	if checkpoint == "" {
+		return nil
+	}
+	return s.replaceCheckpoint(toJobID, "checkpoint carried from "+fromJobID, checkpoint)
+}
+
+func (s *JobService) replaceCheckpoint(jobID, message string, state any) error {
+	payload, err := marshalPayload(state)
+	if err != nil {
+		return err
+	}
+	return s.store.ReplaceJobEvent(storage.JobEvent{
+		JobID:     strings.TrimSpace(jobID),
+		EventType: string(JobEventCheckpoint),
+		Message:   message,
+		Payload:   payload,
+	})
+}
+
+// SaveContextCheckpoint saves a checkpoint for the durable job that owns ctx.
+// It is a no-op when ctx does not belong to a job.
+func SaveContextCheckpoint(ctx context.Context, state any) error {
+	jc, ok := ctx.Value(jobContextKey{}).(jobContext)
+	if !ok || jc.svc == nil {
+		return nil
+	}
+	return jc.svc.SaveCheckpoint(jc.jobID, state)
+}
+
+// ResumeCheckpointFromContext returns the checkpoint payload a resumed
+// attempt was started with.
+func ResumeCheckpointFromContext(ctx context.Context) (string, bool) {
+	jc, ok := ctx.Value(jobContextKey{}).(jobContext)
+	if !ok || jc.checkpoint == "" {
+		return "", false
+	}
+	return jc.checkpoint, true
+}
+
+// Reconcile finds jobs left pending, running or waiting for input by a
+// previous process, marks them interrupted and re-queues them according to
+// their kind's resume policy. It must run at startup, before new jobs are started; jobs running
+// in this process are skipped.
+func (s *JobService) Reconcile(ctx context.Context) ([]JobRecovery, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("job storage is required")
+	}
+
+	// Retries queued by another process are not orphans: start them first
+	// so the scan below skips them.
+	if _, err := s.StartQueued(ctx); err != nil {
+		return nil, err
+	}
+
+	var orphans []storage.Job
+	for _, status := range []JobStatus{JobStatusRunning, JobStatusWaitingInput, JobStatusPending} {
+		jobs, err := s.store.ListJobsByStatus(string(status), 1000)
+		if err != nil {
+			return nil, fmt.Errorf("list %s jobs: %w", status, err)
+		}
+		orphans = append(orphans, jobs...)
+	}
+
+	var recovered []JobRecovery
+	// Oldest first, so re-queued attempts start in their original order.
+	for i := len(orphans) - 1; i >= 0; i-- {
+		job := orphans[i]
+		// Jobs started in this process since the scan are claimed before
+		// their rows exist; claiming here also keeps StartQueued away.
+		if !s.claim(job.JobID) {
+			continue
+		}
+		rec, settled, err := s.recover(ctx, &job)
+		s.unregisterCancel(job.JobID)
+		if err != nil {
+			return recovered, fmt.Errorf("recover job %s: %w", job.JobID, err)
+		}
+		if settled {
+			continue
+		}
+		// Open questions follow the job to its new attempt, which picks
+		// them up when it asks again.
+		if rec.RetryJobID != "" {
+			err = s.store.MoveJobQuestions(job.JobID, rec.RetryJobID)
+		} else {
+			err = s.store.CloseJobQuestions(job.JobID)
+		}
+		if err != nil {
+			return recovered, fmt.Errorf("recover questions of job %s: %w", job.JobID, err)
+		}
+		recovered = append(recovered, rec)
+		s.notifyRecovery(&job, rec)
+	}
+	return recovered, nil
+}
+
+// StartQueued starts retry attempts that another process (such as
+// `ok-gobot jobs retry`) queued in storage, using the runner factory
+// registered for their kind. A queued resume retry carries the checkpoint it
+// continues from.
+func (s *JobService) StartQueued(ctx context.Context) ([]*storage.Job, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("job storage is required")
+	}
+	pending, err := s.store.ListJobsByStatus(string(JobStatusPending), 1000)
+	if err != nil {
+		return nil, fmt.Errorf("list pending jobs: %w", err)
+	}
+
+	var started []*storage.Job
+	for i := len(pending) - 1; i >= 0; i-- {
+		job := pending[i]
+		if job.RetryOfJobID == "" || !s.claim(job.JobID) {
+			continue
+		}
+		ok, err := s.startQueued(ctx, &job)
+		if err != nil {
+			return started, err
+		}
+		if ok {
+			started = append(started, &job)
+		}
+	}
+	return started, nil
+}
+
+// startQueued starts one claimed queued retry and reports whether it did.
+// The claim is released when the job is not started here.
+func (s *JobService) startQueued(ctx context.Context, job *storage.Job) (bool, error) {
+	// The pending list may predate a run of this job that has since
+	// finished here; only a row that is still pending is started.
+	current, err := s.store.GetJob(job.JobID)
+	if err != nil {
+		s.unregisterCancel(job.JobID)
+		return false, err
+	}
+	if current == nil || current.Status != string(JobStatusPending) {
+		s.unregisterCancel(job.JobID)
+		return false, nil
+	}
+	*job = *current
+
+	s.mu.Lock()
+	kind := s.kinds[job.Kind]
+	s.mu.Unlock()
+	if kind.factory == nil {
+		s.unregisterCancel(job.JobID)
+		return false, nil
+	}
+
+	runner, err := kind.factory(job)
+	if err != nil {
+		s.unregisterCancel(job.JobID)
+		msg := fmt.Sprintf("cannot start queued retry: %v", err)
+		if err := s.store.MarkJobFailed(job.JobID, msg); err != nil {
+			return false, err
+		}
+		return false, s.AppendEvent(job.JobID, JobEventFailed, msg, nil)
+	}
+	checkpoint, _, err := s.LatestCheckpoint(job.JobID)
+	if err != nil {
+		s.unregisterCancel(job.JobID)
+		return false, err
+	}
+	s.start(ctx, job, time.Duration(job.TimeoutSeconds)*time.Second, runner, checkpoint, false)
+	return true, nil
+}
+
+// WatchQueued polls for queued retries until ctx is done.
+func (s *JobService) WatchQueued(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			started, err := s.StartQueued(ctx)
+			if err != nil {
+				log.Printf("[jobs] failed to start queued retries: %v", err)
+			}
+			for _, job := range started {
+				log.Printf("[jobs] started queued retry %s of %s", job.JobID, job.RetryOfJobID)
+			}
+		}
+	}
+}
+
+// recover settles one orphaned job and re-queues it by its kind's policy.
+// It reports settled when the job finished after the scan listed it, in
+// which case nothing is changed.
+func (s *JobService) recover(ctx context.Context, job *storage.Job) (JobRecovery, bool, error) {
+	s.mu.Lock()
+	kind, registered := s.kinds[job.Kind]
+	s.mu.Unlock()
+	if !registered || kind.policy == "" {
+		kind.policy = ResumeAbandon
+	}
+
+	rec := JobRecovery{
+		JobID:          job.JobID,
+		Kind:           job.Kind,
+		PreviousStatus: job.Status,
+		Policy:         kind.policy,
+	}
+
+	if job.CancelRequested {
+		if ok, err := s.store.MarkActiveJobCancelled(job.JobID, "cancelled before restart"); err != nil || !ok {
+			return rec, !ok, err
+		}
+		rec.Outcome = "cancelled (cancel was requested before the restart)"
+		return rec, false, s.AppendEvent(job.JobID, JobEventCancelled, "cancelled before restart", nil)
+	}
+
+	reason := fmt.Sprintf("interrupted by restart while %s", job.Status)
+	if ok, err := s.store.MarkJobInterrupted(job.JobID, reason); err != nil || !ok {
+		return rec, !ok, err
+	}
+	if err := s.AppendEvent(job.JobID, JobEventInterrupted, reason, map[string]any{
+		"previous_status": job.Status,
+		"policy":          string(kind.policy),
+	}); err != nil {
+		return rec, false, err
+	}
+
+	if kind.policy == ResumeAbandon {
+		rec.Outcome = "abandoned"
+		return rec, false, nil
+	}
+	if kind.factory == nil {
+		rec.Outcome = "abandoned (no runner registered for this kind)"
+		return rec, false, nil
+	}
+
+	// A job that never started has not used up its attempt, and neither
+	// has one that was only waiting for its user.
+	attempt := job.Attempt
+	if job.Status == string(JobStatusRunning) {
+		attempt++
+	}
+	if job.MaxAttempts > 0 && attempt > job.MaxAttempts {
+		rec.Outcome = fmt.Sprintf("abandoned (reached max attempts %d)", job.MaxAttempts)
+		return rec, false, nil
+	}
+
+	runner, err := kind.factory(job)
+	if err != nil {
+		rec.Outcome = fmt.Sprintf("abandoned (could not rebuild runner: %v)", err)
+		return rec, false, nil
+	}
+
+	var checkpoint string
+	if kind.policy == ResumeFromCheckpoint {
+		if checkpoint, _, err = s.LatestCheckpoint(job.JobID); err != nil {
+			return rec, false, err
+		}
+	}
+
+	// Claim the attempt before its pending row exists, as
+	// RetryDetachedWithMode does.
+	retryID := newJobID()
+	s.claim(retryID)
+	retryJob, err := s.createJob(JobSpec{
+		JobID:              retryID,
+		Kind:               job.Kind,
+		Worker:             job.Worker,
+		SessionKey:         job.SessionKey,
+		DeliverySessionKey: job.DeliverySessionKey,
+		RetryOfJobID:       job.JobID,
+		ParentJobID:        job.ParentJobID,
+		Priority:           JobPriority(job.Priority),
+		CostTier:           CostTier(job.CostTier),
+		Description:        job.Description,
+		Attempt:            attempt,
+		MaxAttempts:        job.MaxAttempts,
+		Timeout:            time.Duration(job.TimeoutSeconds) * time.Second,
+	})
+	if err != nil {
+		s.unregisterCancel(retryID)
+		rec.Outcome = fmt.Sprintf("abandoned (could not re-queue: %v)", err)
+		return rec, false, nil
+	}
+	if err := s.carryCheckpoint(job.JobID, retryJob.JobID, checkpoint); err != nil {
+		s.unregisterCancel(retryID)
+		return rec, false, err
+	}
+	if err := s.AppendEvent(job.JobID, JobEventRetryRequested, fmt.Sprintf("re-queued as %s after restart", retryJob.JobID), map[string]any{
+		"retry_job_id": retryJob.JobID,
+		"policy":       string(kind.policy),
+	}); err != nil {
+		s.unregisterCancel(retryID)
+		return rec, false, err
+	}
+
+	rec.RetryJobID = retryJob.JobID
+	if checkpoint != "" {
+		rec.Outcome = "resumed from checkpoint as " + retryJob.JobID
+	} else {
+		rec.Outcome = "restarted as " + retryJob.JobID
+	}
+	s.start(ctx, retryJob, time.Duration(job.TimeoutSeconds)*time.Second, runner, checkpoint, false)
+	return rec, false, nil
+}
+
+func (s *JobService) notifyRecovery(job *storage.Job, rec JobRecovery) {
+	log.Printf("[jobs] recovered %s (%s, was %s): %s", rec.JobID, rec.Kind, rec.PreviousStatus, rec.Outcome)
+
+	s.mu.Lock()
+	notify := s.notifier
+	s.mu.Unlock()
+	routeKey := strings.TrimSpace(job.DeliverySessionKey)
+	if notify == nil || routeKey == "" {
+		return
+	}
+	route, err := s.store.GetSessionRoute(routeKey)
+	if err != nil || route == nil || route.ChatID == 0 {
+		return
+	}
+
+	label := job.Description
+	if label == "" {
+		label = job.Kind
+	}
+	notify(route.ChatID, fmt.Sprintf("⚠️ Job %s (%s) was interrupted by a restart: %s.", job.JobID, label, rec.Outcome))
+}
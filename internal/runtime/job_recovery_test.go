@@ -0,0 +1,364 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"ok-gobot/internal/storage"
+)
+
+func TestJobServiceReconcileAppliesResumePolicies(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	const routeKey = "agent:test:telegram:group:77"
+	if err := store.SaveSessionRoute(storage.SessionRoute{
+		SessionKey: routeKey,
+		Channel:    "telegram",
+		ChatID:     77,
+	}); err != nil {
+		t.Fatalf("SaveSessionRoute failed: %v", err)
+	}
+
+	orphan := func(id, kind, status string, attempt, maxAttempts int) {
+		t.Helper()
+		if err := store.CreateJob(storage.Job{
+			JobID:              id,
+			Kind:               kind,
+			DeliverySessionKey: routeKey,
+			Description:        id,
+			Status:             status,
+			Attempt:            attempt,
+			MaxAttempts:        maxAttempts,
+		}); err != nil {
+			t.Fatalf("CreateJob(%s) failed: %v", id, err)
+		}
+	}
+	orphan("job-retry", "sync", string(JobStatusRunning), 1, 2)
+	orphan("job-exhausted", "sync", string(JobStatusRunning), 2, 2)
+	orphan("job-queued", "sync", string(JobStatusPending), 2, 2)
+	orphan("job-resume", "crawl", string(JobStatusRunning), 1, 3)
+	orphan("job-unknown", "mystery", string(JobStatusRunning), 1, 3)
+
+	var (
+		mu       sync.Mutex
+		notices  []string
+		resumeCh = make(chan string, 1)
+	)
+	svc := NewJobService(store)
+	svc.SetNotifier(func(chatID int64, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if chatID != 77 {
+			t.Errorf("notice sent to chat %d", chatID)
+		}
+		notices = append(notices, message)
+	})
+	svc.RegisterKind("sync", ResumeRetry, func(job *storage.Job) (JobRunner, error) {
+		return func(ctx context.Context, job *storage.Job, _ *JobService) (JobRunResult, error) {
+			return JobRunResult{Summary: "synced"}, nil
+		}, nil
+	})
+	svc.RegisterKind("crawl", ResumeFromCheckpoint, func(job *storage.Job) (JobRunner, error) {
+		return func(ctx context.Context, job *storage.Job, _ *JobService) (JobRunResult, error) {
+			checkpoint, _ := ResumeCheckpointFromContext(ctx)
+			resumeCh <- checkpoint
+			return JobRunResult{Summary: "crawled"}, nil
+		}, nil
+	})
+	if err := svc.SaveCheckpoint("job-resume", map[string]any{"page": 4}); err != nil {
+		t.Fatalf("SaveCheckpoint failed: %v", err)
+	}
+
+	recovered, err := svc.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(recovered) != 5 {
+		t.Fatalf("expected 5 recovered jobs, got %+v", recovered)
+	}
+	byID := map[string]JobRecovery{}
+	for _, rec := range recovered {
+		byID[rec.JobID] = rec
+		job, _ := store.GetJob(rec.JobID)
+		if job.Status != string(JobStatusInterrupted) {
+			t.Errorf("%s status = %q, want interrupted", rec.JobID, job.Status)
+		}
+	}
+
+	retry := waitForJobStatus(t, store, byID["job-retry"].RetryJobID, string(JobStatusSucceeded))
+	if retry.Attempt != 2 || retry.RetryOfJobID != "job-retry" {
+		t.Errorf("unexpected retry attempt: %+v", retry)
+	}
+	if byID["job-exhausted"].RetryJobID != "" || !strings.Contains(byID["job-exhausted"].Outcome, "max attempts") {
+		t.Errorf("exhausted job should not be retried: %+v", byID["job-exhausted"])
+	}
+	// A job that never started keeps its attempt number.
+	queued := waitForJobStatus(t, store, byID["job-queued"].RetryJobID, string(JobStatusSucceeded))
+	if queued.Attempt != 2 {
+		t.Errorf("queued job attempt = %d, want 2", queued.Attempt)
+	}
+	if byID["job-unknown"].Policy != ResumeAbandon || byID["job-unknown"].RetryJobID != "" {
+		t.Errorf("unregistered kind should be abandoned: %+v", byID["job-unknown"])
+	}
+
+	if got := <-resumeCh; got != `{"page":4}` {
+		t.Errorf("resumed attempt got checkpoint %q", got)
+	}
+	resumed := waitForJobStatus(t, store, byID["job-resume"].RetryJobID, string(JobStatusSucceeded))
+	if checkpoint, ok, _ := svc.LatestCheckpoint(resumed.JobID); !ok || checkpoint != `{"page":4}` {
+		t.Errorf("checkpoint not carried to the new attempt: %q %v", checkpoint, ok)
+	}
+
+	events, _ := store.ListJobEvents("job-retry", 10)
+	var types []string
+	for _, e := range events {
+		types = append(types, e.EventType)
+	}
+	if strings.Join(types, ",") != "interrupted,retry_requested" {
+		t.Errorf("unexpected events for job-retry: %v", types)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(notices) != 5 || !strings.Contains(strings.Join(notices, "\n"), "job-retry (job-retry) was interrupted by a restart: restarted as") {
+		t.Errorf("unexpected notices: %v", notices)
+	}
+
+	// A second pass finds nothing left to recover.
+	again, err := svc.Reconcile(context.Background())
+	if err != nil || len(again) != 0 {
+		t.Fatalf("second Reconcile = %+v, %v", again, err)
+	}
+}
+
+func TestJobServiceRetryResumeCarriesCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	svc := NewJobService(store)
+	original, err := svc.StartDetached(context.Background(), JobSpec{
+		Kind:        "crawl",
+		Description: "crawl pages",
+		MaxAttempts: 3,
+	}, func(ctx context.Context, job *storage.Job, _ *JobService) (JobRunResult, error) {
+		if _, ok := ResumeCheckpointFromContext(ctx); ok {
+			t.Error("first attempt should not get a checkpoint")
+		}
+		if err := SaveContextCheckpoint(ctx, map[string]int{"page": 7}); err != nil {
+			return JobRunResult{}, err
+		}
+		return JobRunResult{}, context.DeadlineExceeded
+	})
+	if err != nil {
+		t.Fatalf("StartDetached failed: %v", err)
+	}
+	waitForJobStatus(t, store, original.JobID, string(JobStatusTimedOut))
+
+	got := make(chan string, 1)
+	svc.RegisterKind("crawl", ResumeAbandon, func(job *storage.Job) (JobRunner, error) {
+		return func(ctx context.Context, job *storage.Job, _ *JobService) (JobRunResult, error) {
+			checkpoint, _ := ResumeCheckpointFromContext(ctx)
+			got <- checkpoint
+			return JobRunResult{Summary: "done"}, nil
+		}, nil
+	})
+
+	retry, err := svc.Retry(context.Background(), original.JobID, RetryResume)
+	if err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if checkpoint := <-got; checkpoint != `{"page":7}` {
+		t.Fatalf("resumed attempt got checkpoint %q", checkpoint)
+	}
+	waitForJobStatus(t, store, retry.JobID, string(JobStatusSucceeded))
+
+	// A restart retry starts without the checkpoint.
+	restart, err := svc.Retry(context.Background(), retry.JobID, RetryRestart)
+	if err != nil {
+		t.Fatalf("Retry(restart) failed: %v", err)
+	}
+	if checkpoint := <-got; checkpoint != "" {
+		t.Fatalf("restart attempt got checkpoint %q", checkpoint)
+	}
+	waitForJobStatus(t, store, restart.JobID, string(JobStatusSucceeded))
+
+	if _, err := svc.Retry(context.Background(), restart.JobID, RetryResume); err == nil || !strings.Contains(err.Error(), "max attempts") {
+		t.Fatalf("expected max attempts error, got %v", err)
+	}
+}
+
+func TestJobServiceStartQueuedRunsStoredRetries(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	for _, job := range []storage.Job{
+		{JobID: "job-orig", Kind: "crawl", Status: string(JobStatusFailed), Attempt: 1, MaxAttempts: 2},
+		{JobID: "job-queued", Kind: "crawl", Status: string(JobStatusPending), RetryOfJobID: "job-orig", Attempt: 2, MaxAttempts: 2},
+		{JobID: "job-fresh", Kind: "crawl", Status: string(JobStatusPending), Attempt: 1, MaxAttempts: 1},
+	} {
+		if err := store.CreateJob(job); err != nil {
+			t.Fatalf("CreateJob failed: %v", err)
+		}
+	}
+
+	svc := NewJobService(store)
+	if err := svc.SaveCheckpoint("job-queued", map[string]int{"page": 2}); err != nil {
+		t.Fatalf("SaveCheckpoint failed: %v", err)
+	}
+	got := make(chan string, 1)
+	svc.RegisterKind("crawl", ResumeAbandon, func(job *storage.Job) (JobRunner, error) {
+		return func(ctx context.Context, job *storage.Job, _ *JobService) (JobRunResult, error) {
+			checkpoint, _ := ResumeCheckpointFromContext(ctx)
+			got <- checkpoint
+			return JobRunResult{Summary: "done"}, nil
+		}, nil
+	})
+
+	started, err := svc.StartQueued(context.Background())
+	if err != nil {
+		t.Fatalf("StartQueued failed: %v", err)
+	}
+	if len(started) != 1 || started[0].JobID != "job-queued" {
+		t.Fatalf("expected only the queued retry to start, got %+v", started)
+	}
+	if checkpoint := <-got; checkpoint != `{"page":2}` {
+		t.Fatalf("queued retry got checkpoint %q", checkpoint)
+	}
+	waitForJobStatus(t, store, "job-queued", string(JobStatusSucceeded))
+
+	fresh, _ := store.GetJob("job-fresh")
+	if fresh.Status != string(JobStatusPending) {
+		t.Fatalf("non-retry pending job should be left alone, got %q", fresh.Status)
+	}
+}
+
+func TestJobServiceRetryIsNotStartedTwiceByStartQueued(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	if err := store.CreateJob(storage.Job{JobID: "job-orig", Kind: "crawl", Status: string(JobStatusFailed), Attempt: 1, MaxAttempts: 100}); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+
+	svc := NewJobService(store)
+	var mu sync.Mutex
+	runs := map[string]int{}
+	svc.RegisterKind("crawl", ResumeAbandon, func(job *storage.Job) (JobRunner, error) {
+		return func(ctx context.Context, job *storage.Job, _ *JobService) (JobRunResult, error) {
+			mu.Lock()
+			runs[job.JobID]++
+			mu.Unlock()
+			return JobRunResult{Summary: "done"}, nil
+		}, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watcherDone := make(chan struct{})
+	go func() {
+		defer close(watcherDone)
+		for ctx.Err() == nil {
+			if _, err := svc.StartQueued(ctx); err != nil {
+				t.Errorf("StartQueued failed: %v", err)
+				return
+			}
+		}
+	}()
+
+	prev := "job-orig"
+	for i := 0; i < 20; i++ {
+		retry, err := svc.Retry(context.Background(), prev, RetryRestart)
+		if err != nil {
+			t.Fatalf("Retry failed: %v", err)
+		}
+		waitForJobStatus(t, store, retry.JobID, string(JobStatusSucceeded))
+		prev = retry.JobID
+	}
+	cancel()
+	<-watcherDone
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id, n := range runs {
+		if n != 1 {
+			t.Errorf("retry %s ran %d times, want 1", id, n)
+		}
+	}
+}
+
+func TestJobServiceSaveCheckpointKeepsOnlyLatest(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	if err := store.CreateJob(storage.Job{JobID: "job-cp", Kind: "crawl", Status: string(JobStatusRunning)}); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+	svc := NewJobService(store)
+	for page := 1; page <= 3; page++ {
+		if err := svc.SaveCheckpoint("job-cp", map[string]int{"page": page}); err != nil {
+			t.Fatalf("SaveCheckpoint failed: %v", err)
+		}
+	}
+
+	checkpoint, ok, err := svc.LatestCheckpoint("job-cp")
+	if err != nil || !ok || checkpoint != `{"page":3}` {
+		t.Fatalf("LatestCheckpoint = %q, %v, %v; want page 3", checkpoint, ok, err)
+	}
+	events, err := store.ListJobEvents("job-cp", 100)
+	if err != nil {
+		t.Fatalf("ListJobEvents failed: %v", err)
+	}
+	var saved int
+	for _, ev := range events {
+		if ev.EventType == string(JobEventCheckpoint) {
+			saved++
+		}
+	}
+	if saved != 1 {
+		t.Errorf("stored %d checkpoint events, want 1", saved)
+	}
+}
+
+func TestJobServiceReconcileSkipsClaimedJobs(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	svc := NewJobService(store)
+	// A job StartDetached has claimed but not yet registered with start.
+	svc.claim("job-starting")
+	if err := store.CreateJob(storage.Job{JobID: "job-starting", Kind: "sync", Status: string(JobStatusPending), Attempt: 1, MaxAttempts: 1}); err != nil {
+		t.Fatalf("CreateJob failed: %v", err)
+	}
+
+	recovered, err := svc.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if len(recovered) != 0 {
+		t.Fatalf("recovered = %+v, want none", recovered)
+	}
+	if got, _ := store.GetJob("job-starting"); got == nil || got.Status != string(JobStatusPending) {
+		t.Fatalf("claimed job = %+v, want it left pending", got)
+	}
+
+	if _, err := svc.StartDetached(context.Background(), JobSpec{JobID: "job-starting", Kind: "sync"}, func(context.Context, *storage.Job, *JobService) (JobRunResult, error) {
+		return JobRunResult{}, nil
+	}); err == nil {
+		t.Fatal("StartDetached reused a claimed job ID")
+	}
+}
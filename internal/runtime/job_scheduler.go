@@ -0,0 +1,271 @@
+package runtime
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobPriority orders queued jobs. Interactive work a user is waiting on in
+// chat (/task) runs before background user jobs (workflows, role runs,
+// routed background jobs), which run before scheduled (cron) jobs.
+type JobPriority string
+
+const (
+	JobPriorityInteractive JobPriority = "interactive"
+	JobPriorityUser        JobPriority = "user"
+	JobPriorityCron        JobPriority = "cron"
+)
+
+// ParseJobPriority normalizes s into a JobPriority. Empty maps to user.
+func ParseJobPriority(s string) (JobPriority, bool) {
+	switch JobPriority(strings.ToLower(strings.TrimSpace(s))) {
+	case JobPriorityInteractive:
+		return JobPriorityInteractive, true
+	case JobPriorityUser, "":
+		return JobPriorityUser, true
+	case JobPriorityCron:
+		return JobPriorityCron, true
+	}
+	return "", false
+}
+
+func (p JobPriority) rank() int {
+	switch p {
+	case JobPriorityInteractive:
+		return 2
+	case JobPriorityCron:
+		return 0
+	}
+	return 1
+}
+
+// SchedulerLimits caps how many jobs run at once. Zero or missing entries
+// mean no limit.
+type SchedulerLimits struct {
+	MaxConcurrent int
+	PerTier       map[CostTier]int
+	PerWorker     map[string]int
+}
+
+// JobQueueEntry describes a job held by the scheduler.
+type JobQueueEntry struct {
+	JobID      string      `json:"job_id"`
+	Kind       string      `json:"kind"`
+	Worker     string      `json:"worker,omitempty"`
+	CostTier   CostTier    `json:"cost_tier"`
+	Priority   JobPriority `json:"priority"`
+	Running    bool        `json:"running"`
+	Position   int         `json:"queue_position,omitempty"` // 1-based; 0 while running
+	EnqueuedAt time.Time   `json:"enqueued_at"`
+
+	fairKey  string
+	seq      uint64
+	reported int
+	launch   func()
+}
+
+// jobScheduler admits queued jobs by priority, round-robin across chats
+// within a priority, subject to global, per-tier and per-worker limits.
+type jobScheduler struct {
+	mu       sync.Mutex
+	limits   SchedulerLimits
+	queue    []*JobQueueEntry
+	running  map[string]*JobQueueEntry
+	seq      uint64
+	served   uint64
+	lastSeen map[string]uint64 // fair key -> dispatch counter when last served
+
+	// onPosition is told when a queued job's position changes.
+	onPosition func(entry JobQueueEntry)
+}
+
+func newJobScheduler() *jobScheduler {
+	return &jobScheduler{
+		running:  make(map[string]*JobQueueEntry),
+		lastSeen: make(map[string]uint64),
+	}
+}
+
+func (q *jobScheduler) setLimits(limits SchedulerLimits) {
+	q.mu.Lock()
+	q.limits = limits
+	q.mu.Unlock()
+	q.dispatch()
+}
+
+// enqueue adds a job and starts whatever the limits allow.
+func (q *jobScheduler) enqueue(entry *JobQueueEntry) {
+	q.mu.Lock()
+	q.seq++
+	entry.seq = q.seq
+	entry.EnqueuedAt = time.Now()
+	q.queue = append(q.queue, entry)
+	q.mu.Unlock()
+	q.dispatch()
+}
+
+// remove drops a job that has not started yet. It reports false when the
+// job is running or unknown.
+func (q *jobScheduler) remove(jobID string) bool {
+	q.mu.Lock()
+	found := false
+	for i, entry := range q.queue {
+		if entry.JobID == jobID {
+			q.queue = append(q.queue[:i], q.queue[i+1:]...)
+			found = true
+			break
+		}
+	}
+	q.mu.Unlock()
+	if found {
+		q.dispatch()
+	}
+	return found
+}
+
+// release frees the slot of a finished job.
+func (q *jobScheduler) release(jobID string) {
+	q.mu.Lock()
+	delete(q.running, jobID)
+	q.mu.Unlock()
+	q.dispatch()
+}
+
+// suspend frees the slot of a running job that is waiting for its user, so
+// other jobs can run meanwhile. It reports false when the job holds no slot.
+func (q *jobScheduler) suspend(jobID string) (*JobQueueEntry, bool) {
+	q.mu.Lock()
+	entry, ok := q.running[jobID]
+	delete(q.running, jobID)
+	q.mu.Unlock()
+	if ok {
+		q.dispatch()
+	}
+	return entry, ok
+}
+
+// resume queues a suspended job again and blocks until it is given a slot
+// or ctx ends.
+func (q *jobScheduler) resume(ctx context.Context, entry *JobQueueEntry) error {
+	admitted := make(chan struct{})
+	entry.Running = false
+	entry.reported = 0
+	entry.launch = func() { close(admitted) }
+	q.enqueue(entry)
+	select {
+	case <-admitted:
+		return nil
+	case <-ctx.Done():
+		q.remove(entry.JobID)
+		return ctx.Err()
+	}
+}
+
+// dispatch launches every queued job that fits the limits, then reports
+// changed queue positions.
+func (q *jobScheduler) dispatch() {
+	q.mu.Lock()
+	var launch []func()
+	for {
+		q.order()
+		idx := -1
+		for i, entry := range q.queue {
+			if q.fits(entry) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			break
+		}
+		entry := q.queue[idx]
+		q.queue = append(q.queue[:idx], q.queue[idx+1:]...)
+		q.running[entry.JobID] = entry
+		entry.Running = true
+		entry.Position = 0
+		q.served++
+		q.lastSeen[entry.fairKey] = q.served
+		launch = append(launch, entry.launch)
+	}
+
+	var moved []JobQueueEntry
+	for i, entry := range q.queue {
+		entry.Position = i + 1
+		if entry.reported != entry.Position {
+			entry.reported = entry.Position
+			moved = append(moved, *entry)
+		}
+	}
+	notify := q.onPosition
+	q.mu.Unlock()
+
+	for _, fn := range launch {
+		go fn()
+	}
+	if notify != nil {
+		for _, entry := range moved {
+			notify(entry)
+		}
+	}
+}
+
+// order sorts the queue by priority, then by how long ago each chat was
+// last served, then by arrival. Callers hold q.mu.
+func (q *jobScheduler) order() {
+	sort.SliceStable(q.queue, func(i, j int) bool {
+		a, b := q.queue[i], q.queue[j]
+		if a.Priority.rank() != b.Priority.rank() {
+			return a.Priority.rank() > b.Priority.rank()
+		}
+		if la, lb := q.lastSeen[a.fairKey], q.lastSeen[b.fairKey]; la != lb {
+			return la < lb
+		}
+		return a.seq < b.seq
+	})
+}
+
+// fits reports whether entry can start under the limits. Callers hold q.mu.
+func (q *jobScheduler) fits(entry *JobQueueEntry) bool {
+	if q.limits.MaxConcurrent > 0 && len(q.running) >= q.limits.MaxConcurrent {
+		return false
+	}
+	tierLimit := q.limits.PerTier[entry.CostTier]
+	workerLimit := q.limits.PerWorker[entry.Worker]
+	if tierLimit <= 0 && workerLimit <= 0 {
+		return true
+	}
+	tierCount, workerCount := 0, 0
+	for _, r := range q.running {
+		if r.CostTier == entry.CostTier {
+			tierCount++
+		}
+		if r.Worker == entry.Worker {
+			workerCount++
+		}
+	}
+	if tierLimit > 0 && tierCount >= tierLimit {
+		return false
+	}
+	if workerLimit > 0 && workerCount >= workerLimit {
+		return false
+	}
+	return true
+}
+
+// snapshot lists running jobs, then queued jobs in queue order.
+func (q *jobScheduler) snapshot() []JobQueueEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]JobQueueEntry, 0, len(q.running)+len(q.queue))
+	for _, entry := range q.running {
+		out = append(out, *entry)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].seq < out[j].seq })
+	for _, entry := range q.queue {
+		out = append(out, *entry)
+	}
+	return out
+}
@@ -0,0 +1,186 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"ok-gobot/internal/storage"
+)
+
+func TestJobSchedulerOrdersByPriorityThenChat(t *testing.T) {
+	t.Parallel()
+
+	q := newJobScheduler()
+	q.setLimits(SchedulerLimits{MaxConcurrent: 1})
+	launched := make(chan string, 10)
+
+	q.enqueue(testQueueEntry("blocker", JobPriorityUser, CostTierStandard, "w", "chat-a", launched))
+	expectLaunch(t, launched, "blocker")
+
+	q.enqueue(testQueueEntry("cron-a", JobPriorityCron, CostTierStandard, "w", "chat-a", launched))
+	q.enqueue(testQueueEntry("user-a1", JobPriorityUser, CostTierStandard, "w", "chat-a", launched))
+	q.enqueue(testQueueEntry("user-a2", JobPriorityUser, CostTierStandard, "w", "chat-a", launched))
+	q.enqueue(testQueueEntry("user-b1", JobPriorityUser, CostTierStandard, "w", "chat-b", launched))
+	q.enqueue(testQueueEntry("interactive-c", JobPriorityInteractive, CostTierStandard, "w", "chat-c", launched))
+
+	// chat-a was served by the blocker, so chat-b goes before a's backlog.
+	prev := "blocker"
+	for _, want := range []string{"interactive-c", "user-b1", "user-a1", "user-a2", "cron-a"} {
+		q.release(prev)
+		expectLaunch(t, launched, want)
+		prev = want
+	}
+}
+
+func TestJobSchedulerEnforcesTierAndWorkerLimits(t *testing.T) {
+	t.Parallel()
+
+	q := newJobScheduler()
+	q.setLimits(SchedulerLimits{
+		MaxConcurrent: 3,
+		PerTier:       map[CostTier]int{CostTierPremium: 1},
+		PerWorker:     map[string]int{"claude": 1},
+	})
+	launched := make(chan string, 10)
+
+	q.enqueue(testQueueEntry("premium-1", JobPriorityUser, CostTierPremium, "x", "chat", launched))
+	q.enqueue(testQueueEntry("premium-2", JobPriorityUser, CostTierPremium, "y", "chat", launched))
+	q.enqueue(testQueueEntry("claude-1", JobPriorityUser, CostTierCheap, "claude", "chat", launched))
+	q.enqueue(testQueueEntry("claude-2", JobPriorityUser, CostTierCheap, "claude", "chat", launched))
+	q.enqueue(testQueueEntry("standard-1", JobPriorityUser, CostTierStandard, "z", "chat", launched))
+	q.enqueue(testQueueEntry("standard-2", JobPriorityUser, CostTierStandard, "z", "chat", launched))
+
+	got := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		got[receiveLaunch(t, launched)] = true
+	}
+	for _, want := range []string{"premium-1", "claude-1", "standard-1"} {
+		if !got[want] {
+			t.Fatalf("expected %s to start first, started %v", want, got)
+		}
+	}
+
+	snap := q.snapshot()
+	if len(snap) != 6 {
+		t.Fatalf("expected 6 snapshot entries, got %d", len(snap))
+	}
+	for i, entry := range snap[3:] {
+		if entry.Running || entry.Position != i+1 {
+			t.Fatalf("queued entry %s: running=%v position=%d, want position %d", entry.JobID, entry.Running, entry.Position, i+1)
+		}
+	}
+
+	// Freeing the premium slot lets the second premium job through; the
+	// global limit still holds the others back.
+	q.release("premium-1")
+	expectLaunch(t, launched, "premium-2")
+	select {
+	case id := <-launched:
+		t.Fatalf("unexpected launch of %s over the global limit", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestJobServiceQueuesOverLimitAndCancelsQueued(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	svc := NewJobService(store)
+	svc.SetSchedulerLimits(SchedulerLimits{MaxConcurrent: 1})
+
+	release := make(chan struct{})
+	first, err := svc.StartDetached(context.Background(), JobSpec{
+		Kind:       "background_task",
+		Worker:     "test_runner",
+		SessionKey: "agent:test:main",
+	}, func(ctx context.Context, job *storage.Job, svc *JobService) (JobRunResult, error) {
+		<-release
+		return JobRunResult{Summary: "first"}, nil
+	})
+	if err != nil {
+		t.Fatalf("StartDetached(first) failed: %v", err)
+	}
+	waitForJobStatus(t, store, first.JobID, string(JobStatusRunning))
+
+	ran := make(chan struct{}, 1)
+	second, err := svc.StartDetached(context.Background(), JobSpec{
+		Kind:       "background_task",
+		Worker:     "test_runner",
+		SessionKey: "agent:test:main",
+		Priority:   JobPriorityCron,
+		CostTier:   CostTierCheap,
+	}, func(ctx context.Context, job *storage.Job, svc *JobService) (JobRunResult, error) {
+		ran <- struct{}{}
+		return JobRunResult{Summary: "second"}, nil
+	})
+	if err != nil {
+		t.Fatalf("StartDetached(second) failed: %v", err)
+	}
+
+	events := waitForJobEvents(t, store, second.JobID, 2)
+	queued := events[len(events)-1]
+	if queued.EventType != string(JobEventQueued) {
+		t.Fatalf("expected queued event, got %q", queued.EventType)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(queued.Payload), &payload); err != nil {
+		t.Fatalf("queued payload: %v", err)
+	}
+	if payload["position"] != float64(1) || payload["priority"] != "cron" || payload["cost_tier"] != "cheap" {
+		t.Fatalf("unexpected queued payload: %v", payload)
+	}
+
+	snap := svc.QueueSnapshot()
+	if len(snap) != 2 || !snap[0].Running || snap[1].JobID != second.JobID || snap[1].Position != 1 {
+		t.Fatalf("unexpected queue snapshot: %+v", snap)
+	}
+
+	if err := svc.Cancel(second.JobID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	cancelled := waitForJobStatus(t, store, second.JobID, string(JobStatusCancelled))
+	if cancelled.Error != "cancelled while queued" {
+		t.Fatalf("unexpected cancel reason: %q", cancelled.Error)
+	}
+
+	close(release)
+	waitForJobStatus(t, store, first.JobID, string(JobStatusSucceeded))
+	select {
+	case <-ran:
+		t.Fatal("cancelled queued job must not run")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func testQueueEntry(id string, priority JobPriority, tier CostTier, worker, chat string, launched chan<- string) *JobQueueEntry {
+	return &JobQueueEntry{
+		JobID:    id,
+		Worker:   worker,
+		CostTier: tier,
+		Priority: priority,
+		fairKey:  chat,
+		launch:   func() { launched <- id },
+	}
+}
+
+func receiveLaunch(t *testing.T, launched <-chan string) string {
+	t.Helper()
+	select {
+	case id := <-launched:
+		return id
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a job to start")
+		return ""
+	}
+}
+
+func expectLaunch(t *testing.T, launched <-chan string, want string) {
+	t.Helper()
+	if got := receiveLaunch(t, launched); got != want {
+		t.Fatalf("started %s, want %s", got, want)
+	}
+}
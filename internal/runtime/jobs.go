@@ -24,6 +24,10 @@ const (
 	JobStatusFailed    JobStatus = "failed"
 	JobStatusCancelled JobStatus = "cancelled"
 	JobStatusTimedOut  JobStatus = "timed_out"
+	// JobStatusInterrupted marks a job orphaned by a daemon restart.
+	JobStatusInterrupted JobStatus = "interrupted"
+	// JobStatusWaitingInput marks a running job blocked on ask_user.
+	JobStatusWaitingInput JobStatus = "waiting_input"
 )
 
 // JobEventType is the persisted event stream for a job.
@@ -40,6 +44,11 @@ const (
 	JobEventTimedOut        JobEventType = "timed_out"
 	JobEventRetryRequested  JobEventType = "retry_requested"
 	JobEventArtifactAdded   JobEventType = "artifact_added"
+	JobEventCheckpoint      JobEventType = "checkpoint"
+	JobEventInterrupted     JobEventType = "interrupted"
+	JobEventQueued          JobEventType = "queued"
+	JobEventInputRequested  JobEventType = "input_requested"
+	JobEventInputReceived   JobEventType = "input_received"
 )
 
 // JobSpec describes a new durable background job.
@@ -50,10 +59,21 @@ type JobSpec struct {
 	SessionKey         string
 	DeliverySessionKey string
 	RetryOfJobID       string
+	ParentJobID        string
+	Priority           JobPriority
+	CostTier           CostTier
 	Description        string
 	Attempt            int
 	MaxAttempts        int
 	Timeout            time.Duration
+
+	// Coordinator jobs only wait on child jobs. They bypass the scheduler
+	// so they never hold a slot their children need.
+	Coordinator bool
+
+	// Input is what a runner factory needs to rebuild the job after a
+	// restart. It is stored with the creation event; see JobInput.
+	Input any
 }
 
 // JobArtifactSpec describes one durable artifact emitted by a job.
@@ -79,16 +99,45 @@ type JobRunner func(context.Context, *storage.Job, *JobService) (JobRunResult, e
 type JobService struct {
 	store *storage.Store
 
-	mu     sync.Mutex
-	active map[string]context.CancelFunc
+	mu       sync.Mutex
+	active   map[string]context.CancelFunc
+	kinds    map[string]jobKind
+	notifier func(chatID int64, message string)
+	sched    *jobScheduler
+
+	asker     QuestionHandler
+	questions map[string]chan struct{} // question ID -> wakes the waiting job
 }
 
 // NewJobService creates a durable job service backed by SQLite storage.
+// Jobs run without concurrency limits until SetSchedulerLimits is called.
 func NewJobService(store *storage.Store) *JobService {
-	return &JobService{
+	s := &JobService{
 		store:  store,
 		active: make(map[string]context.CancelFunc),
+		kinds:  make(map[string]jobKind),
+		sched:  newJobScheduler(),
+
+		questions: make(map[string]chan struct{}),
 	}
+	s.sched.onPosition = func(entry JobQueueEntry) {
+		_ = s.AppendEvent(entry.JobID, JobEventQueued, fmt.Sprintf("queued at position %d", entry.Position), map[string]any{
+			"position":  entry.Position,
+			"priority":  string(entry.Priority),
+			"cost_tier": string(entry.CostTier),
+		})
+	}
+	return s
+}
+
+// SetSchedulerLimits sets the concurrency limits for new and queued jobs.
+func (s *JobService) SetSchedulerLimits(limits SchedulerLimits) {
+	s.sched.setLimits(limits)
+}
+
+// QueueSnapshot lists the jobs the scheduler is running or holding.
+func (s *JobService) QueueSnapshot() []JobQueueEntry {
+	return s.sched.snapshot()
 }
 
 // StartDetached creates a durable job record and executes it in a goroutine.
@@ -97,17 +146,48 @@ func (s *JobService) StartDetached(parentCtx context.Context, spec JobSpec, runn
 		return nil, fmt.Errorf("job runner is required")
 	}
 
+	// Claim the job before its pending row exists, so Reconcile and
+	// StartQueued cannot pick it up before s.start registers it.
+	spec.JobID = strings.TrimSpace(spec.JobID)
+	if spec.JobID == "" {
+		spec.JobID = newJobID()
+	}
+	if !s.claim(spec.JobID) {
+		return nil, fmt.Errorf("job %q is already running", spec.JobID)
+	}
+
 	job, err := s.createJob(spec)
 	if err != nil {
+		s.unregisterCancel(spec.JobID)
 		return nil, err
 	}
 
-	go s.run(parentCtx, job, spec.Timeout, runner)
+	s.start(parentCtx, job, spec.Timeout, runner, "", spec.Coordinator)
 	return job, nil
 }
 
+// RetryMode selects how a retry treats the work of the previous attempt.
+type RetryMode string
+
+const (
+	// RetryRestart runs the job again from scratch.
+	RetryRestart RetryMode = "restart"
+	// RetryResume continues from the last checkpoint the job saved.
+	RetryResume RetryMode = "resume"
+)
+
 // RetryDetached clones a completed job into a fresh durable retry attempt.
 func (s *JobService) RetryDetached(parentCtx context.Context, jobID string, runner JobRunner) (*storage.Job, error) {
+	return s.RetryDetachedWithMode(parentCtx, jobID, RetryRestart, runner)
+}
+
+// RetryDetachedWithMode clones a completed job into a new attempt. In resume
+// mode the attempt receives the job's latest checkpoint, which fails if the
+// job never saved one.
+func (s *JobService) RetryDetachedWithMode(parentCtx context.Context, jobID string, mode RetryMode, runner JobRunner) (*storage.Job, error) {
+	if runner == nil {
+		return nil, fmt.Errorf("job runner is required")
+	}
 	existing, err := s.store.GetJob(jobID)
 	if err != nil {
 		return nil, err
@@ -117,36 +197,100 @@ func (s *JobService) RetryDetached(parentCtx context.Context, jobID string, runn
 	}
 
 	switch existing.Status {
-	case string(JobStatusPending), string(JobStatusRunning):
+	case string(JobStatusPending), string(JobStatusRunning), string(JobStatusWaitingInput):
 		return nil, fmt.Errorf("job %q is not retryable while status=%s", jobID, existing.Status)
 	}
 	if existing.MaxAttempts > 0 && existing.Attempt >= existing.MaxAttempts {
 		return nil, fmt.Errorf("job %q reached max attempts (%d)", jobID, existing.MaxAttempts)
 	}
 
-	retryJob, err := s.StartDetached(parentCtx, JobSpec{
+	var checkpoint string
+	switch mode {
+	case RetryRestart, "":
+		mode = RetryRestart
+	case RetryResume:
+		cp, ok, err := s.LatestCheckpoint(existing.JobID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("job %q has no checkpoint to resume from", jobID)
+		}
+		checkpoint = cp
+	default:
+		return nil, fmt.Errorf("unknown retry mode %q", mode)
+	}
+
+	// Claim the attempt before its pending row exists, so WatchQueued
+	// cannot start it a second time before s.start registers it.
+	retryID := newJobID()
+	s.claim(retryID)
+	started := false
+	defer func() {
+		if !started {
+			s.unregisterCancel(retryID)
+		}
+	}()
+
+	timeout := time.Duration(existing.TimeoutSeconds) * time.Second
+	retryJob, err := s.createJob(JobSpec{
+		JobID:              retryID,
 		Kind:               existing.Kind,
 		Worker:             existing.Worker,
 		SessionKey:         existing.SessionKey,
 		DeliverySessionKey: existing.DeliverySessionKey,
 		RetryOfJobID:       existing.JobID,
+		ParentJobID:        existing.ParentJobID,
+		Priority:           JobPriority(existing.Priority),
+		CostTier:           CostTier(existing.CostTier),
 		Description:        existing.Description,
 		Attempt:            existing.Attempt + 1,
 		MaxAttempts:        existing.MaxAttempts,
-		Timeout:            time.Duration(existing.TimeoutSeconds) * time.Second,
-	}, runner)
+		Timeout:            timeout,
+	})
 	if err != nil {
 		return nil, err
 	}
+	if err := s.carryCheckpoint(existing.JobID, retryJob.JobID, checkpoint); err != nil {
+		return nil, err
+	}
+
+	s.start(parentCtx, retryJob, timeout, runner, checkpoint, false)
+	started = true
 
 	if err := s.AppendEvent(existing.JobID, JobEventRetryRequested, fmt.Sprintf("retry queued as %s", retryJob.JobID), map[string]any{
 		"retry_job_id": retryJob.JobID,
+		"mode":         string(mode),
 	}); err != nil {
 		return nil, err
 	}
 	return retryJob, nil
 }
 
+// Retry retries a completed job with the runner factory registered for its
+// kind (see RegisterKind).
+func (s *JobService) Retry(parentCtx context.Context, jobID string, mode RetryMode) (*storage.Job, error) {
+	existing, err := s.store.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, fmt.Errorf("job %q not found", jobID)
+	}
+
+	s.mu.Lock()
+	kind := s.kinds[existing.Kind]
+	s.mu.Unlock()
+	if kind.factory == nil {
+		return nil, fmt.Errorf("jobs of kind %q cannot be retried in this process", existing.Kind)
+	}
+	runner, err := kind.factory(existing)
+	if err != nil {
+		return nil, fmt.Errorf("rebuild runner for job %q: %w", jobID, err)
+	}
+	return s.RetryDetachedWithMode(parentCtx, jobID, mode, runner)
+}
+
 // Cancel requests cancellation for a durable job and cancels any active context.
 func (s *JobService) Cancel(jobID string) error {
 	jobID = strings.TrimSpace(jobID)
@@ -182,6 +326,38 @@ func (s *JobService) Cancel(jobID string) error {
 	return nil
 }
 
+// Wait blocks until the job reaches a terminal status or ctx is done.
+func (s *JobService) Wait(ctx context.Context, jobID string) (*storage.Job, error) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		job, err := s.store.GetJob(jobID)
+		if err != nil {
+			return nil, err
+		}
+		if job == nil {
+			return nil, fmt.Errorf("job %q not found", jobID)
+		}
+		if IsTerminalStatus(job.Status) {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// IsTerminalStatus reports whether a job in this status will not change again.
+func IsTerminalStatus(status string) bool {
+	switch JobStatus(status) {
+	case JobStatusSucceeded, JobStatusFailed, JobStatusCancelled, JobStatusTimedOut, JobStatusInterrupted:
+		return true
+	}
+	return false
+}
+
 // AppendEvent persists a job lifecycle event with an optional JSON payload.
 func (s *JobService) AppendEvent(jobID string, eventType JobEventType, message string, payload any) error {
 	payloadJSON, err := marshalPayload(payload)
@@ -220,6 +396,62 @@ func (s *JobService) AddArtifact(jobID string, artifact JobArtifactSpec) error {
 	})
 }
 
+type jobContextKey struct{}
+
+type jobContext struct {
+	svc        *JobService
+	jobID      string
+	checkpoint string
+}
+
+// JobIDFromContext returns the durable job that owns ctx, if any.
+func JobIDFromContext(ctx context.Context) (string, bool) {
+	jc, ok := ctx.Value(jobContextKey{}).(jobContext)
+	return jc.jobID, ok
+}
+
+// AddContextArtifact persists an artifact against the durable job that owns
+// ctx. It lets code running deep inside a job (e.g. agent tools) emit
+// artifacts without holding a JobService handle. Returns an empty job ID and
+// no error when ctx does not belong to a job.
+func AddContextArtifact(ctx context.Context, artifact JobArtifactSpec) (string, error) {
+	jc, ok := ctx.Value(jobContextKey{}).(jobContext)
+	if !ok || jc.svc == nil {
+		return "", nil
+	}
+	if err := jc.svc.AddArtifact(jc.jobID, artifact); err != nil {
+		return "", err
+	}
+	return jc.jobID, nil
+}
+
+// JobInput returns the input the job was created with. Retry attempts do not
+// copy it, so the retry chain is followed back to the attempt that has one.
+func (s *JobService) JobInput(jobID string) (string, bool, error) {
+	seen := make(map[string]bool)
+	for id := strings.TrimSpace(jobID); id != "" && !seen[id]; {
+		seen[id] = true
+		event, err := s.store.GetLatestJobEvent(id, string(JobEventCreated))
+		if err != nil {
+			return "", false, err
+		}
+		if event != nil && event.Payload != "" {
+			var created struct {
+				Input string `json:"input"`
+			}
+			if err := json.Unmarshal([]byte(event.Payload), &created); err == nil && created.Input != "" {
+				return created.Input, true, nil
+			}
+		}
+		job, err := s.store.GetJob(id)
+		if err != nil || job == nil {
+			return "", false, err
+		}
+		id = job.RetryOfJobID
+	}
+	return "", false, nil
+}
+
 func (s *JobService) createJob(spec JobSpec) (*storage.Job, error) {
 	if s.store == nil {
 		return nil, fmt.Errorf("job storage is required")
@@ -248,6 +480,15 @@ func (s *JobService) createJob(spec JobSpec) (*storage.Job, error) {
 		maxAttempts = 1
 	}
 
+	priority, ok := ParseJobPriority(string(spec.Priority))
+	if !ok {
+		return nil, fmt.Errorf("invalid job priority %q", spec.Priority)
+	}
+	tier, ok := ParseCostTier(string(spec.CostTier))
+	if !ok {
+		return nil, fmt.Errorf("invalid cost tier %q", spec.CostTier)
+	}
+
 	timeoutSeconds := 0
 	if spec.Timeout > 0 {
 		timeoutSeconds = int(spec.Timeout / time.Second)
@@ -263,6 +504,9 @@ func (s *JobService) createJob(spec JobSpec) (*storage.Job, error) {
 		SessionKey:         strings.TrimSpace(spec.SessionKey),
 		DeliverySessionKey: strings.TrimSpace(spec.DeliverySessionKey),
 		RetryOfJobID:       strings.TrimSpace(spec.RetryOfJobID),
+		ParentJobID:        strings.TrimSpace(spec.ParentJobID),
+		Priority:           string(priority),
+		CostTier:           string(tier),
 		Description:        spec.Description,
 		Status:             string(JobStatusPending),
 		Attempt:            attempt,
@@ -272,40 +516,98 @@ func (s *JobService) createJob(spec JobSpec) (*storage.Job, error) {
 		return nil, err
 	}
 
-	if err := s.AppendEvent(jobID, JobEventCreated, spec.Description, map[string]any{
+	created := map[string]any{
 		"kind":                 strings.TrimSpace(spec.Kind),
 		"worker":               strings.TrimSpace(spec.Worker),
 		"delivery_session_key": strings.TrimSpace(spec.DeliverySessionKey),
 		"retry_of_job_id":      strings.TrimSpace(spec.RetryOfJobID),
+		"parent_job_id":        strings.TrimSpace(spec.ParentJobID),
+		"priority":             string(priority),
+		"cost_tier":            string(tier),
 		"attempt":              attempt,
 		"max_attempts":         maxAttempts,
 		"timeout_seconds":      timeoutSeconds,
-	}); err != nil {
+	}
+	if spec.Input != nil {
+		input, err := marshalPayload(spec.Input)
+		if err != nil {
+			return nil, err
+		}
+		created["input"] = input
+	}
+	if err := s.AppendEvent(jobID, JobEventCreated, spec.Description, created); err != nil {
 		return nil, err
 	}
 
 	return s.store.GetJob(jobID)
 }
 
-func (s *JobService) run(parentCtx context.Context, job *storage.Job, timeout time.Duration, runner JobRunner) {
+// start registers the job as active in this process and hands it to the
+// scheduler, which runs it in a goroutine once a slot is free. Registering
+// first keeps Reconcile and StartQueued from picking up a job that is about
+// to run. The timeout counts from launch, not from enqueue.
+func (s *JobService) start(parentCtx context.Context, job *storage.Job, timeout time.Duration, runner JobRunner, checkpoint string, coordinator bool) {
 	if parentCtx == nil {
 		parentCtx = context.Background()
 	}
 
-	var (
-		ctx    context.Context
-		cancel context.CancelFunc
-	)
-	if timeout > 0 {
-		ctx, cancel = context.WithTimeout(parentCtx, timeout)
-	} else {
-		ctx, cancel = context.WithCancel(parentCtx)
+	ctx, cancel := context.WithCancel(parentCtx)
+	s.registerCancel(job.JobID, cancel)
+	ctx = context.WithValue(ctx, jobContextKey{}, jobContext{svc: s, jobID: job.JobID, checkpoint: checkpoint})
+
+	launch := func() {
+		defer cancel()
+		defer s.unregisterCancel(job.JobID)
+		if !coordinator {
+			defer s.sched.release(job.JobID)
+		}
+		runCtx := ctx
+		if timeout > 0 {
+			var stop context.CancelFunc
+			runCtx, stop = context.WithTimeout(ctx, timeout)
+			defer stop()
+		}
+		s.run(runCtx, job, runner)
+	}
+	if coordinator {
+		go launch()
+		return
 	}
-	defer cancel()
 
-	s.registerCancel(job.JobID, cancel)
-	defer s.unregisterCancel(job.JobID)
+	tier, _ := ParseCostTier(job.CostTier)
+	priority, _ := ParseJobPriority(job.Priority)
+	fairKey := job.DeliverySessionKey
+	if fairKey == "" {
+		fairKey = job.SessionKey
+	}
+	s.sched.enqueue(&JobQueueEntry{
+		JobID:    job.JobID,
+		Kind:     job.Kind,
+		Worker:   job.Worker,
+		CostTier: tier,
+		Priority: priority,
+		fairKey:  fairKey,
+		launch:   launch,
+	})
 
+	// A job cancelled while queued never starts.
+	go func() {
+		<-ctx.Done()
+		if !s.sched.remove(job.JobID) {
+			return
+		}
+		defer s.unregisterCancel(job.JobID)
+		if err := s.store.MarkJobCancelled(job.JobID, "cancelled while queued"); err != nil {
+			log.Printf("[jobs] failed to mark %s cancelled: %v", job.JobID, err)
+			return
+		}
+		if err := s.AppendEvent(job.JobID, JobEventCancelled, "cancelled while queued", nil); err != nil {
+			log.Printf("[jobs] failed to persist cancel event for %s: %v", job.JobID, err)
+		}
+	}()
+}
+
+func (s *JobService) run(ctx context.Context, job *storage.Job, runner JobRunner) {
 	if err := s.store.MarkJobRunning(job.JobID); err != nil {
 		log.Printf("[jobs] failed to mark %s running: %v", job.JobID, err)
 		return
@@ -314,6 +616,8 @@ func (s *JobService) run(parentCtx context.Context, job *storage.Job, timeout ti
 		log.Printf("[jobs] failed to persist start event for %s: %v", job.JobID, err)
 		return
 	}
+	// Questions nobody answered end with the job.
+	defer s.closeQuestions(job.JobID)
 
 	result, runErr := runner(ctx, job, s)
 	if runErr == nil {
@@ -375,6 +679,19 @@ func (s *JobService) registerCancel(jobID string, cancel context.CancelFunc) {
 	s.mu.Unlock()
 }
 
+// claim marks jobID as owned by this process before it is started, so a
+// concurrent StartQueued or Reconcile leaves it alone. It reports false when
+// the job is already claimed or running here.
+func (s *JobService) claim(jobID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.active[jobID]; ok {
+		return false
+	}
+	s.active[jobID] = func() {}
+	return true
+}
+
 func (s *JobService) unregisterCancel(jobID string) {
 	s.mu.Lock()
 	delete(s.active, jobID)
@@ -229,6 +229,84 @@ func TestJobServiceRetryDetachedClonesAttempt(t *testing.T) {
 	}
 }
 
+func TestJobServiceJobInputFollowsRetryChain(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	svc := NewJobService(store)
+	fail := func(ctx context.Context, job *storage.Job, svc *JobService) (JobRunResult, error) {
+		return JobRunResult{}, errors.New("boom")
+	}
+	original, err := svc.StartDetached(context.Background(), JobSpec{
+		Kind:        "background_task",
+		Description: "with input",
+		MaxAttempts: 3,
+		Input:       map[string]string{"task": "summarize"},
+	}, fail)
+	if err != nil {
+		t.Fatalf("StartDetached failed: %v", err)
+	}
+	waitForJobStatus(t, store, original.JobID, string(JobStatusFailed))
+
+	retry, err := svc.RetryDetached(context.Background(), original.JobID, fail)
+	if err != nil {
+		t.Fatalf("RetryDetached failed: %v", err)
+	}
+	waitForJobStatus(t, store, retry.JobID, string(JobStatusFailed))
+
+	input, ok, err := svc.JobInput(retry.JobID)
+	if err != nil || !ok || input != `{"task":"summarize"}` {
+		t.Fatalf("JobInput(retry) = %q, %v, %v", input, ok, err)
+	}
+}
+
+func TestAddContextArtifactAttachesToOwningJob(t *testing.T) {
+	t.Parallel()
+
+	store := newRuntimeTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	if jobID, err := AddContextArtifact(context.Background(), JobArtifactSpec{Name: "x", Type: "text"}); err != nil || jobID != "" {
+		t.Fatalf("expected no-op outside a job, got %q, %v", jobID, err)
+	}
+
+	svc := NewJobService(store)
+	job, err := svc.StartDetached(context.Background(), JobSpec{
+		Kind:        "background_task",
+		Worker:      "test_runner",
+		Description: "emit from context",
+		Timeout:     2 * time.Second,
+	}, func(ctx context.Context, job *storage.Job, _ *JobService) (JobRunResult, error) {
+		jobID, err := AddContextArtifact(ctx, JobArtifactSpec{
+			Name:     "changes.diff",
+			Type:     "diff",
+			MimeType: "text/x-diff",
+			Content:  "+hello",
+		})
+		if err != nil {
+			return JobRunResult{}, err
+		}
+		if jobID != job.JobID {
+			return JobRunResult{}, errors.New("artifact attached to wrong job")
+		}
+		return JobRunResult{Summary: "done"}, nil
+	})
+	if err != nil {
+		t.Fatalf("StartDetached failed: %v", err)
+	}
+
+	waitForJobStatus(t, store, job.JobID, string(JobStatusSucceeded))
+	artifacts, err := store.ListJobArtifacts(job.JobID, 10)
+	if err != nil {
+		t.Fatalf("ListJobArtifacts failed: %v", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].Name != "changes.diff" || artifacts[0].Content != "+hello" {
+		t.Fatalf("unexpected artifacts: %+v", artifacts)
+	}
+}
+
 func newRuntimeTestStore(t *testing.T) *storage.Store {
 	t.Helper()
 
@@ -11,6 +11,7 @@ package runtime
 import (
 	"context"
 	"log"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -107,9 +108,19 @@ func NewHub(ctx context.Context, queueDepth int) *Hub {
 
 // WorkerSnapshot describes the current state of a session worker.
 type WorkerSnapshot struct {
-	SessionKey string `json:"session_key"`
-	Running    bool   `json:"running"`
-	QueueDepth int    `json:"queue_depth"`
+	SessionKey string         `json:"session_key"`
+	Running    bool           `json:"running"`
+	QueueDepth int            `json:"queue_depth"`
+	Browser    *WorkerBrowser `json:"browser,omitempty"` // isolated browser context held by the session
+	Job        *JobQueueEntry `json:"job,omitempty"`     // durable job running or queued in the job scheduler
+}
+
+// WorkerBrowser describes an isolated browser context owned by a session.
+type WorkerBrowser struct {
+	ContextID string    `json:"context_id"`
+	Tabs      int       `json:"tabs"`
+	CreatedAt time.Time `json:"created_at"`
+	LastUsed  time.Time `json:"last_used"`
 }
 
 // ListWorkers returns a snapshot of all active session workers.
@@ -127,6 +138,31 @@ func (h *Hub) ListWorkers() []WorkerSnapshot {
 	return out
 }
 
+// AttachBrowsers sets the Browser field of each snapshot from browsers,
+// keyed by session. Sessions that hold a context but have no worker in
+// this hub (e.g. legacy subagent runs) are appended as running.
+func AttachBrowsers(snaps []WorkerSnapshot, browsers map[string]WorkerBrowser) []WorkerSnapshot {
+	seen := make(map[string]bool, len(snaps))
+	for i := range snaps {
+		if b, ok := browsers[snaps[i].SessionKey]; ok {
+			snaps[i].Browser = &b
+		}
+		seen[snaps[i].SessionKey] = true
+	}
+	keys := make([]string, 0, len(browsers))
+	for key := range browsers {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b := browsers[key]
+		snaps = append(snaps, WorkerSnapshot{SessionKey: key, Running: true, Browser: &b})
+	}
+	return snaps
+}
+
 // RegisterParent records that childKey is a sub-session of parentKey.
 // When the child's run completes (EventDone or EventError), an additional
 // EventChildDone or EventChildFailed event is emitted with SessionKey = parentKey.
@@ -393,3 +429,17 @@ func (h *handle) CloseWithSummary(summary string, err error) {
 		h.hub.notifyParent(h.sessionKey, summary, err)
 	})
 }
+
+// AttachJobs appends one snapshot per scheduler entry, keyed "job:<id>".
+// Queued jobs carry their queue position.
+func AttachJobs(snaps []WorkerSnapshot, entries []JobQueueEntry) []WorkerSnapshot {
+	for i := range entries {
+		entry := entries[i]
+		snaps = append(snaps, WorkerSnapshot{
+			SessionKey: "job:" + entry.JobID,
+			Running:    entry.Running,
+			Job:        &entry,
+		})
+	}
+	return snaps
+}
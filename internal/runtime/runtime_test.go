@@ -415,3 +415,25 @@ func TestAckHandleErrorClose(t *testing.T) {
 		t.Errorf("expected EventError, got: %v", events)
 	}
 }
+
+func TestAttachBrowsers(t *testing.T) {
+	snaps := []WorkerSnapshot{{SessionKey: "a", Running: true}, {SessionKey: "b"}}
+	browsers := map[string]WorkerBrowser{
+		"a": {ContextID: "ctx-a", Tabs: 2},
+		"z": {ContextID: "ctx-z", Tabs: 1},
+	}
+
+	got := AttachBrowsers(snaps, browsers)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 snapshots, got %d", len(got))
+	}
+	if got[0].Browser == nil || got[0].Browser.ContextID != "ctx-a" {
+		t.Fatalf("expected browser attached to a, got %+v", got[0].Browser)
+	}
+	if got[1].Browser != nil {
+		t.Fatalf("expected no browser on b, got %+v", got[1].Browser)
+	}
+	if got[2].SessionKey != "z" || !got[2].Running || got[2].Browser == nil {
+		t.Fatalf("expected orphan context listed as running, got %+v", got[2])
+	}
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FileCheckpoint is the content a file had before a tool in a run changed it.
+// Existed is false when the run created the file, so undo removes it. A
+// non-empty MovedFrom records a rename of MovedFrom to Path instead, which
+// undo reverses.
+type FileCheckpoint struct {
+	ID         int64
+	SessionKey string
+	RunID      string
+	ToolName   string
+	Path       string
+	MovedFrom  string
+	Existed    bool
+	Mode       uint32
+	Content    []byte
+	Size       int64
+	CreatedAt  string
+}
+
+// CheckpointRun summarises the snapshots taken during one agent run.
+type CheckpointRun struct {
+	SessionKey string
+	RunID      string
+	Files      int
+	Bytes      int64
+	Tools      string // comma-separated tool names that touched files
+	CreatedAt  string
+}
+
+// AddFileCheckpoint stores a snapshot. Only the first snapshot of a path
+// within a run is kept, since that is the state undo must restore.
+func (s *Store) AddFileCheckpoint(cp FileCheckpoint) error {
+	if strings.TrimSpace(cp.SessionKey) == "" {
+		return fmt.Errorf("session key is required")
+	}
+	if strings.TrimSpace(cp.RunID) == "" {
+		return fmt.Errorf("run ID is required")
+	}
+	if strings.TrimSpace(cp.Path) == "" {
+		return fmt.Errorf("path is required")
+	}
+	_, err := s.db.Exec(`
+		INSERT OR IGNORE INTO file_checkpoints (session_key, run_id, tool_name, path, moved_from, existed, mode, content, size)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, cp.SessionKey, cp.RunID, cp.ToolName, cp.Path, cp.MovedFrom, cp.Existed, cp.Mode, cp.Content, int64(len(cp.Content)))
+	return err
+}
+
+// ListCheckpointRuns returns the runs with snapshots for a session, newest first.
+func (s *Store) ListCheckpointRuns(sessionKey string, limit int) ([]CheckpointRun, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := s.db.Query(`
+		SELECT session_key, run_id, COUNT(*), COALESCE(SUM(size), 0), GROUP_CONCAT(DISTINCT tool_name), MIN(created_at)
+		FROM file_checkpoints
+		WHERE session_key = ?
+		GROUP BY session_key, run_id
+		ORDER BY MAX(id) DESC
+		LIMIT ?
+	`, sessionKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []CheckpointRun
+	for rows.Next() {
+		var r CheckpointRun
+		if err := rows.Scan(&r.SessionKey, &r.RunID, &r.Files, &r.Bytes, &r.Tools, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// ListFileCheckpoints returns the snapshots of one run, most recent first so
+// they can be restored in reverse order.
+func (s *Store) ListFileCheckpoints(sessionKey, runID string) ([]FileCheckpoint, error) {
+	rows, err := s.db.Query(`
+		SELECT id, session_key, run_id, tool_name, path, moved_from, existed, mode, content, size, created_at
+		FROM file_checkpoints
+		WHERE session_key = ? AND run_id = ?
+		ORDER BY id DESC
+	`, sessionKey, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []FileCheckpoint
+	for rows.Next() {
+		var cp FileCheckpoint
+		if err := rows.Scan(&cp.ID, &cp.SessionKey, &cp.RunID, &cp.ToolName, &cp.Path, &cp.MovedFrom,
+			&cp.Existed, &cp.Mode, &cp.Content, &cp.Size, &cp.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, cp)
+	}
+	return out, rows.Err()
+}
+
+// DeleteCheckpointRun removes all snapshots of a run.
+func (s *Store) DeleteCheckpointRun(sessionKey, runID string) error {
+	_, err := s.db.Exec(`DELETE FROM file_checkpoints WHERE session_key = ? AND run_id = ?`, sessionKey, runID)
+	return err
+}
+
+// PruneFileCheckpoints deletes runs whose newest snapshot is older than
+// maxAge, then drops the oldest runs until the stored content fits in
+// maxTotalBytes. Zero disables the respective limit. Returns the number of
+// deleted snapshots.
+func (s *Store) PruneFileCheckpoints(maxAge time.Duration, maxTotalBytes int64) (int64, error) {
+	var deleted int64
+	if maxAge > 0 {
+		// Whole runs only, so /undo never restores part of a run.
+		res, err := s.db.Exec(`
+			DELETE FROM file_checkpoints
+			WHERE (session_key, run_id) IN (
+				SELECT session_key, run_id
+				FROM file_checkpoints
+				GROUP BY session_key, run_id
+				HAVING MAX(created_at) < datetime('now', ?)
+			)
+		`, fmt.Sprintf("-%d seconds", int64(maxAge.Seconds())))
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	if maxTotalBytes <= 0 {
+		return deleted, nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT session_key, run_id, COALESCE(SUM(size), 0)
+		FROM file_checkpoints
+		GROUP BY session_key, run_id
+		ORDER BY MAX(id) DESC
+	`)
+	if err != nil {
+		return deleted, err
+	}
+	type runKey struct{ sessionKey, runID string }
+	var (
+		total int64
+		drop  []runKey
+	)
+	for rows.Next() {
+		var (
+			key  runKey
+			size int64
+		)
+		if err := rows.Scan(&key.sessionKey, &key.runID, &size); err != nil {
+			rows.Close()
+			return deleted, err
+		}
+		total += size
+		if total > maxTotalBytes {
+			drop = append(drop, key)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return deleted, err
+	}
+	rows.Close()
+
+	for _, key := range drop {
+		res, err := s.db.Exec(`DELETE FROM file_checkpoints WHERE session_key = ? AND run_id = ?`, key.sessionKey, key.runID)
+		if err != nil {
+			return deleted, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	return deleted, nil
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Cron run states beyond the durable job statuses a run finishes with.
+const (
+	CronRunRunning = "running"
+	CronRunSkipped = "skipped" // not started: overlap policy or already queued
+	CronRunMissed  = "missed"  // due while the daemon was down and not caught up
+)
+
+// CronRun is one fire of a cron job.
+type CronRun struct {
+	ID          int64
+	CronJobID   int64
+	JobID       string // durable job that carried the run, if any
+	Trigger     string // schedule or catch_up
+	Status      string
+	Error       string
+	ScheduledAt string // UTC RFC3339 time the run was due
+	StartedAt   string
+	FinishedAt  string
+}
+
+const cronRunColumns = `id, cron_job_id, job_id, trigger, status, error, scheduled_at, started_at, COALESCE(finished_at, '')`
+
+// CreateCronRun records a run and returns its ID. Runs created in a final
+// state (skipped, missed) are finished immediately.
+func (s *Store) CreateCronRun(run CronRun) (int64, error) {
+	if run.CronJobID == 0 {
+		return 0, fmt.Errorf("cron job ID is required")
+	}
+	if run.Status == "" {
+		run.Status = CronRunRunning
+	}
+	if run.Trigger == "" {
+		run.Trigger = "schedule"
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO cron_runs (cron_job_id, job_id, trigger, status, error, scheduled_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, CASE WHEN ? = 'running' THEN NULL ELSE CURRENT_TIMESTAMP END)
+	`, run.CronJobID, run.JobID, run.Trigger, run.Status, run.Error, run.ScheduledAt, run.Status)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// SetCronRunJob links a run to the durable job executing it.
+func (s *Store) SetCronRunJob(runID int64, jobID string) error {
+	_, err := s.db.Exec(`UPDATE cron_runs SET job_id = ? WHERE id = ?`, jobID, runID)
+	return err
+}
+
+// FinishCronRun stores the final status of a run.
+func (s *Store) FinishCronRun(runID int64, status, errMsg string) error {
+	_, err := s.db.Exec(`
+		UPDATE cron_runs SET status = ?, error = ?, finished_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, status, errMsg, runID)
+	return err
+}
+
+// InterruptCronRuns marks runs left running by a stopped process.
+func (s *Store) InterruptCronRuns() (int64, error) {
+	res, err := s.db.Exec(`
+		UPDATE cron_runs SET status = 'interrupted', finished_at = CURRENT_TIMESTAMP WHERE status = 'running'
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ListCronRuns returns a cron job's runs, newest first.
+func (s *Store) ListCronRuns(cronJobID int64, limit int) ([]CronRun, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := s.db.Query(`
+		SELECT `+cronRunColumns+`
+		FROM cron_runs
+		WHERE cron_job_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, cronJobID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []CronRun
+	for rows.Next() {
+		var r CronRun
+		if err := rows.Scan(&r.ID, &r.CronJobID, &r.JobID, &r.Trigger, &r.Status, &r.Error,
+			&r.ScheduledAt, &r.StartedAt, &r.FinishedAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// LastCronRunScheduledAt returns when the most recent run of a cron job was
+// due, or "" when it never ran.
+func (s *Store) LastCronRunScheduledAt(cronJobID int64) (string, error) {
+	var at string
+	err := s.db.QueryRow(`
+		SELECT scheduled_at FROM cron_runs WHERE cron_job_id = ? ORDER BY scheduled_at DESC, id DESC LIMIT 1
+	`, cronJobID).Scan(&at)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return at, err
+}
+
+// CountConsecutiveCronFailures counts the failed or timed-out runs since
+// the last successful one. Other outcomes (skipped, cancelled, interrupted)
+// neither count nor reset the streak.
+func (s *Store) CountConsecutiveCronFailures(cronJobID int64) (int, error) {
+	rows, err := s.db.Query(`
+		SELECT status FROM cron_runs
+		WHERE cron_job_id = ? AND status NOT IN ('running', 'skipped', 'missed')
+		ORDER BY id DESC
+		LIMIT 100
+	`, cronJobID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var status string
+		if err := rows.Scan(&status); err != nil {
+			return 0, err
+		}
+		if status == "succeeded" {
+			break
+		}
+		if status == "failed" || status == "timed_out" {
+			count++
+		}
+	}
+	return count, rows.Err()
+}
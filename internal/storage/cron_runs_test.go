@@ -0,0 +1,70 @@
+package storage
+
+import "testing"
+
+func TestCronRunHistoryAndFailureStreak(t *testing.T) {
+	s := newV2TestStore(t)
+
+	cronID, err := s.SaveCronJobSpec(CronJob{Expression: "0 0 * * * *", Task: "report", ChatID: 42})
+	if err != nil {
+		t.Fatalf("SaveCronJobSpec: %v", err)
+	}
+
+	finish := func(scheduledAt, status string) {
+		t.Helper()
+		id, err := s.CreateCronRun(CronRun{CronJobID: cronID, ScheduledAt: scheduledAt})
+		if err != nil {
+			t.Fatalf("CreateCronRun: %v", err)
+		}
+		if err := s.FinishCronRun(id, status, ""); err != nil {
+			t.Fatalf("FinishCronRun: %v", err)
+		}
+	}
+
+	finish("2026-03-04T08:00:00Z", "succeeded")
+	finish("2026-03-04T09:00:00Z", "failed")
+	if _, err := s.CreateCronRun(CronRun{CronJobID: cronID, Status: CronRunSkipped, ScheduledAt: "2026-03-04T09:30:00Z"}); err != nil {
+		t.Fatalf("CreateCronRun(skipped): %v", err)
+	}
+	finish("2026-03-04T10:00:00Z", "timed_out")
+
+	count, err := s.CountConsecutiveCronFailures(cronID)
+	if err != nil {
+		t.Fatalf("CountConsecutiveCronFailures: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("failure streak = %d, want 2 (skipped runs do not reset it)", count)
+	}
+
+	finish("2026-03-04T11:00:00Z", "succeeded")
+	if count, _ := s.CountConsecutiveCronFailures(cronID); count != 0 {
+		t.Fatalf("failure streak after success = %d, want 0", count)
+	}
+
+	// A run left running by a crash is interrupted on the next start.
+	if _, err := s.CreateCronRun(CronRun{CronJobID: cronID, ScheduledAt: "2026-03-04T12:00:00Z"}); err != nil {
+		t.Fatalf("CreateCronRun: %v", err)
+	}
+	if n, err := s.InterruptCronRuns(); err != nil || n != 1 {
+		t.Fatalf("InterruptCronRuns = %d, %v; want 1", n, err)
+	}
+
+	runs, err := s.ListCronRuns(cronID, 3)
+	if err != nil {
+		t.Fatalf("ListCronRuns: %v", err)
+	}
+	if len(runs) != 3 || runs[0].Status != "interrupted" || runs[0].FinishedAt == "" || runs[1].Status != "succeeded" {
+		t.Fatalf("unexpected runs: %+v", runs)
+	}
+	if runs[2].Trigger != "schedule" {
+		t.Fatalf("trigger = %q, want schedule", runs[2].Trigger)
+	}
+
+	last, err := s.LastCronRunScheduledAt(cronID)
+	if err != nil || last != "2026-03-04T12:00:00Z" {
+		t.Fatalf("LastCronRunScheduledAt = %q, %v", last, err)
+	}
+	if last, _ := s.LastCronRunScheduledAt(cronID + 1); last != "" {
+		t.Fatalf("LastCronRunScheduledAt for a job without runs = %q, want empty", last)
+	}
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Job question states. A question is pending until someone answers it,
+// answered until the asking job picks the answer up, then closed.
+const (
+	JobQuestionPending  = "pending"
+	JobQuestionAnswered = "answered"
+	JobQuestionClosed   = "closed"
+)
+
+// JobQuestion is a question a durable job asked its user.
+type JobQuestion struct {
+	QuestionID    string
+	JobID         string
+	Question      string
+	Choices       []string
+	DefaultAnswer string
+	Status        string
+	Answer        string
+	AnsweredBy    string // telegram, tui, cli or default
+	ChatID        int64  // chat the question was posted to, if any
+	MessageID     int    // Telegram message carrying the question
+	ExpiresAt     string // UTC "2006-01-02 15:04:05"; empty = no timeout
+	CreatedAt     string
+	AnsweredAt    string
+}
+
+const jobQuestionColumns = `question_id, job_id, question, choices, default_answer, status, answer,
+	answered_by, chat_id, message_id, expires_at, created_at, COALESCE(answered_at, '')`
+
+// CreateJobQuestion persists a new pending question.
+func (s *Store) CreateJobQuestion(q JobQuestion) error {
+	if strings.TrimSpace(q.QuestionID) == "" {
+		return fmt.Errorf("question ID is required")
+	}
+	if strings.TrimSpace(q.JobID) == "" {
+		return fmt.Errorf("job ID is required")
+	}
+	if strings.TrimSpace(q.Question) == "" {
+		return fmt.Errorf("question is required")
+	}
+	choices := ""
+	if len(q.Choices) > 0 {
+		data, err := json.Marshal(q.Choices)
+		if err != nil {
+			return err
+		}
+		choices = string(data)
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO job_questions (question_id, job_id, question, choices, default_answer, status, chat_id, expires_at)
+		VALUES (?, ?, ?, ?, ?, 'pending', ?, ?)
+	`, q.QuestionID, strings.TrimSpace(q.JobID), q.Question, choices, q.DefaultAnswer, q.ChatID, q.ExpiresAt)
+	return err
+}
+
+// GetJobQuestion loads a question. It returns (nil, nil) when it does not exist.
+func (s *Store) GetJobQuestion(questionID string) (*JobQuestion, error) {
+	q, err := scanJobQuestion(s.db.QueryRow(`
+		SELECT `+jobQuestionColumns+`
+		FROM job_questions
+		WHERE question_id = ?
+	`, strings.TrimSpace(questionID)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return q, err
+}
+
+// FindOpenJobQuestion returns the pending or answered question a job asked
+// with exactly this text, so a resumed attempt that asks again picks up the
+// earlier question instead of posting a duplicate.
+func (s *Store) FindOpenJobQuestion(jobID, question string) (*JobQuestion, error) {
+	q, err := scanJobQuestion(s.db.QueryRow(`
+		SELECT `+jobQuestionColumns+`
+		FROM job_questions
+		WHERE job_id = ? AND question = ? AND status IN ('pending', 'answered')
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, strings.TrimSpace(jobID), question))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return q, err
+}
+
+// FindJobQuestionByMessage returns the pending question posted as messageID
+// in chatID, if any.
+func (s *Store) FindJobQuestionByMessage(chatID int64, messageID int) (*JobQuestion, error) {
+	q, err := scanJobQuestion(s.db.QueryRow(`
+		SELECT `+jobQuestionColumns+`
+		FROM job_questions
+		WHERE chat_id = ? AND message_id = ? AND status = 'pending'
+	`, chatID, messageID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return q, err
+}
+
+// ListJobQuestions returns the questions a job asked, oldest first.
+func (s *Store) ListJobQuestions(jobID string) ([]JobQuestion, error) {
+	rows, err := s.db.Query(`
+		SELECT `+jobQuestionColumns+`
+		FROM job_questions
+		WHERE job_id = ?
+		ORDER BY created_at ASC, question_id ASC
+	`, strings.TrimSpace(jobID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanJobQuestions(rows)
+}
+
+// ListPendingJobQuestions returns unanswered questions, oldest first. A
+// non-zero chatID limits the list to questions posted to that chat.
+func (s *Store) ListPendingJobQuestions(chatID int64, limit int) ([]JobQuestion, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.db.Query(`
+		SELECT `+jobQuestionColumns+`
+		FROM job_questions
+		WHERE status = 'pending' AND (? = 0 OR chat_id = ?)
+		ORDER BY created_at ASC, question_id ASC
+		LIMIT ?
+	`, chatID, chatID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanJobQuestions(rows)
+}
+
+// SetJobQuestionMessage records where the question was posted.
+func (s *Store) SetJobQuestionMessage(questionID string, chatID int64, messageID int) error {
+	_, err := s.db.Exec(`
+		UPDATE job_questions SET chat_id = ?, message_id = ? WHERE question_id = ?
+	`, chatID, messageID, strings.TrimSpace(questionID))
+	return err
+}
+
+// AnswerJobQuestion stores the answer to a pending question. It reports false
+// when the question was already answered or closed.
+func (s *Store) AnswerJobQuestion(questionID, answer, answeredBy string) (bool, error) {
+	res, err := s.db.Exec(`
+		UPDATE job_questions
+		SET status = 'answered', answer = ?, answered_by = ?, answered_at = CURRENT_TIMESTAMP
+		WHERE question_id = ? AND status = 'pending'
+	`, answer, answeredBy, strings.TrimSpace(questionID))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// CloseJobQuestion marks a question as handled by its job.
+func (s *Store) CloseJobQuestion(questionID string) error {
+	_, err := s.db.Exec(`
+		UPDATE job_questions SET status = 'closed' WHERE question_id = ?
+	`, strings.TrimSpace(questionID))
+	return err
+}
+
+// CloseJobQuestions closes every open question of a job that will not
+// read them any more.
+func (s *Store) CloseJobQuestions(jobID string) error {
+	_, err := s.db.Exec(`
+		UPDATE job_questions SET status = 'closed' WHERE job_id = ? AND status != 'closed'
+	`, strings.TrimSpace(jobID))
+	return err
+}
+
+// MoveJobQuestions hands a job's open questions to a new attempt of it.
+func (s *Store) MoveJobQuestions(fromJobID, toJobID string) error {
+	_, err := s.db.Exec(`
+		UPDATE job_questions SET job_id = ? WHERE job_id = ? AND status != 'closed'
+	`, strings.TrimSpace(toJobID), strings.TrimSpace(fromJobID))
+	return err
+}
+
+func scanJobQuestions(rows *sql.Rows) ([]JobQuestion, error) {
+	var questions []JobQuestion
+	for rows.Next() {
+		q, err := scanJobQuestion(rows)
+		if err != nil {
+			return nil, err
+		}
+		questions = append(questions, *q)
+	}
+	return questions, rows.Err()
+}
+
+func scanJobQuestion(row rowScanner) (*JobQuestion, error) {
+	var (
+		q       JobQuestion
+		choices string
+	)
+	if err := row.Scan(
+		&q.QuestionID,
+		&q.JobID,
+		&q.Question,
+		&choices,
+		&q.DefaultAnswer,
+		&q.Status,
+		&q.Answer,
+		&q.AnsweredBy,
+		&q.ChatID,
+		&q.MessageID,
+		&q.ExpiresAt,
+		&q.CreatedAt,
+		&q.AnsweredAt,
+	); err != nil {
+		return nil, err
+	}
+	if choices != "" {
+		if err := json.Unmarshal([]byte(choices), &q.Choices); err != nil {
+			return nil, fmt.Errorf("decode choices for question %s: %w", q.QuestionID, err)
+		}
+	}
+	return &q, nil
+}
@@ -17,3 +17,18 @@ func TestSaveSessionDefaultsQueueModeToInterrupt(t *testing.T) {
 		t.Fatalf("queue_mode = %q, want interrupt", mode)
 	}
 }
+
+func TestSessionOptionTTSMode(t *testing.T) {
+	s := newV2TestStore(t)
+
+	if err := s.SetSessionOption(42, "tts_mode", "auto"); err != nil {
+		t.Fatalf("SetSessionOption: %v", err)
+	}
+	mode, err := s.GetSessionOption(42, "tts_mode")
+	if err != nil {
+		t.Fatalf("GetSessionOption: %v", err)
+	}
+	if mode != "auto" {
+		t.Fatalf("tts_mode = %q, want auto", mode)
+	}
+}
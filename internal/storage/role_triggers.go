@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Trigger event states.
+const (
+	TriggerEventSeen    = "seen"    // present when the trigger was set up; never run
+	TriggerEventStarted = "started" // a job was started for it
+)
+
+// RoleTrigger is the persisted state of one role trigger.
+type RoleTrigger struct {
+	TriggerID   string
+	Role        string
+	Kind        string
+	Source      string
+	Cursor      string // kind-specific position, e.g. the last IMAP UID
+	Primed      bool   // existing items were recorded as seen
+	LastEventAt string
+	LastError   string
+	UpdatedAt   string
+}
+
+// TriggerEvent is one event a trigger received.
+type TriggerEvent struct {
+	ID        int64
+	TriggerID string
+	EventKey  string // de-duplication key, unique per trigger
+	Status    string
+	Title     string
+	Task      string // rendered task the role was given
+	Params    string // JSON object of the bound role params
+	ChatID    int64  // chat the report goes to (0 = none)
+	JobID     string
+	CreatedAt string
+}
+
+const roleTriggerColumns = `trigger_id, role, kind, source, cursor, primed,
+	COALESCE(last_event_at, ''), last_error, COALESCE(updated_at, '')`
+
+const triggerEventColumns = `id, trigger_id, event_key, status, title, task, params, chat_id, job_id,
+	COALESCE(created_at, '')`
+
+// EnsureRoleTrigger creates the state row of a trigger, or refreshes its
+// role, kind and source, keeping the cursor.
+func (s *Store) EnsureRoleTrigger(t RoleTrigger) error {
+	if t.TriggerID == "" {
+		return fmt.Errorf("trigger ID is required")
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO role_triggers (trigger_id, role, kind, source)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(trigger_id) DO UPDATE SET
+			role = excluded.role, kind = excluded.kind, source = excluded.source,
+			updated_at = CURRENT_TIMESTAMP
+	`, t.TriggerID, t.Role, t.Kind, t.Source)
+	return err
+}
+
+// GetRoleTrigger returns a trigger's state, or (nil, nil) when it has none.
+func (s *Store) GetRoleTrigger(triggerID string) (*RoleTrigger, error) {
+	row := s.db.QueryRow(`SELECT `+roleTriggerColumns+` FROM role_triggers WHERE trigger_id = ?`, triggerID)
+	t, err := scanRoleTrigger(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// ListRoleTriggers returns the state of every trigger seen so far.
+func (s *Store) ListRoleTriggers() ([]RoleTrigger, error) {
+	rows, err := s.db.Query(`SELECT ` + roleTriggerColumns + ` FROM role_triggers ORDER BY role, trigger_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []RoleTrigger
+	for rows.Next() {
+		t, err := scanRoleTrigger(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+// SetRoleTriggerCursor stores a trigger's position and marks it primed.
+func (s *Store) SetRoleTriggerCursor(triggerID, cursor string) error {
+	_, err := s.db.Exec(`
+		UPDATE role_triggers SET cursor = ?, primed = 1, updated_at = CURRENT_TIMESTAMP WHERE trigger_id = ?
+	`, cursor, triggerID)
+	return err
+}
+
+// SetRoleTriggerError records the last error of a trigger; "" clears it.
+func (s *Store) SetRoleTriggerError(triggerID, errMsg string) error {
+	_, err := s.db.Exec(`
+		UPDATE role_triggers SET last_error = ?, updated_at = CURRENT_TIMESTAMP WHERE trigger_id = ?
+	`, errMsg, triggerID)
+	return err
+}
+
+// RecordTriggerEvent stores an event unless the trigger already has one with
+// the same key. It reports whether the event is new.
+func (s *Store) RecordTriggerEvent(ev TriggerEvent) (int64, bool, error) {
+	if ev.TriggerID == "" || ev.EventKey == "" {
+		return 0, false, fmt.Errorf("trigger ID and event key are required")
+	}
+	if ev.Status == "" {
+		ev.Status = TriggerEventStarted
+	}
+	res, err := s.db.Exec(`
+		INSERT OR IGNORE INTO trigger_events (trigger_id, event_key, status, title, task, params, chat_id, job_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, ev.TriggerID, ev.EventKey, ev.Status, ev.Title, ev.Task, ev.Params, ev.ChatID, ev.JobID)
+	if err != nil {
+		return 0, false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, false, nil
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, false, err
+	}
+	if ev.Status == TriggerEventStarted {
+		if _, err := s.db.Exec(`
+			UPDATE role_triggers SET last_event_at = CURRENT_TIMESTAMP WHERE trigger_id = ?
+		`, ev.TriggerID); err != nil {
+			return id, true, err
+		}
+	}
+	return id, true, nil
+}
+
+// SetTriggerEventJob links an event to the job started for it.
+func (s *Store) SetTriggerEventJob(eventID int64, jobID string) error {
+	_, err := s.db.Exec(`UPDATE trigger_events SET job_id = ? WHERE id = ?`, jobID, eventID)
+	return err
+}
+
+// DeleteTriggerEvent removes an event, so the same key is recorded as new
+// the next time it arrives.
+func (s *Store) DeleteTriggerEvent(eventID int64) error {
+	_, err := s.db.Exec(`DELETE FROM trigger_events WHERE id = ?`, eventID)
+	return err
+}
+
+// GetTriggerEvent returns an event by ID, or (nil, nil) when it does not
+// exist.
+func (s *Store) GetTriggerEvent(eventID int64) (*TriggerEvent, error) {
+	ev, err := scanTriggerEvent(s.db.QueryRow(`SELECT `+triggerEventColumns+` FROM trigger_events WHERE id = ?`, eventID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return ev, err
+}
+
+// ListTriggerEvents returns the events that started jobs for a trigger,
+// newest first. An empty trigger ID lists all triggers.
+func (s *Store) ListTriggerEvents(triggerID string, limit int) ([]TriggerEvent, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	query := `SELECT ` + triggerEventColumns + ` FROM trigger_events WHERE status = ?`
+	args := []any{TriggerEventStarted}
+	if triggerID != "" {
+		query += ` AND trigger_id = ?`
+		args = append(args, triggerID)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TriggerEvent
+	for rows.Next() {
+		ev, err := scanTriggerEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *ev)
+	}
+	return out, rows.Err()
+}
+
+func scanRoleTrigger(row rowScanner) (*RoleTrigger, error) {
+	var t RoleTrigger
+	var primed int
+	if err := row.Scan(&t.TriggerID, &t.Role, &t.Kind, &t.Source, &t.Cursor, &primed,
+		&t.LastEventAt, &t.LastError, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	t.Primed = primed != 0
+	return &t, nil
+}
+
+func scanTriggerEvent(row rowScanner) (*TriggerEvent, error) {
+	var ev TriggerEvent
+	if err := row.Scan(&ev.ID, &ev.TriggerID, &ev.EventKey, &ev.Status, &ev.Title, &ev.Task,
+		&ev.Params, &ev.ChatID, &ev.JobID, &ev.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
@@ -0,0 +1,71 @@
+package storage
+
+import "testing"
+
+func TestRoleTriggerStateAndEventDedup(t *testing.T) {
+	s := newV2TestStore(t)
+
+	const id = "filer:watch:inbox/*.pdf"
+	if err := s.EnsureRoleTrigger(RoleTrigger{TriggerID: id, Role: "filer", Kind: "watch", Source: "inbox/*.pdf"}); err != nil {
+		t.Fatalf("EnsureRoleTrigger: %v", err)
+	}
+	state, err := s.GetRoleTrigger(id)
+	if err != nil || state == nil {
+		t.Fatalf("GetRoleTrigger = %+v, %v", state, err)
+	}
+	if state.Primed {
+		t.Fatal("new trigger should not be primed")
+	}
+
+	if err := s.SetRoleTriggerCursor(id, "7:42"); err != nil {
+		t.Fatalf("SetRoleTriggerCursor: %v", err)
+	}
+	// Re-registering the trigger keeps its position.
+	if err := s.EnsureRoleTrigger(RoleTrigger{TriggerID: id, Role: "filer", Kind: "watch", Source: "inbox/*.pdf"}); err != nil {
+		t.Fatalf("EnsureRoleTrigger again: %v", err)
+	}
+	if state, _ = s.GetRoleTrigger(id); !state.Primed || state.Cursor != "7:42" {
+		t.Fatalf("state after re-register = %+v", state)
+	}
+
+	if _, isNew, err := s.RecordTriggerEvent(TriggerEvent{TriggerID: id, EventKey: "old.pdf", Status: TriggerEventSeen}); err != nil || !isNew {
+		t.Fatalf("RecordTriggerEvent(seen) = %v, %v", isNew, err)
+	}
+	eventID, isNew, err := s.RecordTriggerEvent(TriggerEvent{TriggerID: id, EventKey: "new.pdf", Status: TriggerEventStarted, Title: "new.pdf", Task: "File new.pdf"})
+	if err != nil || !isNew {
+		t.Fatalf("RecordTriggerEvent(started) = %v, %v", isNew, err)
+	}
+	if _, isNew, err := s.RecordTriggerEvent(TriggerEvent{TriggerID: id, EventKey: "new.pdf", Status: TriggerEventStarted}); err != nil || isNew {
+		t.Fatalf("duplicate RecordTriggerEvent = %v, %v; want not new", isNew, err)
+	}
+	if err := s.SetTriggerEventJob(eventID, "job-1"); err != nil {
+		t.Fatalf("SetTriggerEventJob: %v", err)
+	}
+
+	ev, err := s.GetTriggerEvent(eventID)
+	if err != nil || ev == nil || ev.Task != "File new.pdf" || ev.JobID != "job-1" {
+		t.Fatalf("GetTriggerEvent = %+v, %v", ev, err)
+	}
+	events, err := s.ListTriggerEvents("", 10)
+	if err != nil || len(events) != 1 || events[0].EventKey != "new.pdf" {
+		t.Fatalf("ListTriggerEvents = %+v, %v; want only the started event", events, err)
+	}
+	if state, _ = s.GetRoleTrigger(id); state.LastEventAt == "" {
+		t.Error("LastEventAt not set by a started event")
+	}
+
+	// A deleted event is recorded as new when it arrives again.
+	if err := s.DeleteTriggerEvent(eventID); err != nil {
+		t.Fatalf("DeleteTriggerEvent: %v", err)
+	}
+	if _, isNew, err := s.RecordTriggerEvent(TriggerEvent{TriggerID: id, EventKey: "new.pdf", Status: TriggerEventStarted}); err != nil || !isNew {
+		t.Fatalf("RecordTriggerEvent after delete = %v, %v; want new", isNew, err)
+	}
+
+	if err := s.SetRoleTriggerError(id, "boom"); err != nil {
+		t.Fatalf("SetRoleTriggerError: %v", err)
+	}
+	if state, _ = s.GetRoleTrigger(id); state.LastError != "boom" {
+		t.Errorf("LastError = %q, want boom", state.LastError)
+	}
+}
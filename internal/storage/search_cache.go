@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GetSearchCache returns cached search results for key if they have not
+// expired. ok is false on a miss.
+func (s *Store) GetSearchCache(key string) (provider, results string, ok bool, err error) {
+	err = s.db.QueryRow(`
+		SELECT provider, results FROM search_cache
+		WHERE cache_key = ? AND expires_at > datetime('now')
+	`, key).Scan(&provider, &results)
+	if err == sql.ErrNoRows {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, err
+	}
+	return provider, results, true, nil
+}
+
+// PutSearchCache stores search results for key for ttl, replacing any
+// previous entry.
+func (s *Store) PutSearchCache(key, provider, results string, ttl time.Duration) error {
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("cache key is required")
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	_, err := s.db.Exec(`
+		INSERT OR REPLACE INTO search_cache (cache_key, provider, results, created_at, expires_at)
+		VALUES (?, ?, ?, datetime('now'), datetime('now', ?))
+	`, key, provider, results, fmt.Sprintf("+%d seconds", int64(ttl.Seconds())))
+	return err
+}
+
+// PruneSearchCache deletes expired search results and returns how many
+// entries were removed.
+func (s *Store) PruneSearchCache() (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM search_cache WHERE expires_at <= datetime('now')`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchCache_HitMissAndExpiry(t *testing.T) {
+	s := newTestStore(t)
+
+	if _, _, ok, err := s.GetSearchCache("k"); err != nil || ok {
+		t.Fatalf("empty cache: ok=%v err=%v", ok, err)
+	}
+
+	if err := s.PutSearchCache("k", "searxng", `[{"title":"a"}]`, time.Hour); err != nil {
+		t.Fatalf("PutSearchCache: %v", err)
+	}
+	provider, results, ok, err := s.GetSearchCache("k")
+	if err != nil || !ok {
+		t.Fatalf("expected hit, ok=%v err=%v", ok, err)
+	}
+	if provider != "searxng" || results != `[{"title":"a"}]` {
+		t.Errorf("unexpected entry %q %q", provider, results)
+	}
+
+	// Force the entry into the past and check it is neither served nor kept.
+	if _, err := s.db.Exec(`UPDATE search_cache SET expires_at = datetime('now', '-1 seconds')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok, _ := s.GetSearchCache("k"); ok {
+		t.Error("expired entry served from cache")
+	}
+	n, err := s.PruneSearchCache()
+	if err != nil || n != 1 {
+		t.Errorf("PruneSearchCache = %d, %v; want 1", n, err)
+	}
+}
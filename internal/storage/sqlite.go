@@ -112,6 +112,7 @@ func (s *Store) migrate() error {
 		`ALTER TABLE sessions ADD COLUMN verbose INTEGER DEFAULT 0;`,
 		`ALTER TABLE sessions ADD COLUMN queue_mode TEXT DEFAULT 'interrupt';`,
 		`ALTER TABLE sessions ADD COLUMN queue_debounce_ms INTEGER DEFAULT 1500;`,
+		`ALTER TABLE sessions ADD COLUMN tts_mode TEXT DEFAULT 'off';`,
 		// Cron jobs table
 		`CREATE TABLE IF NOT EXISTS cron_jobs (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -223,6 +224,56 @@ func (s *Store) migrate() error {
 		`ALTER TABLE cron_jobs ADD COLUMN type TEXT NOT NULL DEFAULT 'llm';`,
 		// Cron job timeout in seconds (0 = use default)
 		`ALTER TABLE cron_jobs ADD COLUMN timeout_seconds INTEGER NOT NULL DEFAULT 0;`,
+		// One-shot cron jobs: UTC RFC3339 fire time ('' = recurring expression)
+		`ALTER TABLE cron_jobs ADD COLUMN run_at TEXT NOT NULL DEFAULT '';`,
+		// IANA timezone the job's schedule is evaluated in ('' = server local time)
+		`ALTER TABLE cron_jobs ADD COLUMN timezone TEXT NOT NULL DEFAULT '';`,
+		// Per-chat IANA timezone used when scheduling from that chat
+		`ALTER TABLE sessions ADD COLUMN timezone TEXT DEFAULT '';`,
+		// Cron job run policies ('' / 0 = scheduler defaults)
+		`ALTER TABLE cron_jobs ADD COLUMN concurrency_policy TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE cron_jobs ADD COLUMN misfire_policy TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE cron_jobs ADD COLUMN misfire_limit INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE cron_jobs ADD COLUMN jitter_seconds INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE cron_jobs ADD COLUMN alert_after INTEGER NOT NULL DEFAULT 0;`,
+		// Cron run history, one row per fire (including skipped ones)
+		`CREATE TABLE IF NOT EXISTS cron_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			cron_job_id INTEGER NOT NULL,
+			job_id TEXT NOT NULL DEFAULT '',
+			trigger TEXT NOT NULL DEFAULT 'schedule',
+			status TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			scheduled_at TEXT NOT NULL,
+			started_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			finished_at DATETIME
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_cron_runs_cron_job ON cron_runs(cron_job_id, id);`,
+		// Event-triggered roles: per-trigger cursor and the events already seen
+		`CREATE TABLE IF NOT EXISTS role_triggers (
+			trigger_id TEXT PRIMARY KEY,
+			role TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			source TEXT NOT NULL DEFAULT '',
+			cursor TEXT NOT NULL DEFAULT '',
+			primed INTEGER NOT NULL DEFAULT 0,
+			last_event_at DATETIME,
+			last_error TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS trigger_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			trigger_id TEXT NOT NULL,
+			event_key TEXT NOT NULL,
+			status TEXT NOT NULL,
+			title TEXT NOT NULL DEFAULT '',
+			task TEXT NOT NULL DEFAULT '',
+			job_id TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(trigger_id, event_key)
+		);`,
+		`ALTER TABLE trigger_events ADD COLUMN params TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE trigger_events ADD COLUMN chat_id INTEGER NOT NULL DEFAULT 0;`,
 	}
 
 	for _, migration := range migrations {
@@ -364,6 +415,74 @@ func (s *Store) migrateCanonicalSchema() error {
 		);`,
 		`CREATE INDEX IF NOT EXISTS idx_context_nodes_session ON context_nodes(session_key, density);`,
 		`CREATE INDEX IF NOT EXISTS idx_context_nodes_parent ON context_nodes(parent_id);`,
+		// file_checkpoints: prior content of files touched by mutating tool calls,
+		// grouped by session and run so a run's changes can be rolled back.
+		`CREATE TABLE IF NOT EXISTS file_checkpoints (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_key TEXT NOT NULL,
+			run_id      TEXT NOT NULL,
+			tool_name   TEXT NOT NULL DEFAULT '',
+			path        TEXT NOT NULL,
+			existed     INTEGER NOT NULL DEFAULT 1,
+			mode        INTEGER NOT NULL DEFAULT 420,
+			content     BLOB,
+			size        INTEGER NOT NULL DEFAULT 0,
+			created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(session_key, run_id, path)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_file_checkpoints_session ON file_checkpoints(session_key, id);`,
+		// moved_from marks a checkpoint that records a rename; undo moves path
+		// back to moved_from instead of restoring content.
+		`ALTER TABLE file_checkpoints ADD COLUMN moved_from TEXT NOT NULL DEFAULT '';`,
+		// search_cache: serialized web search results keyed by provider chain and
+		// query parameters, reused until expires_at.
+		`CREATE TABLE IF NOT EXISTS search_cache (
+			cache_key  TEXT PRIMARY KEY,
+			provider   TEXT NOT NULL DEFAULT '',
+			results    TEXT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_search_cache_expires ON search_cache(expires_at);`,
+		// web_cache: documents extracted by web_fetch with the validators needed
+		// for conditional GETs, so repeat and paged reads avoid refetching.
+		`CREATE TABLE IF NOT EXISTS web_cache (
+			url           TEXT PRIMARY KEY,
+			etag          TEXT NOT NULL DEFAULT '',
+			last_modified TEXT NOT NULL DEFAULT '',
+			content_type  TEXT NOT NULL DEFAULT '',
+			kind          TEXT NOT NULL DEFAULT '',
+			title         TEXT NOT NULL DEFAULT '',
+			meta          TEXT NOT NULL DEFAULT '',
+			content       TEXT NOT NULL,
+			fetched_at    DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+		// parent_job_id links workflow step jobs to the workflow job that ran them.
+		`ALTER TABLE jobs ADD COLUMN parent_job_id TEXT NOT NULL DEFAULT '';`,
+		`CREATE INDEX IF NOT EXISTS idx_jobs_parent_job_id ON jobs(parent_job_id);`,
+		// priority and cost_tier order and cap jobs in the runtime scheduler.
+		`ALTER TABLE jobs ADD COLUMN priority TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE jobs ADD COLUMN cost_tier TEXT NOT NULL DEFAULT '';`,
+		// job_questions: questions a job asked its user through ask_user. They
+		// outlive the process so an answer given across a restart still reaches
+		// the resumed attempt.
+		`CREATE TABLE IF NOT EXISTS job_questions (
+			question_id    TEXT PRIMARY KEY,
+			job_id         TEXT NOT NULL,
+			question       TEXT NOT NULL,
+			choices        TEXT NOT NULL DEFAULT '',
+			default_answer TEXT NOT NULL DEFAULT '',
+			status         TEXT NOT NULL DEFAULT 'pending',
+			answer         TEXT NOT NULL DEFAULT '',
+			answered_by    TEXT NOT NULL DEFAULT '',
+			chat_id        INTEGER NOT NULL DEFAULT 0,
+			message_id     INTEGER NOT NULL DEFAULT 0,
+			expires_at     TEXT NOT NULL DEFAULT '',
+			created_at     DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			answered_at    DATETIME
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_job_questions_job ON job_questions(job_id, status);`,
+		`CREATE INDEX IF NOT EXISTS idx_job_questions_chat ON job_questions(chat_id, status);`,
 	}
 
 	for _, migration := range migrations {
@@ -851,6 +970,15 @@ type CronJob struct {
 	CreatedAt      string
 	Type           string // "llm" (AI agent) or "exec" (direct shell)
 	TimeoutSeconds int    // 0 = use default
+	RunAt          string // one-shot fire time, UTC RFC3339; empty for recurring jobs
+	Timezone       string // IANA zone for Expression; empty = server local time
+
+	// Run policies; zero values mean the scheduler's defaults.
+	ConcurrencyPolicy string // allow, skip or queue while a run is going
+	MisfirePolicy     string // skip, run_once or run_all for runs missed while down
+	MisfireLimit      int    // most missed runs run_all replays
+	JitterSeconds     int    // random delay of up to this many seconds per fire
+	AlertAfter        int    // alert after this many consecutive failures; <0 = never
 }
 
 // SaveCronJob creates or updates a cron job
@@ -860,12 +988,27 @@ func (s *Store) SaveCronJob(expression, task string, chatID int64) (int64, error
 
 // SaveCronJobFull creates a cron job with all parameters
 func (s *Store) SaveCronJobFull(expression, task string, chatID int64, jobType string, timeoutSeconds int) (int64, error) {
-	if jobType == "" {
-		jobType = "llm"
-	}
-	result, err := s.db.Exec(
-		"INSERT INTO cron_jobs (expression, task, chat_id, type, timeout_seconds) VALUES (?, ?, ?, ?, ?)",
-		expression, task, chatID, jobType, timeoutSeconds,
+	return s.SaveCronJobSpec(CronJob{
+		Expression:     expression,
+		Task:           task,
+		ChatID:         chatID,
+		Type:           jobType,
+		TimeoutSeconds: timeoutSeconds,
+	})
+}
+
+// SaveCronJobSpec creates a cron job from job, including one-shot and
+// timezone fields. ID, NextRun, Enabled and CreatedAt are ignored.
+func (s *Store) SaveCronJobSpec(job CronJob) (int64, error) {
+	if job.Type == "" {
+		job.Type = "llm"
+	}
+	result, err := s.db.Exec(`
+		INSERT INTO cron_jobs (expression, task, chat_id, type, timeout_seconds, run_at, timezone,
+			concurrency_policy, misfire_policy, misfire_limit, jitter_seconds, alert_after)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.Expression, job.Task, job.ChatID, job.Type, job.TimeoutSeconds, job.RunAt, job.Timezone,
+		job.ConcurrencyPolicy, job.MisfirePolicy, job.MisfireLimit, job.JitterSeconds, job.AlertAfter,
 	)
 	if err != nil {
 		return 0, err
@@ -875,12 +1018,42 @@ func (s *Store) SaveCronJobFull(expression, task string, chatID int64, jobType s
 
 // GetCronJobs returns all enabled cron jobs
 func (s *Store) GetCronJobs() ([]CronJob, error) {
+	return s.queryCronJobs("WHERE enabled = 1")
+}
+
+// ListCronJobs returns every cron job, enabled or not, by ID.
+func (s *Store) ListCronJobs() ([]CronJob, error) {
+	return s.queryCronJobs("ORDER BY id")
+}
+
+// GetCronJob loads a cron job, enabled or not. It returns (nil, nil) when
+// it does not exist.
+func (s *Store) GetCronJob(id int64) (*CronJob, error) {
+	jobs, err := s.queryCronJobs("WHERE id = ?", id)
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// UpdateCronJobPolicies stores the run policy fields of job.
+func (s *Store) UpdateCronJobPolicies(job CronJob) error {
+	_, err := s.db.Exec(`
+		UPDATE cron_jobs
+		SET concurrency_policy = ?, misfire_policy = ?, misfire_limit = ?, jitter_seconds = ?, alert_after = ?
+		WHERE id = ?
+	`, job.ConcurrencyPolicy, job.MisfirePolicy, job.MisfireLimit, job.JitterSeconds, job.AlertAfter, job.ID)
+	return err
+}
+
+func (s *Store) queryCronJobs(clause string, args ...any) ([]CronJob, error) {
 	rows, err := s.db.Query(`
 		SELECT id, expression, task, chat_id, next_run, enabled, created_at,
-		       COALESCE(type, 'llm'), COALESCE(timeout_seconds, 0)
+		       COALESCE(type, 'llm'), COALESCE(timeout_seconds, 0),
+		       COALESCE(run_at, ''), COALESCE(timezone, ''),
+		       concurrency_policy, misfire_policy, misfire_limit, jitter_seconds, alert_after
 		FROM cron_jobs 
-		WHERE enabled = 1
-	`)
+		`+clause, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -890,7 +1063,8 @@ func (s *Store) GetCronJobs() ([]CronJob, error) {
 	for rows.Next() {
 		var job CronJob
 		var nextRun sql.NullString
-		if err := rows.Scan(&job.ID, &job.Expression, &job.Task, &job.ChatID, &nextRun, &job.Enabled, &job.CreatedAt, &job.Type, &job.TimeoutSeconds); err != nil {
+		if err := rows.Scan(&job.ID, &job.Expression, &job.Task, &job.ChatID, &nextRun, &job.Enabled, &job.CreatedAt, &job.Type, &job.TimeoutSeconds, &job.RunAt, &job.Timezone,
+			&job.ConcurrencyPolicy, &job.MisfirePolicy, &job.MisfireLimit, &job.JitterSeconds, &job.AlertAfter); err != nil {
 			continue
 		}
 		if nextRun.Valid {
@@ -1205,7 +1379,7 @@ func (s *Store) SetEmergencyStopEnabled(enabled bool) error {
 func (s *Store) GetSessionOption(chatID int64, column string) (string, error) {
 	// Validate column name to prevent SQL injection
 	validColumns := map[string]bool{
-		"usage_mode": true, "think_level": true, "queue_mode": true,
+		"usage_mode": true, "think_level": true, "queue_mode": true, "tts_mode": true, "timezone": true,
 	}
 	if !validColumns[column] {
 		return "", fmt.Errorf("invalid column: %s", column)
@@ -1221,7 +1395,7 @@ func (s *Store) GetSessionOption(chatID int64, column string) (string, error) {
 // SetSessionOption sets a string option on a session
 func (s *Store) SetSessionOption(chatID int64, column, value string) error {
 	validColumns := map[string]bool{
-		"usage_mode": true, "think_level": true, "queue_mode": true,
+		"usage_mode": true, "think_level": true, "queue_mode": true, "tts_mode": true, "timezone": true,
 	}
 	if !validColumns[column] {
 		return fmt.Errorf("invalid column: %s", column)
@@ -1380,6 +1554,9 @@ type Job struct {
 	SessionKey         string
 	DeliverySessionKey string
 	RetryOfJobID       string
+	ParentJobID        string
+	Priority           string
+	CostTier           string
 	Description        string
 	Status             string
 	CancelRequested    bool
@@ -1446,10 +1623,10 @@ func (s *Store) CreateJob(job Job) error {
 
 	_, err := s.db.Exec(`
 		INSERT INTO jobs (
-			job_id, kind, worker, session_key, delivery_session_key, retry_of_job_id,
-			description, status, cancel_requested, attempt, max_attempts, timeout_seconds,
-			summary, error
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			job_id, kind, worker, session_key, delivery_session_key, retry_of_job_id, parent_job_id,
+			priority, cost_tier, description, status, cancel_requested, attempt, max_attempts,
+			timeout_seconds, summary, error
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		jobID,
 		kind,
@@ -1457,6 +1634,9 @@ func (s *Store) CreateJob(job Job) error {
 		strings.TrimSpace(job.SessionKey),
 		strings.TrimSpace(job.DeliverySessionKey),
 		strings.TrimSpace(job.RetryOfJobID),
+		strings.TrimSpace(job.ParentJobID),
+		strings.TrimSpace(job.Priority),
+		strings.TrimSpace(job.CostTier),
 		job.Description,
 		status,
 		cancelRequested,
@@ -1477,44 +1657,18 @@ func (s *Store) GetJob(jobID string) (*Job, error) {
 		return nil, nil
 	}
 
-	var (
-		job                Job
-		cancelRequestedInt int
-	)
-	err := s.db.QueryRow(`
-		SELECT job_id, kind, worker, session_key, delivery_session_key, retry_of_job_id,
-		       description, status, cancel_requested, attempt, max_attempts, timeout_seconds,
-		       summary, error, created_at, COALESCE(started_at, ''), COALESCE(completed_at, ''), updated_at
+	job, err := scanJob(s.db.QueryRow(`
+		SELECT `+jobColumns+`
 		FROM jobs
 		WHERE job_id = ?
-	`, jobID).Scan(
-		&job.JobID,
-		&job.Kind,
-		&job.Worker,
-		&job.SessionKey,
-		&job.DeliverySessionKey,
-		&job.RetryOfJobID,
-		&job.Description,
-		&job.Status,
-		&cancelRequestedInt,
-		&job.Attempt,
-		&job.MaxAttempts,
-		&job.TimeoutSeconds,
-		&job.Summary,
-		&job.Error,
-		&job.CreatedAt,
-		&job.StartedAt,
-		&job.CompletedAt,
-		&job.UpdatedAt,
-	)
+	`, jobID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	job.CancelRequested = cancelRequestedInt != 0
-	return &job, nil
+	return job, nil
 }
 
 // ListJobs returns the newest durable jobs first.
@@ -1536,18 +1690,14 @@ func (s *Store) ListJobsByStatus(status string, limit int) ([]Job, error) {
 	)
 	if status == "" {
 		rows, err = s.db.Query(`
-			SELECT job_id, kind, worker, session_key, delivery_session_key, retry_of_job_id,
-			       description, status, cancel_requested, attempt, max_attempts, timeout_seconds,
-			       summary, error, created_at, COALESCE(started_at, ''), COALESCE(completed_at, ''), updated_at
+			SELECT `+jobColumns+`
 			FROM jobs
 			ORDER BY created_at DESC, job_id DESC
 			LIMIT ?
 		`, limit)
 	} else {
 		rows, err = s.db.Query(`
-			SELECT job_id, kind, worker, session_key, delivery_session_key, retry_of_job_id,
-			       description, status, cancel_requested, attempt, max_attempts, timeout_seconds,
-			       summary, error, created_at, COALESCE(started_at, ''), COALESCE(completed_at, ''), updated_at
+			SELECT `+jobColumns+`
 			FROM jobs
 			WHERE status = ?
 			ORDER BY created_at DESC, job_id DESC
@@ -1559,36 +1709,74 @@ func (s *Store) ListJobsByStatus(status string, limit int) ([]Job, error) {
 	}
 	defer rows.Close()
 
+	return scanJobs(rows)
+}
+
+// ListChildJobs returns the jobs spawned by a parent job, oldest first.
+func (s *Store) ListChildJobs(parentJobID string) ([]Job, error) {
+	rows, err := s.db.Query(`
+		SELECT `+jobColumns+`
+		FROM jobs
+		WHERE parent_job_id = ?
+		ORDER BY created_at ASC, job_id ASC
+	`, strings.TrimSpace(parentJobID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanJobs(rows)
+}
+
+const jobColumns = `job_id, kind, worker, session_key, delivery_session_key, retry_of_job_id, parent_job_id,
+	priority, cost_tier, description, status, cancel_requested, attempt, max_attempts,
+	timeout_seconds, summary, error, created_at, COALESCE(started_at, ''), COALESCE(completed_at, ''), updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row rowScanner) (*Job, error) {
+	var (
+		job                Job
+		cancelRequestedInt int
+	)
+	if err := row.Scan(
+		&job.JobID,
+		&job.Kind,
+		&job.Worker,
+		&job.SessionKey,
+		&job.DeliverySessionKey,
+		&job.RetryOfJobID,
+		&job.ParentJobID,
+		&job.Priority,
+		&job.CostTier,
+		&job.Description,
+		&job.Status,
+		&cancelRequestedInt,
+		&job.Attempt,
+		&job.MaxAttempts,
+		&job.TimeoutSeconds,
+		&job.Summary,
+		&job.Error,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.CompletedAt,
+		&job.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	job.CancelRequested = cancelRequestedInt != 0
+	return &job, nil
+}
+
+func scanJobs(rows *sql.Rows) ([]Job, error) {
 	var jobs []Job
 	for rows.Next() {
-		var (
-			job                Job
-			cancelRequestedInt int
-		)
-		if err := rows.Scan(
-			&job.JobID,
-			&job.Kind,
-			&job.Worker,
-			&job.SessionKey,
-			&job.DeliverySessionKey,
-			&job.RetryOfJobID,
-			&job.Description,
-			&job.Status,
-			&cancelRequestedInt,
-			&job.Attempt,
-			&job.MaxAttempts,
-			&job.TimeoutSeconds,
-			&job.Summary,
-			&job.Error,
-			&job.CreatedAt,
-			&job.StartedAt,
-			&job.CompletedAt,
-			&job.UpdatedAt,
-		); err != nil {
+		job, err := scanJob(rows)
+		if err != nil {
 			return nil, err
 		}
-		job.CancelRequested = cancelRequestedInt != 0
-		jobs = append(jobs, job)
+		jobs = append(jobs, *job)
 	}
 	return jobs, rows.Err()
 }
@@ -1619,6 +1807,18 @@ func (s *Store) MarkJobRunning(jobID string) error {
 	return err
 }
 
+// MarkJobWaitingInput parks a running job while it waits for an answer from
+// its user. MarkJobRunning moves it back.
+func (s *Store) MarkJobWaitingInput(jobID string) error {
+	_, err := s.db.Exec(`
+		UPDATE jobs
+		SET status = 'waiting_input',
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE job_id = ?
+	`, strings.TrimSpace(jobID))
+	return err
+}
+
 func (s *Store) markJobTerminal(jobID, status, summary, errMsg string) error {
 	_, err := s.db.Exec(`
 		UPDATE jobs
@@ -1652,6 +1852,39 @@ func (s *Store) MarkJobTimedOut(jobID, errMsg string) error {
 	return s.markJobTerminal(jobID, "timed_out", "", errMsg)
 }
 
+// markActiveJobTerminal is markJobTerminal for a job that is still pending,
+// running or waiting_input. It reports false, changing nothing, when the job
+// has already reached another status.
+func (s *Store) markActiveJobTerminal(jobID, status, errMsg string) (bool, error) {
+	res, err := s.db.Exec(`
+		UPDATE jobs
+		SET status = ?,
+		    summary = '',
+		    error = ?,
+		    completed_at = CURRENT_TIMESTAMP,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE job_id = ? AND status IN ('pending', 'running', 'waiting_input')
+	`, status, errMsg, strings.TrimSpace(jobID))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// MarkJobInterrupted marks a job that was left pending, running or waiting_input by a
+// process that stopped before it finished. It reports false when the job has
+// finished in the meantime.
+func (s *Store) MarkJobInterrupted(jobID, errMsg string) (bool, error) {
+	return s.markActiveJobTerminal(jobID, "interrupted", errMsg)
+}
+
+// MarkActiveJobCancelled cancels a job that is still pending, running or
+// waiting_input, and reports false when it has already finished.
+func (s *Store) MarkActiveJobCancelled(jobID, errMsg string) (bool, error) {
+	return s.markActiveJobTerminal(jobID, "cancelled", errMsg)
+}
+
 // AddJobEvent persists a lifecycle event for the job.
 func (s *Store) AddJobEvent(event JobEvent) error {
 	jobID := strings.TrimSpace(event.JobID)
@@ -1669,6 +1902,55 @@ func (s *Store) AddJobEvent(event JobEvent) error {
 	return err
 }
 
+// ReplaceJobEvent adds an event and deletes the job's earlier events of the
+// same type, for state where only the latest copy matters.
+func (s *Store) ReplaceJobEvent(event JobEvent) error {
+	jobID := strings.TrimSpace(event.JobID)
+	if jobID == "" {
+		return fmt.Errorf("job ID is required")
+	}
+	eventType := strings.TrimSpace(event.EventType)
+	if eventType == "" {
+		return fmt.Errorf("event type is required")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	if _, err := tx.Exec(`DELETE FROM job_events WHERE job_id = ? AND event_type = ?`, jobID, eventType); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO job_events (job_id, event_type, message, payload)
+		VALUES (?, ?, ?, ?)
+	`, jobID, eventType, event.Message, event.Payload); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetLatestJobEvent returns the job's most recent event of the given type,
+// or nil if it has none.
+func (s *Store) GetLatestJobEvent(jobID, eventType string) (*JobEvent, error) {
+	var event JobEvent
+	err := s.db.QueryRow(`
+		SELECT id, job_id, event_type, message, payload, created_at
+		FROM job_events
+		WHERE job_id = ? AND event_type = ?
+		ORDER BY id DESC
+		LIMIT 1
+	`, strings.TrimSpace(jobID), strings.TrimSpace(eventType)).Scan(
+		&event.ID, &event.JobID, &event.EventType, &event.Message, &event.Payload, &event.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
 // ListJobEvents returns job lifecycle events in chronological order.
 func (s *Store) ListJobEvents(jobID string, limit int) ([]JobEvent, error) {
 	if limit <= 0 {
@@ -1703,23 +1985,74 @@ func (s *Store) ListJobEvents(jobID string, limit int) ([]JobEvent, error) {
 
 // AddJobArtifact persists one durable job artifact.
 func (s *Store) AddJobArtifact(artifact JobArtifact) error {
+	_, err := s.InsertJobArtifact(artifact)
+	return err
+}
+
+// InsertJobArtifact persists one artifact and returns its row ID. The job ID
+// is an owner key: a durable job ID, or a pseudo owner such as
+// "session:<key>" for artifacts produced outside jobs.
+func (s *Store) InsertJobArtifact(artifact JobArtifact) (int64, error) {
 	jobID := strings.TrimSpace(artifact.JobID)
 	if jobID == "" {
-		return fmt.Errorf("job ID is required")
+		return 0, fmt.Errorf("job ID is required")
 	}
 	name := strings.TrimSpace(artifact.Name)
 	if name == "" {
-		return fmt.Errorf("artifact name is required")
+		return 0, fmt.Errorf("artifact name is required")
 	}
 	artifactType := strings.TrimSpace(artifact.ArtifactType)
 	if artifactType == "" {
-		return fmt.Errorf("artifact type is required")
+		return 0, fmt.Errorf("artifact type is required")
 	}
-	_, err := s.db.Exec(`
+	res, err := s.db.Exec(`
 		INSERT INTO job_artifacts (job_id, name, artifact_type, mime_type, content, uri, metadata)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`, jobID, name, artifactType, artifact.MimeType, artifact.Content, artifact.URI, artifact.Metadata)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetJobArtifact returns one artifact by row ID, or nil if it does not exist.
+func (s *Store) GetJobArtifact(id int64) (*JobArtifact, error) {
+	var artifact JobArtifact
+	err := s.db.QueryRow(`
+		SELECT id, job_id, name, artifact_type, mime_type, content, uri, metadata, created_at
+		FROM job_artifacts
+		WHERE id = ?
+	`, id).Scan(
+		&artifact.ID,
+		&artifact.JobID,
+		&artifact.Name,
+		&artifact.ArtifactType,
+		&artifact.MimeType,
+		&artifact.Content,
+		&artifact.URI,
+		&artifact.Metadata,
+		&artifact.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &artifact, nil
+}
+
+// PruneJobArtifacts removes artifacts of the given type older than maxAgeHours
+// and returns how many were deleted.
+func (s *Store) PruneJobArtifacts(artifactType string, maxAgeHours int) (int64, error) {
+	res, err := s.db.Exec(`
+		DELETE FROM job_artifacts
+		WHERE artifact_type = ? AND created_at < datetime('now', ?)
+	`, artifactType, fmt.Sprintf("-%d hours", maxAgeHours))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
 }
 
 // ListJobArtifacts returns job artifacts in chronological order.
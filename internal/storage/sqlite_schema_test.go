@@ -415,6 +415,71 @@ func TestJobCRUDAndLinkedArtifacts(t *testing.T) {
 	}
 }
 
+func TestMarkJobInterruptedSkipsFinishedJobs(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	defer store.Close() //nolint:errcheck
+
+	for _, id := range []string{"job-done", "job-orphan"} {
+		if err := store.CreateJob(Job{JobID: id, Kind: "background_task", Status: "pending", Attempt: 1, MaxAttempts: 1}); err != nil {
+			t.Fatalf("CreateJob failed: %v", err)
+		}
+	}
+	if err := store.MarkJobSucceeded("job-done", "finished"); err != nil {
+		t.Fatalf("MarkJobSucceeded failed: %v", err)
+	}
+
+	if ok, err := store.MarkJobInterrupted("job-done", "interrupted by restart"); err != nil || ok {
+		t.Fatalf("MarkJobInterrupted(finished) = %v, %v; want false", ok, err)
+	}
+	if got, _ := store.GetJob("job-done"); got == nil || got.Status != "succeeded" || got.Summary != "finished" {
+		t.Fatalf("finished job was rewritten: %+v", got)
+	}
+	if ok, err := store.MarkJobInterrupted("job-orphan", "interrupted by restart"); err != nil || !ok {
+		t.Fatalf("MarkJobInterrupted(pending) = %v, %v; want true", ok, err)
+	}
+	if got, _ := store.GetJob("job-orphan"); got == nil || got.Status != "interrupted" {
+		t.Fatalf("orphan status = %+v, want interrupted", got)
+	}
+}
+
+func TestJobArtifactGetAndPrune(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	id, err := store.InsertJobArtifact(JobArtifact{
+		JobID:        "session:telegram:1",
+		Name:         "tool_output/local",
+		ArtifactType: "tool_output",
+		Content:      "big output",
+	})
+	if err != nil {
+		t.Fatalf("InsertJobArtifact failed: %v", err)
+	}
+
+	got, err := store.GetJobArtifact(id)
+	if err != nil {
+		t.Fatalf("GetJobArtifact failed: %v", err)
+	}
+	if got == nil || got.Content != "big output" || got.JobID != "session:telegram:1" {
+		t.Fatalf("unexpected artifact: %+v", got)
+	}
+	if missing, err := store.GetJobArtifact(id + 100); err != nil || missing != nil {
+		t.Fatalf("expected nil for missing artifact, got %+v, %v", missing, err)
+	}
+
+	if _, err := store.db.Exec(`UPDATE job_artifacts SET created_at = datetime('now', '-3 days') WHERE id = ?`, id); err != nil {
+		t.Fatalf("backdate artifact: %v", err)
+	}
+	if n, err := store.PruneJobArtifacts("report", 24); err != nil || n != 0 {
+		t.Fatalf("prune of other type removed %d rows (err %v)", n, err)
+	}
+	if n, err := store.PruneJobArtifacts("tool_output", 24); err != nil || n != 1 {
+		t.Fatalf("expected 1 pruned artifact, got %d (err %v)", n, err)
+	}
+}
+
 func newTestStore(t *testing.T) *Store {
 	t.Helper()
 
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WebCacheEntry is a document extracted by web_fetch along with the HTTP
+// validators used to revalidate it.
+type WebCacheEntry struct {
+	URL          string
+	ETag         string
+	LastModified string
+	ContentType  string
+	Kind         string // html, pdf, json, text, or feed
+	Title        string
+	Meta         string
+	Content      string
+	FetchedAt    time.Time
+}
+
+// GetWebCache returns the cached document for url, or nil when there is none.
+func (s *Store) GetWebCache(url string) (*WebCacheEntry, error) {
+	var e WebCacheEntry
+	err := s.db.QueryRow(`
+		SELECT url, etag, last_modified, content_type, kind, title, meta, content, fetched_at
+		FROM web_cache WHERE url = ?
+	`, url).Scan(&e.URL, &e.ETag, &e.LastModified, &e.ContentType, &e.Kind, &e.Title, &e.Meta, &e.Content, &e.FetchedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// PutWebCache stores or replaces the cached document for e.URL and marks it
+// as fetched now.
+func (s *Store) PutWebCache(e WebCacheEntry) error {
+	if strings.TrimSpace(e.URL) == "" {
+		return fmt.Errorf("url is required")
+	}
+	_, err := s.db.Exec(`
+		INSERT OR REPLACE INTO web_cache (url, etag, last_modified, content_type, kind, title, meta, content, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	`, e.URL, e.ETag, e.LastModified, e.ContentType, e.Kind, e.Title, e.Meta, e.Content)
+	return err
+}
+
+// TouchWebCache marks a cached document as revalidated now.
+func (s *Store) TouchWebCache(url string) error {
+	_, err := s.db.Exec(`UPDATE web_cache SET fetched_at = datetime('now') WHERE url = ?`, url)
+	return err
+}
+
+// PruneWebCache deletes documents not fetched or revalidated within maxAge
+// and returns how many were removed.
+func (s *Store) PruneWebCache(maxAge time.Duration) (int64, error) {
+	if maxAge <= 0 {
+		return 0, nil
+	}
+	res, err := s.db.Exec(`DELETE FROM web_cache WHERE fetched_at < datetime('now', ?)`,
+		fmt.Sprintf("-%d seconds", int64(maxAge.Seconds())))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
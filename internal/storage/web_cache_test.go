@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebCache_PutGetTouchPrune(t *testing.T) {
+	s := newTestStore(t)
+
+	if e, err := s.GetWebCache("https://example.com/a"); err != nil || e != nil {
+		t.Fatalf("empty cache: %+v, %v", e, err)
+	}
+
+	entry := WebCacheEntry{URL: "https://example.com/a", ETag: `"v1"`, Kind: "html", Title: "A", Content: "body"}
+	if err := s.PutWebCache(entry); err != nil {
+		t.Fatalf("PutWebCache: %v", err)
+	}
+	got, err := s.GetWebCache(entry.URL)
+	if err != nil || got == nil {
+		t.Fatalf("GetWebCache: %+v, %v", got, err)
+	}
+	if got.ETag != `"v1"` || got.Content != "body" || got.Kind != "html" {
+		t.Errorf("unexpected entry %+v", got)
+	}
+	if time.Since(got.FetchedAt) > time.Minute {
+		t.Errorf("FetchedAt not set to now: %v", got.FetchedAt)
+	}
+
+	if _, err := s.db.Exec(`UPDATE web_cache SET fetched_at = datetime('now', '-2 days')`); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.TouchWebCache(entry.URL); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.PruneWebCache(24 * time.Hour); err != nil || n != 0 {
+		t.Errorf("touched entry pruned: %d, %v", n, err)
+	}
+
+	if _, err := s.db.Exec(`UPDATE web_cache SET fetched_at = datetime('now', '-2 days')`); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.PruneWebCache(24 * time.Hour); err != nil || n != 1 {
+		t.Errorf("PruneWebCache = %d, %v; want 1", n, err)
+	}
+}
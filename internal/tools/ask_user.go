@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ok-gobot/internal/runtime"
+)
+
+const (
+	defaultAskTimeout = 30 * time.Minute
+	maxAskTimeout     = 24 * time.Hour
+	maxAskChoices     = 8
+)
+
+// AskUserTool lets a background job pause until its user answers a
+// question. The job waits in the waiting_input status; the question goes to
+// the job's delivery chat and the TUI.
+type AskUserTool struct {
+	// ask is runtime.AskUser; tests swap it out.
+	ask func(ctx context.Context, q runtime.Question) (string, error)
+}
+
+// NewAskUserTool creates the ask_user tool.
+func NewAskUserTool() *AskUserTool {
+	return &AskUserTool{ask: runtime.AskUser}
+}
+
+func (a *AskUserTool) Name() string { return "ask_user" }
+
+func (a *AskUserTool) Description() string {
+	return "Ask the user a clarifying question from a background job and wait for the answer (only inside jobs such as workflow steps and cron tasks; in a chat, just ask in your reply)"
+}
+
+func (a *AskUserTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"question": map[string]interface{}{
+				"type":        "string",
+				"description": "The question to ask; make it answerable without further context",
+			},
+			"choices": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional answers offered as buttons (max 8)",
+			},
+			"default": map[string]interface{}{
+				"type":        "string",
+				"description": "Answer to use if the user does not reply before the timeout",
+			},
+			"timeout": map[string]interface{}{
+				"type":        "string",
+				"description": "How long to wait, e.g. 10m or 2h (default 30m, max 24h)",
+			},
+		},
+		"required": []string{"question"},
+	}
+}
+
+func (a *AskUserTool) Execute(ctx context.Context, args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: ask_user <question>")
+	}
+	return a.ExecuteJSON(ctx, map[string]string{"question": strings.Join(args, " ")})
+}
+
+func (a *AskUserTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	q := runtime.Question{
+		Text:    strings.TrimSpace(params["question"]),
+		Default: strings.TrimSpace(params["default"]),
+		Timeout: defaultAskTimeout,
+	}
+	if q.Text == "" {
+		return "", fmt.Errorf("question is required")
+	}
+	if raw := strings.TrimSpace(params["choices"]); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &q.Choices); err != nil {
+			return "", fmt.Errorf("choices must be an array of strings: %w", err)
+		}
+		if len(q.Choices) > maxAskChoices {
+			return "", fmt.Errorf("at most %d choices are allowed", maxAskChoices)
+		}
+	}
+	if raw := strings.TrimSpace(params["timeout"]); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return "", fmt.Errorf("timeout must be a positive duration such as 10m")
+		}
+		if timeout > maxAskTimeout {
+			timeout = maxAskTimeout
+		}
+		q.Timeout = timeout
+	}
+
+	answer, err := a.ask(ctx, q)
+	switch {
+	case errors.Is(err, runtime.ErrNotInJob):
+		return "", &ToolDenial{
+			ToolName:    a.Name(),
+			Family:      "ask_user",
+			Reason:      "ask_user only works inside background jobs",
+			Remediation: "Ask the question in your reply instead.",
+		}
+	case errors.Is(err, runtime.ErrNoAnswer):
+		return "The user did not answer in time and there is no default. Proceed with your best judgement and say what you assumed.", nil
+	case err != nil:
+		return "", err
+	}
+	return fmt.Sprintf("User answered: %s", answer), nil
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"ok-gobot/internal/runtime"
+)
+
+func TestAskUserToolPassesQuestion(t *testing.T) {
+	var got runtime.Question
+	tool := &AskUserTool{ask: func(ctx context.Context, q runtime.Question) (string, error) {
+		got = q
+		return "staging", nil
+	}}
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{
+		"question": "Where should I deploy?",
+		"choices":  `["staging","production"]`,
+		"default":  "staging",
+		"timeout":  "48h",
+	})
+	if err != nil {
+		t.Fatalf("ExecuteJSON failed: %v", err)
+	}
+	if out != "User answered: staging" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if got.Text != "Where should I deploy?" || len(got.Choices) != 2 || got.Default != "staging" {
+		t.Fatalf("unexpected question: %+v", got)
+	}
+	if got.Timeout != maxAskTimeout {
+		t.Fatalf("timeout = %s, want it capped at %s", got.Timeout, maxAskTimeout)
+	}
+}
+
+func TestAskUserToolErrors(t *testing.T) {
+	tool := &AskUserTool{ask: func(ctx context.Context, q runtime.Question) (string, error) {
+		if q.Timeout != defaultAskTimeout {
+			t.Errorf("timeout = %s, want default %s", q.Timeout, defaultAskTimeout)
+		}
+		return "", runtime.ErrNotInJob
+	}}
+
+	var denial *ToolDenial
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"question": "ok?"}); !errors.As(err, &denial) {
+		t.Fatalf("outside a job: expected ToolDenial, got %v", err)
+	}
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"question": "ok?", "choices": "yes"}); err == nil {
+		t.Fatal("expected an error for malformed choices")
+	}
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"question": "ok?", "timeout": "soon"}); err == nil {
+		t.Fatal("expected an error for a bad timeout")
+	}
+
+	tool.ask = func(ctx context.Context, q runtime.Question) (string, error) {
+		return "", runtime.ErrNoAnswer
+	}
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"question": "ok?", "timeout": "1m"})
+	if err != nil || !strings.Contains(out, "did not answer") {
+		t.Fatalf("timed out question = %q, %v", out, err)
+	}
+}
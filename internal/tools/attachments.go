@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"sync"
+)
+
+// Attachment is a file produced by a tool call that the transport should
+// deliver to the user alongside the text result (e.g. a chart PNG).
+type Attachment struct {
+	Path     string
+	MimeType string
+	Caption  string
+}
+
+// IsImage reports whether the attachment can be shown as a photo.
+func (a Attachment) IsImage() bool {
+	switch a.MimeType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// IsVoice reports whether the attachment can be sent as a voice note.
+func (a Attachment) IsVoice() bool {
+	return a.MimeType == "audio/ogg"
+}
+
+// AttachmentCollector gathers attachments emitted during one tool call.
+type AttachmentCollector struct {
+	mu    sync.Mutex
+	items []Attachment
+}
+
+// Attachments returns the attachments collected so far.
+func (c *AttachmentCollector) Attachments() []Attachment {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]Attachment, len(c.items))
+	copy(out, c.items)
+	return out
+}
+
+type attachmentCollectorKey struct{}
+
+// WithAttachmentCollector returns a context whose tool attachments are
+// gathered by the returned collector.
+func WithAttachmentCollector(ctx context.Context) (context.Context, *AttachmentCollector) {
+	c := &AttachmentCollector{}
+	return context.WithValue(ctx, attachmentCollectorKey{}, c), c
+}
+
+// AddAttachment hands a produced file to the caller's collector. It returns
+// false when nobody is collecting (the file is then only mentioned in text).
+func AddAttachment(ctx context.Context, a Attachment) bool {
+	if ctx == nil {
+		return false
+	}
+	c, ok := ctx.Value(attachmentCollectorKey{}).(*AttachmentCollector)
+	if !ok {
+		return false
+	}
+	c.mu.Lock()
+	c.items = append(c.items, a)
+	c.mu.Unlock()
+	return true
+}
+
+type sessionKeyContextKey struct{}
+
+// WithSessionKey tags a run's context with the session it belongs to so tools
+// can keep per-session state such as scratch directories.
+func WithSessionKey(ctx context.Context, sessionKey string) context.Context {
+	if sessionKey == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, sessionKeyContextKey{}, sessionKey)
+}
+
+// SessionKeyFromContext returns the session key set by WithSessionKey, or "".
+func SessionKeyFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	key, _ := ctx.Value(sessionKeyContextKey{}).(string)
+	return key
+}
@@ -0,0 +1,327 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+
+	"ok-gobot/internal/browser"
+	"ok-gobot/internal/logger"
+	"ok-gobot/internal/runtime"
+)
+
+const (
+	defaultBrowserDownloadTimeout = 2 * time.Minute
+	maxBrowserDownloadTimeout     = 10 * time.Minute
+)
+
+// browserSessionDir is the run_code scratch directory of the calling session.
+// Downloads land in its downloads/ subdirectory, next to Telegram uploads, so
+// run_code and upload can use them directly.
+func (b *BrowserTool) browserSessionDir(ctx context.Context) string {
+	root := b.scratchDir
+	if root == "" {
+		root = DefaultScratchDir()
+	}
+	return RunCodeSessionDir(root, SessionKeyFromContext(ctx))
+}
+
+// resolveUploadPath finds a local file for upload or cookie import. Relative
+// paths are looked up in the session scratch directory, then the workspace;
+// absolute paths must lie inside one of them.
+func (b *BrowserTool) resolveUploadPath(ctx context.Context, path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	roots := []string{b.browserSessionDir(ctx)}
+	if b.workspaceRoot != "" {
+		roots = append(roots, b.workspaceRoot)
+	}
+	var lastErr error
+	for _, root := range roots {
+		full, err := resolvePath(root, path)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		st, err := os.Stat(full)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !st.Mode().IsRegular() {
+			return "", fmt.Errorf("%s is not a regular file", path)
+		}
+		return full, nil
+	}
+	if lastErr != nil && !os.IsNotExist(lastErr) {
+		return "", fmt.Errorf("file %q is not allowed: only the session scratch directory and workspace are readable", path)
+	}
+	return "", fmt.Errorf("file %q not found in the session scratch directory or workspace", path)
+}
+
+// deliverBrowserFile registers a produced file as a job artifact and, when
+// send is set, hands it to the transport for delivery to the chat.
+func deliverBrowserFile(ctx context.Context, path, mimeType, caption string, send bool) string {
+	var notes []string
+	if send {
+		if AddAttachment(ctx, Attachment{Path: path, MimeType: mimeType, Caption: caption}) {
+			notes = append(notes, "sent to chat")
+		}
+	}
+	if jobID, err := runtime.AddContextArtifact(ctx, runtime.JobArtifactSpec{
+		Name:     "browser/" + filepath.Base(path),
+		Type:     "file",
+		MimeType: mimeType,
+		URI:      "file://" + path,
+	}); err != nil {
+		notes = append(notes, fmt.Sprintf("artifact failed: %v", err))
+	} else if jobID != "" {
+		notes = append(notes, "artifact of job "+jobID)
+	}
+	return strings.Join(notes, ", ")
+}
+
+// fileMimeType guesses a MIME type from the extension, then the content.
+func fileMimeType(path string) string {
+	if t := mime.TypeByExtension(strings.ToLower(filepath.Ext(path))); t != "" {
+		if mediaType, _, err := mime.ParseMediaType(t); err == nil {
+			return mediaType
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	return mediaType
+}
+
+func fileResult(fields map[string]interface{}) string {
+	payload, _ := json.Marshal(fields)
+	return string(payload)
+}
+
+// download clicks an element or opens a URL and waits for the file it
+// downloads.
+func (b *BrowserTool) download(ctx context.Context, params map[string]string) (string, error) {
+	snapshotID, ref, selector, rawURL := params["snapshot_id"], params["ref"], params["selector"], params["url"]
+	if rawURL != "" {
+		if err := validateBrowserURL(rawURL); err != nil {
+			return "", err
+		}
+	} else if selector == "" && (snapshotID == "" || ref == "") {
+		return "", fmt.Errorf("download requires url, selector, or snapshot_id+ref")
+	}
+	timeout := defaultBrowserDownloadTimeout
+	if raw := strings.TrimSpace(params["timeout_seconds"]); raw != "" {
+		secs, err := strconv.Atoi(raw)
+		if err != nil || secs <= 0 {
+			return "", fmt.Errorf("invalid timeout_seconds: %q", raw)
+		}
+		timeout = min(time.Duration(secs)*time.Second, maxBrowserDownloadTimeout)
+	}
+
+	tabCtx, err := b.ensureRunning(ctx)
+	if err != nil {
+		return "", err
+	}
+	opCtx, cancel := context.WithTimeout(tabCtx, timeout)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	trigger := func(c context.Context) error {
+		switch {
+		case rawURL != "":
+			return chromedp.Run(c, chromedp.Navigate(rawURL))
+		case selector != "":
+			return chromedp.Run(c, chromedp.WaitVisible(selector), chromedp.Click(selector))
+		default:
+			return b.manager.ClickByRef(c, snapshotID, ref)
+		}
+	}
+	file, err := b.manager.Download(opCtx, filepath.Join(b.browserSessionDir(ctx), "downloads"), trigger)
+	if err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+
+	mimeType := fileMimeType(file.Path)
+	delivery := deliverBrowserFile(ctx, file.Path, mimeType, filepath.Base(file.Path), params["send"] != "false")
+	return fileResult(map[string]interface{}{
+		"path":       file.Path,
+		"url":        file.URL,
+		"mime_type":  mimeType,
+		"size_bytes": file.Size,
+		"delivery":   delivery,
+	}), nil
+}
+
+// upload attaches local files to a file input.
+func (b *BrowserTool) upload(ctx context.Context, params map[string]string) (string, error) {
+	snapshotID, ref, selector := params["snapshot_id"], params["ref"], params["selector"]
+	if selector == "" && (snapshotID == "" || ref == "") {
+		return "", fmt.Errorf("upload requires selector or snapshot_id+ref")
+	}
+
+	var requested []string
+	if raw := strings.TrimSpace(params["paths"]); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &requested); err != nil {
+			return "", fmt.Errorf("paths must be an array of strings: %w", err)
+		}
+	}
+	if p := strings.TrimSpace(params["path"]); p != "" {
+		requested = append(requested, p)
+	}
+	if len(requested) == 0 {
+		return "", fmt.Errorf("upload requires path or paths")
+	}
+	files := make([]string, 0, len(requested))
+	for _, p := range requested {
+		full, err := b.resolveUploadPath(ctx, p)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, full)
+	}
+
+	tabCtx, err := b.ensureRunning(ctx)
+	if err != nil {
+		return "", err
+	}
+	opCtx, cancel := browserOpCtx(tabCtx)
+	defer cancel()
+	if selector != "" {
+		err = b.manager.UploadFiles(opCtx, selector, files)
+	} else {
+		err = b.manager.UploadFilesByRef(opCtx, snapshotID, ref, files)
+	}
+	if err != nil {
+		return "", fmt.Errorf("upload failed: %w", err)
+	}
+	return fmt.Sprintf("Attached %d file(s): %s", len(files), strings.Join(files, ", ")), nil
+}
+
+// printPDF saves the current page as a PDF in the session downloads.
+func (b *BrowserTool) printPDF(ctx context.Context, params map[string]string) (string, error) {
+	tabCtx, err := b.ensureRunning(ctx)
+	if err != nil {
+		return "", err
+	}
+	opCtx, cancel := browserOpCtx(tabCtx)
+	defer cancel()
+
+	var title string
+	if err := chromedp.Run(opCtx, chromedp.Title(&title)); err != nil {
+		logger.Debugf("Browser: page title for PDF: %v", err)
+	}
+	buf, err := b.manager.PrintToPDF(opCtx, params["landscape"] == "true")
+	if err != nil {
+		return "", fmt.Errorf("failed to print PDF: %w", err)
+	}
+
+	dir := filepath.Join(b.browserSessionDir(ctx), "downloads")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create download directory: %w", err)
+	}
+	name := browser.SafeFileName(params["name"], "")
+	if name == "" {
+		name = browser.SafeFileName(title, "page") + "_" + time.Now().Format("20060102_150405")
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".pdf") {
+		name += ".pdf"
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf, 0o600); err != nil {
+		return "", fmt.Errorf("failed to save PDF: %w", err)
+	}
+
+	delivery := deliverBrowserFile(ctx, path, "application/pdf", name, params["send"] != "false")
+	return fileResult(map[string]interface{}{
+		"path":       path,
+		"size_bytes": len(buf),
+		"delivery":   delivery,
+	}), nil
+}
+
+// exportCookies writes the profile's cookies to a JSON file in the session
+// directory. The file holds credentials, so it is never sent to the chat.
+func (b *BrowserTool) exportCookies(ctx context.Context, params map[string]string) (string, error) {
+	tabCtx, err := b.ensureRunning(ctx)
+	if err != nil {
+		return "", err
+	}
+	opCtx, cancel := browserOpCtx(tabCtx)
+	defer cancel()
+
+	domain := strings.TrimSpace(params["domain"])
+	cookies, err := b.manager.ExportCookies(opCtx, domain)
+	if err != nil {
+		return "", fmt.Errorf("failed to read cookies: %w", err)
+	}
+
+	profile := b.profileLabel(ctx)
+	name := "cookies-" + profile
+	if domain != "" {
+		name += "-" + browser.SafeFileName(domain, "domain")
+	}
+	dir := filepath.Join(b.browserSessionDir(ctx), "cookies")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create cookie directory: %w", err)
+	}
+	path := filepath.Join(dir, name+".json")
+	data, err := json.MarshalIndent(cookies, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write cookies: %w", err)
+	}
+	return fileResult(map[string]interface{}{
+		"path":    path,
+		"cookies": len(cookies),
+		"profile": profile,
+	}), nil
+}
+
+// importCookies loads cookies from a JSON file into the profile.
+func (b *BrowserTool) importCookies(ctx context.Context, params map[string]string) (string, error) {
+	path, err := b.resolveUploadPath(ctx, params["path"])
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	cookies, err := browser.ParseCookies(data)
+	if err != nil {
+		return "", err
+	}
+
+	tabCtx, err := b.ensureRunning(ctx)
+	if err != nil {
+		return "", err
+	}
+	opCtx, cancel := browserOpCtx(tabCtx)
+	defer cancel()
+	n, err := b.manager.ImportCookies(opCtx, cookies)
+	if err != nil {
+		return "", fmt.Errorf("failed to import cookies: %w", err)
+	}
+	profile := b.profileLabel(ctx)
+	return fmt.Sprintf("Imported %d of %d cookies into profile %s", n, len(cookies), profile), nil
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBrowserResolveUploadPath(t *testing.T) {
+	scratch, workspace, outside := t.TempDir(), t.TempDir(), t.TempDir()
+	b := &BrowserTool{scratchDir: scratch, workspaceRoot: workspace}
+	ctx := WithSessionKey(context.Background(), "agent:main:telegram:dm:42")
+
+	sessionDir := b.browserSessionDir(ctx)
+	writeFile := func(path string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(filepath.Join(sessionDir, "downloads", "invoice.pdf"))
+	writeFile(filepath.Join(workspace, "docs", "cv.pdf"))
+	writeFile(filepath.Join(outside, "secret.txt"))
+
+	got, err := b.resolveUploadPath(ctx, "downloads/invoice.pdf")
+	if err != nil || got != filepath.Join(sessionDir, "downloads", "invoice.pdf") {
+		t.Fatalf("session file: %q, %v", got, err)
+	}
+	got, err = b.resolveUploadPath(ctx, "docs/cv.pdf")
+	if err != nil || !strings.HasSuffix(got, filepath.Join("docs", "cv.pdf")) {
+		t.Fatalf("workspace file: %q, %v", got, err)
+	}
+
+	for _, p := range []string{filepath.Join(outside, "secret.txt"), "../../secret.txt", "missing.pdf", "docs", ""} {
+		if _, err := b.resolveUploadPath(ctx, p); err == nil {
+			t.Errorf("expected %q to be rejected", p)
+		}
+	}
+}
+
+func TestFileMimeType(t *testing.T) {
+	dir := t.TempDir()
+	pdf := filepath.Join(dir, "a.pdf")
+	noExt := filepath.Join(dir, "report")
+	if err := os.WriteFile(pdf, []byte("%PDF-1.4"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(noExt, []byte("%PDF-1.7\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := fileMimeType(pdf); got != "application/pdf" {
+		t.Errorf("by extension: %s", got)
+	}
+	if got := fileMimeType(noExt); got != "application/pdf" {
+		t.Errorf("by content: %s", got)
+	}
+}
@@ -0,0 +1,218 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ok-gobot/internal/browser"
+)
+
+// BrowserScriptRunner replays recorded scripts; BrowserTool implements it.
+type BrowserScriptRunner interface {
+	ScriptsDir() string
+	RunScript(ctx context.Context, script *browser.Script, params map[string]string) (*browser.RunResult, error)
+}
+
+// BrowserScriptTool lists and replays browser flows recorded with the browser
+// tool's record_start/record_stop commands. A replay needs no model calls;
+// when a step breaks, the report tells the agent where to take over.
+type BrowserScriptTool struct {
+	runner BrowserScriptRunner
+}
+
+// NewBrowserScriptTool creates a browser_script tool backed by runner.
+func NewBrowserScriptTool(runner BrowserScriptRunner) *BrowserScriptTool {
+	return &BrowserScriptTool{runner: runner}
+}
+
+func (t *BrowserScriptTool) Name() string {
+	return "browser_script"
+}
+
+func (t *BrowserScriptTool) Description() string {
+	return "Replay recorded browser flows (logins, checkouts, form submissions) deterministically. Commands: list, show <name>, run <name> [params], delete <name>. " +
+		"Prefer this over step-by-step browsing when a matching script exists; if a step fails, finish the remaining steps interactively."
+}
+
+// GetSchema returns the JSON Schema for browser_script parameters.
+func (t *BrowserScriptTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"command": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"list", "show", "run", "delete"},
+				"description": "Operation to perform",
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Script name (for show/run/delete)",
+			},
+			"params": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+				"description":          "Values for the script's {{param}} placeholders (for run). Secret parameters left out are read from OKGOBOT_SCRIPT_<NAME> environment variables.",
+			},
+		},
+		"required": []string{"command"},
+	}
+}
+
+// Execute runs a command with positional args: <command> [name] [key=value...].
+func (t *BrowserScriptTool) Execute(ctx context.Context, args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: browser_script <list|show|run|delete> [name] [key=value...]")
+	}
+	params := map[string]string{"command": args[0]}
+	if len(args) > 1 {
+		params["name"] = args[1]
+	}
+	if len(args) > 2 {
+		values := make(map[string]string, len(args)-2)
+		for _, kv := range args[2:] {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				return "", fmt.Errorf("invalid parameter %q, expected key=value", kv)
+			}
+			values[k] = v
+		}
+		data, _ := json.Marshal(values)
+		params["params"] = string(data)
+	}
+	return t.ExecuteJSON(ctx, params)
+}
+
+// ExecuteJSON runs a command with structured JSON parameters.
+func (t *BrowserScriptTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	dir := t.runner.ScriptsDir()
+	name := strings.TrimSpace(params["name"])
+
+	switch params["command"] {
+	case "list":
+		return t.list(dir)
+	case "show":
+		script, err := browser.LoadScript(dir, name)
+		if err != nil {
+			return "", err
+		}
+		return formatBrowserScript(script), nil
+	case "run":
+		script, err := browser.LoadScript(dir, name)
+		if err != nil {
+			return "", err
+		}
+		values := map[string]string{}
+		if raw := strings.TrimSpace(params["params"]); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &values); err != nil {
+				return "", fmt.Errorf("params must be an object of strings: %w", err)
+			}
+		}
+		result, err := t.runner.RunScript(ctx, script, script.WithEnvParams(values))
+		if err != nil {
+			return "", err
+		}
+		return t.report(ctx, script, result), nil
+	case "delete":
+		if err := browser.DeleteScript(dir, name); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted browser script %q", name), nil
+	case "":
+		return "", fmt.Errorf("command is required")
+	default:
+		return "", fmt.Errorf("unknown command: %s", params["command"])
+	}
+}
+
+func (t *BrowserScriptTool) list(dir string) (string, error) {
+	scripts, err := browser.ListScripts(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(scripts) == 0 {
+		return "No browser scripts recorded. Use the browser tool's record_start/record_stop commands around a successful flow to create one.", nil
+	}
+	var sb strings.Builder
+	for _, s := range scripts {
+		fmt.Fprintf(&sb, "- %s (%d steps", s.Name, len(s.Steps))
+		if len(s.Params) > 0 {
+			names := make([]string, 0, len(s.Params))
+			for _, p := range s.Params {
+				names = append(names, p.Name)
+			}
+			fmt.Fprintf(&sb, "; params: %s", strings.Join(names, ", "))
+		}
+		sb.WriteString(")")
+		if s.Description != "" {
+			fmt.Fprintf(&sb, ": %s", s.Description)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+func formatBrowserScript(s *browser.Script) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Script %s", s.Name)
+	if s.Description != "" {
+		fmt.Fprintf(&sb, ": %s", s.Description)
+	}
+	sb.WriteString("\n")
+	for _, p := range s.Params {
+		switch {
+		case p.Secret:
+			fmt.Fprintf(&sb, "param %s (secret, env %s)\n", p.Name, browser.ParamEnvVar(p.Name))
+		case p.Default != "":
+			fmt.Fprintf(&sb, "param %s (default %q)\n", p.Name, p.Default)
+		default:
+			fmt.Fprintf(&sb, "param %s\n", p.Name)
+		}
+	}
+	for i, step := range s.Steps {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, step.Describe())
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// report renders a run result. Failures are returned as normal output, not
+// errors, so the agent gets the context it needs to take over.
+func (t *BrowserScriptTool) report(ctx context.Context, s *browser.Script, r *browser.RunResult) string {
+	for _, path := range r.Screenshots {
+		AddAttachment(ctx, Attachment{Path: path, MimeType: "image/png", Caption: filepath.Base(path)})
+	}
+
+	var sb strings.Builder
+	if r.OK() {
+		fmt.Fprintf(&sb, "Script %q completed: %d/%d steps in %s.", s.Name, r.Completed, r.Total, r.Duration.Round(100*time.Millisecond))
+		for _, path := range r.Screenshots {
+			fmt.Fprintf(&sb, "\nScreenshot: %s", path)
+		}
+		return sb.String()
+	}
+
+	failed := s.Steps[r.FailedStep-1]
+	fmt.Fprintf(&sb, "Script %q FAILED at step %d/%d (%s): %v\n", s.Name, r.FailedStep, r.Total, failed.Describe(), r.Err)
+	if r.Screenshot != "" {
+		fmt.Fprintf(&sb, "Screenshot of the page at failure: %s\n", r.Screenshot)
+		AddAttachment(ctx, Attachment{Path: r.Screenshot, MimeType: "image/png", Caption: fmt.Sprintf("%s: step %d failed", s.Name, r.FailedStep)})
+	}
+	sb.WriteString("\nRemaining steps (not run):\n")
+	for i := r.FailedStep - 1; i < len(s.Steps); i++ {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, s.Steps[i].Describe())
+	}
+	sb.WriteString("\nThe browser is left on the page where the script stopped. Finish the remaining steps interactively " +
+		"(browser tool, or browser_task from the main agent), then re-record the script if the site has changed.")
+	return sb.String()
+}
+
+func (t *BrowserScriptTool) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"name":        t.Name(),
+		"description": t.Description(),
+		"schema":      t.GetSchema(),
+	})
+}
@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"ok-gobot/internal/browser"
+)
+
+type fakeScriptRunner struct {
+	dir    string
+	params map[string]string
+	result *browser.RunResult
+}
+
+func (f *fakeScriptRunner) ScriptsDir() string { return f.dir }
+
+func (f *fakeScriptRunner) RunScript(_ context.Context, _ *browser.Script, params map[string]string) (*browser.RunResult, error) {
+	f.params = params
+	return f.result, nil
+}
+
+func saveTestScript(t *testing.T, dir string) {
+	t.Helper()
+	err := browser.SaveScript(dir, &browser.Script{
+		Name:        "login",
+		Description: "Sign in to the dashboard",
+		Params:      []browser.ScriptParam{{Name: "user", Default: "ann"}, {Name: "password", Secret: true}},
+		Steps: []browser.ScriptStep{
+			{Action: browser.StepNavigate, URL: "https://example.com/login"},
+			{Action: browser.StepType, Selector: "#user", Value: "{{user}}"},
+			{Action: browser.StepType, Selector: "#pass", Value: "{{password}}"},
+			{Action: browser.StepClick, Selector: "#submit", Text: "Sign in"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBrowserScriptTool_ListAndShow(t *testing.T) {
+	runner := &fakeScriptRunner{dir: t.TempDir()}
+	tool := NewBrowserScriptTool(runner)
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"command": "list"})
+	if err != nil || !strings.Contains(out, "No browser scripts") {
+		t.Fatalf("empty list = %q, %v", out, err)
+	}
+
+	saveTestScript(t, runner.dir)
+	out, err = tool.ExecuteJSON(context.Background(), map[string]string{"command": "list"})
+	if err != nil || !strings.Contains(out, "login (4 steps; params: user, password): Sign in to the dashboard") {
+		t.Fatalf("list = %q, %v", out, err)
+	}
+
+	out, err = tool.ExecuteJSON(context.Background(), map[string]string{"command": "show", "name": "login"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"param password (secret, env OKGOBOT_SCRIPT_PASSWORD)", `type "{{password}}" into #pass`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("show missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestBrowserScriptTool_RunReportsFailureForTakeover(t *testing.T) {
+	runner := &fakeScriptRunner{dir: t.TempDir(), result: &browser.RunResult{
+		Script: "login", Completed: 3, Total: 4, FailedStep: 4,
+		Err: errors.New("context deadline exceeded"), Screenshot: "/tmp/login_failed.png",
+	}}
+	saveTestScript(t, runner.dir)
+	t.Setenv(browser.ParamEnvVar("password"), "s3cret")
+
+	ctx, collector := WithAttachmentCollector(context.Background())
+	out, err := NewBrowserScriptTool(runner).ExecuteJSON(ctx, map[string]string{
+		"command": "run", "name": "login", "params": `{"user":"bob"}`,
+	})
+	if err != nil {
+		t.Fatalf("step failures must not be tool errors: %v", err)
+	}
+	if runner.params["user"] != "bob" || runner.params["password"] != "s3cret" {
+		t.Errorf("params = %v", runner.params)
+	}
+	for _, want := range []string{"FAILED at step 4/4", "click #submit", "/tmp/login_failed.png", "Remaining steps", "Finish the remaining steps"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+	if got := collector.Attachments(); len(got) != 1 || got[0].Path != "/tmp/login_failed.png" {
+		t.Errorf("attachments = %+v", got)
+	}
+}
+
+func TestBrowserScriptTool_RunSuccessAndDelete(t *testing.T) {
+	runner := &fakeScriptRunner{dir: t.TempDir(), result: &browser.RunResult{Script: "login", Completed: 4, Total: 4}}
+	saveTestScript(t, runner.dir)
+	tool := NewBrowserScriptTool(runner)
+
+	out, err := tool.Execute(context.Background(), "run", "login", "password=x")
+	if err != nil || !strings.Contains(out, `Script "login" completed: 4/4 steps`) {
+		t.Fatalf("run = %q, %v", out, err)
+	}
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"command": "delete", "name": "login"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"command": "run", "name": "login"}); err == nil {
+		t.Error("expected error running a deleted script")
+	}
+}
@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ok-gobot/internal/browser"
+	"ok-gobot/internal/logger"
+)
+
+// SessionReleaser is implemented by tools that hold per-session resources
+// which must be freed when the session's run ends.
+type SessionReleaser interface {
+	ReleaseSession(sessionKey string)
+}
+
+// ReleaseSessionResources frees per-session resources held by tools in r,
+// looking through registry decorators such as the emergency-stop guard.
+func ReleaseSessionResources(r *Registry, sessionKey string) {
+	if r == nil || sessionKey == "" {
+		return
+	}
+	for _, t := range r.List() {
+		for t != nil {
+			if rel, ok := t.(SessionReleaser); ok {
+				rel.ReleaseSession(sessionKey)
+				break
+			}
+			wrapped, ok := t.(interface{ Unwrap() Tool })
+			if !ok {
+				break
+			}
+			t = wrapped.Unwrap()
+		}
+	}
+}
+
+// browserSession is the tab state of one browser user: the shared profile
+// for interactive sessions, or an isolated context for one subagent run.
+type browserSession struct {
+	key      string // isolated context key; "" for the shared profile
+	tabs     map[string]*tabEntry
+	active   string
+	recorder *browser.Recorder // non-nil while a flow is being recorded
+}
+
+func newBrowserSession(key string) *browserSession {
+	return &browserSession{key: key, tabs: make(map[string]*tabEntry)}
+}
+
+// clearTabsLocked cancels all tab contexts and resets state. Must hold b.mu.
+func (s *browserSession) clearTabsLocked() {
+	for _, entry := range s.tabs {
+		entry.cancel()
+	}
+	s.tabs = make(map[string]*tabEntry)
+	s.active = ""
+}
+
+// isSubagentSessionKey reports whether key belongs to a subagent run, either
+// a canonical agent:<id>:subagent:<slug> key or a legacy subagent:<chat>:<ts> key.
+func isSubagentSessionKey(key string) bool {
+	return strings.HasPrefix(key, "subagent:") || strings.Contains(key, ":subagent:")
+}
+
+// session returns the tab state for the caller. Subagent runs get their own
+// isolated context (unless isolation is off); everyone else shares the
+// persistent profile.
+func (b *BrowserTool) session(ctx context.Context) *browserSession {
+	key := SessionKeyFromContext(ctx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.shared == nil {
+		b.shared = newBrowserSession("")
+	}
+	if b.sharedForSubagents || !isSubagentSessionKey(key) {
+		return b.shared
+	}
+	if b.isolated == nil {
+		b.isolated = make(map[string]*browserSession)
+	}
+	s, ok := b.isolated[key]
+	if !ok {
+		s = newBrowserSession(key)
+		b.isolated[key] = s
+	}
+	return s
+}
+
+// profileLabel names the cookie jar the caller uses, for messages and file names.
+func (b *BrowserTool) profileLabel(ctx context.Context) string {
+	if b.session(ctx).key != "" {
+		return "isolated"
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.profile
+}
+
+// ensureIsolatedTab returns the active tab of an isolated session, opening
+// its browser context on first use.
+func (b *BrowserTool) ensureIsolatedTab(callCtx context.Context, s *browserSession) (context.Context, error) {
+	if !b.manager.IsChromeInstalled() && b.manager.RemoteDebugURL == "" {
+		return nil, fmt.Errorf("Chrome not found. Please install Google Chrome.")
+	}
+	root, err := b.manager.OpenContext(callCtx, s.key)
+	if err != nil {
+		return nil, err
+	}
+	rootID := b.targetIDFromCtx(root)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := s.tabs[rootID]; !ok {
+		// New (or recreated) context: forget tabs of the previous one.
+		s.clearTabsLocked()
+		// The root tab belongs to the manager; closing it disposes the context.
+		s.tabs[rootID] = &tabEntry{ctx: root, cancel: func() {}}
+		logger.Debugf("Browser: isolated context opened for %s", s.key)
+	}
+	if entry, ok := s.tabs[s.active]; ok && entry.ctx.Err() == nil {
+		return entry.ctx, nil
+	}
+	delete(s.tabs, s.active)
+	s.active = rootID
+	return root, nil
+}
+
+// ReleaseSession closes the isolated browser context of a finished run.
+func (b *BrowserTool) ReleaseSession(sessionKey string) {
+	b.mu.Lock()
+	s, ok := b.isolated[sessionKey]
+	if ok {
+		delete(b.isolated, sessionKey)
+		s.clearTabsLocked()
+		s.recorder = nil
+	}
+	b.mu.Unlock()
+	if ok && b.manager != nil {
+		b.manager.CloseContext(sessionKey)
+		logger.Debugf("Browser: isolated context closed for %s", sessionKey)
+	}
+}
+
+// Contexts lists the open isolated browser contexts.
+func (b *BrowserTool) Contexts() []browser.ContextInfo {
+	if b.manager == nil {
+		return nil
+	}
+	return b.manager.Contexts()
+}
+
+// MaxContexts returns the cap on concurrent isolated contexts.
+func (b *BrowserTool) MaxContexts() int {
+	if b.manager == nil || b.manager.MaxContexts <= 0 {
+		return browser.DefaultMaxContexts
+	}
+	return b.manager.MaxContexts
+}
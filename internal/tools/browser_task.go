@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	"ok-gobot/internal/browser"
 	"ok-gobot/internal/delegation"
 )
 
@@ -40,7 +42,7 @@ func (t *BrowserTaskTool) Execute(ctx context.Context, args ...string) (string,
 	if len(args) == 0 {
 		return "", fmt.Errorf("task description required")
 	}
-	return t.run(ctx, args[0])
+	return t.run(ctx, args[0], "")
 }
 
 func (t *BrowserTaskTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
@@ -48,14 +50,28 @@ func (t *BrowserTaskTool) ExecuteJSON(ctx context.Context, params map[string]str
 	if task == "" {
 		return "", fmt.Errorf("'task' is required")
 	}
-	return t.run(ctx, task)
+	recordAs := strings.TrimSpace(params["record_as"])
+	if recordAs != "" {
+		if err := browser.ValidateScriptName(recordAs); err != nil {
+			return "", err
+		}
+	}
+	return t.run(ctx, task, recordAs)
 }
 
-func (t *BrowserTaskTool) run(ctx context.Context, task string) (string, error) {
+func (t *BrowserTaskTool) run(ctx context.Context, task, recordAs string) (string, error) {
 	if t.submitter == nil {
 		return "", fmt.Errorf("subagent submitter not configured")
 	}
 
+	recordRules := ""
+	if recordAs != "" {
+		recordRules = fmt.Sprintf(`
+- Record this flow: call browser command=record_start name=%s before the first step, and command=record_stop once the task has succeeded (command=record_cancel if it failed)
+- When typing user-specific input (search terms, emails, passwords, codes) pass param=<short_name> so the script can be replayed with other values
+- After the final step, add a browser command=assert that proves the flow succeeded (a confirmation text or URL)`, recordAs)
+	}
+
 	prompt := fmt.Sprintf(`You are a browser worker. Your ONLY job is to complete this task and return structured data.
 
 TASK: %s
@@ -66,7 +82,7 @@ RULES:
 - If the site has a Cloudflare challenge, say "BLOCKED: Cloudflare challenge" and stop
 - If you can't find the data, say "NOT_FOUND: <reason>" and stop
 - Be concise — extract the specific data requested, nothing more
-- Do NOT send messages to the user — just return your findings as your final response`, task)
+- Do NOT send messages to the user — just return your findings as your final response%s`, task, recordRules)
 
 	job := delegation.Job{
 		MaxToolCalls: 50,
@@ -76,6 +92,7 @@ RULES:
 		MemoryPolicy: delegation.MemoryPolicyReadOnly,
 		ToolAllowlist: []string{
 			"browser",
+			"browser_script",
 		},
 	}.WithDefaults()
 
@@ -95,6 +112,10 @@ func (t *BrowserTaskTool) GetSchema() map[string]interface{} {
 				"type":        "string",
 				"description": "Focused browser task description, e.g. 'Go to ksp.co.il, search for iPhone 16 Pro, find the price'",
 			},
+			"record_as": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional script name: record the successful flow so browser_script can replay it later without the model",
+			},
 		},
 		"required": []string{"task"},
 	}
@@ -21,12 +21,17 @@ import (
 type BrowserTool struct {
 	manager *browser.Manager
 
-	mu      sync.Mutex
-	tabs    map[string]*tabEntry // targetID -> entry
-	active  string               // targetID of the focused tab
-	profile string               // current profile name
+	mu       sync.Mutex
+	shared   *browserSession            // interactive sessions share the persistent profile
+	isolated map[string]*browserSession // subagent session key -> isolated context state
+	profile  string                     // current profile name
+
+	sharedForSubagents bool // subagents use the shared profile instead of isolated contexts
 
 	screenshotDir string
+	scriptsDir    string
+	scratchDir    string // run_code scratch root; downloads go to <session>/downloads
+	workspaceRoot string // uploads may also come from here
 }
 
 type tabEntry struct {
@@ -47,9 +52,10 @@ func NewBrowserTool(profilePath, chromePath, debugURL string) *BrowserTool {
 		logger.Debugf("Browser: no remote debug URL, will launch locally")
 	}
 	return &BrowserTool{
-		manager: mgr,
-		tabs:    make(map[string]*tabEntry),
-		profile: browser.ProfileOpenclaw,
+		manager:  mgr,
+		shared:   newBrowserSession(""),
+		isolated: make(map[string]*browserSession),
+		profile:  browser.ProfileOpenclaw,
 	}
 }
 
@@ -58,13 +64,14 @@ func (b *BrowserTool) Name() string {
 }
 
 func (b *BrowserTool) Description() string {
-	return "Control a real Chrome browser. Commands: open [url], navigate <url>, screenshot, snapshot, click, type, fill, tabs, focus <target_id>, close [target_id], stop."
+	return "Control a real Chrome browser. Commands: open [url], navigate <url>, screenshot, snapshot, click, type, fill, assert, download, upload, pdf, cookies_export, cookies_import, tabs, focus <target_id>, close [target_id], stop. " +
+		"record_start <name> / record_stop / record_cancel capture the navigate, click, type, wait and assert steps in between as a replayable browser_script."
 }
 
 // Execute runs browser commands
 func (b *BrowserTool) Execute(ctx context.Context, args ...string) (string, error) {
 	if len(args) == 0 {
-		return "", fmt.Errorf("usage: browser <open|navigate|snapshot|click|type|fill|screenshot|tabs|focus|close|stop>")
+		return "", fmt.Errorf("usage: browser <open|navigate|snapshot|click|type|fill|screenshot|download|upload|pdf|cookies_export|cookies_import|tabs|focus|close|stop|record_start|record_stop|record_cancel>")
 	}
 
 	command := args[0]
@@ -75,45 +82,80 @@ func (b *BrowserTool) Execute(ctx context.Context, args ...string) (string, erro
 		if len(args) >= 2 {
 			url = args[1]
 		}
-		return b.open(url)
+		return b.open(ctx, url)
 	case "stop":
-		return b.stop()
+		return b.stop(ctx)
 	case "navigate":
 		if len(args) < 2 {
 			return "", fmt.Errorf("URL required")
 		}
-		return b.navigate(args[1])
+		return b.navigate(ctx, args[1])
 	case "snapshot":
-		return b.snapshot()
+		return b.snapshot(ctx)
 	case "click":
-		return b.clickDispatch(args[1:])
+		return b.clickDispatch(ctx, args[1:])
 	case "type", "fill":
-		return b.typeDispatch(args[1:])
+		return b.typeDispatch(ctx, args[1:])
 	case "screenshot":
-		return b.screenshotCmd()
+		return b.screenshotCmd(ctx, nil)
+	case "download":
+		if len(args) < 2 {
+			return "", fmt.Errorf("usage: browser download <url|selector>")
+		}
+		if strings.HasPrefix(args[1], "http://") || strings.HasPrefix(args[1], "https://") {
+			return b.download(ctx, map[string]string{"url": args[1]})
+		}
+		return b.download(ctx, map[string]string{"selector": args[1]})
+	case "upload":
+		if len(args) < 3 {
+			return "", fmt.Errorf("usage: browser upload <selector> <path>")
+		}
+		return b.upload(ctx, map[string]string{"selector": args[1], "path": args[2]})
+	case "pdf":
+		return b.printPDF(ctx, map[string]string{})
+	case "cookies_export":
+		domain := ""
+		if len(args) >= 2 {
+			domain = args[1]
+		}
+		return b.exportCookies(ctx, map[string]string{"domain": domain})
+	case "cookies_import":
+		if len(args) < 2 {
+			return "", fmt.Errorf("usage: browser cookies_import <path>")
+		}
+		return b.importCookies(ctx, map[string]string{"path": args[1]})
 	case "wait":
 		if len(args) < 2 {
 			return "", fmt.Errorf("selector required")
 		}
-		return b.wait(args[1])
+		return b.wait(ctx, args[1])
 	case "text":
 		if len(args) < 2 {
 			return "", fmt.Errorf("selector required")
 		}
-		return b.getText(args[1])
+		return b.getText(ctx, args[1])
 	case "tabs":
-		return b.listTabs()
+		return b.listTabs(ctx)
 	case "focus":
 		if len(args) < 2 {
 			return "", fmt.Errorf("target_id required")
 		}
-		return b.focusTab(args[1])
+		return b.focusTab(ctx, args[1])
 	case "close":
 		targetID := ""
 		if len(args) >= 2 {
 			targetID = args[1]
 		}
-		return b.closeTab(targetID)
+		return b.closeTab(ctx, targetID)
+	case "record_start":
+		if len(args) < 2 {
+			return "", fmt.Errorf("script name required")
+		}
+		return b.recordStart(ctx, args[1], strings.Join(args[2:], " "))
+	case "record_stop":
+		return b.recordStop(ctx)
+	case "record_cancel":
+		return b.recordCancel(ctx)
 	default:
 		return "", fmt.Errorf("unknown command: %s", command)
 	}
@@ -128,26 +170,26 @@ func (b *BrowserTool) ExecuteJSON(ctx context.Context, params map[string]string)
 
 	switch command {
 	case "open", "start":
-		return b.open(params["url"])
+		return b.open(ctx, params["url"])
 	case "stop":
-		return b.stop()
+		return b.stop(ctx)
 	case "navigate":
 		url := params["url"]
 		if url == "" {
 			return "", fmt.Errorf("url is required for navigate")
 		}
-		return b.navigate(url)
+		return b.navigate(ctx, url)
 	case "snapshot":
-		return b.snapshot()
+		return b.snapshot(ctx)
 	case "click":
 		snapshotID := params["snapshot_id"]
 		ref := params["ref"]
 		selector := params["selector"]
 		if snapshotID != "" && ref != "" {
-			return b.clickByRef(snapshotID, ref)
+			return b.clickByRef(ctx, snapshotID, ref)
 		}
 		if selector != "" {
-			return b.clickCSS(selector)
+			return b.clickCSS(ctx, selector)
 		}
 		return "", fmt.Errorf("click requires snapshot_id+ref or selector")
 	case "type", "fill":
@@ -159,65 +201,88 @@ func (b *BrowserTool) ExecuteJSON(ctx context.Context, params map[string]string)
 		ref := params["ref"]
 		selector := params["selector"]
 		if snapshotID != "" && ref != "" {
-			return b.typeByRef(snapshotID, ref, value)
+			return b.typeByRef(ctx, snapshotID, ref, value, params["param"])
 		}
 		if selector != "" {
-			return b.fillCSS(selector, value)
+			return b.fillCSS(ctx, selector, value, params["param"])
 		}
 		return "", fmt.Errorf("%s requires snapshot_id+ref or selector", command)
 	case "screenshot":
-		return b.screenshotCmd()
+		return b.screenshotCmd(ctx, params)
+	case "download":
+		return b.download(ctx, params)
+	case "upload":
+		return b.upload(ctx, params)
+	case "pdf":
+		return b.printPDF(ctx, params)
+	case "cookies_export":
+		return b.exportCookies(ctx, params)
+	case "cookies_import":
+		return b.importCookies(ctx, params)
 	case "wait":
 		selector := params["selector"]
 		if selector == "" {
 			return "", fmt.Errorf("selector is required for wait")
 		}
-		return b.wait(selector)
+		return b.wait(ctx, selector)
 	case "text":
 		selector := params["selector"]
 		if selector == "" {
 			return "", fmt.Errorf("selector is required for text")
 		}
-		return b.getText(selector)
+		return b.getText(ctx, selector)
+	case "assert":
+		return b.assert(ctx, params["selector"], params["value"], params["url"])
 	case "tabs":
-		return b.listTabs()
+		return b.listTabs(ctx)
 	case "focus":
 		targetID := params["target_id"]
 		if targetID == "" {
 			return "", fmt.Errorf("target_id is required for focus")
 		}
-		return b.focusTab(targetID)
+		return b.focusTab(ctx, targetID)
 	case "close":
-		return b.closeTab(params["target_id"])
+		return b.closeTab(ctx, params["target_id"])
+	case "record_start":
+		return b.recordStart(ctx, params["name"], params["description"])
+	case "record_stop":
+		return b.recordStop(ctx)
+	case "record_cancel":
+		return b.recordCancel(ctx)
 	default:
 		return "", fmt.Errorf("unknown command: %s", command)
 	}
 }
 
-func (b *BrowserTool) clickDispatch(args []string) (string, error) {
+func (b *BrowserTool) clickDispatch(callCtx context.Context, args []string) (string, error) {
 	switch len(args) {
 	case 1:
-		return b.clickCSS(args[0])
+		return b.clickCSS(callCtx, args[0])
 	case 2:
-		return b.clickByRef(args[0], args[1])
+		return b.clickByRef(callCtx, args[0], args[1])
 	default:
 		return "", fmt.Errorf("usage: browser click <selector> OR browser click <snapshot_id> <ref>")
 	}
 }
 
-func (b *BrowserTool) typeDispatch(args []string) (string, error) {
+func (b *BrowserTool) typeDispatch(callCtx context.Context, args []string) (string, error) {
 	switch len(args) {
 	case 2:
-		return b.fillCSS(args[0], args[1])
+		return b.fillCSS(callCtx, args[0], args[1], "")
 	case 3:
-		return b.typeByRef(args[0], args[1], args[2])
+		return b.typeByRef(callCtx, args[0], args[1], args[2], "")
 	default:
 		return "", fmt.Errorf("usage: browser type <selector> <value> OR browser type <snapshot_id> <ref> <value>")
 	}
 }
 
-// ensureRunning auto-starts browser and returns the active tab context.
-func (b *BrowserTool) ensureRunning() (context.Context, error) {
+// ensureRunning auto-starts browser and returns the caller's active tab context.
+func (b *BrowserTool) ensureRunning(callCtx context.Context) (context.Context, error) {
+	s := b.session(callCtx)
+	if s.key != "" {
+		return b.ensureIsolatedTab(callCtx, s)
+	}
+
 	if !b.manager.IsRunning() {
 		if !b.manager.IsChromeInstalled() {
 			return nil, fmt.Errorf("Chrome not found. Please install Google Chrome.")
@@ -229,7 +294,7 @@ func (b *BrowserTool) ensureRunning() (context.Context, error) {
 		logger.Debugf("Browser: Chrome started successfully")
 		// Browser restarted — drop stale tabs.
 		b.mu.Lock()
-		b.clearTabsLocked()
+		b.clearAllTabsLocked()
 		b.mu.Unlock()
 	} else {
 		logger.Debugf("Browser: already running")
@@ -238,18 +303,18 @@ func (b *BrowserTool) ensureRunning() (context.Context, error) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if b.active != "" {
-		if entry, ok := b.tabs[b.active]; ok {
+	if s.active != "" {
+		if entry, ok := s.tabs[s.active]; ok {
 			if entry.ctx.Err() != nil {
 				logger.Debugf("Browser: active tab context dead: %v", entry.ctx.Err())
-				delete(b.tabs, b.active)
-				b.active = ""
+				delete(s.tabs, s.active)
+				s.active = ""
 			} else {
-				logger.Debugf("Browser: reusing active tab %s", b.active)
+				logger.Debugf("Browser: reusing active tab %s", s.active)
 				return entry.ctx, nil
 			}
 		} else {
-			b.active = ""
+			s.active = ""
 		}
 	}
 
@@ -261,13 +326,23 @@ func (b *BrowserTool) ensureRunning() (context.Context, error) {
 	}
 
 	targetID := b.targetIDFromCtx(ctx)
-	b.tabs[targetID] = &tabEntry{ctx: ctx, cancel: cancel}
-	b.active = targetID
+	s.tabs[targetID] = &tabEntry{ctx: ctx, cancel: cancel}
+	s.active = targetID
 	logger.Debugf("Browser: new tab created: %s", targetID)
 	return ctx, nil
 }
 
-func (b *BrowserTool) open(url string) (string, error) {
+func (b *BrowserTool) open(callCtx context.Context, url string) (string, error) {
+	if b.session(callCtx).key != "" {
+		// Isolated contexts open lazily; never restart the shared browser.
+		if url != "" {
+			return b.navigate(callCtx, url)
+		}
+		if _, err := b.ensureRunning(callCtx); err != nil {
+			return "", err
+		}
+		return "Browser opened (isolated context)", nil
+	}
 	if !b.manager.IsChromeInstalled() {
 		return "", fmt.Errorf("Chrome not found. Please install Google Chrome.")
 	}
@@ -278,18 +353,24 @@ func (b *BrowserTool) open(url string) (string, error) {
 
 	// Reset stale tab state after (re-)start.
 	b.mu.Lock()
-	b.clearTabsLocked()
+	b.shared.clearTabsLocked()
 	b.mu.Unlock()
 
 	if url != "" {
-		return b.navigate(url)
+		return b.navigate(callCtx, url)
 	}
 	return "Browser opened", nil
 }
 
-func (b *BrowserTool) stop() (string, error) {
+func (b *BrowserTool) stop(callCtx context.Context) (string, error) {
+	if key := b.session(callCtx).key; key != "" {
+		b.ReleaseSession(key)
+		return "Isolated browser context closed", nil
+	}
+
 	b.mu.Lock()
-	b.clearTabsLocked()
+	b.clearAllTabsLocked()
+	b.isolated = make(map[string]*browserSession)
 	b.mu.Unlock()
 
 	b.manager.Stop()
@@ -332,12 +413,12 @@ func validateBrowserURL(rawURL string) error {
 	return nil
 }
 
-func (b *BrowserTool) navigate(navURL string) (string, error) {
+func (b *BrowserTool) navigate(callCtx context.Context, navURL string) (string, error) {
 	if err := validateBrowserURL(navURL); err != nil {
 		return "", err
 	}
 
-	tabCtx, err := b.ensureRunning()
+	tabCtx, err := b.ensureRunning(callCtx)
 	if err != nil {
 		return "", err
 	}
@@ -354,11 +435,15 @@ func (b *BrowserTool) navigate(navURL string) (string, error) {
 		logger.Debugf("Browser: WaitReady after navigate: %v", err)
 	}
 
-	return fmt.Sprintf("Navigated to %s", navURL), nil
+	msg := fmt.Sprintf("Navigated to %s", navURL)
+	if rec := b.activeRecorder(callCtx); rec != nil {
+		msg += recordStep(rec, browser.ScriptStep{Action: browser.StepNavigate, URL: navURL})
+	}
+	return msg, nil
 }
 
-func (b *BrowserTool) snapshot() (string, error) {
-	tabCtx, err := b.ensureRunning()
+func (b *BrowserTool) snapshot(callCtx context.Context) (string, error) {
+	tabCtx, err := b.ensureRunning(callCtx)
 	if err != nil {
 		return "", err
 	}
@@ -382,21 +467,37 @@ func (b *BrowserTool) snapshot() (string, error) {
 	return string(payload), nil
 }
 
-func (b *BrowserTool) clickByRef(snapshotID, ref string) (string, error) {
-	tabCtx, err := b.ensureRunning()
+func (b *BrowserTool) clickByRef(callCtx context.Context, snapshotID, ref string) (string, error) {
+	tabCtx, err := b.ensureRunning(callCtx)
 	if err != nil {
 		return "", err
 	}
 	ctx, cancel := browserOpCtx(tabCtx)
 	defer cancel()
+
+	// Describe the element before clicking: the click may navigate away
+	// and invalidate the snapshot.
+	rec := b.activeRecorder(callCtx)
+	var info browser.ElementInfo
+	var descErr error
+	if rec != nil {
+		info, descErr = b.manager.DescribeRef(ctx, snapshotID, ref)
+	}
 	if err := b.manager.ClickByRef(ctx, snapshotID, ref); err != nil {
 		return "", fmt.Errorf("click failed: %w", err)
 	}
-	return fmt.Sprintf("Clicked ref %s (snapshot %s)", ref, snapshotID), nil
+	msg := fmt.Sprintf("Clicked ref %s (snapshot %s)", ref, snapshotID)
+	if rec != nil {
+		if descErr != nil {
+			return msg + fmt.Sprintf(" [not recorded: %v]", descErr), nil
+		}
+		msg += recordStep(rec, browser.ScriptStep{Action: browser.StepClick, Selector: info.Selector, Text: info.Text})
+	}
+	return msg, nil
 }
 
-func (b *BrowserTool) clickCSS(selector string) (string, error) {
-	tabCtx, err := b.ensureRunning()
+func (b *BrowserTool) clickCSS(callCtx context.Context, selector string) (string, error) {
+	tabCtx, err := b.ensureRunning(callCtx)
 	if err != nil {
 		return "", err
 	}
@@ -408,24 +509,39 @@ func (b *BrowserTool) clickCSS(selector string) (string, error) {
 	); err != nil {
 		return "", fmt.Errorf("failed to click: %w", err)
 	}
-	return fmt.Sprintf("Clicked %s", selector), nil
+	msg := fmt.Sprintf("Clicked %s", selector)
+	if rec := b.activeRecorder(callCtx); rec != nil {
+		msg += recordStep(rec, browser.ScriptStep{Action: browser.StepClick, Selector: selector})
+	}
+	return msg, nil
 }
 
-func (b *BrowserTool) typeByRef(snapshotID, ref, value string) (string, error) {
-	tabCtx, err := b.ensureRunning()
+func (b *BrowserTool) typeByRef(callCtx context.Context, snapshotID, ref, value, param string) (string, error) {
+	tabCtx, err := b.ensureRunning(callCtx)
 	if err != nil {
 		return "", err
 	}
 	ctx, cancel := browserOpCtx(tabCtx)
 	defer cancel()
+
+	rec := b.activeRecorder(callCtx)
+	var info browser.ElementInfo
+	var descErr error
+	if rec != nil {
+		info, descErr = b.manager.DescribeRef(ctx, snapshotID, ref)
+	}
 	if err := b.manager.TypeByRef(ctx, snapshotID, ref, value); err != nil {
 		return "", fmt.Errorf("type failed: %w", err)
 	}
-	return fmt.Sprintf("Typed into ref %s (snapshot %s)", ref, snapshotID), nil
+	msg := fmt.Sprintf("Typed into ref %s (snapshot %s)", ref, snapshotID)
+	if rec != nil {
+		msg += recordType(rec, info, descErr, value, param)
+	}
+	return msg, nil
 }
 
-func (b *BrowserTool) fillCSS(selector, value string) (string, error) {
-	tabCtx, err := b.ensureRunning()
+func (b *BrowserTool) fillCSS(callCtx context.Context, selector, value, param string) (string, error) {
+	tabCtx, err := b.ensureRunning(callCtx)
 	if err != nil {
 		return "", err
 	}
@@ -437,11 +553,21 @@ func (b *BrowserTool) fillCSS(selector, value string) (string, error) {
 	); err != nil {
 		return "", fmt.Errorf("failed to fill: %w", err)
 	}
-	return fmt.Sprintf("Filled %s", selector), nil
+	msg := fmt.Sprintf("Filled %s", selector)
+	if rec := b.activeRecorder(callCtx); rec != nil {
+		// Keep the caller's selector; the description is only used to
+		// detect password-like fields.
+		info, descErr := b.manager.DescribeSelector(ctx, selector)
+		info.Selector = selector
+		msg += recordType(rec, info, descErr, value, param)
+	}
+	return msg, nil
 }
 
-func (b *BrowserTool) screenshotCmd() (string, error) {
-	tabCtx, err := b.ensureRunning()
+// screenshotCmd captures the viewport, the full page (full_page=true) or one
+// element (selector). send=true also delivers the image to the chat.
+func (b *BrowserTool) screenshotCmd(callCtx context.Context, params map[string]string) (string, error) {
+	tabCtx, err := b.ensureRunning(callCtx)
 	if err != nil {
 		return "", err
 	}
@@ -450,7 +576,14 @@ func (b *BrowserTool) screenshotCmd() (string, error) {
 	defer cancel()
 
 	var buf []byte
-	if err := chromedp.Run(ctx, chromedp.CaptureScreenshot(&buf)); err != nil {
+	var action chromedp.Action = chromedp.CaptureScreenshot(&buf)
+	switch {
+	case params["selector"] != "":
+		action = b.manager.ElementScreenshot(params["selector"], &buf)
+	case params["full_page"] == "true":
+		action = b.manager.FullScreenshot(&buf)
+	}
+	if err := chromedp.Run(ctx, action); err != nil {
 		return "", fmt.Errorf("failed to take screenshot: %w", err)
 	}
 
@@ -469,15 +602,18 @@ func (b *BrowserTool) screenshotCmd() (string, error) {
 		return "", fmt.Errorf("failed to save screenshot: %w", err)
 	}
 
-	payload, _ := json.Marshal(map[string]interface{}{
+	fields := map[string]interface{}{
 		"path":       path,
 		"size_bytes": len(buf),
-	})
-	return string(payload), nil
+	}
+	if params["send"] == "true" {
+		fields["delivery"] = deliverBrowserFile(callCtx, path, "image/png", filename, true)
+	}
+	return fileResult(fields), nil
 }
 
-func (b *BrowserTool) wait(selector string) (string, error) {
-	tabCtx, err := b.ensureRunning()
+func (b *BrowserTool) wait(callCtx context.Context, selector string) (string, error) {
+	tabCtx, err := b.ensureRunning(callCtx)
 	if err != nil {
 		return "", err
 	}
@@ -489,11 +625,15 @@ func (b *BrowserTool) wait(selector string) (string, error) {
 		return "", fmt.Errorf("timeout waiting for element: %w", err)
 	}
 
-	return fmt.Sprintf("Element %s is visible", selector), nil
+	msg := fmt.Sprintf("Element %s is visible", selector)
+	if rec := b.activeRecorder(callCtx); rec != nil {
+		msg += recordStep(rec, browser.ScriptStep{Action: browser.StepWait, Selector: selector})
+	}
+	return msg, nil
 }
 
-func (b *BrowserTool) getText(selector string) (string, error) {
-	tabCtx, err := b.ensureRunning()
+func (b *BrowserTool) getText(callCtx context.Context, selector string) (string, error) {
+	tabCtx, err := b.ensureRunning(callCtx)
 	if err != nil {
 		return "", err
 	}
@@ -509,20 +649,197 @@ func (b *BrowserTool) getText(selector string) (string, error) {
 	return text, nil
 }
 
+func (b *BrowserTool) assert(callCtx context.Context, selector, want, wantURL string) (string, error) {
+	if wantURL == "" && (selector == "" || want == "") {
+		return "", fmt.Errorf("assert requires url, or selector and value")
+	}
+	tabCtx, err := b.ensureRunning(callCtx)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := browserOpCtx(tabCtx)
+	defer cancel()
+
+	var step browser.ScriptStep
+	if wantURL != "" {
+		var current string
+		if err := chromedp.Run(ctx, chromedp.Location(&current)); err != nil {
+			return "", fmt.Errorf("failed to read URL: %w", err)
+		}
+		if !strings.Contains(current, wantURL) {
+			return "", fmt.Errorf("assertion failed: URL %q does not contain %q", current, wantURL)
+		}
+		step = browser.ScriptStep{Action: browser.StepAssertURL, URL: wantURL}
+	} else {
+		var text string
+		if err := chromedp.Run(ctx, chromedp.Text(selector, &text)); err != nil {
+			return "", fmt.Errorf("failed to get text: %w", err)
+		}
+		if !strings.Contains(text, want) {
+			return "", fmt.Errorf("assertion failed: %s does not contain %q", selector, want)
+		}
+		step = browser.ScriptStep{Action: browser.StepAssertText, Selector: selector, Value: want}
+	}
+
+	msg := "Assertion passed"
+	if rec := b.activeRecorder(callCtx); rec != nil {
+		msg += recordStep(rec, step)
+	}
+	return msg, nil
+}
+
+// --- Flow recording ---
+
+// ScriptsDir returns the directory recorded browser scripts are saved in.
+func (b *BrowserTool) ScriptsDir() string {
+	if b.scriptsDir != "" {
+		return b.scriptsDir
+	}
+	return browser.DefaultScriptsDir()
+}
+
+func (b *BrowserTool) activeRecorder(callCtx context.Context) *browser.Recorder {
+	s := b.session(callCtx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return s.recorder
+}
+
+func (b *BrowserTool) recordStart(callCtx context.Context, name, description string) (string, error) {
+	rec, err := browser.NewRecorder(name, description)
+	if err != nil {
+		return "", err
+	}
+	s := b.session(callCtx)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s.recorder != nil {
+		return "", fmt.Errorf("already recording %q; use record_stop or record_cancel first", s.recorder.Name())
+	}
+	s.recorder = rec
+	return fmt.Sprintf("Recording browser script %q. Successful navigate, click, type, wait and assert steps are captured until record_stop.", name), nil
+}
+
+func (b *BrowserTool) recordStop(callCtx context.Context) (string, error) {
+	s := b.session(callCtx)
+	b.mu.Lock()
+	rec := s.recorder
+	s.recorder = nil
+	b.mu.Unlock()
+	if rec == nil {
+		return "", fmt.Errorf("not recording")
+	}
+
+	script := rec.Script()
+	if err := browser.SaveScript(b.ScriptsDir(), script); err != nil {
+		return "", fmt.Errorf("failed to save script: %w", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Saved browser script %q with %d steps.", script.Name, len(script.Steps))
+	if len(script.Params) > 0 {
+		names := make([]string, 0, len(script.Params))
+		for _, p := range script.Params {
+			if p.Secret {
+				names = append(names, p.Name+" (secret)")
+			} else {
+				names = append(names, p.Name)
+			}
+		}
+		fmt.Fprintf(&sb, " Parameters: %s.", strings.Join(names, ", "))
+	}
+	fmt.Fprintf(&sb, " Replay with browser_script command=run name=%s.", script.Name)
+	return sb.String(), nil
+}
+
+func (b *BrowserTool) recordCancel(callCtx context.Context) (string, error) {
+	s := b.session(callCtx)
+	b.mu.Lock()
+	rec := s.recorder
+	s.recorder = nil
+	b.mu.Unlock()
+	if rec == nil {
+		return "", fmt.Errorf("not recording")
+	}
+	return fmt.Sprintf("Discarded recording %q (%d steps)", rec.Name(), rec.Len()), nil
+}
+
+func recordStep(rec *browser.Recorder, step browser.ScriptStep) string {
+	rec.Add(step)
+	return fmt.Sprintf(" [recorded step %d]", rec.Len())
+}
+
+// recordType records a typed value. Values for password-like fields, or
+// fields the agent named with param, are stored as {{param}} placeholders.
+// When the field could not be described the step is skipped rather than
+// risk writing a secret to disk.
+func recordType(rec *browser.Recorder, info browser.ElementInfo, descErr error, value, param string) string {
+	if descErr != nil {
+		return fmt.Sprintf(" [not recorded: %v]", descErr)
+	}
+	recorded := value
+	switch {
+	case param != "":
+		recorded = rec.Param(param, value, info.Secret)
+	case info.Secret:
+		recorded = rec.Param(orDefault(info.Field, "secret"), value, true)
+	}
+	return recordStep(rec, browser.ScriptStep{Action: browser.StepType, Selector: info.Selector, Value: recorded})
+}
+
+// RunScript replays a saved script in the active tab. Cancelling ctx aborts
+// the replay without closing the tab.
+func (b *BrowserTool) RunScript(ctx context.Context, script *browser.Script, params map[string]string) (*browser.RunResult, error) {
+	tabCtx, err := b.ensureRunning(ctx)
+	if err != nil {
+		return nil, err
+	}
+	runCtx, cancel := context.WithCancel(tabCtx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	return browser.RunScript(runCtx, browser.NewChromeDriver(), script, params, browser.RunOptions{ScreenshotDir: b.screenshotDir})
+}
+
 // --- Tab management ---
 
-func (b *BrowserTool) listTabs() (string, error) {
+// sessionTabs lists the tabs the caller may see: those of its isolated
+// context, or the shared profile's tabs.
+func (b *BrowserTool) sessionTabs(s *browserSession) ([]browser.TabInfo, error) {
+	if s.key != "" {
+		return b.manager.ContextTabs(s.key)
+	}
 	if !b.manager.IsRunning() {
-		return "", fmt.Errorf("browser is not running; use 'open' first")
+		return nil, fmt.Errorf("browser is not running; use 'open' first")
+	}
+	return b.manager.ListTabs(b.profile)
+}
+
+// ownsTab reports whether targetID is one of the caller's tabs, so sessions
+// cannot focus or close each other's tabs.
+func (b *BrowserTool) ownsTab(s *browserSession, targetID string) error {
+	tabs, err := b.sessionTabs(s)
+	if err != nil {
+		return err
+	}
+	for _, t := range tabs {
+		if t.TargetID == targetID {
+			return nil
+		}
 	}
+	return fmt.Errorf("tab %s not found", targetID)
+}
 
-	tabs, err := b.manager.ListTabs(b.profile)
+func (b *BrowserTool) listTabs(callCtx context.Context) (string, error) {
+	s := b.session(callCtx)
+	tabs, err := b.sessionTabs(s)
 	if err != nil {
 		return "", err
 	}
 
 	b.mu.Lock()
-	activeID := b.active
+	activeID := s.active
 	b.mu.Unlock()
 
 	type tabOut struct {
@@ -545,9 +862,10 @@ func (b *BrowserTool) listTabs() (string, error) {
 	return string(payload), nil
 }
 
-func (b *BrowserTool) focusTab(targetID string) (string, error) {
-	if !b.manager.IsRunning() {
-		return "", fmt.Errorf("browser is not running; use 'open' first")
+func (b *BrowserTool) focusTab(callCtx context.Context, targetID string) (string, error) {
+	s := b.session(callCtx)
+	if err := b.ownsTab(s, targetID); err != nil {
+		return "", err
 	}
 
 	if err := b.manager.FocusTab(b.profile, targetID); err != nil {
@@ -556,59 +874,63 @@ func (b *BrowserTool) focusTab(targetID string) (string, error) {
 
 	b.mu.Lock()
 	// If we don't already have a context for this tab, create one.
-	if _, ok := b.tabs[targetID]; !ok {
+	if _, ok := s.tabs[targetID]; !ok {
 		ctx, cancel, err := b.manager.ContextForTarget(b.profile, targetID)
 		if err != nil {
 			b.mu.Unlock()
 			return "", fmt.Errorf("failed to attach to tab: %w", err)
 		}
-		b.tabs[targetID] = &tabEntry{ctx: ctx, cancel: cancel}
+		s.tabs[targetID] = &tabEntry{ctx: ctx, cancel: cancel}
 	}
-	b.active = targetID
+	s.active = targetID
 	b.mu.Unlock()
 
 	return fmt.Sprintf("Focused tab %s", targetID), nil
 }
 
-func (b *BrowserTool) closeTab(targetID string) (string, error) {
-	if !b.manager.IsRunning() {
-		return "", fmt.Errorf("browser is not running; use 'open' first")
-	}
+func (b *BrowserTool) closeTab(callCtx context.Context, targetID string) (string, error) {
+	s := b.session(callCtx)
 
 	b.mu.Lock()
 	if targetID == "" {
-		targetID = b.active
+		targetID = s.active
 	}
 	b.mu.Unlock()
 
 	if targetID == "" {
 		return "", fmt.Errorf("no active tab to close; specify a target_id")
 	}
+	if err := b.ownsTab(s, targetID); err != nil {
+		return "", err
+	}
+	if s.key != "" && targetID == b.manager.ContextRootTab(s.key) {
+		return "", fmt.Errorf("tab %s is the first tab of this run's isolated context and closes when the run ends", targetID)
+	}
 
 	if err := b.manager.CloseTab(b.profile, targetID); err != nil {
 		return "", fmt.Errorf("failed to close tab: %w", err)
 	}
 
 	b.mu.Lock()
-	if entry, ok := b.tabs[targetID]; ok {
+	if entry, ok := s.tabs[targetID]; ok {
 		entry.cancel()
-		delete(b.tabs, targetID)
+		delete(s.tabs, targetID)
 	}
-	if b.active == targetID {
-		b.active = ""
+	if s.active == targetID {
+		s.active = ""
 	}
 	b.mu.Unlock()
 
 	return fmt.Sprintf("Closed tab %s", targetID), nil
 }
 
-// clearTabsLocked cancels all tab contexts and resets state. Must hold b.mu.
-func (b *BrowserTool) clearTabsLocked() {
-	for _, entry := range b.tabs {
-		entry.cancel()
+// clearAllTabsLocked drops the tab state of every session after the browser
+// (re)started. Must hold b.mu.
+func (b *BrowserTool) clearAllTabsLocked() {
+	b.shared.clearTabsLocked()
+	for _, s := range b.isolated {
+		s.clearTabsLocked()
 	}
-	b.tabs = make(map[string]*tabEntry)
-	b.active = ""
 }
 
 func (b *BrowserTool) targetIDFromCtx(ctx context.Context) string {
@@ -627,11 +949,11 @@ func (b *BrowserTool) GetSchema() map[string]interface{} {
 			"command": map[string]interface{}{
 				"type":        "string",
 				"description": "Browser command to execute",
-				"enum":        []string{"open", "navigate", "snapshot", "click", "type", "fill", "screenshot", "text", "wait", "tabs", "focus", "close", "stop"},
+				"enum":        []string{"open", "navigate", "snapshot", "click", "type", "fill", "screenshot", "text", "wait", "assert", "download", "upload", "pdf", "cookies_export", "cookies_import", "tabs", "focus", "close", "stop", "record_start", "record_stop", "record_cancel"},
 			},
 			"url": map[string]interface{}{
 				"type":        "string",
-				"description": "URL to navigate to (for 'open' or 'navigate')",
+				"description": "URL to navigate to (for 'open'/'navigate') or to download (for 'download'); for 'assert', a substring the current URL must contain",
 			},
 			"snapshot_id": map[string]interface{}{
 				"type":        "string",
@@ -643,16 +965,57 @@ func (b *BrowserTool) GetSchema() map[string]interface{} {
 			},
 			"selector": map[string]interface{}{
 				"type":        "string",
-				"description": "CSS selector (for click/type/text/wait)",
+				"description": "CSS selector (for click/type/text/wait/assert; the element to click for download, the file input for upload, the element to capture for screenshot)",
 			},
 			"value": map[string]interface{}{
 				"type":        "string",
-				"description": "Value to type (for type/fill)",
+				"description": "Value to type (for type/fill); for 'assert', text the selector must contain",
+			},
+			"param": map[string]interface{}{
+				"type":        "string",
+				"description": "While recording: store the typed value as script parameter {{param}} so replays can supply a different value. Password fields become secret parameters automatically.",
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Script name for record_start (lowercase letters, digits, '-' and '_')",
+			},
+			"description": map[string]interface{}{
+				"type":        "string",
+				"description": "What the recorded flow does (for record_start)",
 			},
 			"target_id": map[string]interface{}{
 				"type":        "string",
 				"description": "Tab target ID (for focus/close)",
 			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Local file for upload or cookies_import; relative paths resolve in the session scratch directory (uploads/, downloads/) or the workspace",
+			},
+			"paths": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Several files for a multi-file upload",
+			},
+			"full_page": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Capture the whole scrollable page (for screenshot)",
+			},
+			"landscape": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Landscape orientation (for pdf)",
+			},
+			"send": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Deliver the file to the chat. Defaults to true for download/pdf and false for screenshot",
+			},
+			"domain": map[string]interface{}{
+				"type":        "string",
+				"description": "Only export cookies for this domain and its subdomains (for cookies_export)",
+			},
+			"timeout_seconds": map[string]interface{}{
+				"type":        "integer",
+				"description": "How long to wait for a download to finish (default 120, max 600)",
+			},
 		},
 		"required": []string{"command"},
 	}
@@ -192,3 +192,77 @@ func TestBrowserToolSchemaCommandEnumIsValid(t *testing.T) {
 		t.Fatal("schema missing properties after round-trip")
 	}
 }
+
+func TestIsSubagentSessionKey(t *testing.T) {
+	cases := map[string]bool{
+		"agent:default:subagent:research-1": true,
+		"subagent:12345:1700000000":         true,
+		"agent:default:telegram:dm:42":      false,
+		"":                                  false,
+	}
+	for key, want := range cases {
+		if got := isSubagentSessionKey(key); got != want {
+			t.Errorf("isSubagentSessionKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestBrowserToolSessionsIsolateSubagents(t *testing.T) {
+	tool := NewBrowserTool("", "", "")
+
+	main := WithSessionKey(context.Background(), "agent:default:telegram:dm:42")
+	subA := WithSessionKey(context.Background(), "agent:default:subagent:a")
+	subB := WithSessionKey(context.Background(), "agent:default:subagent:b")
+
+	if tool.session(main) != tool.session(context.Background()) {
+		t.Fatal("expected interactive sessions to share the profile")
+	}
+	if tool.session(subA) == tool.session(main) {
+		t.Fatal("expected a subagent to get its own session")
+	}
+	if tool.session(subA) == tool.session(subB) {
+		t.Fatal("expected subagents not to share sessions")
+	}
+	if tool.session(subA) != tool.session(subA) {
+		t.Fatal("expected the same subagent to reuse its session")
+	}
+	if got := tool.profileLabel(subA); got != "isolated" {
+		t.Fatalf("profileLabel = %q, want isolated", got)
+	}
+
+	tool.ReleaseSession("agent:default:subagent:a")
+	if _, ok := tool.isolated["agent:default:subagent:a"]; ok {
+		t.Fatal("expected ReleaseSession to drop the session")
+	}
+}
+
+func TestReleaseSessionResourcesThroughEmergencyStopGuard(t *testing.T) {
+	tool := NewBrowserTool("", "", "")
+	reg := NewRegistryWithEmergencyStop(stubEmergencyStopProvider{})
+	reg.Register(tool)
+
+	registered, _ := reg.Get("browser")
+	if registered == Tool(tool) {
+		t.Fatal("expected the browser tool to be guarded by the emergency stop")
+	}
+	if bt, ok := AsBrowserTool(registered); !ok || bt != tool {
+		t.Fatalf("AsBrowserTool = %p, %v; want the registered tool", bt, ok)
+	}
+
+	key := "agent:default:subagent:a"
+	tool.session(WithSessionKey(context.Background(), key))
+	ReleaseSessionResources(reg, key)
+	if _, ok := tool.isolated[key]; ok {
+		t.Fatal("expected ReleaseSessionResources to reach the guarded browser tool")
+	}
+}
+
+func TestBrowserToolSessionsSharedWhenIsolationOff(t *testing.T) {
+	tool := NewBrowserTool("", "", "")
+	tool.sharedForSubagents = true
+
+	sub := WithSessionKey(context.Background(), "agent:default:subagent:a")
+	if tool.session(sub) != tool.session(context.Background()) {
+		t.Fatal("expected subagents to share the profile when isolation is off")
+	}
+}
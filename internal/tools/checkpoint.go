@@ -0,0 +1,325 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"ok-gobot/internal/storage"
+)
+
+// Checkpoint defaults used when CheckpointPolicy leaves a limit unset.
+const (
+	DefaultCheckpointMaxAge        = 7 * 24 * time.Hour
+	DefaultCheckpointMaxTotalBytes = 64 << 20
+	DefaultCheckpointMaxFileBytes  = 2 << 20
+)
+
+// CheckpointStore persists the prior content of files changed by tools so a
+// run's edits can be rolled back. Implemented by storage.Store.
+type CheckpointStore interface {
+	AddFileCheckpoint(cp storage.FileCheckpoint) error
+	ListCheckpointRuns(sessionKey string, limit int) ([]storage.CheckpointRun, error)
+	ListFileCheckpoints(sessionKey, runID string) ([]storage.FileCheckpoint, error)
+	DeleteCheckpointRun(sessionKey, runID string) error
+	PruneFileCheckpoints(maxAge time.Duration, maxTotalBytes int64) (int64, error)
+}
+
+// CheckpointPolicy bounds how much snapshot data is kept.
+type CheckpointPolicy struct {
+	MaxAge        time.Duration // runs whose newest snapshot is older are pruned
+	MaxTotalBytes int64         // oldest runs are pruned beyond this total
+	MaxFileBytes  int64         // larger files are not snapshotted
+}
+
+// CheckpointManager hands out per-run recorders and rolls runs back.
+type CheckpointManager struct {
+	store  CheckpointStore
+	policy CheckpointPolicy
+}
+
+// NewCheckpointManager creates a manager, filling unset limits with defaults.
+func NewCheckpointManager(store CheckpointStore, policy CheckpointPolicy) *CheckpointManager {
+	if policy.MaxAge <= 0 {
+		policy.MaxAge = DefaultCheckpointMaxAge
+	}
+	if policy.MaxTotalBytes <= 0 {
+		policy.MaxTotalBytes = DefaultCheckpointMaxTotalBytes
+	}
+	if policy.MaxFileBytes <= 0 {
+		policy.MaxFileBytes = DefaultCheckpointMaxFileBytes
+	}
+	return &CheckpointManager{store: store, policy: policy}
+}
+
+// Recorder returns a recorder that snapshots files for one run of a session.
+func (m *CheckpointManager) Recorder(sessionKey, runID string) *CheckpointRecorder {
+	return &CheckpointRecorder{
+		store:        m.store,
+		sessionKey:   sessionKey,
+		runID:        runID,
+		maxFileBytes: m.policy.MaxFileBytes,
+		seen:         make(map[string]bool),
+	}
+}
+
+// Prune drops snapshots that exceed the age or size budget.
+func (m *CheckpointManager) Prune() (int64, error) {
+	return m.store.PruneFileCheckpoints(m.policy.MaxAge, m.policy.MaxTotalBytes)
+}
+
+// List returns the most recent checkpointed runs of a session, newest first.
+func (m *CheckpointManager) List(sessionKey string, limit int) ([]storage.CheckpointRun, error) {
+	return m.store.ListCheckpointRuns(sessionKey, limit)
+}
+
+// UndoResult describes what rolling back one run did.
+type UndoResult struct {
+	RunID    string
+	Restored []string // files written back to their prior content or moved back
+	Removed  []string // files the run had created
+	Failed   []string // "path: error" for files that could not be restored
+}
+
+// Undo rolls back the last n checkpointed runs of a session, newest first.
+// A run's checkpoints are dropped once it has been rolled back, even if some
+// files failed, so repeated undos walk further back in history.
+func (m *CheckpointManager) Undo(sessionKey string, n int) ([]UndoResult, error) {
+	if n <= 0 {
+		n = 1
+	}
+	runs, err := m.store.ListCheckpointRuns(sessionKey, n)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]UndoResult, 0, len(runs))
+	for _, run := range runs {
+		snapshots, err := m.store.ListFileCheckpoints(sessionKey, run.RunID)
+		if err != nil {
+			return results, err
+		}
+		result := UndoResult{RunID: run.RunID}
+		for _, cp := range snapshots {
+			removed, err := restoreCheckpoint(cp)
+			switch {
+			case err != nil:
+				result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", cp.Path, err))
+			case removed:
+				result.Removed = append(result.Removed, cp.Path)
+			case cp.MovedFrom != "":
+				result.Restored = append(result.Restored, cp.MovedFrom)
+			default:
+				result.Restored = append(result.Restored, cp.Path)
+			}
+		}
+		if err := m.store.DeleteCheckpointRun(sessionKey, run.RunID); err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// restoreCheckpoint puts a file back to its snapshot, or moves a renamed
+// path back. It reports removed=true when the file did not exist before the
+// run and was deleted.
+func restoreCheckpoint(cp storage.FileCheckpoint) (removed bool, err error) {
+	if cp.MovedFrom != "" {
+		if _, err := os.Lstat(cp.MovedFrom); err == nil {
+			return false, fmt.Errorf("%s already exists", cp.MovedFrom)
+		}
+		if err := os.MkdirAll(filepath.Dir(cp.MovedFrom), 0755); err != nil {
+			return false, err
+		}
+		return false, os.Rename(cp.Path, cp.MovedFrom)
+	}
+	if !cp.Existed {
+		if err := os.Remove(cp.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+		return true, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(cp.Path), 0755); err != nil {
+		return false, err
+	}
+	mode := os.FileMode(cp.Mode).Perm()
+	if mode == 0 {
+		mode = 0644
+	}
+	if err := os.WriteFile(cp.Path, cp.Content, mode); err != nil {
+		return false, err
+	}
+	return false, os.Chmod(cp.Path, mode)
+}
+
+// CheckpointRecorder snapshots files before a run's tools change them.
+type CheckpointRecorder struct {
+	store        CheckpointStore
+	sessionKey   string
+	runID        string
+	maxFileBytes int64
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// Snapshot records the current state of path unless it was already recorded
+// in this run. Directories, other non-regular files and files larger than the
+// size limit cannot be restored from a snapshot, so they return a
+// ToolDenial and the change must not go ahead.
+func (r *CheckpointRecorder) Snapshot(toolName, path string) error {
+	path = filepath.Clean(path)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seen[path] {
+		return nil
+	}
+
+	cp := storage.FileCheckpoint{
+		SessionKey: r.sessionKey,
+		RunID:      r.runID,
+		ToolName:   toolName,
+		Path:       path,
+	}
+	info, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		cp.Existed = false
+	case err != nil:
+		return err
+	case !info.Mode().IsRegular():
+		return checkpointDenial(toolName, fmt.Sprintf("%s is not a regular file", path))
+	case info.Size() > r.maxFileBytes:
+		return checkpointDenial(toolName, fmt.Sprintf("%s is %d bytes, over the %d-byte snapshot limit", path, info.Size(), r.maxFileBytes))
+	default:
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		cp.Existed = true
+		cp.Mode = uint32(info.Mode().Perm())
+		cp.Content = content
+	}
+
+	if err := r.store.AddFileCheckpoint(cp); err != nil {
+		return err
+	}
+	r.seen[path] = true
+	return nil
+}
+
+// SnapshotMove records a rename of src to dst. Regular files within the size
+// limit are snapshotted at both ends; anything else, such as a directory, is
+// recorded as a move that undo renames back.
+func (r *CheckpointRecorder) SnapshotMove(toolName, src, dst string) error {
+	src, dst = filepath.Clean(src), filepath.Clean(dst)
+	info, err := os.Lstat(src)
+	if err != nil {
+		// The move itself reports the missing source.
+		return nil
+	}
+	if info.Mode().IsRegular() && info.Size() <= r.maxFileBytes {
+		if err := r.Snapshot(toolName, src); err != nil {
+			return err
+		}
+		return r.Snapshot(toolName, dst)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Only one checkpoint per path is kept in a run, so a move onto a path
+	// that already has one could not be undone.
+	if r.seen[dst] {
+		return checkpointDenial(toolName, fmt.Sprintf("%s was already changed in this run", dst))
+	}
+	if err := r.store.AddFileCheckpoint(storage.FileCheckpoint{
+		SessionKey: r.sessionKey,
+		RunID:      r.runID,
+		ToolName:   toolName,
+		Path:       dst,
+		MovedFrom:  src,
+		Existed:    true,
+	}); err != nil {
+		return err
+	}
+	r.seen[dst] = true
+	return nil
+}
+
+func checkpointDenial(toolName, reason string) *ToolDenial {
+	return &ToolDenial{
+		ToolName:    toolName,
+		Family:      "checkpoint",
+		Reason:      reason + "; the change could not be undone, so it was not made",
+		Remediation: "Raise tools.checkpoints.max_file_bytes, or make the change outside the agent.",
+	}
+}
+
+// Count returns how many paths have been recorded so far.
+func (r *CheckpointRecorder) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.seen)
+}
+
+type checkpointRecorderKey struct{}
+
+// WithCheckpointRecorder attaches a recorder to a run's context. File-mutating
+// tools executed with that context snapshot their targets first.
+func WithCheckpointRecorder(ctx context.Context, r *CheckpointRecorder) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, checkpointRecorderKey{}, r)
+}
+
+func recorderFromContext(ctx context.Context) (*CheckpointRecorder, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	r, ok := ctx.Value(checkpointRecorderKey{}).(*CheckpointRecorder)
+	return r, ok
+}
+
+// snapshotFiles records resolved paths with the context's recorder, if any.
+// A failed snapshot aborts the write so no unrecoverable change is made.
+func snapshotFiles(ctx context.Context, toolName string, paths ...string) error {
+	r, ok := recorderFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := r.Snapshot(toolName, path); err != nil {
+			if _, ok := IsToolDenial(err); ok {
+				return err
+			}
+			return fmt.Errorf("failed to checkpoint %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// snapshotMove records a rename with the context's recorder, if any. A failed
+// snapshot aborts the move.
+func snapshotMove(ctx context.Context, toolName, src, dst string) error {
+	r, ok := recorderFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if err := r.SnapshotMove(toolName, src, dst); err != nil {
+		if _, ok := IsToolDenial(err); ok {
+			return err
+		}
+		return fmt.Errorf("failed to checkpoint move of %s: %w", src, err)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ok-gobot/internal/storage"
+)
+
+func newCheckpointTestManager(t *testing.T, policy CheckpointPolicy) (*CheckpointManager, *storage.Store) {
+	t.Helper()
+	store, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("storage.New: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return NewCheckpointManager(store, policy), store
+}
+
+func TestCheckpoint_UndoRestoresFileToolChanges(t *testing.T) {
+	manager, _ := newCheckpointTestManager(t, CheckpointPolicy{})
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("original\n"), 0600) //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "old.txt"), []byte("moved\n"), 0644)  //nolint:errcheck
+
+	tool := &FileTool{BasePath: dir}
+	ctx := WithCheckpointRecorder(context.Background(), manager.Recorder("dm:1", "run-1"))
+	for _, params := range []map[string]string{
+		{"command": "write", "path": "a.txt", "content": "first\n"},
+		{"command": "write", "path": "a.txt", "content": "second\n"},
+		{"command": "create", "path": "new.txt", "content": "fresh\n"},
+		{"command": "move", "path": "old.txt", "destination": "sub/renamed.txt"},
+	} {
+		if _, err := tool.ExecuteJSON(ctx, params); err != nil {
+			t.Fatalf("%v: %v", params, err)
+		}
+	}
+
+	runs, err := manager.List("dm:1", 10)
+	if err != nil || len(runs) != 1 || runs[0].Files != 4 {
+		t.Fatalf("List = %+v, %v; want one run with 4 files", runs, err)
+	}
+
+	results, err := manager.Undo("dm:1", 1)
+	if err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if len(results) != 1 || len(results[0].Failed) != 0 {
+		t.Fatalf("unexpected undo results: %+v", results)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "a.txt"))
+	if string(data) != "original\n" {
+		t.Errorf("a.txt = %q, want original content", data)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "a.txt")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("a.txt mode not restored: %v, %v", info, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "old.txt")); string(data) != "moved\n" {
+		t.Errorf("old.txt = %q, want restored", data)
+	}
+	for _, name := range []string{"new.txt", "sub/renamed.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed, stat err = %v", name, err)
+		}
+	}
+
+	if runs, _ := manager.List("dm:1", 10); len(runs) != 0 {
+		t.Errorf("expected checkpoints to be consumed by undo, got %+v", runs)
+	}
+}
+
+func TestCheckpoint_UndoWalksBackThroughRuns(t *testing.T) {
+	manager, _ := newCheckpointTestManager(t, CheckpointPolicy{})
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, []byte("v0\n"), 0644) //nolint:errcheck
+
+	patch := &PatchTool{BasePath: dir}
+	for i, next := range []string{"v1", "v2"} {
+		prev := "v0"
+		if i > 0 {
+			prev = "v1"
+		}
+		ctx := WithCheckpointRecorder(context.Background(), manager.Recorder("dm:1", "run-"+next))
+		diff := "--- a/notes.txt\n+++ b/notes.txt\n@@ -1 +1 @@\n-" + prev + "\n+" + next + "\n"
+		if _, err := patch.ExecuteJSON(ctx, map[string]string{"patch": diff}); err != nil {
+			t.Fatalf("patch to %s: %v", next, err)
+		}
+	}
+
+	if _, err := manager.Undo("dm:1", 1); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "v1\n" {
+		t.Fatalf("after one undo got %q, want v1", data)
+	}
+	if _, err := manager.Undo("dm:1", 5); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "v0\n" {
+		t.Fatalf("after second undo got %q, want v0", data)
+	}
+	if results, err := manager.Undo("dm:1", 1); err != nil || len(results) != 0 {
+		t.Fatalf("expected nothing left to undo, got %+v, %v", results, err)
+	}
+}
+
+func TestCheckpoint_SessionsAreIsolatedAndLargeFilesRefused(t *testing.T) {
+	manager, _ := newCheckpointTestManager(t, CheckpointPolicy{MaxFileBytes: 8})
+	dir := t.TempDir()
+	big := strings.Repeat("x", 64)
+	os.WriteFile(filepath.Join(dir, "big.txt"), []byte(big), 0644) //nolint:errcheck
+
+	tool := &FileTool{BasePath: dir}
+	ctx := WithCheckpointRecorder(context.Background(), manager.Recorder("dm:1", "run-1"))
+	for _, args := range [][]string{{"write", "big.txt", "small"}, {"delete", "big.txt"}} {
+		_, err := tool.Execute(ctx, args...)
+		if denial, ok := IsToolDenial(err); !ok || denial.Family != "checkpoint" {
+			t.Fatalf("%v: expected checkpoint denial, got %v", args, err)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "big.txt")); string(data) != big {
+		t.Fatalf("refused change still modified big.txt: %q", data)
+	}
+	if _, err := tool.Execute(ctx, "write", "small.txt", "hi"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	runs, _ := manager.List("dm:1", 10)
+	if len(runs) != 1 || runs[0].Files != 1 {
+		t.Fatalf("expected only small.txt to be checkpointed, got %+v", runs)
+	}
+	if runs, _ := manager.List("dm:2", 10); len(runs) != 0 {
+		t.Fatalf("other session should have no checkpoints, got %+v", runs)
+	}
+}
+
+func TestCheckpoint_UndoMovesDirectoryBack(t *testing.T) {
+	manager, _ := newCheckpointTestManager(t, CheckpointPolicy{MaxFileBytes: 8})
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "src", "nested"), 0755)                                    //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "src", "nested", "a.txt"), []byte("alpha"), 0644)         //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "src", "big.txt"), []byte(strings.Repeat("x", 64)), 0644) //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "large.bin"), []byte(strings.Repeat("y", 64)), 0644)      //nolint:errcheck
+
+	tool := &FileTool{BasePath: dir}
+	ctx := WithCheckpointRecorder(context.Background(), manager.Recorder("dm:1", "run-1"))
+	if _, err := tool.Execute(ctx, "move", "src", "archive/dst"); err != nil {
+		t.Fatalf("move dir: %v", err)
+	}
+	if _, err := tool.Execute(ctx, "move", "large.bin", "large2.bin"); err != nil {
+		t.Fatalf("move large file: %v", err)
+	}
+	if _, err := tool.Execute(ctx, "write", "archive/dst/nested/a.txt", "beta"); err != nil {
+		t.Fatalf("write moved file: %v", err)
+	}
+
+	results, err := manager.Undo("dm:1", 1)
+	if err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if len(results) != 1 || len(results[0].Failed) != 0 {
+		t.Fatalf("unexpected undo result: %+v", results)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "src", "nested", "a.txt")); string(data) != "alpha" {
+		t.Errorf("src/nested/a.txt = %q, want alpha", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "src", "big.txt")); err != nil {
+		t.Errorf("src/big.txt not moved back: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "large.bin")); err != nil {
+		t.Errorf("large.bin not moved back: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "archive", "dst")); !os.IsNotExist(err) {
+		t.Errorf("archive/dst should be gone, got %v", err)
+	}
+}
+
+func TestCheckpoint_PruneBySize(t *testing.T) {
+	manager, store := newCheckpointTestManager(t, CheckpointPolicy{MaxTotalBytes: 10})
+	for i, runID := range []string{"run-1", "run-2", "run-3"} {
+		if err := store.AddFileCheckpoint(storage.FileCheckpoint{
+			SessionKey: "dm:1",
+			RunID:      runID,
+			Path:       "/tmp/file" + runID,
+			Existed:    true,
+			Content:    []byte(strings.Repeat("a", 4+i)),
+		}); err != nil {
+			t.Fatalf("AddFileCheckpoint: %v", err)
+		}
+	}
+
+	deleted, err := manager.Prune()
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted = %d, want 2", deleted)
+	}
+	runs, _ := manager.List("dm:1", 10)
+	if len(runs) != 1 || runs[0].RunID != "run-3" {
+		t.Errorf("expected only the newest run to survive, got %+v", runs)
+	}
+}
+
+func TestCheckpoint_PruneByAgeKeepsWholeRuns(t *testing.T) {
+	manager, store := newCheckpointTestManager(t, CheckpointPolicy{MaxAge: time.Hour})
+	for _, cp := range []struct{ runID, path string }{
+		{"run-stale", "/tmp/a"}, {"run-stale", "/tmp/b"},
+		{"run-live", "/tmp/c"}, {"run-live", "/tmp/d"},
+	} {
+		if err := store.AddFileCheckpoint(storage.FileCheckpoint{SessionKey: "dm:1", RunID: cp.runID, Path: cp.path, Existed: true, Content: []byte("x")}); err != nil {
+			t.Fatalf("AddFileCheckpoint: %v", err)
+		}
+	}
+	// run-live started long ago but touched a file recently.
+	if _, err := store.DB().Exec(`UPDATE file_checkpoints SET created_at = datetime('now', '-2 hours') WHERE path IN ('/tmp/a', '/tmp/b', '/tmp/c')`); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := manager.Prune()
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted = %d, want the 2 snapshots of run-stale", deleted)
+	}
+	runs, _ := manager.List("dm:1", 10)
+	if len(runs) != 1 || runs[0].RunID != "run-live" || runs[0].Files != 2 {
+		t.Errorf("expected run-live to survive whole, got %+v", runs)
+	}
+}
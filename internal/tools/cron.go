@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"ok-gobot/internal/cron"
 	"ok-gobot/internal/storage"
 )
 
@@ -14,10 +15,13 @@ import (
 type CronScheduler interface {
 	AddJob(expression, task string, chatID int64) (int64, error)
 	AddExecJob(expression, task string, chatID int64, timeoutSeconds int) (int64, error)
+	AddScheduledJob(job storage.CronJob) (int64, error)
 	RemoveJob(jobID int64) error
 	ToggleJob(jobID int64, enabled bool) error
 	ListJobs() ([]storage.CronJob, error)
 	GetNextRun(jobID int64) (time.Time, error)
+	ChatLocation(chatID int64) *time.Location
+	SetChatTimezone(chatID int64, name string) error
 }
 
 // CronTool manages scheduled tasks
@@ -39,7 +43,7 @@ func (c *CronTool) Name() string {
 }
 
 func (c *CronTool) Description() string {
-	return "Manage scheduled tasks (add, list, remove, toggle)"
+	return "Manage scheduled tasks: recurring cron jobs (add), one-shot reminders at a natural-language time such as \"in 2h\" or \"next friday 14:00\" (at), list, remove, toggle, and the chat's timezone"
 }
 
 // GetSchema returns the JSON Schema for cron tool parameters
@@ -49,13 +53,21 @@ func (c *CronTool) GetSchema() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"command": map[string]interface{}{
 				"type":        "string",
-				"description": "Cron command: add, list, remove, toggle",
-				"enum":        []string{"add", "list", "remove", "toggle"},
+				"description": "Cron command: add (recurring), at (one-shot), list, remove, toggle, timezone",
+				"enum":        []string{"add", "at", "list", "remove", "toggle", "timezone"},
 			},
 			"expression": map[string]interface{}{
 				"type":        "string",
 				"description": "Cron expression (for add command), e.g. '0 9 * * *'",
 			},
+			"when": map[string]interface{}{
+				"type":        "string",
+				"description": "When a one-shot job runs (for at command): 'in 2h', 'tomorrow at 9', 'next friday 14:00', '2026-03-01 09:30'",
+			},
+			"timezone": map[string]interface{}{
+				"type":        "string",
+				"description": "IANA timezone, e.g. 'Europe/Berlin'. For add/at: overrides the chat's timezone for this job. For timezone command: sets the chat's timezone",
+			},
 			"task": map[string]interface{}{
 				"type":        "string",
 				"description": "Task description (for add command)",
@@ -64,6 +76,11 @@ func (c *CronTool) GetSchema() map[string]interface{} {
 				"type":        "string",
 				"description": "Job ID (for remove/toggle commands)",
 			},
+			"enabled": map[string]interface{}{
+				"type":        "string",
+				"description": "For toggle: on or off (default on)",
+				"enum":        []string{"on", "off"},
+			},
 			"type": map[string]interface{}{
 				"type":        "string",
 				"description": "Job type: 'llm' (AI agent processes task) or 'exec' (direct shell execution). Default: llm",
@@ -73,6 +90,20 @@ func (c *CronTool) GetSchema() map[string]interface{} {
 				"type":        "string",
 				"description": "Timeout in seconds for exec jobs (default: 900)",
 			},
+			"overlap": map[string]interface{}{
+				"type":        "string",
+				"description": "For add: what to do when the previous run is still going — allow, skip (default) or queue",
+				"enum":        []string{"allow", "skip", "queue"},
+			},
+			"misfire": map[string]interface{}{
+				"type":        "string",
+				"description": "For add: runs missed while the bot was down — skip (default), run_once or run_all",
+				"enum":        []string{"skip", "run_once", "run_all"},
+			},
+			"jitter": map[string]interface{}{
+				"type":        "string",
+				"description": "For add: random delay in seconds before each run, to spread load",
+			},
 		},
 		"required": []string{"command"},
 	}
@@ -89,6 +120,13 @@ func (c *CronTool) Execute(ctx context.Context, args ...string) (string, error)
 	switch command {
 	case "add":
 		return c.addJob(cmdArgs)
+	case "at":
+		if len(cmdArgs) < 2 {
+			return "", fmt.Errorf("usage: cron at <when> <task>\n\nExample:\n  cron at \"tomorrow 9:00\" \"Remind me to call the bank\"")
+		}
+		return c.addOnce(cmdArgs[0], strings.Join(cmdArgs[1:], " "), "")
+	case "timezone", "tz":
+		return c.timezone(strings.Join(cmdArgs, " "))
 	case "list":
 		return c.listJobs()
 	case "remove", "delete":
@@ -102,6 +140,49 @@ func (c *CronTool) Execute(ctx context.Context, args ...string) (string, error)
 	}
 }
 
+// ExecuteJSON handles structured calls, so named params such as type,
+// timeout, when and timezone reach the command.
+func (c *CronTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	switch command := strings.TrimSpace(params["command"]); command {
+	case "add":
+		expression := strings.TrimSpace(params["expression"])
+		task := strings.TrimSpace(params["task"])
+		if expression == "" || task == "" {
+			return "", fmt.Errorf("expression and task are required")
+		}
+		spec := storage.CronJob{
+			Task:              task,
+			Type:              strings.TrimSpace(params["type"]),
+			Timezone:          strings.TrimSpace(params["timezone"]),
+			ConcurrencyPolicy: strings.TrimSpace(params["overlap"]),
+			MisfirePolicy:     strings.TrimSpace(params["misfire"]),
+		}
+		spec.TimeoutSeconds, _ = strconv.Atoi(strings.TrimSpace(params["timeout"]))
+		spec.JitterSeconds, _ = strconv.Atoi(strings.TrimSpace(params["jitter"]))
+		return c.addRecurring(expression, spec)
+	case "at":
+		when := strings.TrimSpace(params["when"])
+		task := strings.TrimSpace(params["task"])
+		if when == "" || task == "" {
+			return "", fmt.Errorf("when and task are required")
+		}
+		return c.addOnce(when, task, strings.TrimSpace(params["timezone"]))
+	case "timezone", "tz":
+		return c.timezone(params["timezone"])
+	case "remove", "delete", "toggle":
+		args := []string{command}
+		if id := strings.TrimSpace(params["id"]); id != "" {
+			args = append(args, id)
+		}
+		if state := strings.TrimSpace(params["enabled"]); state != "" {
+			args = append(args, state)
+		}
+		return c.Execute(ctx, args...)
+	default:
+		return c.Execute(ctx, command)
+	}
+}
+
 func (c *CronTool) addJob(args []string) (string, error) {
 	if len(args) < 2 {
 		return "", fmt.Errorf("usage: cron add <expression> <task> [--type exec] [--timeout 900]\n\nExamples:\n" +
@@ -132,8 +213,28 @@ func (c *CronTool) addJob(args []string) (string, error) {
 		return "", fmt.Errorf("expression and task are required")
 	}
 
-	expression := cleanArgs[0]
-	task := strings.Join(cleanArgs[1:], " ")
+	return c.addRecurring(cleanArgs[0], storage.CronJob{
+		Task:           strings.Join(cleanArgs[1:], " "),
+		Type:           jobType,
+		TimeoutSeconds: timeoutSec,
+	})
+}
+
+// addRecurring schedules a cron expression with the task, type, timeout,
+// timezone and run policies taken from spec. Without an explicit timezone
+// (or CRON_TZ= prefix) it runs in the chat's timezone, if one is set.
+func (c *CronTool) addRecurring(expression string, spec storage.CronJob) (string, error) {
+	if c.scheduler == nil {
+		return "", fmt.Errorf("scheduler not configured")
+	}
+
+	tz, expression := cron.SplitTimezone(expression)
+	if tz == "" {
+		tz = spec.Timezone
+	}
+	if tz == "" {
+		tz = c.chatTimezone()
+	}
 
 	// Add seconds field if not present (5 fields -> 6 fields)
 	fields := strings.Fields(expression)
@@ -141,31 +242,107 @@ func (c *CronTool) addJob(args []string) (string, error) {
 		expression = "0 " + expression
 	}
 
+	job := spec
+	job.Expression = expression
+	job.ChatID = c.chatID
+	job.Timezone = tz
+	if job.Type == "exec" {
+		if job.TimeoutSeconds == 0 {
+			job.TimeoutSeconds = 900
+		}
+	} else {
+		job.Type = "llm"
+		job.TimeoutSeconds = 0
+	}
+
+	jobID, err := c.scheduler.AddScheduledJob(job)
+	if err != nil {
+		return "", fmt.Errorf("failed to add job: %w", err)
+	}
+	job.ID = jobID
+
+	typeLabel := "AI"
+	if job.Type == "exec" {
+		typeLabel = "exec"
+	}
+	return fmt.Sprintf("Job #%d created (%s)\nTask: %s\nSchedule: %s\nNext run: %s\nPolicies: %s",
+		jobID, typeLabel, job.Task, cron.DescribeSchedule(job), c.nextRun(job), cron.DescribePolicies(job)), nil
+}
+
+// addOnce schedules task to run once at a natural-language time, read in
+// the given timezone or the chat's.
+func (c *CronTool) addOnce(when, task, timezone string) (string, error) {
 	if c.scheduler == nil {
 		return "", fmt.Errorf("scheduler not configured")
 	}
 
-	var jobID int64
-	var err error
-	if jobType == "exec" {
-		if timeoutSec == 0 {
-			timeoutSec = 900
+	loc := c.scheduler.ChatLocation(c.chatID)
+	if timezone != "" {
+		var err error
+		if loc, err = cron.LoadLocation(timezone); err != nil {
+			return "", err
 		}
-		jobID, err = c.scheduler.AddExecJob(expression, task, c.chatID, timeoutSec)
-	} else {
-		jobID, err = c.scheduler.AddJob(expression, task, c.chatID)
 	}
+	runAt, err := cron.ParseWhen(when, time.Now().In(loc))
+	if err != nil {
+		return "", err
+	}
+
+	job := storage.CronJob{
+		Task:     task,
+		ChatID:   c.chatID,
+		Type:     "llm",
+		RunAt:    runAt.UTC().Format(time.RFC3339),
+		Timezone: loc.String(),
+	}
+	if loc == time.Local {
+		job.Timezone = ""
+	}
+	jobID, err := c.scheduler.AddScheduledJob(job)
 	if err != nil {
 		return "", fmt.Errorf("failed to add job: %w", err)
 	}
 
-	nextRun, _ := c.scheduler.GetNextRun(jobID)
-	typeLabel := "AI"
-	if jobType == "exec" {
-		typeLabel = "exec"
+	return fmt.Sprintf("Job #%d created (one-shot)\nTask: %s\nRuns: %s (in %s)\nIt is deleted after it runs.",
+		jobID, task, runAt.Format("Mon 2006-01-02 15:04 MST"), time.Until(runAt).Round(time.Minute)), nil
+}
+
+// timezone shows or sets the chat's scheduling timezone.
+func (c *CronTool) timezone(name string) (string, error) {
+	if c.scheduler == nil {
+		return "", fmt.Errorf("scheduler not configured")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		loc := c.scheduler.ChatLocation(c.chatID)
+		return fmt.Sprintf("🕐 Timezone: %s (now %s)", loc, time.Now().In(loc).Format("2006-01-02 15:04 MST")), nil
+	}
+	if c.chatID == 0 {
+		return "", fmt.Errorf("timezone can only be set from a chat")
+	}
+	if err := c.scheduler.SetChatTimezone(c.chatID, name); err != nil {
+		return "", err
+	}
+	loc := c.scheduler.ChatLocation(c.chatID)
+	return fmt.Sprintf("✅ Timezone set to %s (now %s). New schedules use it.", loc, time.Now().In(loc).Format("2006-01-02 15:04 MST")), nil
+}
+
+// chatTimezone is the chat's timezone name, or "" for server local time.
+func (c *CronTool) chatTimezone() string {
+	loc := c.scheduler.ChatLocation(c.chatID)
+	if loc == time.Local {
+		return ""
 	}
-	return fmt.Sprintf("Job #%d created (%s)\nTask: %s\nSchedule: %s\nNext run: %s",
-		jobID, typeLabel, task, expression, nextRun.Format("2006-01-02 15:04:05")), nil
+	return loc.String()
+}
+
+// nextRun formats a job's next fire time in its own timezone.
+func (c *CronTool) nextRun(job storage.CronJob) string {
+	t, err := c.scheduler.GetNextRun(job.ID)
+	if err != nil || t.IsZero() {
+		return "N/A"
+	}
+	return t.In(cron.JobLocation(job)).Format("2006-01-02 15:04 MST")
 }
 
 func (c *CronTool) listJobs() (string, error) {
@@ -199,18 +376,16 @@ func (c *CronTool) listJobs() (string, error) {
 			status = "⏸️"
 		}
 
-		nextRun := "N/A"
-		if t, err := c.scheduler.GetNextRun(job.ID); err == nil {
-			nextRun = t.Format("2006-01-02 15:04")
-		}
-
 		typeTag := ""
 		if job.Type == "exec" {
 			typeTag = " [exec]"
 		}
+		if cron.IsOneShot(job) {
+			typeTag += " [once]"
+		}
 		sb.WriteString(fmt.Sprintf("%s #%d%s: %s\n", status, job.ID, typeTag, job.Task))
-		sb.WriteString(fmt.Sprintf("   Schedule: %s\n", job.Expression))
-		sb.WriteString(fmt.Sprintf("   Next: %s\n\n", nextRun))
+		sb.WriteString(fmt.Sprintf("   Schedule: %s\n", cron.DescribeSchedule(job)))
+		sb.WriteString(fmt.Sprintf("   Next: %s\n\n", c.nextRun(job)))
 	}
 
 	return sb.String(), nil
@@ -311,9 +486,11 @@ func (c *CronTool) help() string {
 
 Commands:
   cron add <expression> <task>  - Create a new scheduled task
+  cron at <when> <task>         - Run a task once, then delete it
   cron list                     - List all scheduled tasks
   cron remove <job_id>          - Remove a task
   cron toggle <job_id> [on|off] - Enable/disable a task
+  cron timezone [zone]          - Show or set this chat's timezone
 
 Cron Expression Format (5 fields):
   minute hour day-of-month month day-of-week
@@ -323,5 +500,12 @@ Examples:
   "0 9 * * 1"     - Every Monday at 9:00 AM
   "*/30 * * * *"  - Every 30 minutes
   "0 0 1 * *"     - First day of every month
-  "0 18 * * 1-5"  - Weekdays at 6:00 PM`
+  "0 18 * * 1-5"  - Weekdays at 6:00 PM
+  "CRON_TZ=Asia/Tokyo 0 9 * * *" - 9:00 AM Tokyo time
+
+Times for "at":
+  "in 2h", "in 1 day and 3 hours", "tomorrow at 9", "tonight",
+  "next friday 14:00", "2026-03-01 09:30"
+
+Schedules use the chat's timezone (cron timezone), or server time if unset.`
 }
@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"ok-gobot/internal/storage"
+)
+
+type fakeCronScheduler struct {
+	added []storage.CronJob
+	loc   *time.Location
+}
+
+func (f *fakeCronScheduler) AddJob(expression, task string, chatID int64) (int64, error) {
+	return f.AddScheduledJob(storage.CronJob{Expression: expression, Task: task, ChatID: chatID})
+}
+
+func (f *fakeCronScheduler) AddExecJob(expression, task string, chatID int64, timeoutSeconds int) (int64, error) {
+	return f.AddScheduledJob(storage.CronJob{Expression: expression, Task: task, ChatID: chatID, Type: "exec", TimeoutSeconds: timeoutSeconds})
+}
+
+func (f *fakeCronScheduler) AddScheduledJob(job storage.CronJob) (int64, error) {
+	job.ID = int64(len(f.added) + 1)
+	f.added = append(f.added, job)
+	return job.ID, nil
+}
+
+func (f *fakeCronScheduler) RemoveJob(int64) error                   { return nil }
+func (f *fakeCronScheduler) ToggleJob(int64, bool) error             { return nil }
+func (f *fakeCronScheduler) ListJobs() ([]storage.CronJob, error)    { return f.added, nil }
+func (f *fakeCronScheduler) GetNextRun(int64) (time.Time, error)     { return time.Time{}, nil }
+func (f *fakeCronScheduler) ChatLocation(int64) *time.Location       { return f.loc }
+func (f *fakeCronScheduler) SetChatTimezone(_ int64, _ string) error { return nil }
+
+func TestCronToolOneShotUsesChatTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("zone data unavailable: %v", err)
+	}
+	sched := &fakeCronScheduler{loc: tokyo}
+	tool := NewCronTool(sched, 42)
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{
+		"command": "at",
+		"when":    "in 2h",
+		"task":    "Call the bank",
+	})
+	if err != nil {
+		t.Fatalf("ExecuteJSON(at) failed: %v", err)
+	}
+	if !strings.Contains(out, "one-shot") || !strings.Contains(out, "JST") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+	job := sched.added[0]
+	runAt, err := time.Parse(time.RFC3339, job.RunAt)
+	if err != nil {
+		t.Fatalf("RunAt %q: %v", job.RunAt, err)
+	}
+	if d := time.Until(runAt); d < 119*time.Minute || d > 121*time.Minute {
+		t.Fatalf("run at %s, want about 2h from now", runAt)
+	}
+	if job.Timezone != "Asia/Tokyo" || job.ChatID != 42 || job.Expression != "" {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"command": "at", "when": "someday", "task": "x"}); err == nil {
+		t.Fatal("expected an error for an unparseable time")
+	}
+}
+
+func TestCronToolAddKeepsNamedParams(t *testing.T) {
+	sched := &fakeCronScheduler{loc: time.Local}
+	tool := NewCronTool(sched, 42)
+
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{
+		"command":    "add",
+		"expression": "30 3 * * *",
+		"task":       "backup.sh",
+		"type":       "exec",
+		"timeout":    "120",
+		"timezone":   "UTC",
+	}); err != nil {
+		t.Fatalf("ExecuteJSON(add) failed: %v", err)
+	}
+	job := sched.added[0]
+	if job.Expression != "0 30 3 * * *" || job.Type != "exec" || job.TimeoutSeconds != 120 || job.Timezone != "UTC" {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+}
+
+func TestCronToolAddPassesRunPolicies(t *testing.T) {
+	sched := &fakeCronScheduler{loc: time.Local}
+	tool := NewCronTool(sched, 42)
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{
+		"command":    "add",
+		"expression": "*/5 * * * *",
+		"task":       "Check the deploy queue",
+		"overlap":    "queue",
+		"misfire":    "run_once",
+		"jitter":     "30",
+	})
+	if err != nil {
+		t.Fatalf("ExecuteJSON(add) failed: %v", err)
+	}
+	job := sched.added[0]
+	if job.ConcurrencyPolicy != "queue" || job.MisfirePolicy != "run_once" || job.JitterSeconds != 30 || job.Type != "llm" {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+	if !strings.Contains(out, "overlap=queue misfire=run_once jitter=30s") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
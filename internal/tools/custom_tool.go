@@ -0,0 +1,712 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultCustomToolTimeout = 60 * time.Second
+	maxCustomToolTimeout     = 30 * time.Minute
+	maxCustomToolOutput      = 1024 * 1024
+)
+
+var (
+	customToolNamePattern   = regexp.MustCompile(`^[a-z][a-z0-9_]{1,63}$`)
+	customPlaceholderRegexp = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+)
+
+// CustomToolDefinition is a tool declared in the workspace (tools/*.yaml or a
+// markdown file with YAML frontmatter). Exactly one of Command, Script, or
+// HTTP describes how it runs; {{param}} placeholders are filled from the
+// call's parameters and escaped for their position.
+type CustomToolDefinition struct {
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description"`
+	Parameters  map[string]interface{} `yaml:"parameters"` // JSON Schema object
+	Command     string                 `yaml:"command"`    // bash command; values are shell-quoted
+	Script      string                 `yaml:"script"`     // script path relative to the definition
+	Args        []string               `yaml:"args"`       // script arguments, one value per element
+	HTTP        *CustomHTTPTemplate    `yaml:"http"`
+	WorkDir     string                 `yaml:"workdir"`  // working directory, no placeholders (default: workspace)
+	Timeout     string                 `yaml:"timeout"`  // e.g. "30s" (default 60s)
+	Approval    bool                   `yaml:"approval"` // ask the chat before every call
+
+	// Source is the definition file the tool was loaded from.
+	Source string `yaml:"-"`
+}
+
+// CustomHTTPTemplate describes an HTTP call. Values are URL-escaped in URL
+// and JSON-escaped in Body; ${VAR} in headers expands from the environment
+// so tokens stay out of the workspace.
+type CustomHTTPTemplate struct {
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"` // empty: non-GET requests send the parameters as a JSON object
+}
+
+// ParseCustomToolDefinition parses a definition from YAML, or from the
+// frontmatter of a markdown file whose body becomes the description when
+// none is set.
+func ParseCustomToolDefinition(data []byte, markdown bool) (*CustomToolDefinition, error) {
+	body := ""
+	if markdown {
+		front, rest, ok := splitFrontmatter(string(data))
+		if !ok {
+			return nil, fmt.Errorf("markdown tool definitions need YAML frontmatter")
+		}
+		data, body = []byte(front), strings.TrimSpace(rest)
+	}
+
+	var def CustomToolDefinition
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&def); err != nil {
+		return nil, fmt.Errorf("invalid definition: %w", err)
+	}
+	if def.Description == "" {
+		def.Description = body
+	}
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// Validate checks the definition and normalizes its parameter schema.
+func (d *CustomToolDefinition) Validate() error {
+	d.Name = strings.TrimSpace(d.Name)
+	d.Description = strings.TrimSpace(d.Description)
+	if !customToolNamePattern.MatchString(d.Name) {
+		return fmt.Errorf("invalid tool name %q: use lowercase letters, digits and underscores", d.Name)
+	}
+	if d.Description == "" {
+		return fmt.Errorf("%s: description is required", d.Name)
+	}
+
+	kinds := 0
+	if d.Command != "" {
+		kinds++
+	}
+	if d.Script != "" {
+		kinds++
+	}
+	if d.HTTP != nil {
+		kinds++
+	}
+	if kinds != 1 {
+		return fmt.Errorf("%s: set exactly one of command, script or http", d.Name)
+	}
+	if len(d.Args) > 0 && d.Script == "" {
+		return fmt.Errorf("%s: args are only used with script", d.Name)
+	}
+	if d.HTTP != nil {
+		d.HTTP.Method = strings.ToUpper(orDefault(strings.TrimSpace(d.HTTP.Method), "GET"))
+		if u, err := url.Parse(strings.ReplaceAll(d.HTTP.URL, "{{", "")); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("%s: http.url must be an http(s) URL", d.Name)
+		}
+	}
+	if d.Timeout != "" {
+		timeout, err := time.ParseDuration(d.Timeout)
+		if err != nil || timeout <= 0 || timeout > maxCustomToolTimeout {
+			return fmt.Errorf("%s: invalid timeout %q (max %s)", d.Name, d.Timeout, maxCustomToolTimeout)
+		}
+	}
+
+	if d.Parameters == nil {
+		d.Parameters = map[string]interface{}{}
+	}
+	if _, ok := d.Parameters["type"]; !ok {
+		d.Parameters["type"] = "object"
+	}
+	if d.Parameters["type"] != "object" {
+		return fmt.Errorf("%s: parameters must be a JSON Schema object", d.Name)
+	}
+	props, err := d.properties()
+	if err != nil {
+		return err
+	}
+	if _, ok := d.Parameters["properties"]; !ok {
+		d.Parameters["properties"] = map[string]interface{}{}
+	}
+	for _, name := range d.required() {
+		if _, ok := props[name]; !ok {
+			return fmt.Errorf("%s: required parameter %q is not declared", d.Name, name)
+		}
+	}
+
+	for _, tmpl := range d.templates() {
+		for _, m := range customPlaceholderRegexp.FindAllStringSubmatch(tmpl, -1) {
+			if _, ok := props[m[1]]; !ok {
+				return fmt.Errorf("%s: placeholder {{%s}} is not a declared parameter", d.Name, m[1])
+			}
+		}
+	}
+	if name := quotedPlaceholder(d.Command); name != "" {
+		return fmt.Errorf("%s: placeholder {{%s}} is inside quotes; values are quoted for you, so leave it bare or use $TOOL_%s", d.Name, name, strings.ToUpper(name))
+	}
+	return nil
+}
+
+// quotedPlaceholder returns the first placeholder in a bash command that sits
+// inside single or double quotes, where a shell-quoted value would close the
+// surrounding quotes instead of staying one word.
+func quotedPlaceholder(command string) string {
+	var quote byte
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case quote == c:
+			quote = 0
+		case c == '\\' && quote != '\'':
+			i++ // escaped character
+		case quote != 0 && c == '{':
+			if loc := customPlaceholderRegexp.FindStringSubmatchIndex(command[i:]); loc != nil && loc[0] == 0 {
+				return command[i+loc[2] : i+loc[3]]
+			}
+		}
+	}
+	return ""
+}
+
+// properties returns the declared parameter schemas.
+func (d *CustomToolDefinition) properties() (map[string]map[string]interface{}, error) {
+	out := map[string]map[string]interface{}{}
+	raw, ok := d.Parameters["properties"]
+	if !ok || raw == nil {
+		return out, nil
+	}
+	props, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: parameters.properties must be a mapping", d.Name)
+	}
+	for name, schema := range props {
+		s, ok := schema.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: parameter %q must be a schema mapping", d.Name, name)
+		}
+		out[name] = s
+	}
+	return out, nil
+}
+
+func (d *CustomToolDefinition) required() []string {
+	list, _ := d.Parameters["required"].([]interface{})
+	var out []string
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// templates returns every string that may contain placeholders.
+func (d *CustomToolDefinition) templates() []string {
+	out := []string{d.Command}
+	out = append(out, d.Args...)
+	if d.HTTP != nil {
+		out = append(out, d.HTTP.URL, d.HTTP.Body)
+		for _, v := range d.HTTP.Headers {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Kind reports how the tool runs: "command", "script" or "http".
+func (d *CustomToolDefinition) Kind() string {
+	switch {
+	case d.Script != "":
+		return "script"
+	case d.HTTP != nil:
+		return "http"
+	default:
+		return "command"
+	}
+}
+
+// CustomTool runs a workspace tool definition.
+type CustomTool struct {
+	def          *CustomToolDefinition
+	root         string // workspace root; commands run here by default
+	timeout      time.Duration
+	client       *http.Client
+	validateURL  func(rawURL string) error
+	ApprovalFunc func(command string) (bool, error)
+}
+
+// NewCustomTool creates a tool from a validated definition. root is the
+// workspace the definition belongs to.
+func NewCustomTool(def *CustomToolDefinition, root string) *CustomTool {
+	timeout := defaultCustomToolTimeout
+	if d, err := time.ParseDuration(def.Timeout); err == nil && d > 0 {
+		timeout = d
+	}
+	t := &CustomTool{
+		def:         def,
+		root:        root,
+		timeout:     timeout,
+		validateURL: validateURL,
+	}
+	t.client = &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects")
+			}
+			if err := t.validateURL(req.URL.String()); err != nil {
+				return fmt.Errorf("redirect blocked (SSRF): %w", err)
+			}
+			return checkNetworkAllowlist(req.Context(), t.Name(), req.URL.String())
+		},
+	}
+	return t
+}
+
+// Definition returns the tool's definition.
+func (t *CustomTool) Definition() *CustomToolDefinition {
+	return t.def
+}
+
+func (t *CustomTool) Name() string {
+	return t.def.Name
+}
+
+func (t *CustomTool) Description() string {
+	return t.def.Description
+}
+
+func (t *CustomTool) GetSchema() map[string]interface{} {
+	return t.def.Parameters
+}
+
+// ToolFamily places shell and script tools under the "local" estop family.
+func (t *CustomTool) ToolFamily() string {
+	if t.def.Kind() == "http" {
+		return ""
+	}
+	return "local"
+}
+
+// Capabilities maps the tool onto the agent capability policy.
+func (t *CustomTool) Capabilities() []string {
+	if t.def.Kind() == "http" {
+		return []string{"network"}
+	}
+	return []string{"shell"}
+}
+
+// WithApproval returns a copy of the tool that asks fn before each call.
+func (t *CustomTool) WithApproval(fn func(command string) (bool, error)) Tool {
+	clone := *t
+	clone.ApprovalFunc = fn
+	return &clone
+}
+
+func (t *CustomTool) Execute(ctx context.Context, args ...string) (string, error) {
+	params := map[string]string{}
+	props, _ := t.def.properties()
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// Positional args fill parameters in required-first, then alphabetical order.
+	order := append([]string{}, t.def.required()...)
+	for _, name := range names {
+		if !containsString(order, name) {
+			order = append(order, name)
+		}
+	}
+	for i, arg := range args {
+		if i >= len(order) {
+			return "", fmt.Errorf("%s takes at most %d arguments", t.Name(), len(order))
+		}
+		params[order[i]] = arg
+	}
+	return t.ExecuteJSON(ctx, params)
+}
+
+// ExecuteJSON validates params against the schema, renders the template and runs it.
+func (t *CustomTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	values, err := t.resolveParams(params)
+	if err != nil {
+		return "", err
+	}
+
+	if t.def.Approval {
+		summary := t.describeCall(values)
+		if t.ApprovalFunc == nil {
+			return "", &ToolDenial{
+				ToolName:    t.Name(),
+				Family:      "custom_tool",
+				Reason:      "this tool requires approval and no approval channel is available",
+				Remediation: "Run it from a Telegram chat so it can be approved.",
+			}
+		}
+		approved, err := t.ApprovalFunc(summary)
+		if err != nil {
+			return "", fmt.Errorf("approval check failed: %w", err)
+		}
+		if !approved {
+			return "Tool call denied by user", nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	switch t.def.Kind() {
+	case "http":
+		return t.runHTTP(ctx, values)
+	case "script":
+		return t.runScript(ctx, values)
+	default:
+		return t.runCommand(ctx, values)
+	}
+}
+
+// resolveParams applies defaults and checks required params, enums and types.
+func (t *CustomTool) resolveParams(params map[string]string) (map[string]string, error) {
+	props, err := t.def.properties()
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for name, schema := range props {
+		value, ok := params[name]
+		if !ok {
+			if def, has := schema["default"]; has {
+				value, ok = fmt.Sprintf("%v", def), true
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := checkCustomParam(name, value, schema); err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+	for name := range params {
+		if _, ok := props[name]; !ok {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	for _, name := range t.def.required() {
+		if _, ok := values[name]; !ok {
+			return nil, fmt.Errorf("missing required parameter %q", name)
+		}
+	}
+	return values, nil
+}
+
+func checkCustomParam(name, value string, schema map[string]interface{}) error {
+	switch schema["type"] {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("parameter %q must be an integer", name)
+		}
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("parameter %q must be a number", name)
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("parameter %q must be true or false", name)
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		for _, v := range enum {
+			if fmt.Sprintf("%v", v) == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("parameter %q must be one of %v", name, enum)
+	}
+	return nil
+}
+
+// describeCall renders the call for approval prompts.
+func (t *CustomTool) describeCall(values map[string]string) string {
+	switch t.def.Kind() {
+	case "http":
+		return fmt.Sprintf("%s: HTTP %s %s", t.Name(), t.def.HTTP.Method, renderToolTemplate(t.def.HTTP.URL, values, url.QueryEscape))
+	case "script":
+		args := make([]string, len(t.def.Args))
+		for i, a := range t.def.Args {
+			args[i] = shellQuote(renderToolTemplate(a, values, nil))
+		}
+		return strings.TrimSpace(fmt.Sprintf("%s: %s %s", t.Name(), t.def.Script, strings.Join(args, " ")))
+	default:
+		return fmt.Sprintf("%s: %s", t.Name(), renderToolTemplate(t.def.Command, values, shellQuote))
+	}
+}
+
+func (t *CustomTool) workDir() (string, error) {
+	if t.def.WorkDir == "" {
+		return t.root, nil
+	}
+	dir := t.def.WorkDir
+	if strings.HasPrefix(dir, "~/") {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, dir[2:])
+	}
+	if !filepath.IsAbs(dir) {
+		return resolvePath(t.root, dir)
+	}
+	return dir, nil
+}
+
+func (t *CustomTool) runCommand(ctx context.Context, values map[string]string) (string, error) {
+	dir, err := t.workDir()
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(ctx, "bash", "-c", renderToolTemplate(t.def.Command, values, shellQuote))
+	cmd.Dir = dir
+	cmd.Env = customToolEnv(values)
+	return runCustomProcess(cmd)
+}
+
+func (t *CustomTool) runScript(ctx context.Context, values map[string]string) (string, error) {
+	dir, err := t.workDir()
+	if err != nil {
+		return "", err
+	}
+	script := t.def.Script
+	if !filepath.IsAbs(script) {
+		script = filepath.Join(filepath.Dir(t.def.Source), script)
+	}
+	args := make([]string, 0, len(t.def.Args))
+	for _, a := range t.def.Args {
+		args = append(args, renderToolTemplate(a, values, nil))
+	}
+
+	// Scripts installed without the executable bit run through the
+	// interpreter their extension implies.
+	name := script
+	if info, err := os.Stat(script); err != nil {
+		return "", fmt.Errorf("script not found: %w", err)
+	} else if info.Mode()&0o111 == 0 {
+		interpreter, ok := scriptInterpreters[strings.ToLower(filepath.Ext(script))]
+		if !ok {
+			return "", fmt.Errorf("script %s is not executable", t.def.Script)
+		}
+		name = interpreter
+		args = append([]string{script}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = customToolEnv(values)
+	return runCustomProcess(cmd)
+}
+
+var scriptInterpreters = map[string]string{
+	".sh":   "bash",
+	".bash": "bash",
+	".py":   "python3",
+	".js":   "node",
+	".rb":   "ruby",
+	".pl":   "perl",
+}
+
+// customToolEnvPassthrough lists the parent environment variables workspace
+// commands and scripts may see. API keys and bot tokens are left out.
+var customToolEnvPassthrough = []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "LC_CTYPE", "TZ", "TMPDIR"}
+
+// customToolEnv builds a minimal environment and passes parameters to
+// processes as TOOL_<NAME> variables.
+func customToolEnv(values map[string]string) []string {
+	env := make([]string, 0, len(customToolEnvPassthrough)+len(values))
+	for _, name := range customToolEnvPassthrough {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	for name, value := range values {
+		env = append(env, "TOOL_"+strings.ToUpper(name)+"="+value)
+	}
+	return env
+}
+
+func runCustomProcess(cmd *exec.Cmd) (string, error) {
+	var out limitedBuffer
+	out.max = maxCustomToolOutput
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	result := out.String()
+	if out.truncated {
+		result += "\n[output truncated]"
+	}
+	return result, err
+}
+
+// limitedBuffer keeps the first max bytes written to it.
+type limitedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+func (t *CustomTool) runHTTP(ctx context.Context, values map[string]string) (string, error) {
+	spec := t.def.HTTP
+	rawURL := renderToolTemplate(spec.URL, values, url.QueryEscape)
+	if err := t.validateURL(rawURL); err != nil {
+		return "", err
+	}
+	if err := checkNetworkAllowlist(ctx, t.Name(), rawURL); err != nil {
+		return "", err
+	}
+
+	var body io.Reader
+	contentType := ""
+	switch {
+	case spec.Body != "":
+		body = strings.NewReader(renderToolTemplate(spec.Body, values, jsonEscape))
+	case spec.Method != "GET" && spec.Method != "HEAD" && len(values) > 0:
+		data, err := json.Marshal(values)
+		if err != nil {
+			return "", err
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, spec.Method, rawURL, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	// Expand ${VAR} in the definition before filling in parameters, so a
+	// parameter value can never name an environment variable to read.
+	for key, value := range spec.Headers {
+		value = renderToolTemplate(os.ExpandEnv(value), values, stripNewlines)
+		req.Header.Set(key, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCustomToolOutput+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	result := string(data)
+	if len(data) > maxCustomToolOutput {
+		result = string(data[:maxCustomToolOutput]) + "\n[output truncated]"
+	}
+	if resp.StatusCode >= 400 {
+		return result, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return result, nil
+}
+
+// renderTemplate replaces {{param}} placeholders with escape(value). Missing
+// optional params render as empty values.
+func renderToolTemplate(tmpl string, values map[string]string, escape func(string) string) string {
+	return customPlaceholderRegexp.ReplaceAllStringFunc(tmpl, func(m string) string {
+		name := customPlaceholderRegexp.FindStringSubmatch(m)[1]
+		value := values[name]
+		if escape != nil {
+			return escape(value)
+		}
+		return value
+	})
+}
+
+// shellQuote quotes s as a single bash word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// jsonEscape escapes s for use inside a JSON string literal.
+func jsonEscape(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data[1 : len(data)-1])
+}
+
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomToolSet holds the workspace tools currently loaded. It is swapped as
+// a whole on reload so runs in flight keep the set they started with.
+type CustomToolSet struct {
+	mu    sync.RWMutex
+	tools []Tool
+}
+
+// NewCustomToolSet creates an empty set.
+func NewCustomToolSet() *CustomToolSet {
+	return &CustomToolSet{}
+}
+
+// Replace swaps in a freshly loaded set of tools.
+func (s *CustomToolSet) Replace(tools []*CustomTool) {
+	list := make([]Tool, 0, len(tools))
+	for _, t := range tools {
+		list = append(list, t)
+	}
+	s.mu.Lock()
+	s.tools = list
+	s.mu.Unlock()
+}
+
+// List returns the loaded tools. A nil set has none.
+func (s *CustomToolSet) List() []Tool {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Tool, len(s.tools))
+	copy(out, s.tools)
+	return out
+}
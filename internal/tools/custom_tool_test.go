@@ -0,0 +1,259 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mustCustomTool(t *testing.T, yamlDef, root string) *CustomTool {
+	t.Helper()
+	def, err := ParseCustomToolDefinition([]byte(yamlDef), false)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	def.Source = filepath.Join(root, "tools", def.Name+".yaml")
+	tool := NewCustomTool(def, root)
+	tool.validateURL = func(string) error { return nil } // allow httptest servers on loopback
+	return tool
+}
+
+func TestParseCustomToolDefinitionValidates(t *testing.T) {
+	t.Parallel()
+
+	bad := map[string]string{
+		"bad name":            "name: Deploy\ndescription: x\ncommand: echo",
+		"no description":      "name: deploy\ncommand: echo",
+		"two kinds":           "name: deploy\ndescription: x\ncommand: echo\nscript: run.sh",
+		"unknown placeholder": "name: deploy\ndescription: x\ncommand: echo {{env}}",
+		"unknown field":       "name: deploy\ndescription: x\ncommand: echo\nshell: zsh",
+		"undeclared required": "name: deploy\ndescription: x\ncommand: echo\nparameters:\n  required: [env]",
+		"double-quoted value": "name: deploy\ndescription: x\nparameters:\n  properties:\n    env: {type: string}\ncommand: echo \"to {{env}}\"",
+		"single-quoted value": "name: deploy\ndescription: x\nparameters:\n  properties:\n    env: {type: string}\ncommand: echo 'to {{env}}'",
+		"bad url":             "name: deploy\ndescription: x\nhttp:\n  url: ftp://host/x",
+	}
+	for name, def := range bad {
+		if _, err := ParseCustomToolDefinition([]byte(def), false); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	md := "---\nname: uptime\ncommand: uptime\n---\nShow how long the host has been up.\n"
+	def, err := ParseCustomToolDefinition([]byte(md), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Description != "Show how long the host has been up." || def.Parameters["type"] != "object" {
+		t.Errorf("unexpected markdown definition: %+v", def)
+	}
+}
+
+func TestCustomToolCommandQuotesParameters(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	tool := mustCustomTool(t, `
+name: greet
+description: Greet someone
+parameters:
+  properties:
+    who: {type: string}
+    times: {type: integer, default: 2}
+  required: [who]
+command: "for i in $(seq {{times}}); do printf '%s|' {{who}}; done; echo $TOOL_WHO"
+`, root)
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"who": "a'b; touch pwned"})
+	if err != nil {
+		t.Fatalf("execute: %v (%s)", err, out)
+	}
+	if strings.TrimSpace(out) != "a'b; touch pwned|a'b; touch pwned|a'b; touch pwned" {
+		t.Errorf("unexpected output: %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(root, "pwned")); err == nil {
+		t.Fatal("parameter value was executed by the shell")
+	}
+
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"who": "x", "times": "two"}); err == nil {
+		t.Error("expected integer validation error")
+	}
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{}); err == nil {
+		t.Error("expected missing parameter error")
+	}
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"who": "x", "extra": "y"}); err == nil {
+		t.Error("expected unknown parameter error")
+	}
+}
+
+func TestCustomToolCommandEnvironmentDropsSecrets(t *testing.T) {
+	t.Setenv("TELEGRAM_BOT_TOKEN", "secret-token")
+
+	tool := mustCustomTool(t, `
+name: envcheck
+description: Print the environment
+parameters:
+  properties:
+    who: {type: string}
+command: echo "token=${TELEGRAM_BOT_TOKEN:-unset} who=$TOOL_WHO path=${PATH:+set}"
+`, t.TempDir())
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"who": "ann"})
+	if err != nil {
+		t.Fatalf("execute: %v (%s)", err, out)
+	}
+	if strings.TrimSpace(out) != "token=unset who=ann path=set" {
+		t.Errorf("unexpected environment: %q", out)
+	}
+}
+
+func TestCustomToolHTTPTemplate(t *testing.T) {
+	var gotQuery, gotAuth string
+	var gotBody map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("q")
+		gotAuth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte("created"))
+	}))
+	defer srv.Close()
+
+	t.Setenv("CUSTOM_TOOL_TEST_TOKEN", "secret")
+	tool := mustCustomTool(t, `
+name: ticket
+description: Open a ticket
+parameters:
+  properties:
+    title: {type: string}
+  required: [title]
+http:
+  method: post
+  url: `+srv.URL+`/tickets?q={{title}}
+  headers:
+    Authorization: Bearer ${CUSTOM_TOOL_TEST_TOKEN}
+  body: '{"title": "{{title}}"}'
+`, t.TempDir())
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"title": `disk "full" & more`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "created" || gotQuery != `disk "full" & more` || gotBody["title"] != `disk "full" & more` || gotAuth != "Bearer secret" {
+		t.Errorf("unexpected request: query=%q body=%v auth=%q out=%q", gotQuery, gotBody, gotAuth, out)
+	}
+
+	ctx := withNetworkAllowlist(context.Background(), []string{"example.com"})
+	var denial *ToolDenial
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"title": "x"}); !errors.As(err, &denial) {
+		t.Errorf("expected network allowlist denial, got %v", err)
+	}
+}
+
+func TestCustomToolHTTPHeadersDoNotExpandParams(t *testing.T) {
+	var gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("X-Title")
+	}))
+	defer srv.Close()
+
+	t.Setenv("CUSTOM_TOOL_TEST_SECRET", "leaked")
+	tool := mustCustomTool(t, `
+name: tag
+description: Tag a ticket
+parameters:
+  properties:
+    title: {type: string}
+http:
+  url: `+srv.URL+`/tag
+  headers:
+    X-Title: "{{title}}"
+`, t.TempDir())
+
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"title": "${CUSTOM_TOOL_TEST_SECRET}"}); err != nil {
+		t.Fatal(err)
+	}
+	if gotHeader != "${CUSTOM_TOOL_TEST_SECRET}" {
+		t.Errorf("X-Title = %q, want the parameter passed through literally", gotHeader)
+	}
+}
+
+func TestCustomToolHTTPBlocksPrivateURLs(t *testing.T) {
+	t.Parallel()
+
+	def, err := ParseCustomToolDefinition([]byte(`
+name: probe
+description: Probe a host
+parameters:
+  properties:
+    host: {type: string}
+http:
+  url: http://127.0.0.1/{{host}}
+`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := NewCustomTool(def, t.TempDir())
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"host": "admin"}); err == nil {
+		t.Fatal("expected SSRF check to block a loopback URL")
+	}
+}
+
+func TestCustomToolApprovalAndGuards(t *testing.T) {
+	t.Parallel()
+
+	tool := mustCustomTool(t, `
+name: restart
+description: Restart a service
+parameters:
+  properties:
+    service: {type: string, enum: [web, worker]}
+  required: [service]
+command: echo restarting {{service}}
+approval: true
+`, t.TempDir())
+
+	var denial *ToolDenial
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"service": "web"}); !errors.As(err, &denial) {
+		t.Fatalf("expected denial without approval channel, got %v", err)
+	}
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"service": "db"}); err == nil || errors.As(err, &denial) {
+		t.Fatalf("expected enum validation error, got %v", err)
+	}
+
+	var asked string
+	bound, ok := BindApproval(tool, func(command string) (bool, error) {
+		asked = command
+		return true, nil
+	})
+	if !ok {
+		t.Fatal("custom tool should accept an approval function")
+	}
+	out, err := bound.(*CustomTool).ExecuteJSON(context.Background(), map[string]string{"service": "web"})
+	if err != nil || strings.TrimSpace(out) != "restarting web" {
+		t.Fatalf("unexpected result %q, %v", out, err)
+	}
+	if asked != "restart: echo restarting 'web'" {
+		t.Errorf("unexpected approval prompt %q", asked)
+	}
+
+	// Shell-backed tools fall under the local estop family and the shell capability.
+	registry := NewRegistryWithEmergencyStop(stubEmergencyStopProvider{enabled: true})
+	registry.Register(bound)
+	guarded, _ := registry.Get("restart")
+	if _, err := guarded.(jsonExecutor).ExecuteJSON(context.Background(), map[string]string{"service": "web"}); !errors.As(err, &denial) || denial.Family != "local" {
+		t.Errorf("expected estop denial, got %v", err)
+	}
+
+	plain := NewRegistry()
+	plain.Register(tool)
+	restricted := ApplyPolicy(plain, &CapabilityPolicy{Network: true})
+	denied, _ := restricted.Get("restart")
+	if _, err := denied.Execute(context.Background(), "web"); !errors.As(err, &denial) || denial.Family != "shell" {
+		t.Errorf("expected shell capability denial, got %v", err)
+	}
+}
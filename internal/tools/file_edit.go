@@ -0,0 +1,451 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxFileListEntries = 500
+
+// customToolsDir is the workspace directory the custom tool loader reads
+// (bootstrap.CustomToolsDir).
+const customToolsDir = "tools"
+
+// checkCustomToolsWrite refuses a write under the workspace tools/
+// directory. Definitions there are hot-loaded and run commands and scripts
+// without approval, so only the operator may add or change them.
+func checkCustomToolsWrite(toolName, root, fullPath string) error {
+	if root == "" || fullPath == "" {
+		return nil
+	}
+	dir := filepath.Join(filepath.Clean(root), customToolsDir)
+	if fullPath != dir && !strings.HasPrefix(fullPath, dir+string(os.PathSeparator)) {
+		return nil
+	}
+	return &ToolDenial{
+		ToolName:    toolName,
+		Family:      "file_write",
+		Reason:      fmt.Sprintf("%s/ holds custom tool definitions and only the operator may change them", customToolsDir),
+		Remediation: "Ask the operator to add or edit the tool definition.",
+	}
+}
+
+// isFileWriteOp reports whether a file tool operation modifies the filesystem.
+func isFileWriteOp(op string) bool {
+	switch op {
+	case "write", "replace", "create", "delete", "move":
+		return true
+	}
+	return false
+}
+
+// ExecuteJSON runs a file operation with structured JSON parameters.
+func (f *FileTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	op := params["command"]
+	path := params["path"]
+
+	destination := ""
+	if op == "move" {
+		destination = params["destination"]
+	}
+	if err := f.checkpoint(ctx, op, path, destination); err != nil {
+		return "", err
+	}
+
+	switch op {
+	case "read":
+		start, end, err := parseLineRange(params["start_line"], params["end_line"])
+		if err != nil {
+			return "", err
+		}
+		if start == 0 && end == 0 {
+			return f.Read(path)
+		}
+		return f.ReadLines(path, start, end)
+	case "write":
+		if err := f.Write(path, params["content"]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Wrote %d bytes to %s", len(params["content"]), path), nil
+	case "list":
+		return f.List(path, params["pattern"])
+	case "replace":
+		return f.Replace(path, params["old_string"], params["new_string"], params["regex"] == "true", params["all"] == "true")
+	case "create":
+		return f.Create(path, params["content"])
+	case "delete":
+		return f.Delete(path)
+	case "move":
+		return f.Move(path, params["destination"])
+	case "":
+		return "", fmt.Errorf("command is required")
+	default:
+		return "", fmt.Errorf("unknown operation: %s", op)
+	}
+}
+
+// checkpoint snapshots the files a write operation is about to touch so the
+// run can be undone. Paths that fail to resolve are left for the operation
+// itself to reject.
+func (f *FileTool) checkpoint(ctx context.Context, op, path, destination string) error {
+	if !isFileWriteOp(op) {
+		return nil
+	}
+	if op == "move" {
+		src, err := resolvePath(f.BasePath, path)
+		if err != nil {
+			return nil
+		}
+		dst, err := resolvePath(f.BasePath, destination)
+		if err != nil {
+			return nil
+		}
+		return snapshotMove(ctx, "file", src, dst)
+	}
+	var paths []string
+	for _, p := range []string{path, destination} {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		if fullPath, err := resolvePath(f.BasePath, p); err == nil {
+			paths = append(paths, fullPath)
+		}
+	}
+	return snapshotFiles(ctx, "file", paths...)
+}
+
+func parseLineRange(startRaw, endRaw string) (int, int, error) {
+	var start, end int
+	var err error
+	if s := strings.TrimSpace(startRaw); s != "" {
+		if start, err = strconv.Atoi(s); err != nil || start < 1 {
+			return 0, 0, fmt.Errorf("invalid start_line: %q", startRaw)
+		}
+	}
+	if s := strings.TrimSpace(endRaw); s != "" {
+		if end, err = strconv.Atoi(s); err != nil || end < 1 {
+			return 0, 0, fmt.Errorf("invalid end_line: %q", endRaw)
+		}
+	}
+	if start > 0 && end > 0 && end < start {
+		return 0, 0, fmt.Errorf("end_line %d is before start_line %d", end, start)
+	}
+	return start, end, nil
+}
+
+// ReadLines returns lines start..end (1-based, inclusive) prefixed with their
+// line numbers. A zero start means the first line; a zero end means the last.
+func (f *FileTool) ReadLines(path string, start, end int) (string, error) {
+	content, err := f.Read(path)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	total := len(lines)
+	if start <= 0 {
+		start = 1
+	}
+	if end <= 0 || end > total {
+		end = total
+	}
+	if start > total {
+		return "", fmt.Errorf("start_line %d is past the end of %s (%d lines)", start, path, total)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (lines %d-%d of %d)\n", path, start, end, total)
+	for i := start; i <= end; i++ {
+		fmt.Fprintf(&sb, "%6d\t%s\n", i, lines[i-1])
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// List returns the entries of a directory. With a pattern, entries are
+// filtered by glob; patterns containing "/" or "**" match recursively
+// against paths relative to the directory.
+func (f *FileTool) List(path, pattern string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		path = "."
+	}
+	dir, err := resolvePath(f.BasePath, path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", path)
+	}
+	pattern = strings.TrimSpace(pattern)
+	if pattern != "" {
+		if _, err := filepath.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return "", fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+
+	var entries []string
+	truncated := false
+	if pattern != "" && (strings.Contains(pattern, "/") || strings.Contains(pattern, "**")) {
+		err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil || p == dir {
+				return nil
+			}
+			if d.IsDir() && skipListDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			rel, _ := filepath.Rel(dir, p)
+			rel = filepath.ToSlash(rel)
+			if !matchGlob(pattern, rel) {
+				return nil
+			}
+			if len(entries) >= maxFileListEntries {
+				truncated = true
+				return filepath.SkipAll
+			}
+			if d.IsDir() {
+				rel += "/"
+			}
+			entries = append(entries, rel)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	} else {
+		items, err := os.ReadDir(dir)
+		if err != nil {
+			return "", err
+		}
+		for _, item := range items {
+			if pattern != "" {
+				if ok, _ := filepath.Match(pattern, item.Name()); !ok {
+					continue
+				}
+			}
+			if len(entries) >= maxFileListEntries {
+				truncated = true
+				break
+			}
+			name := item.Name()
+			if item.IsDir() {
+				name += "/"
+			}
+			entries = append(entries, name)
+		}
+	}
+
+	if len(entries) == 0 {
+		return "No entries found", nil
+	}
+	sort.Strings(entries)
+	out := strings.Join(entries, "\n")
+	if truncated {
+		out += fmt.Sprintf("\n... (truncated at %d entries)", maxFileListEntries)
+	}
+	return out, nil
+}
+
+func skipListDir(name string) bool {
+	switch name {
+	case ".git", "node_modules", "vendor", "__pycache__":
+		return true
+	}
+	return false
+}
+
+// matchGlob matches a slash-separated path against a glob where "**"
+// matches any number of path segments.
+func matchGlob(pattern, name string) bool {
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlobSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// Replace substitutes oldText with newText in a file. Unless all is set, the
+// match must be unique so the edit lands exactly where the caller expects.
+// With useRegex, oldText is a Go regexp and newText may reference groups ($1).
+func (f *FileTool) Replace(path, oldText, newText string, useRegex, all bool) (string, error) {
+	if oldText == "" {
+		return "", fmt.Errorf("old_string is required")
+	}
+	fullPath, err := resolvePath(f.BasePath, path)
+	if err != nil {
+		return "", err
+	}
+	if err := checkCustomToolsWrite(f.Name(), f.BasePath, fullPath); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return "", err
+	}
+	content := string(data)
+
+	var updated string
+	var count, firstOffset int
+	if useRegex {
+		re, err := regexp.Compile(oldText)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex: %w", err)
+		}
+		matches := re.FindAllStringSubmatchIndex(content, -1)
+		count = len(matches)
+		if count > 0 {
+			firstOffset = matches[0][0]
+		}
+		if err := checkReplaceCount(path, count, all); err != nil {
+			return "", err
+		}
+		if all {
+			updated = re.ReplaceAllString(content, newText)
+		} else {
+			m := matches[0]
+			expanded := re.ExpandString(nil, newText, content, m)
+			updated = content[:m[0]] + string(expanded) + content[m[1]:]
+		}
+	} else {
+		count = strings.Count(content, oldText)
+		firstOffset = strings.Index(content, oldText)
+		if err := checkReplaceCount(path, count, all); err != nil {
+			return "", err
+		}
+		updated = strings.Replace(content, oldText, newText, -1)
+	}
+
+	if err := os.WriteFile(fullPath, []byte(updated), fileMode(fullPath)); err != nil {
+		return "", err
+	}
+	line := strings.Count(content[:firstOffset], "\n") + 1
+	if count == 1 {
+		return fmt.Sprintf("Replaced 1 occurrence in %s (line %d)", path, line), nil
+	}
+	return fmt.Sprintf("Replaced %d occurrences in %s (first at line %d)", count, path, line), nil
+}
+
+func checkReplaceCount(path string, count int, all bool) error {
+	switch {
+	case count == 0:
+		return fmt.Errorf("old_string not found in %s", path)
+	case count > 1 && !all:
+		return fmt.Errorf("old_string matches %d times in %s; include more surrounding context to make it unique or set all=true", count, path)
+	}
+	return nil
+}
+
+// Create writes a new file and fails if the path already exists.
+func (f *FileTool) Create(path, content string) (string, error) {
+	fullPath, err := resolvePath(f.BasePath, path)
+	if err != nil {
+		return "", err
+	}
+	if err := checkCustomToolsWrite(f.Name(), f.BasePath, fullPath); err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(fullPath); err == nil {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return "", err
+	}
+	file, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close() //nolint:errcheck
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Created %s (%d bytes)", path, len(content)), nil
+}
+
+// Delete removes a file or an empty directory. The workspace root itself
+// cannot be deleted.
+func (f *FileTool) Delete(path string) (string, error) {
+	fullPath, err := resolvePath(f.BasePath, path)
+	if err != nil {
+		return "", err
+	}
+	if f.BasePath != "" && filepath.Clean(fullPath) == filepath.Clean(f.BasePath) {
+		return "", fmt.Errorf("refusing to delete the workspace root")
+	}
+	if err := checkCustomToolsWrite(f.Name(), f.BasePath, fullPath); err != nil {
+		return "", err
+	}
+	if err := os.Remove(fullPath); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Deleted %s", path), nil
+}
+
+// Move renames a file or directory. Both ends must stay inside the workspace
+// and the destination must not exist.
+func (f *FileTool) Move(path, destination string) (string, error) {
+	if strings.TrimSpace(destination) == "" {
+		return "", fmt.Errorf("destination is required")
+	}
+	src, err := resolvePath(f.BasePath, path)
+	if err != nil {
+		return "", err
+	}
+	dst, err := resolvePath(f.BasePath, destination)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range []string{src, dst} {
+		if err := checkCustomToolsWrite(f.Name(), f.BasePath, p); err != nil {
+			return "", err
+		}
+	}
+	if _, err := os.Lstat(src); err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return "", fmt.Errorf("%s already exists", destination)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Moved %s to %s", path, destination), nil
+}
+
+// fileMode returns the existing permissions of path, or 0644.
+func fileMode(path string) os.FileMode {
+	if info, err := os.Stat(path); err == nil {
+		return info.Mode().Perm()
+	}
+	return 0644
+}
@@ -0,0 +1,196 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileTool_ReadLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "f.txt"), []byte("a\nb\nc\nd\n"), 0644) //nolint:errcheck
+	tool := &FileTool{BasePath: tmpDir}
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"command": "read", "path": "f.txt", "start_line": "2", "end_line": "3"})
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	want := "f.txt (lines 2-3 of 4)\n     2\tb\n     3\tc"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	// Positional form and open-ended range.
+	out, err = tool.Execute(context.Background(), "read", "f.txt", "4")
+	if err != nil || !strings.HasSuffix(out, "     4\td") {
+		t.Errorf("positional read = %q, %v", out, err)
+	}
+
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"command": "read", "path": "f.txt", "start_line": "9"}); err == nil {
+		t.Error("expected error for start past end of file")
+	}
+}
+
+func TestFileTool_List(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, p := range []string{"a.go", "b.txt", "pkg/c.go", "pkg/sub/d.go", ".git/config"} {
+		full := filepath.Join(tmpDir, p)
+		os.MkdirAll(filepath.Dir(full), 0755) //nolint:errcheck
+		os.WriteFile(full, []byte("x"), 0644) //nolint:errcheck
+	}
+	tool := &FileTool{BasePath: tmpDir}
+
+	out, err := tool.List(".", "")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if out != ".git/\na.go\nb.txt\npkg/" {
+		t.Errorf("list = %q", out)
+	}
+
+	out, err = tool.List(".", "*.go")
+	if err != nil || out != "a.go" {
+		t.Errorf("glob = %q, %v", out, err)
+	}
+
+	out, err = tool.List(".", "**/*.go")
+	if err != nil || out != "a.go\npkg/c.go\npkg/sub/d.go" {
+		t.Errorf("recursive glob = %q, %v", out, err)
+	}
+
+	if _, err := tool.List("../", ""); err == nil {
+		t.Error("expected listing outside the workspace to fail")
+	}
+}
+
+func TestFileTool_Replace(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "f.go")
+	reset := func() {
+		os.WriteFile(path, []byte("x := 1\ny := 1\nz := 2\n"), 0644) //nolint:errcheck
+	}
+	tool := &FileTool{BasePath: tmpDir}
+	ctx := context.Background()
+
+	reset()
+	_, err := tool.ExecuteJSON(ctx, map[string]string{"command": "replace", "path": "f.go", "old_string": ":= 1", "new_string": ":= 3"})
+	if err == nil || !strings.Contains(err.Error(), "matches 2 times") {
+		t.Fatalf("expected uniqueness error, got %v", err)
+	}
+
+	out, err := tool.ExecuteJSON(ctx, map[string]string{"command": "replace", "path": "f.go", "old_string": "y := 1", "new_string": "y := 3"})
+	if err != nil || !strings.Contains(out, "line 2") {
+		t.Fatalf("unique replace = %q, %v", out, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "x := 1\ny := 3\nz := 2\n" {
+		t.Errorf("content = %q", data)
+	}
+
+	reset()
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"command": "replace", "path": "f.go", "old_string": ":= 1", "new_string": ":= 0", "all": "true"}); err != nil {
+		t.Fatalf("replace all: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "x := 0\ny := 0\nz := 2\n" {
+		t.Errorf("content = %q", data)
+	}
+
+	reset()
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"command": "replace", "path": "f.go", "old_string": `z := (\d)`, "new_string": "z := $1$1", "regex": "true"}); err != nil {
+		t.Fatalf("regex replace: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "x := 1\ny := 1\nz := 22\n" {
+		t.Errorf("content = %q", data)
+	}
+
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"command": "replace", "path": "f.go", "old_string": "nope", "new_string": ""}); err == nil {
+		t.Error("expected not-found error")
+	}
+}
+
+func TestFileTool_CreateDeleteMove(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := &FileTool{BasePath: tmpDir}
+	ctx := context.Background()
+
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"command": "create", "path": "dir/new.txt", "content": "hi"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"command": "create", "path": "dir/new.txt", "content": "again"}); err == nil {
+		t.Error("create must not overwrite existing files")
+	}
+
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"command": "move", "path": "dir/new.txt", "destination": "other/moved.txt"}); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "other", "moved.txt")); string(data) != "hi" {
+		t.Errorf("moved content = %q", data)
+	}
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"command": "move", "path": "other/moved.txt", "destination": "../escape.txt"}); err == nil {
+		t.Error("expected move outside workspace to fail")
+	}
+
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"command": "delete", "path": "other/moved.txt"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "other", "moved.txt")); !os.IsNotExist(err) {
+		t.Errorf("file still exists: %v", err)
+	}
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"command": "delete", "path": "."}); err == nil {
+		t.Error("expected deleting the workspace root to fail")
+	}
+}
+
+func TestFileAndPatchToolsRefuseCustomToolDefinitions(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tmpDir, "tools"), 0755)                                           //nolint:errcheck
+	os.WriteFile(filepath.Join(tmpDir, "tools", "ping.yaml"), []byte("name: ping\n"), 0644)     //nolint:errcheck
+	os.WriteFile(filepath.Join(tmpDir, "evil.yaml"), []byte("name: evil\ncommand: id\n"), 0644) //nolint:errcheck
+	file := &FileTool{BasePath: tmpDir}
+	ctx := context.Background()
+
+	for _, params := range []map[string]string{
+		{"command": "write", "path": "tools/evil.yaml", "content": "command: id"},
+		{"command": "create", "path": "tools/evil.yaml", "content": "command: id"},
+		{"command": "replace", "path": "tools/ping.yaml", "old_string": "ping", "new_string": "pong"},
+		{"command": "delete", "path": "tools/ping.yaml"},
+		{"command": "move", "path": "evil.yaml", "destination": "tools/evil.yaml"},
+	} {
+		_, err := file.ExecuteJSON(ctx, params)
+		if denial, ok := IsToolDenial(err); !ok || denial.Family != "file_write" {
+			t.Errorf("%s: expected file_write denial, got %v", params["command"], err)
+		}
+	}
+	if _, err := file.ExecuteJSON(ctx, map[string]string{"command": "read", "path": "tools/ping.yaml"}); err != nil {
+		t.Errorf("read under tools/ failed: %v", err)
+	}
+
+	diff := "--- /dev/null\n+++ b/tools/evil.yaml\n@@ -0,0 +1 @@\n+command: id\n"
+	_, err := NewPatchTool(tmpDir).Execute(ctx, diff)
+	if _, ok := IsToolDenial(err); !ok {
+		t.Errorf("patch: expected denial, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "tools", "evil.yaml")); !os.IsNotExist(err) {
+		t.Errorf("tools/evil.yaml was written: %v", err)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"**/*.go", "a.go", true},
+		{"**/*.go", "x/y/a.go", true},
+		{"pkg/*.go", "pkg/a.go", true},
+		{"pkg/*.go", "pkg/sub/a.go", false},
+		{"pkg/**", "pkg/sub/a.go", true},
+		{"*.go", "a.txt", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,837 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ok-gobot/internal/runtime"
+)
+
+const (
+	defaultGitOutputBytes = 20000       // characters returned to the model
+	maxGitOutputBytes     = 200000      // hard cap for max_bytes
+	maxGitArtifactBytes   = 1024 * 1024 // full diffs stored as job artifacts
+	defaultGitLogLimit    = 20
+	maxGitLogLimit        = 200
+	gitCommandTimeout     = 60 * time.Second
+)
+
+// GitTool runs structured git operations inside a single repository root.
+// Every path argument is resolved against the root, so the agent cannot reach
+// repositories outside its workspace.
+type GitTool struct {
+	RepoPath            string
+	AllowHistoryRewrite bool // permit force-push, amend, and ref-moving resets
+	ReadOnly            bool // refuse actions that change the worktree, index, refs, or remotes
+	ApprovalFunc        func(command string) (bool, error)
+}
+
+// NewGitTool creates a git tool scoped to repoPath.
+func NewGitTool(repoPath string) *GitTool {
+	return &GitTool{RepoPath: repoPath}
+}
+
+func (g *GitTool) Name() string {
+	return "git"
+}
+
+func (g *GitTool) Description() string {
+	return "Run git inside the workspace: status, diff, log, show, branch, checkout, add, commit, stash, worktree, reset, push. " +
+		"Diffs are size-capped; the full diff is saved as a job artifact. Pushing requires user approval."
+}
+
+// WithApproval returns a copy of the tool that asks fn before pushing.
+func (g *GitTool) WithApproval(fn func(command string) (bool, error)) Tool {
+	clone := *g
+	clone.ApprovalFunc = fn
+	return &clone
+}
+
+// WithRoot returns a copy of the tool scoped to a different repository root.
+func (g *GitTool) WithRoot(root string) *GitTool {
+	clone := *g
+	clone.RepoPath = root
+	return &clone
+}
+
+// WithHistoryRewrite returns a copy of the tool that permits force-push and
+// history rewrites.
+func (g *GitTool) WithHistoryRewrite() Tool {
+	clone := *g
+	clone.AllowHistoryRewrite = true
+	return &clone
+}
+
+// WithReadOnly returns a copy of the tool that only inspects the repository.
+func (g *GitTool) WithReadOnly() Tool {
+	clone := *g
+	clone.ReadOnly = true
+	return &clone
+}
+
+// Root returns the absolute repository root the tool is scoped to.
+func (g *GitTool) Root() string {
+	if abs, err := filepath.Abs(g.RepoPath); err == nil {
+		return abs
+	}
+	return filepath.Clean(g.RepoPath)
+}
+
+// GetSchema returns the JSON Schema for git tool parameters.
+func (g *GitTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"description": "Git operation to run",
+				"enum":        []string{"status", "diff", "log", "show", "branch", "checkout", "add", "commit", "stash", "worktree", "reset", "push"},
+			},
+			"paths": map[string]interface{}{
+				"type":        "array",
+				"description": "Path filters relative to the repository root (diff, log, show, add, checkout, reset)",
+				"items":       map[string]interface{}{"type": "string"},
+			},
+			"ref": map[string]interface{}{
+				"type":        "string",
+				"description": "Commit, branch, or range (diff base, log start, show target, checkout target, branch start point, reset target)",
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Branch name (branch, checkout with create) or worktree branch",
+			},
+			"message": map[string]interface{}{
+				"type":        "string",
+				"description": "Commit or stash message",
+			},
+			"subaction": map[string]interface{}{
+				"type":        "string",
+				"description": "stash: push|pop|apply|list|drop; worktree: add|remove|list; branch: list|create|delete; reset: soft|mixed|hard",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Worktree directory (relative to the repository root)",
+			},
+			"staged": map[string]interface{}{
+				"type":        "boolean",
+				"description": "diff: compare the index instead of the working tree",
+			},
+			"stat": map[string]interface{}{
+				"type":        "boolean",
+				"description": "diff/show: only print the diffstat",
+			},
+			"create": map[string]interface{}{
+				"type":        "boolean",
+				"description": "checkout: create the branch given in name",
+			},
+			"all": map[string]interface{}{
+				"type":        "boolean",
+				"description": "add: stage all changes; commit: stage tracked changes before committing",
+			},
+			"amend": map[string]interface{}{
+				"type":        "boolean",
+				"description": "commit: amend the previous commit (history rewrite)",
+			},
+			"force": map[string]interface{}{
+				"type":        "boolean",
+				"description": "push/worktree remove: force (history rewrite, usually refused)",
+			},
+			"remote": map[string]interface{}{
+				"type":        "string",
+				"description": "push: remote name (default origin)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "log: number of commits (default 20, max 200)",
+			},
+			"max_bytes": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum output characters returned (default 20000)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// gitParams is the parsed form of one git invocation.
+type gitParams struct {
+	Action    string
+	Subaction string
+	Paths     []string
+	Ref       string
+	Name      string
+	Message   string
+	Path      string
+	Remote    string
+	Staged    bool
+	Stat      bool
+	Create    bool
+	All       bool
+	Amend     bool
+	Force     bool
+	Limit     int
+	MaxBytes  int
+}
+
+// Execute runs git with positional args: <action> [paths...]. Commit takes
+// the message as the remaining args.
+func (g *GitTool) Execute(ctx context.Context, args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: git <action> [args...]")
+	}
+	p := gitParams{Action: args[0]}
+	rest := args[1:]
+	switch p.Action {
+	case "commit":
+		p.Message = strings.Join(rest, " ")
+	case "show", "checkout":
+		if len(rest) > 0 {
+			p.Ref = rest[0]
+			p.Paths = rest[1:]
+		}
+	case "branch":
+		if len(rest) > 0 {
+			p.Subaction = "create"
+			p.Name = rest[0]
+		}
+	case "stash", "worktree":
+		if len(rest) > 0 {
+			p.Subaction = rest[0]
+		}
+		if len(rest) > 1 {
+			p.Path = rest[1]
+		}
+	default:
+		p.Paths = rest
+	}
+	return g.run(ctx, p)
+}
+
+// ExecuteJSON runs git with structured JSON parameters.
+func (g *GitTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	p := gitParams{
+		Action:    params["action"],
+		Subaction: params["subaction"],
+		Ref:       params["ref"],
+		Name:      params["name"],
+		Message:   params["message"],
+		Path:      params["path"],
+		Remote:    params["remote"],
+		Staged:    params["staged"] == "true",
+		Stat:      params["stat"] == "true",
+		Create:    params["create"] == "true",
+		All:       params["all"] == "true",
+		Amend:     params["amend"] == "true",
+		Force:     params["force"] == "true",
+	}
+	paths, err := parseGitPaths(params["paths"])
+	if err != nil {
+		return "", err
+	}
+	p.Paths = paths
+	for key, dst := range map[string]*int{"limit": &p.Limit, "max_bytes": &p.MaxBytes} {
+		raw := strings.TrimSpace(params[key])
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s: %w", key, err)
+		}
+		*dst = n
+	}
+	return g.run(ctx, p)
+}
+
+// parseGitPaths accepts a JSON array or a whitespace/comma separated list.
+func parseGitPaths(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(raw, "[") {
+		var paths []string
+		if err := json.Unmarshal([]byte(raw), &paths); err != nil {
+			return nil, fmt.Errorf("paths must be an array of strings: %w", err)
+		}
+		return paths, nil
+	}
+	return strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' }), nil
+}
+
+func (g *GitTool) run(ctx context.Context, p gitParams) (string, error) {
+	if strings.TrimSpace(g.RepoPath) == "" {
+		return "", fmt.Errorf("git tool has no repository root configured")
+	}
+	for _, v := range []string{p.Ref, p.Name, p.Remote} {
+		if strings.HasPrefix(v, "-") {
+			return "", fmt.Errorf("invalid argument %q: options are not accepted here", v)
+		}
+	}
+	paths, err := g.relativePaths(p.Paths)
+	if err != nil {
+		return "", err
+	}
+	if g.ReadOnly && gitMutates(p) {
+		return "", &ToolDenial{
+			ToolName:    g.Name(),
+			Family:      "file_write",
+			Reason:      fmt.Sprintf("git %s denied by agent policy (read-only mode)", strings.TrimSpace(p.Action+" "+p.Subaction)),
+			Remediation: "Ask the operator to set file_write_scope to \"full\".",
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(p.Action)) {
+	case "status":
+		return g.git(ctx, p.MaxBytes, "status", "--short", "--branch")
+	case "diff":
+		return g.diff(ctx, p, paths)
+	case "log":
+		limit := p.Limit
+		if limit <= 0 {
+			limit = defaultGitLogLimit
+		}
+		if limit > maxGitLogLimit {
+			limit = maxGitLogLimit
+		}
+		args := []string{"log", "--no-color", "--date=short", "--pretty=format:%h %ad %an  %s", "-n", strconv.Itoa(limit)}
+		if p.Ref != "" {
+			args = append(args, p.Ref)
+		}
+		return g.git(ctx, p.MaxBytes, withPathspec(args, paths)...)
+	case "show":
+		ref := p.Ref
+		if ref == "" {
+			ref = "HEAD"
+		}
+		args := []string{"show", "--no-color", "--no-ext-diff", "--no-textconv", "--stat"}
+		if !p.Stat {
+			args = append(args, "--patch")
+		}
+		return g.git(ctx, p.MaxBytes, withPathspec(append(args, ref), paths)...)
+	case "branch":
+		return g.branch(ctx, p)
+	case "checkout":
+		return g.checkout(ctx, p, paths)
+	case "add":
+		if p.All {
+			return g.gitOK(ctx, "Staged all changes", "add", "-A")
+		}
+		if len(paths) == 0 {
+			return "", fmt.Errorf("add requires paths or all=true")
+		}
+		return g.gitOK(ctx, "Staged "+strings.Join(paths, ", "), withPathspec([]string{"add"}, paths)...)
+	case "commit":
+		return g.commit(ctx, p)
+	case "stash":
+		return g.stash(ctx, p)
+	case "worktree":
+		return g.worktree(ctx, p)
+	case "reset":
+		return g.reset(ctx, p, paths)
+	case "push":
+		return g.push(ctx, p)
+	case "":
+		return "", fmt.Errorf("action is required")
+	default:
+		return "", fmt.Errorf("unknown git action: %s", p.Action)
+	}
+}
+
+// gitMutates reports whether an invocation changes the worktree, index, refs,
+// or a remote. Only status, diff, log, show and the list subactions are reads.
+func gitMutates(p gitParams) bool {
+	sub := strings.ToLower(strings.TrimSpace(p.Subaction))
+	switch strings.ToLower(strings.TrimSpace(p.Action)) {
+	case "status", "diff", "log", "show", "":
+		return false
+	case "branch":
+		return sub == "create" || sub == "delete" || (sub == "" && p.Name != "")
+	case "stash":
+		return sub != "list"
+	case "worktree":
+		return sub != "" && sub != "list"
+	default:
+		return true
+	}
+}
+
+// relativePaths validates path filters against the repository root and
+// returns them relative to it.
+func (g *GitTool) relativePaths(paths []string) ([]string, error) {
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		full, err := resolvePath(g.RepoPath, p)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(filepath.Clean(g.RepoPath), full)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rel)
+	}
+	return out, nil
+}
+
+func withPathspec(args, paths []string) []string {
+	if len(paths) == 0 {
+		return args
+	}
+	return append(append(args, "--"), paths...)
+}
+
+func (g *GitTool) diff(ctx context.Context, p gitParams, paths []string) (string, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--no-textconv"}
+	if p.Staged {
+		args = append(args, "--cached")
+	}
+	if p.Stat {
+		args = append(args, "--stat")
+	}
+	if p.Ref != "" {
+		args = append(args, p.Ref)
+	}
+	args = withPathspec(args, paths)
+
+	out, err := g.exec(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(out) == "" {
+		return "No changes", nil
+	}
+
+	result := capGitOutput(out, p.MaxBytes)
+	if !p.Stat {
+		content := out
+		if len(content) > maxGitArtifactBytes {
+			content = content[:maxGitArtifactBytes]
+		}
+		jobID, err := runtime.AddContextArtifact(ctx, runtime.JobArtifactSpec{
+			Name:     "git.diff",
+			Type:     "diff",
+			MimeType: "text/x-diff",
+			Content:  content,
+			Metadata: map[string]any{
+				"ref":    p.Ref,
+				"staged": p.Staged,
+				"paths":  paths,
+				"bytes":  len(out),
+			},
+		})
+		if err != nil {
+			result += fmt.Sprintf("\n\n(failed to save diff artifact: %v)", err)
+		} else if jobID != "" {
+			result += fmt.Sprintf("\n\n(full diff saved as artifact git.diff on job %s)", jobID)
+		}
+	}
+	return result, nil
+}
+
+func (g *GitTool) branch(ctx context.Context, p gitParams) (string, error) {
+	sub := p.Subaction
+	if sub == "" {
+		sub = "list"
+		if p.Name != "" {
+			sub = "create"
+		}
+	}
+	switch sub {
+	case "list":
+		return g.git(ctx, p.MaxBytes, "branch", "--list", "-vv", "--no-color")
+	case "create":
+		if p.Name == "" {
+			return "", fmt.Errorf("branch create requires name")
+		}
+		args := []string{"branch", p.Name}
+		if p.Ref != "" {
+			args = append(args, p.Ref)
+		}
+		return g.gitOK(ctx, "Created branch "+p.Name, args...)
+	case "delete":
+		if p.Name == "" {
+			return "", fmt.Errorf("branch delete requires name")
+		}
+		flag := "-d"
+		if p.Force {
+			if err := g.checkRewrite("force-delete an unmerged branch"); err != nil {
+				return "", err
+			}
+			flag = "-D"
+		}
+		return g.gitOK(ctx, "Deleted branch "+p.Name, "branch", flag, p.Name)
+	default:
+		return "", fmt.Errorf("unknown branch subaction: %s (use list, create, delete)", sub)
+	}
+}
+
+func (g *GitTool) checkout(ctx context.Context, p gitParams, paths []string) (string, error) {
+	if p.Create {
+		if p.Name == "" {
+			return "", fmt.Errorf("checkout with create requires name")
+		}
+		args := []string{"checkout", "-b", p.Name}
+		if p.Ref != "" {
+			args = append(args, p.Ref)
+		}
+		return g.git(ctx, p.MaxBytes, args...)
+	}
+	target := p.Ref
+	if target == "" {
+		target = p.Name
+	}
+	if len(paths) > 0 {
+		if target == "" {
+			target = "HEAD"
+		}
+		return g.gitOK(ctx, "Restored "+strings.Join(paths, ", ")+" from "+target,
+			withPathspec([]string{"checkout", target}, paths)...)
+	}
+	if target == "" {
+		return "", fmt.Errorf("checkout requires ref, name, or paths")
+	}
+	return g.git(ctx, p.MaxBytes, "checkout", target)
+}
+
+func (g *GitTool) commit(ctx context.Context, p gitParams) (string, error) {
+	if p.Amend {
+		if err := g.checkRewrite("amend a commit"); err != nil {
+			return "", err
+		}
+	}
+	if strings.TrimSpace(p.Message) == "" && !p.Amend {
+		return "", fmt.Errorf("commit requires message")
+	}
+	args := []string{"commit"}
+	if p.All {
+		args = append(args, "-a")
+	}
+	if p.Amend {
+		args = append(args, "--amend")
+		if strings.TrimSpace(p.Message) == "" {
+			args = append(args, "--no-edit")
+		}
+	}
+	if strings.TrimSpace(p.Message) != "" {
+		args = append(args, "-m", p.Message)
+	}
+	return g.git(ctx, p.MaxBytes, args...)
+}
+
+func (g *GitTool) stash(ctx context.Context, p gitParams) (string, error) {
+	sub := p.Subaction
+	if sub == "" {
+		sub = "push"
+	}
+	switch sub {
+	case "push":
+		args := []string{"stash", "push", "--include-untracked"}
+		if p.Message != "" {
+			args = append(args, "-m", p.Message)
+		}
+		return g.git(ctx, p.MaxBytes, args...)
+	case "list":
+		return g.git(ctx, p.MaxBytes, "stash", "list")
+	case "pop", "apply", "drop":
+		args := []string{"stash", sub}
+		if p.Ref != "" {
+			args = append(args, p.Ref)
+		}
+		return g.git(ctx, p.MaxBytes, args...)
+	default:
+		return "", fmt.Errorf("unknown stash subaction: %s (use push, pop, apply, list, drop)", sub)
+	}
+}
+
+func (g *GitTool) worktree(ctx context.Context, p gitParams) (string, error) {
+	sub := p.Subaction
+	if sub == "" {
+		sub = "list"
+	}
+	if sub == "list" {
+		return g.git(ctx, p.MaxBytes, "worktree", "list")
+	}
+	if strings.TrimSpace(p.Path) == "" {
+		return "", fmt.Errorf("worktree %s requires path", sub)
+	}
+	dir, err := resolvePath(g.RepoPath, p.Path)
+	if err != nil {
+		return "", err
+	}
+	switch sub {
+	case "add":
+		args := []string{"worktree", "add"}
+		if p.Name != "" {
+			args = append(args, "-b", p.Name)
+		}
+		args = append(args, dir)
+		if p.Ref != "" {
+			args = append(args, p.Ref)
+		}
+		return g.git(ctx, p.MaxBytes, args...)
+	case "remove":
+		args := []string{"worktree", "remove"}
+		if p.Force {
+			if err := g.checkRewrite("force-remove a worktree with local changes"); err != nil {
+				return "", err
+			}
+			args = append(args, "--force")
+		}
+		return g.gitOK(ctx, "Removed worktree "+p.Path, append(args, dir)...)
+	default:
+		return "", fmt.Errorf("unknown worktree subaction: %s (use add, remove, list)", sub)
+	}
+}
+
+func (g *GitTool) reset(ctx context.Context, p gitParams, paths []string) (string, error) {
+	// Unstaging paths leaves history untouched.
+	if len(paths) > 0 && p.Subaction == "" {
+		args := []string{"reset", "-q"}
+		if p.Ref != "" {
+			args = append(args, p.Ref)
+		}
+		return g.gitOK(ctx, "Unstaged "+strings.Join(paths, ", "), withPathspec(args, paths)...)
+	}
+
+	mode := p.Subaction
+	if mode == "" {
+		mode = "mixed"
+	}
+	switch mode {
+	case "soft", "mixed", "hard":
+	default:
+		return "", fmt.Errorf("unknown reset mode: %s (use soft, mixed, hard)", mode)
+	}
+	if p.Ref != "" || mode == "hard" {
+		if err := g.checkRewrite("reset --" + mode + " " + p.Ref); err != nil {
+			return "", err
+		}
+	}
+	args := []string{"reset", "--" + mode}
+	if p.Ref != "" {
+		args = append(args, p.Ref)
+	}
+	return g.git(ctx, p.MaxBytes, args...)
+}
+
+func (g *GitTool) push(ctx context.Context, p gitParams) (string, error) {
+	if p.Force {
+		if err := g.checkRewrite("force-push"); err != nil {
+			return "", err
+		}
+	}
+	remote := p.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	if strings.HasPrefix(remote, "-") || strings.HasPrefix(p.Name, "-") {
+		return "", fmt.Errorf("invalid push target %q %q", remote, p.Name)
+	}
+	// A "+" refspec forces the update and ":dst" deletes the remote ref;
+	// both rewrite published history like --force does.
+	if strings.HasPrefix(p.Name, "+") {
+		if err := g.checkRewrite("force-push " + p.Name); err != nil {
+			return "", err
+		}
+	}
+	if strings.HasPrefix(p.Name, ":") {
+		if err := g.checkRewrite("delete remote ref " + strings.TrimPrefix(p.Name, ":")); err != nil {
+			return "", err
+		}
+	}
+	args := []string{"push"}
+	if p.Force {
+		args = append(args, "--force-with-lease")
+	}
+	args = append(args, remote)
+	if p.Name != "" {
+		args = append(args, p.Name)
+	}
+
+	if g.ApprovalFunc == nil {
+		return "", &ToolDenial{
+			ToolName:    g.Name(),
+			Family:      "git_push",
+			Reason:      "push requires user approval and this run has no chat to ask",
+			Remediation: "Run the task from a Telegram chat so the push can be approved.",
+		}
+	}
+	approved, err := g.ApprovalFunc("git " + strings.Join(args, " "))
+	if err != nil {
+		return "", fmt.Errorf("approval check failed: %w", err)
+	}
+	if !approved {
+		return "Push denied by user", nil
+	}
+	// remote.<name>.receivepack is a list that -c cannot replace, so the
+	// remote's configured program is overridden on the command line.
+	args = append([]string{"push", "--receive-pack=git-receive-pack"}, args[1:]...)
+	return g.git(ctx, p.MaxBytes, args...)
+}
+
+// checkRewrite refuses operations that rewrite published history unless the
+// capability policy allows it.
+func (g *GitTool) checkRewrite(what string) error {
+	if g.AllowHistoryRewrite {
+		return nil
+	}
+	return &ToolDenial{
+		ToolName:    g.Name(),
+		Family:      "git_rewrite",
+		Reason:      "refusing to " + strings.TrimSpace(what) + ": force-push and history rewrite are disabled",
+		Remediation: "Set `capabilities.git_history_rewrite: true` on the agent to allow it.",
+	}
+}
+
+// git runs a command and returns its capped output.
+func (g *GitTool) git(ctx context.Context, maxBytes int, args ...string) (string, error) {
+	out, err := g.exec(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(out) == "" {
+		return "OK", nil
+	}
+	return capGitOutput(out, maxBytes), nil
+}
+
+// gitOK runs a command that is usually silent and reports summary on success.
+func (g *GitTool) gitOK(ctx context.Context, summary string, args ...string) (string, error) {
+	out, err := g.exec(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+	if out = strings.TrimSpace(out); out != "" {
+		return summary + "\n" + capGitOutput(out, 0), nil
+	}
+	return summary, nil
+}
+
+func (g *GitTool) exec(ctx context.Context, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitCommandTimeout)
+	defer cancel()
+
+	// Hooks, fsmonitor, signing, and the ext:: transport are disabled and
+	// command-running config is overridden (see configOverrides), so a
+	// repository's own config cannot run arbitrary code through the tool;
+	// pager and prompts are disabled to keep git non-interactive.
+	full := []string{"-C", g.RepoPath,
+		"-c", "core.hooksPath=/dev/null",
+		"-c", "core.fsmonitor=false",
+		"-c", "commit.gpgsign=false",
+		"-c", "tag.gpgsign=false",
+		"-c", "log.showSignature=false",
+		"-c", "protocol.ext.allow=never",
+		"-c", "color.ui=false",
+	}
+	full = append(full, g.configOverrides(ctx)...)
+	full = append(full, args...)
+	cmd := exec.CommandContext(ctx, "git", full...)
+	cmd.Env = append(cmd.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_PROXY_COMMAND=", // set, even empty, it stops git reading core.gitProxy
+		"GIT_PAGER=cat",
+		"GIT_EDITOR=true",
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = strings.TrimSpace(stdout.String())
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s failed: %s", args[0], msg)
+	}
+	out := stdout.String()
+	if errOut := strings.TrimSpace(stderr.String()); errOut != "" {
+		// Commands like checkout and push report progress on stderr.
+		out = strings.TrimRight(out, "\n")
+		if out != "" {
+			out += "\n"
+		}
+		out += errOut
+	}
+	return out, nil
+}
+
+// gitCommandConfigRe matches config keys whose values git runs as commands.
+var gitCommandConfigRe = `^(filter\..+\.(clean|smudge|process)|merge\..+\.driver|credential\.(.+\.)?helper|core\.(sshcommand|askpass))$`
+
+// configOverrides returns -c options that neutralize the commands a
+// repository's config would have git run: filter drivers are blanked and
+// merge drivers become a plain text merge. The ssh command, credential
+// helpers and askpass are reset only when the repository itself sets them,
+// so the operator's global setup keeps working. Reading config runs no
+// command.
+func (g *GitTool) configOverrides(ctx context.Context) []string {
+	cmd := exec.CommandContext(ctx, "git", "-C", g.RepoPath, "config", "--show-scope", "--name-only", "--get-regexp", gitCommandConfigRe)
+	out, err := cmd.Output()
+	if err != nil {
+		// Exit status 1 means none of these keys are configured.
+		return nil
+	}
+	var overrides []string
+	seen := make(map[string]bool)
+	add := func(key, value string) {
+		if !seen[key] {
+			seen[key] = true
+			overrides = append(overrides, "-c", key+"="+value)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		scope, key, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		repoScoped := scope == "local" || scope == "worktree"
+		switch {
+		case strings.HasPrefix(key, "filter."):
+			prefix := key[:strings.LastIndex(key, ".")]
+			add(prefix+".clean", "")
+			add(prefix+".smudge", "")
+			add(prefix+".process", "")
+			add(prefix+".required", "false")
+		case strings.HasPrefix(key, "merge."):
+			add(key, "git merge-file --marker-size=%L %A %O %B")
+		case !repoScoped:
+		case strings.HasPrefix(key, "credential."):
+			// An empty helper clears the list, URL-specific helpers included.
+			add("credential.helper", "")
+		case key == "core.sshcommand":
+			add(key, "ssh")
+		default:
+			add(key, "")
+		}
+	}
+	return overrides
+}
+
+func capGitOutput(out string, maxBytes int) string {
+	if maxBytes <= 0 {
+		maxBytes = defaultGitOutputBytes
+	}
+	if maxBytes > maxGitOutputBytes {
+		maxBytes = maxGitOutputBytes
+	}
+	out = strings.TrimRight(out, "\n")
+	if len(out) <= maxBytes {
+		return out
+	}
+	return strings.ToValidUTF8(out[:maxBytes], "") + fmt.Sprintf("\n\n... (output truncated at %d of %d bytes)", maxBytes, len(out))
+}
@@ -0,0 +1,374 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestGitRepo creates a repository with one commit containing a.txt.
+func newTestGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "bot@example.com"},
+		{"config", "user.name", "Bot"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewGitTool(dir)
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"action": "add", "all": "true"}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"action": "commit", "message": "initial"}); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	return dir
+}
+
+func TestGitTool_StatusDiffCommitLog(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool := NewGitTool(dir)
+	ctx := context.Background()
+
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\n"), 0644) //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("bee\n"), 0644)      //nolint:errcheck
+
+	out, err := tool.ExecuteJSON(ctx, map[string]string{"action": "status"})
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if !strings.Contains(out, " M a.txt") || !strings.Contains(out, "?? b.txt") {
+		t.Errorf("unexpected status: %q", out)
+	}
+
+	out, err = tool.ExecuteJSON(ctx, map[string]string{"action": "diff", "paths": `["a.txt"]`})
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if !strings.Contains(out, "+two") {
+		t.Errorf("diff missing change: %q", out)
+	}
+
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"action": "add", "paths": `["a.txt","b.txt"]`}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	out, err = tool.ExecuteJSON(ctx, map[string]string{"action": "diff", "staged": "true", "stat": "true"})
+	if err != nil {
+		t.Fatalf("diff --staged: %v", err)
+	}
+	if !strings.Contains(out, "2 files changed") {
+		t.Errorf("unexpected staged stat: %q", out)
+	}
+
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"action": "commit", "message": "second change"}); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	out, err = tool.ExecuteJSON(ctx, map[string]string{"action": "log", "limit": "1"})
+	if err != nil {
+		t.Fatalf("log: %v", err)
+	}
+	if !strings.Contains(out, "second change") || strings.Contains(out, "initial") {
+		t.Errorf("unexpected log: %q", out)
+	}
+}
+
+func TestGitTool_DiffSizeCap(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool := NewGitTool(dir)
+
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte(strings.Repeat("line\n", 500)), 0644) //nolint:errcheck
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"action": "diff", "max_bytes": "200"})
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+	if !strings.Contains(out, "(output truncated at 200 of") {
+		t.Errorf("expected truncation note, got %q", out)
+	}
+}
+
+func TestGitTool_PathsStayInsideRoot(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool := NewGitTool(dir)
+
+	_, err := tool.ExecuteJSON(context.Background(), map[string]string{"action": "diff", "paths": `["../outside"]`})
+	if err == nil || !strings.Contains(err.Error(), "outside allowed directory") {
+		t.Fatalf("expected path escape to be refused, got %v", err)
+	}
+	_, err = tool.ExecuteJSON(context.Background(), map[string]string{"action": "show", "ref": "--output=/tmp/x"})
+	if err == nil {
+		t.Fatal("expected option-looking ref to be refused")
+	}
+}
+
+func TestGitTool_BranchCheckoutStash(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool := NewGitTool(dir)
+	ctx := context.Background()
+
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"action": "checkout", "create": "true", "name": "feature"}); err != nil {
+		t.Fatalf("checkout -b: %v", err)
+	}
+	out, err := tool.ExecuteJSON(ctx, map[string]string{"action": "branch"})
+	if err != nil {
+		t.Fatalf("branch list: %v", err)
+	}
+	if !strings.Contains(out, "* feature") {
+		t.Errorf("expected feature to be current, got %q", out)
+	}
+
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("changed\n"), 0644) //nolint:errcheck
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"action": "stash", "message": "wip"}); err != nil {
+		t.Fatalf("stash: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "a.txt"))
+	if string(data) != "one\n" {
+		t.Errorf("stash did not restore working tree: %q", data)
+	}
+	out, err = tool.ExecuteJSON(ctx, map[string]string{"action": "stash", "subaction": "list"})
+	if err != nil || !strings.Contains(out, "wip") {
+		t.Fatalf("stash list = %q, %v", out, err)
+	}
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"action": "stash", "subaction": "pop"}); err != nil {
+		t.Fatalf("stash pop: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "a.txt"))
+	if string(data) != "changed\n" {
+		t.Errorf("stash pop did not restore change: %q", data)
+	}
+}
+
+func TestGitTool_Worktree(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool := NewGitTool(dir)
+	ctx := context.Background()
+
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"action": "worktree", "subaction": "add", "path": "wt", "name": "wt-branch"}); err != nil {
+		t.Fatalf("worktree add: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "wt", "a.txt")); err != nil {
+		t.Fatalf("worktree not checked out: %v", err)
+	}
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"action": "worktree", "subaction": "remove", "path": "wt"}); err != nil {
+		t.Fatalf("worktree remove: %v", err)
+	}
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"action": "worktree", "subaction": "add", "path": "/tmp/elsewhere"}); err == nil {
+		t.Fatal("expected worktree outside root to be refused")
+	}
+}
+
+func TestGitTool_HistoryRewriteRefused(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool := NewGitTool(dir)
+	ctx := context.Background()
+
+	for _, params := range []map[string]string{
+		{"action": "push", "force": "true"},
+		{"action": "push", "name": "+main"},
+		{"action": "push", "name": "+HEAD:main"},
+		{"action": "push", "name": ":main"},
+		{"action": "commit", "amend": "true", "message": "rewritten"},
+		{"action": "reset", "subaction": "hard", "ref": "HEAD"},
+	} {
+		_, err := tool.ExecuteJSON(ctx, params)
+		denial, ok := IsToolDenial(err)
+		if !ok {
+			t.Fatalf("%v: expected ToolDenial, got %v", params, err)
+		}
+		if denial.Family != "git_rewrite" {
+			t.Errorf("%v: denial.Family = %q, want git_rewrite", params, denial.Family)
+		}
+	}
+
+	allowed := tool.WithHistoryRewrite().(*GitTool)
+	if _, err := allowed.ExecuteJSON(ctx, map[string]string{"action": "commit", "amend": "true", "message": "rewritten"}); err != nil {
+		t.Fatalf("amend with policy: %v", err)
+	}
+	out, _ := allowed.ExecuteJSON(ctx, map[string]string{"action": "log"})
+	if !strings.Contains(out, "rewritten") {
+		t.Errorf("expected amended commit in log, got %q", out)
+	}
+}
+
+func TestGitTool_PushRequiresApproval(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool := NewGitTool(dir)
+
+	_, err := tool.ExecuteJSON(context.Background(), map[string]string{"action": "push"})
+	if denial, ok := IsToolDenial(err); !ok || denial.Family != "git_push" {
+		t.Fatalf("expected git_push denial without approval, got %v", err)
+	}
+
+	var prompt string
+	denied := tool.WithApproval(func(cmd string) (bool, error) {
+		prompt = cmd
+		return false, nil
+	}).(*GitTool)
+	out, err := denied.ExecuteJSON(context.Background(), map[string]string{"action": "push", "name": "main"})
+	if err != nil || out != "Push denied by user" {
+		t.Fatalf("expected user denial, got %q, %v", out, err)
+	}
+	if prompt != "git push origin main" {
+		t.Errorf("approval prompt = %q", prompt)
+	}
+
+	if _, err := denied.ExecuteJSON(context.Background(), map[string]string{"action": "push", "name": "--mirror"}); err == nil {
+		t.Error("expected option-like refspec to be rejected")
+	}
+}
+
+func TestGitTool_RepoConfigCannotRunCommands(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool := NewGitTool(dir)
+	ctx := context.Background()
+
+	marker := filepath.Join(t.TempDir(), "ran")
+	script := filepath.Join(t.TempDir(), "evil.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ntouch "+marker+"\ncat\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"config", "core.fsmonitor", script},
+		{"config", "filter.evil.clean", script},
+		{"config", "filter.evil.smudge", script},
+		{"config", "filter.evil.required", "true"},
+		{"config", "diff.evil.textconv", script},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte("*.txt filter=evil diff=evil\n"), 0644) //nolint:errcheck
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("changed\n"), 0644)                              //nolint:errcheck
+
+	for _, params := range []map[string]string{
+		{"action": "status"},
+		{"action": "diff"},
+		{"action": "add", "all": "true"},
+		{"action": "commit", "message": "filtered"},
+		{"action": "show"},
+		{"action": "checkout", "paths": `["a.txt"]`, "ref": "HEAD~1"},
+	} {
+		if _, err := tool.ExecuteJSON(ctx, params); err != nil {
+			t.Fatalf("%v: %v", params, err)
+		}
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("repository config ran a command through the git tool")
+	}
+}
+
+func TestGitTool_SigningMergeAndTransportConfigCannotRunCommands(t *testing.T) {
+	dir := newTestGitRepo(t)
+	ctx := context.Background()
+	tool := NewGitTool(dir).WithApproval(func(string) (bool, error) { return true, nil }).(*GitTool)
+
+	marker := filepath.Join(t.TempDir(), "ran")
+	script := filepath.Join(t.TempDir(), "evil.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ntouch "+marker+"\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	remote := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v\n%s", err, out)
+	}
+	for _, args := range [][]string{
+		{"config", "commit.gpgsign", "true"},
+		{"config", "gpg.program", script},
+		{"config", "merge.evil.driver", script},
+		{"config", "credential.helper", "!" + script},
+		{"config", "core.sshCommand", script},
+		{"remote", "add", "origin", remote},
+		{"config", "remote.origin.receivepack", script},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte("*.txt merge=evil\n"), 0644) //nolint:errcheck
+
+	for _, params := range []map[string]string{
+		{"action": "add", "all": "true"},
+		{"action": "commit", "message": "signed?"},
+		{"action": "push", "name": "main"},
+	} {
+		if _, err := tool.ExecuteJSON(ctx, params); err != nil {
+			t.Fatalf("%v: %v", params, err)
+		}
+	}
+
+	// A stash that conflicts with a newer commit goes through the merge driver.
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("stashed\n"), 0644) //nolint:errcheck
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"action": "stash"}); err != nil {
+		t.Fatalf("stash: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("committed\n"), 0644) //nolint:errcheck
+	for _, params := range []map[string]string{
+		{"action": "add", "all": "true"},
+		{"action": "commit", "message": "conflict"},
+	} {
+		if _, err := tool.ExecuteJSON(ctx, params); err != nil {
+			t.Fatalf("%v: %v", params, err)
+		}
+	}
+	tool.ExecuteJSON(ctx, map[string]string{"action": "stash", "subaction": "pop"}) //nolint:errcheck
+
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("repository config ran a command through the git tool")
+	}
+}
+
+func TestCapGitOutputKeepsRunesWhole(t *testing.T) {
+	out := capGitOutput(strings.Repeat("é", 10), 5)
+	head, _, _ := strings.Cut(out, "\n")
+	if head != "éé" {
+		t.Errorf("capped output = %q, want two whole runes", head)
+	}
+}
+
+func TestGitTool_ReadOnly(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool := NewGitTool(dir).WithReadOnly().(*GitTool)
+	ctx := context.Background()
+
+	for _, params := range []map[string]string{
+		{"action": "status"},
+		{"action": "diff"},
+		{"action": "log"},
+		{"action": "show"},
+		{"action": "branch"},
+		{"action": "stash", "subaction": "list"},
+		{"action": "worktree"},
+	} {
+		if _, err := tool.ExecuteJSON(ctx, params); err != nil {
+			t.Errorf("%v: expected read to be allowed, got %v", params, err)
+		}
+	}
+
+	for _, params := range []map[string]string{
+		{"action": "checkout", "paths": `["a.txt"]`},
+		{"action": "reset", "subaction": "hard"},
+		{"action": "stash"},
+		{"action": "add", "all": "true"},
+		{"action": "branch", "name": "feature"},
+		{"action": "worktree", "subaction": "add", "path": "wt"},
+	} {
+		_, err := tool.ExecuteJSON(ctx, params)
+		if denial, ok := IsToolDenial(err); !ok || denial.Family != "file_write" {
+			t.Errorf("%v: expected file_write denial, got %v", params, err)
+		}
+	}
+}
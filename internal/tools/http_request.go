@@ -0,0 +1,628 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultHTTPRequestMaxBytes = 1024 * 1024 // 1MB response body limit
+	httpRequestOutputLimit     = 12000       // characters returned to the model
+)
+
+// HTTPCredentialProfile describes credentials that http_request injects
+// server-side. The model only ever refers to a profile by name.
+type HTTPCredentialProfile struct {
+	Type     string   // "bearer", "basic", or "header"
+	Token    string   // bearer token
+	Username string   // basic auth username
+	Password string   // basic auth password
+	Header   string   // header name for Type "header"
+	Value    string   // header value for Type "header"
+	Hosts    []string // hostnames the profile may be sent to; empty = any
+}
+
+// secrets returns the credential values that must never appear in tool output,
+// including the encoded token a basic profile sends.
+func (p HTTPCredentialProfile) secrets() []string {
+	var out []string
+	if strings.ToLower(p.Type) == "basic" && p.Password != "" {
+		out = append(out, base64.StdEncoding.EncodeToString([]byte(p.Username+":"+p.Password)))
+	}
+	for _, s := range []string{p.Token, p.Password, p.Value} {
+		if len(s) >= 4 {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// redactSecrets replaces every secret in s with "***".
+func redactSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, "***")
+	}
+	return s
+}
+
+// apply sets the profile's credentials on req.
+func (p HTTPCredentialProfile) apply(req *http.Request) error {
+	switch strings.ToLower(p.Type) {
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	case "basic":
+		req.SetBasicAuth(p.Username, p.Password)
+	case "header":
+		if p.Header == "" {
+			return fmt.Errorf("header profile requires a header name")
+		}
+		req.Header.Set(p.Header, p.Value)
+	default:
+		return fmt.Errorf("unsupported credential type %q", p.Type)
+	}
+	return nil
+}
+
+// credentialHeader returns the header name carrying the profile's secret.
+func (p HTTPCredentialProfile) credentialHeader() string {
+	if strings.ToLower(p.Type) == "header" {
+		return p.Header
+	}
+	return "Authorization"
+}
+
+// HTTPRequestTool calls JSON/HTTP APIs with the same SSRF protections as
+// web_fetch. Mutating methods are routed through ApprovalFunc.
+type HTTPRequestTool struct {
+	Profiles         map[string]HTTPCredentialProfile
+	MaxResponseBytes int64
+	ApprovalFunc     func(command string) (bool, error)
+
+	userAgent   string
+	timeout     time.Duration
+	validateURL func(rawURL string) error
+}
+
+// NewHTTPRequestTool creates a new http_request tool.
+func NewHTTPRequestTool(profiles map[string]HTTPCredentialProfile, maxResponseBytes int64) *HTTPRequestTool {
+	if maxResponseBytes <= 0 {
+		maxResponseBytes = defaultHTTPRequestMaxBytes
+	}
+	return &HTTPRequestTool{
+		Profiles:         profiles,
+		MaxResponseBytes: maxResponseBytes,
+		userAgent:        "OKGoBot/1.0",
+		timeout:          30 * time.Second,
+		validateURL:      validateURL,
+	}
+}
+
+func (h *HTTPRequestTool) Name() string {
+	return "http_request"
+}
+
+func (h *HTTPRequestTool) Description() string {
+	desc := "Call HTTP/JSON APIs (GET, POST, PUT, PATCH, DELETE) with headers and bodies. " +
+		"JSON responses are pretty-printed; use extract (e.g. .data.items[].name) to select fields. " +
+		"Mutating methods require user approval."
+	if names := h.profileNames(); len(names) > 0 {
+		desc += " Credential profiles: " + strings.Join(names, ", ") + "."
+	}
+	return desc
+}
+
+// WithApproval returns a copy of the tool that asks fn before mutating requests.
+func (h *HTTPRequestTool) WithApproval(fn func(command string) (bool, error)) Tool {
+	clone := *h
+	clone.ApprovalFunc = fn
+	return &clone
+}
+
+// GetSchema returns the JSON Schema for http_request parameters.
+func (h *HTTPRequestTool) GetSchema() map[string]interface{} {
+	profile := map[string]interface{}{
+		"type":        "string",
+		"description": "Named credential profile injected server-side (never put secrets in headers)",
+	}
+	if names := h.profileNames(); len(names) > 0 {
+		profile["enum"] = names
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"method": map[string]interface{}{
+				"type":        "string",
+				"description": "HTTP method (default GET)",
+				"enum":        []string{"GET", "HEAD", "OPTIONS", "POST", "PUT", "PATCH", "DELETE"},
+			},
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "Absolute http(s) URL",
+			},
+			"query": map[string]interface{}{
+				"type":                 "object",
+				"description":          "Query parameters to append to the URL",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"headers": map[string]interface{}{
+				"type":                 "object",
+				"description":          "Extra request headers",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"body": map[string]interface{}{
+				"type":        "string",
+				"description": "Raw request body",
+			},
+			"json": map[string]interface{}{
+				"description": "JSON request body (sets Content-Type: application/json)",
+			},
+			"profile": profile,
+			"extract": map[string]interface{}{
+				"type":        "string",
+				"description": "jq-style path applied to JSON responses, e.g. .items[0].id or .results[].name",
+			},
+			"max_bytes": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum response bytes to read (capped by server limit)",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+// httpRequestParams is the parsed form of one http_request invocation.
+type httpRequestParams struct {
+	Method   string
+	URL      string
+	Query    map[string]string
+	Headers  map[string]string
+	Body     string
+	JSONBody string
+	Profile  string
+	Extract  string
+	MaxBytes int64
+}
+
+// Execute runs http_request with positional args: <method> <url> [body].
+func (h *HTTPRequestTool) Execute(ctx context.Context, args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: http_request <method> <url> [body]")
+	}
+	p := httpRequestParams{Method: "GET"}
+	if len(args) == 1 {
+		p.URL = args[0]
+	} else {
+		p.Method = args[0]
+		p.URL = args[1]
+		p.Body = strings.Join(args[2:], " ")
+	}
+	return h.do(ctx, p)
+}
+
+// ExecuteJSON runs http_request with structured JSON parameters.
+func (h *HTTPRequestTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	p := httpRequestParams{
+		Method:   params["method"],
+		URL:      params["url"],
+		Body:     params["body"],
+		JSONBody: params["json"],
+		Profile:  params["profile"],
+		Extract:  params["extract"],
+	}
+	for key, dst := range map[string]*map[string]string{"query": &p.Query, "headers": &p.Headers} {
+		raw := strings.TrimSpace(params[key])
+		if raw == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(raw), dst); err != nil {
+			return "", fmt.Errorf("%s must be an object of strings: %w", key, err)
+		}
+	}
+	if raw := strings.TrimSpace(params["max_bytes"]); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid max_bytes: %w", err)
+		}
+		p.MaxBytes = n
+	}
+	return h.do(ctx, p)
+}
+
+func (h *HTTPRequestTool) do(ctx context.Context, p httpRequestParams) (string, error) {
+	method := strings.ToUpper(strings.TrimSpace(p.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions,
+		http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return "", fmt.Errorf("unsupported method: %s", method)
+	}
+
+	target, err := url.Parse(strings.TrimSpace(p.URL))
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if len(p.Query) > 0 {
+		q := target.Query()
+		for k, v := range p.Query {
+			q.Set(k, v)
+		}
+		target.RawQuery = q.Encode()
+	}
+	rawURL := target.String()
+
+	if err := h.validateURL(rawURL); err != nil {
+		return "", err
+	}
+	if err := checkNetworkAllowlist(ctx, h.Name(), rawURL); err != nil {
+		return "", err
+	}
+
+	var profile *HTTPCredentialProfile
+	if p.Profile != "" {
+		prof, ok := h.Profiles[p.Profile]
+		if !ok {
+			return "", fmt.Errorf("unknown credential profile: %s", p.Profile)
+		}
+		if len(prof.Hosts) > 0 && !hostAllowed(target.Hostname(), prof.Hosts) {
+			return "", fmt.Errorf("credential profile %q is not allowed for host %s", p.Profile, target.Hostname())
+		}
+		profile = &prof
+	}
+
+	if p.Body != "" && p.JSONBody != "" {
+		return "", fmt.Errorf("body and json are mutually exclusive")
+	}
+
+	if isMutatingHTTPMethod(method) {
+		command := fmt.Sprintf("HTTP %s %s", method, rawURL)
+		if profile != nil {
+			command += fmt.Sprintf(" (profile %s)", p.Profile)
+		}
+		if h.ApprovalFunc == nil {
+			return "", &ToolDenial{
+				ToolName:    h.Name(),
+				Family:      "http_write",
+				Reason:      fmt.Sprintf("%s requests require approval and no approval channel is available", method),
+				Remediation: "Run the request from a Telegram chat so it can be approved, or use a read-only method.",
+			}
+		}
+		approved, err := h.ApprovalFunc(command)
+		if err != nil {
+			return "", fmt.Errorf("approval check failed: %w", err)
+		}
+		if !approved {
+			return "Request denied by user", nil
+		}
+	}
+
+	var body io.Reader
+	if p.JSONBody != "" {
+		if !json.Valid([]byte(p.JSONBody)) {
+			return "", fmt.Errorf("json body is not valid JSON")
+		}
+		body = strings.NewReader(p.JSONBody)
+	} else if p.Body != "" {
+		body = strings.NewReader(p.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", h.userAgent)
+	req.Header.Set("Accept", "application/json, text/plain;q=0.9, */*;q=0.8")
+	if p.JSONBody != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range p.Headers {
+		if strings.EqualFold(k, "Host") {
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	var secrets []string
+	credHeader := ""
+	if profile != nil {
+		if err := profile.apply(req); err != nil {
+			return "", fmt.Errorf("credential profile %q: %w", p.Profile, err)
+		}
+		secrets = profile.secrets()
+		credHeader = profile.credentialHeader()
+	}
+
+	resp, err := h.client(target.Host, credHeader).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %s", redactSecrets(err.Error(), secrets))
+	}
+	defer resp.Body.Close()
+
+	limit := h.MaxResponseBytes
+	if p.MaxBytes > 0 && p.MaxBytes < limit {
+		limit = p.MaxBytes
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	truncated := int64(len(data)) > limit
+	if truncated {
+		// Back off to a rune boundary so text bodies stay valid UTF-8.
+		cut := int(limit)
+		for cut > 0 && !utf8.RuneStart(data[cut]) {
+			cut--
+		}
+		data = data[:cut]
+	}
+
+	result, err := formatHTTPResponse(resp, data, truncated, p.Extract)
+	if err != nil {
+		return "", errors.New(redactSecrets(err.Error(), secrets))
+	}
+	result = redactSecrets(result, secrets)
+	if len(result) > httpRequestOutputLimit {
+		result = strings.ToValidUTF8(result[:httpRequestOutputLimit], "") + "\n\n... (truncated)"
+	}
+	return result, nil
+}
+
+// client builds a per-request HTTP client. Every redirect hop is revalidated
+// for SSRF and the network allowlist, and the credential header is dropped
+// when the redirect leaves the original host. A mutating request that a 307
+// or 308 would carry to another host is refused: the user approved the
+// original target only.
+func (h *HTTPRequestTool) client(originHost, credHeader string) *http.Client {
+	return &http.Client{
+		Timeout: h.timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects")
+			}
+			if err := h.validateURL(req.URL.String()); err != nil {
+				return fmt.Errorf("redirect blocked (SSRF): %w", err)
+			}
+			if err := checkNetworkAllowlist(req.Context(), h.Name(), req.URL.String()); err != nil {
+				return err
+			}
+			if req.URL.Host != originHost && isMutatingHTTPMethod(req.Method) {
+				return fmt.Errorf("redirect blocked: %s to %s was not approved", req.Method, req.URL.Host)
+			}
+			if credHeader != "" && req.URL.Host != originHost {
+				req.Header.Del(credHeader)
+			}
+			return nil
+		},
+	}
+}
+
+func (h *HTTPRequestTool) profileNames() []string {
+	names := make([]string, 0, len(h.Profiles))
+	for name := range h.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isMutatingHTTPMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// formatHTTPResponse renders the status line, key headers and body.
+// JSON bodies are pretty-printed and optionally narrowed with extract.
+func formatHTTPResponse(resp *http.Response, data []byte, truncated bool, extract string) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "HTTP %s\n", resp.Status)
+	for _, name := range []string{"Content-Type", "Location", "Retry-After", "X-RateLimit-Remaining"} {
+		if v := resp.Header.Get(name); v != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", name, v)
+		}
+	}
+	if truncated {
+		fmt.Fprintf(&sb, "(response truncated at %d bytes)\n", len(data))
+	}
+	sb.WriteString("\n")
+
+	isJSON := strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "json") || json.Valid(data)
+	if extract != "" {
+		if !isJSON || truncated {
+			return "", fmt.Errorf("extract requires a complete JSON response")
+		}
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return "", fmt.Errorf("failed to parse JSON response: %w", err)
+		}
+		selected, err := extractJSONPath(doc, extract)
+		if err != nil {
+			return "", err
+		}
+		out, _ := json.MarshalIndent(selected, "", "  ")
+		sb.Write(out)
+		return sb.String(), nil
+	}
+
+	if isJSON {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, data, "", "  "); err == nil {
+			sb.Write(pretty.Bytes())
+			return sb.String(), nil
+		}
+	}
+	sb.Write(data)
+	return sb.String(), nil
+}
+
+type jsonPathStepKind int
+
+const (
+	jsonPathKey jsonPathStepKind = iota
+	jsonPathIndex
+	jsonPathIterate
+)
+
+type jsonPathStep struct {
+	kind  jsonPathStepKind
+	key   string
+	index int
+}
+
+// parseJSONPath parses a small jq-style path: .a.b, .a["b c"], .a[0], .a[-1], .a[].b
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || path == "." {
+		return nil, nil
+	}
+	var steps []jsonPathStep
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unclosed [", path)
+			}
+			inner := strings.TrimSpace(path[i+1 : i+end])
+			i += end + 1
+			switch {
+			case inner == "":
+				steps = append(steps, jsonPathStep{kind: jsonPathIterate})
+			case strings.HasPrefix(inner, `"`):
+				key, err := strconv.Unquote(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path %q: bad key %s", path, inner)
+				}
+				steps = append(steps, jsonPathStep{kind: jsonPathKey, key: key})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path %q: bad index %s", path, inner)
+				}
+				steps = append(steps, jsonPathStep{kind: jsonPathIndex, index: n})
+			}
+		default:
+			j := i
+			for j < len(path) && path[j] != '.' && path[j] != '[' {
+				j++
+			}
+			steps = append(steps, jsonPathStep{kind: jsonPathKey, key: path[i:j]})
+			i = j
+		}
+	}
+	return steps, nil
+}
+
+// extractJSONPath selects values from a decoded JSON document. Missing keys
+// and out-of-range indexes yield null, like jq. Once [] is used, the result
+// is the list of all matches.
+func extractJSONPath(doc interface{}, path string) (interface{}, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []interface{}{doc}
+	iterated := false
+	for _, step := range steps {
+		var next []interface{}
+		for _, v := range values {
+			switch step.kind {
+			case jsonPathKey:
+				if v == nil {
+					next = append(next, nil)
+					continue
+				}
+				m, ok := v.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("cannot read key %q from %s", step.key, jsonTypeName(v))
+				}
+				next = append(next, m[step.key])
+			case jsonPathIndex:
+				if v == nil {
+					next = append(next, nil)
+					continue
+				}
+				arr, ok := v.([]interface{})
+				if !ok {
+					return nil, fmt.Errorf("cannot index %s with [%d]", jsonTypeName(v), step.index)
+				}
+				idx := step.index
+				if idx < 0 {
+					idx += len(arr)
+				}
+				if idx < 0 || idx >= len(arr) {
+					next = append(next, nil)
+					continue
+				}
+				next = append(next, arr[idx])
+			case jsonPathIterate:
+				switch c := v.(type) {
+				case []interface{}:
+					next = append(next, c...)
+				case map[string]interface{}:
+					keys := make([]string, 0, len(c))
+					for k := range c {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, c[k])
+					}
+				default:
+					return nil, fmt.Errorf("cannot iterate over %s", jsonTypeName(v))
+				}
+			}
+		}
+		if step.kind == jsonPathIterate {
+			iterated = true
+		}
+		values = next
+	}
+
+	if iterated {
+		if values == nil {
+			values = []interface{}{}
+		}
+		return values, nil
+	}
+	return values[0], nil
+}
+
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
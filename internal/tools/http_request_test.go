@@ -0,0 +1,310 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// newTestHTTPRequestTool returns a tool that can reach httptest servers on loopback.
+func newTestHTTPRequestTool(profiles map[string]HTTPCredentialProfile) *HTTPRequestTool {
+	tool := NewHTTPRequestTool(profiles, 0)
+	tool.validateURL = func(string) error { return nil }
+	return tool
+}
+
+func TestHTTPRequestTool_GetPrettyPrintsJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "2" {
+			t.Errorf("query page = %q, want 2", r.URL.Query().Get("page"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items":[{"id":1,"name":"a"},{"id":2,"name":"b"}]}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	tool := newTestHTTPRequestTool(nil)
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{
+		"url":   srv.URL,
+		"query": `{"page":"2"}`,
+	})
+	if err != nil {
+		t.Fatalf("ExecuteJSON: %v", err)
+	}
+	if !strings.HasPrefix(out, "HTTP 200 OK") {
+		t.Errorf("output should start with status line, got %q", out)
+	}
+	if !strings.Contains(out, "\n  \"items\": [") {
+		t.Errorf("expected pretty-printed JSON, got %q", out)
+	}
+}
+
+func TestHTTPRequestTool_Extract(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items":[{"id":1,"name":"a"},{"id":2,"name":"b"}]}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	tool := newTestHTTPRequestTool(nil)
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{
+		"url":     srv.URL,
+		"extract": ".items[].name",
+	})
+	if err != nil {
+		t.Fatalf("ExecuteJSON: %v", err)
+	}
+	body := out[strings.Index(out, "\n\n")+2:]
+	var names []string
+	if err := json.Unmarshal([]byte(body), &names); err != nil {
+		t.Fatalf("extracted body is not a JSON array: %v (%q)", err, body)
+	}
+	if strings.Join(names, ",") != "a,b" {
+		t.Errorf("names = %v, want [a b]", names)
+	}
+}
+
+func TestHTTPRequestTool_ProfileInjectedAndRedacted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Echo the auth header back to make sure the secret is scrubbed.
+		w.Write([]byte("auth=" + r.Header.Get("Authorization"))) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	tool := newTestHTTPRequestTool(map[string]HTTPCredentialProfile{
+		"api": {Type: "bearer", Token: "supersecrettoken"},
+	})
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{
+		"url":     srv.URL,
+		"profile": "api",
+	})
+	if err != nil {
+		t.Fatalf("ExecuteJSON: %v", err)
+	}
+	if strings.Contains(out, "supersecrettoken") {
+		t.Errorf("secret leaked into output: %q", out)
+	}
+	if !strings.Contains(out, "auth=Bearer ***") {
+		t.Errorf("expected injected bearer header, got %q", out)
+	}
+}
+
+func TestHTTPRequestTool_BasicProfileTokenRedacted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("auth=" + r.Header.Get("Authorization"))) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	tool := newTestHTTPRequestTool(map[string]HTTPCredentialProfile{
+		"api": {Type: "basic", Username: "bot", Password: "hunter22"},
+	})
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"url": srv.URL, "profile": "api"})
+	if err != nil {
+		t.Fatalf("ExecuteJSON: %v", err)
+	}
+	if strings.Contains(out, base64.StdEncoding.EncodeToString([]byte("bot:hunter22"))) {
+		t.Errorf("basic auth token leaked into output: %q", out)
+	}
+	if !strings.Contains(out, "auth=Basic ***") {
+		t.Errorf("expected redacted basic header, got %q", out)
+	}
+}
+
+func TestHTTPRequestTool_ProfileHostRestriction(t *testing.T) {
+	tool := newTestHTTPRequestTool(map[string]HTTPCredentialProfile{
+		"gh": {Type: "bearer", Token: "tok", Hosts: []string{"api.github.com"}},
+	})
+	_, err := tool.ExecuteJSON(context.Background(), map[string]string{
+		"url":     "https://evil.example.com/",
+		"profile": "gh",
+	})
+	if err == nil || !strings.Contains(err.Error(), "not allowed for host") {
+		t.Fatalf("expected host restriction error, got %v", err)
+	}
+}
+
+func TestHTTPRequestTool_MutatingRequiresApproval(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = r.Method + " " + r.Header.Get("Content-Type") + " " + string(b)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	params := map[string]string{"method": "POST", "url": srv.URL, "json": `{"a":1}`}
+
+	// No approval channel: denied.
+	tool := newTestHTTPRequestTool(nil)
+	_, err := tool.ExecuteJSON(context.Background(), params)
+	if _, ok := IsToolDenial(err); !ok {
+		t.Fatalf("expected ToolDenial without approval func, got %v", err)
+	}
+
+	// User denies.
+	denied := tool.WithApproval(func(string) (bool, error) { return false, nil }).(*HTTPRequestTool)
+	out, err := denied.ExecuteJSON(context.Background(), params)
+	if err != nil || out != "Request denied by user" {
+		t.Fatalf("expected denial message, got %q, %v", out, err)
+	}
+	if got != "" {
+		t.Fatal("request must not be sent when denied")
+	}
+
+	// User approves.
+	var prompt string
+	approved := tool.WithApproval(func(cmd string) (bool, error) {
+		prompt = cmd
+		return true, nil
+	}).(*HTTPRequestTool)
+	out, err = approved.ExecuteJSON(context.Background(), params)
+	if err != nil {
+		t.Fatalf("ExecuteJSON: %v", err)
+	}
+	if !strings.HasPrefix(out, "HTTP 201") {
+		t.Errorf("unexpected output %q", out)
+	}
+	if !strings.HasPrefix(prompt, "HTTP POST ") {
+		t.Errorf("approval prompt = %q", prompt)
+	}
+	if got != `POST application/json {"a":1}` {
+		t.Errorf("server saw %q", got)
+	}
+}
+
+func TestHTTPRequestTool_MutatingRedirectStaysOnHost(t *testing.T) {
+	var hit bool
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer other.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		target := other.URL
+		if r.URL.Query().Get("local") != "" {
+			target = "/moved"
+		}
+		http.Redirect(w, r, target, http.StatusTemporaryRedirect)
+	}))
+	defer srv.Close()
+
+	tool := newTestHTTPRequestTool(nil).WithApproval(func(string) (bool, error) { return true, nil }).(*HTTPRequestTool)
+
+	_, err := tool.ExecuteJSON(context.Background(), map[string]string{"method": "POST", "url": srv.URL, "body": "x"})
+	if err == nil || !strings.Contains(err.Error(), "redirect blocked") {
+		t.Fatalf("cross-host 307 for POST: err = %v", err)
+	}
+	if hit {
+		t.Fatal("POST was replayed to another host")
+	}
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"method": "POST", "url": srv.URL + "?local=1", "body": "x"})
+	if err != nil || !strings.HasPrefix(out, "HTTP 204") {
+		t.Fatalf("same-host 307 for POST = %q, %v", out, err)
+	}
+}
+
+func TestHTTPRequestTool_ResponseSizeLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 500))) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	tool := newTestHTTPRequestTool(nil)
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"url": srv.URL, "max_bytes": "100"})
+	if err != nil {
+		t.Fatalf("ExecuteJSON: %v", err)
+	}
+	if !strings.Contains(out, "(response truncated at 100 bytes)") {
+		t.Errorf("expected truncation note, got %q", out)
+	}
+	body := out[strings.Index(out, "\n\n")+2:]
+	if len(body) != 100 {
+		t.Errorf("expected 100 body bytes, got %d", len(body))
+	}
+}
+
+func TestHTTPRequestTool_TruncatesOnRuneBoundary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(strings.Repeat("é", httpRequestOutputLimit))) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	tool := newTestHTTPRequestTool(nil)
+	for _, maxBytes := range []string{"101", ""} {
+		out, err := tool.ExecuteJSON(context.Background(), map[string]string{"url": srv.URL, "max_bytes": maxBytes})
+		if err != nil {
+			t.Fatalf("ExecuteJSON: %v", err)
+		}
+		if !utf8.ValidString(out) {
+			t.Errorf("max_bytes=%q: output is not valid UTF-8", maxBytes)
+		}
+	}
+}
+
+func TestHTTPRequestTool_NetworkAllowlist(t *testing.T) {
+	tool := newTestHTTPRequestTool(nil)
+	ctx := withNetworkAllowlist(context.Background(), []string{"example.com"})
+	_, err := tool.ExecuteJSON(ctx, map[string]string{"url": "https://other.org/"})
+	denial, ok := IsToolDenial(err)
+	if !ok {
+		t.Fatalf("expected ToolDenial, got %v", err)
+	}
+	if denial.Family != "network" {
+		t.Errorf("denial.Family = %q, want network", denial.Family)
+	}
+}
+
+func TestHTTPRequestTool_BlocksPrivateAddresses(t *testing.T) {
+	tool := NewHTTPRequestTool(nil, 0)
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"url": "http://127.0.0.1:8080/"}); err == nil {
+		t.Fatal("expected SSRF protection to block loopback")
+	}
+}
+
+func TestExtractJSONPath(t *testing.T) {
+	var doc interface{}
+	json.Unmarshal([]byte(`{"a":{"b":[10,20,30]},"list":[{"n":"x"},{"n":"y"}],"odd key":true}`), &doc) //nolint:errcheck
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{".", `{"a":{"b":[10,20,30]},"list":[{"n":"x"},{"n":"y"}],"odd key":true}`},
+		{".a.b[1]", `20`},
+		{".a.b[-1]", `30`},
+		{".a.b[9]", `null`},
+		{".missing", `null`},
+		{".list[].n", `["x","y"]`},
+		{`["odd key"]`, `true`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := extractJSONPath(doc, tt.path)
+			if err != nil {
+				t.Fatalf("extractJSONPath(%q): %v", tt.path, err)
+			}
+			raw, _ := json.Marshal(got)
+			if string(raw) != tt.want {
+				t.Errorf("extractJSONPath(%q) = %s, want %s", tt.path, raw, tt.want)
+			}
+		})
+	}
+
+	if _, err := extractJSONPath(doc, ".a.b.c"); err == nil {
+		t.Error("expected error reading key from array")
+	}
+	if _, err := extractJSONPath(doc, ".a[0"); err == nil {
+		t.Error("expected error for unclosed bracket")
+	}
+}
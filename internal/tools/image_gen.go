@@ -7,11 +7,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"ok-gobot/internal/runtime"
 )
 
 // ImageGenerator interface for image generation providers
@@ -19,26 +24,42 @@ type ImageGenerator interface {
 	Generate(ctx context.Context, prompt string, opts ImageOptions) (*GeneratedImage, error)
 }
 
+// ImageEditor is implemented by providers that can change an existing image.
+// An empty prompt asks for a variation of the source image.
+type ImageEditor interface {
+	Edit(ctx context.Context, imagePath, prompt string, opts ImageOptions) (*GeneratedImage, error)
+}
+
 // ImageOptions holds options for image generation
 type ImageOptions struct {
-	Size    string // "1024x1024", "1792x1024", "1024x1792"
-	Quality string // "standard", "hd"
-	Style   string // "vivid", "natural"
-	Model   string // "dall-e-3", etc.
+	Size           string  // "1024x1024", "1792x1024", "1024x1792"
+	Quality        string  // "standard", "hd"
+	Style          string  // "vivid", "natural"
+	Model          string  // "dall-e-3", etc.
+	Seed           int64   // fixed seed for reproducible results (0 = random)
+	NegativePrompt string  // things to avoid (Stable Diffusion providers)
+	Strength       float64 // how much an edit may change the source, 0-1 (0 = provider default)
 }
 
 // GeneratedImage holds the result of image generation
 type GeneratedImage struct {
-	Path          string
+	Path          string // set once the image is saved
+	Data          []byte // encoded image returned by the provider
+	MimeType      string
 	RevisedPrompt string
 	URL           string
+	Seed          int64 // seed reported by the provider (0 = unknown)
+	Model         string
 }
 
-// OpenAIImageGenerator generates images using OpenAI's DALL-E API
+// OpenAIImageGenerator generates images using OpenAI's DALL-E API or any
+// endpoint that implements the OpenAI images API.
 type OpenAIImageGenerator struct {
-	apiKey  string
-	baseURL string
-	client  *http.Client
+	apiKey     string
+	baseURL    string
+	model      string // default model (empty = dall-e-3 for generation)
+	compatible bool   // send only the portable subset of parameters
+	client     *http.Client
 }
 
 // NewOpenAIImageGenerator creates a new OpenAI image generator
@@ -48,34 +69,51 @@ func NewOpenAIImageGenerator(apiKey, baseURL string) *OpenAIImageGenerator {
 	}
 	return &OpenAIImageGenerator{
 		apiKey:  apiKey,
-		baseURL: baseURL,
+		baseURL: strings.TrimRight(baseURL, "/"),
 		client:  &http.Client{Timeout: 120 * time.Second},
 	}
 }
 
+// NewOpenAICompatibleImageGenerator creates a generator for third-party
+// endpoints that mimic the OpenAI images API (e.g. LocalAI, Together).
+// DALL-E specific parameters such as quality and style are not sent.
+func NewOpenAICompatibleImageGenerator(apiKey, baseURL, model string) *OpenAIImageGenerator {
+	g := NewOpenAIImageGenerator(apiKey, baseURL)
+	g.model = model
+	g.compatible = true
+	return g
+}
+
 // Generate creates an image from a prompt
 func (g *OpenAIImageGenerator) Generate(ctx context.Context, prompt string, opts ImageOptions) (*GeneratedImage, error) {
-	if opts.Size == "" {
-		opts.Size = "1024x1024"
-	}
-	if opts.Quality == "" {
-		opts.Quality = "standard"
-	}
-	if opts.Model == "" {
-		opts.Model = "dall-e-3"
-	}
+	model := orDefault(opts.Model, g.model)
 
 	reqBody := map[string]interface{}{
-		"model":           opts.Model,
 		"prompt":          prompt,
 		"n":               1,
-		"size":            opts.Size,
-		"quality":         opts.Quality,
 		"response_format": "b64_json",
 	}
-
-	if opts.Style != "" {
-		reqBody["style"] = opts.Style
+	if g.compatible {
+		if model != "" {
+			reqBody["model"] = model
+		}
+		if opts.Size != "" {
+			reqBody["size"] = opts.Size
+		}
+		if opts.Seed != 0 {
+			reqBody["seed"] = opts.Seed
+		}
+		if opts.NegativePrompt != "" {
+			reqBody["negative_prompt"] = opts.NegativePrompt
+		}
+	} else {
+		model = orDefault(model, "dall-e-3")
+		reqBody["model"] = model
+		reqBody["size"] = orDefault(opts.Size, "1024x1024")
+		reqBody["quality"] = orDefault(opts.Quality, "standard")
+		if opts.Style != "" {
+			reqBody["style"] = opts.Style
+		}
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -87,9 +125,102 @@ func (g *OpenAIImageGenerator) Generate(ctx context.Context, prompt string, opts
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+g.apiKey)
+
+	img, err := g.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	img.Model = model
+	if img.Seed == 0 {
+		img.Seed = opts.Seed
+	}
+	return img, nil
+}
+
+// Edit changes imagePath according to prompt via /images/edits, or returns a
+// variation via /images/variations when prompt is empty.
+func (g *OpenAIImageGenerator) Edit(ctx context.Context, imagePath, prompt string, opts ImageOptions) (*GeneratedImage, error) {
+	model := orDefault(opts.Model, g.model)
+	endpoint := "/images/edits"
+	if prompt == "" {
+		endpoint = "/images/variations"
+		if !g.compatible {
+			model = "dall-e-2" // the only OpenAI model with variations
+		}
+	} else if !g.compatible && (model == "" || model == "dall-e-3") {
+		model = "gpt-image-1" // dall-e-3 cannot edit
+	}
+
+	// OpenAI wants PNG input; dall-e-2 additionally needs a square image.
+	data, err := imageAsPNG(imagePath, model == "dall-e-2")
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("image", strings.TrimSuffix(filepath.Base(imagePath), filepath.Ext(imagePath))+".png")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, err
+	}
+	fields := map[string]string{"n": "1"}
+	if prompt != "" {
+		fields["prompt"] = prompt
+	}
+	if model != "" {
+		fields["model"] = model
+	}
+	if opts.Size != "" {
+		fields["size"] = opts.Size
+	}
+	if !strings.HasPrefix(model, "gpt-image") {
+		// gpt-image models always return base64 and reject this field.
+		fields["response_format"] = "b64_json"
+	}
+	if g.compatible && opts.Seed != 0 {
+		fields["seed"] = strconv.FormatInt(opts.Seed, 10)
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := w.WriteField(k, fields[k]); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+endpoint, &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	img, err := g.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	img.Model = model
+	if img.Seed == 0 {
+		img.Seed = opts.Seed
+	}
+	return img, nil
+}
+
+// do sends an images API request and decodes the first returned image,
+// downloading it when the endpoint answers with a URL instead of base64.
+func (g *OpenAIImageGenerator) do(ctx context.Context, req *http.Request) (*GeneratedImage, error) {
+	if g.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+g.apiKey)
+	}
 
 	resp, err := g.client.Do(req)
 	if err != nil {
@@ -111,7 +242,9 @@ func (g *OpenAIImageGenerator) Generate(ctx context.Context, prompt string, opts
 			B64JSON       string `json:"b64_json"`
 			URL           string `json:"url"`
 			RevisedPrompt string `json:"revised_prompt"`
+			Seed          int64  `json:"seed"`
 		} `json:"data"`
+		Seed int64 `json:"seed"`
 	}
 
 	if err := json.Unmarshal(body, &result); err != nil {
@@ -121,45 +254,77 @@ func (g *OpenAIImageGenerator) Generate(ctx context.Context, prompt string, opts
 	if len(result.Data) == 0 {
 		return nil, fmt.Errorf("no image generated")
 	}
+	first := result.Data[0]
 
-	// Decode and save the image
-	imgData, err := base64.StdEncoding.DecodeString(result.Data[0].B64JSON)
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %w", err)
+	img := &GeneratedImage{
+		RevisedPrompt: first.RevisedPrompt,
+		URL:           first.URL,
+		Seed:          first.Seed,
 	}
-
-	// Save to temp file
-	tempDir := filepath.Join(os.TempDir(), "okgobot-images")
-	os.MkdirAll(tempDir, 0755)
-
-	filename := fmt.Sprintf("img_%d.png", time.Now().UnixNano())
-	filePath := filepath.Join(tempDir, filename)
-
-	if err := os.WriteFile(filePath, imgData, 0644); err != nil {
-		return nil, fmt.Errorf("failed to save image: %w", err)
+	if img.Seed == 0 {
+		img.Seed = result.Seed
+	}
+	switch {
+	case first.B64JSON != "":
+		img.Data, err = base64.StdEncoding.DecodeString(first.B64JSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image: %w", err)
+		}
+	case first.URL != "":
+		img.Data, err = downloadImage(ctx, g.client, first.URL)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("response contains no image data")
 	}
+	img.MimeType = http.DetectContentType(img.Data)
+	return img, nil
+}
 
-	return &GeneratedImage{
-		Path:          filePath,
-		RevisedPrompt: result.Data[0].RevisedPrompt,
-		URL:           result.Data[0].URL,
-	}, nil
+// ImageProvider is a named image backend offered by the image_gen tool.
+type ImageProvider struct {
+	Name      string
+	Generator ImageGenerator
 }
 
 // ImageTool provides image generation capabilities
 type ImageTool struct {
-	generator ImageGenerator
-	tempDir   string
+	providers       []ImageProvider
+	defaultProvider string
+	outputDir       string // generated images and their .json metadata
+	scratchDir      string // run_code scratch root holding Telegram uploads
+}
+
+// DefaultImageOutputDir returns ~/.ok-gobot/images.
+func DefaultImageOutputDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ok-gobot", "images")
 }
 
 // NewImageTool creates a new image generation tool
 func NewImageTool(apiKey, baseURL string) *ImageTool {
-	tempDir := filepath.Join(os.TempDir(), "okgobot-images")
-	os.MkdirAll(tempDir, 0755)
+	return NewImageToolWithProviders([]ImageProvider{{Name: "openai", Generator: NewOpenAIImageGenerator(apiKey, baseURL)}}, "", "", "")
+}
 
+// NewImageToolWithProviders creates an image tool over several backends.
+// defaultProvider selects the backend used without --provider (empty = the
+// first one). Images are stored in outputDir (empty = DefaultImageOutputDir).
+func NewImageToolWithProviders(providers []ImageProvider, defaultProvider, outputDir, scratchDir string) *ImageTool {
+	if outputDir == "" {
+		outputDir = DefaultImageOutputDir()
+	}
+	if scratchDir == "" {
+		scratchDir = DefaultScratchDir()
+	}
+	if defaultProvider == "" && len(providers) > 0 {
+		defaultProvider = providers[0].Name
+	}
 	return &ImageTool{
-		generator: NewOpenAIImageGenerator(apiKey, baseURL),
-		tempDir:   tempDir,
+		providers:       providers,
+		defaultProvider: defaultProvider,
+		outputDir:       outputDir,
+		scratchDir:      scratchDir,
 	}
 }
 
@@ -168,63 +333,284 @@ func (t *ImageTool) Name() string {
 }
 
 func (t *ImageTool) Description() string {
-	return "Generate images from text descriptions using DALL-E"
+	names := make([]string, 0, len(t.providers))
+	for _, p := range t.providers {
+		names = append(names, p.Name)
+	}
+	return fmt.Sprintf("Generate images from text descriptions, or edit/vary an image the user sent (providers: %s). Images are sent to the chat and stored with their prompt and seed", strings.Join(names, ", "))
+}
+
+func (t *ImageTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"prompt": map[string]interface{}{
+				"type":        "string",
+				"description": "What to draw, or for edits what to change. Leave empty with image set to get a variation",
+			},
+			"image": map[string]interface{}{
+				"type":        "string",
+				"description": "Image to edit: a path from a [Photo attached ...] note such as uploads/photo_123.jpg, a previously generated image path, or \"last\" for the most recent photo the user sent",
+			},
+			"provider": map[string]interface{}{
+				"type":        "string",
+				"description": "Image backend (default " + t.defaultProvider + ")",
+			},
+			"size": map[string]interface{}{
+				"type":        "string",
+				"description": "WIDTHxHEIGHT, e.g. 1024x1024, 1792x1024, 1024x1792",
+			},
+			"seed": map[string]interface{}{
+				"type":        "integer",
+				"description": "Seed for reproducible results; reuse the seed of an earlier image to iterate on it",
+			},
+			"negative_prompt": map[string]interface{}{
+				"type":        "string",
+				"description": "Things to avoid (Stable Diffusion backends)",
+			},
+			"strength": map[string]interface{}{
+				"type":        "number",
+				"description": "For edits: how much the image may change, 0-1",
+			},
+			"quality": map[string]interface{}{
+				"type":        "string",
+				"description": "standard or hd (DALL-E 3)",
+			},
+			"style": map[string]interface{}{
+				"type":        "string",
+				"description": "vivid or natural (DALL-E 3)",
+			},
+		},
+	}
 }
 
 func (t *ImageTool) Execute(ctx context.Context, args ...string) (string, error) {
 	if len(args) == 0 {
-		return "", fmt.Errorf("usage: image_gen <prompt> [--size 1024x1024] [--quality standard|hd] [--style vivid|natural]")
+		return "", fmt.Errorf("usage: image_gen <prompt> [--provider <name>] [--image <path>|last] [--seed N] [--negative <text>] [--strength 0-1] [--size 1024x1024] [--quality standard|hd] [--style vivid|natural]")
 	}
 
 	// Parse arguments
-	opts := ImageOptions{}
+	params := map[string]string{}
+	flags := map[string]string{
+		"--size": "size", "--quality": "quality", "--style": "style", "--provider": "provider",
+		"--image": "image", "--seed": "seed", "--negative": "negative_prompt", "--strength": "strength",
+	}
 	var promptParts []string
 
 	for i := 0; i < len(args); i++ {
-		switch args[i] {
-		case "--size":
-			if i+1 < len(args) {
-				opts.Size = args[i+1]
-				i++
-			}
-		case "--quality":
-			if i+1 < len(args) {
-				opts.Quality = args[i+1]
-				i++
-			}
-		case "--style":
+		if key, ok := flags[args[i]]; ok {
 			if i+1 < len(args) {
-				opts.Style = args[i+1]
+				params[key] = args[i+1]
 				i++
 			}
-		default:
-			promptParts = append(promptParts, args[i])
+			continue
 		}
+		promptParts = append(promptParts, args[i])
 	}
+	params["prompt"] = strings.Join(promptParts, " ")
+	return t.ExecuteJSON(ctx, params)
+}
 
-	prompt := strings.Join(promptParts, " ")
-	if prompt == "" {
+// ExecuteJSON generates or edits one image and delivers it to the chat.
+func (t *ImageTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	prompt := strings.TrimSpace(params["prompt"])
+	source := strings.TrimSpace(params["image"])
+	if prompt == "" && source == "" {
 		return "", fmt.Errorf("prompt is required")
 	}
 
-	if t.generator == nil {
-		return "", fmt.Errorf("image generator not configured")
+	providerName := orDefault(strings.TrimSpace(params["provider"]), t.defaultProvider)
+	generator := t.provider(providerName)
+	if generator == nil {
+		return "", fmt.Errorf("image provider %q not configured", providerName)
 	}
 
-	result, err := t.generator.Generate(ctx, prompt, opts)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate image: %w", err)
+	opts := ImageOptions{
+		Size:           strings.TrimSpace(params["size"]),
+		Quality:        strings.TrimSpace(params["quality"]),
+		Style:          strings.TrimSpace(params["style"]),
+		NegativePrompt: strings.TrimSpace(params["negative_prompt"]),
+	}
+	if raw := strings.TrimSpace(params["seed"]); raw != "" {
+		seed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || seed < 0 {
+			return "", fmt.Errorf("invalid seed %q", raw)
+		}
+		opts.Seed = seed
+	}
+	if raw := strings.TrimSpace(params["strength"]); raw != "" {
+		strength, err := strconv.ParseFloat(raw, 64)
+		if err != nil || strength < 0 || strength > 1 {
+			return "", fmt.Errorf("invalid strength %q: must be between 0 and 1", raw)
+		}
+		opts.Strength = strength
+	}
+
+	var result *GeneratedImage
+	var err error
+	if source != "" {
+		editor, ok := generator.(ImageEditor)
+		if !ok {
+			return "", fmt.Errorf("image provider %q cannot edit images", providerName)
+		}
+		source, err = t.resolveSourceImage(ctx, source)
+		if err != nil {
+			return "", err
+		}
+		result, err = editor.Edit(ctx, source, prompt, opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to edit image: %w", err)
+		}
+	} else {
+		result, err = generator.Generate(ctx, prompt, opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate image: %w", err)
+		}
+	}
+
+	meta := imageMetadata{
+		Prompt:         prompt,
+		RevisedPrompt:  result.RevisedPrompt,
+		NegativePrompt: opts.NegativePrompt,
+		Seed:           result.Seed,
+		Provider:       providerName,
+		Model:          result.Model,
+		Size:           opts.Size,
+		SourceImage:    source,
+		CreatedAt:      time.Now().UTC(),
+	}
+	if err := t.saveImage(result, meta); err != nil {
+		return "", err
 	}
 
-	response := fmt.Sprintf("🎨 Image generated!\n\nPrompt: %s\nFile: %s", prompt, result.Path)
+	action := "generated"
+	if source != "" {
+		action = "edited"
+	}
+	response := fmt.Sprintf("🎨 Image %s!\n\nProvider: %s\nPrompt: %s\nFile: %s", action, providerName, prompt, result.Path)
+	if result.Seed != 0 {
+		response += fmt.Sprintf("\nSeed: %d", result.Seed)
+	}
 	if result.RevisedPrompt != "" && result.RevisedPrompt != prompt {
 		response += fmt.Sprintf("\n\nRevised prompt: %s", result.RevisedPrompt)
 	}
 
+	if AddAttachment(ctx, Attachment{Path: result.Path, MimeType: result.MimeType, Caption: truncateCaption(prompt)}) {
+		response += "\n(sent to chat)"
+	}
+	if jobID, err := runtime.AddContextArtifact(ctx, runtime.JobArtifactSpec{
+		Name:     "image_gen/" + filepath.Base(result.Path),
+		Type:     "image",
+		MimeType: result.MimeType,
+		URI:      "file://" + result.Path,
+		Metadata: meta,
+	}); err != nil {
+		response += fmt.Sprintf("\n(artifact failed: %v)", err)
+	} else if jobID != "" {
+		response += "\n(artifact of job " + jobID + ")"
+	}
+
 	return response, nil
 }
 
-// GetImagePath returns the path to the generated image (for sending via Telegram)
+// GetImagePath returns the directory generated images are stored in (for sending via Telegram)
 func (t *ImageTool) GetImagePath() string {
-	return t.tempDir
+	return t.outputDir
+}
+
+func (t *ImageTool) provider(name string) ImageGenerator {
+	for _, p := range t.providers {
+		if p.Name == name {
+			return p.Generator
+		}
+	}
+	return nil
+}
+
+// imageMetadata is written next to every stored image as <image>.json so a
+// result can be reproduced or iterated on later.
+type imageMetadata struct {
+	Prompt         string    `json:"prompt"`
+	RevisedPrompt  string    `json:"revised_prompt,omitempty"`
+	NegativePrompt string    `json:"negative_prompt,omitempty"`
+	Seed           int64     `json:"seed,omitempty"`
+	Provider       string    `json:"provider"`
+	Model          string    `json:"model,omitempty"`
+	Size           string    `json:"size,omitempty"`
+	SourceImage    string    `json:"source_image,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// saveImage writes the image and its metadata sidecar into the output dir.
+func (t *ImageTool) saveImage(img *GeneratedImage, meta imageMetadata) error {
+	if len(img.Data) == 0 {
+		return fmt.Errorf("provider returned an empty image")
+	}
+	if err := os.MkdirAll(t.outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create image directory: %w", err)
+	}
+	if img.MimeType == "" || !strings.HasPrefix(img.MimeType, "image/") {
+		img.MimeType = http.DetectContentType(img.Data)
+	}
+	ext := ".png"
+	switch img.MimeType {
+	case "image/jpeg":
+		ext = ".jpg"
+	case "image/webp":
+		ext = ".webp"
+	}
+
+	img.Path = filepath.Join(t.outputDir, fmt.Sprintf("img_%d%s", time.Now().UnixNano(), ext))
+	if err := os.WriteFile(img.Path, img.Data, 0644); err != nil {
+		return fmt.Errorf("failed to save image: %w", err)
+	}
+	metaJSON, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(img.Path+".json", metaJSON, 0644); err != nil {
+		return fmt.Errorf("failed to save image metadata: %w", err)
+	}
+	return nil
+}
+
+// resolveSourceImage finds the image to edit. "last" is the newest photo the
+// user sent in this session; relative paths are looked up in the session
+// scratch directory; absolute paths must lie in it or in the output dir.
+func (t *ImageTool) resolveSourceImage(ctx context.Context, path string) (string, error) {
+	sessionDir := RunCodeSessionDir(t.scratchDir, SessionKeyFromContext(ctx))
+	if path == "last" {
+		matches, _ := filepath.Glob(filepath.Join(sessionDir, "uploads", "photo_*"))
+		var newest string
+		var newestTime time.Time
+		for _, m := range matches {
+			if st, err := os.Stat(m); err == nil && st.ModTime().After(newestTime) {
+				newest, newestTime = m, st.ModTime()
+			}
+		}
+		if newest == "" {
+			return "", fmt.Errorf("no photo from the user in this session")
+		}
+		return newest, nil
+	}
+
+	for _, root := range []string{sessionDir, t.outputDir} {
+		full, err := resolvePath(root, path)
+		if err != nil {
+			continue
+		}
+		if st, err := os.Stat(full); err == nil && st.Mode().IsRegular() {
+			return full, nil
+		}
+	}
+	return "", fmt.Errorf("image %q not found in the session uploads or generated images", path)
+}
+
+func truncateCaption(s string) string {
+	const maxCaption = 1024 // Telegram caption limit
+	r := []rune(s)
+	if len(r) <= maxCaption {
+		return s
+	}
+	return string(r[:maxCaption-1]) + "…"
 }
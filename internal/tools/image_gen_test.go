@@ -0,0 +1,202 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func readImageMeta(t *testing.T, path string) imageMetadata {
+	t.Helper()
+	data, err := os.ReadFile(path + ".json")
+	if err != nil {
+		t.Fatalf("metadata sidecar: %v", err)
+	}
+	var meta imageMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatal(err)
+	}
+	return meta
+}
+
+func TestImageToolAutomatic1111StoresPromptAndSeed(t *testing.T) {
+	pngData := testPNG(t, 8, 8)
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sdapi/v1/txt2img" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"images": []string{base64.StdEncoding.EncodeToString(pngData)},
+			"info":   `{"seed": 1234, "sd_model_name": "sdxl"}`,
+		})
+	}))
+	defer srv.Close()
+
+	gen, err := NewImageGenerator(ImageProviderConfig{Type: "automatic1111", BaseURL: srv.URL, NegativePrompt: "blurry"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outDir := t.TempDir()
+	tool := NewImageToolWithProviders([]ImageProvider{{Name: "local", Generator: gen}}, "", outDir, t.TempDir())
+
+	ctx, collector := WithAttachmentCollector(context.Background())
+	out, err := tool.ExecuteJSON(ctx, map[string]string{"prompt": "a red fox", "size": "512x768"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["seed"].(float64) != -1 || got["width"].(float64) != 512 || got["height"].(float64) != 768 || got["negative_prompt"] != "blurry" {
+		t.Errorf("unexpected txt2img request: %v", got)
+	}
+	if !strings.Contains(out, "Seed: 1234") {
+		t.Errorf("seed missing from output: %s", out)
+	}
+
+	atts := collector.Attachments()
+	if len(atts) != 1 || !atts[0].IsImage() || filepath.Dir(atts[0].Path) != outDir {
+		t.Fatalf("unexpected attachments: %+v", atts)
+	}
+	meta := readImageMeta(t, atts[0].Path)
+	if meta.Prompt != "a red fox" || meta.Seed != 1234 || meta.Provider != "local" || meta.Model != "sdxl" {
+		t.Errorf("unexpected metadata: %+v", meta)
+	}
+}
+
+func TestOpenAICompatibleImageGeneratorDownloadsURL(t *testing.T) {
+	pngData := testPNG(t, 4, 4)
+	var srv *httptest.Server
+	var got map[string]interface{}
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/images/generations":
+			if r.Header.Get("Authorization") != "Bearer key" {
+				t.Errorf("missing auth header")
+			}
+			json.NewDecoder(r.Body).Decode(&got)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []map[string]string{{"url": srv.URL + "/files/out.png"}},
+			})
+		case "/files/out.png":
+			w.Write(pngData)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	gen := NewOpenAICompatibleImageGenerator("key", srv.URL+"/v1", "flux")
+	img, err := gen.Generate(context.Background(), "a cat", ImageOptions{Seed: 42, Quality: "hd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["model"] != "flux" || got["seed"].(float64) != 42 {
+		t.Errorf("unexpected request: %v", got)
+	}
+	if _, ok := got["quality"]; ok {
+		t.Errorf("DALL-E only parameters should not be sent: %v", got)
+	}
+	if !bytes.Equal(img.Data, pngData) || img.MimeType != "image/png" || img.Seed != 42 {
+		t.Errorf("unexpected image: mime=%s seed=%d len=%d", img.MimeType, img.Seed, len(img.Data))
+	}
+}
+
+func TestImageToolEditsLastUserPhoto(t *testing.T) {
+	var fields map[string]string
+	var uploaded []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/images/edits" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+		fields = map[string]string{}
+		for k, v := range r.MultipartForm.Value {
+			fields[k] = v[0]
+		}
+		f, _, err := r.FormFile("image")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		buf.ReadFrom(f)
+		uploaded = buf.Bytes()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]string{{"b64_json": base64.StdEncoding.EncodeToString(testPNG(t, 2, 2))}},
+		})
+	}))
+	defer srv.Close()
+
+	scratch := t.TempDir()
+	uploads := filepath.Join(RunCodeSessionDir(scratch, "telegram:1"), "uploads")
+	os.MkdirAll(uploads, 0700)
+	var jpg bytes.Buffer
+	jpeg.Encode(&jpg, image.NewRGBA(image.Rect(0, 0, 6, 4)), nil)
+	os.WriteFile(filepath.Join(uploads, "photo_1.jpg"), []byte("old"), 0600)
+	os.Chtimes(filepath.Join(uploads, "photo_1.jpg"), time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+	os.WriteFile(filepath.Join(uploads, "photo_2.jpg"), jpg.Bytes(), 0600)
+
+	tool := NewImageToolWithProviders([]ImageProvider{{Name: "openai", Generator: NewOpenAIImageGenerator("key", srv.URL)}}, "", t.TempDir(), scratch)
+	ctx := WithSessionKey(context.Background(), "telegram:1")
+	out, err := tool.ExecuteJSON(ctx, map[string]string{"prompt": "add a hat", "image": "last"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Image edited") {
+		t.Errorf("unexpected output: %s", out)
+	}
+	if fields["prompt"] != "add a hat" || fields["model"] != "gpt-image-1" {
+		t.Errorf("unexpected edit fields: %v", fields)
+	}
+	if _, ok := fields["response_format"]; ok {
+		t.Errorf("gpt-image models reject response_format: %v", fields)
+	}
+	if http.DetectContentType(uploaded) != "image/png" {
+		t.Errorf("source photo should be converted to PNG")
+	}
+
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"prompt": "x", "image": "../../etc/passwd"}); err == nil {
+		t.Error("expected paths outside the session to be rejected")
+	}
+}
+
+func TestNewImageGeneratorValidatesConfig(t *testing.T) {
+	if _, err := NewImageGenerator(ImageProviderConfig{Type: "openai_compatible"}); err == nil {
+		t.Error("openai_compatible without base_url should fail")
+	}
+	if _, err := NewImageGenerator(ImageProviderConfig{Type: "openai"}); err == nil {
+		t.Error("openai without api_key should fail")
+	}
+	if _, err := NewImageGenerator(ImageProviderConfig{Type: "midjourney"}); err == nil {
+		t.Error("unknown type should fail")
+	}
+	if _, _, err := parseImageSize("huge"); err == nil {
+		t.Error("invalid size should fail")
+	}
+}
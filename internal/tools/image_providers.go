@@ -0,0 +1,289 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"  // register decoders for source images
+	_ "image/jpeg" // (Telegram photos are JPEG)
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAutomatic1111URL = "http://127.0.0.1:7860"
+	maxImageDownload        = 20 * 1024 * 1024
+)
+
+// ImageProviderConfig describes one image generation backend.
+type ImageProviderConfig struct {
+	Name           string // name used with --provider (default: the type)
+	Type           string // "openai", "openai_compatible", or "automatic1111"
+	BaseURL        string // API base URL (required for openai_compatible)
+	APIKey         string // bearer token; "user:password" for automatic1111 --api-auth
+	Model          string // default model / Stable Diffusion checkpoint
+	Steps          int    // sampling steps (automatic1111, 0 = 25)
+	NegativePrompt string // default negative prompt (automatic1111)
+}
+
+// NewImageGenerator builds an image backend from config. It fails when a
+// required URL or API key is missing.
+func NewImageGenerator(cfg ImageProviderConfig) (ImageGenerator, error) {
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	apiKey := strings.TrimSpace(cfg.APIKey)
+
+	switch strings.ToLower(strings.TrimSpace(cfg.Type)) {
+	case "openai":
+		if apiKey == "" {
+			return nil, fmt.Errorf("openai: api_key is required")
+		}
+		g := NewOpenAIImageGenerator(apiKey, baseURL)
+		g.model = cfg.Model
+		return g, nil
+	case "openai_compatible":
+		if baseURL == "" {
+			return nil, fmt.Errorf("openai_compatible: base_url is required")
+		}
+		return NewOpenAICompatibleImageGenerator(apiKey, baseURL, cfg.Model), nil
+	case "automatic1111":
+		return NewAutomatic1111Generator(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown image provider type %q", cfg.Type)
+	}
+}
+
+// Automatic1111Generator talks to the Stable Diffusion web UI API
+// (/sdapi/v1/txt2img and /sdapi/v1/img2img). Forge, SD.Next and ComfyUI
+// bridges that expose the same API work as well.
+type Automatic1111Generator struct {
+	baseURL        string
+	auth           string // "user:password" for --api-auth
+	model          string
+	steps          int
+	negativePrompt string
+	client         *http.Client
+}
+
+// NewAutomatic1111Generator creates a generator for a local Stable Diffusion web UI.
+func NewAutomatic1111Generator(cfg ImageProviderConfig) *Automatic1111Generator {
+	steps := cfg.Steps
+	if steps <= 0 {
+		steps = 25
+	}
+	return &Automatic1111Generator{
+		baseURL:        orDefault(strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/"), defaultAutomatic1111URL),
+		auth:           strings.TrimSpace(cfg.APIKey),
+		model:          cfg.Model,
+		steps:          steps,
+		negativePrompt: cfg.NegativePrompt,
+		client:         &http.Client{Timeout: 10 * time.Minute}, // local GPUs can be slow
+	}
+}
+
+// Generate creates an image with txt2img.
+func (g *Automatic1111Generator) Generate(ctx context.Context, prompt string, opts ImageOptions) (*GeneratedImage, error) {
+	width, height, err := parseImageSize(orDefault(opts.Size, "1024x1024"))
+	if err != nil {
+		return nil, err
+	}
+	body := g.baseRequest(prompt, opts)
+	body["width"] = width
+	body["height"] = height
+	return g.post(ctx, "/sdapi/v1/txt2img", body, opts)
+}
+
+// Edit reworks an image with img2img. Without a prompt the source is redrawn
+// with light denoising, which gives a variation.
+func (g *Automatic1111Generator) Edit(ctx context.Context, imagePath, prompt string, opts ImageOptions) (*GeneratedImage, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	var width, height int
+	if opts.Size != "" {
+		if width, height, err = parseImageSize(opts.Size); err != nil {
+			return nil, err
+		}
+	} else {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("unsupported image: %w", err)
+		}
+		// Stable Diffusion needs dimensions divisible by 8.
+		width, height = cfg.Width/8*8, cfg.Height/8*8
+	}
+
+	strength := opts.Strength
+	if strength == 0 {
+		strength = 0.6
+		if prompt == "" {
+			strength = 0.4
+		}
+	}
+
+	body := g.baseRequest(prompt, opts)
+	body["init_images"] = []string{base64.StdEncoding.EncodeToString(data)}
+	body["denoising_strength"] = strength
+	body["width"] = width
+	body["height"] = height
+	return g.post(ctx, "/sdapi/v1/img2img", body, opts)
+}
+
+func (g *Automatic1111Generator) baseRequest(prompt string, opts ImageOptions) map[string]interface{} {
+	seed := opts.Seed
+	if seed == 0 {
+		seed = -1 // random
+	}
+	body := map[string]interface{}{
+		"prompt":          prompt,
+		"negative_prompt": orDefault(opts.NegativePrompt, g.negativePrompt),
+		"seed":            seed,
+		"steps":           g.steps,
+		"batch_size":      1,
+		"n_iter":          1,
+	}
+	if model := orDefault(opts.Model, g.model); model != "" {
+		body["override_settings"] = map[string]string{"sd_model_checkpoint": model}
+	}
+	return body
+}
+
+func (g *Automatic1111Generator) post(ctx context.Context, path string, body map[string]interface{}, opts ImageOptions) (*GeneratedImage, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", g.baseURL+path, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if user, pass, ok := strings.Cut(g.auth, ":"); ok {
+		req.SetBasicAuth(user, pass)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, truncateCaption(string(respBody)))
+	}
+
+	var result struct {
+		Images []string `json:"images"`
+		Info   string   `json:"info"` // JSON document encoded as a string
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(result.Images) == 0 {
+		return nil, fmt.Errorf("no image generated")
+	}
+
+	// Images may carry a data URL prefix.
+	encoded := result.Images[0]
+	if i := strings.Index(encoded, ","); i >= 0 && strings.HasPrefix(encoded, "data:") {
+		encoded = encoded[i+1:]
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	img := &GeneratedImage{Data: data, MimeType: http.DetectContentType(data), Seed: opts.Seed}
+	var info struct {
+		Seed        int64  `json:"seed"`
+		SDModelName string `json:"sd_model_name"`
+	}
+	if result.Info != "" && json.Unmarshal([]byte(result.Info), &info) == nil {
+		if info.Seed > 0 {
+			img.Seed = info.Seed
+		}
+		img.Model = info.SDModelName
+	}
+	if img.Model == "" {
+		img.Model = orDefault(opts.Model, g.model)
+	}
+	return img, nil
+}
+
+// parseImageSize parses "WIDTHxHEIGHT".
+func parseImageSize(size string) (int, int, error) {
+	w, h, ok := strings.Cut(strings.ToLower(size), "x")
+	if ok {
+		width, errW := strconv.Atoi(strings.TrimSpace(w))
+		height, errH := strconv.Atoi(strings.TrimSpace(h))
+		if errW == nil && errH == nil && width > 0 && height > 0 && width <= 4096 && height <= 4096 {
+			return width, height, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid size %q: use WIDTHxHEIGHT, e.g. 1024x1024", size)
+}
+
+// downloadImage fetches an image URL returned by a provider.
+func downloadImage(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download image: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageDownload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	return data, nil
+}
+
+// imageAsPNG returns the image at path encoded as PNG, center-cropped to a
+// square when square is set.
+func imageAsPNG(path string, square bool) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if !square && http.DetectContentType(data) == "image/png" {
+		return data, nil
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image: %w", err)
+	}
+	bounds := src.Bounds()
+	if square && bounds.Dx() != bounds.Dy() {
+		side := min(bounds.Dx(), bounds.Dy())
+		x := bounds.Min.X + (bounds.Dx()-side)/2
+		y := bounds.Min.Y + (bounds.Dy()-side)/2
+		bounds = image.Rect(x, y, x+side, y+side)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
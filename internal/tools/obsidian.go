@@ -4,24 +4,66 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
+
+	"ok-gobot/internal/logger"
+	"ok-gobot/internal/memory"
+)
+
+const (
+	defaultObsidianSearchLimit = 10
+	defaultObsidianListLimit   = 50
 )
 
+// MemorySearcher runs semantic search over indexed memory.
+// Implemented by memory.MemoryManager.
+type MemorySearcher interface {
+	Search(ctx context.Context, query string, topK int) ([]memory.MemoryResult, error)
+}
+
+// ObsidianConfig configures the obsidian tool.
+type ObsidianConfig struct {
+	VaultPath     string         // vault directory (default ~/Obsidian)
+	DailyFolder   string         // overrides the vault's daily-notes settings
+	DailyFormat   string         // daily note name as a moment.js format
+	DailyTemplate string         // template note for new daily notes
+	Indexer       MemoryIndexer  // indexes notes into semantic memory (nil = off)
+	Searcher      MemorySearcher // semantic search over indexed notes (nil = full-text only)
+}
+
 // ObsidianTool provides access to Obsidian vault
 type ObsidianTool struct {
 	VaultPath string
+
+	dailyFolder   string
+	dailyFormat   string
+	dailyTemplate string
+	indexer       MemoryIndexer
+	searcher      MemorySearcher
+	now           func() time.Time
 }
 
 // NewObsidianTool creates a new Obsidian tool
-func NewObsidianTool(vaultPath string) *ObsidianTool {
+func NewObsidianTool(cfg ObsidianConfig) *ObsidianTool {
+	vaultPath := cfg.VaultPath
 	if vaultPath == "" {
 		// Default vault location
 		homeDir, _ := os.UserHomeDir()
 		vaultPath = filepath.Join(homeDir, "Obsidian")
 	}
-	return &ObsidianTool{VaultPath: vaultPath}
+	return &ObsidianTool{
+		VaultPath:     vaultPath,
+		dailyFolder:   cfg.DailyFolder,
+		dailyFormat:   cfg.DailyFormat,
+		dailyTemplate: cfg.DailyTemplate,
+		indexer:       cfg.Indexer,
+		searcher:      cfg.Searcher,
+		now:           time.Now,
+	}
 }
 
 func (o *ObsidianTool) Name() string {
@@ -29,30 +71,187 @@ func (o *ObsidianTool) Name() string {
 }
 
 func (o *ObsidianTool) Description() string {
-	return "Read and write Obsidian vault notes"
+	return "Work with the Obsidian vault: read, write and append notes (also under a heading), follow [[wikilinks]] and backlinks, query frontmatter properties and tags, open or create daily notes, and search notes ranked by relevance"
+}
+
+// GetSchema returns the JSON Schema for obsidian parameters.
+func (o *ObsidianTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"read", "write", "append", "append_heading", "list", "search", "links", "backlinks", "frontmatter", "query", "daily"},
+				"description": "read/write/append a note; append_heading adds content at the end of a heading's section; " +
+					"list a folder; search notes by text; links = outgoing [[wikilinks]] of a note; backlinks = notes linking to it; " +
+					"frontmatter = a note's properties and tags; query = notes by tag and/or property; daily = open or create a daily note",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Vault-relative note path without .md (e.g. Projects/Roadmap), or folder for list",
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "Markdown to write or append (write, append, append_heading, daily)",
+			},
+			"heading": map[string]interface{}{
+				"type":        "string",
+				"description": "Heading whose section receives content (append_heading, daily). Created at the end of the note if missing",
+			},
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Search text",
+			},
+			"semantic": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Search by meaning through semantic memory instead of by words (needs index_memory)",
+			},
+			"tag": map[string]interface{}{
+				"type":        "string",
+				"description": "Tag to filter by, with or without # (query). Nested tags match their parent",
+			},
+			"property": map[string]interface{}{
+				"type":        "string",
+				"description": "Frontmatter property to filter by (query)",
+			},
+			"value": map[string]interface{}{
+				"type":        "string",
+				"description": "Required property value, case-insensitive; omit to match any value (query)",
+			},
+			"date": map[string]interface{}{
+				"type":        "string",
+				"description": "Daily note date: YYYY-MM-DD, today, yesterday, tomorrow or a day offset like -2 (default today)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum results (search default 10, others 50)",
+			},
+		},
+		"required": []string{"action"},
+	}
 }
 
 func (o *ObsidianTool) Execute(ctx context.Context, args ...string) (string, error) {
-	if len(args) < 2 {
-		return "", fmt.Errorf("usage: obsidian <read|write|list> <path> [content]")
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: obsidian <read|write|append|list|search|links|backlinks|frontmatter|daily> <path|query> [content]")
 	}
 
-	operation := args[0]
-	path := args[1]
+	params := map[string]string{"action": args[0]}
+	switch args[0] {
+	case "search":
+		params["query"] = strings.Join(args[1:], " ")
+	case "daily":
+		if len(args) > 1 {
+			params["date"] = args[1]
+		}
+		if len(args) > 2 {
+			params["content"] = strings.Join(args[2:], " ")
+		}
+	default:
+		if len(args) < 2 && args[0] != "list" {
+			return "", fmt.Errorf("usage: obsidian %s <path> [content]", args[0])
+		}
+		if len(args) > 1 {
+			params["path"] = args[1]
+		}
+		if len(args) > 2 {
+			params["content"] = strings.Join(args[2:], " ")
+		}
+	}
+	return o.ExecuteJSON(ctx, params)
+}
+
+// ExecuteJSON runs one vault action.
+func (o *ObsidianTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	action := strings.TrimSpace(params["action"])
+	notePath := strings.TrimSpace(params["path"])
+	content := params["content"]
 
-	switch operation {
+	needPath := func() error {
+		if notePath == "" {
+			return fmt.Errorf("path is required for %s", action)
+		}
+		return nil
+	}
+	limit, err := parseNonNegativeInt(params["limit"], 0)
+	if err != nil {
+		return "", fmt.Errorf("invalid limit: %w", err)
+	}
+	listLimit := limit
+	if listLimit == 0 {
+		listLimit = defaultObsidianListLimit
+	}
+
+	switch action {
 	case "read":
-		return o.ReadNote(path)
+		if err := needPath(); err != nil {
+			return "", err
+		}
+		return o.ReadNote(notePath)
 	case "write":
-		if len(args) < 3 {
+		if err := needPath(); err != nil {
+			return "", err
+		}
+		if content == "" {
 			return "", fmt.Errorf("content required for write")
 		}
-		content := strings.Join(args[2:], " ")
-		return "", o.WriteNote(path, content)
+		if err := o.writeNote(ctx, notePath, content); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Wrote %s", notePath), nil
+	case "append":
+		if err := needPath(); err != nil {
+			return "", err
+		}
+		if content == "" {
+			return "", fmt.Errorf("content required for append")
+		}
+		return o.appendNote(ctx, notePath, "", content)
+	case "append_heading":
+		if err := needPath(); err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(params["heading"]) == "" || content == "" {
+			return "", fmt.Errorf("heading and content are required for append_heading")
+		}
+		return o.appendNote(ctx, notePath, params["heading"], content)
 	case "list":
-		return o.ListNotes(path)
+		return o.ListNotes(notePath)
+	case "search":
+		query := strings.TrimSpace(params["query"])
+		if query == "" {
+			return "", fmt.Errorf("query is required for search")
+		}
+		if limit == 0 {
+			limit = defaultObsidianSearchLimit
+		}
+		if params["semantic"] == "true" {
+			return o.semanticSearch(ctx, query, limit)
+		}
+		return o.search(query, limit)
+	case "links":
+		if err := needPath(); err != nil {
+			return "", err
+		}
+		return o.outgoingLinks(notePath)
+	case "backlinks":
+		if err := needPath(); err != nil {
+			return "", err
+		}
+		return o.backlinks(notePath, listLimit)
+	case "frontmatter":
+		if err := needPath(); err != nil {
+			return "", err
+		}
+		return o.frontmatter(notePath)
+	case "query":
+		return o.query(params["tag"], params["property"], params["value"], listLimit)
+	case "daily":
+		return o.daily(ctx, params["date"], params["heading"], content)
+	case "":
+		return "", fmt.Errorf("action is required")
 	default:
-		return "", fmt.Errorf("unknown operation: %s", operation)
+		return "", fmt.Errorf("unknown operation: %s", action)
 	}
 }
 
@@ -88,16 +287,11 @@ func (o *ObsidianTool) resolveVaultPath(relativePath string) (string, error) {
 
 // ReadNote reads a note from the vault
 func (o *ObsidianTool) ReadNote(relativePath string) (string, error) {
-	fullPath, err := o.resolveVaultPath(relativePath)
+	fullPath, err := o.notePath(relativePath)
 	if err != nil {
 		return "", err
 	}
 
-	// Add .md extension if not present
-	if !strings.HasSuffix(fullPath, ".md") {
-		fullPath += ".md"
-	}
-
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -109,32 +303,100 @@ func (o *ObsidianTool) ReadNote(relativePath string) (string, error) {
 	return string(content), nil
 }
 
-// WriteNote writes a note to the vault
-func (o *ObsidianTool) WriteNote(relativePath string, content string) error {
+// notePath resolves a vault-relative note path to its .md file.
+func (o *ObsidianTool) notePath(relativePath string) (string, error) {
 	fullPath, err := o.resolveVaultPath(relativePath)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// Add .md extension if not present
 	if !strings.HasSuffix(fullPath, ".md") {
 		fullPath += ".md"
 	}
+	return fullPath, nil
+}
 
-	// Ensure directory exists
-	dir := filepath.Dir(fullPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+// WriteNote writes a note to the vault
+func (o *ObsidianTool) WriteNote(relativePath string, content string) error {
+	return o.writeNote(context.Background(), relativePath, content)
+}
+
+func (o *ObsidianTool) writeNote(ctx context.Context, relativePath, content string) error {
+	fullPath, err := o.notePath(relativePath)
+	if err != nil {
+		return err
 	}
 
 	// Add frontmatter if not present
 	if !strings.HasPrefix(content, "---") {
 		frontmatter := fmt.Sprintf("---\ncreated: %s\n---\n\n",
-			time.Now().Format("2006-01-02 15:04"))
+			o.now().Format("2006-01-02 15:04"))
 		content = frontmatter + content
 	}
 
-	return os.WriteFile(fullPath, []byte(content), 0644)
+	return o.saveFile(ctx, fullPath, content)
+}
+
+// saveFile snapshots, writes and (when enabled) re-indexes a note file.
+func (o *ObsidianTool) saveFile(ctx context.Context, fullPath, content string) error {
+	if err := snapshotFiles(ctx, "obsidian", fullPath); err != nil {
+		return err
+	}
+
+	// Ensure directory exists
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		return err
+	}
+	o.indexNote(ctx, fullPath)
+	return nil
+}
+
+// appendNote adds content at the end of a note, or at the end of heading's
+// section when heading is set. Missing notes and headings are created.
+func (o *ObsidianTool) appendNote(ctx context.Context, relativePath, heading, content string) (string, error) {
+	fullPath, err := o.notePath(relativePath)
+	if err != nil {
+		return "", err
+	}
+	existing, err := os.ReadFile(fullPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	updated, msg := appendToNote(string(existing), heading, content)
+	if err := o.saveFile(ctx, fullPath, updated); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s", msg, relativePath), nil
+}
+
+// appendToNote returns existing with content appended and a short summary.
+func appendToNote(existing, heading, content string) (string, string) {
+	content = strings.TrimRight(content, "\n")
+	if strings.TrimSpace(heading) == "" {
+		if strings.TrimSpace(existing) == "" {
+			return content + "\n", "Created"
+		}
+		return strings.TrimRight(existing, "\n") + "\n" + content + "\n", "Appended to"
+	}
+	if updated, ok := insertUnderHeading(existing, heading, content); ok {
+		return updated, fmt.Sprintf("Appended under %q in", strings.TrimLeft(strings.TrimSpace(heading), "# "))
+	}
+
+	// Heading not found: add it as a level-2 heading unless #s were given.
+	title := strings.TrimSpace(heading)
+	if !strings.HasPrefix(title, "#") {
+		title = "## " + title
+	}
+	section := title + "\n\n" + content + "\n"
+	if strings.TrimSpace(existing) == "" {
+		return section, fmt.Sprintf("Created heading %q in", strings.TrimLeft(title, "# "))
+	}
+	return strings.TrimRight(existing, "\n") + "\n\n" + section, fmt.Sprintf("Created heading %q in", strings.TrimLeft(title, "# "))
 }
 
 // ListNotes lists notes in a directory
@@ -153,6 +415,9 @@ func (o *ObsidianTool) ListNotes(relativePath string) (string, error) {
 	result.WriteString(fmt.Sprintf("Notes in %s:\n\n", relativePath))
 
 	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
 		if entry.IsDir() {
 			result.WriteString(fmt.Sprintf("📁 %s/\n", entry.Name()))
 		} else if strings.HasSuffix(entry.Name(), ".md") {
@@ -163,30 +428,351 @@ func (o *ObsidianTool) ListNotes(relativePath string) (string, error) {
 	return result.String(), nil
 }
 
-// SearchNotes searches for notes containing a term
-func (o *ObsidianTool) SearchNotes(term string) ([]string, error) {
-	var matches []string
+// SearchNotes ranks notes against query by full-text relevance.
+func (o *ObsidianTool) SearchNotes(query string, limit int) ([]NoteHit, error) {
+	idx, err := loadVault(o.VaultPath)
+	if err != nil {
+		return nil, err
+	}
+	hits := rankNotes(idx.notes, query)
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
 
-	err := filepath.Walk(o.VaultPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+func (o *ObsidianTool) search(query string, limit int) (string, error) {
+	hits, err := o.SearchNotes(query, limit)
+	if err != nil {
+		return "", err
+	}
+	if len(hits) == 0 {
+		return fmt.Sprintf("No notes match %q", query), nil
+	}
+	terms := len(searchTerms(query))
+	var b strings.Builder
+	fmt.Fprintf(&b, "Notes matching %q:\n\n", query)
+	for i, h := range hits {
+		fmt.Fprintf(&b, "%d. %s (score %.1f", i+1, h.Path, h.Score)
+		if h.Matched < terms {
+			fmt.Fprintf(&b, ", %d/%d terms", h.Matched, terms)
+		}
+		b.WriteString(")\n")
+		if h.Snippet != "" {
+			fmt.Fprintf(&b, "   %s\n", h.Snippet)
 		}
+	}
+	return b.String(), nil
+}
+
+// memoryPrefix is the source-file prefix of vault notes in semantic memory.
+func (o *ObsidianTool) memoryPrefix() string {
+	return filepath.Base(filepath.Clean(o.VaultPath)) + "/"
+}
 
-		if !info.IsDir() && strings.HasSuffix(path, ".md") {
-			content, err := os.ReadFile(path)
-			if err != nil {
-				return nil // Skip files we can't read
+func (o *ObsidianTool) semanticSearch(ctx context.Context, query string, limit int) (string, error) {
+	if o.searcher == nil {
+		return "", fmt.Errorf("semantic search is unavailable: set tools.obsidian.index_memory and enable memory")
+	}
+	results, err := o.searcher.Search(ctx, query, limit*4)
+	if err != nil {
+		return "", err
+	}
+	prefix := o.memoryPrefix()
+	seen := make(map[string]bool)
+	var b strings.Builder
+	n := 0
+	for _, r := range results {
+		if !strings.HasPrefix(r.SourceFile, prefix) {
+			continue
+		}
+		note := strings.TrimSuffix(strings.TrimPrefix(r.SourceFile, prefix), ".md")
+		if seen[note] {
+			continue
+		}
+		seen[note] = true
+		n++
+		if n == 1 {
+			fmt.Fprintf(&b, "Notes related to %q:\n\n", query)
+		}
+		fmt.Fprintf(&b, "%d. %s (similarity %.2f)", n, note, r.Similarity)
+		if r.HeaderPath != "" {
+			fmt.Fprintf(&b, " — %s", r.HeaderPath)
+		}
+		fmt.Fprintf(&b, "\n   %s\n", truncateRunes(strings.Join(strings.Fields(r.Content), " "), 200))
+		if n == limit {
+			break
+		}
+	}
+	if n == 0 {
+		return fmt.Sprintf("No indexed notes relate to %q", query), nil
+	}
+	return b.String(), nil
+}
+
+// loadNote loads the vault and finds the note for a path or link target.
+func (o *ObsidianTool) loadNote(relativePath string) (*vaultIndex, *vaultNote, error) {
+	if _, err := o.resolveVaultPath(relativePath); err != nil {
+		return nil, nil, err
+	}
+	idx, err := loadVault(o.VaultPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	note := idx.resolve(filepath.ToSlash(filepath.Clean(relativePath)))
+	if note == nil {
+		return nil, nil, fmt.Errorf("note not found: %s", relativePath)
+	}
+	return idx, note, nil
+}
+
+func (o *ObsidianTool) outgoingLinks(relativePath string) (string, error) {
+	idx, note, err := o.loadNote(relativePath)
+	if err != nil {
+		return "", err
+	}
+	links := note.links()
+	if len(links) == 0 {
+		return fmt.Sprintf("%s has no [[links]]", note.Path), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Links from %s:\n\n", note.Path)
+	seen := make(map[string]bool)
+	for _, l := range links {
+		key := l.Target + l.Heading
+		if seen[key] || l.Target == "" {
+			continue
+		}
+		seen[key] = true
+		kind := "→"
+		if l.Embed {
+			kind = "⤷ embed"
+		}
+		if target := idx.resolve(l.Target); target != nil {
+			fmt.Fprintf(&b, "%s %s%s\n", kind, target.Path, l.Heading)
+		} else if path.Ext(l.Target) != "" {
+			fmt.Fprintf(&b, "%s %s (attachment)\n", kind, l.Target)
+		} else {
+			fmt.Fprintf(&b, "%s %s%s (unresolved)\n", kind, l.Target, l.Heading)
+		}
+	}
+	return b.String(), nil
+}
+
+func (o *ObsidianTool) backlinks(relativePath string, limit int) (string, error) {
+	idx, note, err := o.loadNote(relativePath)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	count := 0
+	for _, src := range idx.notes {
+		if src == note {
+			continue
+		}
+		lines := strings.Split(src.Content, "\n")
+		for _, l := range src.links() {
+			if idx.resolve(l.Target) != note {
+				continue
+			}
+			count++
+			if count > limit {
+				break
 			}
+			fmt.Fprintf(&b, "← %s:%d  %s\n", src.Path, l.Line, truncateRunes(strings.TrimSpace(lines[l.Line-1]), 160))
+		}
+	}
+	if count == 0 {
+		return fmt.Sprintf("No notes link to %s", note.Path), nil
+	}
+	header := fmt.Sprintf("Backlinks to %s:\n\n", note.Path)
+	if count > limit {
+		return header + b.String() + fmt.Sprintf("… %d more\n", count-limit), nil
+	}
+	return header + b.String(), nil
+}
+
+func (o *ObsidianTool) frontmatter(relativePath string) (string, error) {
+	_, note, err := o.loadNote(relativePath)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", note.Path)
+	if strings.TrimSpace(note.rawFront) == "" {
+		b.WriteString("\nNo frontmatter\n")
+	} else {
+		fmt.Fprintf(&b, "\n---\n%s---\n", note.rawFront)
+	}
+	if tags := note.tags(); len(tags) > 0 {
+		fmt.Fprintf(&b, "\nTags: #%s\n", strings.Join(tags, " #"))
+	}
+	return b.String(), nil
+}
+
+func (o *ObsidianTool) query(tag, property, value string, limit int) (string, error) {
+	tag = strings.TrimSpace(tag)
+	property = strings.TrimSpace(property)
+	value = strings.TrimSpace(value)
+	if tag == "" && property == "" {
+		return "", fmt.Errorf("tag or property is required for query")
+	}
+	idx, err := loadVault(o.VaultPath)
+	if err != nil {
+		return "", err
+	}
 
-			if strings.Contains(string(content), term) {
-				// Get relative path
-				rel, _ := filepath.Rel(o.VaultPath, path)
-				matches = append(matches, strings.TrimSuffix(rel, ".md"))
+	var matches []string
+	for _, n := range idx.notes {
+		if tag != "" && !n.hasTag(tag) {
+			continue
+		}
+		line := n.Path
+		if property != "" {
+			shown, ok := n.propertyMatches(property, value)
+			if !ok {
+				continue
 			}
+			line += fmt.Sprintf("  (%s: %s)", property, shown)
 		}
+		matches = append(matches, line)
+	}
 
-		return nil
-	})
+	var filters []string
+	if tag != "" {
+		filters = append(filters, "#"+strings.TrimPrefix(tag, "#"))
+	}
+	if property != "" {
+		if value != "" {
+			filters = append(filters, fmt.Sprintf("%s = %s", property, value))
+		} else {
+			filters = append(filters, "has "+property)
+		}
+	}
+	if len(matches) == 0 {
+		return fmt.Sprintf("No notes with %s", strings.Join(filters, " and ")), nil
+	}
+	sort.Strings(matches)
+	total := len(matches)
+	if total > limit {
+		matches = matches[:limit]
+	}
+	out := fmt.Sprintf("%d note(s) with %s:\n\n%s\n", total, strings.Join(filters, " and "), strings.Join(matches, "\n"))
+	if total > limit {
+		out += fmt.Sprintf("… %d more\n", total-limit)
+	}
+	return out, nil
+}
+
+// dailySettings merges configured daily-note settings over the vault's own.
+func (o *ObsidianTool) dailySettings() dailyNoteSettings {
+	s := readDailyNoteSettings(o.VaultPath)
+	if o.dailyFolder != "" {
+		s.Folder = o.dailyFolder
+	}
+	if o.dailyFormat != "" {
+		s.Format = o.dailyFormat
+	}
+	if o.dailyTemplate != "" {
+		s.Template = o.dailyTemplate
+	}
+	if s.Format == "" {
+		s.Format = "YYYY-MM-DD"
+	}
+	return s
+}
+
+// daily returns the daily note for date, creating it from the vault's
+// template first if needed. Content, if given, is appended (under heading).
+func (o *ObsidianTool) daily(ctx context.Context, date, heading, content string) (string, error) {
+	day, err := parseNoteDate(date, o.now())
+	if err != nil {
+		return "", err
+	}
+	settings := o.dailySettings()
+	title := formatMoment(day, settings.Format)
+	relativePath := path.Join(strings.Trim(settings.Folder, "/"), title)
+
+	fullPath, err := o.notePath(relativePath)
+	if err != nil {
+		return "", err
+	}
+	existing, err := os.ReadFile(fullPath)
+	created := false
+	if os.IsNotExist(err) {
+		body, err := o.dailyTemplateBody(settings.Template, path.Base(title), day)
+		if err != nil {
+			return "", err
+		}
+		existing = []byte(body)
+		created = true
+	} else if err != nil {
+		return "", err
+	}
+
+	text := string(existing)
+	if content != "" {
+		text, _ = appendToNote(text, heading, content)
+	}
+	if created || content != "" {
+		if err := o.saveFile(ctx, fullPath, text); err != nil {
+			return "", err
+		}
+	}
+
+	status := "Daily note"
+	if created {
+		status = "Created daily note"
+	}
+	return fmt.Sprintf("%s %s\n\n%s", status, relativePath, text), nil
+}
+
+// dailyTemplateBody renders the daily-note template, or a bare title heading
+// when the vault has none.
+func (o *ObsidianTool) dailyTemplateBody(template, title string, day time.Time) (string, error) {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Sprintf("# %s\n", title), nil
+	}
+	tmpl, err := o.ReadNote(template)
+	if err != nil {
+		return "", fmt.Errorf("daily note template: %w", err)
+	}
+	return renderTemplate(tmpl, title, day), nil
+}
+
+// indexNote re-indexes a written note into semantic memory.
+func (o *ObsidianTool) indexNote(ctx context.Context, fullPath string) {
+	if o.indexer == nil {
+		return
+	}
+	if err := o.indexer.IndexFile(ctx, filepath.Dir(filepath.Clean(o.VaultPath)), fullPath); err != nil {
+		logger.Debugf("Obsidian: failed to index %s: %v", fullPath, err)
+	}
+}
 
-	return matches, err
+// IndexVault indexes every note into semantic memory. Unchanged chunks are
+// skipped by the indexer, so repeated runs only embed what changed.
+func (o *ObsidianTool) IndexVault(ctx context.Context) (int, error) {
+	if o.indexer == nil {
+		return 0, nil
+	}
+	idx, err := loadVault(o.VaultPath)
+	if err != nil {
+		return 0, err
+	}
+	root := filepath.Dir(filepath.Clean(o.VaultPath))
+	indexed := 0
+	for _, n := range idx.notes {
+		if err := ctx.Err(); err != nil {
+			return indexed, err
+		}
+		fullPath := filepath.Join(o.VaultPath, filepath.FromSlash(n.Path)+".md")
+		if err := o.indexer.IndexFile(ctx, root, fullPath); err != nil {
+			return indexed, fmt.Errorf("index %s: %w", n.Path, err)
+		}
+		indexed++
+	}
+	return indexed, nil
 }
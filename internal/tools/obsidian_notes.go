@@ -0,0 +1,609 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// wikilinkRe matches [[target]], [[target#heading]], [[target|alias]] and
+	// embeds (![[target]]).
+	wikilinkRe = regexp.MustCompile(`(!?)\[\[([^\[\]|#^]*)([#^][^\[\]|]*)?(?:\|[^\[\]]*)?\]\]`)
+	// inlineTagRe matches #tags in note bodies; a tag needs at least one non-digit.
+	inlineTagRe   = regexp.MustCompile(`(?:^|[\s(,])#([\p{L}\p{N}_/-]*[\p{L}_/-][\p{L}\p{N}_/-]*)`)
+	inlineCodeRe  = regexp.MustCompile("`[^`\n]*`")
+	noteHeadingRe = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	templateVarRe = regexp.MustCompile(`\{\{\s*(date|time|title)(?::([^}]*))?\s*\}\}`)
+)
+
+// vaultNote is one markdown note loaded from the vault.
+type vaultNote struct {
+	Path        string // vault-relative, slash-separated, without .md
+	Content     string
+	Frontmatter map[string]interface{}
+	rawFront    string
+	Body        string // content after the frontmatter
+}
+
+// Name returns the note's file name without folder and extension.
+func (n *vaultNote) Name() string {
+	return path.Base(n.Path)
+}
+
+// noteLink is an outgoing [[wikilink]].
+type noteLink struct {
+	Target  string // as written, without heading or alias
+	Heading string // "#heading" or "^block", if any
+	Embed   bool
+	Line    int // 1-based line in the note
+}
+
+// vaultIndex holds all notes of a vault and resolves links between them.
+type vaultIndex struct {
+	notes  []*vaultNote
+	byPath map[string]*vaultNote   // lowercased path
+	byName map[string][]*vaultNote // lowercased file name
+}
+
+// loadVault reads every note in root, skipping dot-folders such as .obsidian and .trash.
+func loadVault(root string) (*vaultIndex, error) {
+	idx := &vaultIndex{
+		byPath: make(map[string]*vaultNote),
+		byName: make(map[string][]*vaultNote),
+	}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			return nil // skip unreadable entries
+		}
+		if d.IsDir() {
+			if p != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".md") {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		note := parseNote(strings.TrimSuffix(filepath.ToSlash(rel), ".md"), string(content))
+		idx.notes = append(idx.notes, note)
+		idx.byPath[strings.ToLower(note.Path)] = note
+		name := strings.ToLower(note.Name())
+		idx.byName[name] = append(idx.byName[name], note)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(idx.notes, func(i, j int) bool { return idx.notes[i].Path < idx.notes[j].Path })
+	return idx, nil
+}
+
+func parseNote(notePath, content string) *vaultNote {
+	note := &vaultNote{Path: notePath, Content: content, Body: content}
+	if front, body, ok := splitFrontmatter(content); ok {
+		note.rawFront = front
+		note.Body = body
+		var fm map[string]interface{}
+		if yaml.Unmarshal([]byte(front), &fm) == nil {
+			note.Frontmatter = fm
+		}
+	}
+	return note
+}
+
+// splitFrontmatter separates a leading YAML block delimited by --- lines.
+func splitFrontmatter(content string) (front, body string, ok bool) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	if !strings.HasPrefix(content, "---\n") && !strings.HasPrefix(content, "---\r\n") {
+		return "", content, false
+	}
+	rest := content[strings.Index(content, "\n")+1:]
+	for offset := 0; offset <= len(rest); {
+		end := strings.Index(rest[offset:], "\n")
+		line := rest[offset:]
+		if end >= 0 {
+			line = rest[offset : offset+end]
+		}
+		if strings.TrimRight(line, "\r") == "---" {
+			if end < 0 {
+				return rest[:offset], "", true
+			}
+			return rest[:offset], rest[offset+end+1:], true
+		}
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+	return "", content, false
+}
+
+// resolve returns the note a link target points to, preferring an exact
+// path, then a path suffix, then the shortest path with that file name.
+func (idx *vaultIndex) resolve(target string) *vaultNote {
+	target = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(target), ".md"))
+	if target == "" {
+		return nil
+	}
+	if n, ok := idx.byPath[target]; ok {
+		return n
+	}
+	var candidates []*vaultNote
+	if strings.Contains(target, "/") {
+		for _, n := range idx.notes {
+			if strings.HasSuffix(strings.ToLower(n.Path), "/"+target) {
+				candidates = append(candidates, n)
+			}
+		}
+	} else {
+		candidates = idx.byName[target]
+	}
+	var best *vaultNote
+	for _, n := range candidates {
+		if best == nil || len(n.Path) < len(best.Path) {
+			best = n
+		}
+	}
+	return best
+}
+
+// stripCode blanks fenced code blocks and inline code so links and tags in
+// code samples are ignored. Line numbers are preserved.
+func stripCode(body string) []string {
+	lines := strings.Split(body, "\n")
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			lines[i] = ""
+			continue
+		}
+		if inFence {
+			lines[i] = ""
+			continue
+		}
+		lines[i] = inlineCodeRe.ReplaceAllString(line, "")
+	}
+	return lines
+}
+
+// links returns the note's outgoing wikilinks, including those in frontmatter.
+func (n *vaultNote) links() []noteLink {
+	var out []noteLink
+	for i, line := range stripCode(n.Content) {
+		for _, m := range wikilinkRe.FindAllStringSubmatch(line, -1) {
+			out = append(out, noteLink{
+				Target:  strings.TrimSpace(m[2]),
+				Heading: m[3],
+				Embed:   m[1] == "!",
+				Line:    i + 1,
+			})
+		}
+	}
+	return out
+}
+
+// tags returns the note's tags from frontmatter and inline #tags, lowercased
+// and without the leading #.
+func (n *vaultNote) tags() []string {
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag != "" {
+			seen[tag] = true
+		}
+	}
+	for _, key := range []string{"tags", "tag"} {
+		switch v := n.Frontmatter[key].(type) {
+		case string:
+			for _, t := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+				add(t)
+			}
+		case []interface{}:
+			for _, t := range v {
+				add(fmt.Sprint(t))
+			}
+		}
+	}
+	for _, line := range stripCode(n.Body) {
+		for _, m := range inlineTagRe.FindAllStringSubmatch(line, -1) {
+			add(m[1])
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for tag := range seen {
+		out = append(out, tag)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// hasTag reports whether the note carries tag or a nested tag below it.
+func (n *vaultNote) hasTag(tag string) bool {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	for _, t := range n.tags() {
+		if t == tag || strings.HasPrefix(t, tag+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// propertyMatches reports whether frontmatter key equals want (case-insensitive).
+// List properties match if any element does; an empty want matches any value.
+func (n *vaultNote) propertyMatches(key, want string) (string, bool) {
+	var value interface{}
+	found := false
+	for k, v := range n.Frontmatter {
+		if strings.EqualFold(k, key) {
+			value, found = v, true
+			break
+		}
+	}
+	if !found {
+		return "", false
+	}
+	shown := formatProperty(value)
+	if want == "" {
+		return shown, true
+	}
+	match := func(v interface{}) bool {
+		s := strings.Trim(fmt.Sprint(v), "[]")
+		return strings.EqualFold(strings.TrimSpace(s), want) || strings.EqualFold(fmt.Sprint(v), want)
+	}
+	if list, ok := value.([]interface{}); ok {
+		for _, v := range list {
+			if match(v) {
+				return shown, true
+			}
+		}
+		return shown, false
+	}
+	return shown, match(value)
+}
+
+func formatProperty(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return val.Format("2006-01-02")
+	case []interface{}:
+		parts := make([]string, len(val))
+		for i, p := range val {
+			parts[i] = formatProperty(p)
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// NoteHit is a ranked full-text search result.
+type NoteHit struct {
+	Path    string
+	Score   float64
+	Matched int // number of query terms found
+	Snippet string
+}
+
+// searchTerms splits a query into lowercased, de-duplicated words.
+func searchTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, t := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// rankNotes scores notes against query. Notes matching more terms rank first;
+// within that, matches in the title, headings and tags outweigh body hits,
+// body hits are damped logarithmically, and an exact phrase earns a bonus.
+func rankNotes(notes []*vaultNote, query string) []NoteHit {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	phrase := strings.ToLower(strings.TrimSpace(query))
+
+	var hits []NoteHit
+	for _, n := range notes {
+		title := strings.ToLower(n.Name())
+		body := strings.ToLower(n.Content)
+		var headings strings.Builder
+		for _, line := range strings.Split(n.Body, "\n") {
+			if m := noteHeadingRe.FindStringSubmatch(line); m != nil {
+				headings.WriteString(strings.ToLower(m[2]))
+				headings.WriteByte('\n')
+			}
+		}
+		tags := strings.ToLower(strings.Join(n.tags(), " "))
+
+		hit := NoteHit{Path: n.Path}
+		for _, term := range terms {
+			count := strings.Count(body, term)
+			inTitle := strings.Contains(title, term)
+			if count == 0 && !inTitle {
+				continue
+			}
+			hit.Matched++
+			hit.Score += 1 + math.Log1p(float64(count))
+			if inTitle {
+				hit.Score += 5
+			}
+			if strings.Contains(headings.String(), term) {
+				hit.Score += 2
+			}
+			if strings.Contains(tags, term) {
+				hit.Score += 3
+			}
+		}
+		if hit.Matched == 0 {
+			continue
+		}
+		if len(terms) > 1 && strings.Contains(body, phrase) {
+			hit.Score += 4
+		}
+		hit.Snippet = bestSnippet(n.Body, terms)
+		hits = append(hits, hit)
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Matched != hits[j].Matched {
+			return hits[i].Matched > hits[j].Matched
+		}
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Path < hits[j].Path
+	})
+	return hits
+}
+
+// bestSnippet returns the line containing the most query terms.
+func bestSnippet(body string, terms []string) string {
+	best, bestCount := "", 0
+	for _, line := range strings.Split(body, "\n") {
+		lower := strings.ToLower(line)
+		count := 0
+		for _, t := range terms {
+			if strings.Contains(lower, t) {
+				count++
+			}
+		}
+		if count > bestCount {
+			best, bestCount = line, count
+		}
+	}
+	return truncateRunes(strings.TrimSpace(best), 160)
+}
+
+func truncateRunes(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "…"
+}
+
+// insertUnderHeading appends text at the end of the section that starts at
+// heading (matched case-insensitively, leading #s optional). found is false
+// when the heading does not exist; the caller decides whether to create it.
+func insertUnderHeading(content, heading, text string) (string, bool) {
+	want := strings.ToLower(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(heading), "#")))
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	start, level := -1, 0
+	inFence := false
+	end := len(lines)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		m := noteHeadingRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if start < 0 {
+			if strings.ToLower(strings.TrimSpace(m[2])) == want {
+				start, level = i, len(m[1])
+			}
+			continue
+		}
+		if len(m[1]) <= level {
+			end = i
+			break
+		}
+	}
+	if start < 0 {
+		return content, false
+	}
+
+	pos := end
+	for pos > start+1 && strings.TrimSpace(lines[pos-1]) == "" {
+		pos--
+	}
+	insert := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if pos == start+1 {
+		// Empty section: keep a blank line after the heading.
+		insert = append([]string{""}, insert...)
+	}
+	if pos < len(lines) && pos == end {
+		// Keep a blank line before the next heading.
+		insert = append(insert, "")
+	}
+	out := make([]string, 0, len(lines)+len(insert))
+	out = append(out, lines[:pos]...)
+	out = append(out, insert...)
+	out = append(out, lines[pos:]...)
+	return strings.Join(out, "\n") + "\n", true
+}
+
+// dailyNoteSettings mirrors the core Daily notes plugin settings.
+type dailyNoteSettings struct {
+	Folder   string `json:"folder"`
+	Format   string `json:"format"`
+	Template string `json:"template"`
+}
+
+// readDailyNoteSettings loads .obsidian/daily-notes.json from the vault, if any.
+func readDailyNoteSettings(vault string) dailyNoteSettings {
+	var s dailyNoteSettings
+	data, err := os.ReadFile(filepath.Join(vault, ".obsidian", "daily-notes.json"))
+	if err == nil {
+		_ = json.Unmarshal(data, &s)
+	}
+	return s
+}
+
+// parseNoteDate accepts YYYY-MM-DD, today, yesterday, tomorrow, or a signed
+// day offset such as -1.
+func parseNoteDate(raw string, now time.Time) (time.Time, error) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	switch raw {
+	case "", "today":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return now.AddDate(0, 0, 1), nil
+	}
+	if strings.HasPrefix(raw, "+") || strings.HasPrefix(raw, "-") {
+		if days, err := strconv.Atoi(raw); err == nil {
+			return now.AddDate(0, 0, days), nil
+		}
+	}
+	t, err := time.ParseInLocation("2006-01-02", raw, now.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD, today, yesterday or tomorrow)", raw)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), now.Hour(), now.Minute(), now.Second(), 0, now.Location()), nil
+}
+
+// momentTokens are the moment.js format tokens Obsidian users commonly put in
+// note names and templates, longest first.
+var momentTokens = []struct {
+	token string
+	fn    func(time.Time) string
+}{
+	{"YYYY", func(t time.Time) string { return t.Format("2006") }},
+	{"YY", func(t time.Time) string { return t.Format("06") }},
+	{"MMMM", func(t time.Time) string { return t.Format("January") }},
+	{"MMM", func(t time.Time) string { return t.Format("Jan") }},
+	{"MM", func(t time.Time) string { return t.Format("01") }},
+	{"M", func(t time.Time) string { return strconv.Itoa(int(t.Month())) }},
+	{"dddd", func(t time.Time) string { return t.Format("Monday") }},
+	{"ddd", func(t time.Time) string { return t.Format("Mon") }},
+	{"Do", func(t time.Time) string { return ordinal(t.Day()) }},
+	{"DD", func(t time.Time) string { return t.Format("02") }},
+	{"D", func(t time.Time) string { return strconv.Itoa(t.Day()) }},
+	{"WW", func(t time.Time) string { _, w := t.ISOWeek(); return fmt.Sprintf("%02d", w) }},
+	{"W", func(t time.Time) string { _, w := t.ISOWeek(); return strconv.Itoa(w) }},
+	{"HH", func(t time.Time) string { return t.Format("15") }},
+	{"H", func(t time.Time) string { return strconv.Itoa(t.Hour()) }},
+	{"hh", func(t time.Time) string { return t.Format("03") }},
+	{"h", func(t time.Time) string { return t.Format("3") }},
+	{"mm", func(t time.Time) string { return t.Format("04") }},
+	{"ss", func(t time.Time) string { return t.Format("05") }},
+	{"A", func(t time.Time) string { return t.Format("PM") }},
+	{"a", func(t time.Time) string { return t.Format("pm") }},
+}
+
+// formatMoment formats t with a moment.js layout. Text in [brackets] is literal.
+func formatMoment(t time.Time, layout string) string {
+	var b strings.Builder
+	for i := 0; i < len(layout); {
+		if layout[i] == '[' {
+			if end := strings.IndexByte(layout[i:], ']'); end > 0 {
+				b.WriteString(layout[i+1 : i+end])
+				i += end + 1
+				continue
+			}
+		}
+		matched := false
+		for _, tok := range momentTokens {
+			if strings.HasPrefix(layout[i:], tok.token) {
+				b.WriteString(tok.fn(t))
+				i += len(tok.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(layout[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// renderTemplate fills the core Templates plugin variables: {{title}},
+// {{date}}, {{time}}, {{date:FORMAT}} and {{time:FORMAT}}.
+func renderTemplate(tmpl, title string, t time.Time) string {
+	return templateVarRe.ReplaceAllStringFunc(tmpl, func(m string) string {
+		parts := templateVarRe.FindStringSubmatch(m)
+		layout := strings.TrimSpace(parts[2])
+		switch parts[1] {
+		case "title":
+			return title
+		case "date":
+			if layout == "" {
+				layout = "YYYY-MM-DD"
+			}
+		case "time":
+			if layout == "" {
+				layout = "HH:mm"
+			}
+		}
+		return formatMoment(t, layout)
+	})
+}
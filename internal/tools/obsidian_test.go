@@ -0,0 +1,202 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestVault(t *testing.T, files map[string]string) *ObsidianTool {
+	t.Helper()
+	vault := filepath.Join(t.TempDir(), "Vault")
+	for name, content := range files {
+		p := filepath.Join(vault, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(vault, 0755); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewObsidianTool(ObsidianConfig{VaultPath: vault})
+	tool.now = func() time.Time { return time.Date(2026, 3, 14, 9, 30, 0, 0, time.Local) }
+	return tool
+}
+
+func runObsidian(t *testing.T, tool *ObsidianTool, params map[string]string) string {
+	t.Helper()
+	out, err := tool.ExecuteJSON(context.Background(), params)
+	if err != nil {
+		t.Fatalf("%s failed: %v", params["action"], err)
+	}
+	return out
+}
+
+func TestObsidianLinksAndBacklinks(t *testing.T) {
+	tool := newTestVault(t, map[string]string{
+		"Projects/Roadmap.md": "See [[Ideas]] and [[Ideas#Later|later ideas]], ![[diagram.png]] and [[Missing]].\n`[[NotALink]]`\n",
+		"Ideas.md":            "# Ideas\n",
+		"Journal/Mon.md":      "Worked on [[Projects/Roadmap]].\n",
+		".trash/Old.md":       "[[Projects/Roadmap]]\n",
+	})
+
+	out := runObsidian(t, tool, map[string]string{"action": "links", "path": "Projects/Roadmap"})
+	for _, want := range []string{"→ Ideas\n", "→ Ideas#Later", "diagram.png (attachment)", "Missing (unresolved)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("links output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "NotALink") {
+		t.Errorf("links inside code should be ignored:\n%s", out)
+	}
+
+	out = runObsidian(t, tool, map[string]string{"action": "backlinks", "path": "Roadmap"})
+	if !strings.Contains(out, "← Journal/Mon:1") || strings.Contains(out, ".trash") {
+		t.Errorf("unexpected backlinks:\n%s", out)
+	}
+}
+
+func TestObsidianQueryByTagAndProperty(t *testing.T) {
+	tool := newTestVault(t, map[string]string{
+		"a.md": "---\ntags: [project, work/urgent]\nstatus: active\n---\nBody\n",
+		"b.md": "---\nstatus: done\n---\nInline #project/side tag\n",
+		"c.md": "No tags here # not a tag\n```\n#code\n```\n",
+	})
+
+	out := runObsidian(t, tool, map[string]string{"action": "query", "tag": "#project"})
+	if !strings.Contains(out, "2 note(s)") || !strings.Contains(out, "a\n") || !strings.Contains(out, "b") {
+		t.Errorf("tag query: %s", out)
+	}
+
+	out = runObsidian(t, tool, map[string]string{"action": "query", "property": "status", "value": "Active"})
+	if !strings.Contains(out, "1 note(s)") || !strings.Contains(out, "a  (status: active)") {
+		t.Errorf("property query: %s", out)
+	}
+
+	out = runObsidian(t, tool, map[string]string{"action": "frontmatter", "path": "a"})
+	if !strings.Contains(out, "status: active") || !strings.Contains(out, "#project #work/urgent") {
+		t.Errorf("frontmatter: %s", out)
+	}
+	out = runObsidian(t, tool, map[string]string{"action": "frontmatter", "path": "c"})
+	if !strings.Contains(out, "No frontmatter") || strings.Contains(out, "Tags") {
+		t.Errorf("frontmatter without tags: %s", out)
+	}
+}
+
+func TestObsidianSearchRanksTitleAndAllTerms(t *testing.T) {
+	tool := newTestVault(t, map[string]string{
+		"Garden.md":      "Notes about tomatoes.\n",
+		"Recipes.md":     "Tomato soup uses garden tomatoes and basil.\n",
+		"Unrelated.md":   "Nothing to see.\n",
+		"Basil Notes.md": "Basil only.\n",
+	})
+
+	hits, err := tool.SearchNotes("garden tomatoes", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].Path != "Garden" || hits[1].Path != "Recipes" {
+		t.Fatalf("unexpected ranking: %+v", hits)
+	}
+	if hits[1].Snippet != "Tomato soup uses garden tomatoes and basil." {
+		t.Errorf("unexpected snippet %q", hits[1].Snippet)
+	}
+
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"action": "search", "query": "x", "semantic": "true"}); err == nil {
+		t.Error("expected semantic search without memory to fail")
+	}
+}
+
+func TestObsidianAppendHeading(t *testing.T) {
+	tool := newTestVault(t, map[string]string{
+		"Todo.md": "# Todo\n\n## Today\n- one\n\n## Later\n- later\n",
+	})
+
+	runObsidian(t, tool, map[string]string{"action": "append_heading", "path": "Todo", "heading": "today", "content": "- two"})
+	runObsidian(t, tool, map[string]string{"action": "append_heading", "path": "Todo", "heading": "Done", "content": "- shipped"})
+
+	got, err := tool.ReadNote("Todo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Todo\n\n## Today\n- one\n- two\n\n## Later\n- later\n\n## Done\n\n- shipped\n"
+	if got != want {
+		t.Errorf("append_heading result:\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestObsidianDailyNoteFromTemplate(t *testing.T) {
+	tool := newTestVault(t, map[string]string{
+		".obsidian/daily-notes.json": `{"folder":"Journal","format":"YYYY-MM-DD dddd","template":"Templates/Daily"}`,
+		"Templates/Daily.md":         "# {{title}}\nCreated {{date:D MMMM YYYY}} at {{time}}\n\n## Log\n",
+	})
+
+	out := runObsidian(t, tool, map[string]string{"action": "daily", "heading": "Log", "content": "- standup"})
+	if !strings.HasPrefix(out, "Created daily note Journal/2026-03-14 Saturday") {
+		t.Errorf("unexpected daily output: %s", out)
+	}
+	got, err := tool.ReadNote("Journal/2026-03-14 Saturday")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# 2026-03-14 Saturday\nCreated 14 March 2026 at 09:30\n\n## Log\n\n- standup\n"
+	if got != want {
+		t.Errorf("daily note:\n%q\nwant\n%q", got, want)
+	}
+
+	out = runObsidian(t, tool, map[string]string{"action": "daily", "date": "yesterday"})
+	if !strings.HasPrefix(out, "Created daily note Journal/2026-03-13 Friday") {
+		t.Errorf("unexpected daily output for yesterday: %s", out)
+	}
+	out = runObsidian(t, tool, map[string]string{"action": "daily"})
+	if !strings.HasPrefix(out, "Daily note Journal/2026-03-14 Saturday") {
+		t.Errorf("expected existing daily note to be reused: %s", out)
+	}
+}
+
+func TestObsidianRejectsPathsOutsideVault(t *testing.T) {
+	tool := newTestVault(t, nil)
+	for _, action := range []string{"read", "write", "append", "links"} {
+		_, err := tool.ExecuteJSON(context.Background(), map[string]string{"action": action, "path": "../secret", "content": "x"})
+		if err == nil {
+			t.Errorf("%s: expected traversal to be rejected", action)
+		}
+	}
+}
+
+func TestObsidianWritesAreIndexed(t *testing.T) {
+	tool := newTestVault(t, map[string]string{"Existing.md": "hello\n"})
+	indexer := &recordingIndexer{}
+	tool.indexer = indexer
+
+	n, err := tool.IndexVault(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("IndexVault = %d, %v", n, err)
+	}
+
+	runObsidian(t, tool, map[string]string{"action": "append", "path": "New", "content": "text"})
+	if indexer.root != filepath.Dir(tool.VaultPath) || indexer.path != filepath.Join(tool.VaultPath, "New.md") {
+		t.Errorf("unexpected index call: root=%s path=%s", indexer.root, indexer.path)
+	}
+}
+
+func TestFormatMoment(t *testing.T) {
+	day := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	cases := map[string]string{
+		"YYYY-MM-DD":           "2026-01-02",
+		"ddd, MMM Do YY":       "Fri, Jan 2nd 26",
+		"[Week] WW, h:mm A":    "Week 01, 3:04 PM",
+		"YYYY/MM/[notes]-D.M.": "2026/01/notes-2.1.",
+	}
+	for layout, want := range cases {
+		if got := formatMoment(day, layout); got != want {
+			t.Errorf("formatMoment(%q) = %q, want %q", layout, got, want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"ok-gobot/internal/runtime"
+	"ok-gobot/internal/storage"
+)
+
+const (
+	// DefaultSpoolThreshold is the tool result size (in characters) above
+	// which output is stored as an artifact instead of entering the context.
+	DefaultSpoolThreshold = 16000
+
+	// SpoolArtifactType marks job_artifacts rows holding spooled tool output.
+	SpoolArtifactType = "tool_output"
+
+	spoolHeadChars   = 3000
+	spoolTailChars   = 1500
+	maxSpoolBytes    = 8 * 1024 * 1024 // larger outputs are cut before storing
+	defaultReadLines = 200
+	maxReadLines     = 1000
+	maxReadChars     = 12000
+	maxGrepMatches   = 200
+)
+
+// ArtifactStore persists spooled tool output as job artifacts.
+type ArtifactStore interface {
+	InsertJobArtifact(artifact storage.JobArtifact) (int64, error)
+	GetJobArtifact(id int64) (*storage.JobArtifact, error)
+}
+
+// OutputSpool moves oversized tool results out of the model context: the
+// full text is stored as an artifact and the model gets a head/tail preview
+// plus an id it can page through with read_artifact.
+type OutputSpool struct {
+	store     ArtifactStore
+	threshold int
+}
+
+// NewOutputSpool creates a spool. threshold is in characters (0 = default).
+func NewOutputSpool(store ArtifactStore, threshold int) *OutputSpool {
+	if threshold <= 0 {
+		threshold = DefaultSpoolThreshold
+	}
+	return &OutputSpool{store: store, threshold: threshold}
+}
+
+// spoolOwner is the artifact owner key for ctx: the durable job running the
+// tool, or the chat session. read_artifact only serves the same owner.
+func spoolOwner(ctx context.Context) string {
+	if jobID, ok := runtime.JobIDFromContext(ctx); ok && jobID != "" {
+		return jobID
+	}
+	return "session:" + SessionKeyFromContext(ctx)
+}
+
+// Spool returns result unchanged when it is small, and otherwise stores it
+// and returns a preview that tells the model how to read the rest.
+func (s *OutputSpool) Spool(ctx context.Context, toolName, args, result string) string {
+	if s == nil || s.store == nil || toolName == "read_artifact" {
+		return result
+	}
+	chars := utf8.RuneCountInString(result)
+	if chars <= s.threshold {
+		return result
+	}
+
+	content := result
+	cut := false
+	if len(content) > maxSpoolBytes {
+		content = strings.ToValidUTF8(content[:maxSpoolBytes], "")
+		cut = true
+	}
+	lines := strings.Count(content, "\n") + 1
+	meta, _ := json.Marshal(map[string]any{
+		"tool":  toolName,
+		"args":  truncateRunes(args, 500),
+		"chars": chars,
+		"lines": lines,
+		"cut":   cut,
+	})
+	id, err := s.store.InsertJobArtifact(storage.JobArtifact{
+		JobID:        spoolOwner(ctx),
+		Name:         "tool_output/" + toolName,
+		ArtifactType: SpoolArtifactType,
+		MimeType:     "text/plain",
+		Content:      content,
+		Metadata:     string(meta),
+	})
+	if err != nil {
+		// Better a truncated result than one that overflows the context.
+		return headTail(result) + fmt.Sprintf("\n\n[Output truncated from %d chars; storing the full output failed: %v]", chars, err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[Output of %s was too large for the context (%d chars, %d lines) and was stored as artifact %d.\n", toolName, chars, lines, id)
+	fmt.Fprintf(&sb, "Use read_artifact with id=%d and offset/limit (lines) to page through it, or pattern to grep it.", id)
+	if cut {
+		fmt.Fprintf(&sb, " Only the first %d MB were stored.", maxSpoolBytes/(1024*1024))
+	}
+	sb.WriteString("]\n\n")
+	sb.WriteString(headTail(result))
+	return sb.String()
+}
+
+// headTail keeps the beginning and end of s, cut at line boundaries.
+func headTail(s string) string {
+	r := []rune(s)
+	if len(r) <= spoolHeadChars+spoolTailChars {
+		return s
+	}
+	head := string(r[:spoolHeadChars])
+	if i := strings.LastIndex(head, "\n"); i > spoolHeadChars/2 {
+		head = head[:i]
+	}
+	tail := string(r[len(r)-spoolTailChars:])
+	if i := strings.Index(tail, "\n"); i >= 0 && i < spoolTailChars/2 {
+		tail = tail[i+1:]
+	}
+	omitted := len(r) - utf8.RuneCountInString(head) - utf8.RuneCountInString(tail)
+	return fmt.Sprintf("%s\n\n… [%d chars omitted] …\n\n%s", head, omitted, tail)
+}
+
+// ReadArtifactTool pages and greps through spooled tool output.
+type ReadArtifactTool struct {
+	store ArtifactStore
+}
+
+// NewReadArtifactTool creates the read_artifact tool.
+func NewReadArtifactTool(store ArtifactStore) *ReadArtifactTool {
+	return &ReadArtifactTool{store: store}
+}
+
+func (t *ReadArtifactTool) Name() string {
+	return "read_artifact"
+}
+
+func (t *ReadArtifactTool) Description() string {
+	return "Read a large tool output that was stored as an artifact: page by line (offset/limit) or grep with a regular expression"
+}
+
+func (t *ReadArtifactTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{
+				"type":        "integer",
+				"description": "Artifact id from the truncated tool output",
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "First line to return, 0-based (default 0)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Lines to return (default %d, max %d)", defaultReadLines, maxReadLines),
+			},
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Regular expression; returns matching lines with line numbers instead of a page",
+			},
+			"context": map[string]interface{}{
+				"type":        "integer",
+				"description": "Lines of context around each grep match (default 0, max 10)",
+			},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *ReadArtifactTool) Execute(ctx context.Context, args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: read_artifact <id> [offset] [limit]")
+	}
+	params := map[string]string{"id": args[0]}
+	if len(args) > 1 {
+		params["offset"] = args[1]
+	}
+	if len(args) > 2 {
+		params["limit"] = args[2]
+	}
+	return t.ExecuteJSON(ctx, params)
+}
+
+// ExecuteJSON returns one page of an artifact, or the lines matching pattern.
+func (t *ReadArtifactTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(params["id"]), "#"), 10, 64)
+	if err != nil || id <= 0 {
+		return "", fmt.Errorf("invalid artifact id %q", params["id"])
+	}
+	offset, err := parseNonNegativeInt(params["offset"], 0)
+	if err != nil {
+		return "", fmt.Errorf("invalid offset: %w", err)
+	}
+	limit, err := parseNonNegativeInt(params["limit"], defaultReadLines)
+	if err != nil {
+		return "", fmt.Errorf("invalid limit: %w", err)
+	}
+	if limit == 0 {
+		limit = defaultReadLines
+	}
+	limit = min(limit, maxReadLines)
+	contextLines, err := parseNonNegativeInt(params["context"], 0)
+	if err != nil {
+		return "", fmt.Errorf("invalid context: %w", err)
+	}
+	contextLines = min(contextLines, 10)
+
+	artifact, err := t.store.GetJobArtifact(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to load artifact %d: %w", id, err)
+	}
+	if artifact == nil || artifact.JobID != spoolOwner(ctx) {
+		return "", fmt.Errorf("artifact %d not found", id)
+	}
+
+	lines := strings.Split(artifact.Content, "\n")
+	if pattern := params["pattern"]; pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern: %w", err)
+		}
+		return grepLines(id, lines, re, offset, contextLines), nil
+	}
+
+	if offset >= len(lines) {
+		return fmt.Sprintf("Artifact %d has %d lines; offset %d is past the end.", id, len(lines), offset), nil
+	}
+	end := min(offset+limit, len(lines))
+	var sb strings.Builder
+	chars := 0
+	for i := offset; i < end; i++ {
+		if chars+len(lines[i]) > maxReadChars && i > offset {
+			end = i
+			break
+		}
+		sb.WriteString(lines[i])
+		sb.WriteByte('\n')
+		chars += len(lines[i]) + 1
+	}
+	header := fmt.Sprintf("[Artifact %d (%s): lines %d-%d of %d", id, artifact.Name, offset, end-1, len(lines))
+	if end < len(lines) {
+		header += fmt.Sprintf("; next offset %d", end)
+	}
+	return header + "]\n" + sb.String(), nil
+}
+
+// grepLines returns lines matching re (from line offset on) with their line
+// numbers and optional context.
+func grepLines(id int64, lines []string, re *regexp.Regexp, offset, contextLines int) string {
+	var sb strings.Builder
+	matches := 0
+	lastPrinted := -1
+	for i := offset; i < len(lines); i++ {
+		if !re.MatchString(lines[i]) {
+			continue
+		}
+		matches++
+		if matches > maxGrepMatches || sb.Len() > maxReadChars {
+			fmt.Fprintf(&sb, "… more matches; continue with offset %d\n", i)
+			break
+		}
+		from := max(i-contextLines, lastPrinted+1, offset)
+		if lastPrinted >= 0 && from > lastPrinted+1 && contextLines > 0 {
+			sb.WriteString("--\n")
+		}
+		for j := from; j <= min(i+contextLines, len(lines)-1); j++ {
+			sep := "-"
+			if j == i {
+				sep = ":"
+			}
+			fmt.Fprintf(&sb, "%d%s%s\n", j, sep, lines[j])
+			lastPrinted = j
+		}
+	}
+	if matches == 0 {
+		return fmt.Sprintf("No lines in artifact %d match %q.", id, re.String())
+	}
+	return fmt.Sprintf("[Artifact %d: lines matching %q]\n%s", id, re.String(), sb.String())
+}
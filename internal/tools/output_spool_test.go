@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ok-gobot/internal/storage"
+)
+
+func newSpoolStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func spooledArtifactID(t *testing.T, preview string) string {
+	t.Helper()
+	m := regexp.MustCompile(`stored as artifact (\d+)`).FindStringSubmatch(preview)
+	if m == nil {
+		t.Fatalf("preview does not reference an artifact: %.200s", preview)
+	}
+	return m[1]
+}
+
+func TestOutputSpoolKeepsSmallResults(t *testing.T) {
+	spool := NewOutputSpool(newSpoolStore(t), 100)
+	if got := spool.Spool(context.Background(), "search", "", "short"); got != "short" {
+		t.Errorf("small result changed: %q", got)
+	}
+
+	var nilSpool *OutputSpool
+	big := strings.Repeat("x", 200)
+	if got := nilSpool.Spool(context.Background(), "search", "", big); got != big {
+		t.Error("nil spool should pass results through")
+	}
+}
+
+func TestOutputSpoolStoresLargeResultsAndPages(t *testing.T) {
+	store := newSpoolStore(t)
+	spool := NewOutputSpool(store, 1000)
+	ctx := WithSessionKey(context.Background(), "telegram:1")
+
+	var lines []string
+	for i := 0; i < 2000; i++ {
+		lines = append(lines, fmt.Sprintf("line %04d", i))
+	}
+	lines[1500] = "ERROR: disk full"
+	result := strings.Join(lines, "\n")
+
+	preview := spool.Spool(ctx, "local", `{"command":"build"}`, result)
+	if len(preview) >= len(result) {
+		t.Fatalf("preview is not shorter than the result (%d >= %d)", len(preview), len(result))
+	}
+	if !strings.Contains(preview, "line 0000") || !strings.Contains(preview, "line 1999") {
+		t.Error("preview should keep head and tail")
+	}
+	id := spooledArtifactID(t, preview)
+
+	reader := NewReadArtifactTool(store)
+	page, err := reader.ExecuteJSON(ctx, map[string]string{"id": id, "offset": "10", "limit": "5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(page, "line 0010\n") || !strings.Contains(page, "line 0014\n") || strings.Contains(page, "line 0015") {
+		t.Errorf("unexpected page: %s", page)
+	}
+	if !strings.Contains(page, "next offset 15") {
+		t.Errorf("page header should give the next offset: %s", page)
+	}
+
+	grep, err := reader.ExecuteJSON(ctx, map[string]string{"id": id, "pattern": "^ERROR", "context": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(grep, "1500:ERROR: disk full") || !strings.Contains(grep, "1499-line 1499") {
+		t.Errorf("unexpected grep output: %s", grep)
+	}
+
+	other := WithSessionKey(context.Background(), "telegram:2")
+	if _, err := reader.ExecuteJSON(other, map[string]string{"id": id}); err == nil {
+		t.Error("artifacts of another session must not be readable")
+	}
+
+	if got := spool.Spool(ctx, "read_artifact", "", result); got != result {
+		t.Error("read_artifact output must not be spooled again")
+	}
+}
+
+func TestReadArtifactToolValidatesID(t *testing.T) {
+	reader := NewReadArtifactTool(newSpoolStore(t))
+	if _, err := reader.ExecuteJSON(context.Background(), map[string]string{"id": "abc"}); err == nil {
+		t.Error("expected invalid id error")
+	}
+	if _, err := reader.ExecuteJSON(context.Background(), map[string]string{"id": strconv.Itoa(42)}); err == nil {
+		t.Error("expected missing artifact error")
+	}
+}
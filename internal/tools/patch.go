@@ -5,10 +5,15 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
+// defaultPatchFuzz is how many leading/trailing context lines a hunk may
+// drop when its full context no longer matches (GNU patch default).
+const defaultPatchFuzz = 2
+
 // PatchTool applies unified diff patches to files
 type PatchTool struct {
 	BasePath string
@@ -24,125 +29,602 @@ func (p *PatchTool) Name() string {
 }
 
 func (p *PatchTool) Description() string {
-	return "Apply a unified diff patch to a file. Args: filepath followed by the patch content in unified diff format."
+	return "Apply a unified diff. Accepts single-file diffs with an explicit path or multi-file git-style diffs " +
+		"(new, deleted, and renamed files). Hunks tolerate line offsets and fuzz; use dry_run to see which hunks would fail. " +
+		"Nothing is written unless every hunk applies."
+}
+
+// GetSchema returns the JSON Schema for patch tool parameters.
+func (p *PatchTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"patch": map[string]interface{}{
+				"type":        "string",
+				"description": "Unified diff content; may contain several files (diff --git / --- / +++ headers)",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Target file for a single-file diff without usable headers (relative to base directory)",
+			},
+			"dry_run": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Report which hunks apply or fail without writing anything",
+			},
+			"fuzz": map[string]interface{}{
+				"type":        "integer",
+				"description": "Context lines a hunk may ignore when matching (default 2, 0 = strict)",
+			},
+		},
+		"required": []string{"patch"},
+	}
 }
 
+// Execute applies a patch with positional args. The legacy form is
+// <filepath> <patch-content>; a single argument is treated as a multi-file diff.
 func (p *PatchTool) Execute(ctx context.Context, args ...string) (string, error) {
-	if len(args) < 2 {
-		return "", fmt.Errorf("usage: patch <filepath> <patch-content>")
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: patch <filepath> <patch-content> | patch <multi-file-diff>")
+	}
+	if len(args) == 1 || looksLikeDiff(args[0]) {
+		return p.apply(ctx, strings.Join(args, " "), "", false, defaultPatchFuzz)
+	}
+	return p.apply(ctx, strings.Join(args[1:], " "), args[0], false, defaultPatchFuzz)
+}
+
+// ExecuteJSON applies a patch with structured JSON parameters.
+func (p *PatchTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	fuzz := defaultPatchFuzz
+	if raw := strings.TrimSpace(params["fuzz"]); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid fuzz: %q", raw)
+		}
+		fuzz = n
 	}
+	return p.apply(ctx, params["patch"], params["path"], params["dry_run"] == "true", fuzz)
+}
+
+func looksLikeDiff(s string) bool {
+	s = strings.TrimSpace(s)
+	return strings.HasPrefix(s, "diff --git ") || strings.HasPrefix(s, "--- ") || strings.HasPrefix(s, "@@")
+}
 
-	filepath := args[0]
-	patchContent := strings.Join(args[1:], " ")
+// filePatch is the set of hunks for one file in a (possibly multi-file) diff.
+type filePatch struct {
+	OldPath string // empty for new files
+	NewPath string // empty for deleted files
+	IsNew   bool
+	Deleted bool
+	Hunks   []Hunk
+}
 
-	// Parse and apply the patch
-	if err := p.applyPatch(filepath, patchContent); err != nil {
-		return "", fmt.Errorf("failed to apply patch: %w", err)
+func (fp filePatch) displayPath() string {
+	if fp.NewPath != "" {
+		return fp.NewPath
 	}
+	return fp.OldPath
+}
 
-	return fmt.Sprintf("Successfully applied patch to %s", filepath), nil
+// patchChange is the planned outcome for one file.
+type patchChange struct {
+	patch   filePatch
+	source  string // resolved path read from ("" for new files)
+	target  string // resolved path written to ("" for deletions)
+	content string
+	report  []string
+	failed  bool
 }
 
-// applyPatch parses a unified diff and applies it to the target file
-func (p *PatchTool) applyPatch(targetPath, patchContent string) error {
-	fullPath, err := resolvePath(p.BasePath, targetPath)
+func (p *PatchTool) apply(ctx context.Context, patchContent, targetPath string, dryRun bool, fuzz int) (string, error) {
+	if strings.TrimSpace(patchContent) == "" {
+		return "", fmt.Errorf("patch content is required")
+	}
+	patches, err := parseFilePatches(patchContent)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("failed to parse patch: %w", err)
+	}
+	if len(patches) == 0 {
+		return "", fmt.Errorf("failed to parse patch: no hunks found")
+	}
+	if targetPath != "" {
+		if len(patches) != 1 {
+			return "", fmt.Errorf("path can only be used with a single-file patch (got %d files)", len(patches))
+		}
+		if !patches[0].IsNew {
+			patches[0].OldPath = targetPath
+		}
+		if !patches[0].Deleted {
+			patches[0].NewPath = targetPath
+		}
 	}
 
-	// Read current file content
-	content, err := os.ReadFile(fullPath)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+	changes := make([]*patchChange, 0, len(patches))
+	failed := false
+	for _, fp := range patches {
+		change, err := p.plan(fp, fuzz)
+		if err != nil {
+			return "", err
+		}
+		failed = failed || change.failed
+		changes = append(changes, change)
 	}
 
-	lines := strings.Split(string(content), "\n")
+	report := formatPatchReport(changes)
+	switch {
+	case dryRun && failed:
+		return "Dry run: some hunks would FAIL\n" + report, nil
+	case dryRun:
+		return "Dry run: all hunks apply cleanly\n" + report, nil
+	case failed:
+		return "", fmt.Errorf("failed to apply patch; no files were changed\n%s", report)
+	}
 
-	// Parse the patch
-	hunks, err := parsePatch(patchContent)
-	if err != nil {
-		return fmt.Errorf("failed to parse patch: %w", err)
+	for _, change := range changes {
+		if err := snapshotFiles(ctx, "patch", change.source, change.target); err != nil {
+			return "", err
+		}
 	}
+	for _, change := range changes {
+		if err := change.write(); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", change.patch.displayPath(), err)
+		}
+	}
+	return "Applied patch\n" + report, nil
+}
 
-	// Apply hunks in reverse order to maintain line numbers
-	for i := len(hunks) - 1; i >= 0; i-- {
-		hunk := hunks[i]
-		if err := applyHunk(&lines, hunk); err != nil {
-			return fmt.Errorf("failed to apply hunk: %w", err)
+// plan resolves paths and applies hunks in memory.
+func (p *PatchTool) plan(fp filePatch, fuzz int) (*patchChange, error) {
+	change := &patchChange{patch: fp}
+	var err error
+	if fp.OldPath != "" && !fp.IsNew {
+		if change.source, err = resolvePath(p.BasePath, fp.OldPath); err != nil {
+			return nil, err
+		}
+	}
+	if fp.NewPath != "" && !fp.Deleted {
+		if change.target, err = resolvePath(p.BasePath, fp.NewPath); err != nil {
+			return nil, err
+		}
+	}
+	if change.source == "" && change.target == "" {
+		return nil, fmt.Errorf("patch section has no file path; pass path for single-file diffs")
+	}
+	for _, path := range []string{change.source, change.target} {
+		if err := checkCustomToolsWrite(p.Name(), p.BasePath, path); err != nil {
+			return nil, err
 		}
 	}
 
-	// Write the modified content back
-	newContent := strings.Join(lines, "\n")
-	if err := os.WriteFile(fullPath, []byte(newContent), 0644); err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+	var lines []string
+	if fp.IsNew {
+		if _, err := os.Lstat(change.target); err == nil {
+			change.failed = true
+			change.report = append(change.report, "FAILED: file already exists")
+			return change, nil
+		}
+	} else {
+		data, err := os.ReadFile(change.source)
+		if err != nil {
+			change.failed = true
+			change.report = append(change.report, fmt.Sprintf("FAILED: %v", err))
+			return change, nil
+		}
+		lines = strings.Split(string(data), "\n")
+	}
+
+	if fp.Deleted {
+		return change, nil
+	}
+	if change.source != "" && change.target != change.source {
+		if _, err := os.Lstat(change.target); err == nil {
+			change.failed = true
+			change.report = append(change.report, "FAILED: rename target already exists")
+			return change, nil
+		}
 	}
 
+	if fp.IsNew {
+		lines = []string{""}
+	}
+	result, hunkReports, ok := applyHunks(lines, fp.Hunks, fuzz)
+	change.report = hunkReports
+	change.failed = !ok
+	change.content = strings.Join(result, "\n")
+	if fp.IsNew && !strings.HasSuffix(change.content, "\n") {
+		change.content += "\n"
+	}
+	return change, nil
+}
+
+func (c *patchChange) write() error {
+	if c.patch.Deleted {
+		return os.Remove(c.source)
+	}
+	mode := os.FileMode(0644)
+	if c.source != "" {
+		mode = fileMode(c.source)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.target), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.target, []byte(c.content), mode); err != nil {
+		return err
+	}
+	if c.source != "" && c.source != c.target {
+		return os.Remove(c.source)
+	}
 	return nil
 }
 
-// Hunk represents a single patch hunk
-type Hunk struct {
-	OldStart int
-	OldCount int
-	NewStart int
-	NewCount int
-	Lines    []string // Lines with +/- prefix
+func formatPatchReport(changes []*patchChange) string {
+	var sb strings.Builder
+	for _, c := range changes {
+		kind := "M"
+		switch {
+		case c.patch.IsNew:
+			kind = "A"
+		case c.patch.Deleted:
+			kind = "D"
+		case c.patch.OldPath != c.patch.NewPath:
+			kind = "R"
+		}
+		name := c.patch.displayPath()
+		if kind == "R" {
+			name = c.patch.OldPath + " -> " + c.patch.NewPath
+		}
+		fmt.Fprintf(&sb, "  %s %s", kind, name)
+		if len(c.report) > 0 {
+			sb.WriteString(": " + strings.Join(c.report, "; "))
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
 }
 
-// parsePatch parses unified diff format
-func parsePatch(patchContent string) ([]Hunk, error) {
-	var hunks []Hunk
-	scanner := bufio.NewScanner(strings.NewReader(patchContent))
+// applyHunks applies hunks in order, tolerating line offsets and up to fuzz
+// ignored context lines per side. It returns the new lines, a per-hunk
+// report, and whether every hunk applied.
+func applyHunks(lines []string, hunks []Hunk, fuzz int) ([]string, []string, bool) {
+	var reports []string
+	ok := true
+	delta := 0    // net lines added by earlier hunks
+	minStart := 0 // hunks may not overlap earlier ones
+
+	for i, hunk := range hunks {
+		expected := hunk.OldStart - 1 + delta
+		if hunk.OldCount == 0 {
+			// Pure insertions are anchored after OldStart.
+			expected = hunk.OldStart + delta
+		}
+		pos, offset, used, oldLen, newLines, found := locateHunk(lines, hunk, expected, minStart, fuzz)
+		if !found {
+			ok = false
+			reports = append(reports, fmt.Sprintf("hunk %d FAILED at line %d (context not found)", i+1, hunk.OldStart))
+			continue
+		}
 
-	var currentHunk *Hunk
+		msg := fmt.Sprintf("hunk %d at line %d", i+1, pos+1)
+		if offset != 0 {
+			msg += fmt.Sprintf(" (offset %+d)", offset)
+		}
+		if used > 0 {
+			msg += fmt.Sprintf(" (fuzz %d)", used)
+		}
+		reports = append(reports, msg)
 
-	for scanner.Scan() {
-		line := scanner.Text()
+		result := make([]string, 0, len(lines)-oldLen+len(newLines))
+		result = append(result, lines[:pos]...)
+		result = append(result, newLines...)
+		result = append(result, lines[pos+oldLen:]...)
+		lines = result
 
-		// Skip file headers (--- and +++)
-		if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") {
-			continue
-		}
+		delta += len(newLines) - oldLen
+		minStart = pos + len(newLines)
+	}
+	return lines, reports, ok
+}
 
-		// Parse hunk header: @@ -start,count +start,count @@
-		if strings.HasPrefix(line, "@@") {
-			// Save previous hunk if exists
-			if currentHunk != nil {
-				hunks = append(hunks, *currentHunk)
+// locateHunk finds where a hunk applies, searching outward from expected.
+// It first tries the full context, then drops up to fuzz context lines from
+// each end, and at each level also retries ignoring trailing whitespace.
+func locateHunk(lines []string, hunk Hunk, expected, minStart, fuzz int) (pos, offset, used, oldLen int, newLines []string, found bool) {
+	body := hunkBody(hunk)
+	for f := 0; f <= fuzz; f++ {
+		trimmed, ok := trimHunkContext(body, f)
+		if !ok {
+			break
+		}
+		var oldLines []string
+		newLines = newLines[:0]
+		for _, l := range trimmed {
+			if l[0] != '+' {
+				oldLines = append(oldLines, l[1:])
 			}
+			if l[0] != '-' {
+				newLines = append(newLines, l[1:])
+			}
+		}
+		lead := 0
+		for lead < f && lead < len(body) && body[lead][0] == ' ' {
+			lead++
+		}
+		for _, loose := range []bool{false, true} {
+			if p, ok := findLines(lines, oldLines, expected+lead, minStart, loose); ok {
+				return p, p - (expected + lead), f, len(oldLines), append([]string(nil), newLines...), true
+			}
+		}
+	}
+	return 0, 0, 0, 0, nil, false
+}
 
-			hunk, err := parseHunkHeader(line)
-			if err != nil {
-				return nil, err
+// hunkBody returns the hunk's change lines, dropping "\ No newline" markers
+// and stray blank lines past the declared hunk length. Blank lines inside the
+// hunk are treated as empty context lines.
+func hunkBody(hunk Hunk) []string {
+	var body []string
+	oldSeen, newSeen := 0, 0
+	for _, line := range hunk.Lines {
+		if strings.HasPrefix(line, "\\") {
+			continue
+		}
+		if line == "" {
+			if oldSeen >= hunk.OldCount && newSeen >= hunk.NewCount {
+				continue
 			}
-			currentHunk = &hunk
+			line = " "
+		}
+		switch line[0] {
+		case ' ':
+			oldSeen++
+			newSeen++
+		case '-':
+			oldSeen++
+		case '+':
+			newSeen++
+		default:
 			continue
 		}
+		body = append(body, line)
+	}
+	return body
+}
+
+// trimHunkContext removes up to f leading and trailing context lines.
+// ok is false when no further context can be removed at this level.
+func trimHunkContext(body []string, f int) ([]string, bool) {
+	if f == 0 {
+		return body, true
+	}
+	start, end := 0, len(body)
+	for start < f && start < end && body[start][0] == ' ' {
+		start++
+	}
+	for end > start && len(body)-end < f && body[end-1][0] == ' ' {
+		end--
+	}
+	if start == 0 && end == len(body) {
+		return nil, false
+	}
+	return body[start:end], true
+}
+
+// findLines searches for needle in lines closest to expected, never before minStart.
+func findLines(lines, needle []string, expected, minStart int, loose bool) (int, bool) {
+	maxStart := len(lines) - len(needle)
+	if maxStart < minStart {
+		return 0, false
+	}
+	if expected < minStart {
+		expected = minStart
+	}
+	if expected > maxStart {
+		expected = maxStart
+	}
+	for dist := 0; ; dist++ {
+		before, after := expected-dist, expected+dist
+		if before < minStart && after > maxStart {
+			return 0, false
+		}
+		if before >= minStart && linesMatchAt(lines, needle, before, loose) {
+			return before, true
+		}
+		if dist > 0 && after <= maxStart && linesMatchAt(lines, needle, after, loose) {
+			return after, true
+		}
+	}
+}
 
-		// Add line to current hunk
-		if currentHunk != nil {
-			currentHunk.Lines = append(currentHunk.Lines, line)
+func linesMatchAt(lines, needle []string, pos int, loose bool) bool {
+	for i, want := range needle {
+		got := lines[pos+i]
+		if loose {
+			got, want = strings.TrimRight(got, " \t\r"), strings.TrimRight(want, " \t\r")
+		}
+		if got != want {
+			return false
 		}
 	}
+	return true
+}
 
-	// Add last hunk
-	if currentHunk != nil {
-		hunks = append(hunks, *currentHunk)
+// parseFilePatches splits a unified diff into per-file patches. Hunks that
+// appear before any file header form a single patch without paths.
+func parseFilePatches(patchContent string) ([]filePatch, error) {
+	var patches []filePatch
+	var current *filePatch
+	var hunk *Hunk
+	oldLeft, newLeft := 0, 0
+
+	flushHunk := func() {
+		if hunk != nil && current != nil {
+			current.Hunks = append(current.Hunks, *hunk)
+		}
+		hunk = nil
+	}
+	flushFile := func() {
+		flushHunk()
+		if current != nil && (len(current.Hunks) > 0 || current.Deleted || current.OldPath != current.NewPath) {
+			patches = append(patches, *current)
+		}
+		current = nil
 	}
 
+	scanner := bufio.NewScanner(strings.NewReader(patchContent))
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Inside a hunk, lines are consumed until the declared counts are met,
+		// so removed lines such as "-- comment" are not mistaken for headers.
+		if hunk != nil && (oldLeft > 0 || newLeft > 0) {
+			if line == "" || strings.HasPrefix(line, " ") {
+				oldLeft--
+				newLeft--
+				hunk.Lines = append(hunk.Lines, line)
+				continue
+			}
+			if strings.HasPrefix(line, "-") {
+				oldLeft--
+				hunk.Lines = append(hunk.Lines, line)
+				continue
+			}
+			if strings.HasPrefix(line, "+") {
+				newLeft--
+				hunk.Lines = append(hunk.Lines, line)
+				continue
+			}
+			if strings.HasPrefix(line, "\\") {
+				hunk.Lines = append(hunk.Lines, line)
+				continue
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flushFile()
+			oldPath, newPath := parseGitDiffHeader(line)
+			current = &filePatch{OldPath: oldPath, NewPath: newPath}
+		case strings.HasPrefix(line, "new file mode"):
+			if current != nil {
+				current.IsNew = true
+				current.OldPath = ""
+			}
+		case strings.HasPrefix(line, "deleted file mode"):
+			if current != nil {
+				current.Deleted = true
+				current.NewPath = ""
+			}
+		case strings.HasPrefix(line, "rename from "):
+			if current != nil {
+				current.OldPath = strings.TrimPrefix(line, "rename from ")
+			}
+		case strings.HasPrefix(line, "rename to "):
+			if current != nil {
+				current.NewPath = strings.TrimPrefix(line, "rename to ")
+			}
+		case strings.HasPrefix(line, "--- "):
+			if current == nil || len(current.Hunks) > 0 || hunk != nil {
+				flushFile()
+				current = &filePatch{}
+			}
+			path := parseDiffFileHeader(line[4:])
+			if path == "" {
+				current.IsNew = true
+				current.OldPath = ""
+			} else {
+				current.OldPath = path
+				if current.NewPath == "" && !current.Deleted {
+					current.NewPath = path
+				}
+			}
+		case strings.HasPrefix(line, "+++ "):
+			if current == nil {
+				current = &filePatch{}
+			}
+			path := parseDiffFileHeader(line[4:])
+			if path == "" {
+				current.Deleted = true
+				current.NewPath = ""
+			} else {
+				current.NewPath = path
+				if current.OldPath == "" && !current.IsNew {
+					current.OldPath = path
+				}
+			}
+		case strings.HasPrefix(line, "@@"):
+			flushHunk()
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			if current == nil {
+				current = &filePatch{}
+			}
+			hunk = &h
+			oldLeft, newLeft = h.OldCount, h.NewCount
+		case hunk != nil && (line == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") || strings.HasPrefix(line, "\\")):
+			// Tolerate hunks whose header under-counts their lines.
+			hunk.Lines = append(hunk.Lines, line)
+		}
+	}
+	flushFile()
+
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	return patches, nil
+}
+
+// parseGitDiffHeader extracts paths from "diff --git a/x b/y".
+func parseGitDiffHeader(line string) (string, string) {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if i := strings.Index(rest, " b/"); i >= 0 && strings.HasPrefix(rest, "a/") {
+		return rest[2:i], rest[i+3:]
+	}
+	parts := strings.Fields(rest)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return stripDiffPrefix(parts[0]), stripDiffPrefix(parts[1])
+}
+
+// parseDiffFileHeader extracts the path from a ---/+++ header, dropping
+// timestamps and a/ b/ prefixes. Returns "" for /dev/null.
+func parseDiffFileHeader(header string) string {
+	if i := strings.Index(header, "\t"); i >= 0 {
+		header = header[:i]
+	}
+	header = strings.TrimSpace(header)
+	if header == "/dev/null" {
+		return ""
+	}
+	return stripDiffPrefix(header)
+}
+
+func stripDiffPrefix(path string) string {
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		return path[2:]
+	}
+	return path
+}
 
-	return hunks, nil
+// Hunk represents a single patch hunk
+type Hunk struct {
+	OldStart int
+	OldCount int
+	NewStart int
+	NewCount int
+	Lines    []string // Lines with +/- prefix
 }
 
 // parseHunkHeader parses @@ -start,count +start,count @@
 func parseHunkHeader(header string) (Hunk, error) {
-	// Remove @@ prefix and suffix
+	// Remove @@ prefix and any trailing section heading after the closing @@
 	header = strings.TrimPrefix(header, "@@")
-	header = strings.TrimSuffix(header, "@@")
+	if i := strings.Index(header, "@@"); i >= 0 {
+		header = header[:i]
+	}
 	header = strings.TrimSpace(header)
 
 	parts := strings.Fields(header)
@@ -187,70 +669,3 @@ func parseHunkHeader(header string) (Hunk, error) {
 		NewCount: newCount,
 	}, nil
 }
-
-// applyHunk applies a single hunk to the lines
-func applyHunk(lines *[]string, hunk Hunk) error {
-	// Convert to 0-based index
-	startIdx := hunk.OldStart - 1
-
-	if startIdx < 0 || startIdx > len(*lines) {
-		return fmt.Errorf("hunk start index out of range: %d", startIdx)
-	}
-
-	// Collect new lines and track position
-	var newLines []string
-	oldIdx := startIdx
-
-	for _, line := range hunk.Lines {
-		if len(line) == 0 {
-			continue
-		}
-
-		prefix := line[0]
-		content := ""
-		if len(line) > 1 {
-			content = line[1:]
-		}
-
-		switch prefix {
-		case ' ':
-			// Context line - verify and keep
-			if oldIdx >= len(*lines) {
-				return fmt.Errorf("context line beyond file end")
-			}
-			if (*lines)[oldIdx] != content {
-				return fmt.Errorf("context mismatch at line %d: expected %q, got %q",
-					oldIdx+1, content, (*lines)[oldIdx])
-			}
-			newLines = append(newLines, content)
-			oldIdx++
-
-		case '-':
-			// Remove line - verify and skip
-			if oldIdx >= len(*lines) {
-				return fmt.Errorf("remove line beyond file end")
-			}
-			if (*lines)[oldIdx] != content {
-				return fmt.Errorf("remove line mismatch at line %d: expected %q, got %q",
-					oldIdx+1, content, (*lines)[oldIdx])
-			}
-			oldIdx++
-
-		case '+':
-			// Add line
-			newLines = append(newLines, content)
-		}
-	}
-
-	// Replace the old range with new lines
-	endIdx := oldIdx
-
-	// Build new file content
-	result := make([]string, 0, len(*lines)-hunk.OldCount+hunk.NewCount)
-	result = append(result, (*lines)[:startIdx]...)
-	result = append(result, newLines...)
-	result = append(result, (*lines)[endIdx:]...)
-
-	*lines = result
-	return nil
-}
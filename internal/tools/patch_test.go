@@ -211,7 +211,7 @@ func TestParseHunkHeader(t *testing.T) {
 	}
 }
 
-func TestParsePatch(t *testing.T) {
+func TestParseFilePatches(t *testing.T) {
 	patch := `--- a/test.txt
 +++ b/test.txt
 @@ -1,3 +1,4 @@
@@ -225,10 +225,14 @@ func TestParsePatch(t *testing.T) {
 +modified line 6
 `
 
-	hunks, err := parsePatch(patch)
+	patches, err := parseFilePatches(patch)
 	if err != nil {
-		t.Fatalf("parsePatch() error = %v", err)
+		t.Fatalf("parseFilePatches() error = %v", err)
 	}
+	if len(patches) != 1 || patches[0].OldPath != "test.txt" || patches[0].NewPath != "test.txt" {
+		t.Fatalf("Expected one patch for test.txt, got %+v", patches)
+	}
+	hunks := patches[0].Hunks
 
 	if len(hunks) != 2 {
 		t.Errorf("Expected 2 hunks, got %d", len(hunks))
@@ -254,3 +258,156 @@ func TestParsePatch(t *testing.T) {
 		t.Errorf("Second hunk: expected 3 lines, got %d", len(hunks[1].Lines))
 	}
 }
+
+func TestPatchTool_MultiFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("alpha\nbeta\ngamma\n"), 0644) //nolint:errcheck
+	os.WriteFile(filepath.Join(tmpDir, "old.txt"), []byte("bye\n"), 0644)              //nolint:errcheck
+
+	patch := `diff --git a/a.txt b/a.txt
+index 1111111..2222222 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1,3 +1,3 @@
+ alpha
+-beta
++BETA
+ gamma
+diff --git a/docs/new.md b/docs/new.md
+new file mode 100644
+--- /dev/null
++++ b/docs/new.md
+@@ -0,0 +1,2 @@
++# New
++hello
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`
+	tool := NewPatchTool(tmpDir)
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"patch": patch})
+	if err != nil {
+		t.Fatalf("ExecuteJSON: %v", err)
+	}
+	for _, want := range []string{"M a.txt", "A docs/new.md", "D old.txt"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q: %s", want, out)
+		}
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "a.txt")); string(data) != "alpha\nBETA\ngamma\n" {
+		t.Errorf("a.txt = %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "docs", "new.md")); string(data) != "# New\nhello\n" {
+		t.Errorf("docs/new.md = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "old.txt")); !os.IsNotExist(err) {
+		t.Errorf("old.txt should be deleted, stat err = %v", err)
+	}
+}
+
+func TestPatchTool_OffsetAndFuzz(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "f.txt")
+	// Two lines were inserted at the top since the diff was made, and the
+	// trailing context line has since changed.
+	os.WriteFile(testFile, []byte("new 1\nnew 2\nline 1\nline 2\nline 3\nCHANGED\n"), 0644) //nolint:errcheck
+
+	patch := `--- a/f.txt
++++ b/f.txt
+@@ -1,4 +1,4 @@
+ line 1
+-line 2
++line two
+ line 3
+ line 4
+`
+	tool := NewPatchTool(tmpDir)
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"patch": patch})
+	if err != nil {
+		t.Fatalf("ExecuteJSON: %v", err)
+	}
+	if !strings.Contains(out, "offset +2") || !strings.Contains(out, "fuzz 1") {
+		t.Errorf("expected offset and fuzz in report, got %s", out)
+	}
+	if data, _ := os.ReadFile(testFile); string(data) != "new 1\nnew 2\nline 1\nline two\nline 3\nCHANGED\n" {
+		t.Errorf("f.txt = %q", data)
+	}
+
+	// Strict mode refuses the same patch.
+	os.WriteFile(testFile, []byte("line 1\nline 2\nline 3\nCHANGED\n"), 0644) //nolint:errcheck
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"patch": patch, "fuzz": "0"}); err == nil {
+		t.Error("expected strict patch to fail")
+	}
+}
+
+func TestPatchTool_DryRunReportsFailuresAndWritesNothing(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("one\ntwo\n"), 0644)    //nolint:errcheck
+	os.WriteFile(filepath.Join(tmpDir, "b.txt"), []byte("three\nfour\n"), 0644) //nolint:errcheck
+
+	patch := `--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+-one
++ONE
+ two
+--- a/b.txt
++++ b/b.txt
+@@ -1,2 +1,2 @@
+-missing
++nope
+ elsewhere
+`
+	tool := NewPatchTool(tmpDir)
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"patch": patch, "dry_run": "true"})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !strings.Contains(out, "would FAIL") || !strings.Contains(out, "b.txt: hunk 1 FAILED") || !strings.Contains(out, "a.txt: hunk 1 at line 1") {
+		t.Errorf("unexpected dry-run report: %s", out)
+	}
+
+	// A real run must be all-or-nothing.
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"patch": patch}); err == nil {
+		t.Fatal("expected failure")
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "a.txt")); string(data) != "one\ntwo\n" {
+		t.Errorf("a.txt modified despite failing patch: %q", data)
+	}
+}
+
+func TestPatchTool_MultiFileSecurityCheck(t *testing.T) {
+	tmpDir := t.TempDir()
+	tool := NewPatchTool(tmpDir)
+	patch := `diff --git a/../escape.txt b/../escape.txt
+new file mode 100644
+--- /dev/null
++++ b/../escape.txt
+@@ -0,0 +1 @@
++pwned
+`
+	_, err := tool.ExecuteJSON(context.Background(), map[string]string{"patch": patch})
+	if err == nil || !strings.Contains(err.Error(), "outside allowed directory") {
+		t.Fatalf("expected path escape to be refused, got %v", err)
+	}
+}
+
+func TestParseFilePatches_RemovedLineLooksLikeHeader(t *testing.T) {
+	patch := `--- a/q.sql
++++ b/q.sql
+@@ -1,2 +1,1 @@
+--- legacy comment
+ SELECT 1;
+`
+	patches, err := parseFilePatches(patch)
+	if err != nil {
+		t.Fatalf("parseFilePatches: %v", err)
+	}
+	if len(patches) != 1 || len(patches[0].Hunks) != 1 || len(patches[0].Hunks[0].Lines) != 2 {
+		t.Fatalf("unexpected parse result: %+v", patches)
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -11,26 +12,32 @@ import (
 // CapabilityPolicy controls which capabilities an agent is allowed to exercise.
 // A nil *CapabilityPolicy is fully permissive (backward compatible with no config).
 type CapabilityPolicy struct {
-	Shell            bool     // Allow shell execution tools (local, ssh). Default: true.
-	Network          bool     // Allow network tools (web_fetch, search, browser). Default: true.
-	NetworkAllowlist []string // Allowed hostnames when Network is true. Empty = all. Future per-request enforcement.
-	Cron             bool     // Allow cron scheduling. Default: true.
-	MemoryWrite      bool     // Allow memory write tools. Default: true. Ready for future memory_capture tools.
-	Spawn            bool     // Allow sub-agent/job spawning (browser_task). Default: true.
-	FilesystemRoots  []string // Allowed absolute filesystem paths. Empty = no restriction.
-	FileReadOnly     bool     // Deny file/patch write operations.
+	Shell             bool     // Allow shell execution tools (local, ssh, run_code, git). Default: true.
+	Network           bool     // Allow network tools (web_fetch, search, browser). Default: true.
+	NetworkAllowlist  []string // Allowed hostnames when Network is true. Empty = all. Enforced per request by web_fetch and http_request.
+	Cron              bool     // Allow cron scheduling. Default: true.
+	MemoryWrite       bool     // Allow memory write tools and web_fetch save. Default: true.
+	Spawn             bool     // Allow sub-agent/job spawning (browser_task). Default: true.
+	FilesystemRoots   []string // Allowed absolute filesystem paths. Empty = no restriction.
+	FileReadOnly      bool     // Deny file/patch write operations and mutating git actions.
+	GitHistoryRewrite bool     // Allow force-push, amend, and ref-moving resets in the git tool. Default: false.
 }
 
 // capabilitiesForTool maps tool names to the capabilities that govern them.
 // A tool requires ALL listed capabilities to be allowed.
 var capabilitiesForTool = map[string][]string{
-	"local":        {"shell"},
-	"ssh":          {"shell"},
-	"web_fetch":    {"network"},
-	"search":       {"network"},
-	"browser":      {"network"},
-	"browser_task": {"network", "spawn"},
-	"cron":         {"cron"},
+	"local":          {"shell"},
+	"run_code":       {"shell"},
+	"ssh":            {"shell"},
+	"web_fetch":      {"network"},
+	"http_request":   {"network"},
+	"search":         {"network"},
+	"browser":        {"network"},
+	"browser_task":   {"network", "spawn"},
+	"browser_script": {"network"},
+	"git":            {"shell"},
+	"cron":           {"cron"},
+	"workflow":       {"spawn"},
 }
 
 // CapabilityForTool returns the capabilities governing the named tool.
@@ -66,10 +73,10 @@ func (p *CapabilityPolicy) IsAllowed(capability string) bool {
 // DeniedCapability returns the first denied capability for the named tool,
 // or "" if the tool is fully allowed by capability checks.
 func (p *CapabilityPolicy) DeniedCapability(toolName string) string {
-	caps, ok := capabilitiesForTool[toolName]
-	if !ok {
-		return ""
-	}
+	return p.firstDenied(capabilitiesForTool[toolName])
+}
+
+func (p *CapabilityPolicy) firstDenied(caps []string) string {
 	for _, cap := range caps {
 		if !p.IsAllowed(cap) {
 			return cap
@@ -78,6 +85,27 @@ func (p *CapabilityPolicy) DeniedCapability(toolName string) string {
 	return ""
 }
 
+// capabilityDeclarer is implemented by tools that declare their own
+// capabilities (workspace-defined tools, whose names are not known in advance).
+type capabilityDeclarer interface {
+	Capabilities() []string
+}
+
+// declaredCapabilities returns the capabilities a tool declares itself,
+// looking through registry decorators.
+func declaredCapabilities(tool Tool) []string {
+	for {
+		if declarer, ok := tool.(capabilityDeclarer); ok {
+			return declarer.Capabilities()
+		}
+		wrapped, ok := tool.(interface{ Unwrap() Tool })
+		if !ok {
+			return nil
+		}
+		tool = wrapped.Unwrap()
+	}
+}
+
 // ApplyPolicy returns a new registry with tools wrapped according to the
 // given capability policy. Tools denied by policy return ToolDenial on
 // execution. File tools are wrapped for filesystem/write-scope restrictions.
@@ -98,9 +126,13 @@ func wrapForPolicy(tool Tool, policy *CapabilityPolicy) Tool {
 	name := tool.Name()
 
 	// Boolean capability denial.
+	declared := declaredCapabilities(tool)
 	if denied := policy.DeniedCapability(name); denied != "" {
 		return wrapToolWithPolicyDenial(tool, denied)
 	}
+	if denied := policy.firstDenied(declared); denied != "" {
+		return wrapToolWithPolicyDenial(tool, denied)
+	}
 
 	// File-specific restrictions.
 	needsWriteGuard := (name == "file" || name == "patch") && policy.FileReadOnly
@@ -109,6 +141,52 @@ func wrapForPolicy(tool Tool, policy *CapabilityPolicy) Tool {
 		return wrapToolWithFilePolicy(tool, policy)
 	}
 
+	// Git is confined to roots and read-only mode like the file tools;
+	// history rewrites are opt-in.
+	if name == "git" {
+		return scopeGitTool(tool, policy)
+	}
+
+	// Workflows run exec and worker steps as local processes.
+	if !policy.Shell {
+		if scoped, ok := tool.(interface{ WithoutShell() Tool }); ok {
+			tool = scoped.WithoutShell()
+		}
+	}
+
+	// Saving fetched pages writes to memory.
+	if !policy.MemoryWrite {
+		if saver, ok := tool.(interface{ WithoutMemoryWrite() Tool }); ok {
+			tool = saver.WithoutMemoryWrite()
+		}
+	}
+
+	// Per-request network allowlist.
+	if (name == "web_fetch" || name == "http_request" || containsString(declared, "network")) && len(policy.NetworkAllowlist) > 0 {
+		return wrapToolWithNetworkPolicy(tool, policy.NetworkAllowlist)
+	}
+
+	return tool
+}
+
+// scopeGitTool applies filesystem roots, write scope, and history-rewrite
+// settings to the git tool.
+func scopeGitTool(tool Tool, policy *CapabilityPolicy) Tool {
+	if len(policy.FilesystemRoots) > 0 {
+		if rooted, ok := tool.(interface{ Root() string }); ok && !isPathInRoots(rooted.Root(), policy.FilesystemRoots) {
+			return wrapToolWithPolicyDenial(tool, "filesystem")
+		}
+	}
+	if policy.GitHistoryRewrite {
+		if rewritable, ok := tool.(interface{ WithHistoryRewrite() Tool }); ok {
+			tool = rewritable.WithHistoryRewrite()
+		}
+	}
+	if policy.FileReadOnly {
+		if readOnly, ok := tool.(interface{ WithReadOnly() Tool }); ok {
+			tool = readOnly.WithReadOnly()
+		}
+	}
 	return tool
 }
 
@@ -221,55 +299,88 @@ func (g *filePolicyGuard) Execute(ctx context.Context, args ...string) (string,
 }
 
 func (g *filePolicyGuard) check(args []string) *ToolDenial {
+	var op string
+	var paths []string
+	switch g.tool.Name() {
+	case "file":
+		if len(args) > 0 {
+			op = args[0]
+		}
+		if len(args) > 1 {
+			paths = append(paths, args[1])
+		}
+		if op == "move" && len(args) > 2 {
+			paths = append(paths, args[2])
+		}
+	case "patch":
+		// Mirrors PatchTool.Execute: a lone diff names its own files.
+		switch {
+		case len(args) == 1 || (len(args) > 1 && looksLikeDiff(args[0])):
+			paths = patchTargets(strings.Join(args, " "))
+		case len(args) > 1:
+			paths = append(paths, args[0])
+		}
+	case "grep":
+		if len(args) > 1 {
+			paths = append(paths, args[1])
+		}
+	}
+	return g.checkOp(op, paths)
+}
+
+// checkJSON applies the same rules to structured parameters.
+func (g *filePolicyGuard) checkJSON(params map[string]string) *ToolDenial {
+	var op string
+	paths := []string{params["path"], params["destination"]}
+	switch g.tool.Name() {
+	case "file":
+		op = params["command"]
+	case "patch":
+		// Without an explicit path, every file named in the diff is touched.
+		if strings.TrimSpace(params["path"]) == "" {
+			paths = patchTargets(params["patch"])
+		}
+	}
+	return g.checkOp(op, paths)
+}
+
+// patchTargets returns the file paths named in a diff's headers. A diff
+// that does not parse is rejected by the patch tool itself.
+func patchTargets(diff string) []string {
+	patches, err := parseFilePatches(diff)
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, fp := range patches {
+		paths = append(paths, fp.OldPath, fp.NewPath)
+	}
+	return paths
+}
+
+func (g *filePolicyGuard) checkOp(op string, paths []string) *ToolDenial {
 	name := g.tool.Name()
 
-	// Write-scope check.
-	if g.readOnly {
-		switch name {
-		case "file":
-			if len(args) > 0 && args[0] == "write" {
-				return &ToolDenial{
-					ToolName:    name,
-					Family:      "file_write",
-					Reason:      "file writes denied by agent policy (read-only mode)",
-					Remediation: "Ask the operator to set file_write_scope to \"full\".",
-				}
-			}
-		case "patch":
-			// Patch is always a write operation.
-			return &ToolDenial{
-				ToolName:    name,
-				Family:      "file_write",
-				Reason:      "file writes denied by agent policy (read-only mode)",
-				Remediation: "Ask the operator to set file_write_scope to \"full\".",
-			}
+	// Write-scope check. Patch is always a write operation.
+	if g.readOnly && (name == "patch" || (name == "file" && isFileWriteOp(op))) {
+		return &ToolDenial{
+			ToolName:    name,
+			Family:      "file_write",
+			Reason:      "file writes denied by agent policy (read-only mode)",
+			Remediation: "Ask the operator to set file_write_scope to \"full\".",
 		}
 	}
 
 	// Filesystem roots check.
 	if len(g.filesystemRoots) > 0 {
-		var path string
-		switch name {
-		case "file":
-			if len(args) > 1 {
-				path = args[1]
-			}
-		case "patch":
-			if len(args) > 0 {
-				path = args[0]
-			}
-		case "grep":
-			if len(args) > 1 {
-				path = args[1]
-			}
-		}
-
-		if path != "" && filepath.IsAbs(path) && !isPathInRoots(path, g.filesystemRoots) {
-			return &ToolDenial{
-				ToolName:    name,
-				Family:      "filesystem",
-				Reason:      fmt.Sprintf("path %q is outside allowed filesystem roots", path),
-				Remediation: "Ask the operator to update filesystem_roots in the capability policy.",
+		for _, path := range paths {
+			if path != "" && filepath.IsAbs(path) && !isPathInRoots(path, g.filesystemRoots) {
+				return &ToolDenial{
+					ToolName:    name,
+					Family:      "filesystem",
+					Reason:      fmt.Sprintf("path %q is outside allowed filesystem roots", path),
+					Remediation: "Ask the operator to update filesystem_roots in the capability policy.",
+				}
 			}
 		}
 	}
@@ -289,7 +400,7 @@ func isPathInRoots(path string, roots []string) bool {
 	return false
 }
 
-// Variants that preserve ToolSchema interface.
+// Variants that preserve ToolSchema and/or jsonExecutor interfaces.
 
 type filePolicyGuardWithSchema struct {
 	*filePolicyGuard
@@ -300,6 +411,35 @@ func (g *filePolicyGuardWithSchema) GetSchema() map[string]interface{} {
 	return g.schema.GetSchema()
 }
 
+type filePolicyGuardWithJSON struct {
+	*filePolicyGuard
+	json jsonExecutor
+}
+
+func (g *filePolicyGuardWithJSON) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	if denial := g.checkJSON(params); denial != nil {
+		return "", denial
+	}
+	return g.json.ExecuteJSON(ctx, params)
+}
+
+type filePolicyGuardWithSchemaAndJSON struct {
+	*filePolicyGuard
+	schema ToolSchema
+	json   jsonExecutor
+}
+
+func (g *filePolicyGuardWithSchemaAndJSON) GetSchema() map[string]interface{} {
+	return g.schema.GetSchema()
+}
+
+func (g *filePolicyGuardWithSchemaAndJSON) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	if denial := g.checkJSON(params); denial != nil {
+		return "", denial
+	}
+	return g.json.ExecuteJSON(ctx, params)
+}
+
 func wrapToolWithFilePolicy(tool Tool, policy *CapabilityPolicy) Tool {
 	base := &filePolicyGuard{
 		tool:            tool,
@@ -307,11 +447,159 @@ func wrapToolWithFilePolicy(tool Tool, policy *CapabilityPolicy) Tool {
 		filesystemRoots: policy.FilesystemRoots,
 	}
 
-	if schema, ok := tool.(ToolSchema); ok {
+	schema, hasSchema := tool.(ToolSchema)
+	jsonExec, hasJSON := tool.(jsonExecutor)
+
+	switch {
+	case hasSchema && hasJSON:
+		return &filePolicyGuardWithSchemaAndJSON{
+			filePolicyGuard: base,
+			schema:          schema,
+			json:            jsonExec,
+		}
+	case hasSchema:
 		return &filePolicyGuardWithSchema{
 			filePolicyGuard: base,
 			schema:          schema,
 		}
+	case hasJSON:
+		return &filePolicyGuardWithJSON{
+			filePolicyGuard: base,
+			json:            jsonExec,
+		}
+	default:
+		return base
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Network policy guard — carries the hostname allowlist into the request
+// context so network tools can enforce it on every hop, including redirects.
+// ---------------------------------------------------------------------------
+
+type networkAllowlistKey struct{}
+
+func withNetworkAllowlist(ctx context.Context, allowlist []string) context.Context {
+	return context.WithValue(ctx, networkAllowlistKey{}, allowlist)
+}
+
+func networkAllowlistFromContext(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	allowlist, _ := ctx.Value(networkAllowlistKey{}).([]string)
+	return allowlist
+}
+
+// checkNetworkAllowlist returns a ToolDenial when rawURL's host is not covered
+// by the allowlist carried in ctx. No allowlist means every host is allowed.
+func checkNetworkAllowlist(ctx context.Context, toolName, rawURL string) error {
+	allowlist := networkAllowlistFromContext(ctx)
+	if len(allowlist) == 0 {
+		return nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if hostAllowed(parsed.Hostname(), allowlist) {
+		return nil
+	}
+	return &ToolDenial{
+		ToolName:    toolName,
+		Family:      "network",
+		Reason:      fmt.Sprintf("host %q is not in the network allowlist", parsed.Hostname()),
+		Remediation: "Ask the operator to add the host to network_allowlist in the capability policy.",
+	}
+}
+
+// hostAllowed reports whether host matches any allowlist entry. An entry
+// matches itself and its subdomains; a leading "*." is accepted for clarity.
+func hostAllowed(host string, allowlist []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, entry := range allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		entry = strings.TrimPrefix(entry, "*.")
+		if entry == "" {
+			continue
+		}
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+type networkPolicyGuard struct {
+	tool      Tool
+	allowlist []string
+}
+
+func (g *networkPolicyGuard) Name() string        { return g.tool.Name() }
+func (g *networkPolicyGuard) Description() string { return g.tool.Description() }
+func (g *networkPolicyGuard) Unwrap() Tool        { return g.tool }
+
+func (g *networkPolicyGuard) Execute(ctx context.Context, args ...string) (string, error) {
+	return g.tool.Execute(withNetworkAllowlist(ctx, g.allowlist), args...)
+}
+
+// Variants that preserve ToolSchema and/or jsonExecutor interfaces.
+
+type networkPolicyGuardWithSchema struct {
+	*networkPolicyGuard
+	schema ToolSchema
+}
+
+func (g *networkPolicyGuardWithSchema) GetSchema() map[string]interface{} {
+	return g.schema.GetSchema()
+}
+
+type networkPolicyGuardWithJSON struct {
+	*networkPolicyGuard
+	json jsonExecutor
+}
+
+func (g *networkPolicyGuardWithJSON) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	return g.json.ExecuteJSON(withNetworkAllowlist(ctx, g.allowlist), params)
+}
+
+type networkPolicyGuardWithSchemaAndJSON struct {
+	*networkPolicyGuard
+	schema ToolSchema
+	json   jsonExecutor
+}
+
+func (g *networkPolicyGuardWithSchemaAndJSON) GetSchema() map[string]interface{} {
+	return g.schema.GetSchema()
+}
+
+func (g *networkPolicyGuardWithSchemaAndJSON) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	return g.json.ExecuteJSON(withNetworkAllowlist(ctx, g.allowlist), params)
+}
+
+func wrapToolWithNetworkPolicy(tool Tool, allowlist []string) Tool {
+	base := &networkPolicyGuard{tool: tool, allowlist: allowlist}
+	schema, hasSchema := tool.(ToolSchema)
+	jsonExec, hasJSON := tool.(jsonExecutor)
+
+	switch {
+	case hasSchema && hasJSON:
+		return &networkPolicyGuardWithSchemaAndJSON{
+			networkPolicyGuard: base,
+			schema:             schema,
+			json:               jsonExec,
+		}
+	case hasSchema:
+		return &networkPolicyGuardWithSchema{
+			networkPolicyGuard: base,
+			schema:             schema,
+		}
+	case hasJSON:
+		return &networkPolicyGuardWithJSON{
+			networkPolicyGuard: base,
+			json:               jsonExec,
+		}
+	default:
+		return base
 	}
-	return base
 }
@@ -21,6 +21,7 @@ func TestCapabilityPolicy_DeniedCapability(t *testing.T) {
 		{"network denied blocks browser", CapabilityPolicy{Shell: true, Cron: true, MemoryWrite: true, Spawn: true}, "browser", "network"},
 		{"network denied blocks browser_task", CapabilityPolicy{Shell: true, Cron: true, MemoryWrite: true}, "browser_task", "network"},
 		{"spawn denied blocks browser_task", CapabilityPolicy{Shell: true, Network: true, Cron: true, MemoryWrite: true}, "browser_task", "spawn"},
+		{"shell denied blocks git", CapabilityPolicy{Network: true, Cron: true, MemoryWrite: true, Spawn: true}, "git", "shell"},
 		{"cron denied blocks cron", CapabilityPolicy{Shell: true, Network: true, MemoryWrite: true, Spawn: true}, "cron", "cron"},
 		{"all allowed passes file", CapabilityPolicy{Shell: true, Network: true, Cron: true, MemoryWrite: true, Spawn: true}, "file", ""},
 		{"unmapped tool allowed", CapabilityPolicy{}, "image", ""},
@@ -345,3 +346,151 @@ func (s *stubSchemaAndJSONTool) ExecuteJSON(_ context.Context, _ map[string]stri
 	s.jsonCalled++
 	return "ok", nil
 }
+
+func TestApplyPolicy_NetworkAllowlistCarriedToTool(t *testing.T) {
+	base := NewRegistry()
+	tool := NewHTTPRequestTool(nil, 0)
+	tool.validateURL = func(string) error { return nil }
+	base.Register(tool)
+
+	reg := ApplyPolicy(base, &CapabilityPolicy{
+		Shell:            true,
+		Network:          true,
+		NetworkAllowlist: []string{"*.example.com"},
+		Cron:             true,
+		MemoryWrite:      true,
+		Spawn:            true,
+	})
+
+	wrapped, _ := reg.Get("http_request")
+	if _, ok := wrapped.(ToolSchema); !ok {
+		t.Fatal("network guard must preserve ToolSchema")
+	}
+	je, ok := wrapped.(jsonExecutor)
+	if !ok {
+		t.Fatal("network guard must preserve ExecuteJSON")
+	}
+	_, err := je.ExecuteJSON(context.Background(), map[string]string{"url": "https://blocked.org/"})
+	if _, isDenial := IsToolDenial(err); !isDenial {
+		t.Fatalf("expected ToolDenial for host outside allowlist, got %v", err)
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	allowlist := []string{"example.com", "*.api.io"}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"sub.example.com", true},
+		{"EXAMPLE.COM", true},
+		{"badexample.com", false},
+		{"x.api.io", true},
+		{"api.io", true},
+		{"other.org", false},
+	}
+	for _, tt := range tests {
+		if got := hostAllowed(tt.host, allowlist); got != tt.want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestApplyPolicy_FilePolicyCoversJSONParams(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	reg := NewRegistry()
+	reg.Register(&FileTool{BasePath: tmpDir})
+
+	result := ApplyPolicy(reg, &CapabilityPolicy{
+		Shell: true, Network: true, Cron: true, MemoryWrite: true, Spawn: true,
+		FileReadOnly:    true,
+		FilesystemRoots: []string{tmpDir},
+	})
+	tool, _ := result.Get("file")
+	je, ok := tool.(jsonExecutor)
+	if !ok {
+		t.Fatal("file policy guard must preserve ExecuteJSON")
+	}
+
+	for _, op := range []string{"replace", "create", "delete", "move"} {
+		_, err := je.ExecuteJSON(context.Background(), map[string]string{"command": op, "path": "a.txt"})
+		if denial, ok := IsToolDenial(err); !ok || denial.Family != "file_write" {
+			t.Errorf("%s: expected file_write denial, got %v", op, err)
+		}
+	}
+
+	_, err := je.ExecuteJSON(context.Background(), map[string]string{"command": "list", "path": "/etc"})
+	if denial, ok := IsToolDenial(err); !ok || denial.Family != "filesystem" {
+		t.Errorf("expected filesystem denial for list outside roots, got %v", err)
+	}
+}
+
+func TestApplyPolicy_FilePolicyChecksEveryPatchTarget(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	reg := NewRegistry()
+	reg.Register(NewPatchTool(tmpDir))
+
+	result := ApplyPolicy(reg, &CapabilityPolicy{
+		Shell: true, Network: true, Cron: true, MemoryWrite: true, Spawn: true,
+		FilesystemRoots: []string{tmpDir},
+	})
+	tool, _ := result.Get("patch")
+
+	diff := "--- a/ok.txt\n+++ b/ok.txt\n@@ -1 +1 @@\n-a\n+b\n" +
+		"--- /etc/hosts\n+++ /etc/hosts\n@@ -1 +1 @@\n-a\n+b\n"
+
+	_, err := tool.Execute(context.Background(), diff)
+	if denial, ok := IsToolDenial(err); !ok || denial.Family != "filesystem" {
+		t.Errorf("positional: expected filesystem denial, got %v", err)
+	}
+
+	je, ok := tool.(jsonExecutor)
+	if !ok {
+		t.Fatal("file policy guard must preserve ExecuteJSON")
+	}
+	_, err = je.ExecuteJSON(context.Background(), map[string]string{"patch": diff})
+	if denial, ok := IsToolDenial(err); !ok || denial.Family != "filesystem" {
+		t.Errorf("json: expected filesystem denial, got %v", err)
+	}
+
+	readOnly := ApplyPolicy(reg, &CapabilityPolicy{
+		Shell: true, Network: true, Cron: true, MemoryWrite: true, Spawn: true,
+		FileReadOnly: true,
+	})
+	tool, _ = readOnly.Get("patch")
+	_, err = tool.Execute(context.Background(), diff)
+	if denial, ok := IsToolDenial(err); !ok || denial.Family != "file_write" {
+		t.Errorf("read-only: expected file_write denial, got %v", err)
+	}
+}
+
+func TestApplyPolicy_ScopesGitTool(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	reg := NewRegistry()
+	reg.Register(NewGitTool(dir))
+
+	readOnly := ApplyPolicy(reg, &CapabilityPolicy{Shell: true, FileReadOnly: true})
+	tool, _ := readOnly.Get("git")
+	if gitTool, ok := AsGitTool(tool); !ok || !gitTool.ReadOnly {
+		t.Fatalf("expected read-only git tool, got %T", tool)
+	}
+
+	outside := ApplyPolicy(reg, &CapabilityPolicy{Shell: true, FilesystemRoots: []string{"/nonexistent-root"}})
+	_, err := outside.Execute(context.Background(), "git", "status")
+	if denial, ok := IsToolDenial(err); !ok || denial.Family != "filesystem" {
+		t.Fatalf("expected filesystem denial for repo outside roots, got %v", err)
+	}
+
+	inside := ApplyPolicy(reg, &CapabilityPolicy{Shell: true, FilesystemRoots: []string{dir}})
+	_, err = inside.Execute(context.Background(), "git", "log")
+	if _, ok := IsToolDenial(err); ok {
+		t.Fatalf("expected repo inside roots to be allowed, got %v", err)
+	}
+}
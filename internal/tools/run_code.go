@@ -0,0 +1,465 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"ok-gobot/internal/runtime"
+)
+
+const (
+	defaultRunCodeTimeout   = 30 * time.Second
+	maxRunCodeTimeout       = 5 * time.Minute
+	defaultRunCodeMemoryMB  = 1024
+	maxRunCodeOutputBytes   = 16000
+	maxRunCodeImages        = 10
+	maxRunCodeListedFiles   = 20
+	maxRunCodeApprovalChars = 1500
+)
+
+// runCodeImageTypes maps produced file extensions to attachment MIME types.
+var runCodeImageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// runCodeEnvPassthrough lists the parent environment variables an interpreter
+// may see. Everything else (API keys, bot tokens) is stripped.
+var runCodeEnvPassthrough = []string{
+	"PATH", "HOME", "USER", "LANG", "LC_ALL", "LC_CTYPE", "TZ",
+	"PYENV_ROOT", "PYENV_VERSION", "VIRTUAL_ENV", "CONDA_PREFIX", "PYTHONPATH",
+	"NODE_PATH", "GOROOT", "GOPATH", "GOMODCACHE", "GOPROXY",
+}
+
+var unsafeSessionChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// RunCodeConfig configures the run_code tool.
+type RunCodeConfig struct {
+	ScratchDir string        // parent of per-session working directories
+	Timeout    time.Duration // default time limit per run
+	MemoryMB   int           // memory limit per run
+	Python     string        // python interpreter binary
+	Node       string        // node binary
+	Go         string        // go toolchain binary
+	Unattended bool          // run without approval when no chat can be asked
+}
+
+// RunCodeTool executes Python, JavaScript, or Go snippets in a per-session
+// scratch directory and returns their output plus any images they produce.
+//
+// Snippets run as the bot's user with its network and filesystem access; the
+// scratch directory, stripped environment and resource limits only keep runs
+// tidy and are not a sandbox. Each run is therefore treated like a shell
+// command: it is approved in the owning chat through ApprovalFunc, and runs
+// with no chat to ask are refused unless the config opts into Unattended.
+type RunCodeTool struct {
+	cfg          RunCodeConfig
+	ApprovalFunc func(command string) (bool, error)
+}
+
+// DefaultScratchDir returns the default parent of run_code session directories.
+func DefaultScratchDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".ok-gobot", "scratch")
+}
+
+// NewRunCodeTool creates a run_code tool, filling unset config with defaults.
+func NewRunCodeTool(cfg RunCodeConfig) *RunCodeTool {
+	if cfg.ScratchDir == "" {
+		cfg.ScratchDir = DefaultScratchDir()
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultRunCodeTimeout
+	}
+	if cfg.MemoryMB <= 0 {
+		cfg.MemoryMB = defaultRunCodeMemoryMB
+	}
+	if cfg.Python == "" {
+		cfg.Python = "python3"
+	}
+	if cfg.Node == "" {
+		cfg.Node = "node"
+	}
+	if cfg.Go == "" {
+		cfg.Go = "go"
+	}
+	return &RunCodeTool{cfg: cfg}
+}
+
+// RunCodeSessionDir returns the scratch directory of a session under root.
+// Uploaded files placed there are visible to run_code snippets.
+func RunCodeSessionDir(root, sessionKey string) string {
+	name := strings.Trim(unsafeSessionChars.ReplaceAllString(sessionKey, "_"), "_.")
+	if name == "" {
+		name = "default"
+	}
+	return filepath.Join(root, name)
+}
+
+func (t *RunCodeTool) Name() string {
+	return "run_code"
+}
+
+func (t *RunCodeTool) Description() string {
+	return "Run a Python, JavaScript, or Go snippet in this session's scratch directory (uploaded files live there). Returns stdout/stderr; saved images (e.g. matplotlib savefig PNGs) are sent to the user."
+}
+
+// WithApproval returns a copy of the tool that asks fn before each run.
+func (t *RunCodeTool) WithApproval(fn func(command string) (bool, error)) Tool {
+	clone := *t
+	clone.ApprovalFunc = fn
+	return &clone
+}
+
+// GetSchema returns the JSON Schema for run_code parameters.
+func (t *RunCodeTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"language": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"python", "javascript", "go"},
+				"description": "Snippet language",
+			},
+			"code": map[string]interface{}{
+				"type":        "string",
+				"description": "Source code. Go snippets must be a complete main package. Save charts to files (e.g. plt.savefig('chart.png')) to return them.",
+			},
+			"timeout_seconds": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Time limit in seconds (default %d, max %d)", int(t.cfg.Timeout.Seconds()), int(maxRunCodeTimeout.Seconds())),
+			},
+		},
+		"required": []string{"language", "code"},
+	}
+}
+
+// Execute runs a snippet with positional args: <language> <code...>.
+func (t *RunCodeTool) Execute(ctx context.Context, args ...string) (string, error) {
+	if len(args) < 2 {
+		return "", fmt.Errorf("usage: run_code <python|javascript|go> <code>")
+	}
+	return t.run(ctx, args[0], strings.Join(args[1:], " "), t.cfg.Timeout)
+}
+
+// ExecuteJSON runs a snippet with structured JSON parameters.
+func (t *RunCodeTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	timeout := t.cfg.Timeout
+	if raw := strings.TrimSpace(params["timeout_seconds"]); raw != "" {
+		secs, err := strconv.Atoi(raw)
+		if err != nil || secs <= 0 {
+			return "", fmt.Errorf("invalid timeout_seconds: %q", raw)
+		}
+		timeout = time.Duration(secs) * time.Second
+	}
+	if timeout > maxRunCodeTimeout {
+		timeout = maxRunCodeTimeout
+	}
+	return t.run(ctx, params["language"], params["code"], timeout)
+}
+
+func (t *RunCodeTool) run(ctx context.Context, language, code string, timeout time.Duration) (string, error) {
+	if strings.TrimSpace(code) == "" {
+		return "", fmt.Errorf("code is required")
+	}
+	lang, err := normalizeRunCodeLanguage(language)
+	if err != nil {
+		return "", err
+	}
+	if denied, err := t.approve(lang, code); err != nil || denied != "" {
+		return denied, err
+	}
+
+	dir := RunCodeSessionDir(t.cfg.ScratchDir, SessionKeyFromContext(ctx))
+	runDir := filepath.Join(dir, ".run")
+	for _, d := range []string{runDir, filepath.Join(dir, "tmp")} {
+		if err := os.MkdirAll(d, 0700); err != nil {
+			return "", fmt.Errorf("failed to prepare scratch directory: %w", err)
+		}
+	}
+	before := scanScratchFiles(dir)
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	env := t.environment(dir)
+	started := time.Now()
+
+	var argv []string
+	switch lang {
+	case "python":
+		script := filepath.Join(runDir, "main.py")
+		if err := os.WriteFile(script, []byte(code), 0600); err != nil {
+			return "", err
+		}
+		argv = t.limited(t.cfg.Python, "-u", script)
+	case "javascript":
+		script := filepath.Join(runDir, "main.js")
+		if err := os.WriteFile(script, []byte(code), 0600); err != nil {
+			return "", err
+		}
+		// V8 reserves more address space than ulimit -v allows; cap its heap instead.
+		argv = []string{t.cfg.Node, fmt.Sprintf("--max-old-space-size=%d", t.cfg.MemoryMB), script}
+	case "go":
+		source := filepath.Join(runDir, "main.go")
+		binary := filepath.Join(runDir, "main")
+		if err := os.WriteFile(source, []byte(code), 0600); err != nil {
+			return "", err
+		}
+		// Build first and run the binary directly so a timeout kills the
+		// program itself rather than only the `go run` parent.
+		build := exec.CommandContext(runCtx, t.cfg.Go, "build", "-o", binary, source)
+		build.Dir = dir
+		killGroupOnCancel(build)
+		build.Env = append(env, "GOTOOLCHAIN=local", "GO111MODULE=off", "GOCACHE="+filepath.Join(t.cfg.ScratchDir, ".gocache"))
+		if out, err := build.CombinedOutput(); err != nil {
+			if runCtx.Err() != nil {
+				return "", fmt.Errorf("go build exceeded the %s time limit", timeout)
+			}
+			return fmt.Sprintf("❌ Build failed (%.1fs)\n%s", time.Since(started).Seconds(), capRunCodeOutput(string(out))), nil
+		}
+		argv = t.limited(binary)
+		env = append(env, fmt.Sprintf("GOMEMLIMIT=%dMiB", t.cfg.MemoryMB))
+	}
+
+	var stdout, stderr cappedBuffer
+	stdout.limit, stderr.limit = maxRunCodeOutputBytes, maxRunCodeOutputBytes
+	cmd := exec.CommandContext(runCtx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = 2 * time.Second
+	killGroupOnCancel(cmd)
+	runErr := cmd.Run()
+	elapsed := time.Since(started)
+
+	var sb strings.Builder
+	switch {
+	case runCtx.Err() == context.DeadlineExceeded:
+		fmt.Fprintf(&sb, "⏱ Killed after %s time limit\n", timeout)
+	case ctx.Err() != nil:
+		return "", ctx.Err()
+	case runErr != nil:
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return "", fmt.Errorf("failed to start %s: %w", argv[0], runErr)
+		}
+		fmt.Fprintf(&sb, "❌ Exit code %d (%.1fs)\n", exitErr.ExitCode(), elapsed.Seconds())
+	default:
+		fmt.Fprintf(&sb, "✅ Exit code 0 (%.1fs)\n", elapsed.Seconds())
+	}
+	if out := stdout.String(); out != "" {
+		sb.WriteString("stdout:\n" + out)
+		if !strings.HasSuffix(out, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	if out := stderr.String(); out != "" {
+		sb.WriteString("stderr:\n" + out)
+		if !strings.HasSuffix(out, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+
+	images, files := changedScratchFiles(dir, before)
+	if len(images) > maxRunCodeImages {
+		images = images[:maxRunCodeImages]
+	}
+	if len(images) > 0 {
+		sb.WriteString("images:")
+		for _, rel := range images {
+			path := filepath.Join(dir, rel)
+			mime := runCodeImageTypes[strings.ToLower(filepath.Ext(rel))]
+			sent := AddAttachment(ctx, Attachment{Path: path, MimeType: mime, Caption: rel})
+			if _, err := runtime.AddContextArtifact(ctx, runtime.JobArtifactSpec{
+				Name:     "run_code/" + rel,
+				Type:     "image",
+				MimeType: mime,
+				URI:      "file://" + path,
+			}); err != nil {
+				sb.WriteString(fmt.Sprintf(" (artifact failed: %v)", err))
+			}
+			if sent {
+				sb.WriteString(" " + rel + " (sent to user)")
+			} else {
+				sb.WriteString(" " + path)
+			}
+		}
+		sb.WriteString("\n")
+	}
+	if len(files) > 0 {
+		if len(files) > maxRunCodeListedFiles {
+			files = append(files[:maxRunCodeListedFiles], fmt.Sprintf("… %d more", len(files)-maxRunCodeListedFiles))
+		}
+		sb.WriteString("files written: " + strings.Join(files, ", ") + "\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// approve asks the owning chat to confirm a run. It returns a non-empty
+// message when the user declined.
+func (t *RunCodeTool) approve(lang, code string) (string, error) {
+	if t.ApprovalFunc == nil {
+		if t.cfg.Unattended {
+			return "", nil
+		}
+		return "", &ToolDenial{
+			ToolName:    t.Name(),
+			Family:      "local",
+			Reason:      "running code requires user approval and this run has no chat to ask",
+			Remediation: "Run the task from a Telegram chat so the snippet can be approved, or set tools.run_code.unattended.",
+		}
+	}
+	approved, err := t.ApprovalFunc(fmt.Sprintf("run_code %s:\n%s", lang, truncateRunes(code, maxRunCodeApprovalChars)))
+	if err != nil {
+		return "", fmt.Errorf("approval check failed: %w", err)
+	}
+	if !approved {
+		return "Run denied by user", nil
+	}
+	return "", nil
+}
+
+func normalizeRunCodeLanguage(language string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(language)) {
+	case "python", "py", "python3":
+		return "python", nil
+	case "javascript", "js", "node":
+		return "javascript", nil
+	case "go", "golang":
+		return "go", nil
+	case "":
+		return "", fmt.Errorf("language is required (python, javascript, or go)")
+	default:
+		return "", fmt.Errorf("unsupported language %q (use python, javascript, or go)", language)
+	}
+}
+
+// killGroupOnCancel runs cmd in its own process group and kills the whole
+// group on timeout or cancel, so processes a snippet spawns do not outlive
+// the deadline.
+func killGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// limited wraps a command so it runs under the configured address-space limit.
+func (t *RunCodeTool) limited(argv ...string) []string {
+	script := fmt.Sprintf(`ulimit -v %d 2>/dev/null; exec "$@"`, t.cfg.MemoryMB*1024)
+	return append([]string{"bash", "-c", script, "run_code"}, argv...)
+}
+
+// environment builds a minimal environment for snippets: a few parent
+// variables needed to locate interpreters, with secrets left out.
+func (t *RunCodeTool) environment(dir string) []string {
+	env := make([]string, 0, len(runCodeEnvPassthrough)+4)
+	for _, name := range runCodeEnvPassthrough {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return append(env,
+		"TMPDIR="+filepath.Join(dir, "tmp"),
+		"MPLBACKEND=Agg",
+		"PYTHONDONTWRITEBYTECODE=1",
+		"NO_COLOR=1",
+	)
+}
+
+type scratchFileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// scanScratchFiles records the files in a scratch directory, ignoring the
+// tool's own bookkeeping directories.
+func scanScratchFiles(dir string) map[string]scratchFileState {
+	state := make(map[string]scratchFileState)
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error { //nolint:errcheck
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if d.IsDir() {
+			if rel == ".run" || rel == "tmp" || (rel != "." && strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			state[rel] = scratchFileState{size: info.Size(), modTime: info.ModTime()}
+		}
+		return nil
+	})
+	return state
+}
+
+// changedScratchFiles returns images and other files created or modified
+// since before was taken, sorted by path.
+func changedScratchFiles(dir string, before map[string]scratchFileState) (images, files []string) {
+	for rel, now := range scanScratchFiles(dir) {
+		if prev, ok := before[rel]; ok && prev == now {
+			continue
+		}
+		if _, ok := runCodeImageTypes[strings.ToLower(filepath.Ext(rel))]; ok {
+			images = append(images, rel)
+		} else {
+			files = append(files, rel)
+		}
+	}
+	sort.Strings(images)
+	sort.Strings(files)
+	return images, files
+}
+
+// cappedBuffer keeps the first limit bytes written and counts the rest.
+type cappedBuffer struct {
+	buf     bytes.Buffer
+	limit   int
+	dropped int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room > 0 {
+		if len(p) <= room {
+			b.buf.Write(p)
+		} else {
+			b.buf.Write(p[:room])
+			b.dropped += len(p) - room
+		}
+	} else {
+		b.dropped += len(p)
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+	if b.dropped == 0 {
+		return b.buf.String()
+	}
+	return fmt.Sprintf("%s\n… (%d more bytes truncated)", b.buf.String(), b.dropped)
+}
+
+func capRunCodeOutput(s string) string {
+	if len(s) <= maxRunCodeOutputBytes {
+		return s
+	}
+	return fmt.Sprintf("%s\n… (%d more bytes truncated)", s[:maxRunCodeOutputBytes], len(s)-maxRunCodeOutputBytes)
+}
@@ -0,0 +1,222 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRunCodeTool(t *testing.T) *RunCodeTool {
+	t.Helper()
+	return NewRunCodeTool(RunCodeConfig{ScratchDir: t.TempDir(), Unattended: true})
+}
+
+func requireBinary(t *testing.T, name string) {
+	t.Helper()
+	if _, err := exec.LookPath(name); err != nil {
+		t.Skipf("%s not installed", name)
+	}
+}
+
+func TestRunCode_PythonOutputAndImages(t *testing.T) {
+	requireBinary(t, "python3")
+	tool := newTestRunCodeTool(t)
+	ctx := WithSessionKey(context.Background(), "dm:42")
+	ctx, collector := WithAttachmentCollector(ctx)
+
+	code := `import sys
+print("sum", 2 + 3)
+print("warn", file=sys.stderr)
+open("chart.png", "wb").write(b"\x89PNG\r\n\x1a\n")
+open("out.csv", "w").write("a,b\n")
+`
+	out, err := tool.ExecuteJSON(ctx, map[string]string{"language": "python", "code": code})
+	if err != nil {
+		t.Fatalf("run_code: %v", err)
+	}
+	for _, want := range []string{"Exit code 0", "sum 5", "stderr:\nwarn", "chart.png (sent to user)", "files written: out.csv"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	attachments := collector.Attachments()
+	if len(attachments) != 1 || !attachments[0].IsImage() {
+		t.Fatalf("expected one image attachment, got %+v", attachments)
+	}
+	wantDir := RunCodeSessionDir(tool.cfg.ScratchDir, "dm:42")
+	if filepath.Dir(attachments[0].Path) != wantDir {
+		t.Errorf("attachment %s not in session dir %s", attachments[0].Path, wantDir)
+	}
+
+	// Unchanged files are not reported again on the next run.
+	out, err = tool.ExecuteJSON(ctx, map[string]string{"language": "python", "code": "print(open('out.csv').read())"})
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if strings.Contains(out, "images:") || strings.Contains(out, "files written") {
+		t.Errorf("unchanged files reported again:\n%s", out)
+	}
+}
+
+func TestRunCode_SessionsAreSeparated(t *testing.T) {
+	requireBinary(t, "python3")
+	tool := newTestRunCodeTool(t)
+
+	a := WithSessionKey(context.Background(), "dm:1")
+	b := WithSessionKey(context.Background(), "dm:2")
+	if _, err := tool.ExecuteJSON(a, map[string]string{"language": "python", "code": "open('secret.txt','w').write('x')"}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := tool.ExecuteJSON(b, map[string]string{"language": "python", "code": "import os; print(os.path.exists('secret.txt'))"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "False") {
+		t.Errorf("session dm:2 can see dm:1's files:\n%s", out)
+	}
+}
+
+func TestRunCode_TimeoutAndExitCode(t *testing.T) {
+	requireBinary(t, "python3")
+	tool := newTestRunCodeTool(t)
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{
+		"language":        "python",
+		"code":            "import time\ntime.sleep(10)",
+		"timeout_seconds": "1",
+	})
+	if err != nil {
+		t.Fatalf("run_code: %v", err)
+	}
+	if !strings.Contains(out, "Killed after 1s") {
+		t.Errorf("expected timeout note, got:\n%s", out)
+	}
+
+	out, err = tool.ExecuteJSON(context.Background(), map[string]string{"language": "python", "code": "raise SystemExit(3)"})
+	if err != nil {
+		t.Fatalf("run_code: %v", err)
+	}
+	if !strings.Contains(out, "Exit code 3") {
+		t.Errorf("expected exit code 3, got:\n%s", out)
+	}
+}
+
+func TestRunCode_TimeoutKillsSpawnedProcesses(t *testing.T) {
+	requireBinary(t, "python3")
+	tool := newTestRunCodeTool(t)
+	dir := RunCodeSessionDir(tool.cfg.ScratchDir, "")
+	marker := filepath.Join(dir, "survivor.txt")
+
+	// The child outlives its parent unless the whole process group is killed.
+	code := "import subprocess, time\n" +
+		"subprocess.Popen(['bash', '-c', 'sleep 2; echo alive > survivor.txt'])\n" +
+		"time.sleep(10)"
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{
+		"language":        "python",
+		"code":            code,
+		"timeout_seconds": "1",
+	})
+	if err != nil {
+		t.Fatalf("run_code: %v", err)
+	}
+	if !strings.Contains(out, "Killed after 1s") {
+		t.Fatalf("expected timeout note, got:\n%s", out)
+	}
+
+	time.Sleep(2 * time.Second)
+	if _, err := os.Stat(marker); err == nil {
+		t.Fatal("a process spawned by the snippet survived the timeout")
+	}
+}
+
+func TestRunCode_EnvironmentDropsSecrets(t *testing.T) {
+	requireBinary(t, "python3")
+	t.Setenv("OKGOBOT_TEST_SECRET", "hunter2")
+	tool := newTestRunCodeTool(t)
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{
+		"language": "python",
+		"code":     "import os; print(os.environ.get('OKGOBOT_TEST_SECRET', 'absent'))",
+	})
+	if err != nil {
+		t.Fatalf("run_code: %v", err)
+	}
+	if !strings.Contains(out, "absent") {
+		t.Errorf("secret leaked into snippet environment:\n%s", out)
+	}
+}
+
+func TestRunCode_JavaScriptAndGo(t *testing.T) {
+	tool := newTestRunCodeTool(t)
+	ctx := context.Background()
+
+	t.Run("javascript", func(t *testing.T) {
+		requireBinary(t, "node")
+		out, err := tool.ExecuteJSON(ctx, map[string]string{"language": "js", "code": "console.log([1,2,3].map(x => x * 2).join(','))"})
+		if err != nil || !strings.Contains(out, "2,4,6") {
+			t.Fatalf("node run = %q, %v", out, err)
+		}
+	})
+
+	t.Run("go", func(t *testing.T) {
+		requireBinary(t, "go")
+		code := "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(\"hello from go\") }\n"
+		out, err := tool.ExecuteJSON(ctx, map[string]string{"language": "go", "code": code, "timeout_seconds": "120"})
+		if err != nil || !strings.Contains(out, "hello from go") {
+			t.Fatalf("go run = %q, %v", out, err)
+		}
+		out, err = tool.ExecuteJSON(ctx, map[string]string{"language": "go", "code": "package main\nfunc main() { undefined() }\n", "timeout_seconds": "120"})
+		if err != nil || !strings.Contains(out, "Build failed") {
+			t.Fatalf("expected build failure, got %q, %v", out, err)
+		}
+	})
+}
+
+func TestRunCode_RejectsUnknownLanguage(t *testing.T) {
+	tool := newTestRunCodeTool(t)
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"language": "ruby", "code": "puts 1"}); err == nil {
+		t.Fatal("expected unsupported language error")
+	}
+	if _, err := os.Stat(filepath.Join(tool.cfg.ScratchDir, "default")); !os.IsNotExist(err) {
+		t.Errorf("scratch dir should not be created for rejected runs: %v", err)
+	}
+}
+
+func TestRunCode_RequiresApproval(t *testing.T) {
+	requireBinary(t, "python3")
+	tool := NewRunCodeTool(RunCodeConfig{ScratchDir: t.TempDir()})
+	ctx := context.Background()
+	params := map[string]string{"language": "python", "code": "print('ran')"}
+
+	if _, err := tool.ExecuteJSON(ctx, params); err == nil {
+		t.Fatal("run without approval channel succeeded")
+	} else if _, ok := IsToolDenial(err); !ok {
+		t.Fatalf("run without approval channel = %v, want ToolDenial", err)
+	}
+
+	var asked string
+	denied, ok := BindApproval(tool, func(command string) (bool, error) {
+		asked = command
+		return false, nil
+	})
+	if !ok {
+		t.Fatal("run_code does not accept an approval func")
+	}
+	out, err := denied.(*RunCodeTool).ExecuteJSON(ctx, params)
+	if err != nil || out != "Run denied by user" {
+		t.Fatalf("declined run = %q, %v", out, err)
+	}
+	if !strings.Contains(asked, "print('ran')") {
+		t.Errorf("approval prompt %q does not show the code", asked)
+	}
+
+	approved := tool.WithApproval(func(string) (bool, error) { return true, nil }).(*RunCodeTool)
+	if out, err := approved.ExecuteJSON(ctx, params); err != nil || !strings.Contains(out, "ran") {
+		t.Fatalf("approved run = %q, %v", out, err)
+	}
+}
@@ -1,30 +1,69 @@
 package tools
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"net/http"
-	"net/url"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 )
 
-// SearchTool provides web search capabilities
+const (
+	defaultSearchCount    = 5
+	maxSearchCount        = 20
+	maxSearchPage         = 10
+	defaultSearchCacheTTL = time.Hour
+)
+
+// SearchQuery is one web search request passed to a provider.
+type SearchQuery struct {
+	Query     string
+	Count     int    // results per page (1-20)
+	Page      int    // 1-based result page
+	Freshness string // "", "day", "week", "month", or "year"
+	Site      string // restrict results to this domain
+}
+
+// SearchResult represents a single search result
+type SearchResult struct {
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	Snippet   string `json:"snippet,omitempty"`
+	Published string `json:"published,omitempty"`
+}
+
+// SearchProvider is a web search backend (SearXNG, Brave, Exa, Serper).
+type SearchProvider interface {
+	Name() string
+	Search(ctx context.Context, q SearchQuery) ([]SearchResult, error)
+}
+
+// SearchCache stores serialized results between identical queries.
+// Implemented by storage.Store.
+type SearchCache interface {
+	GetSearchCache(key string) (provider, results string, ok bool, err error)
+	PutSearchCache(key, provider, results string, ttl time.Duration) error
+}
+
+// SearchTool provides web search over a chain of providers. Providers are
+// tried in order; the next one is used when a provider fails.
 type SearchTool struct {
-	APIKey string
-	Engine string // "exa", "brave", "serper", etc.
+	providers []SearchProvider
+	cache     SearchCache
+	cacheTTL  time.Duration
 }
 
-// NewSearchTool creates a new search tool
-func NewSearchTool(apiKey, engine string) *SearchTool {
-	if engine == "" {
-		engine = "brave"
-	}
-	return &SearchTool{
-		APIKey: apiKey,
-		Engine: engine,
+// NewSearchTool creates a search tool over providers. A nil cache or a
+// non-positive ttl disables caching; ttl 0 uses the one-hour default.
+func NewSearchTool(providers []SearchProvider, cache SearchCache, ttl time.Duration) *SearchTool {
+	if ttl == 0 {
+		ttl = defaultSearchCacheTTL
 	}
+	return &SearchTool{providers: providers, cache: cache, cacheTTL: ttl}
 }
 
 func (s *SearchTool) Name() string {
@@ -32,147 +71,201 @@ func (s *SearchTool) Name() string {
 }
 
 func (s *SearchTool) Description() string {
-	return fmt.Sprintf("Search the web using %s", s.Engine)
+	names := make([]string, 0, len(s.providers))
+	for _, p := range s.providers {
+		names = append(names, p.Name())
+	}
+	return fmt.Sprintf("Search the web (%s). Returns titles, URLs, snippets and publish dates; use web_fetch to read a result", strings.Join(names, " → "))
 }
 
-// SearchResult represents a single search result
-type SearchResult struct {
-	Title   string
-	URL     string
-	Snippet string
+// GetSchema returns the JSON Schema for search parameters.
+func (s *SearchTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Search query",
+			},
+			"count": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Number of results (default %d, max %d)", defaultSearchCount, maxSearchCount),
+			},
+			"page": map[string]interface{}{
+				"type":        "integer",
+				"description": "Result page, starting at 1",
+			},
+			"freshness": map[string]interface{}{
+				"type":        "string",
+				"description": "Only return results published within this period",
+				"enum":        []string{"day", "week", "month", "year"},
+			},
+			"site": map[string]interface{}{
+				"type":        "string",
+				"description": "Restrict results to a domain, e.g. go.dev",
+			},
+		},
+		"required": []string{"query"},
+	}
 }
 
-// Execute performs a web search
+// Execute performs a web search with default parameters.
 func (s *SearchTool) Execute(ctx context.Context, args ...string) (string, error) {
 	if len(args) == 0 {
 		return "", fmt.Errorf("search query required")
 	}
-
-	searchQuery := args[0]
-
-	switch s.Engine {
-	case "brave":
-		return s.searchBrave(searchQuery)
-	case "exa":
-		return s.searchExa(searchQuery)
-	default:
-		return "", fmt.Errorf("unsupported search engine: %s", s.Engine)
-	}
+	return s.ExecuteJSON(ctx, map[string]string{"query": strings.Join(args, " ")})
 }
 
-// searchBrave performs a search using Brave Search API
-func (s *SearchTool) searchBrave(query string) (string, error) {
-	if s.APIKey == "" {
-		return "", fmt.Errorf("Brave Search API key not configured")
-	}
-
-	baseURL := "https://api.search.brave.com/res/v1/web/search"
-	params := url.Values{}
-	params.Add("q", query)
-	params.Add("count", "5")
-
-	req, err := http.NewRequest("GET", baseURL+"?"+params.Encode(), nil)
+// ExecuteJSON performs a web search with structured parameters.
+func (s *SearchTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	q, err := parseSearchQuery(params)
 	if err != nil {
 		return "", err
 	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("X-Subscription-Token", s.APIKey)
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	provider, results, cached, err := s.search(ctx, q)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
+	return formatSearchResults(q, provider, results, cached), nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("search API returned status %d", resp.StatusCode)
+func parseSearchQuery(params map[string]string) (SearchQuery, error) {
+	q := SearchQuery{
+		Query:     strings.TrimSpace(params["query"]),
+		Count:     defaultSearchCount,
+		Page:      1,
+		Freshness: strings.ToLower(strings.TrimSpace(params["freshness"])),
+		Site:      normalizeSearchSite(params["site"]),
 	}
-
-	var result struct {
-		Web struct {
-			Results []struct {
-				Title string `json:"title"`
-				URL   string `json:"url"`
-				Desc  string `json:"description"`
-			} `json:"results"`
-		} `json:"web"`
+	if q.Query == "" {
+		return q, fmt.Errorf("search query required")
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+	if raw := strings.TrimSpace(params["count"]); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return q, fmt.Errorf("invalid count %q", raw)
+		}
+		q.Count = min(n, maxSearchCount)
 	}
-
-	if len(result.Web.Results) == 0 {
-		return "No results found.", nil
+	if raw := strings.TrimSpace(params["page"]); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSearchPage {
+			return q, fmt.Errorf("invalid page %q (1-%d)", raw, maxSearchPage)
+		}
+		q.Page = n
 	}
-
-	var output string
-	output = fmt.Sprintf("Search results for '%s':\n\n", query)
-	for i, r := range result.Web.Results {
-		output += fmt.Sprintf("%d. **%s**\n   %s\n   %s\n\n",
-			i+1, r.Title, r.URL, r.Desc)
+	switch q.Freshness {
+	case "", "day", "week", "month", "year":
+	default:
+		return q, fmt.Errorf("invalid freshness %q (use day, week, month or year)", q.Freshness)
 	}
-
-	return output, nil
+	return q, nil
 }
 
-// searchExa performs a search using Exa API
-func (s *SearchTool) searchExa(query string) (string, error) {
-	if s.APIKey == "" {
-		return "", fmt.Errorf("Exa API key not configured")
+// normalizeSearchSite reduces "https://Go.dev/doc" to "go.dev".
+func normalizeSearchSite(site string) string {
+	site = strings.ToLower(strings.TrimSpace(site))
+	site = strings.TrimPrefix(site, "site:")
+	if i := strings.Index(site, "://"); i >= 0 {
+		site = site[i+3:]
 	}
-
-	baseURL := "https://api.exa.ai/search"
-	payload := map[string]interface{}{
-		"query":         query,
-		"numResults":    5,
-		"useAutoprompt": true,
+	if i := strings.IndexAny(site, "/?#"); i >= 0 {
+		site = site[:i]
 	}
+	return site
+}
 
-	jsonPayload, _ := json.Marshal(payload)
-	req, err := http.NewRequest("POST", baseURL, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return "", err
+// search returns results from the cache or the first provider that succeeds.
+func (s *SearchTool) search(ctx context.Context, q SearchQuery) (string, []SearchResult, bool, error) {
+	if len(s.providers) == 0 {
+		return "", nil, false, fmt.Errorf("no search provider configured (set tools.search.providers)")
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", s.APIKey)
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+	key := s.cacheKey(q)
+	if s.cache != nil && s.cacheTTL > 0 {
+		provider, raw, ok, err := s.cache.GetSearchCache(key)
+		if err != nil {
+			log.Printf("[search] cache read failed: %v", err)
+		} else if ok {
+			var results []SearchResult
+			if err := json.Unmarshal([]byte(raw), &results); err == nil {
+				return provider, results, true, nil
+			}
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Exa API returned status %d", resp.StatusCode)
+	var errs []string
+	for _, p := range s.providers {
+		results, err := p.Search(ctx, q)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", nil, false, ctx.Err()
+			}
+			log.Printf("[search] %s failed, trying next provider: %v", p.Name(), err)
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			continue
+		}
+		if len(results) > q.Count {
+			results = results[:q.Count]
+		}
+		if s.cache != nil && s.cacheTTL > 0 {
+			if raw, err := json.Marshal(results); err == nil {
+				if err := s.cache.PutSearchCache(key, p.Name(), string(raw), s.cacheTTL); err != nil {
+					log.Printf("[search] cache write failed: %v", err)
+				}
+			}
+		}
+		return p.Name(), results, false, nil
 	}
+	return "", nil, false, fmt.Errorf("all search providers failed: %s", strings.Join(errs, "; "))
+}
 
-	var result struct {
-		Results []struct {
-			Title   string `json:"title"`
-			URL     string `json:"url"`
-			Summary string `json:"summary"`
-		} `json:"results"`
+// cacheKey identifies a query for a given provider chain, so changing the
+// configured providers does not serve results from the old chain.
+func (s *SearchTool) cacheKey(q SearchQuery) string {
+	names := make([]string, 0, len(s.providers))
+	for _, p := range s.providers {
+		names = append(names, p.Name())
 	}
+	raw := strings.Join([]string{
+		strings.Join(names, ","),
+		strings.ToLower(q.Query),
+		strconv.Itoa(q.Count),
+		strconv.Itoa(q.Page),
+		q.Freshness,
+		q.Site,
+	}, "\x00")
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+func formatSearchResults(q SearchQuery, provider string, results []SearchResult, cached bool) string {
+	if len(results) == 0 {
+		return fmt.Sprintf("No results found for '%s' (%s).", q.Query, provider)
 	}
 
-	if len(result.Results) == 0 {
-		return "No results found.", nil
+	var sb strings.Builder
+	source := provider
+	if cached {
+		source += ", cached"
 	}
-
-	var output string
-	output = fmt.Sprintf("Search results for '%s':\n\n", query)
-	for i, r := range result.Results {
-		output += fmt.Sprintf("%d. **%s**\n   %s\n   %s\n\n",
-			i+1, r.Title, r.URL, r.Summary)
+	fmt.Fprintf(&sb, "Search results for '%s' (%s", q.Query, source)
+	if q.Page > 1 {
+		fmt.Fprintf(&sb, ", page %d", q.Page)
 	}
+	sb.WriteString("):\n\n")
 
-	return output, nil
+	offset := (q.Page - 1) * q.Count
+	for i, r := range results {
+		fmt.Fprintf(&sb, "%d. **%s**\n   %s\n", offset+i+1, r.Title, r.URL)
+		if r.Published != "" {
+			fmt.Fprintf(&sb, "   Published: %s\n", r.Published)
+		}
+		if r.Snippet != "" {
+			fmt.Fprintf(&sb, "   %s\n", r.Snippet)
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
 }
@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeSearchProvider struct {
+	name    string
+	results []SearchResult
+	err     error
+	calls   int
+}
+
+func (f *fakeSearchProvider) Name() string { return f.name }
+
+func (f *fakeSearchProvider) Search(_ context.Context, _ SearchQuery) ([]SearchResult, error) {
+	f.calls++
+	return f.results, f.err
+}
+
+type memorySearchCache struct {
+	entries map[string][2]string
+}
+
+func (m *memorySearchCache) GetSearchCache(key string) (string, string, bool, error) {
+	e, ok := m.entries[key]
+	return e[0], e[1], ok, nil
+}
+
+func (m *memorySearchCache) PutSearchCache(key, provider, results string, _ time.Duration) error {
+	if m.entries == nil {
+		m.entries = map[string][2]string{}
+	}
+	m.entries[key] = [2]string{provider, results}
+	return nil
+}
+
+func TestSearchTool_FallsBackAndCaches(t *testing.T) {
+	broken := &fakeSearchProvider{name: "searxng", err: errors.New("connection refused")}
+	backup := &fakeSearchProvider{name: "brave", results: []SearchResult{
+		{Title: "Go", URL: "https://go.dev", Snippet: "The Go language", Published: "2026-01-02"},
+	}}
+	cache := &memorySearchCache{}
+	tool := NewSearchTool([]SearchProvider{broken, backup}, cache, time.Hour)
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"query": "golang"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	for _, want := range []string{"(brave)", "**Go**", "https://go.dev", "Published: 2026-01-02"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	out, err = tool.ExecuteJSON(context.Background(), map[string]string{"query": "golang"})
+	if err != nil {
+		t.Fatalf("cached search: %v", err)
+	}
+	if !strings.Contains(out, "(brave, cached)") {
+		t.Errorf("expected cached result, got:\n%s", out)
+	}
+	if broken.calls != 1 || backup.calls != 1 {
+		t.Errorf("providers called again on cache hit: %d, %d", broken.calls, backup.calls)
+	}
+
+	// Different parameters are a different cache entry.
+	if _, err := tool.ExecuteJSON(context.Background(), map[string]string{"query": "golang", "site": "go.dev"}); err != nil {
+		t.Fatal(err)
+	}
+	if backup.calls != 2 {
+		t.Errorf("site-restricted query served from cache")
+	}
+}
+
+func TestSearchTool_AllProvidersFail(t *testing.T) {
+	tool := NewSearchTool([]SearchProvider{
+		&fakeSearchProvider{name: "searxng", err: errors.New("down")},
+		&fakeSearchProvider{name: "exa", err: errors.New("status 401")},
+	}, nil, 0)
+	_, err := tool.ExecuteJSON(context.Background(), map[string]string{"query": "x"})
+	if err == nil || !strings.Contains(err.Error(), "searxng: down") || !strings.Contains(err.Error(), "exa: status 401") {
+		t.Fatalf("expected combined provider errors, got %v", err)
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	q, err := parseSearchQuery(map[string]string{"query": "news", "count": "50", "page": "2", "freshness": "Week", "site": "https://Example.com/blog"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Count != maxSearchCount || q.Page != 2 || q.Freshness != "week" || q.Site != "example.com" {
+		t.Errorf("unexpected query %+v", q)
+	}
+	for _, bad := range []map[string]string{
+		{"query": ""},
+		{"query": "x", "count": "0"},
+		{"query": "x", "page": "99"},
+		{"query": "x", "freshness": "hour"},
+	} {
+		if _, err := parseSearchQuery(bad); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestSearXNGProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/search" || q.Get("format") != "json" || q.Get("q") != "ok site:go.dev" ||
+			q.Get("pageno") != "2" || q.Get("time_range") != "month" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{ //nolint:errcheck
+			"results": []map[string]string{
+				{"title": "A", "url": "https://go.dev/a", "content": "  first\n result ", "publishedDate": "2026-03-01T00:00:00"},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	p, err := NewSearchProvider(SearchProviderConfig{Type: "searxng", BaseURL: srv.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := p.Search(context.Background(), SearchQuery{Query: "ok", Count: 5, Page: 2, Freshness: "month", Site: "go.dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Snippet != "first result" || results[0].Published != "2026-03-01T00:00:00" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestBraveProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.Header.Get("X-Subscription-Token") != "key" || q.Get("count") != "3" || q.Get("offset") != "1" || q.Get("freshness") != "pd" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"web":{"results":[{"title":"B","url":"https://b","description":"desc","age":"2 days ago"}]}}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	p, err := NewSearchProvider(SearchProviderConfig{Type: "brave", BaseURL: srv.URL, APIKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := p.Search(context.Background(), SearchQuery{Query: "b", Count: 3, Page: 2, Freshness: "day"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Published != "2 days ago" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestExaProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		if r.Header.Get("x-api-key") != "key" || body["numResults"] != float64(4) || body["startPublishedDate"] == nil {
+			t.Errorf("unexpected request %v", body)
+		}
+		if domains, _ := body["includeDomains"].([]interface{}); len(domains) != 1 || domains[0] != "go.dev" {
+			t.Errorf("includeDomains = %v", body["includeDomains"])
+		}
+		w.Write([]byte(`{"results":[{"title":"1","url":"u1"},{"title":"2","url":"u2"},{"title":"3","url":"u3","text":"t3"},{"title":"4","url":"u4"}]}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	p, err := NewSearchProvider(SearchProviderConfig{Type: "exa", BaseURL: srv.URL, APIKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := p.Search(context.Background(), SearchQuery{Query: "x", Count: 2, Page: 2, Freshness: "year", Site: "go.dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Title != "3" || results[0].Snippet != "t3" {
+		t.Errorf("expected second page, got %+v", results)
+	}
+}
+
+func TestSerperProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body) //nolint:errcheck
+		if r.Header.Get("X-API-KEY") != "key" || body["tbs"] != "qdr:w" || body["page"] != float64(1) {
+			t.Errorf("unexpected request %v", body)
+		}
+		w.Write([]byte(`{"organic":[{"title":"S","link":"https://s","snippet":"snip","date":"Mar 1, 2026"}]}`)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	p, err := NewSearchProvider(SearchProviderConfig{Type: "serper", BaseURL: srv.URL, APIKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := p.Search(context.Background(), SearchQuery{Query: "s", Count: 5, Page: 1, Freshness: "week"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].URL != "https://s" || results[0].Published != "Mar 1, 2026" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestNewSearchProvider_RequiresCredentials(t *testing.T) {
+	for _, cfg := range []SearchProviderConfig{
+		{Type: "searxng"},
+		{Type: "brave"},
+		{Type: "exa"},
+		{Type: "serper"},
+		{Type: "bing", APIKey: "k"},
+	} {
+		if _, err := NewSearchProvider(cfg); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
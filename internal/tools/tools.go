@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -41,12 +42,14 @@ type jsonExecutor interface {
 var dangerousToolFamilyNames = []string{"local", "ssh", "browser", "cron", "message"}
 
 var dangerousToolFamiliesByTool = map[string]string{
-	"local":        "local",
-	"ssh":          "ssh",
-	"browser":      "browser",
-	"browser_task": "browser",
-	"cron":         "cron",
-	"message":      "message",
+	"local":          "local",
+	"run_code":       "local",
+	"ssh":            "ssh",
+	"browser":        "browser",
+	"browser_task":   "browser",
+	"browser_script": "browser",
+	"cron":           "cron",
+	"message":        "message",
 }
 
 // DangerousToolFamilies returns the operator-controlled tool families covered by estop.
@@ -161,7 +164,7 @@ func (f *FileTool) Name() string {
 }
 
 func (f *FileTool) Description() string {
-	return "Read and write files"
+	return "Read (optionally by line range), write, list/glob, replace (exact or regex, unique match required unless all=true), create, delete, and move files"
 }
 
 func (f *FileTool) Read(path string) (string, error) {
@@ -182,6 +185,9 @@ func (f *FileTool) Write(path string, content string) error {
 	if err != nil {
 		return err
 	}
+	if err := checkCustomToolsWrite(f.Name(), f.BasePath, fullPath); err != nil {
+		return err
+	}
 
 	// Ensure directory exists
 	dir := filepath.Dir(fullPath)
@@ -248,21 +254,67 @@ func resolvePath(workspaceRoot, path string) (string, error) {
 }
 
 func (f *FileTool) Execute(ctx context.Context, args ...string) (string, error) {
-	if len(args) < 2 {
-		return "", fmt.Errorf("usage: file <read|write> <path> [content]")
+	if len(args) < 1 {
+		return "", fmt.Errorf("usage: file <read|write|list|replace|create|delete|move> <path> [args]")
 	}
 
 	operation := args[0]
+	if operation == "list" {
+		path, pattern := ".", ""
+		if len(args) > 1 {
+			path = args[1]
+		}
+		if len(args) > 2 {
+			pattern = args[2]
+		}
+		return f.List(path, pattern)
+	}
+	if len(args) < 2 {
+		return "", fmt.Errorf("path required for %s", operation)
+	}
 	path := args[1]
 
+	destination := ""
+	if operation == "move" && len(args) > 2 {
+		destination = args[2]
+	}
+	if err := f.checkpoint(ctx, operation, path, destination); err != nil {
+		return "", err
+	}
+
 	switch operation {
 	case "read":
+		if len(args) > 2 {
+			end := ""
+			if len(args) > 3 {
+				end = args[3]
+			}
+			start, endLine, err := parseLineRange(args[2], end)
+			if err != nil {
+				return "", err
+			}
+			return f.ReadLines(path, start, endLine)
+		}
 		return f.Read(path)
 	case "write":
 		if len(args) < 3 {
 			return "", fmt.Errorf("content required for write")
 		}
 		return "", f.Write(path, strings.Join(args[2:], " "))
+	case "replace":
+		if len(args) != 4 {
+			return "", fmt.Errorf("usage: file replace <path> <old_string> <new_string>")
+		}
+		return f.Replace(path, args[2], args[3], false, false)
+	case "create":
+		return f.Create(path, strings.Join(args[2:], " "))
+	case "delete":
+		return f.Delete(path)
+	case "move":
+		if len(args) < 3 {
+			return "", fmt.Errorf("destination required for move")
+		}
+		return f.Move(path, args[2])
 	default:
 		return "", fmt.Errorf("unknown operation: %s", operation)
 	}
@@ -287,11 +339,22 @@ func NewRegistryWithEmergencyStop(provider EmergencyStopProvider) *Registry {
 	}
 }
 
+// familyDeclarer is implemented by tools that pick their own estop family
+// (workspace-defined tools, whose names are not known in advance).
+type familyDeclarer interface {
+	ToolFamily() string
+}
+
 // Register adds a tool to the registry
 func (r *Registry) Register(tool Tool) {
 	if r.stopStateProvider != nil {
 		if _, ok := tool.(estopGuarded); !ok {
-			if family, dangerous := DangerousToolFamily(tool.Name()); dangerous {
+			family, dangerous := DangerousToolFamily(tool.Name())
+			if declarer, ok := tool.(familyDeclarer); ok && !dangerous {
+				family = declarer.ToolFamily()
+				dangerous = family != ""
+			}
+			if dangerous {
 				tool = wrapToolWithEmergencyStop(tool, family, r.stopStateProvider)
 			}
 		}
@@ -337,6 +400,58 @@ func AsLocalCommand(tool Tool) (*LocalCommand, bool) {
 	return localCmd, ok
 }
 
+// AsGitTool unwraps registry decorators until a GitTool is found.
+func AsGitTool(tool Tool) (*GitTool, bool) {
+	unwrapped := tool
+	for {
+		wrapped, ok := unwrapped.(interface{ Unwrap() Tool })
+		if !ok {
+			break
+		}
+		unwrapped = wrapped.Unwrap()
+	}
+
+	gitTool, ok := unwrapped.(*GitTool)
+	return gitTool, ok
+}
+
+// AsBrowserTool unwraps registry decorators until a BrowserTool is found.
+func AsBrowserTool(tool Tool) (*BrowserTool, bool) {
+	unwrapped := tool
+	for {
+		wrapped, ok := unwrapped.(interface{ Unwrap() Tool })
+		if !ok {
+			break
+		}
+		unwrapped = wrapped.Unwrap()
+	}
+
+	browserTool, ok := unwrapped.(*BrowserTool)
+	return browserTool, ok
+}
+
+// approvalBinder is implemented by tools whose side-effecting actions must be
+// confirmed by the user of the chat that triggered the run.
+type approvalBinder interface {
+	WithApproval(fn func(command string) (bool, error)) Tool
+}
+
+// BindApproval unwraps registry decorators and returns a copy of the tool
+// wired to fn. ok is false when the tool does not request approvals.
+func BindApproval(tool Tool, fn func(command string) (bool, error)) (Tool, bool) {
+	unwrapped := tool
+	for {
+		if binder, ok := unwrapped.(approvalBinder); ok {
+			return binder.WithApproval(fn), true
+		}
+		wrapped, ok := unwrapped.(interface{ Unwrap() Tool })
+		if !ok {
+			return tool, false
+		}
+		unwrapped = wrapped.Unwrap()
+	}
+}
+
 type estopGuarded interface {
 	isEstopGuarded()
 }
@@ -456,23 +571,37 @@ func wrapToolWithEmergencyStop(tool Tool, family string, provider EmergencyStopP
 
 // ToolsConfig holds configuration for optional tools
 type ToolsConfig struct {
-	OpenAIAPIKey    string
-	OpenAIBaseURL   string
-	BraveAPIKey     string
-	ExaAPIKey       string
-	SearchEngine    string // "brave" or "exa"
-	TTSProvider     string // "openai" or "edge"
-	TTSVoice        string // Default TTS voice
-	ChromePath      string // explicit path to Chrome/Chromium binary
-	BrowserProfile  string // user data directory for browser profiles
-	BrowserDebugURL string // connect to existing browser CDP endpoint
-	CronScheduler   CronScheduler
-	MessageSender   MessageSender
-	Contacts        map[string]int64 // alias -> chatID for message tool allowlist
-	CurrentChatID   int64
-	MemoryManager   *memory.MemoryManager
-	PatternStore    recommend.PatternStore
-	EmergencyStop   EmergencyStopProvider
+	OpenAIAPIKey        string
+	OpenAIBaseURL       string
+	SearchProviders     []SearchProviderConfig           // search backends, tried in order
+	SearchCache         SearchCache                      // result cache for search (nil = none)
+	SearchCacheTTL      time.Duration                    // search cache lifetime (0 = 1h, <0 = off)
+	WebFetchCache       WebFetchCache                    // conditional-GET cache for web_fetch (nil = none)
+	ImageProviders      []ImageProviderConfig            // extra image_gen backends
+	ImageProvider       string                           // default image_gen backend name
+	ImageOutputDir      string                           // where generated images are stored (empty = ~/.ok-gobot/images)
+	TTSProvider         string                           // "openai", "edge" or "piper"
+	TTSVoice            string                           // Default TTS voice
+	TTSVoices           map[string]string                // voice per detected language
+	TTSChunkChars       int                              // max characters per voice note (0 = 1000)
+	TTSPiper            PiperConfig                      // offline piper binary and voice models
+	ChromePath          string                           // explicit path to Chrome/Chromium binary
+	BrowserProfile      string                           // user data directory for browser profiles
+	BrowserDebugURL     string                           // connect to existing browser CDP endpoint
+	BrowserContexts     int                              // cap on concurrent isolated subagent contexts (0 = 4)
+	BrowserShared       bool                             // subagents share the persistent profile instead of isolated contexts
+	HTTPProfiles        map[string]HTTPCredentialProfile // credential profiles for http_request
+	HTTPMaxBytes        int64                            // http_request response size limit (0 = 1MB)
+	RunCode             RunCodeConfig                    // run_code scratch directory, limits, and interpreters
+	Obsidian            ObsidianConfig                   // vault path and daily-note settings
+	ObsidianIndexMemory bool                             // index the vault into MemoryManager
+	CronScheduler       CronScheduler
+	MessageSender       MessageSender
+	Contacts            map[string]int64 // alias -> chatID for message tool allowlist
+	CurrentChatID       int64
+	MemoryManager       *memory.MemoryManager
+	PatternStore        recommend.PatternStore
+	EmergencyStop       EmergencyStopProvider
 }
 
 // LoadFromConfig loads tools from TOOLS.md
@@ -491,6 +620,13 @@ func LoadFromConfigWithOptions(basePath string, cfg *ToolsConfig) (*Registry, er
 	// Always register local command
 	registry.Register(&LocalCommand{})
 
+	// Register code runner with per-session scratch directories.
+	var runCodeCfg RunCodeConfig
+	if cfg != nil {
+		runCodeCfg = cfg.RunCode
+	}
+	registry.Register(NewRunCodeTool(runCodeCfg))
+
 	// Try to load TOOLS.md
 	toolsPath := filepath.Join(basePath, "TOOLS.md")
 	content, err := os.ReadFile(toolsPath)
@@ -532,17 +668,60 @@ func LoadFromConfigWithOptions(basePath string, cfg *ToolsConfig) (*Registry, er
 		registry.Register(&FileTool{BasePath: basePath})
 		registry.Register(NewPatchTool(basePath))
 		registry.Register(NewSearchFileTool(basePath))
+		registry.Register(NewGitTool(basePath))
 	}
 
-	// Register Obsidian tool (vault in standard location)
+	// Register Obsidian tool when the vault (configured, or ~/Obsidian) exists.
+	// With IndexMemory the vault is indexed into semantic memory in the background.
 	homeDir, _ := os.UserHomeDir()
-	obsidianVault := filepath.Join(homeDir, "Obsidian")
-	if _, err := os.Stat(obsidianVault); err == nil {
-		registry.Register(NewObsidianTool(obsidianVault))
+	var obsidianCfg ObsidianConfig
+	if cfg != nil {
+		obsidianCfg = cfg.Obsidian
 	}
+	if obsidianCfg.VaultPath == "" {
+		obsidianCfg.VaultPath = filepath.Join(homeDir, "Obsidian")
+	}
+	if info, err := os.Stat(obsidianCfg.VaultPath); err == nil && info.IsDir() {
+		if cfg != nil && cfg.ObsidianIndexMemory && cfg.MemoryManager != nil {
+			obsidianCfg.Indexer = cfg.MemoryManager
+			obsidianCfg.Searcher = cfg.MemoryManager
+		}
+		obsidian := NewObsidianTool(obsidianCfg)
+		registry.Register(obsidian)
+		if obsidianCfg.Indexer != nil {
+			go func() {
+				n, err := obsidian.IndexVault(context.Background())
+				if err != nil {
+					log.Printf("[tools] obsidian vault indexing stopped after %d notes: %v", n, err)
+					return
+				}
+				log.Printf("[tools] obsidian vault indexed into memory (%d notes)", n)
+			}()
+		}
+	} else if cfg != nil && cfg.Obsidian.VaultPath != "" {
+		log.Printf("[tools] obsidian vault %s not found, tool disabled", cfg.Obsidian.VaultPath)
+	}
+
+	// Register web fetch tool; pages saved with save=true go to the soul's
+	// memory/web/ directory and are indexed when semantic memory is enabled.
+	webFetchCfg := WebFetchConfig{MemoryRoot: basePath}
+	if cfg != nil {
+		webFetchCfg.Cache = cfg.WebFetchCache
+		if cfg.MemoryManager != nil {
+			webFetchCfg.Indexer = cfg.MemoryManager
+		}
+	}
+	registry.Register(NewWebFetchTool(webFetchCfg))
 
-	// Register web fetch tool (no config needed)
-	registry.Register(NewWebFetchTool())
+	// Register HTTP API tool; mutating methods stay denied until a per-chat
+	// approval function is bound by the run resolver.
+	var httpProfiles map[string]HTTPCredentialProfile
+	var httpMaxBytes int64
+	if cfg != nil {
+		httpProfiles = cfg.HTTPProfiles
+		httpMaxBytes = cfg.HTTPMaxBytes
+	}
+	registry.Register(NewHTTPRequestTool(httpProfiles, httpMaxBytes))
 
 	// Register browser tool (Chrome automation via CDP)
 	browserProfile := filepath.Join(homeDir, ".ok-gobot", "chrome-profile")
@@ -554,34 +733,74 @@ func LoadFromConfigWithOptions(basePath string, cfg *ToolsConfig) (*Registry, er
 		chromePath = cfg.ChromePath
 		browserDebugURL = cfg.BrowserDebugURL
 	}
-	registry.Register(NewBrowserTool(browserProfile, chromePath, browserDebugURL))
+	browserTool := NewBrowserTool(browserProfile, chromePath, browserDebugURL)
+	if cfg != nil {
+		browserTool.manager.MaxContexts = cfg.BrowserContexts
+		browserTool.sharedForSubagents = cfg.BrowserShared
+	}
+	browserTool.scratchDir = runCodeCfg.ScratchDir
+	browserTool.workspaceRoot = basePath
+	registry.Register(browserTool)
+	registry.Register(NewBrowserScriptTool(browserTool))
 
 	// Register optional tools based on config
 	if cfg != nil {
-		// Search tool
-		if cfg.BraveAPIKey != "" || cfg.ExaAPIKey != "" {
-			apiKey := cfg.BraveAPIKey
-			engine := "brave"
-			if cfg.SearchEngine == "exa" && cfg.ExaAPIKey != "" {
-				apiKey = cfg.ExaAPIKey
-				engine = "exa"
+		// Search tool over the configured provider chain. Providers with a
+		// missing URL or key are skipped instead of disabling search.
+		var providers []SearchProvider
+		for _, pc := range cfg.SearchProviders {
+			provider, err := NewSearchProvider(pc)
+			if err != nil {
+				log.Printf("[tools] search provider skipped: %v", err)
+				continue
 			}
-			registry.Register(NewSearchTool(apiKey, engine))
+			providers = append(providers, provider)
 		}
-
-		// Image generation tool
-		if cfg.OpenAIAPIKey != "" {
-			registry.Register(NewImageTool(cfg.OpenAIAPIKey, cfg.OpenAIBaseURL))
+		if len(providers) > 0 {
+			registry.Register(NewSearchTool(providers, cfg.SearchCache, cfg.SearchCacheTTL))
 		}
 
-		// TTS tool
+		// Image generation tool over OpenAI (when keyed) plus configured backends.
+		var imageProviders []ImageProvider
 		if cfg.OpenAIAPIKey != "" {
-			ttsProvider := cfg.TTSProvider
-			if ttsProvider == "" {
-				ttsProvider = "openai"
+			imageProviders = append(imageProviders, ImageProvider{Name: "openai", Generator: NewOpenAIImageGenerator(cfg.OpenAIAPIKey, cfg.OpenAIBaseURL)})
+		}
+		for _, pc := range cfg.ImageProviders {
+			generator, err := NewImageGenerator(pc)
+			if err != nil {
+				log.Printf("[tools] image provider skipped: %v", err)
+				continue
+			}
+			name := pc.Name
+			if name == "" {
+				name = strings.ToLower(strings.TrimSpace(pc.Type))
+			}
+			if name == "openai" && len(imageProviders) > 0 && imageProviders[0].Name == "openai" {
+				imageProviders[0].Generator = generator // explicit config wins
+				continue
+			}
+			imageProviders = append(imageProviders, ImageProvider{Name: name, Generator: generator})
+		}
+		if len(imageProviders) > 0 {
+			defaultImage := cfg.ImageProvider
+			if defaultImage == "" && len(cfg.ImageProviders) > 0 {
+				// Prefer the first configured backend over the implicit OpenAI one.
+				defaultImage = imageProviders[len(imageProviders)-len(cfg.ImageProviders)].Name
 			}
-			ttsVoice := cfg.TTSVoice
-			registry.Register(NewTTSTool(cfg.OpenAIAPIKey, cfg.OpenAIBaseURL, ttsProvider, ttsVoice))
+			registry.Register(NewImageToolWithProviders(imageProviders, defaultImage, cfg.ImageOutputDir, cfg.RunCode.ScratchDir))
+		}
+
+		// TTS tool (edge and piper need no API key)
+		ttsProvider := cfg.TTSProvider
+		if ttsProvider == "" {
+			ttsProvider = "openai"
+		}
+		if cfg.OpenAIAPIKey != "" || ttsProvider != "openai" {
+			ttsTool := NewTTSTool(cfg.OpenAIAPIKey, cfg.OpenAIBaseURL, ttsProvider, cfg.TTSVoice)
+			ttsTool.providers["piper"] = NewPiperTTSProvider(cfg.TTSPiper)
+			ttsTool.languageVoices = cfg.TTSVoices
+			ttsTool.chunkChars = cfg.TTSChunkChars
+			registry.Register(ttsTool)
 		}
 
 		// Cron tool
@@ -688,19 +907,51 @@ func (f *FileTool) GetSchema() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"command": map[string]interface{}{
 				"type":        "string",
-				"description": "Operation: read or write",
-				"enum":        []string{"read", "write"},
+				"description": "Operation to perform",
+				"enum":        []string{"read", "write", "list", "replace", "create", "delete", "move"},
 			},
 			"path": map[string]interface{}{
 				"type":        "string",
-				"description": "File path (relative to base directory)",
+				"description": "File or directory path (relative to base directory)",
 			},
 			"content": map[string]interface{}{
 				"type":        "string",
-				"description": "Content to write (for write command)",
+				"description": "Content to write (for write and create)",
+			},
+			"start_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "read: first line to return (1-based)",
+			},
+			"end_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "read: last line to return (inclusive)",
+			},
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "list: glob filter, e.g. *.go or **/*_test.go (recursive)",
+			},
+			"old_string": map[string]interface{}{
+				"type":        "string",
+				"description": "replace: text (or regex) to find; must match exactly once unless all=true",
+			},
+			"new_string": map[string]interface{}{
+				"type":        "string",
+				"description": "replace: replacement text ($1 etc. refer to regex groups)",
+			},
+			"regex": map[string]interface{}{
+				"type":        "boolean",
+				"description": "replace: treat old_string as a Go regular expression",
+			},
+			"all": map[string]interface{}{
+				"type":        "boolean",
+				"description": "replace: replace every match instead of requiring a unique one",
+			},
+			"destination": map[string]interface{}{
+				"type":        "string",
+				"description": "move: new path",
 			},
 		},
-		"required": []string{"command", "path"},
+		"required": []string{"command"},
 	}
 }
 
@@ -27,6 +27,8 @@ type TTSTool struct {
 	providers       map[string]TTSProvider
 	defaultProvider string
 	defaultVoice    string
+	languageVoices  map[string]string // language code -> voice, for auto-detected text
+	chunkChars      int               // max characters per voice note (0 = DefaultTTSChunkChars)
 }
 
 // NewTTSTool creates a new TTS tool with configured providers
@@ -47,6 +49,9 @@ func NewTTSTool(apiKey, baseURL, defaultProvider, defaultVoice string) *TTSTool
 	// Initialize Edge TTS provider
 	providers["edge"] = NewEdgeTTSProvider()
 
+	// Initialize offline Piper provider (models in the default directory)
+	providers["piper"] = NewPiperTTSProvider(PiperConfig{})
+
 	return &TTSTool{
 		providers:       providers,
 		defaultProvider: defaultProvider,
@@ -59,12 +64,12 @@ func (t *TTSTool) Name() string {
 }
 
 func (t *TTSTool) Description() string {
-	return "Convert text to speech using multiple providers (OpenAI, Edge TTS)"
+	return "Convert text to speech using multiple providers (OpenAI, Edge TTS, offline Piper). Long text becomes several voice notes; the voice follows the text's language unless --voice is given"
 }
 
 func (t *TTSTool) Execute(ctx context.Context, args ...string) (string, error) {
 	if len(args) == 0 {
-		return "", fmt.Errorf("usage: tts [provider:]<text> [--voice <voice>] [--speed 0.25-4.0]\nProviders: openai, edge\nUse 'edge:text' or 'openai:text' to specify provider")
+		return "", fmt.Errorf("usage: tts [provider:]<text> [--voice <voice>] [--speed 0.25-4.0]\nProviders: openai, edge, piper\nUse 'edge:text', 'piper:text' or 'openai:text' to specify provider")
 	}
 
 	// Parse provider prefix from first arg
@@ -82,8 +87,8 @@ func (t *TTSTool) Execute(ctx context.Context, args ...string) (string, error) {
 		}
 	}
 
-	// Parse arguments
-	voice := t.defaultVoice
+	// Parse arguments; without --voice the voice follows the text's language.
+	voice := ""
 	speed := 1.0
 	var textParts []string
 
@@ -109,33 +114,104 @@ func (t *TTSTool) Execute(ctx context.Context, args ...string) (string, error) {
 		return "", fmt.Errorf("text is required")
 	}
 
-	// Get the provider
+	paths, voice, err := t.speak(ctx, provider, text, voice, speed)
+	if err != nil {
+		return "", err
+	}
+
+	for i, path := range paths {
+		caption := ""
+		if len(paths) > 1 {
+			caption = fmt.Sprintf("%d/%d", i+1, len(paths))
+		}
+		AddAttachment(ctx, Attachment{Path: path, MimeType: audioMimeType(path), Caption: caption})
+	}
+
+	return fmt.Sprintf("🔊 Speech generated!\n\nProvider: %s\nText: %s\nVoice: %s\nFiles: %s", provider, text, voice, strings.Join(paths, ", ")), nil
+}
+
+// DefaultProvider returns the provider used by Speak.
+func (t *TTSTool) DefaultProvider() string {
+	return t.defaultProvider
+}
+
+// Speak synthesizes text with the default provider and returns one audio
+// file per chunk. An empty voice picks one for the text's language.
+func (t *TTSTool) Speak(ctx context.Context, text, voice string) ([]string, error) {
+	paths, _, err := t.speak(ctx, t.defaultProvider, text, voice, 1.0)
+	return paths, err
+}
+
+func (t *TTSTool) speak(ctx context.Context, provider, text, voice string, speed float64) ([]string, string, error) {
 	p, ok := t.providers[provider]
 	if !ok {
-		return "", fmt.Errorf("unknown provider: %s (available: openai, edge)", provider)
+		return nil, "", fmt.Errorf("unknown provider: %s (available: openai, edge, piper)", provider)
 	}
 
 	if !p.IsAvailable() {
-		return "", fmt.Errorf("provider %s is not available", provider)
+		return nil, "", fmt.Errorf("provider %s is not available", provider)
 	}
 
-	// For OpenAI provider, handle speed parameter separately
-	var audioPath string
-	var err error
+	chunks := SplitSpeechText(text, t.chunkChars)
+	if len(chunks) == 0 {
+		return nil, "", fmt.Errorf("text is required")
+	}
+	if voice == "" {
+		voice = t.voiceFor(p, DetectLanguage(text))
+	}
 
-	if provider == "openai" {
-		openaiProvider := p.(*OpenAITTSProvider)
-		audioPath, err = openaiProvider.SynthesizeWithSpeed(ctx, text, voice, speed)
-	} else {
-		// Other providers don't support speed
-		audioPath, err = p.Synthesize(ctx, text, voice)
+	paths := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		var audioPath string
+		var err error
+		// For OpenAI provider, handle speed parameter separately
+		if openaiProvider, ok := p.(*OpenAITTSProvider); ok {
+			audioPath, err = openaiProvider.SynthesizeWithSpeed(ctx, chunk, voice, speed)
+		} else {
+			// Other providers don't support speed
+			audioPath, err = p.Synthesize(ctx, chunk, voice)
+		}
+		if err != nil {
+			for _, done := range paths {
+				os.Remove(done)
+			}
+			return nil, voice, err
+		}
+		paths = append(paths, audioPath)
 	}
+	return paths, voice, nil
+}
 
-	if err != nil {
-		return "", err
+// voiceFor picks a voice for lang: a configured language voice, the default
+// voice if it speaks lang, or the provider's first voice for lang. Providers
+// with language-neutral voice names (OpenAI) keep the default voice.
+func (t *TTSTool) voiceFor(p TTSProvider, lang string) string {
+	if lang == "" {
+		return t.defaultVoice
+	}
+	if v, ok := t.languageVoices[lang]; ok && v != "" {
+		return v
 	}
+	if voiceLanguage(t.defaultVoice) == lang {
+		return t.defaultVoice
+	}
+	for _, v := range p.AvailableVoices() {
+		if voiceLanguage(v) == lang {
+			return v
+		}
+	}
+	return t.defaultVoice
+}
 
-	return fmt.Sprintf("🔊 Speech generated!\n\nProvider: %s\nText: %s\nVoice: %s\nFile: %s", provider, text, voice, audioPath), nil
+func audioMimeType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ogg", ".oga", ".opus":
+		return "audio/ogg"
+	case ".wav":
+		return "audio/wav"
+	default:
+		return "audio/mpeg"
+	}
 }
 
 // OpenAITTSProvider implements TTS using OpenAI API
@@ -268,16 +344,17 @@ func (o *OpenAITTSProvider) Name() string {
 	return "openai"
 }
 
-// convertToOGG converts MP3 to OGG using ffmpeg (shared utility function)
-func convertToOGG(mp3Path string) string {
+// convertToOGG converts an MP3 or WAV file to OGG/Opus using ffmpeg (shared
+// utility function). It returns "" when conversion is not possible.
+func convertToOGG(audioPath string) string {
 	// Check if ffmpeg is available
 	if _, err := exec.LookPath("ffmpeg"); err != nil {
-		return "" // Return empty to use MP3
+		return "" // Return empty to use the original file
 	}
 
-	oggPath := strings.TrimSuffix(mp3Path, ".mp3") + ".ogg"
+	oggPath := strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".ogg"
 
-	cmd := exec.Command("ffmpeg", "-y", "-i", mp3Path,
+	cmd := exec.Command("ffmpeg", "-y", "-i", audioPath,
 		"-c:a", "libopus",
 		"-b:a", "64k",
 		"-vbr", "on",
@@ -285,11 +362,11 @@ func convertToOGG(mp3Path string) string {
 		oggPath)
 
 	if err := cmd.Run(); err != nil {
-		return "" // Return empty to use MP3
+		return "" // Return empty to use the original file
 	}
 
-	// Clean up MP3
-	os.Remove(mp3Path)
+	// Clean up the source file
+	os.Remove(audioPath)
 
 	return oggPath
 }
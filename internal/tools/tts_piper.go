@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PiperConfig configures the offline Piper TTS provider.
+type PiperConfig struct {
+	Binary    string // piper executable (default "piper" from PATH)
+	ModelsDir string // directory holding <voice>.onnx models with their .onnx.json configs
+}
+
+// DefaultPiperModelsDir returns ~/.ok-gobot/piper-voices.
+func DefaultPiperModelsDir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".ok-gobot", "piper-voices")
+}
+
+// PiperTTSProvider implements TTS with a local Piper binary and voice model
+// files, so speech works without network access.
+type PiperTTSProvider struct {
+	binary    string
+	modelsDir string
+	tempDir   string
+}
+
+// NewPiperTTSProvider creates a new Piper TTS provider
+func NewPiperTTSProvider(cfg PiperConfig) *PiperTTSProvider {
+	if cfg.Binary == "" {
+		cfg.Binary = "piper"
+	}
+	if cfg.ModelsDir == "" {
+		cfg.ModelsDir = DefaultPiperModelsDir()
+	}
+	tempDir := filepath.Join(os.TempDir(), "okgobot-tts")
+	os.MkdirAll(tempDir, 0755)
+
+	return &PiperTTSProvider{
+		binary:    cfg.Binary,
+		modelsDir: cfg.ModelsDir,
+		tempDir:   tempDir,
+	}
+}
+
+// Synthesize generates speech from text with the given voice model. An empty
+// voice uses the first installed model.
+func (p *PiperTTSProvider) Synthesize(ctx context.Context, text, voice string) (string, error) {
+	binary, err := exec.LookPath(p.binary)
+	if err != nil {
+		return "", fmt.Errorf("piper binary %q not found. Install it from https://github.com/rhasspy/piper", p.binary)
+	}
+
+	voices := p.AvailableVoices()
+	if len(voices) == 0 {
+		return "", fmt.Errorf("no piper voice models (*.onnx) in %s", p.modelsDir)
+	}
+	if voice == "" {
+		voice = voices[0]
+	}
+	voice = strings.TrimSuffix(voice, ".onnx")
+	if filepath.Base(voice) != voice {
+		return "", fmt.Errorf("invalid voice: %s", voice)
+	}
+	model := filepath.Join(p.modelsDir, voice+".onnx")
+	if _, err := os.Stat(model); err != nil {
+		return "", fmt.Errorf("invalid voice: %s (valid: %v)", voice, voices)
+	}
+
+	wavPath := filepath.Join(p.tempDir, fmt.Sprintf("tts_piper_%d.wav", time.Now().UnixNano()))
+	cmd := exec.CommandContext(ctx, binary, "--model", model, "--output_file", wavPath)
+	cmd.Stdin = strings.NewReader(text)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("piper failed: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	if _, err := os.Stat(wavPath); err != nil {
+		return "", fmt.Errorf("piper did not create output file: %w", err)
+	}
+
+	// Convert to OGG for Telegram voice messages (if ffmpeg available)
+	if oggPath := convertToOGG(wavPath); oggPath != "" {
+		return oggPath, nil
+	}
+	return wavPath, nil
+}
+
+// IsAvailable reports whether the piper binary and at least one voice model exist.
+func (p *PiperTTSProvider) IsAvailable() bool {
+	if _, err := exec.LookPath(p.binary); err != nil {
+		return false
+	}
+	return len(p.AvailableVoices()) > 0
+}
+
+// AvailableVoices lists the installed voice models, e.g. "en_US-lessac-medium".
+func (p *PiperTTSProvider) AvailableVoices() []string {
+	matches, _ := filepath.Glob(filepath.Join(p.modelsDir, "*.onnx"))
+	voices := make([]string, 0, len(matches))
+	for _, m := range matches {
+		voices = append(voices, strings.TrimSuffix(filepath.Base(m), ".onnx"))
+	}
+	sort.Strings(voices)
+	return voices
+}
+
+// Name returns the provider name
+func (p *PiperTTSProvider) Name() string {
+	return "piper"
+}
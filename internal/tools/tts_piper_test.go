@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// newFakePiper installs a shell script that writes the model path and the
+// text it read from stdin into --output_file, plus two voice models.
+func newFakePiper(t *testing.T) *PiperTTSProvider {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake piper needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "piper")
+	body := `#!/bin/sh
+out=""; model=""
+while [ $# -gt 0 ]; do
+  case "$1" in
+    --output_file) out="$2"; shift ;;
+    --model) model="$2"; shift ;;
+  esac
+  shift
+done
+{ echo "$model"; cat; } > "$out"
+`
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	models := filepath.Join(dir, "voices")
+	if err := os.MkdirAll(models, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"ru_RU-irina-medium", "en_US-lessac-medium"} {
+		if err := os.WriteFile(filepath.Join(models, v+".onnx"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewPiperTTSProvider(PiperConfig{Binary: script, ModelsDir: models})
+}
+
+func TestPiperTTSProvider(t *testing.T) {
+	p := newFakePiper(t)
+	if !p.IsAvailable() {
+		t.Fatal("expected fake piper to be available")
+	}
+	if got := strings.Join(p.AvailableVoices(), ","); got != "en_US-lessac-medium,ru_RU-irina-medium" {
+		t.Errorf("AvailableVoices = %s", got)
+	}
+
+	path, err := p.Synthesize(context.Background(), "hello", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+	data, _ := os.ReadFile(path)
+	if ext := filepath.Ext(path); ext != ".wav" && ext != ".ogg" {
+		t.Errorf("unexpected output %s", path)
+	}
+	if filepath.Ext(path) == ".wav" && !strings.Contains(string(data), "en_US-lessac-medium.onnx\nhello") {
+		t.Errorf("unexpected piper input: %q", data)
+	}
+
+	if _, err := p.Synthesize(context.Background(), "x", "../etc/passwd"); err == nil {
+		t.Error("expected path-like voice to be rejected")
+	}
+	if _, err := p.Synthesize(context.Background(), "x", "de_DE-missing"); err == nil {
+		t.Error("expected unknown voice to fail")
+	}
+}
+
+func TestTTSToolSpeakChunksAndPicksVoice(t *testing.T) {
+	piper := newFakePiper(t)
+	tool := NewTTSTool("", "", "piper", "en_US-lessac-medium")
+	tool.providers["piper"] = piper
+	tool.chunkChars = 40
+
+	text := "Привет! Это длинный ответ, который не помещается в одно сообщение. Вот ещё одно предложение."
+	paths, err := tool.Speak(context.Background(), text, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) < 2 {
+		t.Fatalf("expected several voice notes, got %d", len(paths))
+	}
+	for _, path := range paths {
+		data, _ := os.ReadFile(path)
+		if filepath.Ext(path) == ".wav" && !strings.HasPrefix(string(data), filepath.Join(piper.modelsDir, "ru_RU-irina-medium.onnx")) {
+			t.Errorf("expected the Russian voice, got %q", data)
+		}
+		os.Remove(path)
+	}
+
+	tool.languageVoices = map[string]string{"ru": "custom-ru"}
+	if got := tool.voiceFor(piper, "ru"); got != "custom-ru" {
+		t.Errorf("configured language voice ignored: %s", got)
+	}
+	if got := tool.voiceFor(piper, "en"); got != "en_US-lessac-medium" {
+		t.Errorf("default voice should cover its language: %s", got)
+	}
+	if got := tool.voiceFor(tool.providers["openai"], "de"); got != "en_US-lessac-medium" {
+		t.Errorf("expected fallback to default voice, got %s", got)
+	}
+}
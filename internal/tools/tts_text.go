@@ -0,0 +1,250 @@
+package tools
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultTTSChunkChars is the default size of one voice note, roughly a
+// minute of speech.
+const DefaultTTSChunkChars = 1000
+
+var (
+	codeBlockRe    = regexp.MustCompile("(?s)```.*?```")
+	mdLinkRe       = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	bareURLRe      = regexp.MustCompile(`https?://\S+`)
+	mdDecorationRe = regexp.MustCompile("(?m)^[ \\t]{0,3}(#{1,6}\\s+|>\\s?|[-*+]\\s+)|[*_`~]+")
+	spaceRe        = regexp.MustCompile(`[ \t]+`)
+	sentenceEndRe  = regexp.MustCompile(`([.!?…]+["»”)]?)\s+`)
+)
+
+// SpeechText turns a markdown reply into text that reads well aloud: code
+// blocks and URLs are dropped, links keep their label, and markup is removed.
+func SpeechText(md string) string {
+	text := codeBlockRe.ReplaceAllString(md, "")
+	text = mdLinkRe.ReplaceAllString(text, "$1")
+	text = bareURLRe.ReplaceAllString(text, "")
+	text = mdDecorationRe.ReplaceAllString(text, "")
+	text = spaceRe.ReplaceAllString(text, " ")
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	text = strings.Join(lines, "\n")
+	for strings.Contains(text, "\n\n\n") {
+		text = strings.ReplaceAll(text, "\n\n\n", "\n\n")
+	}
+	return strings.TrimSpace(text)
+}
+
+// SplitSpeechText splits text into chunks of at most maxChars runes, breaking
+// between paragraphs or sentences where possible and between words otherwise.
+func SplitSpeechText(text string, maxChars int) []string {
+	if maxChars <= 0 {
+		maxChars = DefaultTTSChunkChars
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	// Break the text into pieces no longer than maxChars.
+	var pieces []string
+	for _, para := range strings.Split(text, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if utf8.RuneCountInString(para) <= maxChars {
+			pieces = append(pieces, para)
+			continue
+		}
+		for _, sentence := range splitSentences(para) {
+			if utf8.RuneCountInString(sentence) <= maxChars {
+				pieces = append(pieces, sentence)
+				continue
+			}
+			pieces = append(pieces, splitWords(sentence, maxChars)...)
+		}
+	}
+
+	// Pack pieces greedily into chunks.
+	var chunks []string
+	var cur strings.Builder
+	curLen := 0
+	for _, p := range pieces {
+		n := utf8.RuneCountInString(p)
+		if curLen > 0 && curLen+1+n > maxChars {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+			curLen = 0
+		}
+		if curLen > 0 {
+			cur.WriteByte(' ')
+			curLen++
+		}
+		cur.WriteString(p)
+		curLen += n
+	}
+	if curLen > 0 {
+		chunks = append(chunks, cur.String())
+	}
+	return chunks
+}
+
+func splitSentences(text string) []string {
+	var out []string
+	last := 0
+	for _, loc := range sentenceEndRe.FindAllStringSubmatchIndex(text, -1) {
+		out = append(out, strings.TrimSpace(text[last:loc[3]]))
+		last = loc[1]
+	}
+	if rest := strings.TrimSpace(text[last:]); rest != "" {
+		out = append(out, rest)
+	}
+	return out
+}
+
+func splitWords(text string, maxChars int) []string {
+	var out []string
+	var cur []rune
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		for len(w) > maxChars {
+			if len(cur) > 0 {
+				out = append(out, string(cur))
+				cur = nil
+			}
+			out = append(out, string(w[:maxChars]))
+			w = w[maxChars:]
+		}
+		if len(cur) > 0 && len(cur)+1+len(w) > maxChars {
+			out = append(out, string(cur))
+			cur = nil
+		}
+		if len(cur) > 0 {
+			cur = append(cur, ' ')
+		}
+		cur = append(cur, w...)
+	}
+	if len(cur) > 0 {
+		out = append(out, string(cur))
+	}
+	return out
+}
+
+// languageStopwords are frequent short words used to tell Latin-script
+// languages apart.
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "that", "of", "to", "it", "with", "for", "this"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "sie", "mit", "auf", "ein", "eine"},
+	"fr": {"le", "la", "les", "et", "est", "vous", "une", "des", "pas", "pour", "avec", "que"},
+	"es": {"el", "los", "las", "y", "es", "que", "una", "por", "para", "con", "del", "muy"},
+	"it": {"il", "gli", "che", "è", "non", "una", "per", "con", "sono", "della", "questo", "anche"},
+	"pt": {"o", "os", "que", "não", "uma", "para", "com", "é", "são", "você", "do", "da"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "van", "dat", "je", "met", "voor", "zijn"},
+	"pl": {"i", "jest", "nie", "się", "to", "na", "że", "jak", "ale", "czy", "dla", "tak"},
+}
+
+// DetectLanguage guesses the ISO 639-1 code of text from its script and, for
+// Latin script, from common words. It returns "" when unsure.
+func DetectLanguage(text string) string {
+	counts := map[string]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case strings.ContainsRune("іїєґІЇЄҐ", r):
+			counts["uk"]++
+			counts["cyrillic"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["cyrillic"]++
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			counts["ja"]++
+		case unicode.Is(unicode.Han, r):
+			counts["zh"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Latin, r):
+			counts["latin"]++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	script, best := "", 0
+	for s, n := range counts {
+		if s == "uk" {
+			continue
+		}
+		if n > best || (n == best && s < script) {
+			script, best = s, n
+		}
+	}
+	switch script {
+	case "cyrillic":
+		if counts["uk"] > 0 {
+			return "uk"
+		}
+		return "ru"
+	case "zh":
+		if counts["ja"] > 0 {
+			return "ja"
+		}
+		return "zh"
+	case "latin":
+		return detectLatinLanguage(text)
+	}
+	return script
+}
+
+func detectLatinLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	scores := map[string]int{}
+	for lang, stop := range languageStopwords {
+		set := make(map[string]bool, len(stop))
+		for _, w := range stop {
+			set[w] = true
+		}
+		for _, w := range words {
+			if set[w] {
+				scores[lang]++
+			}
+		}
+	}
+	lang, best := "", 0
+	for l, n := range scores {
+		if n > best || (n == best && l < lang) {
+			lang, best = l, n
+		}
+	}
+	if best == 0 {
+		return "en"
+	}
+	return lang
+}
+
+// voiceLanguage returns the language code a voice name starts with, as in
+// "en_US-lessac-medium" or "ru-RU-DmitryNeural", or "" for names like "alloy".
+func voiceLanguage(voice string) string {
+	i := strings.IndexAny(voice, "_-")
+	if i < 2 || i > 3 {
+		return ""
+	}
+	return strings.ToLower(voice[:i])
+}
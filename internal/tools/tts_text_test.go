@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSpeechText(t *testing.T) {
+	md := "## Result\n\nSee **the docs** at [this page](https://example.com/docs) or https://example.com.\n\n```go\nfmt.Println(\"x\")\n```\n\n- first `item`\n- second _item_\n"
+	got := SpeechText(md)
+	want := "Result\n\nSee the docs at this page or\n\nfirst item\nsecond item"
+	if got != want {
+		t.Errorf("SpeechText:\n%q\nwant\n%q", got, want)
+	}
+	if strings.Contains(got, "http") || strings.Contains(got, "Println") {
+		t.Errorf("URLs and code should be dropped: %q", got)
+	}
+}
+
+func TestSplitSpeechText(t *testing.T) {
+	if got := SplitSpeechText("  ", 10); got != nil {
+		t.Errorf("expected no chunks for blank text, got %q", got)
+	}
+
+	text := "First sentence here. Second one follows! Third?\n\nNew paragraph."
+	chunks := SplitSpeechText(text, 30)
+	want := []string{"First sentence here.", "Second one follows! Third?", "New paragraph."}
+	if strings.Join(chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("SplitSpeechText = %q, want %q", chunks, want)
+	}
+
+	long := strings.Repeat("слово ", 50) + strings.Repeat("ы", 25)
+	for _, c := range SplitSpeechText(long, 20) {
+		if n := utf8.RuneCountInString(c); n > 20 || n == 0 {
+			t.Errorf("chunk of %d runes: %q", n, c)
+		}
+	}
+
+	if got := SplitSpeechText("Short. Text.", 0); len(got) != 1 {
+		t.Errorf("default limit should keep short text whole, got %q", got)
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	cases := map[string]string{
+		"The weather is nice and you should go for a walk.": "en",
+		"Привет, как дела? Что нового?":                     "ru",
+		"Привіт, як справи? Що нового?":                     "uk",
+		"Das ist nicht der Weg, und ich weiß es.":           "de",
+		"C'est une bonne idée pour vous et les enfants.":    "fr",
+		"今日はいい天気ですね":                                        "ja",
+		"今天天气很好":                                            "zh",
+		"12345 !!!":                                         "",
+	}
+	for text, want := range cases {
+		if got := DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestVoiceLanguage(t *testing.T) {
+	cases := map[string]string{
+		"en_US-lessac-medium": "en",
+		"ru-RU-DmitryNeural":  "ru",
+		"alloy":               "",
+		"":                    "",
+	}
+	for voice, want := range cases {
+		if got := voiceLanguage(voice); got != want {
+			t.Errorf("voiceLanguage(%q) = %q, want %q", voice, got, want)
+		}
+	}
+}
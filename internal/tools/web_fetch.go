@@ -4,40 +4,94 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"golang.org/x/net/html"
+
+	"ok-gobot/internal/storage"
+)
+
+const (
+	defaultWebFetchMaxBytes = 10 * 1024 * 1024
+	defaultWebFetchMaxChars = 12000
+	maxWebFetchChars        = 50000
+	// webFetchFreshFor is how long a cached document is served without
+	// revalidation, so paging through it does not hit the origin each time.
+	webFetchFreshFor = 10 * time.Minute
+	// DefaultWebCacheMaxAge is how long unused web_fetch cache entries are kept.
+	DefaultWebCacheMaxAge = 30 * 24 * time.Hour
 )
 
+// WebFetchCache stores extracted documents with their HTTP validators.
+// Implemented by storage.Store.
+type WebFetchCache interface {
+	GetWebCache(url string) (*storage.WebCacheEntry, error)
+	PutWebCache(e storage.WebCacheEntry) error
+	TouchWebCache(url string) error
+}
+
+// MemoryIndexer indexes a markdown file into semantic memory.
+// Implemented by memory.MemoryManager.
+type MemoryIndexer interface {
+	IndexFile(ctx context.Context, rootPath, absPath string) error
+}
+
+// WebFetchConfig holds optional web_fetch dependencies.
+type WebFetchConfig struct {
+	Cache      WebFetchCache // conditional-GET cache (nil = none)
+	MemoryRoot string        // soul directory; saved pages go to memory/web/ under it
+	Indexer    MemoryIndexer // indexes saved pages (nil = picked up on next reindex)
+	MaxBytes   int64         // response size limit (0 = 10MB)
+}
+
 // WebFetchTool fetches and extracts content from URLs
 type WebFetchTool struct {
-	client    *http.Client
-	userAgent string
+	client      *http.Client
+	userAgent   string
+	cache       WebFetchCache
+	memoryRoot  string
+	indexer     MemoryIndexer
+	maxBytes    int64
+	validateURL func(rawURL string) error
+	noSave      bool // memory writes denied by capability policy
 }
 
 // NewWebFetchTool creates a new web fetch tool
-func NewWebFetchTool() *WebFetchTool {
-	return &WebFetchTool{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 5 {
-					return fmt.Errorf("too many redirects")
-				}
-				// Revalidate each redirect target to prevent SSRF bypass via redirect.
-				if err := validateURL(req.URL.String()); err != nil {
-					return fmt.Errorf("redirect blocked (SSRF): %w", err)
-				}
-				return nil
-			},
+func NewWebFetchTool(cfg WebFetchConfig) *WebFetchTool {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultWebFetchMaxBytes
+	}
+	w := &WebFetchTool{
+		userAgent:   "Mozilla/5.0 (compatible; OKGoBot/1.0)",
+		cache:       cfg.Cache,
+		memoryRoot:  cfg.MemoryRoot,
+		indexer:     cfg.Indexer,
+		maxBytes:    cfg.MaxBytes,
+		validateURL: validateURL,
+	}
+	w.client = &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects")
+			}
+			// Revalidate each redirect target to prevent SSRF bypass via redirect.
+			if err := w.validateURL(req.URL.String()); err != nil {
+				return fmt.Errorf("redirect blocked (SSRF): %w", err)
+			}
+			return checkNetworkAllowlist(req.Context(), "web_fetch", req.URL.String())
 		},
-		userAgent: "Mozilla/5.0 (compatible; OKGoBot/1.0)",
 	}
+	return w
 }
 
 func (w *WebFetchTool) Name() string {
@@ -45,80 +99,279 @@ func (w *WebFetchTool) Name() string {
 }
 
 func (w *WebFetchTool) Description() string {
-	return "Fetch and extract content from a URL"
+	return "Fetch a URL and extract readable text (HTML articles, PDF, JSON, plain text, RSS/Atom). Long documents are paged with offset/max_chars"
+}
+
+// GetSchema returns the JSON Schema for web_fetch parameters.
+func (w *WebFetchTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "Absolute http(s) URL",
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Character offset into the extracted text (use the value from the previous page's \"next offset\")",
+			},
+			"max_chars": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Characters to return (default %d, max %d)", defaultWebFetchMaxChars, maxWebFetchChars),
+			},
+			"save": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Save the full document under memory/web/ and index it for memory_search",
+			},
+			"refresh": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Bypass the cache and fetch the URL again",
+			},
+		},
+		"required": []string{"url"},
+	}
 }
 
 func (w *WebFetchTool) Execute(ctx context.Context, args ...string) (string, error) {
 	if len(args) == 0 {
 		return "", fmt.Errorf("usage: web_fetch <url>")
 	}
+	return w.ExecuteJSON(ctx, map[string]string{"url": args[0]})
+}
 
-	urlStr := args[0]
+// ExecuteJSON fetches a URL and returns one page of its extracted text.
+func (w *WebFetchTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	urlStr := strings.TrimSpace(params["url"])
+	if urlStr == "" {
+		return "", fmt.Errorf("url is required")
+	}
+	offset, err := parseNonNegativeInt(params["offset"], 0)
+	if err != nil {
+		return "", fmt.Errorf("invalid offset: %w", err)
+	}
+	maxChars, err := parseNonNegativeInt(params["max_chars"], defaultWebFetchMaxChars)
+	if err != nil {
+		return "", fmt.Errorf("invalid max_chars: %w", err)
+	}
+	if maxChars == 0 {
+		maxChars = defaultWebFetchMaxChars
+	}
+	maxChars = min(maxChars, maxWebFetchChars)
 
 	// Validate URL and check for SSRF
-	if err := validateURL(urlStr); err != nil {
+	if err := w.validateURL(urlStr); err != nil {
+		return "", err
+	}
+	if err := checkNetworkAllowlist(ctx, w.Name(), urlStr); err != nil {
 		return "", err
 	}
 
-	// Fetch the page
-	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	doc, source, err := w.fetch(ctx, urlStr, params["refresh"] == "true")
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", err
+	}
+
+	var saved string
+	if params["save"] == "true" {
+		if w.noSave {
+			return "", &ToolDenial{
+				ToolName:    w.Name(),
+				Family:      "memory_write",
+				Reason:      `capability "memory_write" denied by agent policy`,
+				Remediation: "Fetch without save, or ask the operator to allow memory_write.",
+			}
+		}
+		saved, err = w.saveToMemory(ctx, urlStr, doc)
+		if err != nil {
+			return "", fmt.Errorf("fetched but could not save to memory: %w", err)
+		}
+	}
+
+	return formatWebPage(urlStr, doc, source, saved, offset, maxChars), nil
+}
+
+func parseNonNegativeInt(raw string, fallback int) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a non-negative integer", raw)
+	}
+	return n, nil
+}
+
+// fetch returns the extracted document for urlStr, from the cache when it is
+// fresh or the origin answers 304 Not Modified. source describes which.
+func (w *WebFetchTool) fetch(ctx context.Context, urlStr string, refresh bool) (webDocument, string, error) {
+	var cached *storage.WebCacheEntry
+	if w.cache != nil && !refresh {
+		entry, err := w.cache.GetWebCache(urlStr)
+		if err != nil {
+			log.Printf("[web_fetch] cache read failed: %v", err)
+		}
+		cached = entry
+	}
+	if cached != nil && time.Since(cached.FetchedAt) < webFetchFreshFor {
+		return cachedWebDocument(cached), "cached", nil
 	}
 
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	if err != nil {
+		return webDocument{}, "", fmt.Errorf("failed to create request: %w", err)
+	}
 	req.Header.Set("User-Agent", w.userAgent)
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,application/pdf,application/json,text/plain;q=0.8,*/*;q=0.5")
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
 
 	resp, err := w.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch URL: %w", err)
+		return webDocument{}, "", fmt.Errorf("failed to fetch URL: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		if err := w.cache.TouchWebCache(urlStr); err != nil {
+			log.Printf("[web_fetch] cache update failed: %v", err)
+		}
+		return cachedWebDocument(cached), "not modified", nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		return webDocument{}, "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
 	}
 
-	// Read body with limit
-	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024)) // 1MB limit
+	body, err := io.ReadAll(io.LimitReader(resp.Body, w.maxBytes+1))
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return webDocument{}, "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if int64(len(body)) > w.maxBytes {
+		return webDocument{}, "", fmt.Errorf("response exceeds %d MB limit", w.maxBytes/(1024*1024))
 	}
 
-	// Try readability extraction first
-	article, err := ExtractArticle(string(body), urlStr)
-	var content, title string
-	var metadata strings.Builder
-
-	if err == nil && article.Length > 100 {
-		// Readability succeeded with meaningful content
-		title = article.Title
-		content = article.Content
+	contentType := resp.Header.Get("Content-Type")
+	doc, err := w.extractDocument(ctx, contentType, urlStr, body)
+	if err != nil {
+		return webDocument{}, "", err
+	}
 
-		// Add metadata if available
-		if article.Byline != "" {
-			metadata.WriteString(fmt.Sprintf("Author: %s\n", article.Byline))
-		}
-		if article.SiteName != "" {
-			metadata.WriteString(fmt.Sprintf("Site: %s\n", article.SiteName))
+	if w.cache != nil {
+		err := w.cache.PutWebCache(storage.WebCacheEntry{
+			URL:          urlStr,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			ContentType:  contentType,
+			Kind:         doc.Kind,
+			Title:        doc.Title,
+			Meta:         doc.Meta,
+			Content:      doc.Content,
+		})
+		if err != nil {
+			log.Printf("[web_fetch] cache write failed: %v", err)
 		}
-		if article.Excerpt != "" {
-			metadata.WriteString(fmt.Sprintf("Excerpt: %s\n", article.Excerpt))
-		}
-	} else {
-		// Fall back to basic extraction
-		content, title = w.extractContent(string(body))
 	}
+	return doc, "", nil
+}
+
+func cachedWebDocument(e *storage.WebCacheEntry) webDocument {
+	return webDocument{Kind: e.Kind, Title: e.Title, Meta: e.Meta, Content: e.Content}
+}
+
+// formatWebPage renders the header and the requested window of the document.
+func formatWebPage(urlStr string, doc webDocument, source, saved string, offset, maxChars int) string {
+	content := []rune(doc.Content)
+	total := len(content)
+	if offset > total {
+		offset = total
+	}
+	end := min(offset+maxChars, total)
+
+	var sb strings.Builder
+	title := doc.Title
+	if title == "" {
+		title = urlStr
+	}
+	fmt.Fprintf(&sb, "**%s**\n\nURL: %s\nType: %s", title, urlStr, doc.Kind)
+	if source != "" {
+		fmt.Fprintf(&sb, " (%s)", source)
+	}
+	sb.WriteString("\n")
+	sb.WriteString(doc.Meta)
+	if saved != "" {
+		fmt.Fprintf(&sb, "Saved to memory: %s\n", saved)
+	}
+	if offset > 0 || end < total {
+		fmt.Fprintf(&sb, "Showing characters %d-%d of %d\n", offset, end, total)
+	}
+	sb.WriteString("\n")
+	sb.WriteString(string(content[offset:end]))
+	if end < total {
+		fmt.Fprintf(&sb, "\n\n... (%d more characters; call web_fetch again with offset=%d)", total-end, end)
+	}
+	return sb.String()
+}
+
+var unsafeMemoryNameChars = regexp.MustCompile(`[^a-z0-9]+`)
 
-	// Format result
-	result := fmt.Sprintf("**%s**\n\nURL: %s\n%s\n%s", title, urlStr, metadata.String(), content)
+// WithoutMemoryWrite returns a copy of the tool that refuses save=true.
+func (w *WebFetchTool) WithoutMemoryWrite() Tool {
+	clone := *w
+	clone.noSave = true
+	return &clone
+}
 
-	// Truncate if too long (increased from 8KB to 12KB)
-	if len(result) > 12000 {
-		result = result[:12000] + "\n\n... (truncated)"
+// saveToMemory writes the full document to memory/web/ as markdown and
+// indexes it when an indexer is available. It returns the relative path.
+func (w *WebFetchTool) saveToMemory(ctx context.Context, urlStr string, doc webDocument) (string, error) {
+	if w.memoryRoot == "" {
+		return "", fmt.Errorf("no memory directory configured")
+	}
+	parsed, err := url.Parse(urlStr)
+	if err != nil {
+		return "", err
+	}
+	name := strings.Trim(unsafeMemoryNameChars.ReplaceAllString(strings.ToLower(parsed.Hostname()+" "+parsed.Path), "-"), "-")
+	if len(name) > 100 {
+		name = strings.Trim(name[:100], "-")
+	}
+	if name == "" {
+		name = "page"
+	}
+	relPath := filepath.Join("memory", "web", name+".md")
+	absPath := filepath.Join(w.memoryRoot, relPath)
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return "", err
 	}
 
-	return result, nil
+	title := doc.Title
+	if title == "" {
+		title = urlStr
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\nSource: %s\nFetched: %s\n", title, urlStr, time.Now().Format("2006-01-02"))
+	sb.WriteString(doc.Meta)
+	sb.WriteString("\n")
+	sb.WriteString(doc.Content)
+	sb.WriteString("\n")
+	if err := os.WriteFile(absPath, []byte(sb.String()), 0644); err != nil {
+		return "", err
+	}
+
+	if w.indexer != nil {
+		if err := w.indexer.IndexFile(ctx, w.memoryRoot, absPath); err != nil {
+			log.Printf("[web_fetch] indexing %s failed: %v", relPath, err)
+			return relPath + " (not indexed yet)", nil
+		}
+		return relPath + " (indexed)", nil
+	}
+	return relPath, nil
 }
 
 // isPrivateIP checks if an IP address is private/internal
@@ -0,0 +1,223 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// webDocument is the text extracted from a fetched resource.
+type webDocument struct {
+	Kind    string // html, pdf, json, text, or feed
+	Title   string
+	Meta    string // extra header lines (author, site, feed info)
+	Content string
+}
+
+// extractDocument picks an extractor from the response content type, falling
+// back to sniffing the body when the type is missing or generic.
+func (w *WebFetchTool) extractDocument(ctx context.Context, contentType, rawURL string, body []byte) (webDocument, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	mediaType = strings.ToLower(mediaType)
+	trimmed := bytes.TrimSpace(body)
+
+	switch {
+	case mediaType == "application/pdf" || bytes.HasPrefix(body, []byte("%PDF-")):
+		text, err := extractPDFText(ctx, body)
+		if err != nil {
+			return webDocument{}, err
+		}
+		title := rawURL
+		if u, err := url.Parse(rawURL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+			title = path.Base(u.Path)
+		}
+		return webDocument{Kind: "pdf", Title: title, Content: text}, nil
+
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return webDocument{Kind: "json", Content: prettyJSON(body)}, nil
+
+	case mediaType == "application/rss+xml" || mediaType == "application/atom+xml" ||
+		mediaType == "application/xml" || mediaType == "text/xml" || (strings.HasSuffix(mediaType, "+xml") && mediaType != "application/xhtml+xml"):
+		if doc, ok := parseFeed(body); ok {
+			return doc, nil
+		}
+		return webDocument{Kind: "text", Content: string(body)}, nil
+
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return w.extractHTML(string(body), rawURL), nil
+
+	case strings.HasPrefix(mediaType, "text/") && mediaType != "text/plain":
+		return webDocument{Kind: "text", Content: string(body)}, nil
+	}
+
+	// Generic, missing or text/plain content type (common for feeds): sniff.
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<?xml")) || bytes.HasPrefix(trimmed, []byte("<rss")) || bytes.HasPrefix(trimmed, []byte("<feed")):
+		if doc, ok := parseFeed(body); ok {
+			return doc, nil
+		}
+	case mediaType != "text/plain" && (bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("["))):
+		if json.Valid(trimmed) {
+			return webDocument{Kind: "json", Content: prettyJSON(body)}, nil
+		}
+	}
+	if mediaType != "text/plain" && looksLikeHTML(trimmed) {
+		return w.extractHTML(string(body), rawURL), nil
+	}
+	if utf8.Valid(body) && !bytes.ContainsRune(body, 0) {
+		return webDocument{Kind: "text", Content: string(body)}, nil
+	}
+	return webDocument{}, fmt.Errorf("unsupported content type %q (binary data)", contentType)
+}
+
+func looksLikeHTML(body []byte) bool {
+	head := strings.ToLower(string(body[:min(len(body), 512)]))
+	return strings.Contains(head, "<!doctype html") || strings.Contains(head, "<html")
+}
+
+// extractHTML extracts the main article with readability, falling back to
+// plain text extraction for pages readability cannot handle.
+func (w *WebFetchTool) extractHTML(body, rawURL string) webDocument {
+	article, err := ExtractArticle(body, rawURL)
+	if err == nil && article.Length > 100 {
+		var meta strings.Builder
+		if article.Byline != "" {
+			fmt.Fprintf(&meta, "Author: %s\n", article.Byline)
+		}
+		if article.SiteName != "" {
+			fmt.Fprintf(&meta, "Site: %s\n", article.SiteName)
+		}
+		if article.Excerpt != "" {
+			fmt.Fprintf(&meta, "Excerpt: %s\n", article.Excerpt)
+		}
+		return webDocument{Kind: "html", Title: article.Title, Meta: meta.String(), Content: article.Content}
+	}
+	content, title := w.extractContent(body)
+	return webDocument{Kind: "html", Title: title, Content: content}
+}
+
+// prettyJSON indents valid JSON and returns anything else unchanged.
+func prettyJSON(body []byte) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, bytes.TrimSpace(body), "", "  "); err != nil {
+		return string(body)
+	}
+	return out.String()
+}
+
+type rssFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Items       []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			PubDate     string `xml:"pubDate"`
+			Description string `xml:"description"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type atomFeed struct {
+	XMLName  xml.Name `xml:"feed"`
+	Title    string   `xml:"title"`
+	Subtitle string   `xml:"subtitle"`
+	Entries  []struct {
+		Title     string `xml:"title"`
+		Updated   string `xml:"updated"`
+		Published string `xml:"published"`
+		Summary   string `xml:"summary"`
+		Content   string `xml:"content"`
+		Links     []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// feedItem is one RSS item or Atom entry in display form.
+type feedItem struct {
+	title, link, date, summary string
+}
+
+// parseFeed renders an RSS 2.0 or Atom feed as a list of entries.
+func parseFeed(body []byte) (webDocument, bool) {
+	var title, subtitle string
+	var items []feedItem
+
+	var rss rssFeed
+	var atom atomFeed
+	switch {
+	case xml.Unmarshal(body, &rss) == nil:
+		title, subtitle = rss.Channel.Title, rss.Channel.Description
+		for _, it := range rss.Channel.Items {
+			items = append(items, feedItem{it.Title, it.Link, it.PubDate, it.Description})
+		}
+	case xml.Unmarshal(body, &atom) == nil:
+		title, subtitle = atom.Title, atom.Subtitle
+		for _, e := range atom.Entries {
+			link := ""
+			for _, l := range e.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = l.Href
+					break
+				}
+			}
+			date := e.Published
+			if date == "" {
+				date = e.Updated
+			}
+			summary := e.Summary
+			if summary == "" {
+				summary = e.Content
+			}
+			items = append(items, feedItem{e.Title, link, date, summary})
+		}
+	default:
+		return webDocument{}, false
+	}
+
+	var meta strings.Builder
+	if subtitle = strings.TrimSpace(subtitle); subtitle != "" {
+		fmt.Fprintf(&meta, "Description: %s\n", subtitle)
+	}
+	fmt.Fprintf(&meta, "Entries: %d\n", len(items))
+
+	var sb strings.Builder
+	for i, it := range items {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, strings.TrimSpace(it.title))
+		if it.link != "" {
+			fmt.Fprintf(&sb, "   %s\n", strings.TrimSpace(it.link))
+		}
+		if it.date != "" {
+			fmt.Fprintf(&sb, "   %s\n", strings.TrimSpace(it.date))
+		}
+		if summary := feedSummary(it.summary); summary != "" {
+			fmt.Fprintf(&sb, "   %s\n", summary)
+		}
+		sb.WriteString("\n")
+	}
+	return webDocument{Kind: "feed", Title: strings.TrimSpace(title), Meta: meta.String(), Content: strings.TrimRight(sb.String(), "\n")}, true
+}
+
+var feedMarkupRe = regexp.MustCompile(`<[^>]*>`)
+
+// feedSummary strips markup from an entry description and shortens it.
+func feedSummary(raw string) string {
+	text := strings.Join(strings.Fields(html.UnescapeString(feedMarkupRe.ReplaceAllString(raw, " "))), " ")
+	if len(text) > 300 {
+		text = strings.ToValidUTF8(text[:300], "") + "…"
+	}
+	return text
+}
@@ -0,0 +1,386 @@
+package tools
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	pdftotextTimeout   = 30 * time.Second
+	maxPDFStreamBytes  = 16 * 1024 * 1024
+	pdfSpaceKernCutoff = -180 // TJ adjustments below this are treated as word gaps
+)
+
+// pdftotextBinary is the poppler text extractor used when installed.
+var pdftotextBinary = "pdftotext"
+
+// extractPDFText returns the text of a PDF. pdftotext (poppler-utils) is used
+// when installed; otherwise a built-in extractor reads the text operators of
+// the content streams, which covers simple PDFs with standard font encodings.
+func extractPDFText(ctx context.Context, body []byte) (string, error) {
+	if bin, err := exec.LookPath(pdftotextBinary); err == nil {
+		runCtx, cancel := context.WithTimeout(ctx, pdftotextTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(runCtx, bin, "-layout", "-enc", "UTF-8", "-", "-")
+		cmd.Stdin = bytes.NewReader(body)
+		if out, err := cmd.Output(); err == nil && strings.TrimSpace(string(out)) != "" {
+			return strings.TrimSpace(string(out)), nil
+		}
+	}
+
+	text := extractPDFTextBuiltin(body)
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("could not extract text from PDF (scanned image or embedded font encoding); install pdftotext for full PDF support")
+	}
+	return text, nil
+}
+
+var (
+	pdfStreamRe    = regexp.MustCompile(`stream\r?\n`)
+	pdfSkipFilters = []string{"/DCTDecode", "/JPXDecode", "/CCITTFaxDecode", "/JBIG2Decode", "/LZWDecode", "/RunLengthDecode", "/ASCII85Decode"}
+)
+
+// extractPDFTextBuiltin decodes each content stream and collects the strings
+// shown by text operators.
+func extractPDFTextBuiltin(body []byte) string {
+	var out strings.Builder
+	for _, loc := range pdfStreamRe.FindAllIndex(body, -1) {
+		// The keyword must follow the stream dictionary, not be part of "endstream".
+		if loc[0] >= 3 && string(body[loc[0]-3:loc[0]]) == "end" {
+			continue
+		}
+		dict := pdfStreamDict(body, loc[0])
+		if dict == "" || strings.Contains(dict, "/Image") || strings.Contains(dict, "/FontFile") || strings.Contains(dict, "/Length1") {
+			continue
+		}
+		end := bytes.Index(body[loc[1]:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		data := bytes.TrimRight(body[loc[1]:loc[1]+end], "\r\n")
+
+		content, ok := decodePDFStream(dict, data)
+		if !ok || !bytes.Contains(content, []byte("BT")) {
+			continue
+		}
+		out.WriteString(parsePDFContentText(content))
+		out.WriteString("\n")
+	}
+	return cleanPDFText(out.String())
+}
+
+// pdfStreamDict returns the dictionary preceding the stream keyword at pos.
+func pdfStreamDict(body []byte, pos int) string {
+	head := bytes.TrimRight(body[:pos], " \t\r\n")
+	if !bytes.HasSuffix(head, []byte(">>")) {
+		return ""
+	}
+	start := bytes.LastIndex(head, []byte(" obj"))
+	if start < 0 {
+		start = bytes.LastIndex(head, []byte("obj"))
+	}
+	if start < 0 {
+		return ""
+	}
+	return string(head[start:])
+}
+
+func decodePDFStream(dict string, data []byte) ([]byte, bool) {
+	for _, f := range pdfSkipFilters {
+		if strings.Contains(dict, f) {
+			return nil, false
+		}
+	}
+	if !strings.Contains(dict, "/FlateDecode") {
+		return data, true
+	}
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+	decoded, err := io.ReadAll(io.LimitReader(r, maxPDFStreamBytes))
+	if err != nil && len(decoded) == 0 {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// pdfOperand is a string, number, or array operand on the content stream stack.
+type pdfOperand struct {
+	str    string
+	num    float64
+	isStr  bool
+	isNum  bool
+	array  []pdfOperand
+	isList bool
+}
+
+// parsePDFContentText interprets the text-showing operators of a content
+// stream (Tj, TJ, ', ") and line movements (Td, TD, T*, Tm, ET).
+func parsePDFContentText(content []byte) string {
+	var out strings.Builder
+	var stack []pdfOperand
+	var arrays [][]pdfOperand
+	lastY := 0.0
+
+	push := func(op pdfOperand) {
+		if len(arrays) > 0 {
+			arrays[len(arrays)-1] = append(arrays[len(arrays)-1], op)
+			return
+		}
+		stack = append(stack, op)
+	}
+	newline := func() {
+		s := out.String()
+		if s != "" && !strings.HasSuffix(s, "\n") {
+			out.WriteString("\n")
+		}
+	}
+	num := func(i int) float64 {
+		if i < 0 || i >= len(stack) || !stack[i].isNum {
+			return 0
+		}
+		return stack[i].num
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, next := readPDFLiteral(content, i)
+			push(pdfOperand{str: s, isStr: true})
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return out.String()
+			}
+			push(pdfOperand{str: decodePDFHex(content[i+1 : i+end]), isStr: true})
+			i += end + 1
+		case c == '[':
+			arrays = append(arrays, nil)
+			i++
+		case c == ']':
+			if len(arrays) > 0 {
+				arr := arrays[len(arrays)-1]
+				arrays = arrays[:len(arrays)-1]
+				push(pdfOperand{array: arr, isList: true})
+			}
+			i++
+		case c == '/':
+			i++
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+		default:
+			start := i
+			for i < len(content) && !isPDFSpace(content[i]) && !isPDFDelimiter(content[i]) {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			token := string(content[start:i])
+			if f, err := strconv.ParseFloat(token, 64); err == nil {
+				push(pdfOperand{num: f, isNum: true})
+				continue
+			}
+
+			switch token {
+			case "ET", "T*":
+				newline()
+			case "Td", "TD":
+				if num(len(stack)-1) != 0 {
+					newline()
+				} else if num(len(stack)-2) > 0 {
+					out.WriteString(" ")
+				}
+			case "Tm":
+				if y := num(len(stack) - 1); y != lastY {
+					newline()
+					lastY = y
+				}
+			case "'", "\"":
+				newline()
+				fallthrough
+			case "Tj":
+				if n := len(stack); n > 0 && stack[n-1].isStr {
+					out.WriteString(stack[n-1].str)
+				}
+			case "TJ":
+				if n := len(stack); n > 0 && stack[n-1].isList {
+					for _, el := range stack[n-1].array {
+						switch {
+						case el.isStr:
+							out.WriteString(el.str)
+						case el.isNum && el.num < pdfSpaceKernCutoff:
+							out.WriteString(" ")
+						}
+					}
+				}
+			case "ID":
+				// Inline image data runs until EI; skip it.
+				if end := bytes.Index(content[i:], []byte("EI")); end >= 0 {
+					i += end + 2
+				} else {
+					i = len(content)
+				}
+			}
+			stack = stack[:0]
+			arrays = arrays[:0]
+		}
+	}
+	return out.String()
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// readPDFLiteral reads a (string) starting at content[start] and returns the
+// decoded text and the index after the closing parenthesis.
+func readPDFLiteral(content []byte, start int) (string, int) {
+	var buf []byte
+	depth := 0
+	i := start
+	for ; i < len(content); i++ {
+		c := content[i]
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return decodePDFString(buf), i + 1
+			}
+		case '\\':
+			i++
+			if i >= len(content) {
+				break
+			}
+			switch e := content[i]; e {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation.
+				if e == '\r' && i+1 < len(content) && content[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					j := i
+					for j < len(content) && j < i+3 && content[j] >= '0' && content[j] <= '7' {
+						j++
+					}
+					v, _ := strconv.ParseUint(string(content[i:j]), 8, 8)
+					buf = append(buf, byte(v))
+					i = j - 1
+				} else {
+					buf = append(buf, e)
+				}
+			}
+			continue
+		}
+		buf = append(buf, c)
+	}
+	return decodePDFString(buf), i
+}
+
+func decodePDFHex(hex []byte) string {
+	clean := make([]byte, 0, len(hex))
+	for _, c := range hex {
+		if !isPDFSpace(c) {
+			clean = append(clean, c)
+		}
+	}
+	if len(clean)%2 == 1 {
+		clean = append(clean, '0')
+	}
+	raw := make([]byte, 0, len(clean)/2)
+	for i := 0; i+1 < len(clean); i += 2 {
+		v, err := strconv.ParseUint(string(clean[i:i+2]), 16, 8)
+		if err != nil {
+			return ""
+		}
+		raw = append(raw, byte(v))
+	}
+	return decodePDFString(raw)
+}
+
+// winAnsiHigh maps the WinAnsi code points in 0x80-0x9F that differ from Latin-1.
+var winAnsiHigh = map[byte]rune{
+	0x80: '€', 0x85: '…', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”',
+	0x95: '•', 0x96: '–', 0x97: '—', 0x99: '™',
+}
+
+// decodePDFString converts a PDF string to UTF-8: UTF-16BE when it carries a
+// byte order mark, otherwise WinAnsi/Latin-1. Control bytes are dropped.
+func decodePDFString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	var sb strings.Builder
+	for _, c := range b {
+		switch {
+		case c == '\n' || c == '\t':
+			sb.WriteByte(' ')
+		case c < 0x20 || c == 0x7F:
+		case c < 0x80:
+			sb.WriteByte(c)
+		default:
+			if r, ok := winAnsiHigh[c]; ok {
+				sb.WriteRune(r)
+			} else if c >= 0xA0 {
+				sb.WriteRune(rune(c))
+			}
+		}
+	}
+	return sb.String()
+}
+
+var pdfBlankLinesRe = regexp.MustCompile(`\n{3,}`)
+
+func cleanPDFText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(pdfBlankLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
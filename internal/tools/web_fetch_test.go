@@ -1,8 +1,21 @@
 package tools
 
 import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"ok-gobot/internal/storage"
 )
 
 func TestIsPrivateIP(t *testing.T) {
@@ -183,3 +196,268 @@ func TestWebFetchTool_WithReadability(t *testing.T) {
 	// The readability integration is tested via the readability_test.go tests
 	t.Skip("Integration test - requires HTTP server")
 }
+
+type memoryWebCache struct {
+	mu      sync.Mutex
+	entries map[string]storage.WebCacheEntry
+}
+
+func (m *memoryWebCache) GetWebCache(url string) (*storage.WebCacheEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[url]
+	if !ok {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+func (m *memoryWebCache) PutWebCache(e storage.WebCacheEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.entries == nil {
+		m.entries = map[string]storage.WebCacheEntry{}
+	}
+	e.FetchedAt = time.Now()
+	m.entries[e.URL] = e
+	return nil
+}
+
+func (m *memoryWebCache) TouchWebCache(url string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.entries[url]
+	e.FetchedAt = time.Now()
+	m.entries[url] = e
+	return nil
+}
+
+// age makes every cached entry old enough to need revalidation.
+func (m *memoryWebCache) age() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, e := range m.entries {
+		e.FetchedAt = time.Now().Add(-time.Hour)
+		m.entries[k] = e
+	}
+}
+
+// newTestWebFetchTool returns a tool that can reach httptest servers on loopback.
+func newTestWebFetchTool(cfg WebFetchConfig) *WebFetchTool {
+	tool := NewWebFetchTool(cfg)
+	tool.validateURL = func(string) error { return nil }
+	return tool
+}
+
+func TestWebFetchTool_PagesLongText(t *testing.T) {
+	text := strings.Repeat("abcdefghij", 30)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, text)
+	}))
+	defer srv.Close()
+
+	tool := newTestWebFetchTool(WebFetchConfig{})
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"url": srv.URL, "max_chars": "100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Type: text") || !strings.Contains(out, "Showing characters 0-100 of 300") || !strings.Contains(out, "offset=100") {
+		t.Errorf("unexpected first page:\n%s", out)
+	}
+
+	out, err = tool.ExecuteJSON(context.Background(), map[string]string{"url": srv.URL, "offset": "250", "max_chars": "100"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Showing characters 250-300 of 300") || strings.Contains(out, "more characters") {
+		t.Errorf("unexpected last page:\n%s", out)
+	}
+}
+
+func TestWebFetchTool_ConditionalGet(t *testing.T) {
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"ok","items":[1,2]}`)
+	}))
+	defer srv.Close()
+
+	cache := &memoryWebCache{}
+	tool := newTestWebFetchTool(WebFetchConfig{Cache: cache})
+	ctx := context.Background()
+
+	out, err := tool.ExecuteJSON(ctx, map[string]string{"url": srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Type: json") || !strings.Contains(out, "\"name\": \"ok\"") {
+		t.Errorf("expected pretty-printed JSON:\n%s", out)
+	}
+
+	// A fresh entry is served without contacting the origin.
+	if out, err = tool.ExecuteJSON(ctx, map[string]string{"url": srv.URL}); err != nil || !strings.Contains(out, "(cached)") {
+		t.Fatalf("expected cached page, got %q, %v", out, err)
+	}
+	if requests != 1 {
+		t.Fatalf("fresh cache entry refetched: %d requests", requests)
+	}
+
+	// A stale entry is revalidated with its ETag.
+	cache.age()
+	if out, err = tool.ExecuteJSON(ctx, map[string]string{"url": srv.URL}); err != nil || !strings.Contains(out, "(not modified)") {
+		t.Fatalf("expected revalidated page, got %q, %v", out, err)
+	}
+	if notModified != 1 {
+		t.Errorf("expected one 304, got %d", notModified)
+	}
+
+	// refresh bypasses the cache entirely.
+	if _, err := tool.ExecuteJSON(ctx, map[string]string{"url": srv.URL, "refresh": "true"}); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 || notModified != 1 {
+		t.Errorf("refresh sent validators: requests=%d notModified=%d", requests, notModified)
+	}
+}
+
+func TestWebFetchTool_FeedsAndPDF(t *testing.T) {
+	rss := `<?xml version="1.0"?><rss version="2.0"><channel><title>Blog</title>
+<item><title>First post</title><link>https://blog/1</link><pubDate>Mon, 02 Mar 2026 10:00:00 GMT</pubDate><description>&lt;p&gt;Hello &amp;amp; welcome&lt;/p&gt;</description></item>
+</channel></rss>`
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom"><title>Atom</title>
+<entry><title>Entry</title><link rel="alternate" href="https://atom/1"/><updated>2026-03-02T10:00:00Z</updated><summary>Sum</summary></entry></feed>`
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, rss)
+	})
+	mux.HandleFunc("/atom", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, atom) // no content type: sniffed
+	})
+	mux.HandleFunc("/doc.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(testPDF(t)) //nolint:errcheck
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tool := newTestWebFetchTool(WebFetchConfig{})
+	ctx := context.Background()
+
+	out, err := tool.ExecuteJSON(ctx, map[string]string{"url": srv.URL + "/rss"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"**Blog**", "Type: feed", "1. First post", "https://blog/1", "Hello & welcome"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rss output missing %q:\n%s", want, out)
+		}
+	}
+
+	out, err = tool.ExecuteJSON(ctx, map[string]string{"url": srv.URL + "/atom"})
+	if err != nil || !strings.Contains(out, "1. Entry") || !strings.Contains(out, "https://atom/1") {
+		t.Errorf("atom output = %q, %v", out, err)
+	}
+
+	pdftotextBinary = "pdftotext-not-installed"
+	t.Cleanup(func() { pdftotextBinary = "pdftotext" })
+	out, err = tool.ExecuteJSON(ctx, map[string]string{"url": srv.URL + "/doc.pdf"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"**doc.pdf**", "Type: pdf", "Hello PDF world", "Second line (with parens)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("pdf output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+// testPDF builds a minimal PDF with one Flate-compressed content stream.
+func testPDF(t *testing.T) []byte {
+	t.Helper()
+	content := "BT /F1 12 Tf 72 712 Td (Hello ) Tj [(PDF) -250 (world)] TJ 0 -14 Td (Second line \\(with parens\\)) Tj ET"
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte(content)) //nolint:errcheck
+	zw.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+type recordingIndexer struct {
+	root, path string
+}
+
+func (r *recordingIndexer) IndexFile(_ context.Context, rootPath, absPath string) error {
+	r.root, r.path = rootPath, absPath
+	return nil
+}
+
+func TestWebFetchTool_SaveToMemory(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/markdown")
+		fmt.Fprint(w, "# Notes\n\nRemember this.")
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	indexer := &recordingIndexer{}
+	tool := newTestWebFetchTool(WebFetchConfig{MemoryRoot: root, Indexer: indexer})
+
+	out, err := tool.ExecuteJSON(context.Background(), map[string]string{"url": srv.URL + "/docs/notes.md", "save": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Saved to memory: memory/web/") || !strings.Contains(out, "(indexed)") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if indexer.root != root || !strings.HasPrefix(indexer.path, filepath.Join(root, "memory", "web")) {
+		t.Fatalf("indexer called with %q, %q", indexer.root, indexer.path)
+	}
+	saved, err := os.ReadFile(indexer.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(saved), "Source: "+srv.URL+"/docs/notes.md") || !strings.Contains(string(saved), "Remember this.") {
+		t.Errorf("unexpected saved document:\n%s", saved)
+	}
+}
+
+func TestWebFetchTool_SaveDeniedByMemoryWritePolicy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "plain page")
+	}))
+	defer srv.Close()
+
+	root := t.TempDir()
+	reg := NewRegistry()
+	reg.Register(newTestWebFetchTool(WebFetchConfig{MemoryRoot: root}))
+	reg = ApplyPolicy(reg, &CapabilityPolicy{Network: true})
+	tool, _ := reg.Get("web_fetch")
+	fetch := tool.(jsonExecutor)
+
+	_, err := fetch.ExecuteJSON(context.Background(), map[string]string{"url": srv.URL, "save": "true"})
+	if denial, ok := IsToolDenial(err); !ok || denial.Family != "memory_write" {
+		t.Fatalf("save with memory_write denied = %v, want memory_write denial", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "memory")); !os.IsNotExist(err) {
+		t.Errorf("memory directory written despite denial: %v", err)
+	}
+	if out, err := fetch.ExecuteJSON(context.Background(), map[string]string{"url": srv.URL}); err != nil || !strings.Contains(out, "plain page") {
+		t.Errorf("plain fetch = %q, %v", out, err)
+	}
+}
@@ -0,0 +1,238 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"ok-gobot/internal/storage"
+	"ok-gobot/internal/workflow"
+)
+
+// WorkflowStarter runs workflows as durable parent/child jobs.
+type WorkflowStarter interface {
+	Start(ctx context.Context, def *workflow.Definition, spec workflow.RunSpec) (*storage.Job, error)
+	Status(jobID string) (string, error)
+}
+
+// WorkflowTool lists, starts and inspects multi-step workflows defined in
+// the workspace (workflows/*.yaml) or passed inline by the agent.
+type WorkflowTool struct {
+	engine   WorkflowStarter
+	basePath string
+
+	// ApprovalFunc confirms workflows that run shell commands or worker
+	// CLIs, whether inline or loaded from the workspace.
+	ApprovalFunc func(command string) (bool, error)
+
+	noShell bool // exec and worker steps denied by capability policy
+}
+
+// NewWorkflowTool creates the workflow tool for the given workspace.
+func NewWorkflowTool(engine WorkflowStarter, basePath string) *WorkflowTool {
+	return &WorkflowTool{engine: engine, basePath: basePath}
+}
+
+func (w *WorkflowTool) Name() string { return "workflow" }
+
+func (w *WorkflowTool) Description() string {
+	return "Run multi-step job pipelines (agent, role, worker and exec steps with dependencies and fan-out) and check their progress"
+}
+
+// WithApproval returns a copy of the tool that asks fn before exec workflows.
+func (w *WorkflowTool) WithApproval(fn func(command string) (bool, error)) Tool {
+	clone := *w
+	clone.ApprovalFunc = fn
+	return &clone
+}
+
+// WithoutShell returns a copy of the tool that refuses workflows with exec
+// or worker steps, for agents whose policy denies the shell capability.
+func (w *WorkflowTool) WithoutShell() Tool {
+	clone := *w
+	clone.noShell = true
+	return &clone
+}
+
+func (w *WorkflowTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"command": map[string]interface{}{
+				"type":        "string",
+				"description": "list: show workspace workflows; run: start a workspace workflow; run_inline: start a workflow from YAML; status: show a run and its steps",
+				"enum":        []string{"list", "run", "run_inline", "status"},
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Workflow name (for run)",
+			},
+			"definition": map[string]interface{}{
+				"type":        "string",
+				"description": "Workflow YAML with name, inputs and steps (for run_inline)",
+			},
+			"inputs": map[string]interface{}{
+				"type":        "string",
+				"description": `Input values as a JSON object, e.g. {"feed":"https://..."}`,
+			},
+			"job_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Workflow job ID (for status)",
+			},
+		},
+		"required": []string{"command"},
+	}
+}
+
+func (w *WorkflowTool) Execute(ctx context.Context, args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: workflow <list|run|status> [args...]")
+	}
+	params := map[string]string{"command": args[0]}
+	if len(args) > 1 {
+		switch args[0] {
+		case "run":
+			params["name"] = args[1]
+		case "status":
+			params["job_id"] = args[1]
+		}
+	}
+	if len(args) > 2 {
+		params["inputs"] = args[2]
+	}
+	return w.ExecuteJSON(ctx, params)
+}
+
+func (w *WorkflowTool) ExecuteJSON(ctx context.Context, params map[string]string) (string, error) {
+	if w.engine == nil {
+		return "", fmt.Errorf("workflows are not available")
+	}
+
+	switch params["command"] {
+	case "list":
+		return w.list()
+	case "run":
+		def, err := workflow.Load(w.basePath, strings.TrimSpace(params["name"]))
+		if err != nil {
+			return "", err
+		}
+		return w.approveAndStart(ctx, def, params["inputs"])
+	case "run_inline":
+		def, err := workflow.Parse([]byte(params["definition"]))
+		if err != nil {
+			return "", err
+		}
+		return w.approveAndStart(ctx, def, params["inputs"])
+	case "status":
+		jobID := strings.TrimSpace(params["job_id"])
+		if jobID == "" {
+			return "", fmt.Errorf("job_id is required")
+		}
+		return w.engine.Status(jobID)
+	default:
+		return "", fmt.Errorf("unknown command: %s", params["command"])
+	}
+}
+
+// checkPolicy refuses workflows whose exec or worker steps would run local
+// processes for an agent without the shell capability.
+func (w *WorkflowTool) checkPolicy(def *workflow.Definition) error {
+	if !w.noShell || !def.RunsLocal() {
+		return nil
+	}
+	return &ToolDenial{
+		ToolName:    w.Name(),
+		Family:      "shell",
+		Reason:      fmt.Sprintf("workflow %s has exec or worker steps and capability \"shell\" is denied by agent policy", def.Name),
+		Remediation: "Use only agent and role steps, or ask the operator to allow shell for this agent.",
+	}
+}
+
+// approveAndStart checks policy and, for workflows with exec or worker
+// steps, asks for approval before starting. Workspace workflows are gated
+// too because the agent can write workflows/ with the file tool.
+func (w *WorkflowTool) approveAndStart(ctx context.Context, def *workflow.Definition, rawInputs string) (string, error) {
+	if err := w.checkPolicy(def); err != nil {
+		return "", err
+	}
+	if def.RunsLocal() {
+		if w.ApprovalFunc == nil {
+			return "", &ToolDenial{
+				ToolName:    w.Name(),
+				Family:      "workflow",
+				Reason:      "workflows with exec or worker steps require approval and no approval channel is available",
+				Remediation: "Run it from a Telegram chat so it can be approved, or use only agent and role steps.",
+			}
+		}
+		approved, err := w.ApprovalFunc(describeLocalSteps(def))
+		if err != nil {
+			return "", fmt.Errorf("approval check failed: %w", err)
+		}
+		if !approved {
+			return "Workflow denied by user", nil
+		}
+	}
+	return w.start(ctx, def, rawInputs)
+}
+
+func (w *WorkflowTool) start(ctx context.Context, def *workflow.Definition, rawInputs string) (string, error) {
+	inputs := map[string]string{}
+	if strings.TrimSpace(rawInputs) != "" {
+		if err := json.Unmarshal([]byte(rawInputs), &inputs); err != nil {
+			return "", fmt.Errorf("inputs must be a JSON object of strings: %w", err)
+		}
+	}
+	job, err := w.engine.Start(ctx, def, workflow.RunSpec{
+		Inputs:     inputs,
+		SessionKey: SessionKeyFromContext(ctx),
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Workflow %s started as job %s (%d steps).\nCheck progress with workflow status %s or `ok-gobot jobs inspect %s`.",
+		def.Name, job.JobID, len(def.Steps), job.JobID, job.JobID), nil
+}
+
+func (w *WorkflowTool) list() (string, error) {
+	defs, errs := workflow.LoadDir(w.basePath)
+	if len(defs) == 0 && len(errs) == 0 {
+		return fmt.Sprintf("No workflows in %s/", workflow.Dir), nil
+	}
+	var sb strings.Builder
+	for _, def := range defs {
+		fmt.Fprintf(&sb, "- %s (%d steps)", def.Name, len(def.Steps))
+		if def.Description != "" {
+			fmt.Fprintf(&sb, ": %s", def.Description)
+		}
+		if len(def.Inputs) > 0 {
+			names := make([]string, 0, len(def.Inputs))
+			for name := range def.Inputs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Fprintf(&sb, " [inputs: %s]", strings.Join(names, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	for _, err := range errs {
+		fmt.Fprintf(&sb, "! %v\n", err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// describeLocalSteps summarises the shell commands and worker tasks an
+// inline workflow runs.
+func describeLocalSteps(def *workflow.Definition) string {
+	var lines []string
+	for _, step := range def.Steps {
+		switch step.Kind() {
+		case workflow.KindExec:
+			lines = append(lines, fmt.Sprintf("%s: %s", step.ID, step.Exec))
+		case workflow.KindWorker:
+			lines = append(lines, fmt.Sprintf("%s: %s worker: %s", step.ID, step.Worker, step.Task))
+		}
+	}
+	return fmt.Sprintf("workflow %s\n%s", def.Name, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ok-gobot/internal/storage"
+	"ok-gobot/internal/workflow"
+)
+
+type stubWorkflowStarter struct {
+	started []string
+}
+
+func (s *stubWorkflowStarter) Start(_ context.Context, def *workflow.Definition, _ workflow.RunSpec) (*storage.Job, error) {
+	s.started = append(s.started, def.Name)
+	return &storage.Job{JobID: "job-" + def.Name}, nil
+}
+
+func (s *stubWorkflowStarter) Status(jobID string) (string, error) {
+	return "", nil
+}
+
+const workerWorkflowYAML = `name: fix
+steps:
+  - id: patch
+    worker: codex
+    task: fix the flaky test
+`
+
+func TestWorkflowTool_InlineWorkerStepsNeedApproval(t *testing.T) {
+	starter := &stubWorkflowStarter{}
+	tool := NewWorkflowTool(starter, t.TempDir())
+	params := map[string]string{"command": "run_inline", "definition": workerWorkflowYAML}
+
+	if _, err := tool.ExecuteJSON(context.Background(), params); err == nil {
+		t.Fatal("inline worker workflow started without an approval channel")
+	} else if _, ok := IsToolDenial(err); !ok {
+		t.Fatalf("expected ToolDenial, got %v", err)
+	}
+
+	var asked string
+	bound := tool.WithApproval(func(command string) (bool, error) {
+		asked = command
+		return true, nil
+	}).(*WorkflowTool)
+	if _, err := bound.ExecuteJSON(context.Background(), params); err != nil {
+		t.Fatalf("approved run failed: %v", err)
+	}
+	if !strings.Contains(asked, "codex worker: fix the flaky test") {
+		t.Errorf("approval prompt %q does not describe the worker step", asked)
+	}
+	if len(starter.started) != 1 {
+		t.Errorf("started = %v, want one run", starter.started)
+	}
+}
+
+func TestWorkflowTool_WorkspaceWorkerStepsNeedApproval(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, workflow.Dir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, workflow.Dir, "fix.yaml"), []byte(workerWorkflowYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	starter := &stubWorkflowStarter{}
+	tool := NewWorkflowTool(starter, dir)
+	params := map[string]string{"command": "run", "name": "fix"}
+
+	if _, err := tool.ExecuteJSON(context.Background(), params); err == nil {
+		t.Fatal("workspace worker workflow started without an approval channel")
+	} else if _, ok := IsToolDenial(err); !ok {
+		t.Fatalf("expected ToolDenial, got %v", err)
+	}
+
+	denied := tool.WithApproval(func(string) (bool, error) { return false, nil }).(*WorkflowTool)
+	if out, err := denied.ExecuteJSON(context.Background(), params); err != nil || !strings.Contains(out, "denied") {
+		t.Fatalf("denied run = %q, %v", out, err)
+	}
+	if len(starter.started) != 0 {
+		t.Errorf("started = %v, want no runs", starter.started)
+	}
+}
+
+func TestWorkflowTool_ShellDeniedByPolicy(t *testing.T) {
+	starter := &stubWorkflowStarter{}
+	reg := NewRegistry()
+	reg.Register(NewWorkflowTool(starter, t.TempDir()).WithApproval(func(string) (bool, error) { return true, nil }))
+	reg = ApplyPolicy(reg, &CapabilityPolicy{Network: true, Spawn: true})
+	tool, _ := reg.Get("workflow")
+	run := tool.(jsonExecutor)
+
+	_, err := run.ExecuteJSON(context.Background(), map[string]string{"command": "run_inline", "definition": workerWorkflowYAML})
+	if denial, ok := IsToolDenial(err); !ok || denial.Family != "shell" {
+		t.Fatalf("worker workflow without shell = %v, want shell denial", err)
+	}
+
+	agentOnly := "name: brief\nsteps:\n  - id: a\n    agent: summarise the news\n"
+	if _, err := run.ExecuteJSON(context.Background(), map[string]string{"command": "run_inline", "definition": agentOnly}); err != nil {
+		t.Fatalf("agent-only workflow without shell failed: %v", err)
+	}
+	if len(starter.started) != 1 || starter.started[0] != "brief" {
+		t.Errorf("started = %v, want only the agent workflow", starter.started)
+	}
+}
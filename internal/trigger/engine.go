@@ -0,0 +1,501 @@
+// Package trigger starts role runs on outside events.
+//
+// Roles declare triggers in their frontmatter (see role.Trigger): an inbound
+// webhook, a file glob to watch, an RSS/Atom feed, an IMAP mailbox or a cron
+// schedule. Each new event is recorded once per trigger, so re-deliveries,
+// re-polls and restarts never run the same event twice, and runs as a
+// durable job that gives the event to the role as its task, with the
+// trigger's params bound.
+//
+// Items that already exist when a trigger is first set up (files matching
+// the glob, feed entries, mail in the folder) are recorded as seen without
+// running; only what arrives afterwards starts the role. Files and feed
+// items that arrive while the daemon is down are picked up on the next
+// start; schedule fires missed while it is down are skipped.
+//
+// Roles can also be run on demand (RunRole), which the API and the CLI use,
+// and edited through SaveRole and DeleteRole, which reload the triggers.
+package trigger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ok-gobot/internal/role"
+	"ok-gobot/internal/runtime"
+	"ok-gobot/internal/storage"
+)
+
+// JobKind is the kind of the durable job a trigger event runs as.
+const JobKind = "role_trigger"
+
+// jobMaxAttempts leaves room for an interrupted role run to resume from its
+// checkpoint after a restart.
+const jobMaxAttempts = 3
+
+// maxEventsPerCheck caps how many new feed items or mails one poll starts,
+// so a feed that suddenly lists 500 entries cannot flood the job queue.
+const maxEventsPerCheck = 20
+
+// manualKind marks events of runs started through RunRole.
+const manualKind = "manual"
+
+// RoleRunner runs a task as the given role with bound params and returns
+// its report.
+type RoleRunner func(ctx context.Context, m *role.Manifest, task string, params map[string]any) (string, error)
+
+// Engine watches the triggers of the workspace's roles.
+type Engine struct {
+	store     *storage.Store
+	jobs      *runtime.JobService
+	workspace string
+	run       RoleRunner
+	client    *http.Client
+
+	edit   sync.Mutex // serializes SaveRole and DeleteRole
+	mu     sync.Mutex
+	ctx    context.Context
+	stop   context.CancelFunc
+	hooks  map[string]binding // webhook path -> trigger
+	active []binding
+	notify func(chatID int64, message string)
+}
+
+// binding is one trigger of a loaded role.
+type binding struct {
+	id       string
+	manifest *role.Manifest
+	trigger  role.Trigger
+}
+
+// NewEngine creates a trigger engine for the roles in workspace/roles.
+func NewEngine(store *storage.Store, jobs *runtime.JobService, workspace string, run RoleRunner) *Engine {
+	return &Engine{
+		store:     store,
+		jobs:      jobs,
+		workspace: workspace,
+		run:       run,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		hooks:     make(map[string]binding),
+	}
+}
+
+// SetNotifier sets the callback that delivers role reports and failures to
+// a trigger's chat.
+func (e *Engine) SetNotifier(fn func(chatID int64, message string)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.notify = fn
+}
+
+// Start loads the roles and starts their triggers. They stop when ctx is
+// cancelled.
+func (e *Engine) Start(ctx context.Context) error {
+	e.mu.Lock()
+	e.ctx = ctx
+	e.mu.Unlock()
+	e.jobs.RegisterKind(JobKind, runtime.ResumeFromCheckpoint, e.rebuildRunner)
+	return e.Reload()
+}
+
+// Reload re-reads the role manifests and restarts every trigger. Invalid
+// manifests are logged and skipped.
+func (e *Engine) Reload() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ctx == nil {
+		return errors.New("trigger engine not started")
+	}
+
+	manifests, err := e.loadRoles()
+	if err != nil {
+		return err
+	}
+
+	if e.stop != nil {
+		e.stop()
+	}
+	ctx, stop := context.WithCancel(e.ctx)
+	e.stop = stop
+	e.hooks = make(map[string]binding)
+	e.active = nil
+
+	for _, m := range manifests {
+		for _, t := range m.AllTriggers() {
+			b := binding{id: t.ID(m.Name), manifest: m, trigger: t}
+			if err := e.store.EnsureRoleTrigger(storage.RoleTrigger{
+				TriggerID: b.id,
+				Role:      m.Name,
+				Kind:      t.Kind(),
+				Source:    t.Source(),
+			}); err != nil {
+				log.Printf("[trigger] %s: failed to save state: %v", b.id, err)
+				continue
+			}
+			if err := checkRunnable(m, t.Kind()); err != nil {
+				e.setError(b, err)
+				continue
+			}
+
+			switch t.Kind() {
+			case role.TriggerWebhook:
+				if other, ok := e.hooks[t.Webhook]; ok {
+					log.Printf("[trigger] %s: webhook path already used by role %s", b.id, other.manifest.Name)
+					continue
+				}
+				e.hooks[t.Webhook] = b
+			case role.TriggerWatch:
+				go e.watch(ctx, b)
+			case role.TriggerFeed:
+				go e.poll(ctx, b, e.checkFeed)
+			case role.TriggerIMAP:
+				go e.poll(ctx, b, e.checkIMAP)
+			case role.TriggerSchedule:
+				go e.schedule(ctx, b)
+			}
+			e.active = append(e.active, b)
+		}
+	}
+	if len(e.active) > 0 {
+		log.Printf("⚡ %d role trigger(s) active", len(e.active))
+	}
+	return nil
+}
+
+// Stop stops every trigger.
+func (e *Engine) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stop != nil {
+		e.stop()
+		e.stop = nil
+	}
+	e.hooks = make(map[string]binding)
+	e.active = nil
+}
+
+// HasWebhooks reports whether any loaded role listens for webhooks.
+func (e *Engine) HasWebhooks() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.hooks) > 0
+}
+
+func (e *Engine) loadRoles() ([]*role.Manifest, error) {
+	dir := e.rolesDir()
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	manifests, errs := role.LoadDirLenient(dir)
+	for _, err := range errs {
+		log.Printf("[trigger] skipping role: %v", err)
+	}
+	return manifests, nil
+}
+
+// dispatch records ev and, unless the trigger saw it before, starts a job
+// running the role on it. It returns the job ID, or "" for a duplicate.
+func (e *Engine) dispatch(b binding, ev Event) (string, error) {
+	task, err := renderTask(b.trigger, ev)
+	if err != nil {
+		return "", err
+	}
+	values, err := renderParams(b.trigger, ev)
+	if err != nil {
+		return "", err
+	}
+	params, err := b.manifest.BindParams(values)
+	if err != nil {
+		return "", err
+	}
+	return e.start(b, ev, task, params, runtime.JobPriorityCron)
+}
+
+// RunRole starts a run of the named role now, outside its triggers, and
+// returns the job ID. The report goes to chatID unless it is 0.
+func (e *Engine) RunRole(name, task string, params map[string]any, chatID int64) (string, error) {
+	m, err := e.loadRole(name)
+	if err != nil {
+		return "", err
+	}
+	if err := checkRunnable(m, manualKind); err != nil {
+		return "", err
+	}
+	bound, err := m.BindParams(params)
+	if err != nil {
+		return "", err
+	}
+	b := binding{id: m.Name + ":" + manualKind + ":", manifest: m, trigger: role.Trigger{Chat: chatID}}
+	if err := e.store.EnsureRoleTrigger(storage.RoleTrigger{TriggerID: b.id, Role: m.Name, Kind: manualKind}); err != nil {
+		return "", err
+	}
+	title := strings.TrimSpace(task)
+	if title == "" {
+		title = "run"
+	}
+	ev := Event{
+		Kind:  manualKind,
+		Key:   strconv.FormatInt(time.Now().UnixNano(), 10),
+		Title: truncateTitle(title),
+		Time:  time.Now(),
+	}
+	return e.start(b, ev, task, bound, runtime.JobPriorityUser)
+}
+
+// checkRunnable refuses roles a job of kind cannot run safely. Jobs have no
+// chat to ask, so approval: always cannot be honoured. Webhook, feed and
+// mail events carry text from outside, so those roles must name the tools
+// that text may steer instead of getting all of them.
+func checkRunnable(m *role.Manifest, kind string) error {
+	if m.Approval == role.ApprovalAlways {
+		return fmt.Errorf("role %s has approval: always, which background runs cannot ask for", m.Name)
+	}
+	switch kind {
+	case role.TriggerWebhook, role.TriggerFeed, role.TriggerIMAP:
+		if len(m.Tools) == 0 {
+			return fmt.Errorf("role %s needs a tools: list to run on %s events", m.Name, kind)
+		}
+	}
+	return nil
+}
+
+// PlanRole resolves a run of the named role without starting it.
+func (e *Engine) PlanRole(name, task string, params map[string]any) (*role.Plan, error) {
+	m, err := e.loadRole(name)
+	if err != nil {
+		return nil, err
+	}
+	return m.Plan(params, task)
+}
+
+// Roles returns the workspace's role manifests, resolved, alongside the
+// errors of files that do not load.
+func (e *Engine) Roles() ([]*role.Manifest, []error) {
+	dir := e.rolesDir()
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	return role.LoadDirLenient(dir)
+}
+
+// RoleSource returns the markdown of the named role.
+func (e *Engine) RoleSource(name string) (string, error) {
+	data, err := role.ReadSource(e.rolesDir(), name)
+	return string(data), err
+}
+
+// SaveRole validates content as the named role's manifest, writes it and
+// reloads the triggers. It reports whether the role is new.
+func (e *Engine) SaveRole(name, content string) (*role.Manifest, bool, error) {
+	e.edit.Lock()
+	defer e.edit.Unlock()
+	m, created, err := role.Save(e.rolesDir(), name, []byte(content))
+	if err != nil {
+		return nil, false, err
+	}
+	return m, created, e.Reload()
+}
+
+// DeleteRole removes the named role and reloads the triggers.
+func (e *Engine) DeleteRole(name string) error {
+	e.edit.Lock()
+	defer e.edit.Unlock()
+	if err := role.Remove(e.rolesDir(), name); err != nil {
+		return err
+	}
+	return e.Reload()
+}
+
+func (e *Engine) loadRole(name string) (*role.Manifest, error) {
+	return role.Load(e.rolesDir(), name)
+}
+
+func (e *Engine) rolesDir() string {
+	return filepath.Join(e.workspace, role.Dir)
+}
+
+// start records the event and, unless the trigger saw it before, starts a
+// job running the role on task. It returns the job ID, or "" for a
+// duplicate. An event whose job cannot be started is not kept.
+func (e *Engine) start(b binding, ev Event, task string, params map[string]any, priority runtime.JobPriority) (string, error) {
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	eventID, isNew, err := e.store.RecordTriggerEvent(storage.TriggerEvent{
+		TriggerID: b.id,
+		EventKey:  ev.Key,
+		Status:    storage.TriggerEventStarted,
+		Title:     ev.Title,
+		Task:      task,
+		Params:    string(paramsJSON),
+		ChatID:    b.trigger.Chat,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to record event: %w", err)
+	}
+	if !isNew {
+		return "", nil
+	}
+
+	tier, ok := runtime.ParseCostTier(b.manifest.Worker)
+	if !ok {
+		tier = "" // a worker adapter name, not a tier
+	}
+	e.mu.Lock()
+	parent := e.ctx
+	e.mu.Unlock()
+	job, err := e.jobs.StartDetached(context.WithoutCancel(parent), runtime.JobSpec{
+		Kind:        JobKind,
+		Worker:      "role:" + b.manifest.Name,
+		SessionKey:  eventSessionKey(eventID),
+		Priority:    priority,
+		CostTier:    tier,
+		Description: fmt.Sprintf("%s on %s: %s", b.manifest.Name, ev.Kind, ev.Title),
+		MaxAttempts: jobMaxAttempts,
+	}, e.runner(b.manifest, b.trigger.Chat, task, params))
+	if err != nil {
+		// Forget the event so a webhook retry or the next poll runs it
+		// instead of being deduplicated.
+		if delErr := e.store.DeleteTriggerEvent(eventID); delErr != nil {
+			log.Printf("[trigger] %s: failed to drop event %d after job start failed: %v", b.id, eventID, delErr)
+		}
+		return "", fmt.Errorf("failed to start job: %w", err)
+	}
+	if err := e.store.SetTriggerEventJob(eventID, job.JobID); err != nil {
+		log.Printf("[trigger] %s: failed to link event %d to job %s: %v", b.id, eventID, job.JobID, err)
+	}
+	log.Printf("[trigger] %s: %s started job %s", b.id, ev.Key, job.JobID)
+	return job.JobID, nil
+}
+
+// seen records items present when a trigger is set up, without running them.
+func (e *Engine) seen(b binding, ev Event) error {
+	_, _, err := e.store.RecordTriggerEvent(storage.TriggerEvent{
+		TriggerID: b.id,
+		EventKey:  ev.Key,
+		Status:    storage.TriggerEventSeen,
+		Title:     ev.Title,
+	})
+	return err
+}
+
+// runner runs the role on task and sends the report to chat.
+func (e *Engine) runner(m *role.Manifest, chat int64, task string, params map[string]any) runtime.JobRunner {
+	return func(ctx context.Context, job *storage.Job, svc *runtime.JobService) (runtime.JobRunResult, error) {
+		if e.run == nil {
+			return runtime.JobRunResult{}, errors.New("role runs are not available")
+		}
+		report, err := e.run(ctx, m, task, params)
+		if err != nil {
+			if ctx.Err() == nil {
+				e.deliver(chat, fmt.Sprintf("⚠️ Role %s failed (job %s): %v", m.Name, job.JobID, err))
+			}
+			return runtime.JobRunResult{}, err
+		}
+		e.deliver(chat, report)
+		return runtime.JobRunResult{Summary: report}, nil
+	}
+}
+
+// rebuildRunner recreates the runner of an interrupted trigger job from the
+// recorded event and the role's current manifest.
+func (e *Engine) rebuildRunner(job *storage.Job) (runtime.JobRunner, error) {
+	var eventID int64
+	if _, err := fmt.Sscanf(job.SessionKey, "trigger:%d", &eventID); err != nil {
+		return nil, fmt.Errorf("job %s is not linked to a trigger event", job.JobID)
+	}
+	ev, err := e.store.GetTriggerEvent(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if ev == nil {
+		return nil, fmt.Errorf("trigger event %d no longer exists", eventID)
+	}
+	state, err := e.store.GetRoleTrigger(ev.TriggerID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, fmt.Errorf("trigger %s no longer exists", ev.TriggerID)
+	}
+	m, err := e.loadRole(state.Role)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRunnable(m, state.Kind); err != nil {
+		return nil, err
+	}
+	var params map[string]any
+	if ev.Params != "" {
+		if err := json.Unmarshal([]byte(ev.Params), &params); err != nil {
+			return nil, fmt.Errorf("trigger event %d: invalid params: %w", eventID, err)
+		}
+	}
+	if params, err = m.BindParams(params); err != nil {
+		return nil, err
+	}
+	return e.runner(m, ev.ChatID, ev.Task, params), nil
+}
+
+func (e *Engine) deliver(chatID int64, message string) {
+	e.mu.Lock()
+	notify := e.notify
+	e.mu.Unlock()
+	if notify != nil && chatID != 0 && strings.TrimSpace(message) != "" {
+		notify(chatID, message)
+	}
+}
+
+// poll runs check now and then every trigger interval until ctx ends.
+func (e *Engine) poll(ctx context.Context, b binding, check func(context.Context, binding) error) {
+	interval := b.trigger.Interval()
+	for {
+		err := check(ctx, b)
+		if ctx.Err() != nil {
+			return
+		}
+		e.setError(b, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// setError records the outcome of a check; nil clears the last error.
+func (e *Engine) setError(b binding, err error) {
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+		log.Printf("[trigger] %s: %v", b.id, err)
+	}
+	if err := e.store.SetRoleTriggerError(b.id, msg); err != nil {
+		log.Printf("[trigger] %s: failed to save state: %v", b.id, err)
+	}
+}
+
+// primed reports whether the trigger recorded its existing items already.
+func (e *Engine) primed(b binding) (bool, error) {
+	state, err := e.store.GetRoleTrigger(b.id)
+	if err != nil {
+		return false, err
+	}
+	return state != nil && state.Primed, nil
+}
+
+func eventSessionKey(eventID int64) string {
+	return fmt.Sprintf("trigger:%d", eventID)
+}
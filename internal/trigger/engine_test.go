@@ -0,0 +1,531 @@
+package trigger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ok-gobot/internal/role"
+	"ok-gobot/internal/runtime"
+	"ok-gobot/internal/storage"
+)
+
+type roleRun struct {
+	role   string
+	task   string
+	params map[string]any
+}
+
+// newTestEngine starts an engine over a workspace holding the given role
+// files. Every role run is sent to the returned channel.
+func newTestEngine(t *testing.T, workspace string, store *storage.Store, roles map[string]string) (*Engine, chan roleRun) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(workspace, role.Dir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range roles {
+		if err := os.WriteFile(filepath.Join(workspace, role.Dir, name+".md"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runs := make(chan roleRun, 10)
+	e := NewEngine(store, runtime.NewJobService(store), workspace, func(ctx context.Context, m *role.Manifest, task string, params map[string]any) (string, error) {
+		runs <- roleRun{role: m.Name, task: task, params: params}
+		return "done", nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		e.Stop()
+		cancel()
+	})
+	if err := e.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	return e, runs
+}
+
+func newTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.New(filepath.Join(t.TempDir(), "trigger-test.db"))
+	if err != nil {
+		t.Fatalf("storage.New: %v", err)
+	}
+	t.Cleanup(func() { store.Close() }) //nolint:errcheck
+	return store
+}
+
+func waitRun(t *testing.T, runs chan roleRun) roleRun {
+	t.Helper()
+	select {
+	case run := <-runs:
+		return run
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for a role run")
+	}
+	return roleRun{}
+}
+
+func expectNoRun(t *testing.T, runs chan roleRun, wait time.Duration) {
+	t.Helper()
+	select {
+	case run := <-runs:
+		t.Fatalf("unexpected role run: %+v", run)
+	case <-time.After(wait):
+	}
+}
+
+func TestWebhookTriggerVerifiesAndDeduplicates(t *testing.T) {
+	t.Setenv("TEST_DEPLOY_HOOK_SECRET", "s3cret")
+
+	e, runs := newTestEngine(t, t.TempDir(), newTestStore(t), map[string]string{
+		"deployer": `---
+tools: [web_fetch]
+triggers:
+  - webhook: ci/deploy
+    secret: $TEST_DEPLOY_HOOK_SECRET
+    task: "Deploy {{.Payload.ref}} ({{.Fields.event}})"
+---
+You deploy things.`,
+	})
+
+	body := []byte(`{"event":"push","ref":"main"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	post := func(path, sig, delivery string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		if sig != "" {
+			req.Header.Set("X-Hub-Signature-256", sig)
+		}
+		if delivery != "" {
+			req.Header.Set("X-Delivery-ID", delivery)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("/hooks/ci/deploy", "", "d1"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unsigned request: status %d, want 401", rec.Code)
+	}
+	if rec := post("/hooks/ci/deploy", "sha256=00ff", "d1"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad signature: status %d, want 401", rec.Code)
+	}
+	if rec := post("/hooks/unknown", signature, "d1"); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown path: status %d, want 404", rec.Code)
+	}
+
+	rec := post("/hooks/ci/deploy", signature, "d1")
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), "job_id") {
+		t.Fatalf("signed request: status %d, body %s", rec.Code, rec.Body)
+	}
+	run := waitRun(t, runs)
+	if run.role != "deployer" || run.task != "Deploy main (push)" {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+
+	// A redelivery with the same ID is acknowledged but not run again.
+	if rec := post("/hooks/ci/deploy", signature, "d1"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "duplicate") {
+		t.Fatalf("redelivery: status %d, body %s", rec.Code, rec.Body)
+	}
+	expectNoRun(t, runs, 200*time.Millisecond)
+
+	// The delivery ID is unsigned, so reusing it with a new body still runs.
+	body = []byte(`{"event":"push","ref":"release"}`)
+	mac = hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if rec := post("/hooks/ci/deploy", signature, "d1"); rec.Code != http.StatusAccepted {
+		t.Fatalf("new body with reused delivery ID: status %d, body %s", rec.Code, rec.Body)
+	}
+	if run := waitRun(t, runs); run.task != "Deploy release (push)" {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+}
+
+func TestWatchTriggerRunsOnNewFilesOnly(t *testing.T) {
+	saved := watchSettle
+	watchSettle = 100 * time.Millisecond
+	defer func() { watchSettle = saved }()
+
+	workspace := t.TempDir()
+	inbox := filepath.Join(workspace, "inbox")
+	if err := os.MkdirAll(inbox, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(inbox, "old.pdf"), []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := newTestStore(t)
+	roles := map[string]string{
+		"filer": `---
+triggers:
+  - watch: inbox/*.pdf
+    task: "File {{.Fields.name}}"
+---
+You file invoices.`,
+	}
+	e, runs := newTestEngine(t, workspace, store, roles)
+
+	// Files present when the trigger is first set up are only recorded.
+	expectNoRun(t, runs, 300*time.Millisecond)
+
+	if err := os.WriteFile(filepath.Join(inbox, "notes.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(inbox, "invoice-1.pdf"), []byte("pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if run := waitRun(t, runs); run.task != "File invoice-1.pdf" {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	expectNoRun(t, runs, 300*time.Millisecond)
+
+	// A file that lands while the daemon is down is picked up on start;
+	// files already handled are not run again.
+	e.Stop()
+	if err := os.WriteFile(filepath.Join(inbox, "invoice-2.pdf"), []byte("pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, runs = newTestEngine(t, workspace, store, roles)
+	if run := waitRun(t, runs); run.task != "File invoice-2.pdf" {
+		t.Fatalf("unexpected run after restart: %+v", run)
+	}
+	expectNoRun(t, runs, 300*time.Millisecond)
+}
+
+func TestFeedTriggerRunsOnNewItems(t *testing.T) {
+	var mu sync.Mutex
+	items := `<item><guid>a</guid><title>First</title><link>https://example.com/a</link></item>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Releases</title>%s</channel></rss>`, items)
+	}))
+	defer srv.Close()
+
+	store := newTestStore(t)
+	e, runs := newTestEngine(t, t.TempDir(), store, nil)
+	b := binding{
+		id:       "releases:feed:" + srv.URL,
+		manifest: &role.Manifest{Name: "releases"},
+		trigger:  role.Trigger{Feed: srv.URL},
+	}
+	if err := store.EnsureRoleTrigger(storage.RoleTrigger{TriggerID: b.id, Role: "releases", Kind: role.TriggerFeed}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.checkFeed(context.Background(), b); err != nil {
+		t.Fatalf("first check: %v", err)
+	}
+	expectNoRun(t, runs, 100*time.Millisecond)
+
+	mu.Lock()
+	items = `<item><guid>b</guid><title>Second</title><link>https://example.com/b</link><description>Notes</description></item>` + items
+	mu.Unlock()
+	if err := e.checkFeed(context.Background(), b); err != nil {
+		t.Fatalf("second check: %v", err)
+	}
+	run := waitRun(t, runs)
+	if !strings.Contains(run.task, "Releases") || !strings.Contains(run.task, "Second") || !strings.Contains(run.task, "https://example.com/b") {
+		t.Fatalf("unexpected task: %q", run.task)
+	}
+
+	if err := e.checkFeed(context.Background(), b); err != nil {
+		t.Fatalf("third check: %v", err)
+	}
+	expectNoRun(t, runs, 100*time.Millisecond)
+
+	events, err := store.ListTriggerEvents(b.id, 10)
+	if err != nil || len(events) != 1 || events[0].EventKey != "b" || events[0].JobID == "" {
+		t.Fatalf("events = %+v, %v", events, err)
+	}
+}
+
+// fakeIMAPServer answers one connection per check from a mailbox whose
+// messages can grow between checks.
+type fakeIMAPServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	messages map[uint64]string // UID -> subject
+}
+
+func newFakeIMAPServer(t *testing.T) *fakeIMAPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeIMAPServer{ln: ln, messages: map[uint64]string{3: "Old"}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeIMAPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, command, _ := strings.Cut(strings.TrimSpace(line), " ")
+
+		s.mu.Lock()
+		var max uint64
+		for uid := range s.messages {
+			if uid > max {
+				max = uid
+			}
+		}
+		switch {
+		case strings.HasPrefix(command, "LOGIN"):
+			if command != `LOGIN "bot@example.com" "pw"` {
+				fmt.Fprintf(conn, "%s NO bad credentials\r\n", tag)
+				s.mu.Unlock()
+				continue
+			}
+		case strings.HasPrefix(command, "SELECT"):
+			fmt.Fprintf(conn, "* %d EXISTS\r\n* OK [UIDVALIDITY 7] ok\r\n* OK [UIDNEXT %d] ok\r\n", len(s.messages), max+1)
+		case strings.HasPrefix(command, "UID SEARCH UID "):
+			var from uint64
+			fmt.Sscanf(strings.TrimPrefix(command, "UID SEARCH UID "), "%d:*", &from)
+			fmt.Fprint(conn, "* SEARCH")
+			for uid := range s.messages {
+				if uid >= from || uid == max {
+					fmt.Fprintf(conn, " %d", uid)
+				}
+			}
+			fmt.Fprint(conn, "\r\n")
+		case strings.HasPrefix(command, "UID FETCH "):
+			var uid uint64
+			fmt.Sscanf(strings.TrimPrefix(command, "UID FETCH "), "%d", &uid)
+			hdr := fmt.Sprintf("From: Alice <alice@example.com>\r\nSubject: %s\r\n\r\n", s.messages[uid])
+			fmt.Fprintf(conn, "* 1 FETCH (UID %d BODY[HEADER.FIELDS (FROM TO SUBJECT DATE MESSAGE-ID)] {%d}\r\n%s)\r\n", uid, len(hdr), hdr)
+		case command == "LOGOUT":
+			fmt.Fprintf(conn, "* BYE\r\n%s OK bye\r\n", tag)
+			s.mu.Unlock()
+			return
+		}
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+		s.mu.Unlock()
+	}
+}
+
+func TestIMAPTriggerRunsOnNewMail(t *testing.T) {
+	t.Setenv("TEST_IMAP_PASSWORD", "pw")
+	srv := newFakeIMAPServer(t)
+	port := srv.ln.Addr().(*net.TCPAddr).Port
+
+	store := newTestStore(t)
+	e, runs := newTestEngine(t, t.TempDir(), store, nil)
+	tr := role.Trigger{IMAP: &role.IMAPSource{
+		Server:   "127.0.0.1",
+		Port:     port,
+		Username: "bot@example.com",
+		Password: "${TEST_IMAP_PASSWORD}",
+		Plain:    true,
+	}}
+	b := binding{id: tr.ID("mail"), manifest: &role.Manifest{Name: "mail"}, trigger: tr}
+	if err := store.EnsureRoleTrigger(storage.RoleTrigger{TriggerID: b.id, Role: "mail", Kind: role.TriggerIMAP}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first check only records where the mailbox is.
+	if err := e.checkIMAP(context.Background(), b); err != nil {
+		t.Fatalf("first check: %v", err)
+	}
+	if state, _ := store.GetRoleTrigger(b.id); state == nil || state.Cursor != "7:3" || !state.Primed {
+		t.Fatalf("state after first check = %+v", state)
+	}
+	// Nothing new: the server still answers "4:*" with the newest message.
+	if err := e.checkIMAP(context.Background(), b); err != nil {
+		t.Fatalf("second check: %v", err)
+	}
+	expectNoRun(t, runs, 100*time.Millisecond)
+
+	srv.mu.Lock()
+	srv.messages[4] = "=?UTF-8?Q?Invoice_=E2=84=968?="
+	srv.mu.Unlock()
+	if err := e.checkIMAP(context.Background(), b); err != nil {
+		t.Fatalf("third check: %v", err)
+	}
+	run := waitRun(t, runs)
+	if !strings.Contains(run.task, "From: Alice <alice@example.com>") || !strings.Contains(run.task, "Subject: Invoice №8") {
+		t.Fatalf("unexpected task: %q", run.task)
+	}
+	if state, _ := store.GetRoleTrigger(b.id); state.Cursor != "7:4" {
+		t.Fatalf("cursor = %q, want 7:4", state.Cursor)
+	}
+}
+
+func TestScheduleTriggerBindsParams(t *testing.T) {
+	store := newTestStore(t)
+	e, runs := newTestEngine(t, t.TempDir(), store, nil)
+
+	m, err := role.Parse("uptime", []byte(`---
+params:
+  host: {type: string, required: true}
+  port: {type: int, default: 443}
+triggers:
+  - schedule: "0 9 * * *"
+    params: {host: db1.internal, port: "5432"}
+---
+Check {{.Params.host}}:{{.Params.port}}.`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	tr := m.Triggers[0]
+	b := binding{id: tr.ID(m.Name), manifest: m, trigger: tr}
+	if err := store.EnsureRoleTrigger(storage.RoleTrigger{TriggerID: b.id, Role: m.Name, Kind: tr.Kind()}); err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2026, 3, 4, 9, 0, 0, 0, time.UTC)
+	if _, err := e.dispatch(b, scheduleEvent(at)); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	run := waitRun(t, runs)
+	if run.params["host"] != "db1.internal" || run.params["port"] != int64(5432) {
+		t.Fatalf("params = %#v", run.params)
+	}
+	if !strings.Contains(run.task, "Scheduled run at 2026-03-04T09:00:00Z") {
+		t.Fatalf("task = %q", run.task)
+	}
+
+	// The same fire is not run twice.
+	if jobID, err := e.dispatch(b, scheduleEvent(at)); err != nil || jobID != "" {
+		t.Fatalf("repeat dispatch = %q, %v", jobID, err)
+	}
+	expectNoRun(t, runs, 100*time.Millisecond)
+}
+
+func TestRunRoleBindsParams(t *testing.T) {
+	e, runs := newTestEngine(t, t.TempDir(), newTestStore(t), map[string]string{
+		"base": `---
+tools: [web_fetch]
+params:
+  host: {type: string, required: true}
+---
+You check servers.`,
+		"uptime": `---
+extends: base
+params:
+  checks: {type: list, default: [ping]}
+---
+Check {{.Params.host}} with {{range .Params.checks}}{{.}} {{end}}`,
+	})
+
+	if _, err := e.RunRole("uptime", "", nil, 0); err == nil || !strings.Contains(err.Error(), "host is required") {
+		t.Fatalf("RunRole without host: %v", err)
+	}
+	if _, err := e.RunRole("nope", "", nil, 0); !errors.Is(err, role.ErrNotFound) {
+		t.Fatalf("RunRole(nope) = %v, want role.ErrNotFound", err)
+	}
+
+	plan, err := e.PlanRole("uptime", "be quick", map[string]any{"host": "web1", "checks": "ping, http"})
+	if err != nil {
+		t.Fatalf("PlanRole: %v", err)
+	}
+	if plan.Prompt != "You check servers.\n\nCheck web1 with ping http" || len(plan.Tools) != 1 {
+		t.Fatalf("plan = %+v", plan)
+	}
+
+	jobID, err := e.RunRole("uptime", "be quick", map[string]any{"host": "web1"}, 0)
+	if err != nil || jobID == "" {
+		t.Fatalf("RunRole = %q, %v", jobID, err)
+	}
+	run := waitRun(t, runs)
+	if run.role != "uptime" || run.task != "be quick" || run.params["host"] != "web1" {
+		t.Fatalf("run = %+v", run)
+	}
+}
+
+func TestUnsafeRolesAreNotTriggered(t *testing.T) {
+	store := newTestStore(t)
+	e, _ := newTestEngine(t, t.TempDir(), store, map[string]string{
+		"open": `---
+triggers:
+  - webhook: ci/open
+    secret: s3cret
+---
+You react to CI.`,
+		"gated": `---
+tools: [web_fetch]
+approval: always
+---
+You ask first.`,
+	})
+
+	if e.HasWebhooks() {
+		t.Fatal("webhook of a role without a tools list was loaded")
+	}
+	state, err := store.GetRoleTrigger((&role.Trigger{Webhook: "ci/open"}).ID("open"))
+	if err != nil || state == nil || !strings.Contains(state.LastError, "tools") {
+		t.Fatalf("trigger state = %+v, %v", state, err)
+	}
+	if _, err := e.RunRole("gated", "go", nil, 0); err == nil || !strings.Contains(err.Error(), "approval") {
+		t.Fatalf("RunRole(gated) = %v, want an approval error", err)
+	}
+}
+
+func TestSaveAndDeleteRoleReloadTriggers(t *testing.T) {
+	e, _ := newTestEngine(t, t.TempDir(), newTestStore(t), nil)
+	if e.HasWebhooks() {
+		t.Fatal("HasWebhooks before any role")
+	}
+
+	if _, _, err := e.SaveRole("hooked", "---\ntriggers:\n  - webhook: [bad\n---\nX."); err == nil {
+		t.Fatal("SaveRole with invalid frontmatter: expected error")
+	}
+	_, created, err := e.SaveRole("hooked", "---\ntools: [web_fetch]\ntriggers:\n  - webhook: ci/hooked\n    secret: s3cret\n---\nYou react to CI.")
+	if err != nil || !created {
+		t.Fatalf("SaveRole = created %v, %v", created, err)
+	}
+	if !e.HasWebhooks() {
+		t.Fatal("webhook trigger not loaded after SaveRole")
+	}
+	if src, err := e.RoleSource("hooked"); err != nil || !strings.Contains(src, "ci/hooked") {
+		t.Fatalf("RoleSource = %q, %v", src, err)
+	}
+	if roles, errs := e.Roles(); len(roles) != 1 || len(errs) != 0 {
+		t.Fatalf("Roles = %v, %v", roles, errs)
+	}
+
+	if err := e.DeleteRole("hooked"); err != nil {
+		t.Fatalf("DeleteRole: %v", err)
+	}
+	if e.HasWebhooks() {
+		t.Fatal("webhook trigger still loaded after DeleteRole")
+	}
+	if err := e.DeleteRole("hooked"); !errors.Is(err, role.ErrNotFound) {
+		t.Fatalf("DeleteRole again = %v, want role.ErrNotFound", err)
+	}
+}
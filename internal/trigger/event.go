@@ -0,0 +1,90 @@
+package trigger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"ok-gobot/internal/role"
+)
+
+// maxEventBody caps the event text handed to the role.
+const maxEventBody = 16000
+
+// Event is one thing a trigger noticed. Trigger task templates are executed
+// against it.
+type Event struct {
+	Kind  string // a role.Trigger kind, or manualKind
+	Key   string // de-duplication key, unique per trigger
+	Title string
+	Body  string
+	// Fields holds kind-specific values: path, name, size (watch); link,
+	// feed, published (feed); from, to, subject, date, message_id (imap);
+	// path, event, delivery (webhook); time (schedule).
+	Fields map[string]string
+	// Payload is the decoded JSON body of a webhook, if it had one.
+	Payload any
+	Time    time.Time
+}
+
+// defaultTasks describe an event when the trigger has no task template.
+var defaultTasks = map[string]string{
+	role.TriggerWebhook:  "A webhook arrived at {{.Fields.path}}{{with .Fields.event}} ({{.}}){{end}}.\n\n{{.Body}}",
+	role.TriggerWatch:    "A file was added or changed: {{.Fields.path}} ({{.Fields.size}} bytes).",
+	role.TriggerFeed:     "A new item was published in {{.Fields.feed}}:\n\n{{.Title}}\n{{.Fields.link}}\n\n{{.Body}}",
+	role.TriggerIMAP:     "A new email arrived.\n\nFrom: {{.Fields.from}}\nTo: {{.Fields.to}}\nDate: {{.Fields.date}}\nSubject: {{.Fields.subject}}",
+	role.TriggerSchedule: "Scheduled run at {{.Fields.time}}.",
+}
+
+// renderTask builds the role's task for ev from the trigger's template.
+func renderTask(t role.Trigger, ev Event) (string, error) {
+	text := t.Task
+	if text == "" {
+		text = defaultTasks[ev.Kind]
+	}
+	tmpl, err := template.New("task").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid task template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ev); err != nil {
+		return "", fmt.Errorf("task template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// renderParams executes the trigger's param templates against ev.
+func renderParams(t role.Trigger, ev Event) (map[string]any, error) {
+	values := make(map[string]any, len(t.Params))
+	for name, text := range t.Params {
+		tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for param %s: %w", name, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, ev); err != nil {
+			return nil, fmt.Errorf("param %s: %w", name, err)
+		}
+		values[name] = buf.String()
+	}
+	return values, nil
+}
+
+// truncateTitle shortens a run title for job descriptions.
+func truncateTitle(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= 80 {
+		return s
+	}
+	return s[:77] + "..."
+}
+
+// truncateBody shortens event text to maxEventBody bytes.
+func truncateBody(s string) string {
+	if len(s) <= maxEventBody {
+		return s
+	}
+	return s[:maxEventBody] + "\n...(truncated)"
+}
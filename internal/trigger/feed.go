@@ -0,0 +1,165 @@
+package trigger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"ok-gobot/internal/role"
+)
+
+const maxFeedBody = 5 << 20
+
+type rssFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Channel struct {
+		Title string `xml:"title"`
+		Items []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			GUID        string `xml:"guid"`
+			PubDate     string `xml:"pubDate"`
+			Description string `xml:"description"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name `xml:"feed"`
+	Title   string   `xml:"title"`
+	Entries []struct {
+		ID        string `xml:"id"`
+		Title     string `xml:"title"`
+		Updated   string `xml:"updated"`
+		Published string `xml:"published"`
+		Summary   string `xml:"summary"`
+		Content   string `xml:"content"`
+		Links     []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// checkFeed fetches a feed trigger's URL and runs the role on entries it has
+// not seen, oldest first.
+func (e *Engine) checkFeed(ctx context.Context, b binding) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.trigger.Feed, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "ok-gobot")
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.5")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch feed: HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBody))
+	if err != nil {
+		return fmt.Errorf("read feed: %w", err)
+	}
+	items, err := parseFeed(b.trigger.Feed, body)
+	if err != nil {
+		return err
+	}
+
+	primed, err := e.primed(b)
+	if err != nil {
+		return err
+	}
+	started := 0
+	for i := len(items) - 1; i >= 0; i-- { // feeds list newest first
+		ev := items[i]
+		if !primed || started >= maxEventsPerCheck {
+			err = e.seen(b, ev)
+		} else {
+			var jobID string
+			jobID, err = e.dispatch(b, ev)
+			if jobID != "" {
+				started++
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !primed {
+		return e.store.SetRoleTriggerCursor(b.id, "")
+	}
+	return nil
+}
+
+// parseFeed reads RSS 2.0 items or Atom entries as events, in feed order.
+func parseFeed(feedURL string, body []byte) ([]Event, error) {
+	var events []Event
+	add := func(feedTitle, id, title, link, date, summary string) {
+		key := strings.TrimSpace(id)
+		if key == "" {
+			key = strings.TrimSpace(link)
+		}
+		if key == "" {
+			sum := sha256.Sum256([]byte(title + "\x00" + date))
+			key = "sha256:" + hex.EncodeToString(sum[:])
+		}
+		events = append(events, Event{
+			Kind:  role.TriggerFeed,
+			Key:   key,
+			Title: strings.TrimSpace(title),
+			Body:  truncateBody(strings.TrimSpace(summary)),
+			Time:  time.Now(),
+			Fields: map[string]string{
+				"feed":      strings.TrimSpace(feedTitle),
+				"feed_url":  feedURL,
+				"link":      strings.TrimSpace(link),
+				"published": strings.TrimSpace(date),
+			},
+		})
+	}
+
+	var rss rssFeed
+	var atom atomFeed
+	switch {
+	case xml.Unmarshal(body, &rss) == nil:
+		for _, it := range rss.Channel.Items {
+			add(rss.Channel.Title, it.GUID, it.Title, it.Link, it.PubDate, it.Description)
+		}
+	case xml.Unmarshal(body, &atom) == nil:
+		for _, en := range atom.Entries {
+			link := ""
+			for _, l := range en.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = l.Href
+					break
+				}
+			}
+			date := en.Published
+			if date == "" {
+				date = en.Updated
+			}
+			summary := en.Summary
+			if summary == "" {
+				summary = en.Content
+			}
+			add(atom.Title, en.ID, en.Title, link, date, summary)
+		}
+	default:
+		return nil, fmt.Errorf("%s is not an RSS or Atom feed", feedURL)
+	}
+
+	for i := range events { // untitled feeds go by their URL
+		if events[i].Fields["feed"] == "" {
+			events[i].Fields["feed"] = feedURL
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,320 @@
+package trigger
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ok-gobot/internal/role"
+	"ok-gobot/internal/storage"
+)
+
+const imapTimeout = time.Minute
+
+var (
+	imapLiteralRe = regexp.MustCompile(`\{(\d+)\}$`)
+	imapCodeRe    = regexp.MustCompile(`\[(UIDVALIDITY|UIDNEXT) (\d+)\]`)
+)
+
+// checkIMAP looks for mail newer than the trigger's cursor (UIDVALIDITY and
+// last UID) and runs the role on each message, oldest first.
+func (e *Engine) checkIMAP(ctx context.Context, b binding) error {
+	src := b.trigger.IMAP
+	folder := src.Folder
+	if folder == "" {
+		folder = "INBOX"
+	}
+
+	c, err := dialIMAP(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer c.close()
+
+	if _, err := c.cmd("LOGIN %s %s", imapQuote(src.Username), imapQuote(src.PasswordValue())); err != nil {
+		return fmt.Errorf("imap login: %w", err)
+	}
+	lines, err := c.cmd("SELECT %s", imapQuote(folder))
+	if err != nil {
+		return fmt.Errorf("imap select %s: %w", folder, err)
+	}
+	var validity, next uint64
+	for _, l := range lines {
+		for _, m := range imapCodeRe.FindAllStringSubmatch(l.text, -1) {
+			n, _ := strconv.ParseUint(m[2], 10, 64)
+			if m[1] == "UIDVALIDITY" {
+				validity = n
+			} else {
+				next = n
+			}
+		}
+	}
+
+	state, err := e.store.GetRoleTrigger(b.id)
+	if err != nil {
+		return err
+	}
+	cursorValidity, last := parseIMAPCursor(state)
+	if state == nil || !state.Primed || cursorValidity != validity {
+		if state != nil && state.Primed {
+			log.Printf("[trigger] %s: mailbox UIDVALIDITY changed; starting from the current mail", b.id)
+		}
+		if next == 0 {
+			// No UIDNEXT: ask for the highest UID instead.
+			uids, err := c.search("UID SEARCH ALL")
+			if err != nil {
+				return err
+			}
+			if len(uids) > 0 {
+				next = uids[len(uids)-1] + 1
+			}
+		}
+		if next > 0 {
+			next--
+		}
+		return e.store.SetRoleTriggerCursor(b.id, formatIMAPCursor(validity, next))
+	}
+
+	uids, err := c.search(fmt.Sprintf("UID SEARCH UID %d:*", last+1))
+	if err != nil {
+		return err
+	}
+	started := 0
+	for _, uid := range uids {
+		if uid <= last {
+			continue // "n:*" matches the newest message even below n
+		}
+		if started >= maxEventsPerCheck {
+			break // the rest wait for the next check
+		}
+		ev, err := c.fetchHeaders(uid, validity, folder)
+		if err != nil {
+			return err
+		}
+		if _, err := e.dispatch(b, ev); err != nil {
+			return err
+		}
+		started++
+		if err := e.store.SetRoleTriggerCursor(b.id, formatIMAPCursor(validity, uid)); err != nil {
+			return err
+		}
+	}
+	c.cmd("LOGOUT") //nolint:errcheck
+	return nil
+}
+
+// parseIMAPCursor reads "uidvalidity:last-uid" from the trigger state.
+func parseIMAPCursor(state *storage.RoleTrigger) (validity, last uint64) {
+	if state == nil {
+		return 0, 0
+	}
+	v, u, ok := strings.Cut(state.Cursor, ":")
+	if !ok {
+		return 0, 0
+	}
+	validity, _ = strconv.ParseUint(v, 10, 64)
+	last, _ = strconv.ParseUint(u, 10, 64)
+	return validity, last
+}
+
+func formatIMAPCursor(validity, uid uint64) string {
+	return fmt.Sprintf("%d:%d", validity, uid)
+}
+
+// imapConn is a minimal IMAP4rev1 client: enough to log in, select a folder,
+// search by UID and fetch headers.
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapLine is one response line with its literals ({n} blocks) split out.
+type imapLine struct {
+	text     string
+	literals []string
+}
+
+func dialIMAP(ctx context.Context, src *role.IMAPSource) (*imapConn, error) {
+	port := src.Port
+	if port == 0 {
+		port = 993
+		if src.Plain {
+			port = 143
+		}
+	}
+	addr := net.JoinHostPort(src.Server, strconv.Itoa(port))
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if src.Plain {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: src.Server}}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("imap connect %s: %w", addr, err)
+	}
+	deadline := time.Now().Add(imapTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline) //nolint:errcheck
+
+	c := &imapConn{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readLine()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting.text, "* OK") && !strings.HasPrefix(greeting.text, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("imap greeting: %s", greeting.text)
+	}
+	return c, nil
+}
+
+func (c *imapConn) close() {
+	c.conn.Close()
+}
+
+// cmd sends a tagged command and returns the untagged lines of its
+// response, or an error unless it completes with OK.
+func (c *imapConn) cmd(format string, args ...any) ([]imapLine, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%03d", c.tag)
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, err
+	}
+
+	var lines []imapLine
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if rest, ok := strings.CutPrefix(line.text, tag+" "); ok {
+			if !strings.HasPrefix(rest, "OK") {
+				return nil, fmt.Errorf("%s", rest)
+			}
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+// readLine reads one response line, including any literals it carries.
+func (c *imapConn) readLine() (imapLine, error) {
+	var line imapLine
+	var text strings.Builder
+	for {
+		part, err := c.r.ReadString('\n')
+		if err != nil {
+			return line, err
+		}
+		part = strings.TrimRight(part, "\r\n")
+		m := imapLiteralRe.FindStringSubmatch(part)
+		if m == nil {
+			text.WriteString(part)
+			line.text = text.String()
+			return line, nil
+		}
+		n, _ := strconv.Atoi(m[1])
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return line, err
+		}
+		text.WriteString(part)
+		line.literals = append(line.literals, string(buf))
+	}
+}
+
+// search runs a UID SEARCH and returns the UIDs in ascending order.
+func (c *imapConn) search(command string) ([]uint64, error) {
+	lines, err := c.cmd("%s", command)
+	if err != nil {
+		return nil, fmt.Errorf("imap search: %w", err)
+	}
+	var uids []uint64
+	for _, l := range lines {
+		rest, ok := strings.CutPrefix(l.text, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, f := range strings.Fields(rest) {
+			if n, err := strconv.ParseUint(f, 10, 64); err == nil {
+				uids = append(uids, n)
+			}
+		}
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	return uids, nil
+}
+
+// fetchHeaders reads a message's envelope headers without marking it seen.
+func (c *imapConn) fetchHeaders(uid, validity uint64, folder string) (Event, error) {
+	lines, err := c.cmd("UID FETCH %d (BODY.PEEK[HEADER.FIELDS (FROM TO SUBJECT DATE MESSAGE-ID)])", uid)
+	if err != nil {
+		return Event{}, fmt.Errorf("imap fetch %d: %w", uid, err)
+	}
+	raw := ""
+	for _, l := range lines {
+		if len(l.literals) > 0 {
+			raw = l.literals[0]
+			break
+		}
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(strings.TrimRight(raw, "\r\n") + "\r\n\r\n"))
+	if err != nil {
+		return Event{}, fmt.Errorf("imap fetch %d: %w", uid, err)
+	}
+
+	dec := new(mime.WordDecoder)
+	header := func(name string) string {
+		v := msg.Header.Get(name)
+		if decoded, err := dec.DecodeHeader(v); err == nil {
+			v = decoded
+		}
+		return strings.TrimSpace(v)
+	}
+	fields := map[string]string{
+		"from":       header("From"),
+		"to":         header("To"),
+		"subject":    header("Subject"),
+		"date":       header("Date"),
+		"message_id": header("Message-Id"),
+		"uid":        strconv.FormatUint(uid, 10),
+		"folder":     folder,
+	}
+	title := fields["subject"]
+	if title == "" {
+		title = "(no subject)"
+	}
+	return Event{
+		Kind:   role.TriggerIMAP,
+		Key:    formatIMAPCursor(validity, uid),
+		Title:  title,
+		Body:   fmt.Sprintf("From: %s\nTo: %s\nDate: %s\nSubject: %s", fields["from"], fields["to"], fields["date"], fields["subject"]),
+		Fields: fields,
+		Time:   time.Now(),
+	}, nil
+}
+
+// imapQuote renders s as an IMAP quoted string.
+func imapQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
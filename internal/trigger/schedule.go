@@ -0,0 +1,41 @@
+package trigger
+
+import (
+	"context"
+	"time"
+
+	"ok-gobot/internal/role"
+)
+
+// schedule starts the role at each time its cron expression matches until
+// ctx ends. Fires missed while the daemon was down are not made up.
+func (e *Engine) schedule(ctx context.Context, b binding) {
+	sched, err := role.ParseSchedule(b.trigger.Schedule)
+	if err != nil {
+		e.setError(b, err)
+		return
+	}
+	for {
+		next := sched.Next(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		_, err := e.dispatch(b, scheduleEvent(next))
+		e.setError(b, err)
+	}
+}
+
+// scheduleEvent describes one fire. Its time is the key, so a fire is never
+// run twice.
+func scheduleEvent(at time.Time) Event {
+	stamp := at.Format(time.RFC3339)
+	return Event{
+		Kind:   role.TriggerSchedule,
+		Key:    at.UTC().Format(time.RFC3339),
+		Title:  "scheduled run at " + stamp,
+		Time:   at,
+		Fields: map[string]string{"time": stamp},
+	}
+}
@@ -0,0 +1,152 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"ok-gobot/internal/role"
+)
+
+// watchSettle is how long a file must stay unchanged before it counts as
+// added, so a download still being written is picked up once, complete.
+var watchSettle = 2 * time.Second
+
+// watch follows a watch trigger's directory until ctx ends. Matching files
+// that appeared while nothing was watching are picked up first.
+func (e *Engine) watch(ctx context.Context, b binding) {
+	pattern := e.resolvePath(b.trigger.Watch)
+	dir, glob := filepath.Dir(pattern), filepath.Base(pattern)
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		e.setError(b, fmt.Errorf("failed to create watcher: %w", err))
+		return
+	}
+	defer w.Close()
+	if err := w.Add(dir); err != nil {
+		e.setError(b, fmt.Errorf("cannot watch %s: %w", dir, err))
+		return
+	}
+	e.setError(b, e.scanWatch(b, pattern))
+
+	settled := make(chan string)
+	timers := make(map[string]*time.Timer)
+	defer func() {
+		for _, t := range timers {
+			t.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Write) {
+				continue
+			}
+			if match, _ := filepath.Match(glob, filepath.Base(ev.Name)); !match {
+				continue
+			}
+			path := ev.Name
+			if t, ok := timers[path]; ok {
+				t.Reset(watchSettle)
+				continue
+			}
+			timers[path] = time.AfterFunc(watchSettle, func() {
+				select {
+				case settled <- path:
+				case <-ctx.Done():
+				}
+			})
+		case path := <-settled:
+			delete(timers, path)
+			if ev, ok := fileEvent(path); ok {
+				if _, err := e.dispatch(b, ev); err != nil {
+					e.setError(b, err)
+				}
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			e.setError(b, err)
+		}
+	}
+}
+
+// scanWatch records the files matching pattern: as seen the first time the
+// trigger runs, as events afterwards (de-duplication drops known ones).
+func (e *Engine) scanWatch(b binding, pattern string) error {
+	primed, err := e.primed(b)
+	if err != nil {
+		return err
+	}
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	for _, path := range matches {
+		ev, ok := fileEvent(path)
+		if !ok {
+			continue
+		}
+		if !primed {
+			err = e.seen(b, ev)
+		} else {
+			_, err = e.dispatch(b, ev)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !primed {
+		return e.store.SetRoleTriggerCursor(b.id, "")
+	}
+	return nil
+}
+
+// fileEvent describes a regular file. Its path, size and modification time
+// form the key, so a file that changes fires again.
+func fileEvent(path string) (Event, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return Event{}, false
+	}
+	return Event{
+		Kind:  role.TriggerWatch,
+		Key:   fmt.Sprintf("%s@%d:%d", path, info.Size(), info.ModTime().UnixNano()),
+		Title: filepath.Base(path),
+		Body:  path,
+		Time:  info.ModTime(),
+		Fields: map[string]string{
+			"path": path,
+			"name": filepath.Base(path),
+			"dir":  filepath.Dir(path),
+			"size": strconv.FormatInt(info.Size(), 10),
+		},
+	}, true
+}
+
+// resolvePath expands ~/ and anchors relative paths in the workspace.
+func (e *Engine) resolvePath(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			p = filepath.Join(home, strings.TrimPrefix(p, "~"))
+		}
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(e.workspace, p)
+	}
+	return filepath.Clean(p)
+}
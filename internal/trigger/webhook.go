@@ -0,0 +1,146 @@
+package trigger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ok-gobot/internal/role"
+)
+
+// WebhookPrefix is the URL path webhook triggers are served under.
+const WebhookPrefix = "/hooks/"
+
+const maxWebhookBody = 1 << 20
+
+// signatureHeaders carry the hex HMAC-SHA256 of the body, optionally
+// prefixed with "sha256=" (GitHub style).
+var signatureHeaders = []string{"X-Hub-Signature-256", "X-Signature-256", "X-Signature"}
+
+// deliveryHeaders carry a sender-assigned delivery ID used to drop retries.
+var deliveryHeaders = []string{"X-GitHub-Delivery", "X-Delivery-ID", "X-Request-ID", "Idempotency-Key"}
+
+// ServeHTTP receives webhook triggers at /hooks/<path>. Requests must be
+// signed with the trigger's secret; a delivery seen before is acknowledged
+// without running again.
+func (e *Engine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeStatus(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, WebhookPrefix), "/")
+
+	e.mu.Lock()
+	b, ok := e.hooks[path]
+	e.mu.Unlock()
+	if !ok {
+		writeStatus(w, http.StatusNotFound, map[string]string{"error": "unknown webhook"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		writeStatus(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "body too large"})
+		return
+	}
+	if !verifySignature(b.trigger.SecretValue(), body, r.Header) {
+		log.Printf("[trigger] %s: rejected request with a bad signature", b.id)
+		writeStatus(w, http.StatusUnauthorized, map[string]string{"error": "invalid signature"})
+		return
+	}
+
+	jobID, err := e.dispatch(b, webhookEvent(path, r.Header, body))
+	if err != nil {
+		log.Printf("[trigger] %s: %v", b.id, err)
+		writeStatus(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if jobID == "" {
+		writeStatus(w, http.StatusOK, map[string]string{"status": "duplicate"})
+		return
+	}
+	writeStatus(w, http.StatusAccepted, map[string]string{"status": "started", "job_id": jobID})
+}
+
+// verifySignature checks the body's HMAC-SHA256 against the signature
+// headers. An empty secret (e.g. an unset environment variable) rejects
+// everything.
+func verifySignature(secret string, body []byte, header http.Header) bool {
+	if secret == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	want := mac.Sum(nil)
+
+	for _, name := range signatureHeaders {
+		sig := strings.TrimSpace(header.Get(name))
+		if sig == "" {
+			continue
+		}
+		got, err := hex.DecodeString(strings.TrimPrefix(sig, "sha256="))
+		if err != nil {
+			return false
+		}
+		return hmac.Equal(got, want)
+	}
+	return false
+}
+
+// webhookEvent describes a signed request. The hash of the body, with the
+// delivery ID when there is one, is the de-duplication key.
+func webhookEvent(path string, header http.Header, body []byte) Event {
+	ev := Event{
+		Kind:  role.TriggerWebhook,
+		Title: "webhook " + WebhookPrefix + path,
+		Body:  truncateBody(string(body)),
+		Time:  time.Now(),
+		Fields: map[string]string{
+			"path":         WebhookPrefix + path,
+			"content_type": header.Get("Content-Type"),
+		},
+	}
+
+	// Delivery headers are not covered by the signature, so the body hash
+	// is always part of the key: a reused ID cannot suppress a new body.
+	sum := sha256.Sum256(body)
+	ev.Key = "sha256:" + hex.EncodeToString(sum[:])
+	for _, name := range deliveryHeaders {
+		if id := strings.TrimSpace(header.Get(name)); id != "" {
+			ev.Key = "delivery:" + id + ":" + ev.Key
+			ev.Fields["delivery"] = id
+			break
+		}
+	}
+
+	var payload any
+	if json.Unmarshal(body, &payload) == nil {
+		ev.Payload = payload
+	}
+	event := header.Get("X-GitHub-Event")
+	if obj, ok := payload.(map[string]any); ok && event == "" {
+		for _, k := range []string{"event", "type", "action"} {
+			if s, ok := obj[k].(string); ok && s != "" {
+				event = s
+				break
+			}
+		}
+	}
+	if event != "" {
+		ev.Fields["event"] = event
+		ev.Title = event + " via " + WebhookPrefix + path
+	}
+	return ev
+}
+
+func writeStatus(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body) //nolint:errcheck
+}
@@ -30,6 +30,8 @@ var commandCompletions = []completionCommand{
 	{name: "/new", description: "start new session"},
 	{name: "/abort", description: "abort active run"},
 	{name: "/stop", description: "alias for /abort"},
+	{name: "/undo", description: "undo file changes of the last run"},
+	{name: "/checkpoints", description: "list undoable file changes"},
 }
 
 func (m *Model) updateCompletion() {
@@ -46,7 +46,8 @@ type chatEntry struct {
 	toolArgs  string
 	toolRes   string
 	toolErr   string
-	streaming bool // true while tokens are still arriving
+	toolFiles []string // artifacts produced by the tool (e.g. run_code charts)
+	streaming bool     // true while tokens are still arriving
 	timestamp time.Time
 	model     string
 	tokens    int
@@ -662,6 +663,9 @@ func (m *Model) handleEvent(msg controlserver.ServerMsg) tea.Cmd {
 			if m.entries[i].role == "tool" && m.entries[i].toolName == msg.ToolName {
 				m.entries[i].toolRes = msg.ToolResult
 				m.entries[i].toolErr = msg.ToolError
+				for _, a := range msg.Artifacts {
+					m.entries[i].toolFiles = append(m.entries[i].toolFiles, strings.TrimPrefix(a.URI, "file://"))
+				}
 				break
 			}
 		}
@@ -677,6 +681,19 @@ func (m *Model) handleEvent(msg controlserver.ServerMsg) tea.Cmd {
 		m.approvalSel = 0
 		m.screen = screenApproval
 
+	case controlserver.KindQuestion:
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "❓ Job %s asks (%s):\n\n%s", msg.Message, msg.QuestionID, msg.Content)
+		for i, choice := range msg.Choices {
+			fmt.Fprintf(&sb, "\n  %d. %s", i+1, choice)
+		}
+		sb.WriteString("\n\nAnswer with /answer <text>")
+		if len(msg.Choices) > 0 {
+			sb.WriteString(" or /answer <number>")
+		}
+		m.addEntry(chatEntry{role: "system", content: sb.String()})
+		m.refreshViewport()
+
 	case controlserver.KindChildDone:
 		label := msg.ChildSessionKey
 		if label == "" {
@@ -981,6 +998,9 @@ func (m *Model) renderToolCard(idx int, e chatEntry) string {
 		if summary != "" {
 			line += " → " + summary
 		}
+		if n := len(e.toolFiles); n > 0 {
+			line += fmt.Sprintf(" 📎%d", n)
+		}
 
 		style := toolCardCollapsedStyle
 		if isFocused {
@@ -1010,6 +1030,9 @@ func (m *Model) renderToolCard(idx int, e chatEntry) string {
 	if e.toolErr != "" {
 		sb.WriteString("\n" + toolErrorStyle.Render("  ✗ "+e.toolErr))
 	}
+	for _, path := range e.toolFiles {
+		sb.WriteString("\n" + toolResultStyle.Render("  📎 "+path))
+	}
 	inner := sb.String()
 
 	style := toolCardBorderStyle
@@ -1500,7 +1523,7 @@ func copyToClipboard(text string) error {
 // isBotCommand returns true for slash commands that should be routed
 // directly to the bot handler rather than the AI.
 func isBotCommand(text string) bool {
-	botCmds := []string{"/status", "/usage", "/context", "/whoami", "/commands", "/verbose", "/compact", "/new", "/abort"}
+	botCmds := []string{"/status", "/usage", "/context", "/whoami", "/commands", "/verbose", "/compact", "/new", "/abort", "/undo", "/checkpoints", "/answer"}
 	lower := commandToken(text)
 	for _, c := range botCmds {
 		if lower == c {
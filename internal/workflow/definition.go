@@ -0,0 +1,473 @@
+// Package workflow runs multi-step job pipelines.
+//
+// A workflow is a YAML document listing steps with dependencies. Each step is
+// an agent task, a role invocation, a worker-adapter task or an exec command,
+// and may fan out over a list. Steps run as child jobs of one parent workflow
+// job, and later steps read earlier outputs through {{steps.<id>.output}}.
+//
+// Example (workflows/digest.yaml):
+//
+//	name: digest
+//	description: Scrape, summarise and post the daily digest
+//	inputs:
+//	  feed: https://example.com/feed.xml
+//	steps:
+//	  - id: scrape
+//	    exec: curl -s {{inputs.feed}} | grep -o '<link>[^<]*' | cut -c7- | head -5
+//	  - id: summarise
+//	    needs: [scrape]
+//	    for_each: "{{steps.scrape.output}}"
+//	    agent: Summarise {{item}} in two sentences.
+//	    retries: 1
+//	    job:
+//	      tools: [web_fetch]
+//	      max_tool_calls: 5
+//	  - id: post
+//	    needs: [summarise]
+//	    role: publisher
+//	    task: "Post this digest to the channel: {{steps.summarise.output}}"
+package workflow
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"ok-gobot/internal/delegation"
+	"ok-gobot/internal/runtime"
+)
+
+// Dir is the workspace directory holding workflow definitions.
+const Dir = "workflows"
+
+// MaxRetries caps the per-step retry count.
+const MaxRetries = 5
+
+// StepKind is what a step runs.
+type StepKind string
+
+const (
+	KindAgent  StepKind = "agent"
+	KindRole   StepKind = "role"
+	KindWorker StepKind = "worker"
+	KindExec   StepKind = "exec"
+)
+
+// Definition is a parsed workflow.
+type Definition struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	Inputs      map[string]string `yaml:"inputs"` // input name -> default ("" = required)
+	Steps       []*Step           `yaml:"steps"`
+
+	// Source is the file the definition was loaded from ("" when inline).
+	Source string `yaml:"-"`
+}
+
+// Step is one node of a workflow.
+type Step struct {
+	ID string `yaml:"id"`
+
+	// Exactly one of these selects the step kind.
+	Agent  string `yaml:"agent"`  // task prompt for the agent
+	Role   string `yaml:"role"`   // role manifest name (roles/<name>.md)
+	Worker string `yaml:"worker"` // worker adapter name: claude, codex, droid
+	Exec   string `yaml:"exec"`   // shell command; template values are passed as environment variables
+
+	// Task is the task text for role and worker steps.
+	Task string `yaml:"task"`
+
+	Needs       []string  `yaml:"needs"`
+	ForEach     string    `yaml:"for_each"`     // list to fan out over (JSON array or lines)
+	MaxParallel int       `yaml:"max_parallel"` // fan-out concurrency (default 4)
+	Retries     int       `yaml:"retries"`
+	Timeout     string    `yaml:"timeout"`
+	Tier        string    `yaml:"tier"` // cost tier for the scheduler (default: the run's)
+	Job         *Contract `yaml:"job"`
+}
+
+// Contract is the delegated-run contract for agent, role and worker steps.
+type Contract struct {
+	Model         string   `yaml:"model"`
+	Thinking      string   `yaml:"thinking"`
+	Tools         []string `yaml:"tools"`
+	MaxToolCalls  int      `yaml:"max_tool_calls"`
+	MaxDuration   string   `yaml:"max_duration"`
+	OutputFormat  string   `yaml:"output_format"`
+	OutputSchema  string   `yaml:"output_schema"`
+	MemoryPolicy  string   `yaml:"memory_policy"`
+	WorkspaceRoot string   `yaml:"workspace_root"`
+}
+
+var (
+	nameRe        = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+	stepIDRe      = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+)
+
+// Parse reads and validates a workflow definition.
+func Parse(data []byte) (*Definition, error) {
+	var def Definition
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&def); err != nil {
+		return nil, fmt.Errorf("invalid workflow definition: %w", err)
+	}
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// Kind returns which kind of step this is.
+func (s *Step) Kind() StepKind {
+	switch {
+	case s.Agent != "":
+		return KindAgent
+	case s.Role != "":
+		return KindRole
+	case s.Worker != "":
+		return KindWorker
+	case s.Exec != "":
+		return KindExec
+	}
+	return ""
+}
+
+// Validate checks names, step kinds, dependencies and template references.
+func (d *Definition) Validate() error {
+	d.Name = strings.TrimSpace(d.Name)
+	if !nameRe.MatchString(d.Name) {
+		return fmt.Errorf("workflow name %q must be lowercase letters, digits, '-' or '_'", d.Name)
+	}
+	if len(d.Steps) == 0 {
+		return fmt.Errorf("workflow %q has no steps", d.Name)
+	}
+
+	byID := make(map[string]*Step, len(d.Steps))
+	for i, step := range d.Steps {
+		if step == nil {
+			return fmt.Errorf("workflow %q: step %d is empty", d.Name, i+1)
+		}
+		step.ID = strings.TrimSpace(step.ID)
+		if !stepIDRe.MatchString(step.ID) {
+			return fmt.Errorf("workflow %q: step id %q must be lowercase letters, digits or '_'", d.Name, step.ID)
+		}
+		if _, dup := byID[step.ID]; dup {
+			return fmt.Errorf("workflow %q: duplicate step id %q", d.Name, step.ID)
+		}
+		byID[step.ID] = step
+	}
+
+	for _, step := range d.Steps {
+		if err := d.validateStep(step, byID); err != nil {
+			return fmt.Errorf("workflow %q: step %q: %w", d.Name, step.ID, err)
+		}
+	}
+	if _, err := d.order(); err != nil {
+		return fmt.Errorf("workflow %q: %w", d.Name, err)
+	}
+	return nil
+}
+
+func (d *Definition) validateStep(step *Step, byID map[string]*Step) error {
+	kinds := 0
+	for _, v := range []string{step.Agent, step.Role, step.Worker, step.Exec} {
+		if strings.TrimSpace(v) != "" {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("exactly one of agent, role, worker or exec is required")
+	}
+	switch step.Kind() {
+	case KindRole, KindWorker:
+		if strings.TrimSpace(step.Task) == "" {
+			return fmt.Errorf("%s steps need a task", step.Kind())
+		}
+	default:
+		if step.Task != "" {
+			return fmt.Errorf("task is only used by role and worker steps")
+		}
+	}
+	if step.Kind() == KindExec && step.Job != nil {
+		return fmt.Errorf("exec steps do not take a job contract")
+	}
+	if ref := quotedPlaceholder(step.Exec); ref != "" {
+		return fmt.Errorf("placeholder {{%s}} is inside quotes; values are quoted for you, so leave it bare", ref)
+	}
+
+	needs := make(map[string]bool, len(step.Needs))
+	for _, dep := range step.Needs {
+		if _, ok := byID[dep]; !ok {
+			return fmt.Errorf("needs unknown step %q", dep)
+		}
+		if dep == step.ID {
+			return fmt.Errorf("cannot need itself")
+		}
+		needs[dep] = true
+	}
+
+	if step.Retries < 0 || step.Retries > MaxRetries {
+		return fmt.Errorf("retries must be between 0 and %d", MaxRetries)
+	}
+	if step.MaxParallel < 0 {
+		return fmt.Errorf("max_parallel must not be negative")
+	}
+	if step.Timeout != "" {
+		if _, err := time.ParseDuration(step.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout %q: %w", step.Timeout, err)
+		}
+	}
+	if step.Tier != "" {
+		if _, ok := runtime.ParseCostTier(step.Tier); !ok {
+			return fmt.Errorf("invalid tier %q (want premium, standard, cheap or local)", step.Tier)
+		}
+	}
+	if _, err := step.contract(); err != nil {
+		return err
+	}
+
+	for _, text := range []string{step.Agent, step.Exec, step.Task, step.ForEach} {
+		for _, ref := range references(text) {
+			if err := d.checkReference(step, ref, needs, text == step.ForEach); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// quotedPlaceholder returns the first placeholder in an exec command that
+// sits inside single or double quotes, where the substituted variable
+// reference would not expand as one word.
+func quotedPlaceholder(command string) string {
+	var quote byte
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case quote == c:
+			quote = 0
+		case c == '\\' && quote != '\'':
+			i++ // escaped character
+		case quote != 0 && c == '{':
+			if loc := placeholderRe.FindStringSubmatchIndex(command[i:]); loc != nil && loc[0] == 0 {
+				return command[i+loc[2] : i+loc[3]]
+			}
+		}
+	}
+	return ""
+}
+
+func (d *Definition) checkReference(step *Step, ref string, needs map[string]bool, inForEach bool) error {
+	parts := strings.Split(ref, ".")
+	switch {
+	case ref == "item":
+		if step.ForEach == "" || inForEach {
+			return fmt.Errorf("{{item}} is only available in steps with for_each")
+		}
+	case parts[0] == "inputs" && len(parts) == 2:
+		if _, ok := d.Inputs[parts[1]]; !ok {
+			return fmt.Errorf("{{%s}} refers to an undeclared input", ref)
+		}
+	case parts[0] == "steps" && len(parts) == 3 && parts[2] == "output",
+		parts[0] == "steps" && len(parts) == 4 && parts[2] == "artifacts":
+		if !needs[parts[1]] {
+			return fmt.Errorf("{{%s}} refers to step %q, which is not listed in needs", ref, parts[1])
+		}
+	default:
+		return fmt.Errorf("unknown placeholder {{%s}}", ref)
+	}
+	return nil
+}
+
+// order returns the steps in dependency order, rejecting cycles.
+func (d *Definition) order() ([]*Step, error) {
+	state := make(map[string]int, len(d.Steps)) // 0 new, 1 visiting, 2 done
+	byID := make(map[string]*Step, len(d.Steps))
+	for _, step := range d.Steps {
+		byID[step.ID] = step
+	}
+	var ordered []*Step
+	var visit func(step *Step) error
+	visit = func(step *Step) error {
+		switch state[step.ID] {
+		case 1:
+			return fmt.Errorf("dependency cycle through step %q", step.ID)
+		case 2:
+			return nil
+		}
+		state[step.ID] = 1
+		for _, dep := range step.Needs {
+			if err := visit(byID[dep]); err != nil {
+				return err
+			}
+		}
+		state[step.ID] = 2
+		ordered = append(ordered, step)
+		return nil
+	}
+	for _, step := range d.Steps {
+		if err := visit(step); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// contract converts the step's job block into a delegation contract.
+func (s *Step) contract() (delegation.Job, error) {
+	if s.Job == nil {
+		return delegation.Job{}.WithDefaults(), nil
+	}
+	c := s.Job
+	job := delegation.Job{
+		Model:         c.Model,
+		Thinking:      c.Thinking,
+		ToolAllowlist: c.Tools,
+		MaxToolCalls:  c.MaxToolCalls,
+		OutputSchema:  c.OutputSchema,
+		WorkspaceRoot: c.WorkspaceRoot,
+	}
+	if c.OutputFormat != "" {
+		format, ok := delegation.ParseOutputFormat(c.OutputFormat)
+		if !ok {
+			return delegation.Job{}, fmt.Errorf("invalid output_format %q", c.OutputFormat)
+		}
+		job.OutputFormat = format
+	}
+	if c.MemoryPolicy != "" {
+		policy, ok := delegation.ParseMemoryPolicy(c.MemoryPolicy)
+		if !ok {
+			return delegation.Job{}, fmt.Errorf("invalid memory_policy %q", c.MemoryPolicy)
+		}
+		job.MemoryPolicy = policy
+	}
+	if c.MaxDuration != "" {
+		d, err := time.ParseDuration(c.MaxDuration)
+		if err != nil {
+			return delegation.Job{}, fmt.Errorf("invalid max_duration %q: %w", c.MaxDuration, err)
+		}
+		job.MaxDuration = d
+	}
+	if c.MaxToolCalls < 0 {
+		return delegation.Job{}, fmt.Errorf("max_tool_calls must not be negative")
+	}
+	return job.WithDefaults(), nil
+}
+
+// HasExec reports whether any step runs a shell command.
+func (d *Definition) HasExec() bool {
+	for _, step := range d.Steps {
+		if step.Kind() == KindExec {
+			return true
+		}
+	}
+	return false
+}
+
+// RunsLocal reports whether any step runs a local process: a shell command
+// or a coding-agent worker CLI.
+func (d *Definition) RunsLocal() bool {
+	for _, step := range d.Steps {
+		if kind := step.Kind(); kind == KindExec || kind == KindWorker {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveInputs merges provided values over the declared defaults.
+func (d *Definition) ResolveInputs(values map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(d.Inputs))
+	for name := range values {
+		if _, ok := d.Inputs[name]; !ok {
+			return nil, fmt.Errorf("workflow %q has no input %q", d.Name, name)
+		}
+	}
+	for name, def := range d.Inputs {
+		v, ok := values[name]
+		if !ok || v == "" {
+			v = def
+		}
+		if v == "" {
+			return nil, fmt.Errorf("workflow %q: input %q is required", d.Name, name)
+		}
+		resolved[name] = v
+	}
+	return resolved, nil
+}
+
+func references(text string) []string {
+	var refs []string
+	for _, m := range placeholderRe.FindAllStringSubmatch(text, -1) {
+		refs = append(refs, m[1])
+	}
+	return refs
+}
+
+// Load reads workflows/<name>.yaml (or .yml) from the workspace.
+func Load(basePath, name string) (*Definition, error) {
+	if !nameRe.MatchString(name) {
+		return nil, fmt.Errorf("invalid workflow name %q", name)
+	}
+	for _, ext := range []string{".yaml", ".yml"} {
+		path := filepath.Join(basePath, Dir, name+ext)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		def, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		if def.Name != name {
+			return nil, fmt.Errorf("%s: name %q does not match the file name", filepath.Base(path), def.Name)
+		}
+		def.Source = path
+		return def, nil
+	}
+	return nil, fmt.Errorf("workflow %q not found in %s/", name, Dir)
+}
+
+// LoadDir reads every workflow in the workspace, sorted by name. Invalid
+// files are reported as errors without stopping the load.
+func LoadDir(basePath string) ([]*Definition, []error) {
+	entries, err := os.ReadDir(filepath.Join(basePath, Dir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+	var (
+		defs []*Definition
+		errs []error
+	)
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		def, err := Load(basePath, strings.TrimSuffix(entry.Name(), ext))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs, errs
+}
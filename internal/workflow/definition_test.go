@@ -0,0 +1,101 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseValidatesSteps(t *testing.T) {
+	t.Parallel()
+
+	def, err := Parse([]byte(`
+name: digest
+inputs:
+  feed: https://example.com/feed
+steps:
+  - id: scrape
+    exec: curl -s {{inputs.feed}}
+  - id: summarise
+    needs: [scrape]
+    for_each: "{{steps.scrape.output}}"
+    agent: Summarise {{item}}
+    retries: 2
+    job:
+      tools: [web_fetch]
+      max_duration: 2m
+  - id: post
+    needs: [summarise]
+    role: publisher
+    task: "Post {{steps.summarise.output}}"
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := def.Steps[1].Kind(); got != KindAgent {
+		t.Errorf("summarise kind = %q", got)
+	}
+	if !def.HasExec() || !def.RunsLocal() {
+		t.Error("expected HasExec and RunsLocal")
+	}
+	contract, err := def.Steps[1].contract()
+	if err != nil || contract.MaxDuration.Minutes() != 2 || len(contract.ToolAllowlist) != 1 {
+		t.Errorf("unexpected contract %+v, %v", contract, err)
+	}
+
+	cases := map[string]string{
+		"no kind":        "name: x\nsteps:\n  - id: a\n",
+		"two kinds":      "name: x\nsteps:\n  - id: a\n    agent: hi\n    exec: ls\n",
+		"unknown need":   "name: x\nsteps:\n  - id: a\n    agent: hi\n    needs: [b]\n",
+		"cycle":          "name: x\nsteps:\n  - id: a\n    agent: hi\n    needs: [b]\n  - id: b\n    agent: hi\n    needs: [a]\n",
+		"undeclared ref": "name: x\nsteps:\n  - id: a\n    agent: hi\n  - id: b\n    agent: \"{{steps.a.output}}\"\n",
+		"item outside":   "name: x\nsteps:\n  - id: a\n    agent: \"{{item}}\"\n",
+		"role no task":   "name: x\nsteps:\n  - id: a\n    role: writer\n",
+		"unknown field":  "name: x\nsteps:\n  - id: a\n    agent: hi\n    retry: 1\n",
+		"bad input":      "name: x\nsteps:\n  - id: a\n    agent: \"{{inputs.nope}}\"\n",
+		"too many tries": "name: x\nsteps:\n  - id: a\n    agent: hi\n    retries: 9\n",
+		"quoted exec":    "name: x\ninputs:\n  v: a\nsteps:\n  - id: a\n    exec: echo \"{{inputs.v}}\"\n",
+	}
+	for name, src := range cases {
+		if _, err := Parse([]byte(src)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadDirAndInputs(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	dir := filepath.Join(base, Dir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("greet.yaml", "name: greet\ninputs:\n  who: \"\"\nsteps:\n  - id: say\n    exec: echo {{inputs.who}}\n")
+	write("broken.yaml", "name: broken\nsteps: []\n")
+	write("notes.txt", "ignored")
+
+	defs, errs := LoadDir(base)
+	if len(defs) != 1 || defs[0].Name != "greet" {
+		t.Fatalf("unexpected definitions %+v", defs)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken.yaml") {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+
+	if _, err := defs[0].ResolveInputs(nil); err == nil {
+		t.Error("missing required input should fail")
+	}
+	if _, err := defs[0].ResolveInputs(map[string]string{"who": "x", "extra": "y"}); err == nil {
+		t.Error("unknown input should fail")
+	}
+	if _, err := Load(base, "missing"); err == nil {
+		t.Error("expected not found")
+	}
+}
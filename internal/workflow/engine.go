@@ -0,0 +1,550 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"ok-gobot/internal/delegation"
+	"ok-gobot/internal/runtime"
+	"ok-gobot/internal/storage"
+	"ok-gobot/internal/worker"
+)
+
+const (
+	// JobKind is the kind of the parent job of a workflow run.
+	JobKind = "workflow"
+	// StepJobKind is the kind of each child job a step runs as.
+	StepJobKind = "workflow_step"
+
+	defaultMaxParallel = 4
+	maxFanOut          = 100
+	maxExecOutput      = 64 * 1024
+	defaultExecTimeout = 10 * time.Minute
+)
+
+// Executors run the step kinds that live outside this package.
+// A nil executor makes steps of that kind fail with a clear error.
+type Executors struct {
+	// Agent runs a task through the agent under the given contract.
+	Agent func(ctx context.Context, task string, job delegation.Job) (string, error)
+	// Role runs a task as the named role under the given contract.
+	Role func(ctx context.Context, role, task string, job delegation.Job) (string, error)
+	// Worker resolves a worker adapter by name.
+	Worker func(name string) (worker.Adapter, error)
+}
+
+// DefaultWorker resolves the built-in worker adapters.
+func DefaultWorker(name string) (worker.Adapter, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "claude":
+		return worker.NewClaudeAdapter(worker.ClaudeConfig{}), nil
+	case "codex":
+		return worker.NewCodexAdapter(worker.CodexConfig{}), nil
+	case "droid":
+		return worker.NewDroidAdapter(worker.DroidConfig{}), nil
+	}
+	return nil, fmt.Errorf("unknown worker %q (want claude, codex or droid)", name)
+}
+
+// RunSpec carries the per-run parameters of a workflow.
+type RunSpec struct {
+	Inputs             map[string]string
+	Priority           runtime.JobPriority
+	CostTier           runtime.CostTier
+	SessionKey         string
+	DeliverySessionKey string
+}
+
+// Engine executes workflows as a parent job with one child job per step
+// (or per fan-out item).
+type Engine struct {
+	store   *storage.Store
+	jobs    *runtime.JobService
+	workDir string
+	exec    Executors
+}
+
+// NewEngine creates a workflow engine. workDir is where exec steps run.
+func NewEngine(store *storage.Store, jobs *runtime.JobService, workDir string, executors Executors) *Engine {
+	return &Engine{store: store, jobs: jobs, workDir: workDir, exec: executors}
+}
+
+// Start validates the inputs and launches the workflow in the background.
+// The returned job is the parent; steps appear as its children.
+func (e *Engine) Start(ctx context.Context, def *Definition, spec RunSpec) (*storage.Job, error) {
+	if def == nil {
+		return nil, fmt.Errorf("workflow definition is required")
+	}
+	inputs, err := def.ResolveInputs(spec.Inputs)
+	if err != nil {
+		return nil, err
+	}
+	description := def.Description
+	if description == "" {
+		description = fmt.Sprintf("workflow %s (%d steps)", def.Name, len(def.Steps))
+	}
+	// The run outlives the request that started it.
+	return e.jobs.StartDetached(context.WithoutCancel(ctx), runtime.JobSpec{
+		Kind:               JobKind,
+		Worker:             JobKind + ":" + def.Name,
+		SessionKey:         spec.SessionKey,
+		DeliverySessionKey: spec.DeliverySessionKey,
+		Priority:           spec.Priority,
+		CostTier:           spec.CostTier,
+		Description:        description,
+		MaxAttempts:        1,
+		Coordinator:        true,
+	}, func(ctx context.Context, job *storage.Job, svc *runtime.JobService) (runtime.JobRunResult, error) {
+		return e.run(ctx, job, def, inputs)
+	})
+}
+
+// stepResult is what later steps can reference from a finished step.
+type stepResult struct {
+	output    string
+	artifacts map[string]string
+}
+
+type stepDone struct {
+	id     string
+	result stepResult
+	err    error
+}
+
+// run schedules steps as their dependencies finish. The first failure
+// cancels the remaining steps.
+func (e *Engine) run(ctx context.Context, parent *storage.Job, def *Definition, inputs map[string]string) (runtime.JobRunResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(map[string]stepResult, len(def.Steps))
+	started := make(map[string]bool, len(def.Steps))
+	doneCh := make(chan stepDone)
+	running := 0
+	var firstErr error
+
+	for {
+		if firstErr == nil {
+			for _, step := range def.Steps {
+				if started[step.ID] || !dependenciesDone(step, results) {
+					continue
+				}
+				started[step.ID] = true
+				running++
+				vars := templateVars(inputs, results)
+				go func(step *Step) {
+					result, err := e.runStep(ctx, parent, def, step, vars)
+					doneCh <- stepDone{id: step.ID, result: result, err: err}
+				}(step)
+			}
+		}
+		if running == 0 {
+			break
+		}
+
+		done := <-doneCh
+		running--
+		if done.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("step %s: %w", done.id, done.err)
+				_ = e.jobs.AppendEvent(parent.JobID, runtime.JobEventProgress, firstErr.Error(), nil)
+				cancel()
+			}
+			continue
+		}
+		results[done.id] = done.result
+		_ = e.jobs.AppendEvent(parent.JobID, runtime.JobEventProgress,
+			fmt.Sprintf("step %s finished (%d/%d)", done.id, len(results), len(def.Steps)), nil)
+	}
+	if firstErr != nil {
+		return runtime.JobRunResult{}, firstErr
+	}
+
+	outputs := make(map[string]string, len(results))
+	for id, result := range results {
+		outputs[id] = result.output
+	}
+	outputsJSON, _ := json.MarshalIndent(outputs, "", "  ")
+	return runtime.JobRunResult{
+		Summary: summarize(def, results),
+		Artifacts: []runtime.JobArtifactSpec{{
+			Name:     "outputs.json",
+			Type:     "json",
+			MimeType: "application/json",
+			Content:  string(outputsJSON),
+		}},
+	}, nil
+}
+
+func dependenciesDone(step *Step, results map[string]stepResult) bool {
+	for _, dep := range step.Needs {
+		if _, ok := results[dep]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// summarize reports the outputs of the final steps — those nothing depends on.
+func summarize(def *Definition, results map[string]stepResult) string {
+	needed := make(map[string]bool)
+	for _, step := range def.Steps {
+		for _, dep := range step.Needs {
+			needed[dep] = true
+		}
+	}
+	var sinks []string
+	for _, step := range def.Steps {
+		if !needed[step.ID] {
+			sinks = append(sinks, step.ID)
+		}
+	}
+	if len(sinks) == 1 {
+		return results[sinks[0]].output
+	}
+	var b strings.Builder
+	for _, id := range sinks {
+		fmt.Fprintf(&b, "## %s\n%s\n\n", id, strings.TrimSpace(results[id].output))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// runStep runs one step, fanning out over for_each items when set.
+func (e *Engine) runStep(ctx context.Context, parent *storage.Job, def *Definition, step *Step, vars map[string]string) (stepResult, error) {
+	if step.ForEach == "" {
+		return e.runChild(ctx, parent, def, step, "", vars)
+	}
+
+	items, err := parseList(render(step.ForEach, vars))
+	if err != nil {
+		return stepResult{}, err
+	}
+	if len(items) > maxFanOut {
+		return stepResult{}, fmt.Errorf("for_each produced %d items (limit %d)", len(items), maxFanOut)
+	}
+
+	parallel := step.MaxParallel
+	if parallel <= 0 {
+		parallel = defaultMaxParallel
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		outputs  = make([]string, len(items))
+		sem      = make(chan struct{}, parallel)
+	)
+	for i, item := range items {
+		wg.Add(1)
+		go func(i int, item string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+			itemVars := make(map[string]string, len(vars)+1)
+			for k, v := range vars {
+				itemVars[k] = v
+			}
+			itemVars["item"] = item
+			result, err := e.runChild(ctx, parent, def, step, fmt.Sprintf("[%d]", i), itemVars)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("item %d: %w", i, err)
+					cancel()
+				}
+				return
+			}
+			outputs[i] = result.output
+		}(i, item)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return stepResult{}, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return stepResult{}, err
+	}
+
+	data, _ := json.Marshal(outputs)
+	return stepResult{output: string(data)}, nil
+}
+
+// runChild runs one unit of work as a child job, retrying failed attempts.
+func (e *Engine) runChild(ctx context.Context, parent *storage.Job, def *Definition, step *Step, suffix string, vars map[string]string) (stepResult, error) {
+	contract, err := step.contract()
+	if err != nil {
+		return stepResult{}, err
+	}
+	runner, workerName, err := e.runnerFor(step, contract, vars)
+	if err != nil {
+		return stepResult{}, err
+	}
+
+	timeout := contract.MaxDuration
+	if step.Kind() == KindExec {
+		timeout = defaultExecTimeout
+	}
+	if step.Timeout != "" {
+		timeout, _ = time.ParseDuration(step.Timeout)
+	}
+	tier := runtime.CostTier(parent.CostTier)
+	if step.Tier != "" {
+		tier, _ = runtime.ParseCostTier(step.Tier)
+	}
+
+	job, err := e.jobs.StartDetached(ctx, runtime.JobSpec{
+		Kind:        StepJobKind,
+		Worker:      workerName,
+		SessionKey:  parent.SessionKey,
+		ParentJobID: parent.JobID,
+		Priority:    runtime.JobPriority(parent.Priority),
+		CostTier:    tier,
+		Description: fmt.Sprintf("%s/%s%s", def.Name, step.ID, suffix),
+		MaxAttempts: step.Retries + 1,
+		Timeout:     timeout,
+	}, runner)
+	if err != nil {
+		return stepResult{}, err
+	}
+
+	for {
+		job, err = e.jobs.Wait(ctx, job.JobID)
+		if err != nil {
+			return stepResult{}, err
+		}
+		if job.Status == string(runtime.JobStatusSucceeded) {
+			return e.collect(job)
+		}
+		if job.Status == string(runtime.JobStatusCancelled) || job.Attempt >= job.MaxAttempts || ctx.Err() != nil {
+			return stepResult{}, fmt.Errorf("%s (%s): %s", job.Status, job.JobID, job.Error)
+		}
+		if job, err = e.jobs.RetryDetached(ctx, job.JobID, runner); err != nil {
+			return stepResult{}, err
+		}
+	}
+}
+
+// collect reads a finished child's output and named artifacts.
+func (e *Engine) collect(job *storage.Job) (stepResult, error) {
+	artifacts, err := e.store.ListJobArtifacts(job.JobID, 50)
+	if err != nil {
+		return stepResult{}, err
+	}
+	result := stepResult{output: job.Summary, artifacts: make(map[string]string, len(artifacts))}
+	for _, artifact := range artifacts {
+		content := artifact.Content
+		if content == "" {
+			content = artifact.URI
+		}
+		result.artifacts[artifact.Name] = content
+	}
+	if output, ok := result.artifacts["output"]; ok {
+		result.output = output
+	}
+	return result, nil
+}
+
+// runnerFor renders the step's templates and builds its job runner.
+func (e *Engine) runnerFor(step *Step, contract delegation.Job, vars map[string]string) (runtime.JobRunner, string, error) {
+	switch step.Kind() {
+	case KindAgent:
+		if e.exec.Agent == nil {
+			return nil, "", errors.New("agent steps are not available")
+		}
+		task := render(step.Agent, vars)
+		return textRunner(func(ctx context.Context) (string, error) {
+			return e.exec.Agent(ctx, task, contract)
+		}), "agent", nil
+
+	case KindRole:
+		if e.exec.Role == nil {
+			return nil, "", errors.New("role steps are not available")
+		}
+		role, task := step.Role, render(step.Task, vars)
+		return textRunner(func(ctx context.Context) (string, error) {
+			return e.exec.Role(ctx, role, task, contract)
+		}), "role:" + role, nil
+
+	case KindWorker:
+		resolve := e.exec.Worker
+		if resolve == nil {
+			resolve = DefaultWorker
+		}
+		adapter, err := resolve(step.Worker)
+		if err != nil {
+			return nil, "", err
+		}
+		workDir := contract.WorkspaceRoot
+		if workDir == "" {
+			workDir = e.workDir
+		}
+		return worker.AdapterJobRunner(adapter, worker.Request{
+			Task:    render(step.Task, vars),
+			Model:   contract.Model,
+			WorkDir: workDir,
+		}), step.Worker, nil
+
+	case KindExec:
+		command, env := renderExec(step.Exec, vars)
+		return textRunner(func(ctx context.Context) (string, error) {
+			return e.runExec(ctx, command, env)
+		}), "exec", nil
+	}
+	return nil, "", fmt.Errorf("step %q has no kind", step.ID)
+}
+
+// textRunner adapts a text-producing function to a job runner whose output
+// is kept as the "output" artifact.
+func textRunner(fn func(ctx context.Context) (string, error)) runtime.JobRunner {
+	return func(ctx context.Context, job *storage.Job, svc *runtime.JobService) (runtime.JobRunResult, error) {
+		output, err := fn(ctx)
+		if err != nil {
+			return runtime.JobRunResult{}, err
+		}
+		return runtime.JobRunResult{
+			Summary: output,
+			Artifacts: []runtime.JobArtifactSpec{{
+				Name:     "output",
+				Type:     "text",
+				MimeType: "text/plain",
+				Content:  output,
+			}},
+		}, nil
+	}
+}
+
+func (e *Engine) runExec(ctx context.Context, command string, env []string) (string, error) {
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Dir = e.workDir
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if len(output) > maxExecOutput {
+		output = output[:maxExecOutput] + "\n... [truncated]"
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if output == "" {
+			return "", err
+		}
+		return "", fmt.Errorf("%w: %s", err, output)
+	}
+	return output, nil
+}
+
+// templateVars flattens inputs and finished step results into placeholder
+// values.
+func templateVars(inputs map[string]string, results map[string]stepResult) map[string]string {
+	vars := make(map[string]string, len(inputs)+len(results))
+	for name, value := range inputs {
+		vars["inputs."+name] = value
+	}
+	for id, result := range results {
+		vars["steps."+id+".output"] = result.output
+		for name, content := range result.artifacts {
+			vars["steps."+id+".artifacts."+name] = content
+		}
+	}
+	return vars
+}
+
+// render substitutes {{...}} placeholders.
+func render(text string, vars map[string]string) string {
+	return placeholderRe.ReplaceAllStringFunc(text, func(match string) string {
+		return vars[placeholderRe.FindStringSubmatch(match)[1]]
+	})
+}
+
+// renderExec replaces each placeholder in an exec command with a quoted
+// environment variable reference and returns the variables to set. Values
+// never become shell source, so step outputs cannot inject commands.
+func renderExec(command string, vars map[string]string) (string, []string) {
+	var env []string
+	rendered := placeholderRe.ReplaceAllStringFunc(command, func(match string) string {
+		name := fmt.Sprintf("WORKFLOW_VALUE_%d", len(env))
+		env = append(env, name+"="+vars[placeholderRe.FindStringSubmatch(match)[1]])
+		return `"$` + name + `"`
+	})
+	return rendered, env
+}
+
+// parseList reads a fan-out list: a JSON array, or one item per line.
+func parseList(text string) ([]string, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "[") {
+		var raw []json.RawMessage
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return nil, fmt.Errorf("for_each: invalid JSON array: %w", err)
+		}
+		items := make([]string, 0, len(raw))
+		for _, r := range raw {
+			var s string
+			if err := json.Unmarshal(r, &s); err == nil {
+				items = append(items, s)
+				continue
+			}
+			items = append(items, string(r))
+		}
+		return items, nil
+	}
+	var items []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			items = append(items, line)
+		}
+	}
+	return items, nil
+}
+
+// Status renders a workflow run and its step jobs as a short tree.
+func (e *Engine) Status(jobID string) (string, error) {
+	job, err := e.store.GetJob(jobID)
+	if err != nil {
+		return "", err
+	}
+	if job == nil {
+		return "", fmt.Errorf("job %q not found", jobID)
+	}
+	children, err := e.store.ListChildJobs(jobID)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s [%s]\n", job.JobID, job.Worker, job.Status)
+	for _, child := range children {
+		attempt := ""
+		if child.MaxAttempts > 1 {
+			attempt = fmt.Sprintf(" attempt %d/%d", child.Attempt, child.MaxAttempts)
+		}
+		fmt.Fprintf(&b, "  %s %s [%s]%s", child.JobID, child.Description, child.Status, attempt)
+		if child.Error != "" {
+			fmt.Fprintf(&b, ": %s", child.Error)
+		}
+		b.WriteString("\n")
+	}
+	switch {
+	case job.Error != "":
+		fmt.Fprintf(&b, "\nError: %s", job.Error)
+	case job.Summary != "":
+		fmt.Fprintf(&b, "\n%s", job.Summary)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
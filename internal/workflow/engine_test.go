@@ -0,0 +1,172 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ok-gobot/internal/delegation"
+	"ok-gobot/internal/runtime"
+	"ok-gobot/internal/storage"
+)
+
+func newEngineTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.New(filepath.Join(t.TempDir(), "workflow.db"))
+	if err != nil {
+		t.Fatalf("storage.New failed: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func waitForWorkflow(t *testing.T, jobs *runtime.JobService, jobID string) *storage.Job {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	job, err := jobs.Wait(ctx, jobID)
+	if err != nil {
+		t.Fatalf("Wait(%s) failed: %v", jobID, err)
+	}
+	return job
+}
+
+func TestEngineRunsStepsAsChildJobs(t *testing.T) {
+	t.Parallel()
+
+	store := newEngineTestStore(t)
+	jobs := runtime.NewJobService(store)
+
+	var flaky atomic.Int32
+	engine := NewEngine(store, jobs, t.TempDir(), Executors{
+		Agent: func(ctx context.Context, task string, job delegation.Job) (string, error) {
+			if task == "summarise b" && flaky.Add(1) == 1 {
+				return "", errors.New("model hiccup")
+			}
+			return strings.ToUpper(task), nil
+		},
+		Role: func(ctx context.Context, role, task string, job delegation.Job) (string, error) {
+			return role + ": " + task, nil
+		},
+	})
+
+	def, err := Parse([]byte(`
+name: pipeline
+inputs:
+  prefix: item
+steps:
+  - id: list
+    exec: printf '%s-a\n%s-b\n' {{inputs.prefix}} {{inputs.prefix}} | sed 's/item-//'
+  - id: summarise
+    needs: [list]
+    for_each: "{{steps.list.output}}"
+    agent: summarise {{item}}
+    retries: 1
+  - id: post
+    needs: [summarise]
+    role: publisher
+    task: "{{steps.summarise.output}}"
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	parent, err := engine.Start(context.Background(), def, RunSpec{})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	done := waitForWorkflow(t, jobs, parent.JobID)
+	if done.Status != string(runtime.JobStatusSucceeded) {
+		t.Fatalf("workflow ended %s: %s", done.Status, done.Error)
+	}
+	if want := `publisher: ["SUMMARISE A","SUMMARISE B"]`; done.Summary != want {
+		t.Errorf("summary = %q, want %q", done.Summary, want)
+	}
+
+	children, err := store.ListChildJobs(parent.JobID)
+	if err != nil {
+		t.Fatalf("ListChildJobs failed: %v", err)
+	}
+	// list, two fan-out items, one retry of the flaky item, post.
+	if len(children) != 5 {
+		t.Fatalf("expected 5 child jobs, got %d", len(children))
+	}
+	var retried bool
+	for _, child := range children {
+		if child.Kind != StepJobKind {
+			t.Errorf("child kind = %q", child.Kind)
+		}
+		if child.RetryOfJobID != "" && child.Status == string(runtime.JobStatusSucceeded) {
+			retried = true
+		}
+	}
+	if !retried {
+		t.Error("expected the flaky item to succeed on retry")
+	}
+
+	status, err := engine.Status(parent.JobID)
+	if err != nil || !strings.Contains(status, "pipeline/summarise[1]") {
+		t.Errorf("unexpected status %q, %v", status, err)
+	}
+}
+
+func TestEngineFailureStopsDependents(t *testing.T) {
+	t.Parallel()
+
+	store := newEngineTestStore(t)
+	jobs := runtime.NewJobService(store)
+	var ran atomic.Bool
+	engine := NewEngine(store, jobs, t.TempDir(), Executors{
+		Agent: func(ctx context.Context, task string, job delegation.Job) (string, error) {
+			ran.Store(true)
+			return "", nil
+		},
+	})
+
+	def, err := Parse([]byte(`
+name: failing
+steps:
+  - id: boom
+    exec: echo "nope; $(echo injected)" && exit 3
+  - id: after
+    needs: [boom]
+    agent: should not run
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	parent, err := engine.Start(context.Background(), def, RunSpec{})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	done := waitForWorkflow(t, jobs, parent.JobID)
+	if done.Status != string(runtime.JobStatusFailed) || !strings.Contains(done.Error, "step boom") {
+		t.Fatalf("unexpected result %s: %s", done.Status, done.Error)
+	}
+	if ran.Load() {
+		t.Error("dependent step ran after a failure")
+	}
+}
+
+func TestRenderExecPassesValuesAsEnvironment(t *testing.T) {
+	t.Parallel()
+
+	value := `a'; "$(echo injected)" $HOME`
+	command, env := renderExec("printf %s {{inputs.x}}", map[string]string{"inputs.x": value})
+	if strings.Contains(command, "injected") {
+		t.Fatalf("value spliced into command: %q", command)
+	}
+	e := &Engine{workDir: t.TempDir()}
+	out, err := e.runExec(context.Background(), command, env)
+	if err != nil || out != value {
+		t.Errorf("runExec = %q, %v; want %q", out, err, value)
+	}
+	items, err := parseList(`["a", {"b": 1}]`)
+	if err != nil || len(items) != 2 || items[0] != "a" || items[1] != `{"b": 1}` {
+		t.Errorf("parseList = %q, %v", items, err)
+	}
+}